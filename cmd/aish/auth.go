@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm/gemini/auth"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the Google sign-in of gemini-cli per profile",
+	Long: `Each profile keeps its own gemini-cli Google sign-in, so a work and a personal
+account can be used side by side. Pick the profile with --profile or AISH_PROFILE;
+without either, the default profile is used, which also falls back to the
+credentials of an installed gemini-cli (~/.gemini).`,
+	Example: `  aish --profile work auth login
+  AISH_PROFILE=work aish -p "list my buckets"
+  aish auth status`,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the Google account bound to the active profile",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profile := config.Profile()
+		st, err := auth.StatusOf(profile)
+		if err != nil {
+			pterm.Error.Printfln("Failed to read the credentials of profile %s: %v", profile, err)
+			os.Exit(aerrors.ExitConfig)
+		}
+
+		pterm.Printfln("Profile:      %s%s", profile, profileSource())
+		pterm.Printfln("Credentials:  %s", st.Path)
+		if !st.SignedIn {
+			pterm.Printfln("Account:      not signed in")
+			if auth.SystemCredentialsAllowed() {
+				pterm.Info.Println("The default profile falls back to the gemini-cli credentials in ~/.gemini, if any.")
+			}
+			pterm.Info.Printfln("Sign in with '%s'.", authLoginHint(profile))
+		} else {
+			if st.Email == "" {
+				// Credentials saved before sign-in recorded the account: look it up once
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				st.Email, _ = auth.BindEmail(ctx)
+				cancel()
+			}
+			pterm.Printfln("Account:      %s", orUnknown(st.Email))
+			pterm.Printfln("Project:      %s", orNone(st.ProjectID))
+			cfg, _ := config.Load()
+			pterm.Printfln("Token:        %s", tokenState(localeFormat(cfg), st.Expiry))
+		}
+
+		profiles, _ := config.Profiles()
+		if len(profiles) > 1 {
+			pterm.Println()
+			pterm.Println("Profiles:")
+			for _, name := range profiles {
+				marker := "  "
+				if name == profile {
+					marker = "* "
+				}
+				other, err := auth.StatusOf(name)
+				switch {
+				case err != nil || !other.SignedIn:
+					pterm.Printfln("  %s%s (not signed in)", marker, name)
+				default:
+					pterm.Printfln("  %s%s (%s)", marker, name, orUnknown(other.Email))
+				}
+			}
+		}
+		if !st.SignedIn {
+			os.Exit(aerrors.ExitConfig)
+		}
+	},
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Sign in to Google for the active profile",
+	Long: `Opens the Google sign-in in a browser and saves the credentials to the active
+profile, replacing any earlier sign-in of that profile only.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profile := config.Profile()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		pterm.Info.Printfln("Signing in profile %s...", profile)
+		if err := auth.StartWebAuthFlow(ctx); err != nil {
+			pterm.Error.Printfln("Sign-in failed: %v", err)
+			os.Exit(aerrors.ExitProvider)
+		}
+		st, _ := auth.StatusOf(profile)
+		pterm.Success.Printfln("Profile %s is signed in as %s.", profile, orUnknown(st.Email))
+	},
+}
+
+var authLogoutForce bool
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout [gemini-cli]",
+	Short: "Revoke and delete the Google sign-in of the active profile",
+	Long: `Revokes the refresh token of the active profile with Google, so it cannot be used
+anymore, and deletes the credentials file of the profile. If Google cannot be
+reached, the credentials are kept so that the logout can be retried; --force
+deletes them anyway. The credentials of an installed gemini-cli (~/.gemini) are
+not touched: sign out of gemini-cli itself for those.`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"gemini-cli"},
+	Run: func(cmd *cobra.Command, args []string) {
+		profile := config.Profile()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		res, err := auth.Logout(ctx, authLogoutForce)
+		if err != nil {
+			pterm.Error.Printfln("Failed to sign out profile %s: %v", profile, err)
+			if res.SignedIn {
+				pterm.Info.Println("The credentials were kept. Retry, or delete them without revoking the token with --force.")
+			}
+			os.Exit(aerrors.ExitProvider)
+		}
+		if !res.SignedIn {
+			pterm.Info.Printfln("Profile %s is not signed in.", profile)
+			if auth.SystemCredentialsAllowed() {
+				pterm.Info.Println("Credentials of gemini-cli in ~/.gemini, if any, are gemini-cli's own and were left alone.")
+			}
+			return
+		}
+		for _, path := range res.Removed {
+			pterm.Printfln("Deleted %s", path)
+		}
+		if !res.Revoked {
+			pterm.Warning.Println("The token was not revoked: Google no longer accepted it, or --force skipped the revocation.")
+		}
+		pterm.Success.Printfln("Profile %s is signed out of %s.", profile, orUnknown(res.Email))
+	},
+}
+
+// profileSource says where the active profile was chosen, for 'aish auth status'.
+func profileSource() string {
+	if os.Getenv(config.EnvAISHProfile) == "" {
+		return ""
+	}
+	if flagProfile != "" {
+		return " (--profile)"
+	}
+	return fmt.Sprintf(" (%s)", config.EnvAISHProfile)
+}
+
+// authLoginHint is the sign-in command for profile.
+func authLoginHint(profile string) string {
+	if profile == config.DefaultProfile {
+		return "aish auth login"
+	}
+	return fmt.Sprintf("aish --profile %s auth login", profile)
+}
+
+// tokenState describes when an access token expires, in the user's locale and time zone;
+// the refresh token renews it.
+func tokenState(format ui.LocaleFormat, expiry time.Time) string {
+	if expiry.IsZero() {
+		return "no expiry recorded"
+	}
+	d := time.Until(expiry).Round(time.Minute)
+	if d <= 0 {
+		return fmt.Sprintf("expired %s ago (%s)", -d, format.DateTime(expiry))
+	}
+	return fmt.Sprintf("valid for %s (until %s)", d, format.DateTime(expiry))
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+func init() {
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authLogoutCmd.Flags().BoolVar(&authLogoutForce, "force", false, "Delete the credentials even when the token cannot be revoked")
+	authCmd.AddCommand(authLogoutCmd)
+	rootCmd.AddCommand(authCmd)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"sync"
+
+	"github.com/TonnyWong1052/aish/internal/cache"
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/history"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Maintain the response cache and history archive",
+}
+
+var cacheCompactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Compress and prune the cache and rotated history segments",
+	Long: `Drops expired and orphaned cache entries, compresses cache files and
+rotated history segments written without compression, and reports the space
+reclaimed. History trimmed to the history limit is already rotated into gzip
+segments; this only compresses segments from older versions. Compressed data is
+read transparently, so this is always safe to run.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cache.ResponseCacheConfig(loadCacheSettings())
+		var cacheStats cache.CompactStats
+		if _, err := os.Stat(cfg.CacheDir); err == nil {
+			c, err := cache.NewCache(cfg)
+			if err != nil {
+				pterm.Error.Printfln("Failed to open cache: %v", err)
+				os.Exit(1)
+			}
+			cacheStats, err = c.Compact()
+			_ = c.Close()
+			if err != nil {
+				pterm.Error.Printfln("Failed to compact cache: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		var archiveStats history.ArchiveCompactStats
+		if dir, err := history.ArchiveDir(); err == nil {
+			if archiveStats, err = history.CompactArchive(dir); err != nil {
+				pterm.Error.Printfln("Failed to compact history archive: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		pterm.Info.Printfln("Cache: %d entries removed, %d files compressed (%s → %s)",
+			cacheStats.Removed, cacheStats.Compressed, formatBytes(cacheStats.BytesBefore), formatBytes(cacheStats.BytesAfter))
+		pterm.Info.Printfln("History archive: %d segments compressed (%s → %s)",
+			archiveStats.Compressed, formatBytes(archiveStats.BytesBefore), formatBytes(archiveStats.BytesAfter))
+		reclaimed := cacheStats.BytesBefore - cacheStats.BytesAfter + archiveStats.BytesBefore - archiveStats.BytesAfter
+		pterm.Success.Printfln("Reclaimed %s.", formatBytes(reclaimed))
+	},
+}
+
+var cachePublishCmd = &cobra.Command{
+	Use:   "publish [dir]",
+	Short: "Copy your cached suggestions into a shared read-only cache",
+	Long: `Copies the unexpired entries of your cache into a system-wide cache directory
+(default ` + config.DefaultSharedCacheDir + `) that every user on the machine reads
+after a miss in their own cache. Typically run by an administrator of a lab or
+classroom machine. The directory must not be writable by group or others, or
+aish ignores it. Set ` + config.EnvAISHSharedCacheDir + ` to use another directory,
+or to "off" to disable the shared cache.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := config.DefaultSharedCacheDir
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		cfg := cache.ResponseCacheConfig(loadCacheSettings())
+		cfg.SharedDir = ""
+		c, err := cache.NewCache(cfg)
+		if err != nil {
+			pterm.Error.Printfln("Failed to open cache: %v", err)
+			os.Exit(1)
+		}
+		defer c.Close()
+		n, err := c.Publish(dir)
+		if err != nil {
+			pterm.Error.Printfln("Failed to publish to %s: %v", dir, err)
+			os.Exit(1)
+		}
+		pterm.Success.Printfln("Published %d entries to %s.", n, dir)
+	},
+}
+
+// loadCacheSettings returns the cache settings of the config, exiting when it cannot be loaded.
+func loadCacheSettings() config.CacheConfig {
+	cfg, err := config.Load()
+	if err != nil {
+		pterm.Error.Printfln("Failed to load config: %v", err)
+		os.Exit(aerrors.ExitConfig)
+	}
+	return cfg.UserPreferences.Cache
+}
+
+// responseStore is the response cache providers answer from, opened on first use.
+var (
+	responseStoreOnce sync.Once
+	responseStore     *cache.ResponseStore
+)
+
+// cachedProvider returns provider name, built from pc, answering repeated requests from the
+// response cache while cache.enabled is set. A cache that cannot be opened is left out rather
+// than failing the request.
+func cachedProvider(cfg *config.Config, name string, pc config.ProviderConfig, p llm.Provider) llm.Provider {
+	responseStoreOnce.Do(func() {
+		responseStore, _ = cache.OpenResponseStore(cfg.UserPreferences.Cache)
+	})
+	return responseStore.Wrap(p, name, pc.Model)
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheCompactCmd)
+	cacheCmd.AddCommand(cachePublishCmd)
+}
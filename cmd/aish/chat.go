@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	aishcontext "github.com/TonnyWong1052/aish/internal/context"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/shell"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var chatCmd = &cobra.Command{
+	Use:   "chat [request]",
+	Short: "Generate, refine and run commands in an interactive session",
+	Long: `Starts an interactive session for working out a command step by step. Describe
+what you want, then refine the command with follow-ups such as "only the last hour"
+or "skip hidden files": each request is sent together with the earlier ones, the
+commands generated for them and the end of what running them printed.
+
+Press Enter on an empty line to run the current command, type /edit to change it
+by hand, /reset to start a new conversation and Esc or /quit to leave.`,
+	Example: `  aish chat
+  aish chat "find large log files"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !isInteractiveTTY() {
+			pterm.Error.Println("aish chat needs a terminal; use 'aish -p' in scripts.")
+			os.Exit(aerrors.ExitGeneric)
+		}
+		cfg, providerName, provider := loadAskProvider()
+
+		var conv llm.Conversation
+		handlers := ui.ChatHandlers{
+			Generate: func(ctx context.Context, request string) (string, error) {
+				release := acquireRequestSlot(ctx, cfg)
+				defer release()
+				cmdText, answeredBy, p, err := generateCommandWithFallback(conv.Context(ctx), nil, cfg, providerName, provider, conv.Prompt(request))
+				if err != nil {
+					return "", err
+				}
+				providerName, provider = answeredBy, p
+				cmdText = strings.TrimSpace(cmdText)
+				conv.Add(request, cmdText)
+				rememberGenerated(request, cmdText)
+				return cmdText, nil
+			},
+			Run: func(command string) (bool, error) {
+				if !confirmSuggestedCommand(cfg, command) {
+					return false, nil
+				}
+				output, err := runChatCommand(command)
+				exitCode := 0
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					exitCode = exitErr.ExitCode()
+				} else if err != nil {
+					exitCode = -1
+				}
+				conv.RecordRun(command, exitCode, output)
+				return true, err
+			},
+			Reset: conv.Reset,
+		}
+		if err := ui.RunChat(handlers, strings.Join(args, " ")); err != nil {
+			pterm.Error.Println(err)
+			os.Exit(aerrors.ExitGeneric)
+		}
+	},
+}
+
+// chatOutputBytes is how much of a command's output runChatCommand keeps for the conversation.
+const chatOutputBytes = 8 << 10
+
+// runChatCommand runs command attached to the terminal like runInteractive, and also returns
+// the end of what it printed.
+func runChatCommand(command string) (string, error) {
+	if aishcontext.IsWSL() {
+		if translated := aishcontext.TranslatePathsForWSL(command); translated != command {
+			fmt.Println("Translated paths for WSL:", translated)
+			command = translated
+		}
+	}
+	tail := &tailBuffer{max: chatOutputBytes}
+	cmd := shell.Command(shell.Dialect(), command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, tail)
+	cmd.Stderr = io.MultiWriter(os.Stderr, tail)
+	err := cmd.Run()
+	return string(tail.buf), err
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+	return len(p), nil
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/janitor"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagCleanMaxAge time.Duration
+	flagCleanDryRun bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove stale capture and temporary files",
+	Long: `Removes orphaned stdout/stderr capture files written by the shell hook,
+temporary files left by interrupted writes and stale daemon sockets from the
+state directory, when they are older than the configured age
+(user_preferences.cleanup_max_age_hours, default 24). The same cleanup also runs
+opportunistically, at most once an hour, when aish starts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := config.HookStateDir()
+		if err != nil {
+			pterm.Error.Printfln("Failed to locate the state directory: %v", err)
+			os.Exit(1)
+		}
+		maxAge := flagCleanMaxAge
+		if maxAge <= 0 {
+			cfg, _ := config.Load()
+			maxAge = cleanupMaxAge(cfg)
+		}
+
+		res, err := janitor.Run(janitor.Options{StateDir: dir, MaxAge: maxAge, DryRun: flagCleanDryRun})
+		if err != nil {
+			pterm.Error.Printfln("Cleanup failed: %v", err)
+			os.Exit(1)
+		}
+		for _, path := range res.Removed {
+			if flagCleanDryRun {
+				pterm.Info.Printfln("Would remove %s", path)
+			} else {
+				pterm.Info.Printfln("Removed %s", path)
+			}
+		}
+		switch {
+		case len(res.Removed) == 0:
+			pterm.Success.Println("Nothing to clean.")
+		case flagCleanDryRun:
+			pterm.Success.Printfln("%d files (%s) would be removed.", len(res.Removed), formatBytes(res.Bytes))
+		default:
+			pterm.Success.Printfln("Removed %d files (%s).", len(res.Removed), formatBytes(res.Bytes))
+		}
+	},
+}
+
+// cleanupMaxAge returns the configured age after which stale files are removed.
+func cleanupMaxAge(cfg *config.Config) time.Duration {
+	if cfg != nil && cfg.UserPreferences.CleanupMaxAgeHours > 0 {
+		return time.Duration(cfg.UserPreferences.CleanupMaxAgeHours) * time.Hour
+	}
+	return janitor.DefaultMaxAge
+}
+
+// runStartupCleanup runs the janitor opportunistically. Failures are ignored: cleanup must
+// never get in the way of the command the user actually ran.
+func runStartupCleanup(cmd *cobra.Command) {
+	if cmd == cleanCmd {
+		return
+	}
+	dir, err := config.HookStateDir()
+	if err != nil {
+		return
+	}
+	var cfg *config.Config
+	if path, err := config.GetConfigPath(); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			cfg, _ = config.Load()
+		}
+	}
+	_, _, _ = janitor.RunIfDue(janitor.Options{StateDir: dir, MaxAge: cleanupMaxAge(cfg)}, janitor.DefaultInterval)
+}
+
+func init() {
+	cleanCmd.Flags().DurationVar(&flagCleanMaxAge, "max-age", 0, "Remove files older than this (e.g. 12h); defaults to the configured age")
+	cleanCmd.Flags().BoolVar(&flagCleanDryRun, "dry-run", false, "List what would be removed without deleting anything")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/history"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate the shell completion script",
+	Long: `Prints the completion script for the given shell. Besides commands and flags, it
+completes provider names for --provider, languages for --lang, the keys of
+'aish config get/set' and the IDs of 'aish history show'.
+
+Bash (needs the bash-completion package):
+  source <(aish completion bash)
+  # or, for every new shell:
+  aish completion bash > ~/.local/share/bash-completion/completions/aish
+
+Zsh:
+  aish completion zsh > "${fpath[1]}/_aish"   # then start a new shell
+
+Fish:
+  aish completion fish > ~/.config/fish/completions/aish.fish
+
+PowerShell:
+  aish completion powershell | Out-String | Invoke-Expression
+  # add that line to $PROFILE to load it in every session`,
+	Example: `  source <(aish completion bash)
+  aish completion fish > ~/.config/fish/completions/aish.fish`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		default:
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+	},
+}
+
+// isCompletionCommand reports whether cmd prints a completion script or answers a completion
+// request from the shell, whose output must not be mixed with notices and prompts.
+func isCompletionCommand(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case completionCmd.Name(), cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return false
+}
+
+func completeProviders(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return config.GetSupportedProviders(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeLanguages(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	langs := make([]string, 0, len(config.SupportedLanguages))
+	for _, code := range config.SupportedLanguages {
+		langs = append(langs, code+"\t"+prompt.LanguageName(code))
+	}
+	return langs, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigKey completes the key of 'aish config get/set' from known. Each provider is one
+// "providers.<name>." entry, completed without a trailing space when it is all that matches;
+// once it is typed, the fields of that provider are listed.
+func completeConfigKey(toComplete string, known []string) ([]string, cobra.ShellCompDirective) {
+	prefix := strings.ToLower(toComplete)
+	var keys []string
+	if rest, ok := strings.CutPrefix(prefix, "providers."); ok && strings.Contains(rest, ".") {
+		name, _, _ := strings.Cut(rest, ".")
+		for _, field := range providerFields {
+			keys = append(keys, "providers."+name+"."+field)
+		}
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	onlyProviders := true
+	for _, key := range known {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+			onlyProviders = false
+		}
+	}
+	for _, name := range config.GetSupportedProviders() {
+		if key := "providers." + name + "."; strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	if onlyProviders && len(keys) > 0 {
+		return keys, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigValue offers values for the keys that take one of a few: provider names,
+// languages and on/off switches.
+func completeConfigValue(key string) ([]string, cobra.ShellCompDirective) {
+	key = canonicalConfigKey(key)
+	switch key {
+	case "default_provider", "fallback_provider", "consensus_provider", "routing.capture_provider", "routing.ask_provider":
+		return config.GetSupportedProviders(), cobra.ShellCompDirectiveNoFileComp
+	case "language":
+		langs, directive := completeLanguages(nil, nil, "")
+		return append([]string{"auto\tFollow the system locale"}, langs...), directive
+	case "dangerous_commands":
+		return []string{config.DangerousCommandsConfirm, config.DangerousCommandsBlock}, cobra.ShellCompDirectiveNoFileComp
+	case "auto_execute", "warmup", "allow_complex_commands", "command_preview", "fix_credential_permissions",
+		"content_filter_retry", "cache.enabled", "cache.enable_similarity", "ui.animations",
+		"updates.check", "telemetry.enabled", "screen_reader", "context.filter_sensitive_cmd", "context.enable_enhanced":
+		return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(key, "providers.") && strings.HasSuffix(key, ".gateway_compat") {
+		return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	if strings.HasPrefix(key, "providers.") && strings.HasSuffix(key, ".api_key_file") {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeHistoryIDs lists the IDs of the stored history entries, newest first, each
+// described by its command.
+func completeHistoryIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	h, err := history.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	ids := make([]string, 0, len(h.Entries))
+	for _, entry := range h.Entries {
+		if entry.ID == 0 {
+			continue
+		}
+		ids = append(ids, fmt.Sprintf("%d\t%s", entry.ID, truncateCompletion(entry.Command)))
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// truncateCompletion keeps a completion description on one short line.
+func truncateCompletion(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 60 {
+		return string(r[:59]) + "…"
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+	configGetCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeConfigKey(toComplete, slices.Concat(configKeys, configGetOnlyKeys))
+	}
+	configSetCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return completeConfigKey(toComplete, configKeys)
+		case 1:
+			return completeConfigValue(args[0])
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	historyShowCmd.ValidArgsFunction = completeHistoryIDs
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+// configSwitchKeys returns the keys the first 'switch lower' in the Run function of the
+// command declared as varName in config.go handles.
+func configSwitchKeys(t *testing.T, varName string) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse config.go: %v", err)
+	}
+	var run *ast.FuncLit
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != varName || len(spec.Values) != 1 {
+			return true
+		}
+		addr, ok := spec.Values[0].(*ast.UnaryExpr)
+		if !ok {
+			return false
+		}
+		lit, ok := addr.X.(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok && isIdent(kv.Key, "Run") {
+				run, _ = kv.Value.(*ast.FuncLit)
+			}
+		}
+		return false
+	})
+	if run == nil {
+		t.Fatalf("no Run function for %s in config.go", varName)
+	}
+
+	var sw *ast.SwitchStmt
+	ast.Inspect(run.Body, func(n ast.Node) bool {
+		if s, ok := n.(*ast.SwitchStmt); ok && sw == nil {
+			if isIdent(s.Tag, "lower") {
+				sw = s
+				return false
+			}
+		}
+		return sw == nil
+	})
+	if sw == nil {
+		t.Fatalf("no switch on lower in %s", varName)
+	}
+
+	var keys []string
+	for _, stmt := range sw.Body.List {
+		for _, expr := range stmt.(*ast.CaseClause).List {
+			lit, ok := expr.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				continue
+			}
+			key, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatalf("unquote %s: %v", lit.Value, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && id.Name == name
+}
+
+func TestConfigKeysMatchGetAndSet(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want []string
+	}{
+		{"configGetCmd", slices.Concat(configKeys, configGetOnlyKeys)},
+		{"configSetCmd", configKeys},
+	}
+	for _, tt := range tests {
+		got := configSwitchKeys(t, tt.cmd)
+		for _, key := range got {
+			if !slices.Contains(tt.want, key) {
+				t.Errorf("%s handles %q, which configKeys does not list", tt.cmd, key)
+			}
+		}
+		for _, key := range tt.want {
+			if !slices.Contains(got, key) {
+				t.Errorf("configKeys lists %q, which %s does not handle", key, tt.cmd)
+			}
+		}
+	}
+}
+
+func TestCanonicalConfigKey(t *testing.T) {
+	tests := map[string]string{
+		"Language":                          "language",
+		"user_preferences.cache.enabled":    "cache.enabled",
+		"auto-execute":                      "auto_execute",
+		"user_preferences.animations":       "ui.animations",
+		"accessibility.screen_reader":       "screen_reader",
+		" providers.openai.model ":          "providers.openai.model",
+		"user_preferences.ui.keys.navigate": "ui.keys.navigate",
+	}
+	for key, want := range tests {
+		if got := canonicalConfigKey(key); got != want {
+			t.Errorf("canonicalConfigKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestCompleteLanguages(t *testing.T) {
+	got, _ := completeLanguages(nil, nil, "")
+	if len(got) != len(config.SupportedLanguages) {
+		t.Fatalf("completeLanguages() = %v, want one entry per config.SupportedLanguages", got)
+	}
+	for i, entry := range got {
+		code, name, _ := strings.Cut(entry, "\t")
+		if code != config.SupportedLanguages[i] {
+			t.Errorf("entry %d = %q, want %q", i, code, config.SupportedLanguages[i])
+		}
+		if name == "" || name != prompt.LanguageName(code) {
+			t.Errorf("entry %q has description %q, want the language name", code, name)
+		}
+	}
+}
+
+func TestCompleteConfigKey(t *testing.T) {
+	got, _ := completeConfigKey("cache.", configKeys)
+	want := []string{"cache.enabled", "cache.max_entries", "cache.suggestion_ttl_hours",
+		"cache.command_ttl_hours", "cache.enable_similarity", "cache.similarity_threshold"}
+	if !slices.Equal(got, want) {
+		t.Errorf("completeConfigKey(cache.) = %v, want %v", got, want)
+	}
+
+	got, _ = completeConfigKey("providers.openai.", configKeys)
+	if len(got) != len(providerFields) || got[0] != "providers.openai."+providerFields[0] {
+		t.Errorf("completeConfigKey(providers.openai.) = %v, want the provider fields", got)
+	}
+}
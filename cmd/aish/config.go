@@ -5,10 +5,16 @@ import (
 	"context"
 	"fmt"
 	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm"
 	"github.com/TonnyWong1052/aish/internal/llm/openai"
 	"github.com/TonnyWong1052/aish/internal/prompt"
 	"github.com/TonnyWong1052/aish/internal/ui"
+	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,7 +38,7 @@ var configShowCmd = &cobra.Command{
 		cfg, err := config.Load()
 		if err != nil {
 			pterm.Error.Printfln("Failed to load config: %v", err)
-			os.Exit(1)
+			os.Exit(aerrors.ExitConfig)
 		}
 
 		pterm.DefaultSection.Println("Current Configuration")
@@ -45,11 +51,45 @@ var configShowCmd = &cobra.Command{
 
 		items := []pterm.BulletListItem{
 			{Level: 0, Text: fmt.Sprintf("Default Provider: %s", cfg.DefaultProvider)},
-			{Level: 1, Text: fmt.Sprintf("API Host: %s", providerCfg.APIEndpoint)},
+			{Level: 1, Text: fmt.Sprintf("API Host: %s", scrubForDemo(providerCfg.APIEndpoint))},
 			{Level: 1, Text: fmt.Sprintf("Model: %s", providerCfg.Model)},
 		}
 		if cfg.DefaultProvider == "gemini-cli" {
-			items = append(items, pterm.BulletListItem{Level: 1, Text: fmt.Sprintf("Project: %s", revealOrNull(providerCfg.Project))})
+			items = append(items, pterm.BulletListItem{Level: 1, Text: fmt.Sprintf("Project: %s", revealOrNull(scrubForDemo(providerCfg.Project)))})
+		}
+		if providerCfg.APIKeyFile != "" {
+			items = append(items, pterm.BulletListItem{Level: 1, Text: fmt.Sprintf("API Key File: %s", scrubForDemo(providerCfg.APIKeyFile))})
+		}
+		if len(providerCfg.ExtraHeaders) > 0 {
+			items = append(items, pterm.BulletListItem{Level: 1, Text: fmt.Sprintf("Extra Headers: %s", strings.Join(headerNames(providerCfg.ExtraHeaders), ", "))})
+		}
+		if cfg.DefaultProvider == config.ProviderVertex {
+			items = append(items, pterm.BulletListItem{Level: 1, Text: fmt.Sprintf("Project: %s", revealOrNull(scrubForDemo(providerCfg.Project)))})
+			items = append(items, pterm.BulletListItem{Level: 1, Text: fmt.Sprintf("Location: %s", revealOrNull(providerCfg.Location))})
+			credentials := "Application Default Credentials"
+			if providerCfg.CredentialsFile != "" {
+				credentials = scrubForDemo(providerCfg.CredentialsFile)
+			}
+			items = append(items, pterm.BulletListItem{Level: 1, Text: "Credentials: " + credentials})
+		}
+		if providerCfg.GatewayCompat {
+			items = append(items, pterm.BulletListItem{Level: 1, Text: "Gateway Compatibility: on"})
+		}
+		if cfg.UserPreferences.Proxy != "" {
+			items = append(items, pterm.BulletListItem{Level: 0, Text: fmt.Sprintf("Proxy: %s", redactProxy(cfg.UserPreferences.Proxy))})
+		}
+		for _, flow := range []string{config.FlowCapture, config.FlowAsk} {
+			provider, model := cfg.Route(flow)
+			if provider == cfg.DefaultProvider && model == "" {
+				continue
+			}
+			if model == "" {
+				model = cfg.Providers[provider].Model
+			}
+			items = append(items, pterm.BulletListItem{Level: 0, Text: fmt.Sprintf("Routing (%s): %s, %s", flow, provider, model)})
+		}
+		if ui.IsDemoMode() {
+			items = append(items, pterm.BulletListItem{Level: 0, Text: "Demo mode: active (mock provider; endpoints, keys and projects hidden)"})
 		}
 		_ = pterm.DefaultBulletList.WithItems(items).Render()
 	},
@@ -64,24 +104,181 @@ var configGetCmd = &cobra.Command{
 		cfg, err := config.Load()
 		if err != nil {
 			pterm.Error.Printfln("Failed to load config: %v", err)
-			os.Exit(1)
+			os.Exit(aerrors.ExitConfig)
 		}
-		lower := strings.ToLower(key)
+		lower := canonicalConfigKey(key)
 		switch lower {
 		case "default_provider":
 			fmt.Println(cfg.DefaultProvider)
 			return
-		case "user_preferences.language", "language":
-			fmt.Println(cfg.UserPreferences.Language)
+		case "language":
+			fmt.Println(cfg.UserPreferences.EffectiveLanguage())
+			return
+		case "language_fallback":
+			fmt.Println(strings.Join(cfg.UserPreferences.LanguageFallback, ","))
+			return
+		case "locale":
+			fmt.Println(cfg.UserPreferences.EffectiveLocale())
+			return
+		case "timezone":
+			fmt.Println(cfg.UserPreferences.TimeLocation())
 			return
-	case "auto_execute", "auto-execute", "user_preferences.auto_execute":
+		case "auto_execute":
 			if cfg.UserPreferences.AutoExecute {
 				fmt.Println("true")
 			} else {
 				fmt.Println("false")
 			}
 			return
-		case "user_preferences.enabled_llm_triggers", "enabled_llm_triggers":
+		case "warmup":
+			if cfg.UserPreferences.Warmup {
+				fmt.Println("true")
+			} else {
+				fmt.Println("false")
+			}
+			return
+		case "cleanup_max_age_hours":
+			fmt.Println(int(cleanupMaxAge(cfg).Hours()))
+			return
+		case "fallback_provider":
+			fmt.Println(cfg.UserPreferences.FallbackProvider)
+			return
+		case "fallback_providers":
+			fmt.Println(strings.Join(cfg.UserPreferences.FallbackProviders, ","))
+			return
+		case "consensus_provider":
+			fmt.Println(cfg.UserPreferences.ConsensusProvider)
+			return
+		case "routing.capture_provider":
+			fmt.Println(cfg.Routing.CaptureProvider)
+			return
+		case "routing.capture_model":
+			fmt.Println(cfg.Routing.CaptureModel)
+			return
+		case "routing.ask_provider":
+			fmt.Println(cfg.Routing.AskProvider)
+			return
+		case "routing.ask_model":
+			fmt.Println(cfg.Routing.AskModel)
+			return
+		case "allow_complex_commands":
+			fmt.Println(cfg.UserPreferences.AllowComplexCommands)
+			return
+		case "dangerous_commands":
+			fmt.Println(cfg.UserPreferences.DangerousCommandPolicy())
+			return
+		case "command_preview":
+			fmt.Println(cfg.UserPreferences.CommandPreview)
+			return
+		case "fix_credential_permissions":
+			fmt.Println(cfg.UserPreferences.FixCredentialPermissions)
+			return
+		case "command_limits.max_length", "command_limits.max_pipes", "command_limits.max_redirects":
+			limits := cfg.UserPreferences.CommandLimits.Effective()
+			n := limits.MaxRedirects
+			switch lower[strings.LastIndex(lower, ".")+1:] {
+			case "max_length":
+				n = limits.MaxLength
+			case "max_pipes":
+				n = limits.MaxPipes
+			}
+			if n < 0 {
+				fmt.Println("unlimited")
+			} else {
+				fmt.Println(n)
+			}
+			return
+		case "slow_provider_seconds":
+			fmt.Println(int(slowProviderAfter(cfg).Seconds()))
+			return
+		case "rate_limit_wait_seconds":
+			fmt.Println(cfg.UserPreferences.RateLimitWaitSeconds)
+			return
+		case "content_filter_retry":
+			fmt.Println(cfg.UserPreferences.ContentFilterRetry)
+			return
+		case "cache.enabled":
+			fmt.Println(cfg.UserPreferences.Cache.Enabled)
+			return
+		case "cache.max_entries":
+			fmt.Println(cfg.UserPreferences.Cache.MaxEntries)
+			return
+		case "cache.suggestion_ttl_hours":
+			fmt.Println(cfg.UserPreferences.Cache.SuggestionTTLHours)
+			return
+		case "cache.command_ttl_hours":
+			fmt.Println(cfg.UserPreferences.Cache.CommandTTLHours)
+			return
+		case "cache.enable_similarity":
+			fmt.Println(cfg.UserPreferences.Cache.EnableSimilarity)
+			return
+		case "cache.similarity_threshold":
+			fmt.Println(cfg.UserPreferences.Cache.SimilarityThreshold)
+			return
+		case "context.max_history_entries":
+			fmt.Println(cfg.UserPreferences.Context.MaxHistoryEntries)
+			return
+		case "context.filter_sensitive_cmd":
+			fmt.Println(cfg.UserPreferences.Context.FilterSensitiveCmd)
+			return
+		case "context.enable_enhanced":
+			fmt.Println(cfg.UserPreferences.Context.EnableEnhanced)
+			return
+		case "proxy":
+			fmt.Println(revealOrNull(redactProxy(cfg.UserPreferences.Proxy)))
+			return
+		case "max_concurrent_requests":
+			switch n := cfg.UserPreferences.MaxConcurrentRequests; {
+			case n == 0:
+				fmt.Println(llm.DefaultMaxConcurrentRequests)
+			case n < 0:
+				fmt.Println("unlimited")
+			default:
+				fmt.Println(n)
+			}
+			return
+		case "ui.animations":
+			if cfg.UserPreferences.UI.AnimationsEnabled() {
+				fmt.Println("true")
+			} else {
+				fmt.Println("false")
+			}
+			return
+		case "ui.output_template":
+			fmt.Println(cfg.UserPreferences.UI.OutputTemplate)
+			return
+		case "ui.key_bindings":
+			if cfg.UserPreferences.UI.KeyBindings == "" {
+				fmt.Println(ui.KeyStyleDefault)
+			} else {
+				fmt.Println(cfg.UserPreferences.UI.KeyBindings)
+			}
+			return
+		case "ui.keys":
+			fmt.Println(ui.FormatKeyOverrides(cfg.UserPreferences.UI.Keys))
+			return
+		case "updates.check":
+			if cfg.UserPreferences.Updates.CheckEnabled() {
+				fmt.Println("true")
+			} else {
+				fmt.Println("false")
+			}
+			return
+		case "telemetry.enabled":
+			if cfg.UserPreferences.Telemetry.Enabled {
+				fmt.Println("true")
+			} else {
+				fmt.Println("false")
+			}
+			return
+		case "screen_reader":
+			if cfg.UserPreferences.Accessibility.ScreenReader {
+				fmt.Println("true")
+			} else {
+				fmt.Println("false")
+			}
+			return
+		case "enabled_llm_triggers":
 			if len(cfg.UserPreferences.EnabledLLMTriggers) == 0 {
 				fmt.Println("")
 			} else {
@@ -89,10 +286,14 @@ var configGetCmd = &cobra.Command{
 			}
 			return
 		}
+		if action, ok := keyActionFromKey(lower); ok {
+			fmt.Println(strings.Join(cfg.UserPreferences.UI.Keys[action], ","))
+			return
+		}
 		if strings.HasPrefix(lower, "providers.") {
 			parts := strings.Split(lower, ".")
 			if len(parts) != 3 {
-				pterm.Error.Println("Use providers.<name>.<field>, fields: api_endpoint|model|api_key|project")
+				pterm.Error.Println("Use providers.<name>.<field>, fields: " + strings.Join(providerFields, "|"))
 				os.Exit(1)
 			}
 			name := parts[1]
@@ -104,20 +305,43 @@ var configGetCmd = &cobra.Command{
 			}
 			switch field {
 			case "api_endpoint":
-				fmt.Println(pc.APIEndpoint)
+				fmt.Println(scrubForDemo(pc.APIEndpoint))
 			case "model":
 				fmt.Println(pc.Model)
 			case "api_key":
-				fmt.Println(maskIfSet(pc.APIKey))
+				fmt.Println(scrubForDemo(maskIfSet(pc.APIKey)))
+			case "api_key_file":
+				fmt.Println(revealOrNull(scrubForDemo(pc.APIKeyFile)))
 			case "project":
-				fmt.Println(revealOrNull(pc.Project))
+				fmt.Println(revealOrNull(scrubForDemo(pc.Project)))
+			case "context_window":
+				fmt.Println(pc.ContextWindow)
+			case "max_output_tokens":
+				fmt.Println(pc.MaxOutputTokens)
+			case "reasoning_effort":
+				fmt.Println(pc.ReasoningEffort)
+			case "transport":
+				fmt.Println(pc.Transport)
+			case "azure_deployment":
+				fmt.Println(pc.AzureDeployment)
+			case "azure_api_version":
+				fmt.Println(pc.AzureAPIVersion)
+			case "extra_headers":
+				fmt.Println(formatHeaders(pc.ExtraHeaders))
+			case "gateway_compat":
+				fmt.Println(pc.GatewayCompat)
+			case "location":
+				fmt.Println(revealOrNull(pc.Location))
+			case "credentials_file":
+				fmt.Println(revealOrNull(scrubForDemo(pc.CredentialsFile)))
 			default:
-				pterm.Error.Println("Unknown field. Use one of: api_endpoint|model|api_key|project")
+				pterm.Error.Println("Unknown field. Use one of: " + strings.Join(providerFields, "|"))
 				os.Exit(1)
 			}
 			return
 		}
 		pterm.Error.Printfln("Unsupported key: %s", key)
+		pterm.Info.Printfln("Supported keys: %s, providers.<name>.<field>", strings.Join(configKeys, ", "))
 		os.Exit(1)
 	},
 }
@@ -132,9 +356,9 @@ var configSetCmd = &cobra.Command{
 		cfg, err := config.Load()
 		if err != nil {
 			pterm.Error.Printfln("Failed to load config: %v", err)
-			os.Exit(1)
+			os.Exit(aerrors.ExitConfig)
 		}
-		lower := strings.ToLower(key)
+		lower := canonicalConfigKey(key)
 		switch lower {
 		case "default_provider":
 			if !config.IsValidProvider(value) {
@@ -142,38 +366,280 @@ var configSetCmd = &cobra.Command{
 				pterm.Info.Printfln("Supported providers: %v", config.GetSupportedProviders())
 				os.Exit(1)
 			}
-			// Ensure provider config exists
-			if _, ok := cfg.Providers[value]; !ok {
-				// Add default config for new provider
-				switch value {
-				case config.ProviderClaude:
-					cfg.Providers[value] = config.ProviderConfig{
-						APIEndpoint: config.ClaudeAPIEndpoint,
-						APIKey:      "",
-						Model:       config.DefaultClaudeModel,
-					}
-				case config.ProviderOllama:
-					cfg.Providers[value] = config.ProviderConfig{
-						APIEndpoint: config.OllamaAPIEndpoint,
-						APIKey:      "",
-						Model:       config.DefaultOllamaModel,
-					}
-				}
+			_ = cfg.UseProvider(value, "")
+		case "language":
+			if strings.EqualFold(value, "auto") {
+				value = "" // Follow the system locale
 			}
-			cfg.DefaultProvider = value
-		case "user_preferences.language", "language":
 			cfg.UserPreferences.Language = value
-		case "auto_execute", "auto-execute", "user_preferences.auto_execute":
-			switch strings.ToLower(value) {
-			case "true", "1", "yes", "on", "enable", "enabled":
-				cfg.UserPreferences.AutoExecute = true
-			case "false", "0", "no", "off", "disable", "disabled":
-				cfg.UserPreferences.AutoExecute = false
-			default:
+		case "language_fallback":
+			var chain []string
+			if !strings.EqualFold(value, "auto") { // auto: the other Chinese script, then English
+				chain = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+			}
+			cfg.UserPreferences.LanguageFallback = chain
+		case "locale":
+			if strings.EqualFold(value, "auto") {
+				value = "" // Follow LC_ALL/LC_TIME/LANG
+			}
+			cfg.UserPreferences.Locale = value
+		case "timezone":
+			if strings.EqualFold(value, "auto") || strings.EqualFold(value, "local") {
+				value = "" // Follow the system time zone
+			} else if _, err := time.LoadLocation(value); err != nil {
+				pterm.Error.Printfln("Invalid value for timezone: %s. Use an IANA name such as Europe/Berlin, or auto", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Timezone = value
+		case "auto_execute":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
 				pterm.Error.Printfln("Invalid value for auto_execute: %s. Use: true/false, 1/0, yes/no, on/off", value)
 				os.Exit(1)
 			}
-		case "user_preferences.enabled_llm_triggers", "enabled_llm_triggers":
+			cfg.UserPreferences.AutoExecute = enabled
+		case "warmup":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for warmup: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Warmup = enabled
+		case "cleanup_max_age_hours":
+			hours, err := strconv.Atoi(value)
+			if err != nil || hours <= 0 {
+				pterm.Error.Printfln("Invalid value for cleanup_max_age_hours: %s. Use a positive number of hours", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.CleanupMaxAgeHours = hours
+		case "fallback_provider":
+			if value != "" && !config.IsValidProvider(value) {
+				pterm.Error.Printfln("Invalid provider: %s. Supported: %s", value, strings.Join(config.GetSupportedProviders(), ", "))
+				os.Exit(1)
+			}
+			cfg.UserPreferences.FallbackProvider = value
+		case "fallback_providers":
+			chain := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+			for _, name := range chain {
+				if !config.IsValidProvider(name) {
+					pterm.Error.Printfln("Invalid provider: %s. Supported: %s", name, strings.Join(config.GetSupportedProviders(), ", "))
+					os.Exit(1)
+				}
+			}
+			cfg.UserPreferences.FallbackProviders = chain
+		case "consensus_provider":
+			if value != "" && !config.IsValidProvider(value) {
+				pterm.Error.Printfln("Invalid provider: %s. Supported: %s", value, strings.Join(config.GetSupportedProviders(), ", "))
+				os.Exit(1)
+			}
+			cfg.UserPreferences.ConsensusProvider = value
+		case "routing.capture_provider", "routing.ask_provider":
+			if value != "" && !config.IsValidProvider(value) {
+				pterm.Error.Printfln("Invalid provider: %s. Supported: %s", value, strings.Join(config.GetSupportedProviders(), ", "))
+				os.Exit(1)
+			}
+			if lower == "routing.capture_provider" {
+				cfg.Routing.CaptureProvider = value
+			} else {
+				cfg.Routing.AskProvider = value
+			}
+		case "routing.capture_model":
+			cfg.Routing.CaptureModel = strings.TrimSpace(value)
+		case "routing.ask_model":
+			cfg.Routing.AskModel = strings.TrimSpace(value)
+		case "allow_complex_commands":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for allow_complex_commands: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.AllowComplexCommands = enabled
+		case "dangerous_commands":
+			switch policy := strings.ToLower(strings.TrimSpace(value)); policy {
+			case "", config.DangerousCommandsConfirm, config.DangerousCommandsBlock:
+				cfg.UserPreferences.DangerousCommands = policy
+			default:
+				pterm.Error.Printfln("Invalid value for dangerous_commands: %s. Use: confirm or block", value)
+				os.Exit(1)
+			}
+		case "command_preview":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for command_preview: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.CommandPreview = enabled
+		case "fix_credential_permissions":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for fix_credential_permissions: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.FixCredentialPermissions = enabled
+		case "command_limits.max_length", "command_limits.max_pipes", "command_limits.max_redirects":
+			n, err := strconv.Atoi(value)
+			if strings.EqualFold(value, "unlimited") {
+				n, err = -1, nil
+			}
+			if err != nil || n == 0 || n < -1 {
+				pterm.Error.Printfln("Invalid value for %s: %s. Use a positive number, or 'unlimited'", key, value)
+				os.Exit(1)
+			}
+			limits := &cfg.UserPreferences.CommandLimits
+			switch lower[strings.LastIndex(lower, ".")+1:] {
+			case "max_length":
+				limits.MaxLength = n
+			case "max_pipes":
+				limits.MaxPipes = n
+			default:
+				limits.MaxRedirects = n
+			}
+		case "slow_provider_seconds":
+			secs, err := strconv.Atoi(value)
+			if err != nil || secs <= 0 {
+				pterm.Error.Printfln("Invalid value for slow_provider_seconds: %s. Use a positive number of seconds", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.SlowProviderSeconds = secs
+		case "rate_limit_wait_seconds":
+			secs, err := strconv.Atoi(value)
+			if err != nil || secs < 0 {
+				pterm.Error.Printfln("Invalid value for rate_limit_wait_seconds: %s. Use a number of seconds, or 0 to not wait", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.RateLimitWaitSeconds = secs
+		case "content_filter_retry":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for content_filter_retry: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.ContentFilterRetry = enabled
+		case "cache.enabled":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for cache.enabled: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Cache.Enabled = enabled
+		case "cache.max_entries":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || n > 10000 {
+				pterm.Error.Printfln("Invalid value for cache.max_entries: %s. Use a number from 1 to 10000", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Cache.MaxEntries = n
+		case "cache.suggestion_ttl_hours", "cache.command_ttl_hours":
+			name, limit := "cache.suggestion_ttl_hours", 72
+			if strings.HasSuffix(key, "command_ttl_hours") {
+				name, limit = "cache.command_ttl_hours", 168
+			}
+			hours, err := strconv.Atoi(value)
+			if err != nil || hours <= 0 || hours > limit {
+				pterm.Error.Printfln("Invalid value for %s: %s. Use a number of hours from 1 to %d", name, value, limit)
+				os.Exit(1)
+			}
+			if name == "cache.command_ttl_hours" {
+				cfg.UserPreferences.Cache.CommandTTLHours = hours
+			} else {
+				cfg.UserPreferences.Cache.SuggestionTTLHours = hours
+			}
+		case "cache.enable_similarity":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for cache.enable_similarity: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Cache.EnableSimilarity = enabled
+		case "cache.similarity_threshold":
+			threshold, err := strconv.ParseFloat(value, 64)
+			if err != nil || threshold <= 0 || threshold > 1 {
+				pterm.Error.Printfln("Invalid value for cache.similarity_threshold: %s. Use a number above 0 and up to 1 (1 = only requests with the same words)", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Cache.SimilarityThreshold = threshold
+		case "context.max_history_entries":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || n > 100 {
+				pterm.Error.Printfln("Invalid value for context.max_history_entries: %s. Use a number from 1 to 100, or turn history off with context.enable_enhanced false", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Context.MaxHistoryEntries = n
+		case "context.filter_sensitive_cmd":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for context.filter_sensitive_cmd: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Context.FilterSensitiveCmd = enabled
+		case "context.enable_enhanced":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for context.enable_enhanced: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Context.EnableEnhanced = enabled
+		case "proxy":
+			value = strings.TrimSpace(value)
+			if value != "" {
+				if err := llm.ValidateProxy(value); err != nil {
+					pterm.Error.Printfln("Invalid value for proxy: %v", err)
+					os.Exit(1)
+				}
+			}
+			cfg.UserPreferences.Proxy = value
+		case "max_concurrent_requests":
+			n, err := strconv.Atoi(value)
+			if strings.EqualFold(value, "unlimited") {
+				n, err = -1, nil
+			}
+			if err != nil || n == 0 || n < -1 {
+				pterm.Error.Printfln("Invalid value for max_concurrent_requests: %s. Use a positive number, or 'unlimited'", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.MaxConcurrentRequests = n
+		case "ui.animations":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for ui.animations: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.UI.Animations = &enabled
+		case "ui.output_template":
+			if _, err := ui.ParseOutputTemplate(value); err != nil {
+				pterm.Error.Printfln("Invalid value for ui.output_template: %v", err)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.UI.OutputTemplate = value
+		case "ui.key_bindings":
+			style := strings.ToLower(value)
+			if _, err := ui.NewKeyMap(style, cfg.UserPreferences.UI.Keys); err != nil {
+				pterm.Error.Printfln("Invalid value for ui.key_bindings: %v", err)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.UI.KeyBindings = style
+		case "updates.check":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for updates.check: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Updates.Check = &enabled
+		case "telemetry.enabled":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for telemetry.enabled: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Telemetry.Enabled = enabled
+		case "screen_reader":
+			enabled, ok := parseBoolValue(value)
+			if !ok {
+				pterm.Error.Printfln("Invalid value for screen_reader: %s. Use: true/false, 1/0, yes/no, on/off", value)
+				os.Exit(1)
+			}
+			cfg.UserPreferences.Accessibility.ScreenReader = enabled
+		case "enabled_llm_triggers":
 			// 逗號分隔清單；允許空字串代表清空
 			var list []string
 			for _, part := range strings.Split(value, ",") {
@@ -184,10 +650,12 @@ var configSetCmd = &cobra.Command{
 			}
 			cfg.UserPreferences.EnabledLLMTriggers = list
 		default:
-			if strings.HasPrefix(lower, "providers.") {
+			if action, ok := keyActionFromKey(lower); ok {
+				setKeyOverride(cfg, action, value)
+			} else if strings.HasPrefix(lower, "providers.") {
 				parts := strings.Split(lower, ".")
 				if len(parts) != 3 {
-					pterm.Error.Println("Use providers.<name>.<field>, fields: api_endpoint|model|api_key|project")
+					pterm.Error.Println("Use providers.<name>.<field>, fields: " + strings.Join(providerFields, "|"))
 					os.Exit(1)
 				}
 				name := parts[1]
@@ -200,21 +668,93 @@ var configSetCmd = &cobra.Command{
 					pc.Model = value
 				case "api_key":
 					pc.APIKey = value
+				case "api_key_file":
+					if name != config.ProviderOpenAI {
+						pterm.Error.Println("api_key_file is only supported for the openai provider (OpenAI-compatible and Azure endpoints)")
+						os.Exit(1)
+					}
+					pc.APIKeyFile = strings.TrimSpace(value)
 				case "project":
 					pc.Project = value
+				case "context_window", "max_output_tokens":
+					n, err := strconv.Atoi(value)
+					if err != nil || n < 0 {
+						pterm.Error.Printfln("Invalid value for %s: %s. Use a non-negative integer (0 = model default)", field, value)
+						os.Exit(1)
+					}
+					if field == "context_window" {
+						pc.ContextWindow = n
+					} else {
+						pc.MaxOutputTokens = n
+					}
+				case "reasoning_effort":
+					switch strings.ToLower(value) {
+					case "", "low", "medium", "high":
+						pc.ReasoningEffort = strings.ToLower(value)
+					default:
+						pterm.Error.Printfln("Invalid value for reasoning_effort: %s. Use: low, medium or high", value)
+						os.Exit(1)
+					}
+				case "transport":
+					switch t := strings.ToLower(value); t {
+					case "", config.GeminiTransportSDK, config.GeminiTransportHTTP, config.GeminiTransportCURL:
+						pc.Transport = t
+					default:
+						pterm.Error.Printfln("Invalid value for transport: %s. Use: sdk, http or curl", value)
+						os.Exit(1)
+					}
+				case "azure_deployment":
+					pc.AzureDeployment = strings.TrimSpace(value)
+				case "azure_api_version":
+					pc.AzureAPIVersion = strings.TrimSpace(value)
+				case "extra_headers":
+					headers, err := parseHeaders(value)
+					if err != nil {
+						pterm.Error.Printfln("Invalid value for extra_headers: %v. Use comma-separated Name=value pairs, or \"\" to clear", err)
+						os.Exit(1)
+					}
+					pc.ExtraHeaders = headers
+				case "gateway_compat":
+					if name != config.ProviderOpenAI {
+						pterm.Error.Println("gateway_compat is only supported for the openai provider (OpenAI-compatible endpoints)")
+						os.Exit(1)
+					}
+					enabled, ok := parseBoolValue(value)
+					if !ok {
+						pterm.Error.Printfln("Invalid value for gateway_compat: %s. Use: true/false, 1/0, yes/no, on/off", value)
+						os.Exit(1)
+					}
+					pc.GatewayCompat = enabled
+				case "location", "credentials_file":
+					if name != config.ProviderVertex {
+						pterm.Error.Printfln("%s is only supported for the vertex provider", field)
+						os.Exit(1)
+					}
+					if field == "location" {
+						pc.Location = strings.TrimSpace(value)
+					} else {
+						pc.CredentialsFile = strings.TrimSpace(value)
+					}
 				default:
-					pterm.Error.Println("Unknown field. Use one of: api_endpoint|model|api_key|project")
+					pterm.Error.Println("Unknown field. Use one of: " + strings.Join(providerFields, "|"))
 					os.Exit(1)
 				}
 				cfg.Providers[name] = pc
+				// A changed provider deserves a fresh attempt from the shell hook
+				if healthPath, err := llm.HealthStatePath(); err == nil {
+					if health := llm.LoadHealthState(healthPath); health.Forget(name) {
+						_ = health.Save()
+					}
+				}
 			} else {
 				pterm.Error.Printfln("Unsupported key: %s", key)
+				pterm.Info.Printfln("Supported keys: %s, providers.<name>.<field>", strings.Join(configKeys, ", "))
 				os.Exit(1)
 			}
 		}
 		if err := cfg.Save(); err != nil {
 			pterm.Error.Printfln("Failed to save config: %v", err)
-			os.Exit(1)
+			os.Exit(aerrors.ExitConfig)
 		}
 		pterm.Success.Println("Updated.")
 	},
@@ -243,7 +783,7 @@ func runConfigureLogic(cmd *cobra.Command, args []string) {
 		)
 		userErr.Cause = err
 		errorHandler.HandleError(userErr)
-		os.Exit(1)
+		os.Exit(userErr.ExitCode())
 	}
 	if cfg.Providers == nil {
 		cfg.Providers = make(map[string]config.ProviderConfig)
@@ -269,6 +809,72 @@ func runConfigureLogic(cmd *cobra.Command, args []string) {
 	}
 }
 
+// parseBoolValue accepts the boolean spellings used by 'aish config set'
+func parseBoolValue(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes", "on", "enable", "enabled":
+		return true, true
+	case "false", "0", "no", "off", "disable", "disabled":
+		return false, true
+	}
+	return false, false
+}
+
+// parseHeaders parses the value of providers.<name>.extra_headers: comma-separated Name=value
+// (or Name: value) pairs. An empty value clears the headers.
+func parseHeaders(value string) (map[string]string, error) {
+	var headers map[string]string
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.IndexAny(pair, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not Name=value", pair)
+		}
+		name := http.CanonicalHeaderKey(strings.TrimSpace(pair[:i]))
+		v := strings.TrimSpace(pair[i+1:])
+		if err := llm.ValidateHeader(name, v); err != nil {
+			return nil, err
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[name] = v
+	}
+	return headers, nil
+}
+
+// headerNames returns the sorted names of headers.
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatHeaders shows headers as parseHeaders reads them, with the values masked as they
+// often carry gateway tokens.
+func formatHeaders(headers map[string]string) string {
+	pairs := make([]string, 0, len(headers))
+	for _, name := range headerNames(headers) {
+		pairs = append(pairs, name+"="+scrubForDemo(maskIfSet(headers[name])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// redactProxy shows a proxy URL with its password masked.
+func redactProxy(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
 // maskIfSet masks non-empty keys for display
 func maskIfSet(v string) string {
 	if strings.TrimSpace(v) == "" {
@@ -289,6 +895,15 @@ func hideIfSet(v string) string {
 	return "hidden"
 }
 
+// scrubForDemo hides v in demo mode, so projected or recorded screens never show
+// endpoints, keys or project IDs.
+func scrubForDemo(v string) string {
+	if ui.IsDemoMode() {
+		return hideIfSet(v)
+	}
+	return v
+}
+
 // revealOrNull shows the raw value when present; otherwise prints 'null'
 func revealOrNull(v string) string {
     if strings.TrimSpace(v) == "" {
@@ -409,9 +1024,6 @@ func plainConfigureWizard(cfg *config.Config) error {
 	}
 
 	// Set defaults
-	if cfg.UserPreferences.Language == "" {
-		cfg.UserPreferences.Language = "english"
-	}
 	if cfg.UserPreferences.MaxHistorySize == 0 {
 		cfg.UserPreferences.MaxHistorySize = 100
 	}
@@ -430,3 +1042,81 @@ func init() {
 	configCmd.Flags().Bool("from-init", false, "Internal flag for init command")
 	_ = configCmd.Flags().MarkHidden("from-init")
 }
+
+// configKeys are the keys 'aish config get' and 'set' accept besides providers.<name>.<field>
+// and ui.keys.<action>, in the short form the docs use; completion offers the same. Preferences
+// may also be written user_preferences.<key>.
+var configKeys = []string{
+	"default_provider", "language", "language_fallback", "locale", "timezone", "auto_execute",
+	"warmup", "cleanup_max_age_hours", "fallback_provider", "fallback_providers",
+	"consensus_provider", "routing.capture_provider", "routing.capture_model",
+	"routing.ask_provider", "routing.ask_model", "allow_complex_commands", "dangerous_commands",
+	"command_preview", "fix_credential_permissions", "command_limits.max_length",
+	"command_limits.max_pipes", "command_limits.max_redirects", "slow_provider_seconds",
+	"rate_limit_wait_seconds", "content_filter_retry", "cache.enabled", "cache.max_entries",
+	"cache.suggestion_ttl_hours", "cache.command_ttl_hours", "cache.enable_similarity",
+	"cache.similarity_threshold", "context.max_history_entries", "context.filter_sensitive_cmd",
+	"context.enable_enhanced", "proxy", "max_concurrent_requests", "ui.animations",
+	"ui.output_template", "ui.key_bindings", "updates.check", "telemetry.enabled",
+	"screen_reader", "enabled_llm_triggers",
+}
+
+// configGetOnlyKeys are read with 'aish config get' but set in parts: ui.keys through
+// ui.keys.<action>.
+var configGetOnlyKeys = []string{"ui.keys"}
+
+// configKeyAliases are older spellings of configKeys that are still accepted.
+var configKeyAliases = map[string]string{
+	"auto-execute":                "auto_execute",
+	"animations":                  "ui.animations",
+	"output_template":             "ui.output_template",
+	"key_bindings":                "ui.key_bindings",
+	"accessibility.screen_reader": "screen_reader",
+}
+
+// providerFields are the fields of providers.<name>.<field>.
+var providerFields = []string{
+	"api_endpoint", "model", "api_key", "api_key_file", "project", "context_window",
+	"max_output_tokens", "reasoning_effort", "transport", "azure_deployment",
+	"azure_api_version", "extra_headers", "gateway_compat", "location", "credentials_file",
+}
+
+// canonicalConfigKey lowercases key and maps the user_preferences.<key> form and the aliases
+// of a key to its entry in configKeys; other keys are only lowercased.
+func canonicalConfigKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.TrimPrefix(key, "user_preferences.")
+	if canonical, ok := configKeyAliases[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// keyActionFromKey returns the action of a ui.keys.<action> config key.
+func keyActionFromKey(lower string) (string, bool) {
+	lower = strings.TrimPrefix(lower, "user_preferences.")
+	return strings.CutPrefix(lower, "ui.keys.")
+}
+
+// setKeyOverride binds action to the comma-separated keys in value, or restores the key
+// binding style's keys when value is empty. The result must still be a valid key map.
+func setKeyOverride(cfg *config.Config, action, value string) {
+	keys := make(map[string][]string, len(cfg.UserPreferences.UI.Keys)+1)
+	for a, k := range cfg.UserPreferences.UI.Keys {
+		keys[a] = k
+	}
+	delete(keys, action)
+	for _, part := range strings.Split(value, ",") {
+		if k := strings.ToLower(strings.TrimSpace(part)); k != "" {
+			keys[action] = append(keys[action], k)
+		}
+	}
+	if _, err := ui.NewKeyMap(cfg.UserPreferences.UI.KeyBindings, keys); err != nil {
+		pterm.Error.Printfln("Invalid value for ui.keys.%s: %v", action, err)
+		os.Exit(1)
+	}
+	if len(keys) == 0 {
+		keys = nil
+	}
+	cfg.UserPreferences.UI.Keys = keys
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm/gemini/auth"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var configProjectCmd = &cobra.Command{
+	Use:   "project [project-id]",
+	Short: "Switch the Google Cloud project used by gemini-cli",
+	Long: `Lists the Google Cloud projects your gemini-cli sign-in can see and sets the
+chosen one as providers.gemini-cli.project, after checking that the account can
+open it.
+
+Without arguments on a terminal, aish lists the projects to pick from; elsewhere
+it prints them.`,
+	Example: `  aish config project
+  aish config project my-gcp-project`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			pterm.Error.Printfln("Failed to load config: %v", err)
+			os.Exit(aerrors.ExitConfig)
+		}
+		current := strings.TrimSpace(cfg.Providers[config.ProviderGeminiCLI].Project)
+		if current == "YOUR_GEMINI_PROJECT_ID" {
+			current = ""
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		// Best effort: a stale token only means the listing below fails with a clearer error
+		_ = auth.EnsureValidToken(ctx)
+
+		var project string
+		if len(args) > 0 {
+			project = strings.TrimSpace(args[0])
+		} else {
+			projects, err := auth.SearchProjectsV3(ctx)
+			if err != nil {
+				pterm.Error.Printfln("Could not list your Google Cloud projects: %v", err)
+				pterm.Info.Printfln("Sign in again with '%s'.", authLoginHint(config.Profile()))
+				os.Exit(aerrors.ExitProvider)
+			}
+			if len(projects) == 0 {
+				pterm.Warning.Println("Your Google account cannot see any active projects. Create one at https://console.cloud.google.com/projectcreate")
+				os.Exit(1)
+			}
+			labels := make([]string, len(projects))
+			defaultLabel := ""
+			for i, p := range projects {
+				labels[i] = projectLabel(p)
+				if p.ProjectID == current {
+					defaultLabel = labels[i]
+				}
+			}
+			if !isInteractiveTTY() {
+				for i, p := range projects {
+					marker := "  "
+					if p.ProjectID == current {
+						marker = "* "
+					}
+					fmt.Println(marker + labels[i])
+				}
+				return
+			}
+			choice, err := ui.AskSelect("Select the Google Cloud project for gemini-cli", labels, defaultLabel)
+			if err != nil {
+				os.Exit(aerrors.ExitUserCancel)
+			}
+			for i, label := range labels {
+				if label == choice {
+					project = projects[i].ProjectID
+				}
+			}
+		}
+		if project == "" {
+			pterm.Error.Println("Project ID cannot be empty")
+			os.Exit(1)
+		}
+
+		if _, err := auth.GetProject(ctx, project); err != nil {
+			pterm.Error.Printfln("Your Google account cannot open project %s: %v", project, err)
+			pterm.Info.Printfln("The project is still %s.", orNone(current))
+			os.Exit(aerrors.ExitProvider)
+		}
+
+		pc := cfg.Providers[config.ProviderGeminiCLI]
+		pc.Project = project
+		cfg.Providers[config.ProviderGeminiCLI] = pc
+		if err := cfg.Save(); err != nil {
+			pterm.Error.Printfln("Failed to save config: %v", err)
+			os.Exit(aerrors.ExitConfig)
+		}
+		pterm.Success.Printfln("gemini-cli now uses project %s.", project)
+		if env := strings.TrimSpace(os.Getenv(config.EnvAISHGeminiProject)); env != "" && env != project {
+			pterm.Warning.Printfln("%s=%s still takes precedence in this shell.", config.EnvAISHGeminiProject, env)
+		}
+	},
+}
+
+// projectLabel shows a project as "Display Name (project-id)", or just the ID when it has no
+// other name. v3 search results keep the resource name (projects/123) in Name, so only
+// DisplayName counts.
+func projectLabel(p auth.GCPProject) string {
+	name := strings.TrimSpace(p.DisplayName)
+	if name == "" || name == p.ProjectID {
+		return p.ProjectID
+	}
+	return fmt.Sprintf("%s (%s)", name, p.ProjectID)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "unset"
+	}
+	return s
+}
+
+func init() {
+	configCmd.AddCommand(configProjectCmd)
+}
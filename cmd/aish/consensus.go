@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/history"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/security"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+)
+
+// consensus is what the consensus provider (user_preferences.consensus_provider) made of a
+// destructive suggestion.
+type consensus struct {
+	reason string       // Why the suggested command counts as destructive
+	second ui.Candidate // The consensus provider's own answer; empty Command when it failed
+	agreed bool
+}
+
+// disagreed reports whether the consensus provider answered with a different command.
+func (c consensus) disagreed() bool {
+	return !c.agreed && c.second.Command != ""
+}
+
+// checkConsensus asks the consensus provider the same question when command is destructive,
+// so a risky command is only offered for direct execution when two providers agree on it.
+// ok is false when no check was made: the command is not destructive or no consensus
+// provider is configured.
+func checkConsensus(ctx context.Context, presenter *ui.Presenter, cfg *config.Config, primaryName, command string,
+	ask func(context.Context, llm.Provider) (*llm.Suggestion, error)) (consensus, bool) {
+
+	reason, destructive := security.Destructive(command)
+	if !destructive {
+		return consensus{}, false
+	}
+	name, provider := secondaryProvider(cfg, cfg.UserPreferences.ConsensusProvider, primaryName)
+	if provider == nil {
+		return consensus{}, false
+	}
+
+	_ = presenter.ShowLoadingWithTimer("Checking with " + name)
+	release := acquireRequestSlot(ctx, cfg)
+	s, err := ask(ctx, provider)
+	release()
+	if err != nil || s == nil || strings.TrimSpace(s.CorrectedCommand) == "" {
+		presenter.StopLoading(false)
+		pterm.Warning.Printfln("This command %s and %s could not double-check it; review it before running.", reason, name)
+		return consensus{reason: reason}, true
+	}
+	presenter.StopLoading(true)
+
+	c := consensus{
+		reason: reason,
+		second: ui.Candidate{Provider: name, Command: strings.TrimSpace(s.CorrectedCommand), Explanation: strings.TrimSpace(s.Explanation)},
+	}
+	c.agreed = sameCommand(command, c.second.Command)
+	if c.agreed {
+		pterm.Info.Printfln("This command %s; %s suggested the same command.", reason, name)
+	}
+	return c, true
+}
+
+// sameCommand compares two commands ignoring differences in whitespace and a trailing ';'.
+func sameCommand(a, b string) bool {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimSpace(s), ";")), " ")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// runChosenCandidate lets the user pick between the primary suggestion and the consensus
+// provider's, runs the chosen one and notes it on entry (which may be nil).
+func runChosenCandidate(cfg *config.Config, c consensus, primary ui.Candidate, entry *history.Entry) {
+	candidates := []ui.Candidate{primary, c.second}
+	i, ok, err := ui.ChooseCandidate(c.reason, candidates)
+	if err != nil || !ok {
+		return
+	}
+	chosen := candidates[i]
+	if !confirmSuggestedCommand(cfg, chosen.Command) {
+		return
+	}
+	if entry != nil {
+		entry.Accepted = true
+		if i > 0 {
+			entry.Provider = chosen.Provider
+			if pc, ok := effectiveProviderConfig(cfg, chosen.Provider); ok {
+				entry.Model = pc.Model
+			}
+			entry.SuggestedCommand = chosen.Command
+			entry.Explanation = chosen.Explanation
+		}
+	}
+	executeCommand(chosen.Command)
+}
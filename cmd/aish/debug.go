@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/diagnostics"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/shell"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Diagnostic tools for troubleshooting aish",
+}
+
+var debugBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Package sanitized diagnostics into a tarball for bug reports",
+	Long: `Collects version info, an environment summary, the configuration with
+secrets masked, the last lines of the log file, the installed shell hook and
+the provider health, with the HTTP timing of the last connection check of each
+provider ('aish use'), into a .tar.gz archive you can attach to a bug report.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		logLines, _ := cmd.Flags().GetInt("log-lines")
+		if output == "" {
+			output = fmt.Sprintf("aish-debug-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+
+		opts := diagnostics.BundleOptions{
+			Version:  versionString(),
+			LogLines: logLines,
+		}
+		opts.Config, opts.ConfigError = config.Load()
+		if opts.Config != nil {
+			opts.LogFile = opts.Config.UserPreferences.Logging.LogFile
+		}
+		if opts.LogFile == "" {
+			if logDir, err := config.LogDir(); err == nil {
+				opts.LogFile = filepath.Join(logDir, config.DefaultLogFileName)
+			}
+		}
+		opts.HookFile, opts.HookSnippet, _ = shell.InstalledHookSnippet()
+		opts.HealthFile, _ = llm.HealthStatePath()
+
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			pterm.Error.Printfln("Failed to create bundle: %v", err)
+			os.Exit(1)
+		}
+		if err := diagnostics.WriteBundle(f, opts); err != nil {
+			_ = f.Close()
+			_ = os.Remove(output)
+			pterm.Error.Printfln("Failed to write bundle: %v", err)
+			os.Exit(1)
+		}
+		if err := f.Close(); err != nil {
+			pterm.Error.Printfln("Failed to write bundle: %v", err)
+			os.Exit(1)
+		}
+
+		pterm.Success.Printfln("Diagnostic bundle written to %s", output)
+		pterm.Info.Println("Secrets are masked, but please review the archive before sharing it.")
+	},
+}
+
+func init() {
+	debugBundleCmd.Flags().StringP("output", "o", "", "path of the tarball (default aish-debug-<timestamp>.tar.gz)")
+	debugBundleCmd.Flags().Int("log-lines", diagnostics.DefaultLogLines, "number of trailing log lines to include")
+	debugCmd.AddCommand(debugBundleCmd)
+}
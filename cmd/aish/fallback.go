@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// defaultSlowProviderAfter is how long the primary provider may take before the user is offered
+// the fallback provider (user_preferences.slow_provider_seconds overrides it).
+const defaultSlowProviderAfter = 8 * time.Second
+
+// errWaitCancelled is returned by awaitSuggestion when the user pressed c while waiting.
+var errWaitCancelled = errors.New("cancelled while waiting for the provider")
+
+type waitChoice int
+
+const (
+	waitFallback waitChoice = iota + 1
+	waitCancel
+)
+
+type suggestionResult struct {
+	suggestion *llm.Suggestion
+	err        error
+}
+
+// slowProviderAfter returns the configured slow-provider threshold.
+func slowProviderAfter(cfg *config.Config) time.Duration {
+	if cfg.UserPreferences.SlowProviderSeconds > 0 {
+		return time.Duration(cfg.UserPreferences.SlowProviderSeconds) * time.Second
+	}
+	return defaultSlowProviderAfter
+}
+
+// fallbackProvider returns the provider offered when primaryName is slow: the configured
+// fallback provider, or else the first usable provider of the fallback chain. It returns an
+// empty name and nil when there is none.
+func fallbackProvider(cfg *config.Config, primaryName string) (string, llm.Provider) {
+	if strings.TrimSpace(cfg.UserPreferences.FallbackProvider) == "" {
+		if chain := fallbackChain(cfg, primaryName); len(chain) > 0 {
+			return chain[0].providerName, chain[0].provider
+		}
+		return "", nil
+	}
+	return secondaryProvider(cfg, cfg.UserPreferences.FallbackProvider, primaryName)
+}
+
+// fallbackChain returns the usable providers of user_preferences.fallback_providers in order,
+// leaving out primaryName and repeated names.
+func fallbackChain(cfg *config.Config, primaryName string) []answer {
+	var chain []answer
+	seen := map[string]bool{}
+	for _, name := range cfg.UserPreferences.FallbackProviders {
+		name, p := secondaryProvider(cfg, name, primaryName)
+		if p == nil || seen[name] {
+			continue
+		}
+		seen[name] = true
+		chain = append(chain, answer{providerName: name, provider: p})
+	}
+	return chain
+}
+
+// tryFallbackChain is called once failed has given err. Unless the request was cancelled, it
+// asks the providers of the fallback chain in turn, skipping those that failed moments ago,
+// until one answers. A rate limit that resets within rate_limit_wait_seconds is waited out and
+// failed asked again first. Each provider it moves past is recorded as failed in health;
+// recording the outcome of the one it returns is left to the caller. Without a chain, or when
+// err is nil, failed and err are returned unchanged.
+func tryFallbackChain(ctx context.Context, presenter *ui.Presenter, cfg *config.Config, health *llm.HealthState,
+	failed answer, err error, get func(context.Context, llm.Provider) (*llm.Suggestion, error)) (answer, error) {
+
+	if err == nil || ctx.Err() != nil || errors.Is(err, errWaitCancelled) {
+		return failed, err
+	}
+	if waitForRateLimit(ctx, presenter, cfg, failed.providerName, err) {
+		started := time.Now()
+		failed.suggestion, err = get(ctx, failed.provider)
+		failed.latency = time.Since(started)
+		if err == nil || ctx.Err() != nil {
+			return failed, err
+		}
+	}
+	current := failed
+	for _, next := range fallbackChain(cfg, failed.providerName) {
+		if _, skip := health.ShouldSkip(next.providerName, time.Now()); skip {
+			continue
+		}
+		health.RecordFailure(current.providerName, err, time.Now())
+		showFallbackPhase(presenter, fmt.Sprintf("%s failed — trying %s", current.providerName, next.providerName))
+		started := time.Now()
+		next.suggestion, err = get(ctx, next.provider)
+		next.latency = time.Since(started)
+		current = next
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return current, err
+}
+
+// waitForRateLimit waits until the rate limit that made providerName fail with err resets, when
+// the provider said when and that is within user_preferences.rate_limit_wait_seconds, counting
+// down on the loading line. It reports whether the wait ran to the end, so the request can be
+// retried.
+func waitForRateLimit(ctx context.Context, presenter *ui.Presenter, cfg *config.Config, providerName string, err error) bool {
+	limit := time.Duration(cfg.UserPreferences.RateLimitWaitSeconds) * time.Second
+	if limit <= 0 || llm.Classify(providerName, err).Type != llm.RateLimitError {
+		return false
+	}
+	wait, ok := llm.RetryAfter(err)
+	if !ok || wait > limit {
+		return false
+	}
+
+	deadline := time.Now().Add(wait)
+	phase := func() string {
+		return fmt.Sprintf("%s rate limited, resets in %s — retrying then", providerName, ui.WaitString(time.Until(deadline)))
+	}
+	showFallbackPhase(presenter, phase())
+	reset := time.NewTimer(wait)
+	defer reset.Stop()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-reset.C:
+			return true
+		case <-tick.C:
+			if presenter != nil && ui.AnimationsEnabled() && !ui.IsQuietOutput() {
+				presenter.SetLoadingPhase(phase())
+			}
+		}
+	}
+}
+
+// retrySanitized asks failed again with get, which sends a sanitized request (see
+// llm.SanitizeContext), when err is a content-filter refusal and content_filter_retry is on.
+// Otherwise failed and err are returned unchanged.
+func retrySanitized(ctx context.Context, presenter *ui.Presenter, cfg *config.Config,
+	failed answer, err error, get func(context.Context, llm.Provider) (*llm.Suggestion, error)) (answer, error) {
+
+	if err == nil || ctx.Err() != nil || !cfg.UserPreferences.ContentFilterRetry ||
+		llm.Classify(failed.providerName, err).Type != llm.ContentFilterError {
+		return failed, err
+	}
+	showFallbackPhase(presenter, fmt.Sprintf("%s declined the request — retrying with a sanitized prompt", failed.providerName))
+	started := time.Now()
+	retried := failed
+	retried.suggestion, err = get(ctx, failed.provider)
+	retried.latency = time.Since(started)
+	retried.sanitized = err == nil
+	return retried, err
+}
+
+// healthyFallback returns the first provider of the fallback chain that has not failed moments
+// ago, for use in place of primaryName when that one has.
+func healthyFallback(cfg *config.Config, health *llm.HealthState, primaryName string) (answer, bool) {
+	for _, next := range fallbackChain(cfg, primaryName) {
+		if _, skip := health.ShouldSkip(next.providerName, time.Now()); !skip {
+			return next, true
+		}
+	}
+	return answer{}, false
+}
+
+// showFallbackPhase shows msg on the loading line, or on stderr when there is no animated one.
+// Quiet output leaves it to reportFallbackAnswer.
+func showFallbackPhase(presenter *ui.Presenter, msg string) {
+	if ui.IsQuietOutput() {
+		return
+	}
+	if presenter != nil && ui.AnimationsEnabled() {
+		presenter.SetLoadingPhase(msg)
+		return
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// reportFallbackAnswer tells the user that a provider of the fallback chain answered in place
+// of primaryName. It prints nothing when primaryName answered itself.
+func reportFallbackAnswer(primaryName, answeredBy string) {
+	if answeredBy == primaryName {
+		return
+	}
+	if ui.IsQuietOutput() {
+		fmt.Fprintf(os.Stderr, "aish: %s failed; answered by %s\n", primaryName, answeredBy)
+		return
+	}
+	pterm.Info.Printfln("%s failed; answered by %s.", primaryName, answeredBy)
+}
+
+// secondaryProvider returns the provider called name when it differs from primaryName and is
+// usable; otherwise an empty name and nil.
+func secondaryProvider(cfg *config.Config, name, primaryName string) (string, llm.Provider) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == primaryName {
+		return "", nil
+	}
+	pc, ok := effectiveProviderConfig(cfg, name)
+	if !ok || isProviderConfigIncomplete(name, pc) {
+		return "", nil
+	}
+	p, err := getProvider(name, pc)
+	if err != nil {
+		return "", nil
+	}
+	return name, cachedProvider(cfg, name, pc, p)
+}
+
+// answer is the suggestion awaitSuggestion settled on and the provider that produced it.
+type answer struct {
+	suggestion   *llm.Suggestion
+	providerName string
+	provider     llm.Provider
+	latency      time.Duration // Time the answering provider took, excluding time spent on an abandoned one
+	sanitized    bool          // Answered from a sanitized request after a content-filter refusal
+}
+
+// awaitSuggestion asks primary for a suggestion. When it is still running after the slow-provider
+// threshold on an interactive terminal, the loading line offers "press f to try fallback provider,
+// c to cancel": f abandons the primary request and asks the fallback provider instead, c returns
+// errWaitCancelled. Once stream (which may be nil) starts receiving the response the offer is
+// withdrawn and the response is printed as it arrives.
+func awaitSuggestion(ctx context.Context, presenter *ui.Presenter, cfg *config.Config, primaryName string, primary llm.Provider,
+	stream *ui.StreamView, get func(context.Context, llm.Provider) (*llm.Suggestion, error)) (answer, error) {
+
+	primaryCtx, cancelPrimary := context.WithCancel(ctx)
+	defer cancelPrimary()
+	started := time.Now()
+	results := make(chan suggestionResult, 1)
+	go func() {
+		s, err := get(primaryCtx, primary)
+		results <- suggestionResult{s, err}
+	}()
+	fromPrimary := func(r suggestionResult) (answer, error) {
+		return answer{suggestion: r.suggestion, providerName: primaryName, provider: primary, latency: time.Since(started)}, r.err
+	}
+
+	if !isInteractiveTTY() {
+		return fromPrimary(<-results)
+	}
+
+	slow := time.NewTimer(slowProviderAfter(cfg))
+	defer slow.Stop()
+	select {
+	case r := <-results:
+		return fromPrimary(r)
+	case <-stream.Started():
+		return fromPrimary(<-results)
+	case <-slow.C:
+	}
+
+	fallbackName, fallback := fallbackProvider(cfg, primaryName)
+	hint := "still waiting — press c to cancel"
+	if fallback != nil {
+		hint = fmt.Sprintf("still waiting — press f to try fallback provider (%s), c to cancel", fallbackName)
+	}
+	if ui.AnimationsEnabled() {
+		presenter.SetLoadingPhase(hint)
+	} else {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+
+	// Raw mode would garble streamed text, so hold it back until the keys are released
+	stream.Hold()
+	defer stream.Release()
+	keys, stopKeys := readWaitKeys(fallback != nil)
+	defer stopKeys()
+	select {
+	case r := <-results:
+		return fromPrimary(r)
+	case <-stream.Started():
+		stopKeys()
+		stream.Release()
+		return fromPrimary(<-results)
+	case choice := <-keys:
+		cancelPrimary()
+		stopKeys()
+		// Chunks the cancelled request still delivers are dropped from here on
+		stream.Reset()
+		if choice == waitCancel {
+			return answer{providerName: primaryName, provider: primary}, errWaitCancelled
+		}
+		presenter.SetLoadingPhase("trying " + fallbackName)
+		stream.Release()
+		started = time.Now()
+		s, err := get(ctx, fallback)
+		return answer{suggestion: s, providerName: fallbackName, provider: fallback, latency: time.Since(started)}, err
+	}
+}
+
+// readWaitKeys reads single key presses from the terminal in raw mode and reports f (only when
+// allowFallback) or c; Ctrl+C also cancels, since raw mode swallows the signal. The returned
+// stop function restores the terminal and may be called more than once.
+func readWaitKeys(allowFallback bool) (<-chan waitChoice, func()) {
+	choices := make(chan waitChoice, 1)
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return choices, func() {}
+	}
+	// Go through SyscallConn rather than Fd, which would switch the file to blocking mode and
+	// keep Close from interrupting the pending Read
+	rc, err := tty.SyscallConn()
+	if err != nil {
+		_ = tty.Close()
+		return choices, func() {}
+	}
+	var state *term.State
+	if cerr := rc.Control(func(fd uintptr) { state, err = term.MakeRaw(int(fd)) }); cerr != nil || err != nil {
+		_ = tty.Close()
+		return choices, func() {}
+	}
+
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if n, err := tty.Read(buf); err != nil || n == 0 {
+				return
+			}
+			switch buf[0] {
+			case 'f', 'F':
+				if allowFallback {
+					choices <- waitFallback
+					return
+				}
+			case 'c', 'C', 0x03:
+				choices <- waitCancel
+				return
+			}
+		}
+	}()
+
+	return choices, sync.OnceFunc(func() {
+		_ = rc.Control(func(fd uintptr) { _ = term.Restore(int(fd), state) })
+		_ = tty.Close()
+	})
+}
@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/TonnyWong1052/aish/internal/classification"
 	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
 	"github.com/TonnyWong1052/aish/internal/history"
 	"github.com/TonnyWong1052/aish/internal/llm"
 	"github.com/TonnyWong1052/aish/internal/ui"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // This is the new parent command for history
@@ -33,32 +40,199 @@ var historyClearCmd = &cobra.Command{
 	},
 }
 
+var (
+	flagHistoryExportOutput    string
+	flagHistoryExportAnonymize bool
+	flagHistoryFormat          string
+	flagHistorySince           string
+	flagHistoryUntil           string
+	flagHistoryErrorType       string
+	flagHistoryExitCode        int
+	flagHistoryCommandPrefix   string
+)
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the error history as JSON lines or CSV",
+	Long: `Writes every history entry, oldest first, to stdout or to --out: as one JSON
+object per line after a format version header, or with --format csv as a CSV
+table. The format is guessed from the file extension when --format is not given.
+With --anonymize, user names, host names, home paths and IP addresses are
+replaced with stable placeholders (<user1>, <host1>, <ip1>), so the export can be
+shared as a failure corpus for debugging or prompt testing. 'aish history import'
+reads either format back.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		hist := loadHistory(cmd, nil)
+		format, err := history.ParseFormat(flagHistoryFormat, flagHistoryExportOutput)
+		if err != nil {
+			pterm.Error.Printfln("--format: %v", err)
+			os.Exit(1)
+		}
+
+		out := os.Stdout
+		if flagHistoryExportOutput != "" && flagHistoryExportOutput != "-" {
+			f, err := os.OpenFile(flagHistoryExportOutput, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				pterm.Error.Printfln("Failed to create %s: %v", flagHistoryExportOutput, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		var anonymizer *history.Anonymizer
+		if flagHistoryExportAnonymize {
+			anonymizer = history.NewAnonymizer()
+		}
+		entries := make([]history.Entry, 0, len(hist.Entries))
+		for i := len(hist.Entries) - 1; i >= 0; i-- {
+			entry := hist.Entries[i]
+			if anonymizer != nil {
+				entry = anonymizer.Entry(entry)
+			}
+			entries = append(entries, entry)
+		}
+		if err := history.Export(out, format, entries); err != nil {
+			pterm.Error.Printfln("Failed to write history: %v", err)
+			os.Exit(1)
+		}
+		if out != os.Stdout {
+			pterm.Success.Printfln("Exported %d entries to %s.", len(entries), flagHistoryExportOutput)
+		}
+	},
+}
+
+var historyImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import history exported by 'aish history export'",
+	Long: `Adds the entries of a JSON lines or CSV export ("-" reads stdin) to the history,
+skipping those already present. The format is guessed from the file extension
+when --format is not given. The history size limit still applies, so the oldest
+entries are dropped when the combined history is larger.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		format, err := history.ParseFormat(flagHistoryFormat, path)
+		if err != nil {
+			pterm.Error.Printfln("--format: %v", err)
+			os.Exit(1)
+		}
+
+		in := os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				pterm.Error.Printfln("Failed to open %s: %v", path, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+		entries, err := history.ReadExport(in, format)
+		if err != nil {
+			pterm.Error.Printfln("Failed to read %s: %v", path, err)
+			os.Exit(1)
+		}
+		added, err := history.Import(entries)
+		if err != nil {
+			pterm.Error.Printfln("Failed to import history: %v", err)
+			os.Exit(1)
+		}
+		pterm.Success.Printfln("Imported %d of %d entries.", added, len(entries))
+	},
+}
+
+var historySearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the error history",
+	Long: `Lists the history entries whose command, captured output, suggestion or
+explanation contains the query, ignoring case. Combine with --error-type,
+--exit-code, --command-prefix, --since and --until to narrow the results, and
+pass an ID to 'aish history show' for the full output.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := config.Load()
+		filter := historyFilter(cmd, cfg)
+		filter.Query = strings.Join(args, " ")
+		hist := loadFilteredHistory(filter)
+		if len(hist.Entries) == 0 {
+			pterm.Info.Println("No matching entries.")
+			return
+		}
+
+		format := localeFormat(cfg)
+		for _, entry := range hist.Entries {
+			fmt.Printf("%6d  %s  [%s] exit %d  %s\n", entry.ID, format.DateTime(entry.Timestamp), entry.ErrorType, entry.ExitCode, entry.Command)
+		}
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a stored entry with its full output",
+	Long:  `Prints a history entry, as listed by 'aish history search', with its complete stdout and stderr.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			pterm.Error.Printfln("Invalid entry ID: %s", args[0])
+			os.Exit(1)
+		}
+		entry, ok, err := history.Lookup(id)
+		if err != nil {
+			pterm.Error.Printfln("Failed to load history: %v", err)
+			os.Exit(1)
+		}
+		if !ok {
+			pterm.Error.Printfln("No history entry with ID %d.", id)
+			os.Exit(1)
+		}
+
+		cfg, _ := config.Load()
+		format := localeFormat(cfg)
+		fmt.Printf("Command:    %s\n", entry.Command)
+		fmt.Printf("Captured:   %s\n", format.DateTime(entry.Timestamp))
+		fmt.Printf("Exit code:  %d\n", entry.ExitCode)
+		fmt.Printf("Error type: %s\n", entry.ErrorType)
+		for _, stream := range []struct{ name, text string }{{"stdout", entry.Stdout}, {"stderr", entry.Stderr}} {
+			if stream.text == "" {
+				continue
+			}
+			fmt.Printf("\n--- %s ---\n%s", stream.name, stream.text)
+			if !strings.HasSuffix(stream.text, "\n") {
+				fmt.Println()
+			}
+		}
+		if entry.SuggestedCommand != "" {
+			fmt.Printf("\nSuggested (%s): %s\n", entry.Provider, entry.SuggestedCommand)
+			if entry.Explanation != "" {
+				fmt.Println(entry.Explanation)
+			}
+		}
+	},
+}
+
 // listHistoryAndAnalyze contains the logic from the original historyCmd
 func listHistoryAndAnalyze(cmd *cobra.Command, args []string) {
-	hist, err := history.Load()
-	if err != nil {
-		pterm.Error.Printfln("Failed to load history: %v", err)
-		os.Exit(1)
-	}
-
+	cfg, _ := config.Load()
+	hist := loadHistory(cmd, cfg)
 	if len(hist.Entries) == 0 {
 		pterm.Info.Println("No history found.")
 		return
 	}
 
+	format := localeFormat(cfg)
 	var options []string
 	for _, entry := range hist.Entries {
 		command := entry.Command
 		if len(command) > 50 {
 			command = command[:47] + "..."
 		}
-		options = append(options, fmt.Sprintf("%s [%s] - %s", entry.Timestamp.Format("2006-01-02 15:04:05"), entry.ErrorType, command))
+		options = append(options, fmt.Sprintf("%s [%s] - %s", format.DateTime(entry.Timestamp), entry.ErrorType, command))
 	}
 
 	fmt.Println()
-	selected, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(options).
-		Show("Select an error to analyze >")
+	selected, _ := ui.AskSelect("Select an error to analyze >", options, "")
 
 	var selectedEntry history.Entry
 	for i, option := range options {
@@ -67,36 +241,149 @@ func listHistoryAndAnalyze(cmd *cobra.Command, args []string) {
 			break
 		}
 	}
+	analyzeHistoryEntry(selectedEntry)
+}
+
+var historyFzfCmd = &cobra.Command{
+	Use:   "fzf",
+	Short: "Pick an error to analyze with fzf",
+	Long: `Pipes the history into fzf, with the captured output in the preview pane, and
+re-analyzes the selected error. Falls back to the built-in picker when fzf is not
+installed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := config.Load()
+		hist := loadHistory(cmd, cfg)
+		if len(hist.Entries) == 0 {
+			pterm.Info.Println("No history found.")
+			return
+		}
+
+		format := localeFormat(cfg)
+		options := make([]string, len(hist.Entries))
+		previews := make([]string, len(hist.Entries))
+		for i, entry := range hist.Entries {
+			options[i] = fmt.Sprintf("%s [%s] %s", format.DateTime(entry.Timestamp), entry.ErrorType, entry.Command)
+			previews[i] = historyPreview(entry, format)
+		}
+		i, err := ui.FuzzySelect("Select an error to analyze", options, previews)
+		if errors.Is(err, ui.ErrSelectionCancelled) {
+			return
+		}
+		if err != nil {
+			pterm.Error.Printfln("Selection failed: %v", err)
+			os.Exit(1)
+		}
+		analyzeHistoryEntry(hist.Entries[i])
+	},
+}
+
+// loadHistory loads the history, newest first, keeping the entries that pass the filter flags.
+// cfg may be nil.
+func loadHistory(cmd *cobra.Command, cfg *config.Config) *history.History {
+	return loadFilteredHistory(historyFilter(cmd, cfg))
+}
+
+// loadFilteredHistory loads the history, newest first, keeping the entries that pass filter.
+func loadFilteredHistory(filter history.Filter) *history.History {
+	hist, err := history.Load()
+	if err != nil {
+		pterm.Error.Printfln("Failed to load history: %v", err)
+		os.Exit(1)
+	}
+	if filter == (history.Filter{}) {
+		return hist
+	}
+	return &history.History{Entries: filter.Apply(hist.Entries)}
+}
+
+// historyFilter builds a filter from --since, --until, --error-type, --exit-code and
+// --command-prefix. Dates in the bounds are read in the configured time zone; cfg may be nil.
+func historyFilter(cmd *cobra.Command, cfg *config.Config) history.Filter {
+	filter := history.Filter{
+		ErrorType:     classification.ErrorType(flagHistoryErrorType),
+		CommandPrefix: flagHistoryCommandPrefix,
+	}
+	if cmd.Flags().Changed("exit-code") {
+		code := flagHistoryExitCode
+		filter.ExitCode = &code
+	}
+
+	var prefs config.UserPreferences
+	if cfg != nil {
+		prefs = cfg.UserPreferences
+	}
+	now := time.Now()
+	for _, bound := range []struct {
+		flag, value string
+		t           *time.Time
+	}{{"--since", flagHistorySince, &filter.Since}, {"--until", flagHistoryUntil, &filter.Until}} {
+		if bound.value == "" {
+			continue
+		}
+		var err error
+		if *bound.t, err = history.ParseTimeBound(bound.value, now, prefs.TimeLocation()); err != nil {
+			pterm.Error.Printfln("%s: %v", bound.flag, err)
+			os.Exit(1)
+		}
+	}
+	return filter
+}
+
+// historyPreview is the text shown next to an entry in the fzf picker.
+func historyPreview(entry history.Entry, format ui.LocaleFormat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ %s\nexit code %d\n", entry.Command, entry.ExitCode)
+	if entry.Stderr != "" {
+		b.WriteString("\n" + entry.Stderr)
+	}
+	if entry.Stdout != "" {
+		b.WriteString("\n" + entry.Stdout)
+	}
+	if entry.SuggestedCommand != "" {
+		source := entry.Provider
+		if entry.LatencyMs > 0 {
+			source += ", " + format.Duration(time.Duration(entry.LatencyMs)*time.Millisecond)
+		}
+		fmt.Fprintf(&b, "\n\nSuggested (%s): %s", source, entry.SuggestedCommand)
+	}
+	return b.String()
+}
 
+// analyzeHistoryEntry asks the default provider about a past error and offers its suggestion.
+func analyzeHistoryEntry(selectedEntry history.Entry) {
 	cfg, err := config.Load()
 	if err != nil {
 		pterm.Error.Printfln("Failed to load config: %v", err)
-		os.Exit(1)
+		os.Exit(aerrors.ExitConfig)
 	}
 
-	providerName := effectiveProviderName(cfg)
-	providerCfg, ok := cfg.Providers[providerName]
+	providerName := flowProviderName(cfg, config.FlowCapture)
+	providerCfg, ok := flowProviderConfig(cfg, config.FlowCapture, providerName)
 	if !ok || isProviderConfigIncomplete(providerName, providerCfg) {
 		pterm.Error.Printfln("Default provider not configured. Please run 'aish config'.")
-		os.Exit(1)
+		os.Exit(aerrors.ExitConfig)
 	}
 	provider, err := getProvider(providerName, providerCfg)
 	if err != nil {
 		pterm.Error.Printfln("Failed to create provider: %v", err)
 		os.Exit(1)
 	}
+	provider = cachedProvider(cfg, providerName, providerCfg, provider)
 
 	presenter := ui.NewPresenter()
 	if err := presenter.ShowLoadingWithTimer("Analyzing selected error"); err != nil {
 		pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
 	}
 
-	suggestion, err := provider.GetSuggestion(context.Background(), llm.CapturedContext{
+	release := acquireRequestSlot(context.Background(), cfg)
+	captured := llm.CapturedContext{
 		Command:  selectedEntry.Command,
 		Stdout:   selectedEntry.Stdout,
 		Stderr:   selectedEntry.Stderr,
 		ExitCode: selectedEntry.ExitCode,
-	}, effectiveLanguage(cfg))
+	}
+	suggestion, err := provider.GetSuggestion(withDebugReports(context.Background()), captured, effectiveLanguage(cfg))
+	release()
 
 	if err != nil {
 		presenter.StopLoading(false)
@@ -105,6 +392,9 @@ func listHistoryAndAnalyze(cmd *cobra.Command, args []string) {
 	}
 	presenter.StopLoading(true)
 
+	// Follow-ups refine the suggestion, so they are sent with the error and the earlier suggestions
+	var conv llm.Conversation
+	conv.Add("", suggestion.CorrectedCommand)
 	for {
 		uiSuggestion := ui.Suggestion{
 			Title:       "Analysis of Historical Error",
@@ -122,25 +412,52 @@ func listHistoryAndAnalyze(cmd *cobra.Command, args []string) {
 		}
 
 		if userInput == "" {
+			if !confirmSuggestedCommand(cfg, suggestion.CorrectedCommand) {
+				return
+			}
 			executeCommand(suggestion.CorrectedCommand)
 			break
 		} else {
 			if err := presenter.ShowLoadingWithTimer("Getting new suggestion"); err != nil {
 				pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
 			}
-			suggestion, err = provider.GetSuggestion(context.Background(), llm.CapturedContext{
-				Command: userInput,
-			}, cfg.UserPreferences.Language)
+			release := acquireRequestSlot(context.Background(), cfg)
+			suggestion, err = provider.GetSuggestion(conv.Context(withDebugReports(context.Background())), conv.FollowUp(captured, userInput), effectiveLanguage(cfg))
+			release()
 			if err != nil {
 				presenter.StopLoading(false)
 				pterm.Error.Printfln("Failed to get new suggestion: %v", err)
 				break
 			}
 			presenter.StopLoading(true)
+			conv.Add(userInput, suggestion.CorrectedCommand)
 		}
 	}
 }
 
 func init() {
 	historyCmd.AddCommand(historyClearCmd)
+	historyCmd.AddCommand(historyExportCmd)
+	historyCmd.AddCommand(historyImportCmd)
+	historyCmd.AddCommand(historyFzfCmd)
+	historyCmd.AddCommand(historySearchCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	for _, c := range []*cobra.Command{historyCmd, historyFzfCmd, historyExportCmd, historySearchCmd} {
+		c.Flags().StringVar(&flagHistorySince, "since", "", "Only entries captured at or after this time (24h, 7d, today, 2025-03-07, RFC 3339)")
+		c.Flags().StringVar(&flagHistoryUntil, "until", "", "Only entries captured before this time (same forms as --since)")
+		c.Flags().StringVar(&flagHistoryErrorType, "error-type", "", "Only entries classified as this error type (e.g. CommandNotFound)")
+		c.Flags().IntVar(&flagHistoryExitCode, "exit-code", 0, "Only entries that exited with this code")
+		c.Flags().StringVar(&flagHistoryCommandPrefix, "command-prefix", "", "Only entries whose command starts with this text")
+	}
+	historyExportCmd.Flags().StringVarP(&flagHistoryExportOutput, "output", "o", "", "Write to this file instead of stdout")
+	historyExportCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "out" {
+			name = "output"
+		}
+		return pflag.NormalizedName(name)
+	})
+	for _, c := range []*cobra.Command{historyExportCmd, historyImportCmd} {
+		c.Flags().StringVar(&flagHistoryFormat, "format", "", "jsonl or csv (default: from the file extension, else jsonl)")
+	}
+	historyExportCmd.Flags().BoolVar(&flagHistoryExportAnonymize, "anonymize", false, "Replace user names, host names, home paths and IPs with placeholders")
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -36,6 +37,14 @@ It will:
 		pterm.Println() // Add some spacing
 
 		pterm.DefaultSection.Println("Step 2: Configuring LLM Provider")
+		if from, _ := cmd.Flags().GetString("from"); from != "" {
+			if err := runTemplateInit(from); err != nil {
+				pterm.Error.Printfln("Configuration from template failed: %v", err)
+				fmt.Println("[aish] Config: template aborted")
+				os.Exit(1)
+			}
+			return
+		}
 		// 依 TTY 能力選擇互動式或純文字精靈
 		if isInteractiveTTY() {
 			pterm.Info.Println("Launching configuration wizard (interactive mode)...")
@@ -97,6 +106,7 @@ It will:
 		if isInteractiveTTY() {
 			// Interactive mode - use the full configuration wizard
 			wizard := ui.NewConfigWizard(cfg, true) // true = enable advanced settings prompt
+			wizard.RefreshLists, _ = cmd.Flags().GetBool("refresh")
 			if err := wizard.Run(); err != nil {
 				pterm.Error.Printfln("Configuration wizard failed: %v", err)
 				fmt.Println("[aish] Config: wizard failed")
@@ -120,7 +130,7 @@ func runSimpleProviderConfig(cfg *config.Config) error {
 	reader := bufio.NewReader(os.Stdin)
 
 	// Show available providers
-	providers := []string{"openai", "gemini", "gemini-cli", "claude", "ollama"}
+	providers := []string{"openai", "gemini", "gemini-cli", "vertex", "claude", "ollama"}
 	fmt.Printf("Available providers: %s\n", strings.Join(providers, ", "))
 	fmt.Println("Tip: 'gemini-cli' is recommended (OAuth login, no API key, easy setup, often higher free usage)")
 	fmt.Println("      'ollama' for local models (no API key, runs on your machine)")
@@ -177,6 +187,11 @@ func runSimpleProviderConfig(cfg *config.Config) error {
 				APIEndpoint: config.OllamaAPIEndpoint,
 				Model:       config.DefaultOllamaModel,
 			}
+		case "vertex":
+			providerCfg = config.ProviderConfig{
+				Location: config.DefaultVertexLocation,
+				Model:    config.DefaultVertexModel,
+			}
 		}
 	}
 
@@ -220,6 +235,20 @@ func runSimpleProviderConfig(cfg *config.Config) error {
 		if projectID != "" {
 			providerCfg.Project = projectID
 		}
+	case "vertex":
+		fmt.Print("Project ID (empty = the service account's project): ")
+		projectID, _ := reader.ReadString('\n')
+		if projectID = strings.TrimSpace(projectID); projectID != "" {
+			providerCfg.Project = projectID
+		}
+		fmt.Printf("Region [%s]: ", providerCfg.Location)
+		location, _ := reader.ReadString('\n')
+		if location = strings.TrimSpace(location); location != "" {
+			providerCfg.Location = location
+		}
+		fmt.Print("Service account key file (empty = Application Default Credentials): ")
+		keyFile, _ := reader.ReadString('\n')
+		providerCfg.CredentialsFile = strings.TrimSpace(keyFile)
 	case "ollama":
 		fmt.Println("✓ No API key required for local Ollama")
 		fmt.Println("Make sure Ollama is installed and running: ollama serve")
@@ -244,4 +273,99 @@ func runSimpleProviderConfig(cfg *config.Config) error {
 func init() {
 	// 提供 --reset 旗標允許使用者重新初始化（備份舊配置並重建）
 	initCmd.Flags().Bool("reset", false, "Reinitialize configuration (backup old config and start fresh)")
+	initCmd.Flags().Bool("refresh", false, "Fetch model and project lists again instead of using the cached ones")
+	initCmd.Flags().String("from", "", "Configure from a team template (https URL or local path), asking only for API keys")
+}
+
+// runTemplateInit configures aish from a team template: the template supplies providers,
+// endpoints and preferences, and only the API keys it leaves out are taken from the
+// environment or asked for. The existing config, if any, is backed up before saving.
+func runTemplateInit(src string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	data, err := config.FetchTemplate(ctx, src)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.FromTemplate(data)
+	if err != nil {
+		return err
+	}
+	pterm.Info.Printfln("Loaded template from %s (default provider: %s)", src, cfg.DefaultProvider)
+
+	for _, secret := range cfg.RequiredSecrets() {
+		key := ""
+		for _, env := range secret.EnvVars {
+			if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+				key = v
+				pterm.Info.Printfln("Using %s for the %s API key", env, secret.Provider)
+				break
+			}
+		}
+		if key == "" {
+			if !isInteractiveTTY() {
+				return fmt.Errorf("%s needs an API key: set %s or run in a terminal", secret.Provider, strings.Join(secret.EnvVars, " or "))
+			}
+			if key, err = ui.AskText(fmt.Sprintf("%s API key", secret.Provider), "", true); err != nil {
+				return err
+			}
+			if strings.TrimSpace(key) == "" {
+				return fmt.Errorf("no API key entered for %s", secret.Provider)
+			}
+		}
+		cfg.SetSecret(secret.Provider, key)
+	}
+
+	if _, err := cfg.ValidateAndFix(); err != nil {
+		return err
+	}
+
+	if cfgPath, e := config.GetConfigPath(); e == nil {
+		if _, statErr := os.Stat(cfgPath); statErr == nil {
+			ts := time.Now().Format("20060102-150405")
+			backup := filepath.Join(filepath.Dir(cfgPath), fmt.Sprintf("config.template.%s.json", ts))
+			if err := os.Rename(cfgPath, backup); err != nil {
+				return fmt.Errorf("back up existing config: %w", err)
+			}
+			pterm.Warning.Printfln("Existing config moved to: %s", backup)
+		}
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	pterm.Success.Println("Configuration saved from template.")
+	return nil
+}
+
+// maybeOfferHookInstall notices when the user switched their default shell (e.g. chsh from bash
+// to zsh) and the new shell's rc file lacks the hook, and offers to install it with one keystroke
+// instead of capture silently stopping. A declined offer is remembered per shell.
+func maybeOfferHookInstall(cmd *cobra.Command) {
+	switch cmd {
+	case initCmd, uninstallCmd, captureCmd, rpcCmd, versionCmd, upgradeCmd, learnCmd:
+		return
+	}
+	if ui.IsJSONOutput() || ui.IsQuietOutput() || !isInteractiveTTY() {
+		return
+	}
+	gap, ok := shell.DetectHookGap()
+	if !ok || shell.HookOfferDeclined(gap.Shell) {
+		return
+	}
+
+	pterm.Warning.Printfln("Your default shell is now %s, but the aish hook is only in %s.", gap.Shell, gap.InstalledIn)
+	install, err := ui.AskConfirm(fmt.Sprintf("Install the hook into %s?", gap.RCFile), true)
+	if err != nil {
+		return
+	}
+	if !install {
+		_ = shell.RecordHookOfferDeclined(gap.Shell)
+		pterm.Info.Println("Okay, not asking again. Run 'aish init' to install it later.")
+		return
+	}
+	if err := shell.InstallHookForShell(gap.Shell); err != nil {
+		pterm.Error.Printfln("Failed to install shell hook: %v", err)
+		return
+	}
+	pterm.Success.Printfln("Hook installed. Open a new %s session or run 'source %s' to activate it.", gap.Shell, gap.RCFile)
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/shell"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var learnCmd = &cobra.Command{
+	Use:   "learn",
+	Short: "Take a guided tour of capture, ask, answer and settings",
+	Long: `Walks through what aish does, one lesson at a time: the shell hook that captures failed
+commands, generating commands from a prompt, plain-text answers and the settings.
+
+The tour is sandboxed: every response comes from the mock provider, nothing is sent over the
+network, no suggested command is run and your configuration is left unchanged. The only
+change it can make is installing the shell hook, and only when you agree.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runTutorial()
+	},
+}
+
+// tutorialLesson is one step of 'aish learn'.
+type tutorialLesson struct {
+	title string
+	run   func(ctx context.Context, p llm.Provider)
+}
+
+func runTutorial() {
+	// Canned responses keep the tour free, offline and identical for everyone
+	ui.SetDemoMode(true)
+	provider, err := getProvider(config.ProviderMock, config.ProviderConfig{})
+	if err != nil {
+		pterm.Error.Printfln("Failed to start the tutorial: %v", err)
+		os.Exit(1)
+	}
+	ctx := context.Background()
+
+	lessons := []tutorialLesson{
+		{"The shell hook", lessonHook},
+		{"Capture: explaining a failed command", lessonCapture},
+		{"Ask: generating a command", lessonAsk},
+		{"Answer: plain-text questions", lessonAnswer},
+		{"Settings", lessonSettings},
+	}
+	pterm.DefaultHeader.Println("Welcome to aish")
+	pterm.Println("This tour takes a few minutes. Responses are canned, nothing is executed and your settings stay as they are.")
+	for i, lesson := range lessons {
+		pterm.Println()
+		pterm.DefaultSection.Printfln("Lesson %d/%d: %s", i+1, len(lessons), lesson.title)
+		lesson.run(ctx, provider)
+		if i < len(lessons)-1 && !tutorialContinue() {
+			pterm.Info.Println("Tutorial stopped. Run 'aish learn' to start again.")
+			return
+		}
+	}
+	pterm.Println()
+	pterm.Success.Println("That's the tour. Run 'aish init' to connect a real provider if you have not yet.")
+}
+
+// tutorialContinue asks whether to go on to the next lesson; without a terminal the tour
+// simply runs through.
+func tutorialContinue() bool {
+	if !isInteractiveTTY() {
+		return true
+	}
+	next, err := ui.AskConfirm("Continue to the next lesson?", true)
+	return err == nil && next
+}
+
+// tutorialInput asks for a line of text, using defaultValue without a terminal.
+func tutorialInput(question, defaultValue string) string {
+	if !isInteractiveTTY() {
+		pterm.Printfln("%s: %s", question, defaultValue)
+		return defaultValue
+	}
+	answer, err := ui.AskText(question, defaultValue, false)
+	if err != nil || strings.TrimSpace(answer) == "" {
+		return defaultValue
+	}
+	return strings.TrimSpace(answer)
+}
+
+func lessonHook(_ context.Context, _ llm.Provider) {
+	pterm.Println("aish helps when a command fails because a small hook in your shell's startup file hands the")
+	pterm.Println("failed command and its output to 'aish capture'. Checking for it now...")
+
+	path, block, err := shell.InstalledHookSnippet()
+	if err != nil {
+		pterm.Warning.Printfln("Could not check for the hook: %v", err)
+		return
+	}
+	if block != "" {
+		pterm.Success.Printfln("The hook is installed in %s.", path)
+		if gap, ok := shell.DetectHookGap(); ok {
+			pterm.Warning.Printfln("Your default shell is %s, but the hook is only in %s. Run 'aish init' to add it to %s.", gap.Shell, gap.InstalledIn, gap.RCFile)
+		}
+		return
+	}
+
+	pterm.Warning.Println("The hook is not installed, so failed commands are not captured yet.")
+	shellName := filepath.Base(os.Getenv("SHELL"))
+	if (shellName != "bash" && shellName != "zsh" && shellName != "fish") || !isInteractiveTTY() {
+		pterm.Info.Println("Run 'aish init' to install it.")
+		return
+	}
+	install, err := ui.AskConfirm(fmt.Sprintf("Install the hook for %s now?", shellName), true)
+	if err != nil || !install {
+		pterm.Info.Println("Okay. Run 'aish init' to install it later.")
+		return
+	}
+	if err := shell.InstallHookForShell(shellName); err != nil {
+		pterm.Error.Printfln("Failed to install shell hook: %v", err)
+		return
+	}
+	// Verify rather than trust the install, since the rc file is what the shell reads
+	if path, block, err := shell.InstalledHookSnippet(); err == nil && block != "" {
+		pterm.Success.Printfln("Hook installed in %s. Open a new %s session or run 'source %s' to activate it.", path, shellName, path)
+		return
+	}
+	pterm.Warning.Println("The hook was written but could not be found afterwards. Run 'aish init' to install it.")
+}
+
+func lessonCapture(ctx context.Context, p llm.Provider) {
+	captured := llm.CapturedContext{
+		Command:  "cat notes.txt",
+		Stderr:   "cat: notes.txt: No such file or directory",
+		ExitCode: 1,
+	}
+	pterm.Println("Suppose you just ran this and it failed:")
+	pterm.Println()
+	pterm.Println(pterm.LightWhite("  $ " + captured.Command))
+	pterm.Println(pterm.LightWhite("  " + captured.Stderr))
+	pterm.Println()
+	pterm.Println("With the hook installed, aish sees the exit code and the error output and explains them:")
+	pterm.Println()
+
+	s, err := p.GetSuggestion(ctx, captured, "en")
+	if err != nil {
+		pterm.Error.Printfln("The mock provider failed: %v", err)
+		return
+	}
+	showTutorialSuggestion("AI Suggestion", s.Explanation, s.CorrectedCommand)
+	pterm.Println("Normally you would press Enter to run the fix, or type a new request to refine it.")
+	pterm.Println("Only some errors trigger aish; 'aish config' lists and changes them.")
+}
+
+func lessonAsk(ctx context.Context, p llm.Provider) {
+	pterm.Println("Describe what you want and 'aish -p' turns it into a command, for example:")
+	pterm.Println(pterm.LightWhite(`  aish -p "show disk space"`))
+	pterm.Println()
+	request := tutorialInput("Try it, describe a task", "show disk space")
+
+	command, err := p.GenerateCommand(ctx, request, "en")
+	if err != nil {
+		pterm.Error.Printfln("The mock provider failed: %v", err)
+		return
+	}
+	showTutorialSuggestion("Generated Command", "", command)
+	pterm.Println("Add -q to print only the command, e.g. for $(aish -q -p \"...\"). Commands you like can be")
+	pterm.Println("kept with 'aish snippet save <name>' and are offered again for the same request.")
+}
+
+func lessonAnswer(ctx context.Context, p llm.Provider) {
+	pterm.Println("Questions that are not about a command get a plain-text answer with 'aish -a':")
+	pterm.Println(pterm.LightWhite(`  aish -a "what is the difference between a process and a thread?"`))
+	pterm.Println()
+	question := tutorialInput("Try it, ask a question", "what does df stand for?")
+
+	answer, err := p.GenerateCommand(ctx, question, "en")
+	if err != nil {
+		pterm.Error.Printfln("The mock provider failed: %v", err)
+		return
+	}
+	if text, ok := extractEchoText(answer); ok {
+		answer = text
+	}
+	pterm.DefaultHeader.Println("AI Answer")
+	ui.PrintDemoWatermark()
+	pterm.Println(answer)
+	pterm.Println()
+	pterm.Println("A real provider answers the question itself; the tutorial only has canned responses.")
+}
+
+func lessonSettings(_ context.Context, _ llm.Provider) {
+	pterm.Println("Settings live in your config file and can be changed in three ways:")
+	_ = pterm.DefaultBulletList.WithItems([]pterm.BulletListItem{
+		{Level: 0, Text: "'aish config' opens an interactive settings editor"},
+		{Level: 0, Text: "'aish config show' prints the current configuration"},
+		{Level: 0, Text: "'aish config set <key> <value>' changes one setting, e.g. 'aish config set language en'"},
+	}).Render()
+
+	// config.Load would write a default file, which the tour must not do
+	path, err := config.GetConfigPath()
+	if err == nil {
+		_, err = os.Stat(path)
+	}
+	var cfg *config.Config
+	if err == nil {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		pterm.Info.Println("No configuration yet: run 'aish init' to choose a provider and create one.")
+		return
+	}
+	pterm.Println("Some of your current settings:")
+	provider := cfg.DefaultProvider
+	if provider == "" {
+		provider = "(none, run 'aish init')"
+	}
+	_ = pterm.DefaultBulletList.WithItems([]pterm.BulletListItem{
+		{Level: 0, Text: "Provider: " + provider},
+		{Level: 0, Text: "Language: " + cfg.UserPreferences.EffectiveLanguage()},
+		{Level: 0, Text: fmt.Sprintf("Auto-execute: %v", cfg.UserPreferences.AutoExecute)},
+	}).Render()
+}
+
+// showTutorialSuggestion prints a suggestion the way aish does, without offering to run it.
+func showTutorialSuggestion(title, explanation, command string) {
+	pterm.DefaultHeader.Println(title)
+	ui.PrintDemoWatermark()
+	if explanation != "" {
+		pterm.Println(pterm.Red("Explanation:"))
+		pterm.Println(explanation)
+		pterm.Println()
+	}
+	pterm.Println(pterm.Green("Suggested Command:"))
+	pterm.Println(pterm.LightGreen(command))
+	pterm.Println()
+}
+
+func init() {
+	rootCmd.AddCommand(learnCmd)
+}
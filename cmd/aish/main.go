@@ -2,9 +2,13 @@ package main
 
 import (
     "context"
+    "errors"
     "fmt"
     "os"
+    "os/exec"
     "os/signal"
+    "path/filepath"
+    "runtime/debug"
     "strconv"
     "strings"
     "syscall"
@@ -12,14 +16,22 @@ import (
 
 	"github.com/TonnyWong1052/aish/internal/classification"
 	"github.com/TonnyWong1052/aish/internal/config"
+	aishcontext "github.com/TonnyWong1052/aish/internal/context"
+	"github.com/TonnyWong1052/aish/internal/diagnostics"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
 	"github.com/TonnyWong1052/aish/internal/history"
 	"github.com/TonnyWong1052/aish/internal/llm"
-	_ "github.com/TonnyWong1052/aish/internal/llm/anthropic"
+	_ "github.com/TonnyWong1052/aish/internal/llm/claude"
 	_ "github.com/TonnyWong1052/aish/internal/llm/gemini"
 	_ "github.com/TonnyWong1052/aish/internal/llm/gemini-cli"
+	_ "github.com/TonnyWong1052/aish/internal/llm/mock"
 	_ "github.com/TonnyWong1052/aish/internal/llm/ollama"
 	_ "github.com/TonnyWong1052/aish/internal/llm/openai"
+	"github.com/TonnyWong1052/aish/internal/llm/relay"
+	_ "github.com/TonnyWong1052/aish/internal/llm/vertex"
 	"github.com/TonnyWong1052/aish/internal/prompt"
+	"github.com/TonnyWong1052/aish/internal/security"
+	"github.com/TonnyWong1052/aish/internal/shell"
 	"github.com/TonnyWong1052/aish/internal/ui"
 
 	"github.com/pterm/pterm"
@@ -37,18 +49,23 @@ Use 'aish init' to get started.
 Use 'aish ask "your question"' or 'aish -p "your question"' to generate a command.
 Use 'aish -a "your question"' to get a plain-text AI answer (no command suggestion).`,
     Example: `  aish -p "create a folder named logs"
+  aish -p "set up a python venv and install requirements" --plan
   aish -a "who are you?"
   aish ask "list files sorted by size"`,
     SilenceUsage:  true,  // avoid printing usage on errors we already handle
     SilenceErrors: true,  // let our UI/error handler own error messages
     CompletionOptions: cobra.CompletionOptions{
-        DisableDefaultCmd: true, // Disable auto-generated completion command
+        DisableDefaultCmd: true, // 'aish completion' (completion.go) replaces the generated one
     },
     Run: func(cmd *cobra.Command, args []string) {
         if flagAnswer != "" { // 新增：一般問答模式（純文字回答，不輸出建議指令）
             runAnswerLogic(flagAnswer)
             return
         }
+        if flagPrompt != "" && flagPlan { // 多步驟計畫：逐步確認並執行
+            runPlanLogic(flagPrompt)
+            return
+        }
         if flagPrompt != "" { // 既有：自然語言轉指令模式
             runPromptLogic(flagPrompt)
             return
@@ -71,6 +88,13 @@ var captureCmd = &cobra.Command{
 		}
 		commandStr := args[1]
 
+		presenter := ui.NewPresenter()
+		phases := llm.NewPhaseTracker(func(phase llm.Phase) {
+			presenter.SetLoadingPhase(string(phase))
+		})
+		phases.Start(llm.PhaseCollecting)
+		defer reportPhaseTimings(phases)
+
 		cfg, err := config.Load()
 		if err != nil || !cfg.Enabled {
 			return
@@ -82,14 +106,26 @@ var captureCmd = &cobra.Command{
 
 		classifier := classification.NewClassifier()
 		errorType := classifier.Classify(exitCode, stdoutStr, stderrStr)
-		_ = history.Add(history.Entry{
+		// Set by the hook (capped at 2KB there) when the command's name is an alias or function
+		expansion := os.Getenv(config.EnvAISHCommandExpansion)
+		var failedStage *llm.PipelineStage
+		if stage, ok := llm.FailedPipelineStage(commandStr, llm.ParsePipeStatus(os.Getenv(config.EnvAISHPipeStatus))); ok {
+			failedStage = &stage
+		}
+		// Stored once the capture is over, so it can say which provider answered and whether
+		// the suggestion was run
+		entry := history.Entry{
 			Timestamp: time.Now(),
 			Command:   commandStr,
 			Stdout:    stdoutStr,
 			Stderr:    stderrStr,
 			ExitCode:  exitCode,
 			ErrorType: errorType,
-		})
+		}
+		if failedStage != nil {
+			entry.FailedStage = failedStage.Index
+		}
+		defer func() { _ = history.Add(entry) }()
 
 		isErrorTypeEnabled := false
 		for _, enabledType := range cfg.UserPreferences.EnabledLLMTriggers {
@@ -102,17 +138,76 @@ var captureCmd = &cobra.Command{
 			return
 		}
 
-		providerName := effectiveProviderName(cfg)
-		providerCfg, ok := cfg.Providers[providerName]
+		// The missing command may just be installed somewhere PATH does not cover, or be one
+		// package install away; say so right away and tell the provider
+		var notes []string
+		if errorType == classification.CommandNotFound {
+			notes = showOffPathHint(commandStr, stderrStr)
+			if len(notes) == 0 {
+				installed, note := offerPackageInstall(commandStr, stderrStr)
+				if installed {
+					return
+				}
+				if note != "" {
+					notes = append(notes, note)
+				}
+			}
+			// Name the distribution and its package manager, so install advice does not default to Homebrew
+			if note, ok := aishcontext.DistroNote(); ok {
+				notes = append(notes, note)
+			}
+		}
+		if block, ok := classification.DetectGatekeeperBlock(commandStr, stdoutStr, stderrStr); ok {
+			showGatekeeperHint(block)
+			notes = append(notes, block.Note())
+		}
+		// Check DNS, the connection and any proxy locally, so the suggestion addresses the layer
+		// that actually fails
+		if errorType == classification.NetworkError {
+			if host, port := aishcontext.NetworkTarget(commandStr, stderrStr+"\n"+stdoutStr); host != "" {
+				checkCtx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+				notes = append(notes, aishcontext.CheckNetwork(checkCtx, host, port).Notes()...)
+				cancel()
+			}
+		}
+		if aishcontext.IsWSL() {
+			notes = append(notes, aishcontext.WSLNote())
+		}
+		// Tell the provider whether SELinux/AppArmor may be behind a permission error, so it
+		// does not default to chmod advice for a policy denial
+		if errorType == classification.PermissionDenied || errorType == classification.PermissionError {
+			if mac, ok := aishcontext.DetectMAC(commandName(commandStr), commandStartTime()); ok {
+				if len(mac.Denials) > 0 {
+					pterm.Info.Printfln("Security policy denial found in the audit log: %s", mac.Denials[len(mac.Denials)-1])
+				}
+				notes = append(notes, mac.Notes()...)
+			}
+		}
+		// What the user ran just before often explains the failure, e.g. a cd into the wrong
+		// directory or a build that never produced the file
+		if c := cfg.UserPreferences.Context; c.EnableEnhanced && c.MaxHistoryEntries > 0 {
+			var cmds []string
+			var err error
+			if session, ok := os.LookupEnv(config.EnvAISHSessionHistory); ok {
+				cmds = aishcontext.SessionCommands(session, c.MaxHistoryEntries, commandStr, c.FilterSensitiveCmd)
+			} else {
+				histfile := aishcontext.HistoryFile(os.Getenv(config.EnvAISHHistFile))
+				cmds, err = aishcontext.RecentCommands(histfile, c.MaxHistoryEntries, commandStr, c.FilterSensitiveCmd)
+			}
+			if err == nil && len(cmds) > 0 {
+				notes = append(notes, aishcontext.HistoryNote(cmds))
+			} else if err != nil && flagDebug {
+				fmt.Fprintf(os.Stderr, "DEBUG aish history: %v\n", err)
+			}
+		}
+
+		providerName := flowProviderName(cfg, config.FlowCapture)
+		providerCfg, ok := flowProviderConfig(cfg, config.FlowCapture, providerName)
 		if !ok || isProviderConfigIncomplete(providerName, providerCfg) {
 			errorHandler := ui.NewErrorHandler(flagDebug)
 		userErr := errorHandler.CreateConfigurationError(
 			"AISH is active, but no LLM provider is configured.",
-			[]string{
-				"Run 'aish init' to configure an LLM provider",
-				"Check your current configuration with 'aish config show'",
-				"Verify your API keys are correctly set",
-			},
+			noProviderSuggestions(cfg),
 		)
 		errorHandler.HandleError(userErr)
 			return
@@ -122,12 +217,33 @@ var captureCmd = &cobra.Command{
         if err != nil {
             return
         }
+        provider = cachedProvider(cfg, providerName, providerCfg, provider)
+        showTrialBanner(providerName)
+
+        // Skip a provider that failed moments ago instead of making the user wait for it again
+        healthPath, _ := llm.HealthStatePath()
+        health := llm.LoadHealthState(healthPath)
+        if h, skip := health.ShouldSkip(providerName, time.Now()); skip {
+            next, ok := healthyFallback(cfg, health, providerName)
+            if !ok {
+                showOfflineHint(providerName, h, errorType)
+                return
+            }
+            pterm.Info.Printfln("Skipping %s: it failed %s ago (%s); asking %s.",
+                providerName, time.Since(h.CheckedAt).Round(time.Second), h.Code, next.providerName)
+            providerName, provider = next.providerName, next.provider
+        }
 
         // 允許 Ctrl+C 取消生成,並確保不會殘留或重啟新的轉圈動畫
         ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
         defer stop()
+        parsed := &llm.ParseReport{}
+        ctx = llm.WithParseReport(withDebugReports(ctx), parsed)
 
-        presenter := ui.NewPresenter()
+        // Open the provider connection while the trigger list and spinner are being drawn
+        if cfg.UserPreferences.Warmup {
+            llm.StartWarmup(ctx, provider)
+        }
 
         // 顯示錯誤觸發器清單,標記當前捕獲的錯誤類型
         presenter.ShowErrorTriggersList(string(errorType), cfg.UserPreferences.EnabledLLMTriggers)
@@ -137,69 +253,108 @@ var captureCmd = &cobra.Command{
             // Spinner failed to start, but continue without it
             pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
         }
-        suggestion, err := provider.GetSuggestion(ctx, llm.CapturedContext{
-            Command:  commandStr,
-            Stdout:   stdoutStr,
-            Stderr:   stderrStr,
-            ExitCode: exitCode,
-        }, effectiveLanguage(cfg))
+        phases.Start(llm.PhaseContacting)
+        release := acquireRequestSlot(ctx, cfg)
+        captured := llm.CapturedContext{
+            Command:     commandStr,
+            Stdout:      stdoutStr,
+            Stderr:      stderrStr,
+            ExitCode:    exitCode,
+            FailedStage: failedStage,
+            Expansion:   expansion,
+            Notes:       notes,
+        }
+        recorder := sessionRecorder()
+        // Print the explanation as it streams in rather than behind the spinner
+        var stream *ui.StreamView
+        if isInteractiveTTY() {
+            stream = presenter.NewStreamView("Generated Command", "Explanation:", "explanation", nil)
+        }
+        suggestionFor := func(c llm.CapturedContext) func(context.Context, llm.Provider) (*llm.Suggestion, error) {
+            return func(ctx context.Context, p llm.Provider) (*llm.Suggestion, error) {
+                ctx = llm.WithSessionRecorder(llm.WithPhaseTracker(ctx, phases), recorder)
+                return p.GetSuggestionStream(ctx, c, effectiveLanguage(cfg), stream.Callback())
+            }
+        }
+        getSuggestion := suggestionFor(captured)
+        answered, err := awaitSuggestion(ctx, presenter, cfg, providerName, provider, stream, getSuggestion)
+        // A refusal by the content filter may go away once the output that tripped it is left out
+        answered, err = retrySanitized(ctx, presenter, cfg, answered, err, suggestionFor(llm.SanitizeContext(captured)))
+        // When the provider fails or times out, the fallback chain gets the request
+        chainFrom := answered.providerName
+        answered, err = tryFallbackChain(ctx, presenter, cfg, health, answered, err, getSuggestion)
+        release()
+        suggestion, providerName, provider := answered.suggestion, answered.providerName, answered.provider
+        streamedExplanation := ""
+        if suggestion != nil && err == nil {
+            streamedExplanation = suggestion.Explanation
+        }
+        explanationShown := stream.Finish(streamedExplanation)
+        saveSessionRecording(recorder, cfg, llm.SessionRecord{
+            Kind: llm.SessionSuggestion, Provider: providerName, Context: &captured, Suggestion: suggestion,
+        }, err)
 
-        if ctx.Err() != nil { // 使用者中斷
+        if ctx.Err() != nil || errors.Is(err, errWaitCancelled) { // 使用者中斷
             presenter.StopLoading(false)
             // 優雅結束：返回而非再次觸發 capture，避免重啟動畫
             return
         }
         if err != nil {
             presenter.StopLoading(false)
+            health.RecordFailure(providerName, err, time.Now())
+            _ = health.Save()
             errorHandler := ui.NewErrorHandler(flagDebug)
-            userErr := errorHandler.CreateProviderError(
-                "Failed to get AI suggestion for the error.",
-                []string{
-                    "Check your internet connection",
-     "Verify your LLM provider configuration",
-     "Try switching to a different provider with 'aish config set default_provider gemini-cli'",
-     "Check if you've exceeded API rate limits",
-    },
-   )
-   userErr.Cause = err
-   errorHandler.HandleError(userErr)
-   return
-        }
-  // Bug Fix: If provider returns (nil, nil), it would cause a panic.
-  // This ensures we handle cases where no suggestion is generated without an explicit error.
-  if suggestion == nil {
-   presenter.StopLoading(false)
-   errorHandler := ui.NewErrorHandler(flagDebug)
-   userErr := errorHandler.CreateProviderError(
-    "The AI provider returned an empty suggestion.",
-    []string{
-     "This can happen with certain errors or prompts.",
-     "Try a different prompt or check the provider's status.",
-     "You can also switch to another provider via 'aish config set default_provider <name>'.",
-    },
-   )
-   errorHandler.HandleError(userErr)
-   return
-  }
-
+            userErr := errorHandler.CreateProviderFailure(providerName, "Failed to get AI suggestion for the error.", err)
+            errorHandler.HandleError(userErr)
+            return
+        }
         presenter.StopLoading(true)
+        health.RecordSuccess(providerName, provider, time.Now())
+        _ = health.Save()
+        reportFallbackAnswer(chainFrom, providerName)
+        if answered.sanitized {
+            pterm.Info.Printfln("%s declined the full error output; this answer is based on the command and the end of its error only.", providerName)
+        }
+        recordParseMethod(providerName, parsed)
+        reportPhaseTimings(phases)
+        recordSuggestion(&entry, cfg, providerName, suggestion, answered.latency)
 
         // Add visual separator before AI analysis
-        pterm.Println()
+        if !explanationShown {
+            pterm.Println()
+        }
+
+        // A destructive suggestion the consensus provider answers differently is not offered on its own
+        if verdict, checked := checkConsensus(ctx, presenter, cfg, providerName, suggestion.CorrectedCommand,
+            func(ctx context.Context, p llm.Provider) (*llm.Suggestion, error) {
+                return p.GetSuggestion(ctx, captured, effectiveLanguage(cfg))
+            }); checked && verdict.disagreed() {
+            runChosenCandidate(cfg, verdict, ui.Candidate{Provider: providerName, Command: suggestion.CorrectedCommand, Explanation: suggestion.Explanation}, &entry)
+            return
+        }
 
+        // Follow-ups refine this suggestion, so they are sent with the failure and the earlier suggestions
+        var conv llm.Conversation
+        conv.Add("", suggestion.CorrectedCommand)
   for {
    // UI Alignment: Use "Generated Command" as title to match the -p flow.
    uiSuggestion := ui.Suggestion{
-    Title:       "Generated Command",
-    Explanation: suggestion.Explanation,
-    Command:     suggestion.CorrectedCommand,
+    Title:            "Generated Command",
+    Explanation:      suggestion.Explanation,
+    Command:          suggestion.CorrectedCommand,
+    ExplanationShown: explanationShown,
    }
+   explanationShown = false
    userInput, shouldContinue, err := presenter.Render(uiSuggestion)
    if err != nil || !shouldContinue {
 				return
 			}
 
             if userInput == "" {
+                if !confirmSuggestedCommand(cfg, suggestion.CorrectedCommand) {
+                    return
+                }
+                entry.Accepted = true
                 executeCommand(suggestion.CorrectedCommand)
                 break
             } else {
@@ -207,9 +362,21 @@ var captureCmd = &cobra.Command{
                 if err := presenter.ShowLoadingWithTimer("Command Generating"); err != nil {
                     pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
                 }
-                suggestion, err = provider.GetSuggestion(ctx, llm.CapturedContext{
-                    Command: userInput,
-                }, cfg.UserPreferences.Language)
+                release := acquireRequestSlot(ctx, cfg)
+                started := time.Now()
+                var stream *ui.StreamView
+                if isInteractiveTTY() {
+                    stream = presenter.NewStreamView("Generated Command", "Explanation:", "explanation", nil)
+                }
+                suggestion, err = provider.GetSuggestionStream(conv.Context(ctx), conv.FollowUp(captured, userInput), effectiveLanguage(cfg), stream.Callback())
+                release()
+                if err == nil && suggestion != nil {
+                    conv.Add(userInput, suggestion.CorrectedCommand)
+                    recordSuggestion(&entry, cfg, providerName, suggestion, time.Since(started))
+                    explanationShown = stream.Finish(suggestion.Explanation)
+                } else {
+                    stream.Finish("")
+                }
                 if ctx.Err() != nil { // 使用者中斷
                     presenter.StopLoading(false)
                     return
@@ -225,78 +392,255 @@ var captureCmd = &cobra.Command{
     },
 }
 
-// runPromptLogic is called by the 'ask' command.
-func runPromptLogic(promptStr string) {
-	cfg, err := config.Load()
-	if err != nil {
-		errorHandler := ui.NewErrorHandler(flagDebug)
-		userErr := errorHandler.CreateConfigurationError(
-			"Unable to load AISH configuration.",
-			[]string{
-				"Run 'aish init' to create initial configuration",
-				"Check if configuration file is corrupted",
-				"Verify ~/.config/aish/ directory permissions",
-			},
-		)
-		userErr.Cause = err
-		errorHandler.HandleError(userErr)
-		os.Exit(1)
+// recordSuggestion notes on a history entry which provider and model produced the suggestion
+// the user is shown, how long it took, and what it was.
+func recordSuggestion(entry *history.Entry, cfg *config.Config, providerName string, s *llm.Suggestion, latency time.Duration) {
+	entry.Provider = providerName
+	if pc, ok := flowProviderConfig(cfg, config.FlowCapture, providerName); ok {
+		entry.Model = pc.Model
 	}
+	entry.LatencyMs = latency.Milliseconds()
+	entry.SuggestedCommand = s.CorrectedCommand
+	entry.Explanation = s.Explanation
+}
 
-	var provider llm.Provider
-	providerName := effectiveProviderName(cfg)
-	if providerCfg, ok := cfg.Providers[providerName]; ok && !isProviderConfigIncomplete(providerName, providerCfg) {
-		if p, err := getProvider(providerName, providerCfg); err == nil {
-			provider = p
+// showOfflineHint replaces the AI analysis while a provider is marked unavailable, using the
+// built-in recovery suggestion for the captured error type.
+func showOfflineHint(providerName string, h llm.ProviderHealth, errorType classification.ErrorType) {
+    retryIn := h.SkipTTL() - time.Since(h.CheckedAt)
+    switch {
+    case h.ResetAt.IsZero():
+        pterm.Warning.Printfln("Skipping %s: it failed %s ago (%s); retrying in %s.",
+            providerName, time.Since(h.CheckedAt).Round(time.Second), h.Code, retryIn.Round(time.Second))
+    case h.Kind == string(llm.QuotaExceededError):
+        pterm.Warning.Printfln("Skipping %s: quota exceeded, resets in %s.", providerName, ui.WaitString(retryIn))
+    default:
+        pterm.Warning.Printfln("Skipping %s: rate limited, resets in %s.", providerName, ui.WaitString(retryIn))
+    }
+    if hint := classification.NewRecoveryManager(nil).GetSuggestion(errorType); hint != "" {
+        pterm.Info.Println(hint)
+    }
+    pterm.Info.Printfln("Updating the provider with 'aish config set providers.%s.<field>' clears this state.", providerName)
+}
+
+// showOffPathHint prints where the missing command of a CommandNotFound error exists outside
+// PATH, with the direct invocation and the PATH export that would fix it, and returns the same
+// facts as notes for the provider. It prints nothing when no such executable exists.
+func showOffPathHint(command, stderr string) []string {
+	name := aishcontext.MissingCommandName(command, stderr)
+	matches := aishcontext.FindOffPath(name)
+	if len(matches) == 0 {
+		return nil
+	}
+	m := matches[0]
+	direct := m.Path
+	if rest, ok := strings.CutPrefix(strings.TrimSpace(command), name); ok && (rest == "" || rest[0] == ' ') {
+		direct = shell.Quote(m.Path) + rest
+	}
+	pterm.Info.Printfln("'%s' is not on your PATH, but exists at %s", name, m.Path)
+	pterm.Printfln("  Run it directly:   %s", direct)
+	if !m.Local {
+		pterm.Printfln("  Or add it to PATH: %s", pathExport(m.Dir))
+	}
+
+	notes := make([]string, 0, len(matches))
+	for _, m := range matches {
+		notes = append(notes, fmt.Sprintf("%s is not on PATH but exists at %s", name, m.Path))
+	}
+	return notes
+}
+
+// commandName returns the base name of the program a command line runs, skipping sudo.
+func commandName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) > 1 && fields[0] == "sudo" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+// commandStartTime returns when the failed command started: the hook writes the command to
+// last_command in its state directory just before running it. Without that file, the last
+// minute is assumed.
+func commandStartTime() time.Time {
+	if dir, err := config.HookStateDir(); err == nil {
+		if info, err := os.Stat(filepath.Join(dir, "last_command")); err == nil {
+			return info.ModTime()
 		}
 	}
+	return time.Now().Add(-time.Minute)
+}
 
-	if provider == nil {
-		errorHandler := ui.NewErrorHandler(flagDebug)
-		userErr := errorHandler.CreateConfigurationError(
-			"No LLM provider configured or configuration incomplete.",
-			[]string{
-				"Run 'aish init' to configure an LLM provider",
-				"Check your current configuration with 'aish config show'",
-				"Verify your API keys are correctly set",
-			},
-		)
-		errorHandler.HandleError(userErr)
-		os.Exit(1)
+// showGatekeeperHint prints how to confirm and lift a macOS Gatekeeper/quarantine block.
+func showGatekeeperHint(block classification.GatekeeperBlock) {
+	pterm.Warning.Printfln("macOS Gatekeeper blocked %s: it is quarantined or from an unverified developer.", block.Path)
+	pterm.Printfln("  Check:  %s", strings.Join(block.Checks, "  /  "))
+	pterm.Printfln("  If you trust it, remove the quarantine: %s", block.Remediation[0])
+	pterm.Printfln("  Or allow it in System Settings → Privacy & Security → Open Anyway, or: %s", block.Remediation[1])
+}
+
+// offerPackageInstall shows the install command for the package that provides the missing
+// command of a CommandNotFound error and, on an interactive terminal, offers to run it. It
+// reports whether the command is now installed, in which case no analysis is needed, and a
+// note for the provider.
+func offerPackageInstall(command, stderr string) (bool, string) {
+	name := aishcontext.MissingCommandName(command, stderr)
+	if name == "" {
+		return false, ""
+	}
+	s, ok := aishcontext.SuggestInstall(name, stderr)
+	if !ok {
+		return false, ""
 	}
+	note := fmt.Sprintf("%s is provided by the %s package %s (install with: %s)", name, s.Manager, s.Package, s.Command)
+	pterm.Info.Printfln("'%s' is provided by the %s package '%s'", name, s.Manager, s.Package)
+	if s.Verified {
+		pterm.Printfln("  Install it with: %s", s.Command)
+	} else {
+		pterm.Printfln("  Install it with: %s  (not verified against your package lists)", s.Command)
+	}
+	if !isInteractiveTTY() {
+		return false, note
+	}
+	install, err := ui.AskConfirm("Run the install now?", false)
+	if err != nil || !install {
+		return false, note
+	}
+	if err := runInteractive(s.Command); err != nil {
+		pterm.Error.Printfln("Install failed: %v", err)
+		return false, note
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		pterm.Success.Printfln("Installed %s: %s", name, path)
+		return true, note
+	}
+	pterm.Warning.Printfln("The install finished, but '%s' is still not found on PATH.", name)
+	return false, note
+}
+
+// pathExport returns the shell line that puts dir in front of PATH, writing the home directory
+// as $HOME so it can be pasted into an rc file.
+func pathExport(dir string) string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if rel, ok := strings.CutPrefix(dir, home); ok && (rel == "" || rel[0] == os.PathSeparator) {
+			dir = "$HOME" + rel
+		}
+	}
+	return fmt.Sprintf(`export PATH="%s:$PATH"`, dir)
+}
+
+// runPromptLogic is called by the 'ask' command.
+func runPromptLogic(promptStr string) {
+	cfg, providerName, provider := loadAskProvider()
 
     // 支援 Ctrl+C 優雅取消
     ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
     defer stop()
+    parsed := &llm.ParseReport{}
+    ctx = llm.WithParseReport(withDebugReports(ctx), parsed)
 
-    presenter := ui.NewPresenter()
-    // Use consistent loading label across prompt and hook flows
-    if err := presenter.ShowLoadingWithTimer("Command Generating"); err != nil {
-        pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
+    if ui.IsQuietOutput() {
+        // Just the command on stdout, for $(aish -q -p "...") and pipelines; a saved recipe
+        // the prompt matches is expanded locally instead of asking the provider
+        cmdText, fromRecipe := expandRecipe(promptStr)
+        if !fromRecipe {
+            release := acquireRequestSlot(ctx, cfg)
+            recorder := sessionRecorder()
+            askedName := providerName
+            var err error
+            cmdText, providerName, provider, err = generateCommandWithFallback(llm.WithSessionRecorder(ctx, recorder), nil, cfg, providerName, provider, promptStr)
+            release()
+            saveSessionRecording(recorder, cfg, llm.SessionRecord{
+                Kind: llm.SessionCommand, Provider: providerName, Prompt: promptStr, Command: cmdText,
+            }, err)
+            if ctx.Err() != nil {
+                os.Exit(aerrors.ExitUserCancel)
+            }
+            if err != nil || strings.TrimSpace(cmdText) == "" {
+                exitWithGenerationError(providerName, "command", err)
+            }
+            reportFallbackAnswer(askedName, providerName)
+            recordParseMethod(providerName, parsed)
+        }
+        // The output is often eval'd, so there is no chance to confirm a dangerous or suspicious command
+        if reason, hint, refused := quietRefusal(cfg, cmdText); refused {
+            fmt.Fprintf(os.Stderr, "aish: not printing the generated command: it %s. %s\n", reason, hint)
+            os.Exit(aerrors.ExitProvider)
+        }
+        rememberGenerated(promptStr, cmdText)
+        fmt.Println(strings.TrimSpace(cmdText))
+        return
     }
 
-    cmdText, err := provider.GenerateCommand(ctx, promptStr, effectiveLanguage(cfg))
-    if ctx.Err() != nil { // 使用者中斷
-        presenter.StopLoading(false)
-        return
+    presenter := ui.NewPresenter()
+    // A saved recipe the prompt matches, or a snippet it asks for, is used instead of
+    // generating the command again
+    generatedCommand, fromSnippet := snippetForPrompt(promptStr)
+    if !fromSnippet {
+        // Use consistent loading label across prompt and hook flows
+        if err := presenter.ShowLoadingWithTimer("Command Generating"); err != nil {
+            pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
+        }
+
+        release := acquireRequestSlot(ctx, cfg)
+        recorder := sessionRecorder()
+        askedName := providerName
+        var cmdText string
+        var err error
+        cmdText, providerName, provider, err = generateCommandWithFallback(llm.WithSessionRecorder(ctx, recorder), presenter, cfg, providerName, provider, promptStr)
+        release()
+        saveSessionRecording(recorder, cfg, llm.SessionRecord{
+            Kind: llm.SessionCommand, Provider: providerName, Prompt: promptStr, Command: cmdText,
+        }, err)
+        if ctx.Err() != nil { // 使用者中斷
+            presenter.StopLoading(false)
+            os.Exit(aerrors.ExitUserCancel)
+        }
+        if err != nil || strings.TrimSpace(cmdText) == "" {
+            presenter.StopLoading(false)
+            exitWithGenerationError(providerName, "command", err)
+        }
+        presenter.StopLoading(true)
+        reportFallbackAnswer(askedName, providerName)
+        recordParseMethod(providerName, parsed)
+        generatedCommand = strings.TrimSpace(cmdText)
+        rememberGenerated(promptStr, generatedCommand)
     }
-    if err != nil || strings.TrimSpace(cmdText) == "" {
-        presenter.StopLoading(false)
-        if err != nil {
-            pterm.Error.Printfln("Failed to generate command: %v", err)
-        } else {
-            pterm.Error.Println("Provider returned empty command. Please refine your prompt or check provider configuration.")
-		}
-		os.Exit(1)
-	}
-	presenter.StopLoading(true)
-    generatedCommand := strings.TrimSpace(cmdText)
-    // Track the latest prompt that produced the current command
+    // Track the latest prompt that produced the current command, and the earlier ones so a
+    // follow-up such as "same but recursive" refines it
     currentPrompt := promptStr
+    var conv llm.Conversation
+    conv.Add(promptStr, generatedCommand)
 
-	// Check if auto-execute is enabled (command line arguments take priority over config file)
+	// The user saved the snippet themselves, so it needs no second opinion
+	var verdict consensus
+	checked := false
+	if !fromSnippet {
+		verdict, checked = checkConsensus(ctx, presenter, cfg, providerName, generatedCommand,
+			func(ctx context.Context, p llm.Provider) (*llm.Suggestion, error) {
+				cmdText, err := p.GenerateCommand(ctx, promptStr, effectiveLanguage(cfg))
+				return &llm.Suggestion{CorrectedCommand: cmdText}, err
+			})
+	}
+	if checked && verdict.disagreed() {
+		runChosenCandidate(cfg, verdict, ui.Candidate{Provider: providerName, Command: generatedCommand}, nil)
+		return
+	}
+
+	// Check if auto-execute is enabled (command line arguments take priority over config file);
+	// a destructive command is only auto-executed when the consensus provider agreed, and a
+	// dangerous one never is
 	shouldAutoExecute := flagAutoExecute || cfg.UserPreferences.AutoExecute
-	if shouldAutoExecute {
+	if reason, dangerous := security.Dangerous(generatedCommand); shouldAutoExecute && dangerous {
+		pterm.Info.Printfln("Not auto-executing a command that %s.", reason)
+	} else if reason, suspicious := suspiciousCommand(cfg, generatedCommand); shouldAutoExecute && suspicious {
+		pterm.Info.Printfln("Not auto-executing a command that %s.", reason)
+	} else if shouldAutoExecute && checked && !verdict.agreed {
+		pterm.Info.Println("Not auto-executing a destructive command that could not be double-checked.")
+	} else if shouldAutoExecute {
 		pterm.Info.Println("Auto-executing command...")
 		executeCommand(generatedCommand)
 		return
@@ -314,6 +658,9 @@ func runPromptLogic(promptStr string) {
 			return
 		}
 		if strings.TrimSpace(userInput) == "" {
+			if !confirmSuggestedCommand(cfg, generatedCommand) {
+				return
+			}
 			executeCommand(generatedCommand)
 			return
 		}
@@ -322,26 +669,68 @@ func runPromptLogic(promptStr string) {
         if err := presenter.ShowLoadingWithTimer("Command Generating"); err != nil {
             pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
         }
-        cmdText, err := provider.GenerateCommand(ctx, userInput, effectiveLanguage(cfg))
+        release := acquireRequestSlot(ctx, cfg)
+        cmdText, answeredBy, p, err := generateCommandWithFallback(conv.Context(ctx), presenter, cfg, providerName, provider, conv.Prompt(userInput))
+        release()
         if ctx.Err() != nil { // 使用者中斷
             presenter.StopLoading(false)
-            return
+            os.Exit(aerrors.ExitUserCancel)
         }
         if err != nil || strings.TrimSpace(cmdText) == "" {
             presenter.StopLoading(false)
-            if err != nil {
-                pterm.Error.Printfln("Failed to generate command: %v", err)
-            } else {
-                pterm.Error.Println("Provider returned empty command. Please refine your prompt or check provider configuration.")
-            }
-            os.Exit(1)
+            exitWithGenerationError(answeredBy, "command", err)
         }
         presenter.StopLoading(true)
+        reportFallbackAnswer(providerName, answeredBy)
+        providerName, provider = answeredBy, p
+        recordParseMethod(providerName, parsed)
         generatedCommand = strings.TrimSpace(cmdText)
         currentPrompt = strings.TrimSpace(userInput)
+        conv.Add(currentPrompt, generatedCommand)
+        rememberGenerated(currentPrompt, generatedCommand)
     }
 }
 
+// loadAskProvider loads the configuration and the provider of the ask flow (-p), exiting
+// with a configuration error when either is missing.
+func loadAskProvider() (*config.Config, string, llm.Provider) {
+	cfg, err := config.Load()
+	if err != nil {
+		errorHandler := ui.NewErrorHandler(flagDebug)
+		userErr := errorHandler.CreateConfigurationError(
+			"Unable to load AISH configuration.",
+			[]string{
+				"Run 'aish init' to create initial configuration",
+				"Check if configuration file is corrupted",
+				"Verify ~/.config/aish/ directory permissions",
+			},
+		)
+		userErr.Cause = err
+		errorHandler.HandleError(userErr)
+		os.Exit(userErr.ExitCode())
+	}
+
+	var provider llm.Provider
+	providerName := flowProviderName(cfg, config.FlowAsk)
+	if providerCfg, ok := flowProviderConfig(cfg, config.FlowAsk, providerName); ok && !isProviderConfigIncomplete(providerName, providerCfg) {
+		if p, err := getProvider(providerName, providerCfg); err == nil {
+			provider = cachedProvider(cfg, providerName, providerCfg, p)
+		}
+	}
+
+	if provider == nil {
+		errorHandler := ui.NewErrorHandler(flagDebug)
+		userErr := errorHandler.CreateConfigurationError(
+			"No LLM provider configured or configuration incomplete.",
+			noProviderSuggestions(cfg),
+		)
+		errorHandler.HandleError(userErr)
+		os.Exit(userErr.ExitCode())
+	}
+	showTrialBanner(providerName)
+	return cfg, providerName, provider
+}
+
 // runAnswerLogic 以一般問答模式處理使用者輸入，僅輸出純文字答案，不提供指令建議或執行。
 func runAnswerLogic(question string) {
     cfg, err := config.Load()
@@ -357,33 +746,31 @@ func runAnswerLogic(question string) {
         )
         userErr.Cause = err
         errorHandler.HandleError(userErr)
-        os.Exit(1)
+        os.Exit(userErr.ExitCode())
     }
 
     var provider llm.Provider
-    providerName := effectiveProviderName(cfg)
-    if providerCfg, ok := cfg.Providers[providerName]; ok && !isProviderConfigIncomplete(providerName, providerCfg) {
+    providerName := flowProviderName(cfg, config.FlowAsk)
+    if providerCfg, ok := flowProviderConfig(cfg, config.FlowAsk, providerName); ok && !isProviderConfigIncomplete(providerName, providerCfg) {
         if p, err := getProvider(providerName, providerCfg); err == nil {
-            provider = p
+            provider = cachedProvider(cfg, providerName, providerCfg, p)
         }
     }
     if provider == nil {
         errorHandler := ui.NewErrorHandler(flagDebug)
         userErr := errorHandler.CreateConfigurationError(
             "No LLM provider configured or configuration incomplete.",
-            []string{
-                "Run 'aish init' to configure an LLM provider",
-                "Check your current configuration with 'aish config show'",
-                "Verify your API keys are correctly set",
-            },
+            noProviderSuggestions(cfg),
         )
         errorHandler.HandleError(userErr)
-        os.Exit(1)
+        os.Exit(userErr.ExitCode())
     }
+    showTrialBanner(providerName)
 
     // 支援 Ctrl+C 優雅取消
     ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
     defer stop()
+    ctx = withDebugReports(ctx)
 
     presenter := ui.NewPresenter()
     if err := presenter.ShowLoadingWithTimer("Answer Generating"); err != nil {
@@ -391,34 +778,113 @@ func runAnswerLogic(question string) {
     }
 
     // 重用 GenerateCommand：若屬一般問答，提示模板會回傳 echo 指令，其內容即為答案。
-    cmdText, err := provider.GenerateCommand(ctx, question, effectiveLanguage(cfg))
+    release := acquireRequestSlot(ctx, cfg)
+    var stream *ui.StreamView
+    if isInteractiveTTY() {
+        stream = presenter.NewStreamView("AI Answer", "", "command", partialEchoText)
+    }
+    cmdText, err := provider.GenerateCommandStream(ctx, question, effectiveLanguage(cfg), stream.Callback())
+    release()
+    ans, isEcho := extractEchoText(cmdText)
+    if !isEcho || err != nil {
+        ans = ""
+    }
+    if stream.Finish(ans) {
+        return
+    }
     if ctx.Err() != nil { // 使用者中斷
         presenter.StopLoading(false)
-        return
+        os.Exit(aerrors.ExitUserCancel)
     }
     if err != nil || strings.TrimSpace(cmdText) == "" {
         presenter.StopLoading(false)
-        if err != nil {
-            pterm.Error.Printfln("Failed to get answer: %v", err)
-        } else {
-            pterm.Error.Println("Provider returned empty answer. Please refine your question or check provider configuration.")
-        }
-        os.Exit(1)
+        exitWithGenerationError(providerName, "answer", err)
     }
     presenter.StopLoading(true)
 
     // 嘗試從 echo 指令抽取文字內容
-    if ans, ok := extractEchoText(cmdText); ok {
+    if isEcho {
         pterm.DefaultHeader.Println("AI Answer")
+        ui.PrintDemoWatermark()
         pterm.Println(ans)
         return
     }
 
     // 若非 echo 指令，為避免顯示或執行指令，僅以純文字回應該指令字串
     pterm.DefaultHeader.Println("AI Answer")
+    ui.PrintDemoWatermark()
     pterm.Println(cmdText)
 }
 
+// generateCommandWithFallback asks provider for a command for prompt and, when it fails or
+// times out, the providers of the fallback chain in turn (see tryFallbackChain). It returns the command with the name
+// and provider that produced it; presenter may be nil when no loading line is shown.
+func generateCommandWithFallback(ctx context.Context, presenter *ui.Presenter, cfg *config.Config,
+    providerName string, provider llm.Provider, prompt string) (string, string, llm.Provider, error) {
+
+    generate := func(ctx context.Context, p llm.Provider) (*llm.Suggestion, error) {
+        cmdText, err := p.GenerateCommand(ctx, prompt, effectiveLanguage(cfg))
+        return &llm.Suggestion{CorrectedCommand: cmdText}, err
+    }
+    s, err := generate(ctx, provider)
+    if err == nil || (len(cfg.UserPreferences.FallbackProviders) == 0 && cfg.UserPreferences.RateLimitWaitSeconds <= 0) {
+        return s.CorrectedCommand, providerName, provider, err
+    }
+
+    healthPath, _ := llm.HealthStatePath()
+    health := llm.LoadHealthState(healthPath)
+    answered, err := tryFallbackChain(ctx, presenter, cfg, health, answer{suggestion: s, providerName: providerName, provider: provider}, err, generate)
+    if err == nil {
+        health.RecordSuccess(answered.providerName, answered.provider, time.Now())
+    } else if ctx.Err() == nil {
+        health.RecordFailure(answered.providerName, err, time.Now())
+    }
+    _ = health.Save()
+    return answered.suggestion.CorrectedCommand, answered.providerName, answered.provider, err
+}
+
+// exitWithGenerationError reports a failed or empty generation of the given kind ("command", "plan" or "answer")
+// and exits with the exit code matching the provider failure.
+func exitWithGenerationError(providerName, kind string, err error) {
+    errorHandler := ui.NewErrorHandler(flagDebug)
+    var userErr *ui.UserFriendlyError
+    if err != nil {
+        userErr = errorHandler.CreateProviderFailure(providerName, fmt.Sprintf("Failed to generate %s: %v", kind, err), err)
+    } else {
+        userErr = errorHandler.CreateProviderError(
+            fmt.Sprintf("Provider returned empty %s.", kind),
+            []string{
+                "Refine your prompt",
+                "Check the provider configuration with 'aish config show'",
+            },
+        )
+        userErr.Code = aerrors.ErrProviderResponse
+    }
+    errorHandler.HandleError(userErr)
+    os.Exit(userErr.ExitCode())
+}
+
+// partialEchoText 回傳串流中尚未完成的 echo 指令目前可顯示的文字：去掉 echo、旗標與開頭引號，
+// 以及結尾可能屬於收尾的引號、分號與空白。非 echo 指令回傳空字串，待完整回應後再顯示。
+func partialEchoText(cmd string) string {
+    s := strings.TrimSpace(cmd)
+    if !strings.HasPrefix(strings.ToLower(s), "echo ") {
+        return ""
+    }
+    body := strings.TrimSpace(s[len("echo "):])
+    for strings.HasPrefix(body, "-") {
+        parts := strings.Fields(body)
+        if len(parts) <= 1 {
+            return ""
+        }
+        body = strings.TrimSpace(strings.TrimPrefix(body, parts[0]))
+    }
+    if body != "" && (body[0] == '\'' || body[0] == '"') {
+        body = body[1:]
+    }
+    return strings.TrimRight(body, "'\"; \t\r\n")
+}
+
 // extractEchoText 嘗試從 echo/printf 形式的指令中抽取被引號包裹的文字內容。
 // 支援：echo '...'
 //      echo "..."
@@ -494,21 +960,60 @@ func lastQuotedSegment(in string) (string, bool) {
 }
 
 func getProvider(providerName string, cfg config.ProviderConfig) (llm.Provider, error) {
+	// Refuse keys any user could have read, and so could have leaked, before sending them
+	if err := cfg.CheckCredentialFiles(); err != nil {
+		pterm.Error.Println(err.Error())
+		return nil, err
+	}
+	return buildProvider(providerName, cfg)
+}
+
+// buildProvider is getProvider without the credential file check, for providers that send
+// nothing (replay).
+func buildProvider(providerName string, cfg config.ProviderConfig) (llm.Provider, error) {
 	pm, err := prompt.NewManager("prompts.json")
-	if err != nil {
+	var lintErr *prompt.LintError
+	switch {
+	case errors.As(err, &lintErr):
+		// A prompts file whose templates cannot run is an error, not something to silently replace;
+		// callers only check for failure, so say what is wrong here
+		pterm.Error.Printfln("Invalid prompt templates: %v", err)
+		return nil, fmt.Errorf("invalid prompt templates: %w", err)
+	case err != nil:
 		pm = prompt.NewDefaultManager()
 	}
+	pm.SetFallbackChain(languageFallbackChain())
 	return llm.GetProvider(providerName, cfg, pm)
 }
 
+// languageFallbackChain returns the configured prompt language fallback chain. A missing
+// config file is not created here, since providers are also built without one (learn, replay).
+func languageFallbackChain() []string {
+	path, err := config.GetConfigPath()
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil
+	}
+	return cfg.UserPreferences.LanguageFallback
+}
+
 func isProviderConfigIncomplete(providerName string, cfg config.ProviderConfig) bool {
     switch providerName {
     case config.ProviderOpenAI:
+        if cfg.APIKeyFile != "" {
+            return false
+        }
         return cfg.APIKey == "" || cfg.APIKey == "YOUR_OPENAI_API_KEY"
     case config.ProviderGemini:
         return cfg.APIKey == "" || cfg.APIKey == "YOUR_GEMINI_API_KEY"
     case config.ProviderGeminiCLI:
-        // 放寬檢查：允許在執行期自動解析（僅從 ~/.config/aish/gemini_oauth_creds.json）
+        // 放寬檢查：允許在執行期自動解析（僅從目前 profile 的 gemini_oauth_creds.json）
         // 即使 Project 未在 config 中，仍視為可啟動，交由 provider 解析
         return false
     case config.ProviderClaude:
@@ -518,6 +1023,11 @@ func isProviderConfigIncomplete(providerName string, cfg config.ProviderConfig)
         // Ollama doesn't require API key (local service)
         // Only check if model is configured
         return cfg.Model == ""
+    case config.ProviderVertex:
+        // Project and credentials may come from ADC, resolved when the provider runs
+        return false
+    case config.ProviderMock, config.ProviderRelay:
+        return false
     default:
         return true
     }
@@ -526,8 +1036,17 @@ func isProviderConfigIncomplete(providerName string, cfg config.ProviderConfig)
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Prints the version number of aish",
+	Long: `Prints the version number of aish. With --check, also asks GitHub for the latest
+release and says whether an upgrade is available.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if flagVersionBackground {
+			recordLatestRelease()
+			return
+		}
 		fmt.Println("aish", versionString())
+		if flagVersionCheck {
+			runVersionCheck()
+		}
 	},
 }
 
@@ -539,13 +1058,23 @@ func init() {
 	rootCmd.AddCommand(historyCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(captureCmd)
 }
 
 func main() {
+	defer recoverFromPanic()
+	llm.SetVersion(versionString())
+	rootCmd.SetArgs(quietAfterPrompt(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		if ui.IsJSONOutput() {
+			_ = aerrors.NewReport(aerrors.ErrUserInput, err.Error(), nil).WriteJSON(os.Stdout)
+		} else {
+			fmt.Println(err)
+		}
+		os.Exit(aerrors.ExitCode(err))
 	}
 }
 
@@ -557,6 +1086,13 @@ var (
     flagPrompt      string
     flagAnswer      string
     flagAutoExecute bool // New auto-execute flag
+    flagPlain       bool // Plain output: no colors, spinners or box drawing
+    flagOutput      string // Output format: text or json
+    flagConfigDir   string // Directory for config, history, logs and cache (overrides XDG locations)
+    flagProfile     string // Credential profile, e.g. work or personal
+    flagDemo        bool   // Classroom/demo mode
+    flagQuiet       bool   // Print only the generated command
+    flagPlan        bool   // Generate a multi-step plan instead of one command
 )
 
 // versionString is injected by ldflags: -X 'main._version=vX.Y.Z'
@@ -573,30 +1109,275 @@ func init() {
     rootCmd.PersistentFlags().BoolVar(&flagAutoExecute, "auto-execute", false, "(deprecated) use --auto instead")
     _ = rootCmd.PersistentFlags().MarkDeprecated("auto-execute", "use --auto instead")
     _ = rootCmd.PersistentFlags().MarkHidden("auto-execute")
+    rootCmd.PersistentFlags().BoolVar(&flagPlain, "plain", false, "plain output without colors, spinners or box drawing (also enabled by NO_COLOR)")
+    rootCmd.PersistentFlags().BoolVar(&flagPlain, "no-color", false, "alias of --plain")
+    rootCmd.PersistentFlags().StringVar(&flagOutput, "output", ui.OutputText, "output format for errors: text or json")
+    rootCmd.PersistentFlags().BoolVar(&flagDemo, "demo", false, "classroom/demo mode: canned mock responses, hidden secrets and watermarked output (also AISH_DEMO_MODE=1)")
+    rootCmd.PersistentFlags().StringVar(&flagConfigDir, "config-dir", "", "directory for config, history, logs and cache (also AISH_CONFIG_DIR)")
+    rootCmd.PersistentFlags().StringVar(&flagProfile, "profile", "", "credential profile to sign in to Google with, e.g. work or personal (also AISH_PROFILE)")
+    _ = rootCmd.RegisterFlagCompletionFunc("provider", completeProviders)
+    _ = rootCmd.RegisterFlagCompletionFunc("lang", completeLanguages)
     rootCmd.Flags().StringVarP(&flagPrompt, "prompt", "p", "", "generates a command from a natural language prompt")
     rootCmd.Flags().StringVarP(&flagAnswer, "answer", "a", "", "answer a general question with plain text")
+    rootCmd.Flags().BoolVarP(&flagQuiet, "quiet", "q", false, "with -p, print only the generated command (no explanation, spinner or prompt)")
+    rootCmd.Flags().BoolVar(&flagPlan, "plan", false, "with -p, generate an ordered plan of several commands and confirm them step by step")
 
 	// Enable debug mode (affects all subcommands)
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if flagConfigDir != "" {
+			if err := config.SetConfigDir(flagConfigDir); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid --config-dir %q: %v\n", flagConfigDir, err)
+				os.Exit(aerrors.ExitCodeFor(aerrors.ErrUserInput))
+			}
+		}
+		if flagProfile != "" {
+			if err := config.SetProfile(flagProfile); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid --profile: %v\n", err)
+				os.Exit(aerrors.ExitCodeFor(aerrors.ErrUserInput))
+			}
+		}
+		// Completion output is read by the shell; notices, cleanup and prompts have no place in it
+		if isCompletionCommand(cmd) {
+			return
+		}
 		if flagDebug {
 			os.Setenv(config.EnvAISHDebug, "1")
 		}
+		if !ui.SetOutputFormat(flagOutput) {
+			fmt.Fprintf(os.Stderr, "invalid --output %q: use text or json\n", flagOutput)
+			os.Exit(aerrors.ExitCodeFor(aerrors.ErrUserInput))
+		}
+		if flagPlain || ui.NoColorRequested() || ui.IsJSONOutput() {
+			ui.SetPlainOutput(true)
+		}
+		applyAccessibilityPreferences()
+		applyNetworkPreferences()
+		if flagQuiet {
+			ui.SetQuietOutput(true)
+		}
+		if flagDemo {
+			// Exported so a hook-triggered 'aish capture' started from this process stays in demo mode
+			os.Setenv(config.EnvAISHDemoMode, "1")
+		}
+		ui.SetDemoMode(demoModeRequested())
+		runStartupCleanup(cmd)
+		auditCredentialPermissions(cmd)
+		maybeNotifyUpdate(cmd)
+		maybeOfferHookInstall(cmd)
+		recordFeatureUsage(cmd)
+	}
+
+}
+
+// quietAfterPrompt moves -q/--quiet in front of -p/--prompt, so the documented
+// 'aish -p -q "..."' works instead of -q being taken as the prompt.
+func quietAfterPrompt(args []string) []string {
+	out := append([]string(nil), args...)
+	for i := 0; i+1 < len(out); i++ {
+		if (out[i] == "-p" || out[i] == "--prompt") && (out[i+1] == "-q" || out[i+1] == "--quiet") {
+			out[i], out[i+1] = out[i+1], out[i]
+			i++
+		}
+	}
+	return out
+}
+
+// recoverFromPanic turns a panic into a crash report under the log directory and a short
+// message pointing at it, instead of dumping a raw Go stack trace onto the user's terminal.
+func recoverFromPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+
+	dir := os.TempDir()
+	if logDir, err := config.LogDir(); err == nil {
+		dir = logDir
+	}
+	path, err := diagnostics.WriteCrashReport(dir, versionString(), r, stack, os.Args)
+
+	if ui.IsJSONOutput() {
+		report := aerrors.NewReport(aerrors.ErrInternal, "aish crashed unexpectedly", nil)
+		if err == nil {
+			report.Details = "crash report: " + path
+		}
+		_ = report.WriteJSON(os.Stdout)
+		os.Exit(report.ExitCode)
 	}
 
+	// Start on a fresh line in case a loading animation was mid-frame
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "aish crashed unexpectedly. This is a bug, sorry about that.")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write a crash report (%v): %v\n", err, r)
+	} else {
+		fmt.Fprintf(os.Stderr, "A crash report was saved to %s\n", path)
+		fmt.Fprintln(os.Stderr, "Please attach it (and 'aish debug bundle' output) when reporting the issue.")
+	}
+	os.Exit(aerrors.ExitCodeFor(aerrors.ErrInternal))
+}
+
+// applyAccessibilityPreferences switches the UI into screen-reader mode when the user enabled it
+// and tells it the display language so Arabic and Hebrew text is laid out right to left.
+func applyAccessibilityPreferences() {
+	ui.SetLanguage(flagLang)
+	cfg, ok := existingConfig()
+	if !ok {
+		return
+	}
+	if cfg.UserPreferences.Accessibility.ScreenReader {
+		ui.SetScreenReaderMode(true)
+	}
+	ui.SetAnimations(cfg.UserPreferences.UI.AnimationsEnabled())
+	ui.SetCommandPreview(cfg.UserPreferences.CommandPreview)
+	if err := ui.SetOutputTemplate(cfg.UserPreferences.UI.OutputTemplate); err != nil {
+		fmt.Fprintf(os.Stderr, "aish: ignoring invalid ui.output_template: %v\n", err)
+	}
+	ui.SetLanguage(effectiveLanguage(cfg))
+}
+
+// applyNetworkPreferences routes every request aish makes, to providers as well as the Google
+// sign-in, token refresh and revocation, through the configured proxy.
+func applyNetworkPreferences() {
+	cfg, ok := existingConfig()
+	if !ok {
+		return
+	}
+	if err := llm.SetProxy(cfg.UserPreferences.Proxy); err != nil {
+		fmt.Fprintf(os.Stderr, "aish: ignoring invalid proxy: %v\n", err)
+	}
+}
+
+// existingConfig loads the config for the setup steps of every command. It only reads an
+// existing config so that commands like 'aish version' never create one.
+func existingConfig() (*config.Config, bool) {
+	path, err := config.GetConfigPath()
+	if err != nil {
+		return nil, false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, false
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, false
+	}
+	return cfg, true
+}
+
+// acquireRequestSlot waits for a free slot of the machine-wide cap on concurrent provider
+// requests and returns the function releasing it. Problems with the lock files never block
+// the request; they only lift the cap.
+func acquireRequestSlot(ctx context.Context, cfg *config.Config) func() {
+	limit := cfg.UserPreferences.MaxConcurrentRequests
+	if limit == 0 {
+		limit = llm.DefaultMaxConcurrentRequests
+	}
+	dir, err := llm.RequestSlotDir()
+	if err != nil {
+		return func() {}
+	}
+	slot, err := llm.AcquireRequestSlot(ctx, dir, limit)
+	if err != nil {
+		return func() {}
+	}
+	return slot.Release
+}
+
+// reportPhaseTimings prints how long each analysis phase took when debugging. The tracker
+// is reset, so a deferred call after an earlier report prints nothing.
+func reportPhaseTimings(phases *llm.PhaseTracker) {
+	timings := phases.Finish()
+	if len(timings) == 0 {
+		return
+	}
+	if flagDebug || os.Getenv(config.EnvAISHDebug) != "" {
+		fmt.Fprintf(os.Stderr, "DEBUG aish phases: %s\n", llm.FormatPhaseTimings(timings))
+	}
+}
+
+// withDebugReports makes every provider request under ctx print its token counts, cost and
+// HTTP timing when debugging.
+func withDebugReports(ctx context.Context) context.Context {
+	if !flagDebug && os.Getenv(config.EnvAISHDebug) == "" {
+		return ctx
+	}
+	ctx = llm.WithHTTPTimingReporter(ctx, func(t llm.HTTPTiming) {
+		fmt.Fprintf(os.Stderr, "DEBUG aish http: %s\n", llm.FormatHTTPTiming(t))
+	})
+	return llm.WithUsageReporter(ctx, func(u llm.Usage) {
+		fmt.Fprintf(os.Stderr, "DEBUG aish usage: %s\n", llm.FormatUsage(u))
+	})
+}
+
+// demoModeRequested reports whether --demo or AISH_DEMO_MODE asks for demo mode.
+func demoModeRequested() bool {
+	if flagDemo {
+		return true
+	}
+	v := strings.TrimSpace(strings.ToLower(os.Getenv(config.EnvAISHDemoMode)))
+	return v == "1" || v == "true" || v == "yes"
 }
 
 func effectiveProviderName(cfg *config.Config) string {
+	if ui.IsDemoMode() {
+		return config.ProviderMock
+	}
 	if strings.TrimSpace(flagProvider) != "" {
 		return flagProvider
 	}
-	return cfg.DefaultProvider
+	return trialProviderName(cfg, cfg.DefaultProvider)
+}
+
+// trialProviderName returns the trial relay in place of provider name when the user opted into
+// trial mode ('aish trial') and name is not configured yet, so the trial ends by itself once a
+// provider of their own is.
+func trialProviderName(cfg *config.Config, name string) string {
+	if !cfg.UserPreferences.TrialRelay || !relay.Available() {
+		return name
+	}
+	if pc, ok := cfg.Providers[name]; ok && !isProviderConfigIncomplete(name, pc) {
+		return name
+	}
+	return config.ProviderRelay
+}
+
+// flowProviderName is effectiveProviderName for a flow that may be pinned to its own provider
+// in the routing section of the configuration. Demo mode and --provider still take precedence.
+func flowProviderName(cfg *config.Config, flow string) string {
+	if ui.IsDemoMode() || strings.TrimSpace(flagProvider) != "" {
+		return effectiveProviderName(cfg)
+	}
+	provider, _ := cfg.Route(flow)
+	return trialProviderName(cfg, provider)
+}
+
+// flowProviderConfig returns the configuration of the named provider with the model pinned for
+// flow, if the flow routes to that provider and pins one.
+func flowProviderConfig(cfg *config.Config, flow, name string) (config.ProviderConfig, bool) {
+	pc, ok := effectiveProviderConfig(cfg, name)
+	if provider, model := cfg.Route(flow); ok && provider == name && model != "" {
+		pc.Model = model
+	}
+	return pc, ok
+}
+
+// effectiveProviderConfig returns the configuration of the named provider. The mock provider
+// and the trial relay need none, so they are always available.
+func effectiveProviderConfig(cfg *config.Config, name string) (config.ProviderConfig, bool) {
+	if name == config.ProviderMock || name == config.ProviderRelay {
+		return cfg.Providers[name], true
+	}
+	pc, ok := cfg.Providers[name]
+	return pc, ok
 }
 
 func effectiveLanguage(cfg *config.Config) string {
 	if strings.TrimSpace(flagLang) != "" {
 		return flagLang
 	}
-	return cfg.UserPreferences.Language
+	return cfg.UserPreferences.EffectiveLanguage()
 }
 
 func versionString() string {
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm/gemini/auth"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// auditCredentialPermissions warns about config and credential files other users can access
+// and, with fix_credential_permissions set, restricts them to their owner. Keys read from a
+// file every user can read are refused when sent (see config.CheckSecretFile), so the warning
+// comes before a request fails.
+func auditCredentialPermissions(cmd *cobra.Command) {
+	switch cmd {
+	case captureCmd, rpcCmd:
+		return
+	}
+	path, err := config.GetConfigPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	issues := config.AuditPermissions(credentialFiles(cfg))
+	if len(issues) == 0 {
+		return
+	}
+
+	if cfg.UserPreferences.FixCredentialPermissions {
+		errs := config.FixPermissions(issues)
+		if !ui.IsQuietOutput() && !ui.IsJSONOutput() {
+			for _, issue := range issues {
+				pterm.Info.Printfln("Restricted %s to its owner (was %04o).", issue.Path, issue.Mode)
+			}
+		}
+		for _, err := range errs {
+			pterm.Warning.Printfln("Could not restrict a credential file: %v", err)
+		}
+		return
+	}
+	if ui.IsQuietOutput() || ui.IsJSONOutput() {
+		return
+	}
+	for _, issue := range issues {
+		if issue.WorldReadable() {
+			pterm.Warning.Printfln("%s is readable by every user (mode %04o); keys in it will not be sent.", issue.Path, issue.Mode)
+		} else {
+			pterm.Warning.Printfln("%s is accessible by other users (mode %04o).", issue.Path, issue.Mode)
+		}
+	}
+	pterm.Info.Println("Restrict them with 'chmod 600 <file>', or let aish do it: aish config set fix_credential_permissions true")
+}
+
+// credentialFiles lists the files holding keys or tokens: those of the configuration and the
+// gemini-cli sign-in of the active profile, including the gemini-cli files it may fall back to.
+func credentialFiles(cfg *config.Config) []string {
+	files := cfg.CredentialFiles()
+	if path, err := auth.CredentialsPath(); err == nil {
+		files = append(files, path)
+	}
+	if home, err := os.UserHomeDir(); err == nil && auth.SystemCredentialsAllowed() {
+		files = append(files, filepath.Join(home, ".gemini", "oauth_creds.json"), filepath.Join(home, ".gemini", "access_token"))
+	}
+	return files
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+)
+
+// runPlanLogic handles 'aish -p "..." --plan': it asks for an ordered plan of commands, shows
+// it, and runs the steps one at a time after each is confirmed, stopping at the first step
+// that fails.
+func runPlanLogic(promptStr string) {
+	cfg, providerName, provider := loadAskProvider()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	parsed := &llm.ParseReport{}
+	ctx = llm.WithParseReport(withDebugReports(ctx), parsed)
+
+	presenter := ui.NewPresenter()
+	if !ui.IsQuietOutput() {
+		if err := presenter.ShowLoadingWithTimer("Plan Generating"); err != nil {
+			pterm.Warning.Printfln("Warning: Could not start loading animation: %v", err)
+		}
+	}
+	release := acquireRequestSlot(ctx, cfg)
+	recorder := sessionRecorder()
+	plan, err := llm.GeneratePlan(llm.WithSessionRecorder(ctx, recorder), provider, promptStr, effectiveLanguage(cfg))
+	release()
+	var commands []string
+	if plan != nil {
+		for _, s := range plan.Steps {
+			commands = append(commands, s.Command)
+		}
+	}
+	saveSessionRecording(recorder, cfg, llm.SessionRecord{
+		Kind: llm.SessionCommand, Provider: providerName, Prompt: promptStr, Command: strings.Join(commands, " && "),
+	}, err)
+	if ctx.Err() != nil {
+		presenter.StopLoading(false)
+		os.Exit(aerrors.ExitUserCancel)
+	}
+	if err != nil {
+		presenter.StopLoading(false)
+		exitWithGenerationError(providerName, "plan", err)
+	}
+	presenter.StopLoading(true)
+	recordParseMethod(providerName, parsed)
+	rememberGenerated(promptStr, strings.Join(commands, " && "))
+
+	if ui.IsQuietOutput() {
+		// The steps chained with &&, which keeps the stop-on-failure of the interactive run
+		for _, c := range commands {
+			if reason, hint, refused := quietRefusal(cfg, c); refused {
+				fmt.Fprintf(os.Stderr, "aish: not printing the generated plan: a step %s. %s\n", reason, hint)
+				os.Exit(aerrors.ExitProvider)
+			}
+		}
+		fmt.Println(strings.Join(commands, " && "))
+		return
+	}
+
+	steps := make([]ui.PlanStep, len(plan.Steps))
+	for i, s := range plan.Steps {
+		steps[i] = ui.PlanStep{Command: s.Command, Explanation: s.Explanation}
+	}
+	presenter.RenderPlan("Command Plan", steps)
+	runPlan(cfg, presenter, steps, flagAutoExecute || cfg.UserPreferences.AutoExecute)
+}
+
+// runPlan confirms and runs steps in order. With autoExecute the steps run without asking,
+// except those confirmSuggestedCommand stops for. The first failing step ends the run with
+// its exit status.
+func runPlan(cfg *config.Config, presenter *ui.Presenter, steps []ui.PlanStep, autoExecute bool) {
+	ran, skipped := 0, 0
+	for i, step := range steps {
+		if !autoExecute {
+			action, err := presenter.ConfirmStep(i, len(steps), step)
+			if err != nil {
+				pterm.Error.Println(err)
+				os.Exit(aerrors.ExitUserCancel)
+			}
+			switch action {
+			case ui.StepSkip:
+				skipped++
+				continue
+			case ui.StepQuit:
+				pterm.Warning.Printfln("Stopped before step %d/%d.", i+1, len(steps))
+				return
+			}
+		}
+		if !confirmSuggestedCommand(cfg, step.Command) {
+			pterm.Warning.Printfln("Stopped before step %d/%d.", i+1, len(steps))
+			return
+		}
+		if err := runCommand(step.Command); err != nil {
+			remaining := len(steps) - i - 1
+			pterm.Error.Printfln("Step %d/%d failed: %v", i+1, len(steps), err)
+			if remaining > 0 {
+				pterm.Info.Printfln("Not running the remaining %d step(s).", remaining)
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+				os.Exit(exitErr.ExitCode())
+			}
+			os.Exit(1)
+		}
+		ran++
+	}
+	if skipped > 0 {
+		pterm.Success.Printfln("Plan finished: %d step(s) run, %d skipped.", ran, skipped)
+		return
+	}
+	pterm.Success.Printfln("Plan finished: all %d step(s) run.", ran)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "Re-run the parsing of a recorded session without the network",
+	Long: `Re-runs a session recorded with AISH_RECORD_SESSION=<file>: the recorded captured context
+(or prompt) is rendered with the current prompt templates, the recorded raw provider response is
+parsed by the same provider code, and the result is shown next to what was recorded. No request
+is sent, so a recording attached to a bug report reproduces the parsing and display anywhere.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		rec, err := llm.LoadSessionRecord(args[0])
+		if err != nil {
+			pterm.Error.Printfln("Could not read the session recording: %v", err)
+			os.Exit(1)
+		}
+		// The response is recorded, so the provider only needs enough configuration to be built
+		provider, err := buildProvider(rec.Provider, config.ProviderConfig{APIKey: "replay", Model: rec.Model})
+		if err != nil {
+			pterm.Error.Printfln("Could not set up provider %q: %v", rec.Provider, err)
+			os.Exit(1)
+		}
+
+		cfg, _ := config.Load()
+		pterm.Info.Printfln("Replaying a %s session recorded %s with %s (aish %s)", rec.Kind,
+			localeFormat(cfg).DateTime(rec.RecordedAt), providerLabel(rec), rec.AishVersion)
+		ctx, replay := llm.WithSessionReplay(context.Background(), rec)
+		// Render for the recorded shell, not the one replaying (recordings before it was kept have none)
+		ctx = llm.WithShellEnvironment(ctx, llm.ShellEnvironment{Shell: rec.Shell, OS: rec.OS})
+		var got *llm.Suggestion
+		switch rec.Kind {
+		case llm.SessionSuggestion:
+			got, err = provider.GetSuggestion(ctx, *rec.Context, rec.Language)
+		case llm.SessionCommand:
+			var command string
+			command, err = provider.GenerateCommand(ctx, rec.Prompt, rec.Language)
+			got = &llm.Suggestion{CorrectedCommand: strings.TrimSpace(command)}
+		}
+
+		if replay.Prompt() != rec.RenderedPrompt {
+			pterm.Warning.Println("The prompt rendered now differs from the recorded one (the prompt templates changed).")
+		}
+		if err != nil {
+			pterm.Error.Printfln("Parsing the recorded response failed: %v", err)
+			if rec.Error != "" {
+				pterm.Info.Printfln("Recorded error: %s", rec.Error)
+			}
+			os.Exit(1)
+		}
+
+		showReplayedSuggestion("Replayed Result", got)
+		want := rec.Suggestion
+		if rec.Kind == llm.SessionCommand {
+			want = &llm.Suggestion{CorrectedCommand: strings.TrimSpace(rec.Command)}
+		}
+		switch {
+		case rec.Error != "":
+			pterm.Warning.Printfln("The recorded session failed instead: %s", rec.Error)
+		case want == nil:
+			pterm.Warning.Println("The recording has no parse result to compare with.")
+		case want.Explanation == got.Explanation && want.CorrectedCommand == got.CorrectedCommand:
+			pterm.Success.Println("Matches the recorded result.")
+		default:
+			pterm.Warning.Println("Differs from the recorded result:")
+			showReplayedSuggestion("Recorded Result", want)
+		}
+	},
+}
+
+// showReplayedSuggestion prints s without asking anything, unlike the capture flow.
+func showReplayedSuggestion(title string, s *llm.Suggestion) {
+	pterm.DefaultHeader.Println(title)
+	if s.Explanation != "" {
+		pterm.Println(pterm.Green("Explanation:"))
+		pterm.Println(s.Explanation)
+		pterm.Println()
+	}
+	pterm.Println(pterm.Green("Suggested Command:"))
+	pterm.Println(pterm.LightGreen(s.CorrectedCommand))
+	pterm.Println()
+}
+
+// providerLabel names the recorded provider and model.
+func providerLabel(rec *llm.SessionRecord) string {
+	if rec.Model == "" {
+		return rec.Provider
+	}
+	return rec.Provider + " (" + rec.Model + ")"
+}
+
+// sessionRecorder returns a recorder when AISH_RECORD_SESSION names a file, and nil otherwise.
+func sessionRecorder() *llm.SessionRecorder {
+	if strings.TrimSpace(os.Getenv(config.EnvAISHRecordSession)) == "" {
+		return nil
+	}
+	return &llm.SessionRecorder{}
+}
+
+// saveSessionRecording completes rec with the exchange r recorded and writes it to the
+// AISH_RECORD_SESSION file. Messages go to stderr so quiet output stays clean.
+func saveSessionRecording(r *llm.SessionRecorder, cfg *config.Config, rec llm.SessionRecord, err error) {
+	if r == nil {
+		return
+	}
+	rec.RecordedAt = time.Now()
+	rec.AishVersion = versionString()
+	rec.Language = effectiveLanguage(cfg)
+	env := llm.DetectShellEnvironment()
+	rec.Shell, rec.OS = env.Shell, env.OS
+	if pc, ok := effectiveProviderConfig(cfg, rec.Provider); ok {
+		rec.Model = pc.Model
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	r.Fill(&rec)
+	path := strings.TrimSpace(os.Getenv(config.EnvAISHRecordSession))
+	if err := llm.SaveSessionRecord(path, &rec); err != nil {
+		fmt.Fprintf(os.Stderr, "aish: could not record the session to %s: %v\n", path, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "aish: session recorded to %s (replay with 'aish replay %s')\n", path, path)
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/rpc"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var rpcCmd = &cobra.Command{
+	Use:     "rpc",
+	Aliases: []string{"lsp"},
+	Short:   "Serve JSON-RPC on stdio for editor plugins",
+	Long: `Runs a JSON-RPC 2.0 server on stdin/stdout with LSP-style Content-Length
+framing, so editor plugins (VS Code, Neovim, ...) can keep one aish process and
+its provider connection for the whole editing session.
+
+Methods:
+  initialize         {provider?, language?} → server info and method list
+  session/configure  {provider?, language?} → switch provider or language
+  generateCommand    {prompt}                → {command}
+  explain            {question} or {command} → {answer}
+  analyze            {command, stdout?, stderr?, exitCode?} → {explanation, correctedCommand}
+  $/cancelRequest    {id}                    cancels a running request
+  shutdown, exit`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// stdout carries the protocol; anything else aish prints must go to stderr
+		ui.SetQuietOutput(true)
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "aish rpc: failed to load config: %v\n", err)
+			os.Exit(aerrors.ExitConfig)
+		}
+
+		session := rpc.NewSession(rpc.SessionOptions{
+			Version:         versionString(),
+			DefaultProvider: effectiveProviderName(cfg),
+			Language:        effectiveLanguage(cfg),
+			NewProvider: func(name string) (llm.Provider, error) {
+				pc, ok := effectiveProviderConfig(cfg, name)
+				if !ok || isProviderConfigIncomplete(name, pc) {
+					return nil, fmt.Errorf("not configured; run 'aish config'")
+				}
+				p, err := getProvider(name, pc)
+				if err != nil {
+					return nil, err
+				}
+				return cachedProvider(cfg, name, pc, p), nil
+			},
+			Answer: func(generated string) string {
+				if ans, ok := extractEchoText(generated); ok {
+					return ans
+				}
+				return strings.TrimSpace(generated)
+			},
+			AcquireSlot: func(ctx context.Context) func() {
+				return acquireRequestSlot(ctx, cfg)
+			},
+		})
+		srv := rpc.NewServer()
+		session.Register(srv)
+
+		if err := srv.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "aish rpc: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rpcCmd)
+}
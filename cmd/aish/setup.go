@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/shell"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Prints or installs the capture hook for one shell",
+	Long: `Prints the hook that captures failed commands for the shell named by --shell, so it can be
+added to the shell's profile by hand, or installs it with --install. Without --shell the
+login shell ($SHELL) is set up, or PowerShell on Windows.
+
+For PowerShell the hook records $LASTEXITCODE, the last command line and the output the
+session shows, and hands them to 'aish capture' through AISH_STDOUT_FILE and AISH_STDERR_FILE:
+
+  aish setup --shell powershell >> $PROFILE
+  aish setup --shell powershell --install
+
+fish cannot redirect its own output, so its hook records the command line and its status,
+plus whatever the command line redirects into the capture files:
+
+  aish setup --shell fish --install`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shellName, _ := cmd.Flags().GetString("shell")
+		shellName = strings.ToLower(strings.TrimSpace(shellName))
+		if shellName == "" {
+			shellName = loginShell()
+		}
+		if shellName == "" {
+			return fmt.Errorf("could not detect your shell; pass --shell (bash, zsh, fish, powershell or pwsh)")
+		}
+		script, err := shell.HookScript(shellName)
+		if err != nil {
+			return err
+		}
+		if install, _ := cmd.Flags().GetBool("install"); !install {
+			fmt.Print(script)
+			return nil
+		}
+		if err := shell.InstallHookForShell(shellName); err != nil {
+			return fmt.Errorf("failed to install shell hook: %w", err)
+		}
+		pterm.Success.Printfln("Hook installed for %s. Open a new session to activate it.", shellName)
+		return nil
+	},
+}
+
+// loginShell names the user's shell for setup: the base name of $SHELL, or powershell on
+// Windows, where $SHELL is normally unset.
+func loginShell() string {
+	if name := filepath.Base(strings.TrimSpace(os.Getenv("SHELL"))); name != "." && name != "/" {
+		return strings.TrimSuffix(name, ".exe")
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return ""
+}
+
+func init() {
+	setupCmd.Flags().String("shell", "", "Shell to set up: bash, zsh, fish, powershell or pwsh (default: your login shell)")
+	setupCmd.Flags().Bool("install", false, "Add the hook to the shell's profile instead of printing it")
+	rootCmd.AddCommand(setupCmd)
+}
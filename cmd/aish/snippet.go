@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/snippets"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagSnippetTags        []string
+	flagSnippetDescription string
+	flagSnippetForce       bool
+	flagSnippetPattern     string
+	flagSnippetTag         string
+)
+
+var snippetCmd = &cobra.Command{
+	Use:   "snippet",
+	Short: "Save and reuse favorite commands",
+	Long: `Keeps a library of named commands. 'aish -p' offers a saved snippet instead of asking
+the provider when the prompt closely matches one (or is exactly its name).
+
+A snippet saved with --pattern is a recipe: its command has <param> placeholders that are
+filled in from a prompt matching the pattern, locally and without calling the provider.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listSnippets(loadSnippets().WithTag(flagSnippetTag))
+	},
+}
+
+var snippetSaveCmd = &cobra.Command{
+	Use:   "save <name> [command]",
+	Short: "Save a command as a snippet (the last generated command by default)",
+	Example: `  aish snippet save deploy-prod --tag k8s
+  aish snippet save big-files 'du -ah . | sort -rh | head -20' -d "biggest files here"
+  aish snippet save backup 'rsync -a <dir>/ <dest>' --pattern 'backup <dir> to <dest>'`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		sn := snippets.Snippet{Name: args[0], Pattern: flagSnippetPattern, Description: flagSnippetDescription, Tags: flagSnippetTags, CreatedAt: time.Now()}
+		if len(args) == 2 {
+			sn.Command = args[1]
+		} else {
+			path, err := snippets.LastGeneratedPath()
+			last, ok := snippets.LastGenerated(path)
+			if err != nil || !ok {
+				pterm.Error.Println("No generated command to save yet. Pass the command: aish snippet save <name> '<command>'")
+				os.Exit(1)
+			}
+			sn.Command = last.Command
+			if sn.Description == "" {
+				sn.Description = last.Prompt
+			}
+		}
+
+		store := loadSnippets()
+		if err := store.Add(sn, flagSnippetForce); err != nil {
+			if errors.Is(err, snippets.ErrExists) {
+				pterm.Error.Printfln("Snippet %q already exists; use --force to replace it.", sn.Name)
+			} else {
+				pterm.Error.Println(err)
+			}
+			os.Exit(1)
+		}
+		if err := store.Save(); err != nil {
+			pterm.Error.Printfln("Failed to save snippets: %v", err)
+			os.Exit(1)
+		}
+		pterm.Success.Printfln("Saved snippet %s: %s", sn.Name, strings.TrimSpace(sn.Command))
+	},
+}
+
+var snippetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved snippets",
+	Run: func(cmd *cobra.Command, args []string) {
+		listSnippets(loadSnippets().WithTag(flagSnippetTag))
+	},
+}
+
+var snippetSearchCmd = &cobra.Command{
+	Use:   "search <words...>",
+	Short: "Find snippets by name, tag, description or command",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		listSnippets(loadSnippets().Search(strings.Join(args, " ")))
+	},
+}
+
+var snippetExpandCmd = &cobra.Command{
+	Use:   "expand <prompt...>",
+	Short: "Show the command a saved recipe expands a prompt to",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sn, command, ok := loadSnippets().Expand(strings.Join(args, " "))
+		if !ok {
+			pterm.Error.Println("No saved recipe matches this prompt.")
+			os.Exit(1)
+		}
+		if ui.IsQuietOutput() {
+			fmt.Println(command)
+			return
+		}
+		pterm.Info.Printfln("Recipe %s (%s):", sn.Name, sn.Pattern)
+		fmt.Println(command)
+	},
+}
+
+var snippetRemoveCmd = &cobra.Command{
+	Use:     "rm <name>",
+	Aliases: []string{"remove", "delete"},
+	Short:   "Delete a snippet",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := loadSnippets()
+		if !store.Remove(args[0]) {
+			pterm.Error.Printfln("No snippet named %q.", args[0])
+			os.Exit(1)
+		}
+		if err := store.Save(); err != nil {
+			pterm.Error.Printfln("Failed to save snippets: %v", err)
+			os.Exit(1)
+		}
+		pterm.Success.Printfln("Deleted snippet %s.", args[0])
+	},
+}
+
+// loadSnippets loads the snippet library or exits with an error.
+func loadSnippets() *snippets.Store {
+	path, err := snippets.Path()
+	if err != nil {
+		pterm.Error.Printfln("Failed to locate the config directory: %v", err)
+		os.Exit(1)
+	}
+	store, err := snippets.Load(path)
+	if err != nil {
+		pterm.Error.Printfln("Failed to load snippets: %v", err)
+		os.Exit(1)
+	}
+	return store
+}
+
+func listSnippets(list []snippets.Snippet) {
+	if ui.IsJSONOutput() {
+		if list == nil {
+			list = []snippets.Snippet{}
+		}
+		data, _ := json.MarshalIndent(list, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(list) == 0 {
+		pterm.Info.Println("No snippets found. Save one with 'aish snippet save <name>'.")
+		return
+	}
+	table := pterm.TableData{{"Name", "Command", "Pattern", "Tags", "Description"}}
+	for _, sn := range list {
+		table = append(table, []string{sn.Name, sn.Command, sn.Pattern, strings.Join(sn.Tags, ", "), sn.Description})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
+// expandRecipe returns the command of the saved recipe promptStr matches, with its
+// parameters filled in.
+func expandRecipe(promptStr string) (string, bool) {
+	store, ok := snippetStore()
+	if !ok {
+		return "", false
+	}
+	_, command, ok := store.Expand(promptStr)
+	return command, ok
+}
+
+// snippetForPrompt returns the expansion of the saved recipe promptStr matches. Otherwise it
+// offers the saved snippet promptStr closely matches, on an interactive terminal, and
+// returns its command when the user takes it.
+func snippetForPrompt(promptStr string) (string, bool) {
+	store, ok := snippetStore()
+	if !ok {
+		return "", false
+	}
+	if sn, command, ok := store.Expand(promptStr); ok {
+		pterm.Info.Printfln("Expanded saved recipe %s locally.", sn.Name)
+		return command, true
+	}
+	if !isInteractiveTTY() {
+		return "", false
+	}
+	sn, _, ok := store.Match(promptStr)
+	if !ok {
+		return "", false
+	}
+	pterm.Info.Printfln("Saved snippet %s matches this prompt: %s", sn.Name, sn.Command)
+	use, err := ui.AskConfirm("Use the saved snippet?", true)
+	if err != nil || !use {
+		return "", false
+	}
+	return sn.Command, true
+}
+
+// snippetStore loads the snippet library for matching prompts; a library that cannot be
+// read just means no snippet is used.
+func snippetStore() (*snippets.Store, bool) {
+	path, err := snippets.Path()
+	if err != nil {
+		return nil, false
+	}
+	store, err := snippets.Load(path)
+	return store, err == nil
+}
+
+// rememberGenerated keeps command as the last generated one, for 'aish snippet save'.
+func rememberGenerated(promptStr, command string) {
+	path, err := snippets.LastGeneratedPath()
+	if err != nil {
+		return
+	}
+	_ = snippets.RememberGenerated(path, snippets.Generated{Prompt: promptStr, Command: strings.TrimSpace(command), At: time.Now()})
+}
+
+func init() {
+	snippetSaveCmd.Flags().StringSliceVarP(&flagSnippetTags, "tag", "t", nil, "Tag the snippet (repeatable or comma-separated)")
+	snippetSaveCmd.Flags().StringVarP(&flagSnippetDescription, "description", "d", "", "Describe the snippet (defaults to the prompt that generated it)")
+	snippetSaveCmd.Flags().StringVar(&flagSnippetPattern, "pattern", "", "Save a recipe: a prompt pattern whose <param> parts fill the command's placeholders")
+	snippetSaveCmd.Flags().BoolVar(&flagSnippetForce, "force", false, "Replace an existing snippet of the same name")
+	snippetCmd.Flags().StringVar(&flagSnippetTag, "tag", "", "Only list snippets with this tag")
+	snippetListCmd.Flags().StringVar(&flagSnippetTag, "tag", "", "Only list snippets with this tag")
+	snippetCmd.AddCommand(snippetSaveCmd, snippetListCmd, snippetSearchCmd, snippetExpandCmd, snippetRemoveCmd)
+	rootCmd.AddCommand(snippetCmd)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/telemetry"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagStatsFeatures bool
+	flagStatsParsing  bool
+	flagStatsReset    bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show local usage statistics",
+	Long: `Shows the anonymous feature-usage counters aish keeps when you opt in with
+'aish config set telemetry.enabled true'. Only command names and how often they
+ran are counted; no arguments, commands, paths or output are recorded, and the
+counters are stored in the state directory and never sent anywhere.
+
+With --parsing, shows per provider how often responses were valid JSON, needed
+repair (smart quotes, trailing commas, surrounding prose) or fell back to
+heuristic parsing. These counts hold nothing but provider names and are always kept.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if flagStatsParsing {
+			showParseStats()
+			return
+		}
+		path, err := telemetry.Path()
+		if err != nil {
+			pterm.Error.Printfln("Failed to locate the state directory: %v", err)
+			os.Exit(1)
+		}
+		if flagStatsReset {
+			if err := telemetry.Reset(path); err != nil {
+				pterm.Error.Printfln("Failed to reset feature counters: %v", err)
+				os.Exit(1)
+			}
+			pterm.Success.Println("Feature counters cleared.")
+			return
+		}
+
+		// Feature counters are the only statistics so far, so --features is also the default
+		counters := telemetry.Load(path)
+		rows := counters.Sorted()
+		if ui.IsJSONOutput() {
+			data, _ := json.MarshalIndent(counters, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		cfg, _ := config.Load()
+		if !telemetry.Enabled(cfg) {
+			pterm.Info.Println("Feature counters are off. Enable them with 'aish config set telemetry.enabled true'; they stay on this machine.")
+		}
+		if len(rows) == 0 {
+			pterm.Info.Println("No feature usage recorded yet.")
+			return
+		}
+		format := localeFormat(cfg)
+		table := pterm.TableData{{"Feature", "Uses"}}
+		for _, r := range rows {
+			table = append(table, []string{r.Feature, format.Int(r.Count)})
+		}
+		pterm.Info.Printfln("Feature usage since %s", format.Date(counters.Since))
+		_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+	},
+}
+
+// showParseStats prints (or with --reset clears) the per-provider parse counters.
+func showParseStats() {
+	path, err := llm.ParseStatsPath()
+	if err != nil {
+		pterm.Error.Printfln("Failed to locate the state directory: %v", err)
+		os.Exit(1)
+	}
+	stats := llm.LoadParseStats(path)
+	if flagStatsReset {
+		if err := stats.Reset(); err != nil {
+			pterm.Error.Printfln("Failed to reset parse counters: %v", err)
+			os.Exit(1)
+		}
+		pterm.Success.Println("Parse counters cleared.")
+		return
+	}
+	if ui.IsJSONOutput() {
+		data, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(stats.Providers) == 0 {
+		pterm.Info.Println("No provider responses parsed yet.")
+		return
+	}
+	cfg, _ := config.Load()
+	format := localeFormat(cfg)
+	percent := func(n, total int) string {
+		return fmt.Sprintf("%s (%s%%)", format.Int(int64(n)), format.Number(100*float64(n)/float64(total), 0))
+	}
+	table := pterm.TableData{{"Provider", "Strict JSON", "Repaired JSON", "Heuristic"}}
+	for _, name := range stats.Names() {
+		c := stats.Providers[name]
+		total := c.Total()
+		table = append(table, []string{name, percent(c.Strict, total), percent(c.Repaired, total), percent(c.Heuristic, total)})
+	}
+	pterm.Info.Printfln("How provider responses were parsed since %s", format.Date(stats.Since))
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
+// recordParseMethod counts how providerName's last response was parsed, as reported on parsed.
+func recordParseMethod(providerName string, parsed *llm.ParseReport) {
+	method := parsed.Method()
+	if method == "" {
+		return
+	}
+	path, err := llm.ParseStatsPath()
+	if err != nil {
+		return
+	}
+	stats := llm.LoadParseStats(path)
+	stats.Record(providerName, method, time.Now())
+	_ = stats.Save()
+}
+
+// recordFeatureUsage counts cmd in the local feature counters when the user opted in.
+func recordFeatureUsage(cmd *cobra.Command) {
+	path, err := config.GetConfigPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil || !telemetry.Enabled(cfg) {
+		return
+	}
+	countersPath, err := telemetry.Path()
+	if err != nil {
+		return
+	}
+	feature := strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()))
+	if feature == "" {
+		feature = "prompt"
+	}
+	_ = telemetry.Increment(countersPath, feature, time.Now())
+}
+
+func init() {
+	statsCmd.Flags().BoolVar(&flagStatsFeatures, "features", false, "Show feature-usage counters (the default)")
+	statsCmd.Flags().BoolVar(&flagStatsParsing, "parsing", false, "Show how each provider's responses were parsed")
+	statsCmd.Flags().BoolVar(&flagStatsReset, "reset", false, "Clear the feature-usage counters (or the parse counters with --parsing)")
+	rootCmd.AddCommand(statsCmd)
+}
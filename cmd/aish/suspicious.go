@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/security"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+)
+
+// suspiciousCommand says why a provider-suggested command needs a second look before it runs:
+// it could run more than the line shown (see security.SuspiciousCommand, unless
+// user_preferences.allow_complex_commands is set) or exceeds user_preferences.command_limits.
+func suspiciousCommand(cfg *config.Config, command string) (string, bool) {
+	if reason, ok := security.SuspiciousCommand(command, cfg.UserPreferences.AllowComplexCommands); ok {
+		return reason, true
+	}
+	limits := cfg.UserPreferences.CommandLimits.Effective()
+	return security.ExceedsLimits(strings.TrimSpace(command), security.CommandLimits{
+		MaxLength:    limits.MaxLength,
+		MaxPipes:     limits.MaxPipes,
+		MaxRedirects: limits.MaxRedirects,
+	})
+}
+
+// quietRefusal says why -q must not print command, whose output is often eval'd with no
+// chance to confirm it: reason completes "it ...", and hint says what to do instead. A
+// dangerous command is refused under either user_preferences.dangerous_commands policy, since
+// neither lets it run unconfirmed.
+func quietRefusal(cfg *config.Config, command string) (reason, hint string, refused bool) {
+	if reason, dangerous := security.Dangerous(command); dangerous {
+		if cfg.UserPreferences.DangerousCommandPolicy() == config.DangerousCommandsBlock {
+			return reason, "Dangerous commands are blocked (user_preferences.dangerous_commands).", true
+		}
+		return reason, "Run without -q to review and confirm it.", true
+	}
+	if reason, suspicious := suspiciousCommand(cfg, command); suspicious {
+		return reason, "Run without -q to review and confirm it.", true
+	}
+	return "", "", false
+}
+
+// dangerousConfirmPhrase is what the user types to run a dangerous command under the
+// confirm policy of user_preferences.dangerous_commands.
+const dangerousConfirmPhrase = "yes-i-know"
+
+// confirmSuggestedCommand lets a suspicious suggested command run only once the user has seen
+// it spelled out and confirmed it on a terminal, and a dangerous one only as
+// confirmDangerousCommand allows. Other commands pass unchanged.
+func confirmSuggestedCommand(cfg *config.Config, command string) bool {
+	if reason, dangerous := security.Dangerous(command); dangerous {
+		return confirmDangerousCommand(cfg, command, reason)
+	}
+	reason, suspicious := suspiciousCommand(cfg, command)
+	if !suspicious {
+		return true
+	}
+	// Quoted, so newlines and escape sequences show up instead of taking effect
+	pterm.Warning.Printfln("The suggested command %s:", reason)
+	pterm.Println("  " + strconv.Quote(command))
+	if !isInteractiveTTY() {
+		pterm.Info.Println("Not running it without a terminal to confirm on.")
+		return false
+	}
+	run, err := ui.AskConfirm("Run it anyway?", false)
+	return err == nil && run
+}
+
+// confirmDangerousCommand applies user_preferences.dangerous_commands to a command that can
+// wreck the system: it is refused under the block policy, and otherwise runs only once the
+// user has typed dangerousConfirmPhrase on a terminal; a plain yes is too easy to give.
+func confirmDangerousCommand(cfg *config.Config, command, reason string) bool {
+	pterm.Error.Printfln("The suggested command %s:", reason)
+	pterm.Println("  " + strconv.Quote(command))
+	if cfg.UserPreferences.DangerousCommandPolicy() == config.DangerousCommandsBlock {
+		pterm.Info.Println("Not running it: dangerous commands are blocked (user_preferences.dangerous_commands).")
+		return false
+	}
+	if !isInteractiveTTY() {
+		pterm.Info.Println("Not running it without a terminal to confirm on.")
+		return false
+	}
+	answer, err := ui.AskText(fmt.Sprintf("Type %q to run it anyway", dangerousConfirmPhrase), "", false)
+	if err != nil || strings.TrimSpace(answer) != dangerousConfirmPhrase {
+		pterm.Info.Println("Not running it.")
+		return false
+	}
+	return true
+}
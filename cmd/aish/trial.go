@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm/relay"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var flagTrialYes bool
+
+var trialCmd = &cobra.Command{
+	Use:   "trial [off]",
+	Short: "Try aish through a hosted relay before configuring a provider",
+	Long: fmt.Sprintf(`Turns on trial mode: until a provider of your own is configured, aish sends its
+requests to a hosted relay that needs no API key. The relay is rate-limited to
+%d requests a day and is meant for trying aish out; configure your own provider
+with 'aish init' to keep using it.
+
+What is sent to the relay is what a provider would get: the failed command and
+its output, or your prompt for 'aish -p'.
+
+'aish trial off' turns trial mode off again.`, relay.DailyLimit),
+	Example: `  aish trial
+  aish trial --yes
+  aish trial off`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"off"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			pterm.Error.Printfln("Failed to load config: %v", err)
+			os.Exit(aerrors.ExitConfig)
+		}
+
+		if len(args) > 0 {
+			if args[0] != "off" {
+				pterm.Error.Printfln("Unknown argument %q; use 'aish trial' or 'aish trial off'.", args[0])
+				os.Exit(aerrors.ExitGeneric)
+			}
+			cfg.UserPreferences.TrialRelay = false
+			if err := cfg.Save(); err != nil {
+				pterm.Error.Printfln("Failed to save config: %v", err)
+				os.Exit(aerrors.ExitConfig)
+			}
+			pterm.Success.Println("Trial mode is off.")
+			return
+		}
+
+		if !relay.Available() {
+			pterm.Error.Printfln("This build of aish has no trial relay. Configure a provider with 'aish init', or set %s to a relay.", config.EnvAISHRelayURL)
+			os.Exit(aerrors.ExitConfig)
+		}
+		if pc, ok := cfg.Providers[cfg.DefaultProvider]; ok && !isProviderConfigIncomplete(cfg.DefaultProvider, pc) {
+			pterm.Info.Printfln("%s is already configured, so trial mode would not be used.", cfg.DefaultProvider)
+			return
+		}
+
+		pterm.Info.Printfln("Trial mode sends failed commands, their output and your prompts to the aish relay at %s.", relay.Endpoint())
+		pterm.Info.Printfln("It allows %d requests a day. Configure your own provider with 'aish init' to lift the limit.", relay.DailyLimit)
+		if !flagTrialYes {
+			if !isInteractiveTTY() {
+				pterm.Error.Println("Run 'aish trial --yes' to turn on trial mode without a terminal.")
+				os.Exit(aerrors.ExitGeneric)
+			}
+			ok, err := ui.AskConfirm("Turn on trial mode?", true)
+			if err != nil || !ok {
+				os.Exit(aerrors.ExitUserCancel)
+			}
+		}
+
+		cfg.UserPreferences.TrialRelay = true
+		if err := cfg.Save(); err != nil {
+			pterm.Error.Printfln("Failed to save config: %v", err)
+			os.Exit(aerrors.ExitConfig)
+		}
+		pterm.Success.Printfln("Trial mode is on: %d requests left today.", relay.Remaining())
+	},
+}
+
+// showTrialBanner reminds the user, whenever the trial relay answers, that it is a rate-limited
+// trial and how to configure a provider of their own.
+func showTrialBanner(providerName string) {
+	if providerName != config.ProviderRelay || ui.IsJSONOutput() || ui.IsQuietOutput() {
+		return
+	}
+	pterm.Info.Printfln("Trial mode: the aish relay answers (%d of %d requests left today). Configure your own provider with 'aish init' for unlimited use.",
+		max(relay.Remaining(), 0), relay.DailyLimit)
+}
+
+// noProviderSuggestions is what the error for a missing provider suggests, offering trial mode
+// when this build has a relay and the user has not turned it on yet.
+func noProviderSuggestions(cfg *config.Config) []string {
+	suggestions := []string{
+		"Run 'aish init' to configure an LLM provider",
+		"Check your current configuration with 'aish config show'",
+		"Verify your API keys are correctly set",
+	}
+	if relay.Available() && !cfg.UserPreferences.TrialRelay {
+		suggestions = append(suggestions, fmt.Sprintf("Try aish without a key first: 'aish trial' (%d requests a day)", relay.DailyLimit))
+	}
+	return suggestions
+}
+
+func init() {
+	trialCmd.Flags().BoolVarP(&flagTrialYes, "yes", "y", false, "Turn on trial mode without asking")
+	rootCmd.AddCommand(trialCmd)
+}
@@ -10,6 +10,7 @@ import (
 
     "github.com/TonnyWong1052/aish/internal/config"
     "github.com/TonnyWong1052/aish/internal/shell"
+    "github.com/TonnyWong1052/aish/internal/ui"
     "github.com/pterm/pterm"
     "github.com/spf13/cobra"
 )
@@ -21,9 +22,7 @@ var uninstallCmd = &cobra.Command{
     Long:  "Removes the shell hooks from your shell config, deletes installed binaries from common locations (/usr/local/bin, /opt/homebrew/bin, ~/bin) and PATH, and deletes the configuration directory at ~/.config/aish.",
     Run: func(cmd *cobra.Command, args []string) {
         pterm.Warning.Println("This will remove aish hooks, binary, and configuration directory.")
-        confirmed, _ := pterm.DefaultInteractiveConfirm.
-            WithDefaultValue(false).
-            Show("Are you sure you want to continue?")
+        confirmed, _ := ui.AskConfirm("Are you sure you want to continue?", false)
 
 		if !confirmed {
 			pterm.Info.Println("Uninstallation cancelled.")
@@ -44,17 +43,19 @@ var uninstallCmd = &cobra.Command{
         // 2) 移除所有可能安裝位置的 aish 二進位（包含 PATH 中出現者）
         removeAllBinaries()
 
-		// 3) 移除設定資料夾 ~/.config/aish
-		configPath, err := config.GetConfigPath()
-		if err == nil {
-			configDir := strings.Replace(configPath, string(filepath.Separator)+"config.json", "", 1)
+		// 3) 移除設定資料夾 ~/.config/aish（以及 XDG 下另外的 state/cache 目錄）
+		for i, dirFn := range []func() (string, error){config.ConfigDir, config.StateDir, config.CacheDir} {
+			configDir, err := dirFn()
+			if err != nil {
+				continue
+			}
 			if _, err := os.Stat(configDir); !os.IsNotExist(err) {
 				if err := os.RemoveAll(configDir); err == nil {
 					pterm.Success.Printfln("Configuration directory removed: %s", configDir)
 				} else {
 					pterm.Error.Printfln("Failed to remove config directory: %v", err)
 				}
-			} else {
+			} else if i == 0 {
 				pterm.Info.Printf("Config directory not found: %s\n", configDir)
 			}
 		}
@@ -140,9 +141,7 @@ func removeAllBinaries() {
 
     if needSystemRemoval {
         pterm.Warning.Println("Found aish binaries in system directories that may require administrator privileges to remove.")
-        confirmed, _ := pterm.DefaultInteractiveConfirm.
-            WithDefaultValue(false).
-            Show("Do you want to attempt to remove system binaries (may require sudo)?")
+        confirmed, _ := ui.AskConfirm("Do you want to attempt to remove system binaries (may require sudo)?", false)
 
         if confirmed {
             for p := range systemCandidates {
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/TonnyWong1052/aish/internal/update"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	flagVersionCheck      bool
+	flagVersionBackground bool
+)
+
+// runVersionCheck looks up the latest release for 'aish version --check'.
+func runVersionCheck() {
+	latest, err := recordLatestRelease()
+	if err != nil {
+		pterm.Error.Printfln("Failed to check for updates: %v", err)
+		os.Exit(1)
+	}
+	current := versionString()
+	if update.CompareVersions(latest, current) > 0 {
+		pterm.Info.Printfln("aish %s is available; run 'aish upgrade' to update.", latest)
+	} else {
+		pterm.Success.Println("You are running the latest release.")
+	}
+}
+
+// recordLatestRelease asks GitHub for the latest release and remembers it, so the next
+// startup can tell the user about it without touching the network.
+func recordLatestRelease() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	rel, err := update.NewClient().Latest(ctx)
+	if err != nil {
+		return "", err
+	}
+	if path, err := update.CheckStatePath(); err == nil {
+		st := update.LoadCheckState(path)
+		st.CheckedAt = time.Now()
+		st.Latest = rel.TagName
+		_ = update.SaveCheckState(path, st)
+	}
+	return rel.TagName, nil
+}
+
+// maybeNotifyUpdate prints the new-release notice (at most once a day) and, once a week,
+// starts a detached 'aish version --background' to refresh what the latest release is. It
+// never waits on the network and stays silent for scripts, JSON output, the shell hook and
+// builds without a release version.
+func maybeNotifyUpdate(cmd *cobra.Command) {
+	switch cmd {
+	case versionCmd, upgradeCmd, captureCmd, rpcCmd:
+		return
+	}
+	if !isReleaseBuild(_version) {
+		return
+	}
+	if ui.IsJSONOutput() || ui.IsQuietOutput() || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	path, err := config.GetConfigPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil || !cfg.UserPreferences.Updates.CheckEnabled() {
+		return
+	}
+
+	statePath, err := update.CheckStatePath()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	st := update.LoadCheckState(statePath)
+	changed := false
+	if notice, ok := st.TakeNotice(versionString(), now); ok {
+		fmt.Fprintln(os.Stderr, notice)
+		changed = true
+	}
+	if st.CheckDue(now) {
+		// Record the attempt first so a failing check is not retried on every start
+		st.CheckedAt = now
+		changed = true
+		startBackgroundUpdateCheck()
+	}
+	if changed {
+		_ = update.SaveCheckState(statePath, st)
+	}
+}
+
+// isReleaseBuild reports whether version, as injected by ldflags, names a release: local and
+// development builds leave it empty or set it to "dev", and any release would look newer.
+func isReleaseBuild(version string) bool {
+	version = strings.TrimSpace(version)
+	return version != "" && !strings.EqualFold(version, "dev")
+}
+
+func startBackgroundUpdateCheck() {
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	child := exec.Command(exe, "version", "--background")
+	if err := child.Start(); err != nil {
+		return
+	}
+	_ = child.Process.Release()
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&flagVersionCheck, "check", false, "Check GitHub for a newer release")
+	versionCmd.Flags().BoolVar(&flagVersionBackground, "background", false, "Record the latest release without printing anything")
+	_ = versionCmd.Flags().MarkHidden("background")
+}
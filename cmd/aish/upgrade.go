@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/TonnyWong1052/aish/internal/update"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var flagUpgradeInsecure bool
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade aish to the latest release",
+	Long: `Downloads the latest aish release for this platform and replaces the running
+binary. The release checksums must carry a valid minisign signature from the
+release key embedded in this build, and the archive must match its signed
+checksum; otherwise nothing is replaced. --insecure skips signature
+verification (for builds without an embedded key) and should only be used when
+you trust the network path to GitHub.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		client := update.NewClient()
+
+		rel, err := client.Latest(ctx)
+		if err != nil {
+			pterm.Error.Printfln("Failed to look up the latest release: %v", err)
+			os.Exit(1)
+		}
+		current := versionString()
+		if update.CompareVersions(rel.TagName, current) <= 0 {
+			pterm.Success.Printfln("aish %s is up to date.", current)
+			return
+		}
+
+		pk, havePK := update.ReleasePublicKey()
+		if !havePK && !flagUpgradeInsecure {
+			pterm.Error.Println("This build has no release signing key, so the download cannot be verified.")
+			pterm.Info.Println("Reinstall from a release build, or pass --insecure to upgrade without verification.")
+			os.Exit(1)
+		}
+
+		archiveName := update.ArchiveName(rel.TagName, runtime.GOOS, runtime.GOARCH)
+		pterm.Info.Printfln("Downloading %s...", archiveName)
+		archive, err := client.Download(ctx, rel, archiveName)
+		if err != nil {
+			pterm.Error.Printfln("Download failed: %v", err)
+			os.Exit(1)
+		}
+
+		if flagUpgradeInsecure {
+			pterm.Warning.Println("Skipping signature verification (--insecure).")
+		} else {
+			checksumsName := update.ChecksumsName(rel.TagName)
+			checksums, err := client.Download(ctx, rel, checksumsName)
+			if err != nil {
+				pterm.Error.Printfln("Failed to download checksums: %v", err)
+				os.Exit(1)
+			}
+			signature, err := client.Download(ctx, rel, checksumsName+".minisig")
+			if err != nil {
+				pterm.Error.Printfln("Release %s is not signed (%v); refusing to install it.", rel.TagName, err)
+				os.Exit(1)
+			}
+			if err := update.VerifyArchive(pk, checksums, signature, archiveName, archive); err != nil {
+				pterm.Error.Printfln("Verification failed, refusing to install: %v", err)
+				os.Exit(1)
+			}
+			pterm.Success.Println("Signature verified.")
+		}
+
+		binary, err := update.ExtractBinary(archive, "aish")
+		if err != nil {
+			pterm.Error.Printfln("Failed to unpack the release: %v", err)
+			os.Exit(1)
+		}
+		exe, err := os.Executable()
+		if err == nil {
+			exe, err = filepath.EvalSymlinks(exe)
+		}
+		if err != nil {
+			pterm.Error.Printfln("Failed to locate the running binary: %v", err)
+			os.Exit(1)
+		}
+		if err := update.ReplaceExecutable(exe, binary); err != nil {
+			pterm.Error.Printfln("Failed to replace %s: %v", exe, err)
+			pterm.Info.Println("If aish was installed by a package manager (Homebrew, apt), upgrade it there instead.")
+			os.Exit(1)
+		}
+		pterm.Success.Printfln("Upgraded aish %s → %s.", current, rel.TagName)
+	},
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&flagUpgradeInsecure, "insecure", false, "Install without verifying the release signature")
+	rootCmd.AddCommand(upgradeCmd)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/ui"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagUseNoCheck bool
+	flagUseTimeout time.Duration
+)
+
+var useCmd = &cobra.Command{
+	Use:   "use [provider] [model]",
+	Short: "Switch the default provider and check that it answers",
+	Long: `Makes provider the default one, optionally with a different model, after
+checking that it answers with the configured credentials. When the check fails
+the default provider is left unchanged; --no-check switches without it. The
+check shows how long the DNS lookup, connection, TLS handshake and the wait for
+the first byte took, which tells a slow network from a slow provider.
+
+Without arguments on a terminal, aish lists the providers to pick from.`,
+	Example: `  aish use claude
+  aish use openai gpt-4o-mini
+  aish use ollama --no-check`,
+	Args: cobra.MaximumNArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return config.GetSupportedProviders(), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			pterm.Error.Printfln("Failed to load config: %v", err)
+			os.Exit(aerrors.ExitConfig)
+		}
+
+		var name, model string
+		switch {
+		case len(args) > 0:
+			name = strings.ToLower(strings.TrimSpace(args[0]))
+			if len(args) > 1 {
+				model = args[1]
+			}
+		case isInteractiveTTY():
+			name, err = ui.AskSelect("Select the default provider", config.GetSupportedProviders(), cfg.DefaultProvider)
+			if err != nil {
+				os.Exit(aerrors.ExitUserCancel)
+			}
+		default:
+			pterm.Info.Printfln("Using %s (%s)", cfg.DefaultProvider, cfg.Providers[cfg.DefaultProvider].Model)
+			pterm.Info.Printfln("Supported providers: %s", strings.Join(config.GetSupportedProviders(), ", "))
+			return
+		}
+
+		previous := cfg.DefaultProvider
+		if err := cfg.UseProvider(name, model); err != nil {
+			pterm.Error.Println(err)
+			os.Exit(1)
+		}
+		pc := cfg.Providers[name]
+
+		if !flagUseNoCheck {
+			if code, ok := checkProviderConnection(name, pc, model != ""); !ok {
+				pterm.Info.Printfln("The default provider is still %s. Fix the settings with 'aish config set providers.%s.<field> <value>' or switch anyway with --no-check.", previous, name)
+				os.Exit(code)
+			}
+		}
+
+		if err := cfg.Save(); err != nil {
+			pterm.Error.Printfln("Failed to save config: %v", err)
+			os.Exit(aerrors.ExitConfig)
+		}
+		pterm.Success.Printfln("Now using %s (%s).", name, pc.Model)
+	},
+}
+
+// checkProviderConnection asks provider name for its models with the settings in pc and
+// reports the result. A model the provider does not list is only a warning, since some
+// providers list a subset of what they serve; it is worth one when the user just named it.
+// On failure it returns the exit code for the error.
+func checkProviderConnection(name string, pc config.ProviderConfig, modelGiven bool) (int, bool) {
+	provider, err := getProvider(name, pc)
+	if err != nil {
+		pterm.Error.Printfln("Could not set up %s: %v", name, err)
+		return aerrors.ExitProvider, false
+	}
+
+	presenter := ui.NewPresenter()
+	presenter.ShowLoading("Checking connection to " + name)
+	ctx, cancel := context.WithTimeout(context.Background(), flagUseTimeout)
+	defer cancel()
+	ctx, trace := llm.TraceHTTP(ctx)
+	models, err := llm.CheckConnection(ctx, name, provider)
+	timing, timed := trace.Timing()
+	if err != nil {
+		presenter.StopLoading(false)
+		pterm.Error.Printfln("%s did not answer: %v", name, err)
+		if timed {
+			pterm.Info.Printfln("HTTP timing: %s", llm.FormatHTTPTiming(timing))
+		}
+		return aerrors.ExitCodeFor(llm.ErrorCodeOf(name, err)), false
+	}
+	presenter.StopLoading(true)
+	if timed {
+		pterm.Info.Printfln("%s answered: %s", name, llm.FormatHTTPTiming(timing))
+	}
+
+	if modelGiven && len(models) > 0 && !llm.ModelListed(models, pc.Model) {
+		pterm.Warning.Printfln("%s does not list the model %s; requests may fail. Available: %s",
+			name, pc.Model, strings.Join(firstModels(models, 10), ", "))
+	}
+	return aerrors.ExitOK, true
+}
+
+// firstModels returns at most n models, marking that the list goes on.
+func firstModels(models []string, n int) []string {
+	if len(models) <= n {
+		return models
+	}
+	return append(models[:n:n], "...")
+}
+
+func init() {
+	useCmd.Flags().BoolVar(&flagUseNoCheck, "no-check", false, "Switch without checking that the provider answers")
+	useCmd.Flags().DurationVar(&flagUseTimeout, "timeout", 15*time.Second, "How long to wait for the provider to answer")
+	rootCmd.AddCommand(useCmd)
+}
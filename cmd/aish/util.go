@@ -3,15 +3,67 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aishcontext "github.com/TonnyWong1052/aish/internal/context"
+	"github.com/TonnyWong1052/aish/internal/shell"
+	"github.com/TonnyWong1052/aish/internal/ui"
 )
 
-// executeCommand prints and runs a command, streaming its output.
+// localeFormat returns how dates, durations and numbers are shown to the user: in the
+// configured locale and time zone, or those of the environment. cfg may be nil.
+func localeFormat(cfg *config.Config) ui.LocaleFormat {
+	var prefs config.UserPreferences
+	if cfg != nil {
+		prefs = cfg.UserPreferences
+	}
+	return ui.NewLocaleFormat(prefs.EffectiveLocale(), prefs.TimeLocation())
+}
+
+// executeCommand prints and runs a command in the user's shell (shell.Dialect), the one it was
+// generated for, streaming its output. Under WSL, Windows paths in
+// the command are first translated for the program that will read them.
 func executeCommand(command string) {
+	_ = runCommand(command)
+}
+
+// runCommand runs command like executeCommand and returns its error, e.g. an *exec.ExitError
+// for a non-zero exit status.
+func runCommand(command string) error {
+	if aishcontext.IsWSL() {
+		if translated := aishcontext.TranslatePathsForWSL(command); translated != command {
+			fmt.Println("Translated paths for WSL:", translated)
+			command = translated
+		}
+	}
 	fmt.Println("Executing:", command)
-	cmd := exec.Command("sh", "-c", command)
+	cmd := shell.Command(shell.Dialect(), command)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	// Do not pass stdin to avoid residual input being interpreted as new commands
-	_ = cmd.Run()
+	return cmd.Run()
+}
+
+// runInteractive runs a command attached to the terminal, so it can ask for a sudo password or
+// a confirmation, and returns its error.
+func runInteractive(command string) error {
+	cmd := shell.Command(shell.Dialect(), command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 KiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit || value <= -unit {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTPE"[exp])
 }
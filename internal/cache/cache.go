@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/TonnyWong1052/aish/internal/config"
 	aerrors "github.com/TonnyWong1052/aish/internal/errors"
     "sync"
 )
@@ -15,7 +16,7 @@ import (
 // CacheEntry cache entry
 type CacheEntry struct {
 	Key        string    `json:"key"`
-	Value      string    `json:"value"`
+	Value      string    `json:"value,omitempty"` // Legacy: older indexes duplicated the content here; Compact drops it
 	CreatedAt  time.Time `json:"created_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
 	AccessedAt time.Time `json:"accessed_at"`
@@ -43,19 +44,23 @@ type CacheConfig struct {
 	CacheDir        string        `json:"cache_dir"`        // Cache directory
 	MaxFileSize     int64         `json:"max_file_size"`    // Maximum size of single cache file
 	Enabled         bool          `json:"enabled"`          // Whether cache is enabled
+	Compress        bool          `json:"compress"`         // Gzip cache files larger than compressThreshold
+	SharedDir       string        `json:"shared_dir"`       // Read-only system-wide cache consulted on a miss ("" disables)
 }
 
 // DefaultCacheConfig 返回默認緩存配置
 func DefaultCacheConfig() CacheConfig {
-	home, _ := os.UserHomeDir()
+	cacheDir, _ := config.CacheDir()
 	return CacheConfig{
 		MaxEntries:      1000,
 		DefaultTTL:      24 * time.Hour,
 		MaxTTL:          7 * 24 * time.Hour,
 		CleanupInterval: time.Hour,
-		CacheDir:        filepath.Join(home, ".config", "aish", "cache"),
+		CacheDir:        cacheDir,
 		MaxFileSize:     1024 * 1024, // 1MB
 		Enabled:         true,
+		Compress:        true,
+		SharedDir:       defaultSharedDir(),
 	}
 }
 
@@ -63,7 +68,9 @@ func DefaultCacheConfig() CacheConfig {
 type Cache struct {
     config CacheConfig
     index  map[string]*CacheEntry
+    shared *sharedLayer
     stats  CacheStats
+    removed map[string]bool // Entries deleted since the index was saved, kept out of the merge
     stopCh   chan struct{}
     stopOnce sync.Once
 }
@@ -71,6 +78,7 @@ type Cache struct {
 // CacheStats 緩存統計
 type CacheStats struct {
 	Hits        int64     `json:"hits"`
+	SharedHits  int64     `json:"shared_hits"` // Hits served by the system-wide cache (included in Hits)
 	Misses      int64     `json:"misses"`
 	Entries     int       `json:"entries"`
 	LastCleanup time.Time `json:"last_cleanup"`
@@ -94,8 +102,8 @@ func NewCache(config CacheConfig) (*Cache, error) {
 		}, nil
 	}
 
-	// 創建緩存目錄
-    if err := os.MkdirAll(config.CacheDir, 0755); err != nil {
+	// 創建緩存目錄（僅限當前用戶訪問）
+    if err := os.MkdirAll(config.CacheDir, 0700); err != nil {
         return nil, aerrors.ErrFileSystemError("create_cache_dir", config.CacheDir, err)
     }
 
@@ -106,6 +114,7 @@ func NewCache(config CacheConfig) (*Cache, error) {
             LastCleanup: time.Now(),
         },
         stopCh: make(chan struct{}),
+        shared: loadSharedLayer(config.SharedDir, config.CacheDir),
     }
 
 	// 加載現有緩存索引
@@ -128,17 +137,32 @@ func (c *Cache) Get(key string) (string, bool) {
 	}
 
 	hashedKey := c.hashKey(key)
-	entry, exists := c.index[hashedKey]
+	if content, ok := c.getLocal(hashedKey); ok {
+		c.stats.Hits++
+		return content, true
+	}
+
+	// 用戶緩存未命中時查詢系統共享緩存
+	if content, ok := c.shared.get(hashedKey); ok {
+		c.stats.Hits++
+		c.stats.SharedHits++
+		return content, true
+	}
 
+	c.stats.Misses++
+	return "", false
+}
+
+// getLocal 從用戶緩存讀取，過期或損壞的條目會被刪除
+func (c *Cache) getLocal(hashedKey string) (string, bool) {
+	entry, exists := c.index[hashedKey]
 	if !exists {
-		c.stats.Misses++
 		return "", false
 	}
 
 	// 檢查是否過期
 	if entry.IsExpired() {
 		c.delete(hashedKey)
-		c.stats.Misses++
 		return "", false
 	}
 
@@ -146,14 +170,11 @@ func (c *Cache) Get(key string) (string, bool) {
 	content, err := c.readCacheFile(hashedKey)
 	if err != nil {
 		c.delete(hashedKey)
-		c.stats.Misses++
 		return "", false
 	}
 
 	// 更新訪問信息
 	entry.Touch()
-	c.stats.Hits++
-
 	return content, true
 }
 
@@ -182,7 +203,6 @@ func (c *Cache) Set(key, value string, ttl time.Duration) error {
 	// 創建緩存條目
 	entry := &CacheEntry{
 		Key:        key,
-		Value:      value,
 		CreatedAt:  now,
 		ExpiresAt:  now.Add(ttl),
 		AccessedAt: now,
@@ -223,6 +243,10 @@ func (c *Cache) Delete(key string) {
 func (c *Cache) delete(hashedKey string) {
 	// 從索引中刪除
 	delete(c.index, hashedKey)
+	if c.removed == nil {
+		c.removed = make(map[string]bool)
+	}
+	c.removed[hashedKey] = true
 
 	// 刪除緩存文件
 	cacheFile := filepath.Join(c.config.CacheDir, hashedKey)
@@ -237,16 +261,12 @@ func (c *Cache) Clear() error {
 		return nil
 	}
 
-	// 刪除所有緩存文件
+	// 刪除所有緩存文件和索引條目，包括其他進程剛保存的
+	c.mergeSavedIndex()
 	for hashedKey := range c.index {
-		cacheFile := filepath.Join(c.config.CacheDir, hashedKey)
-		os.Remove(cacheFile)
+		c.delete(hashedKey)
 	}
 
-	// 清空索引
-	c.index = make(map[string]*CacheEntry)
-	c.stats.Entries = 0
-
 	// 保存空索引
 	return c.saveIndex()
 }
@@ -324,20 +344,29 @@ func (c *Cache) hashKey(key string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-// readCacheFile 讀取緩存文件
+// readCacheFile 讀取緩存文件（自動解壓縮）
 func (c *Cache) readCacheFile(hashedKey string) (string, error) {
 	cacheFile := filepath.Join(c.config.CacheDir, hashedKey)
     data, err := os.ReadFile(cacheFile)
     if err != nil {
         return "", aerrors.ErrFileSystemError("read_cache", cacheFile, err)
     }
+	if data, err = Decompress(data); err != nil {
+		return "", aerrors.ErrFileSystemError("decompress_cache", cacheFile, err)
+	}
 	return string(data), nil
 }
 
 // writeCacheFile 寫入緩存文件
 func (c *Cache) writeCacheFile(hashedKey, content string) error {
 	cacheFile := filepath.Join(c.config.CacheDir, hashedKey)
-    if err := os.WriteFile(cacheFile, []byte(content), 0644); err != nil {
+	data := []byte(content)
+	if c.config.Compress && len(data) >= compressThreshold {
+		if compressed, err := Compress(data); err == nil && len(compressed) < len(data) {
+			data = compressed
+		}
+	}
+    if err := os.WriteFile(cacheFile, data, 0600); err != nil {
         return aerrors.ErrFileSystemError("write_cache", cacheFile, err)
     }
 	return nil
@@ -345,43 +374,95 @@ func (c *Cache) writeCacheFile(hashedKey, content string) error {
 
 // loadIndex 加載緩存索引
 func (c *Cache) loadIndex() error {
-	indexFile := filepath.Join(c.config.CacheDir, "index.json")
+	index, err := readIndex(filepath.Join(c.config.CacheDir, indexFileName))
+	if err != nil {
+		return err
+	}
+	c.index = index
+	c.stats.Entries = len(index)
+	return nil
+}
 
+// readIndex reads the index at indexFile; a missing one is empty.
+func readIndex(indexFile string) (map[string]*CacheEntry, error) {
 	data, err := os.ReadFile(indexFile)
     if err != nil {
         if os.IsNotExist(err) {
-            return nil // 索引文件不存在是正常的
+            return make(map[string]*CacheEntry), nil // 索引文件不存在是正常的
         }
-        return aerrors.ErrFileSystemError("read_index", indexFile, err)
+        return nil, aerrors.ErrFileSystemError("read_index", indexFile, err)
     }
 
 	var index map[string]*CacheEntry
     if err := json.Unmarshal(data, &index); err != nil {
-        return aerrors.ErrFileSystemError("parse_index", indexFile, err)
+        return nil, aerrors.ErrFileSystemError("parse_index", indexFile, err)
     }
+	if index == nil {
+		index = make(map[string]*CacheEntry)
+	}
+	return index, nil
+}
 
-	c.index = index
-	c.stats.Entries = len(index)
-
-	return nil
+// mergeSavedIndex adds the entries other aish processes saved since the index was loaded,
+// leaving out those this one deleted or that have expired.
+func (c *Cache) mergeSavedIndex() {
+	saved, err := readIndex(filepath.Join(c.config.CacheDir, indexFileName))
+	if err != nil {
+		return
+	}
+	for hashedKey, entry := range saved {
+		if _, ok := c.index[hashedKey]; !ok && !c.removed[hashedKey] && !entry.IsExpired() {
+			c.index[hashedKey] = entry
+		}
+	}
+	c.stats.Entries = len(c.index)
 }
 
-// saveIndex 保存緩存索引
+// saveIndex 保存緩存索引. Several aish processes share the index, so it is rewritten while
+// holding the index lock, merged with what the others saved, and replaced by renaming a
+// temporary file: a reader never sees a partial index and no process drops another's entries.
 func (c *Cache) saveIndex() error {
 	if !c.config.Enabled {
 		return nil
 	}
 
-	indexFile := filepath.Join(c.config.CacheDir, "index.json")
+	indexFile := filepath.Join(c.config.CacheDir, indexFileName)
+	lock, err := os.OpenFile(filepath.Join(c.config.CacheDir, indexLockFileName), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return aerrors.ErrFileSystemError("lock_index", indexFile, err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return aerrors.ErrFileSystemError("lock_index", indexFile, err)
+	}
+	defer unlockFile(lock)
+
+	c.mergeSavedIndex()
+	for c.config.MaxEntries > 0 && len(c.index) > c.config.MaxEntries {
+		c.evictLRU()
+	}
 
     data, err := json.MarshalIndent(c.index, "", "  ")
     if err != nil {
         return aerrors.ErrFileSystemError("marshal_index", indexFile, err)
     }
 
-    if err := os.WriteFile(indexFile, data, 0644); err != nil {
-        return aerrors.ErrFileSystemError("write_index", indexFile, err)
-    }
+	tmp, err := os.CreateTemp(c.config.CacheDir, "index-*.tmp")
+	if err != nil {
+		return aerrors.ErrFileSystemError("write_index", indexFile, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), indexFile)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return aerrors.ErrFileSystemError("write_index", indexFile, err)
+	}
+	c.removed = nil
 
 	return nil
 }
@@ -2,6 +2,10 @@ package cache
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -165,6 +169,60 @@ func TestCacheDelete(t *testing.T) {
 	}
 }
 
+func TestCacheConcurrentProcesses(t *testing.T) {
+	config := CacheConfig{
+		Enabled:         true,
+		MaxEntries:      100,
+		DefaultTTL:      time.Hour,
+		MaxTTL:          24 * time.Hour,
+		CleanupInterval: time.Minute,
+		CacheDir:        t.TempDir(),
+		MaxFileSize:     1024,
+	}
+	a, err := NewCache(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewCache(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = a.Set("gone", "value", time.Hour)
+
+	var wg sync.WaitGroup
+	for name, c := range map[string]*Cache{"a": a, "b": b} {
+		wg.Add(1)
+		go func(name string, c *Cache) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				_ = c.Set(fmt.Sprintf("%s-%d", name, i), "value", time.Hour)
+			}
+		}(name, c)
+	}
+	wg.Wait()
+	a.Delete("gone")
+	_ = a.Close()
+	_ = b.Close()
+
+	reopened, err := NewCache(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		for i := 0; i < 20; i++ {
+			if _, ok := reopened.Get(fmt.Sprintf("%s-%d", name, i)); !ok {
+				t.Errorf("entry %s-%d lost", name, i)
+			}
+		}
+	}
+	if _, ok := reopened.Get("gone"); ok {
+		t.Error("deleted entry brought back by another process's save")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(config.CacheDir, "*.tmp")); len(matches) > 0 {
+		t.Errorf("temporary index files left behind: %v", matches)
+	}
+}
+
 func TestCacheClear(t *testing.T) {
 	tempDir := t.TempDir()
 	config := CacheConfig{
@@ -311,3 +369,121 @@ func TestCacheMaxEntries(t *testing.T) {
 		t.Errorf("Cache entries %d exceed max limit %d", stats.Entries, config.MaxEntries)
 	}
 }
+
+func TestCacheCompressionAndCompact(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultCacheConfig()
+	config.CacheDir = dir
+	config.CleanupInterval = time.Hour
+
+	cache, err := NewCache(config)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	defer cache.Close()
+
+	value := strings.Repeat("explanation of the failing command ", 50)
+	if err := cache.Set("key", value, time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, cache.hashKey("key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsCompressed(raw) || len(raw) >= len(value) {
+		t.Errorf("expected a compressed cache file, got %d bytes for %d bytes of content", len(raw), len(value))
+	}
+	if got, ok := cache.Get("key"); !ok || got != value {
+		t.Fatal("compressed entry should read back transparently")
+	}
+
+	// Files from before compression, and orphans, are handled by Compact
+	plainKey := cache.hashKey("plain")
+	if err := os.WriteFile(filepath.Join(dir, plainKey), []byte(value), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache.index[plainKey] = &CacheEntry{Key: "plain", Value: value, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := os.WriteFile(filepath.Join(dir, "orphan"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := cache.Compact()
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if stats.Compressed != 1 || stats.Removed != 1 {
+		t.Errorf("unexpected compact stats: %+v", stats)
+	}
+	if stats.BytesAfter >= stats.BytesBefore {
+		t.Errorf("compaction should shrink the directory: %+v", stats)
+	}
+	if got, ok := cache.Get("plain"); !ok || got != value {
+		t.Error("compacted entry should still read back")
+	}
+	if cache.index[plainKey].Value != "" {
+		t.Error("compact should drop values duplicated in the index")
+	}
+}
+
+func TestCacheSharedLayer(t *testing.T) {
+	sharedDir := filepath.Join(t.TempDir(), "shared")
+
+	// An administrator's cache, published to the shared directory
+	adminCfg := DefaultCacheConfig()
+	adminCfg.CacheDir = t.TempDir()
+	adminCfg.SharedDir = ""
+	admin, err := NewCache(adminCfg)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	if err := admin.Set("npm ERR! code ENOENT", "run npm install first", time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if n, err := admin.Publish(sharedDir); err != nil || n != 1 {
+		t.Fatalf("Publish = %d, %v", n, err)
+	}
+	admin.Close()
+
+	userCfg := DefaultCacheConfig()
+	userCfg.CacheDir = t.TempDir()
+	userCfg.SharedDir = sharedDir
+	user, err := NewCache(userCfg)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	defer user.Close()
+
+	if got, ok := user.Get("npm ERR! code ENOENT"); !ok || got != "run npm install first" {
+		t.Fatalf("expected a shared hit, got %q, %v", got, ok)
+	}
+	if stats := user.GetStats(); stats.SharedHits != 1 || stats.Hits != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// The user's own entries take precedence and are never written to the shared directory
+	if err := user.Set("npm ERR! code ENOENT", "personal answer", time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, _ := user.Get("npm ERR! code ENOENT"); got != "personal answer" {
+		t.Errorf("user entry should shadow the shared one, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(sharedDir, user.hashKey("other"))); !os.IsNotExist(err) {
+		t.Error("shared directory must stay read-only")
+	}
+	if info, err := os.Stat(filepath.Join(userCfg.CacheDir, user.hashKey("npm ERR! code ENOENT"))); err != nil || info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("user cache files should be private, got %v", info.Mode())
+	}
+}
+
+func TestCacheSharedLayerRejectsWritableDir(t *testing.T) {
+	sharedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sharedDir, indexFileName), []byte(`{"x":{"key":"k"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(sharedDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if loadSharedLayer(sharedDir, t.TempDir()) != nil {
+		t.Error("a world-writable shared directory must be ignored")
+	}
+}
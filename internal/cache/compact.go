@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// indexFileName is the cache index stored next to the entry files, and indexLockFileName the
+// file locked while a process rewrites it.
+const (
+	indexFileName     = "index.json"
+	indexLockFileName = "index.lock"
+)
+
+// CompactStats summarizes a Compact run.
+type CompactStats struct {
+	Removed     int   // Expired, missing or orphaned entries deleted
+	Compressed  int   // Entry files rewritten compressed
+	BytesBefore int64 // Size of the cache directory before compaction
+	BytesAfter  int64 // Size of the cache directory after compaction
+}
+
+// Compact drops expired entries and files no longer referenced by the index, compresses entry
+// files written before compression was enabled and removes values duplicated in the index.
+func (c *Cache) Compact() (CompactStats, error) {
+	var stats CompactStats
+	if !c.config.Enabled {
+		return stats, nil
+	}
+	stats.BytesBefore = DirSize(c.config.CacheDir)
+	// Entries other processes saved since the index was loaded are not orphans
+	c.mergeSavedIndex()
+
+	before := len(c.index)
+	c.Cleanup()
+	stats.Removed = before - len(c.index)
+
+	for hashedKey, entry := range c.index {
+		entry.Value = ""
+		path := filepath.Join(c.config.CacheDir, hashedKey)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			c.delete(hashedKey)
+			stats.Removed++
+			continue
+		}
+		if !c.config.Compress || IsCompressed(data) || len(data) < compressThreshold {
+			continue
+		}
+		compressed, err := Compress(data)
+		if err != nil || len(compressed) >= len(data) {
+			continue
+		}
+		if err := os.WriteFile(path, compressed, 0600); err != nil {
+			return stats, err
+		}
+		stats.Compressed++
+	}
+
+	// Files left behind by crashed writes or older versions are not in the index
+	files, err := os.ReadDir(c.config.CacheDir)
+	if err != nil {
+		return stats, err
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || name == indexFileName || name == indexLockFileName || name == similarityFileName || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if _, ok := c.index[name]; !ok {
+			if os.Remove(filepath.Join(c.config.CacheDir, name)) == nil {
+				stats.Removed++
+			}
+		}
+	}
+
+	if err := c.saveIndex(); err != nil {
+		return stats, err
+	}
+	stats.BytesAfter = DirSize(c.config.CacheDir)
+	return stats, nil
+}
+
+// DirSize returns the total size of the regular files below dir (0 when it does not exist).
+func DirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
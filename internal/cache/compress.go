@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// compressThreshold is the smallest payload worth compressing; gzip framing costs ~20 bytes.
+const compressThreshold = 256
+
+// gzipMagic starts every gzip stream and lets readers detect compressed files without metadata.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsCompressed reports whether data is a gzip stream.
+func IsCompressed(data []byte) bool {
+	return bytes.HasPrefix(data, gzipMagic)
+}
+
+// Compress gzips data.
+func Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the content of a gzip stream, or data unchanged when it is not compressed,
+// so files written before compression was enabled stay readable.
+func Decompress(data []byte) ([]byte, error) {
+	if !IsCompressed(data) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -4,7 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"path/filepath"
 	"time"
 
 	"github.com/TonnyWong1052/aish/internal/llm"
@@ -18,6 +18,8 @@ type LLMCacheKey struct {
 	Language    string              `json:"language"`
 	RequestType string              `json:"request_type"` // "suggestion" or "command_generation"
 	Prompt      string              `json:"prompt,omitempty"`
+	Shell       string              `json:"shell,omitempty"` // Shell and OS a generated command is written for, e.g. "zsh on macOS"
+	ExactOnly   bool                `json:"-"`                // Neither answered from nor added to the similarity index
 }
 
 // Hash generates hash value for cache key
@@ -73,6 +75,10 @@ func NewLLMCache(baseCache *Cache, config LLMCacheConfig) *LLMCache {
 	var similarityCache *SimilarityCache
 	if config.EnableSimilarity {
 		similarityCache = NewSimilarityCache(config.MaxSimilarityCache, config.SimilarityThreshold)
+		// 相似度索引與基礎緩存存放在一起，跨進程保留
+		if baseCache != nil && baseCache.config.Enabled {
+			similarityCache.Load(filepath.Join(baseCache.config.CacheDir, similarityFileName))
+		}
 	}
 
 	return &LLMCache{
@@ -96,7 +102,7 @@ func (lc *LLMCache) GetSuggestion(key LLMCacheKey) (*llm.Suggestion, bool) {
 	}
 
 	// 然後嘗試相似匹配
-	if lc.config.EnableSimilarity {
+	if lc.config.EnableSimilarity && !key.ExactOnly {
 		return lc.getSimilarSuggestion(key)
 	}
 
@@ -130,8 +136,8 @@ func (lc *LLMCache) SetSuggestion(key LLMCacheKey, suggestion *llm.Suggestion) e
 	}
 
 	// 添加到相似度緩存
-	if lc.config.EnableSimilarity {
-		lc.similarityCache.Add(key, string(responseJSON))
+	if lc.config.EnableSimilarity && !key.ExactOnly {
+		lc.similarityCache.Add(key)
 	}
 
 	return nil
@@ -151,7 +157,7 @@ func (lc *LLMCache) GetCommand(key LLMCacheKey) (string, bool) {
 	}
 
 	// 然後嘗試相似匹配
-	if lc.config.EnableSimilarity {
+	if lc.config.EnableSimilarity && !key.ExactOnly {
 		return lc.getSimilarCommand(key)
 	}
 
@@ -185,8 +191,8 @@ func (lc *LLMCache) SetCommand(key LLMCacheKey, command string) error {
 	}
 
 	// 添加到相似度緩存
-	if lc.config.EnableSimilarity {
-		lc.similarityCache.Add(key, string(responseJSON))
+	if lc.config.EnableSimilarity && !key.ExactOnly {
+		lc.similarityCache.Add(key)
 	}
 
 	return nil
@@ -224,32 +230,36 @@ func (lc *LLMCache) getExactCommandMatch(key LLMCacheKey) string {
 
 // getSimilarSuggestion 獲取相似的建議
 func (lc *LLMCache) getSimilarSuggestion(key LLMCacheKey) (*llm.Suggestion, bool) {
-	similarResponseStr := lc.similarityCache.GetSimilar(key)
-	if similarResponseStr == "" {
-		return nil, false
-	}
-
-	var response LLMCachedResponse
-	if err := json.Unmarshal([]byte(similarResponseStr), &response); err != nil {
+	response, ok := lc.similarResponse(key)
+	if !ok || response.Suggestion == nil {
 		return nil, false
 	}
-
 	return response.Suggestion, true
 }
 
 // getSimilarCommand 獲取相似的命令
 func (lc *LLMCache) getSimilarCommand(key LLMCacheKey) (string, bool) {
-	similarResponseStr := lc.similarityCache.GetSimilar(key)
-	if similarResponseStr == "" {
+	response, ok := lc.similarResponse(key)
+	if !ok || response.Command == "" {
 		return "", false
 	}
+	return response.Command, true
+}
 
+// similarResponse 從基礎緩存讀取最相似請求的回應；已過期或被驅逐的條目會從相似度索引移除
+func (lc *LLMCache) similarResponse(key LLMCacheKey) (LLMCachedResponse, bool) {
 	var response LLMCachedResponse
-	if err := json.Unmarshal([]byte(similarResponseStr), &response); err != nil {
-		return "", false
+	hash := lc.similarityCache.GetSimilar(key)
+	if hash == "" {
+		return response, false
 	}
-
-	return response.Command, true
+	responseStr, found := lc.cache.Get(hash)
+	if !found || json.Unmarshal([]byte(responseStr), &response) != nil {
+		lc.similarityCache.Remove(hash)
+		return response, false
+	}
+	lc.similarityCache.hits++
+	return response, true
 }
 
 // Clear 清空 LLM 緩存
@@ -290,163 +300,3 @@ type LLMCacheStats struct {
 	SimilarityHits    int64     `json:"similarity_hits"`
 	SimilarityEntries int       `json:"similarity_entries"`
 }
-
-// SimilarityCache 相似度緩存
-type SimilarityCache struct {
-	entries   []SimilarityCacheEntry
-	maxSize   int
-	threshold float64
-	hits      int64
-}
-
-// SimilarityCacheEntry 相似度緩存條目
-type SimilarityCacheEntry struct {
-	Key      LLMCacheKey `json:"key"`
-	Response string      `json:"response"`
-	AddedAt  time.Time   `json:"added_at"`
-}
-
-// NewSimilarityCache 創建新的相似度緩存
-func NewSimilarityCache(maxSize int, threshold float64) *SimilarityCache {
-	return &SimilarityCache{
-		entries:   make([]SimilarityCacheEntry, 0, maxSize),
-		maxSize:   maxSize,
-		threshold: threshold,
-	}
-}
-
-// Add 添加到相似度緩存
-func (sc *SimilarityCache) Add(key LLMCacheKey, response string) {
-	entry := SimilarityCacheEntry{
-		Key:      key,
-		Response: response,
-		AddedAt:  time.Now(),
-	}
-
-	// 如果已滿，移除最舊的條目
-	if len(sc.entries) >= sc.maxSize {
-		sc.entries = sc.entries[1:]
-	}
-
-	sc.entries = append(sc.entries, entry)
-}
-
-// GetSimilar 獲取相似的響應
-func (sc *SimilarityCache) GetSimilar(key LLMCacheKey) string {
-	bestMatch := ""
-	bestSimilarity := 0.0
-
-	for _, entry := range sc.entries {
-		if entry.Key.RequestType != key.RequestType {
-			continue
-		}
-
-		similarity := sc.calculateSimilarity(key, entry.Key)
-		if similarity >= sc.threshold && similarity > bestSimilarity {
-			bestSimilarity = similarity
-			bestMatch = entry.Response
-		}
-	}
-
-	if bestMatch != "" {
-		sc.hits++
-	}
-
-	return bestMatch
-}
-
-// calculateSimilarity 計算兩個緩存鍵的相似度
-func (sc *SimilarityCache) calculateSimilarity(key1, key2 LLMCacheKey) float64 {
-	// 簡單的文本相似度計算
-	// 可以使用更複雜的算法如編輯距離或詞向量相似度
-
-	// 命令相似度
-	var commandSim float64
-	if key1.Context.Command != "" || key2.Context.Command != "" {
-		commandSim = sc.textSimilarity(key1.Context.Command, key2.Context.Command)
-	} else {
-		commandSim = 1.0 // 都為空，視為相同
-	}
-
-	// 錯誤輸出相似度
-	var stderrSim float64
-	if key1.Context.Stderr != "" || key2.Context.Stderr != "" {
-		stderrSim = sc.textSimilarity(key1.Context.Stderr, key2.Context.Stderr)
-	} else {
-		stderrSim = 1.0
-	}
-
-	// 退出代碼相似度
-	var exitCodeSim float64
-	if key1.Context.ExitCode == key2.Context.ExitCode {
-		exitCodeSim = 1.0
-	} else {
-		exitCodeSim = 0.0
-	}
-
-	// 提示相似度（如果有）
-	var promptSim float64 = 1.0
-	if key1.Prompt != "" || key2.Prompt != "" {
-		promptSim = sc.textSimilarity(key1.Prompt, key2.Prompt)
-	}
-
-	// 加權平均
-	totalSim := (commandSim*0.3 + stderrSim*0.4 + exitCodeSim*0.2 + promptSim*0.1)
-
-	return totalSim
-}
-
-// textSimilarity 計算文本相似度（簡單版本）
-func (sc *SimilarityCache) textSimilarity(text1, text2 string) float64 {
-	if text1 == text2 {
-		return 1.0
-	}
-	if text1 == "" || text2 == "" {
-		return 0.0
-	}
-
-	// 轉換為小寫並分詞
-	words1 := strings.Fields(strings.ToLower(text1))
-	words2 := strings.Fields(strings.ToLower(text2))
-
-	if len(words1) == 0 || len(words2) == 0 {
-		return 0.0
-	}
-
-	// 計算詞集合交集
-	wordSet1 := make(map[string]bool)
-	for _, word := range words1 {
-		wordSet1[word] = true
-	}
-
-	intersection := 0
-	for _, word := range words2 {
-		if wordSet1[word] {
-			intersection++
-		}
-	}
-
-	// Jaccard 相似度
-	union := len(words1) + len(words2) - intersection
-	if union == 0 {
-		return 1.0
-	}
-
-	return float64(intersection) / float64(union)
-}
-
-// Clear 清空相似度緩存
-func (sc *SimilarityCache) Clear() {
-	sc.entries = sc.entries[:0]
-	sc.hits = 0
-}
-
-// GetHits 獲取相似度緩存命中次數
-func (sc *SimilarityCache) GetHits() int64 {
-	return sc.hits
-}
-
-// GetSize 獲取相似度緩存大小
-func (sc *SimilarityCache) GetSize() int {
-	return len(sc.entries)
-}
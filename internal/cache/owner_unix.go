@@ -0,0 +1,18 @@
+//go:build unix
+
+package cache
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByUserOrRoot reports whether info belongs to the current user or to root, the only
+// owners trusted to fill the shared cache.
+func ownedByUserOrRoot(info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return st.Uid == 0 || int(st.Uid) == os.Getuid()
+}
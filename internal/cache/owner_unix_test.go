@@ -0,0 +1,35 @@
+//go:build unix
+
+package cache
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+type fakeFileInfo struct {
+	os.FileInfo
+	sys any
+}
+
+func (f fakeFileInfo) Sys() any { return f.sys }
+
+func TestOwnedByUserOrRoot(t *testing.T) {
+	uid := uint32(os.Getuid())
+	tests := []struct {
+		name string
+		sys  any
+		want bool
+	}{
+		{"current user", &syscall.Stat_t{Uid: uid}, true},
+		{"root", &syscall.Stat_t{Uid: 0}, true},
+		{"other user", &syscall.Stat_t{Uid: uid + 1000}, false},
+		{"unknown owner", nil, false},
+	}
+	for _, tt := range tests {
+		if got := ownedByUserOrRoot(fakeFileInfo{sys: tt.sys}); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
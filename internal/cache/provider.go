@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+// ResponseStore keeps provider answers on disk, so a prompt or a failed command seen before is
+// answered without an API call. Entries expire after the TTL of their kind and the least
+// recently used ones are evicted beyond MaxEntries. A store is safe for concurrent use.
+type ResponseStore struct {
+	mu    sync.Mutex
+	base  *Cache
+	cache *LLMCache
+}
+
+// OpenResponseStore opens the response cache in the cache directory, evicting expired entries,
+// with the limits and TTLs of cfg; zero values keep the defaults. It returns nil when cfg
+// disables caching, and a nil store caches nothing.
+func OpenResponseStore(cfg config.CacheConfig) (*ResponseStore, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	baseCfg := ResponseCacheConfig(cfg)
+	base, err := NewCache(baseCfg)
+	if err != nil {
+		return nil, err
+	}
+	base.Cleanup()
+
+	llmCfg := DefaultLLMCacheConfig()
+	llmCfg.DefaultTTL = baseCfg.DefaultTTL
+	if cfg.SuggestionTTLHours > 0 {
+		llmCfg.SuggestionTTL = time.Duration(cfg.SuggestionTTLHours) * time.Hour
+	}
+	if cfg.CommandTTLHours > 0 {
+		llmCfg.CommandTTL = time.Duration(cfg.CommandTTLHours) * time.Hour
+	}
+	llmCfg.EnableSimilarity = cfg.EnableSimilarity
+	if cfg.SimilarityThreshold > 0 {
+		llmCfg.SimilarityThreshold = cfg.SimilarityThreshold
+	}
+	if cfg.MaxSimilarityCache > 0 {
+		llmCfg.MaxSimilarityCache = cfg.MaxSimilarityCache
+	}
+	return &ResponseStore{base: base, cache: NewLLMCache(base, llmCfg)}, nil
+}
+
+// ResponseCacheConfig returns the configuration the response cache is opened with for the cache
+// settings cfg, for maintenance that must see the cache as OpenResponseStore does.
+func ResponseCacheConfig(cfg config.CacheConfig) CacheConfig {
+	baseCfg := DefaultCacheConfig()
+	if cfg.MaxEntries > 0 {
+		baseCfg.MaxEntries = cfg.MaxEntries
+	}
+	if cfg.DefaultTTLHours > 0 {
+		baseCfg.DefaultTTL = time.Duration(cfg.DefaultTTLHours) * time.Hour
+	}
+	return baseCfg
+}
+
+// Wrap returns p answering from the store where it can. Answers are keyed by provider name,
+// model, language and the whole request, and only answers p returned without error are stored,
+// so wrap a provider that validates its answers (see llm.GetProvider). A nil store returns p.
+func (s *ResponseStore) Wrap(p llm.Provider, providerName, model string) llm.Provider {
+	if s == nil || p == nil {
+		return p
+	}
+	return &cachingProvider{Provider: p, store: s, name: providerName, model: model}
+}
+
+// Close stops the store's cleanup routine and saves its index.
+func (s *ResponseStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.base.Close()
+}
+
+func (s *ResponseStore) suggestion(key LLMCacheKey) (*llm.Suggestion, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.GetSuggestion(key)
+}
+
+func (s *ResponseStore) setSuggestion(key LLMCacheKey, suggestion *llm.Suggestion) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A cache that cannot be written only costs the next request an API call
+	_ = s.cache.SetSuggestion(key, suggestion)
+}
+
+func (s *ResponseStore) command(key LLMCacheKey) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.GetCommand(key)
+}
+
+func (s *ResponseStore) setCommand(key LLMCacheKey, command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.cache.SetCommand(key, command)
+}
+
+// cachingProvider answers from a ResponseStore before asking the wrapped provider. Streaming
+// calls answered from the store return at once without passing anything to onChunk.
+type cachingProvider struct {
+	llm.Provider
+	store *ResponseStore
+	name  string
+	model string
+}
+
+// key keys a request; a follow-up in a conversation is only answered by an exact match.
+func (p *cachingProvider) key(ctx context.Context, c llm.CapturedContext, language, prompt string) LLMCacheKey {
+	return LLMCacheKey{Provider: p.name, Model: p.model, Context: c, Language: language, Prompt: prompt, ExactOnly: llm.InConversation(ctx)}
+}
+
+// enhancedKey keys an enhanced suggestion by its extra context as well, which tells it apart
+// from a plain suggestion for the same command.
+func (p *cachingProvider) enhancedKey(ctx context.Context, c llm.EnhancedCapturedContext, language string) LLMCacheKey {
+	extra, _ := json.Marshal(struct {
+		RecentCommands   []string
+		DirectoryListing []string
+		WorkingDirectory string
+		ShellType        string
+	}{c.RecentCommands, c.DirectoryListing, c.WorkingDirectory, c.ShellType})
+	return p.key(ctx, c.CapturedContext, language, "enhanced:"+string(extra))
+}
+
+// commandKey keys a generated command by the shell it is written for as well: the same prompt
+// gets a different command in fish or PowerShell.
+func (p *cachingProvider) commandKey(ctx context.Context, language, prompt string) LLMCacheKey {
+	key := p.key(ctx, llm.CapturedContext{}, language, prompt)
+	env := llm.ShellEnvironmentFrom(ctx)
+	key.Shell = env.Shell + " on " + env.OS
+	return key
+}
+
+func (p *cachingProvider) suggest(key LLMCacheKey, get func() (*llm.Suggestion, error)) (*llm.Suggestion, error) {
+	if s, ok := p.store.suggestion(key); ok {
+		return s, nil
+	}
+	s, err := get()
+	if err == nil && s != nil {
+		p.store.setSuggestion(key, s)
+	}
+	return s, err
+}
+
+func (p *cachingProvider) generate(key LLMCacheKey, get func() (string, error)) (string, error) {
+	if cmd, ok := p.store.command(key); ok {
+		return cmd, nil
+	}
+	cmd, err := get()
+	if err == nil && cmd != "" {
+		p.store.setCommand(key, cmd)
+	}
+	return cmd, err
+}
+
+func (p *cachingProvider) GetSuggestion(ctx context.Context, capturedCtx llm.CapturedContext, language string) (*llm.Suggestion, error) {
+	return p.suggest(p.key(ctx, capturedCtx, language, ""), func() (*llm.Suggestion, error) {
+		return p.Provider.GetSuggestion(ctx, capturedCtx, language)
+	})
+}
+
+func (p *cachingProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx llm.EnhancedCapturedContext, language string) (*llm.Suggestion, error) {
+	return p.suggest(p.enhancedKey(ctx, enhancedCtx, language), func() (*llm.Suggestion, error) {
+		return p.Provider.GetEnhancedSuggestion(ctx, enhancedCtx, language)
+	})
+}
+
+func (p *cachingProvider) GetSuggestionStream(ctx context.Context, capturedCtx llm.CapturedContext, language string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
+	return p.suggest(p.key(ctx, capturedCtx, language, ""), func() (*llm.Suggestion, error) {
+		return p.Provider.GetSuggestionStream(ctx, capturedCtx, language, onChunk)
+	})
+}
+
+func (p *cachingProvider) GenerateCommand(ctx context.Context, prompt string, language string) (string, error) {
+	return p.generate(p.commandKey(ctx, language, prompt), func() (string, error) {
+		return p.Provider.GenerateCommand(ctx, prompt, language)
+	})
+}
+
+func (p *cachingProvider) GenerateCommandStream(ctx context.Context, prompt string, language string, onChunk llm.StreamFunc) (string, error) {
+	return p.generate(p.commandKey(ctx, language, prompt), func() (string, error) {
+		return p.Provider.GenerateCommandStream(ctx, prompt, language, onChunk)
+	})
+}
+
+// GeneratePlan, Warmup and AuthExpiry forward the optional interfaces of the wrapped provider.
+// Plans are not cached: each step depends on what the ones before it did to the system.
+func (p *cachingProvider) GeneratePlan(ctx context.Context, prompt string, language string) (*llm.Plan, error) {
+	return llm.GeneratePlan(ctx, p.Provider, prompt, language)
+}
+
+func (p *cachingProvider) Warmup(ctx context.Context) error {
+	if w, ok := p.Provider.(llm.Warmer); ok {
+		return w.Warmup(ctx)
+	}
+	return nil
+}
+
+func (p *cachingProvider) AuthExpiry() (time.Time, bool) {
+	if r, ok := p.Provider.(llm.AuthExpiryReporter); ok {
+		return r.AuthExpiry()
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+// countingProvider answers every request and counts the calls that reach it.
+type countingProvider struct {
+	llm.Provider
+	calls int
+	err   error
+}
+
+func (p *countingProvider) GetSuggestion(ctx context.Context, c llm.CapturedContext, language string) (*llm.Suggestion, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &llm.Suggestion{Explanation: "typo", CorrectedCommand: "git status"}, nil
+}
+
+func (p *countingProvider) GenerateCommand(ctx context.Context, prompt string, language string) (string, error) {
+	p.calls++
+	return "ls -la " + prompt, nil
+}
+
+func openTestStore(t *testing.T) *ResponseStore {
+	t.Helper()
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	t.Setenv(config.EnvAISHSharedCacheDir, "off")
+	cfg := config.CacheConfig{Enabled: true, MaxEntries: 10, DefaultTTLHours: 1, SuggestionTTLHours: 1, CommandTTLHours: 1, EnableSimilarity: true, SimilarityThreshold: 0.85}
+	store, err := OpenResponseStore(cfg)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestResponseStoreWrap(t *testing.T) {
+	store := openTestStore(t)
+	inner := &countingProvider{}
+	p := store.Wrap(inner, "openai", "gpt-4o")
+	ctx := context.Background()
+	captured := llm.CapturedContext{Command: "gti status", Stderr: "gti: command not found", ExitCode: 127}
+
+	for i := 0; i < 2; i++ {
+		s, err := p.GetSuggestion(ctx, captured, "en")
+		if err != nil || s.CorrectedCommand != "git status" {
+			t.Fatalf("got %+v, %v", s, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("repeated suggestion reached the provider %d times", inner.calls)
+	}
+
+	if _, err := p.GetSuggestion(ctx, captured, "zh-TW"); err != nil || inner.calls != 2 {
+		t.Errorf("another language answered from the cache: %d calls, %v", inner.calls, err)
+	}
+
+	for _, prompt := range []string{"docs", "docs", "src"} {
+		if cmd, err := p.GenerateCommand(ctx, prompt, "en"); err != nil || cmd != "ls -la "+prompt {
+			t.Errorf("GenerateCommand(%q) = %q, %v", prompt, cmd, err)
+		}
+	}
+	if inner.calls != 4 {
+		t.Errorf("expected 4 provider calls, got %d", inner.calls)
+	}
+	fish := llm.WithShellEnvironment(ctx, llm.ShellEnvironment{Shell: "fish", OS: "Linux"})
+	if _, err := p.GenerateCommand(fish, "docs", "en"); err != nil || inner.calls != 5 {
+		t.Errorf("a command for another shell answered from the cache: %d calls, %v", inner.calls, err)
+	}
+
+	other := &countingProvider{}
+	if _, err := store.Wrap(other, "claude", "claude-3-5-haiku").GetSuggestion(ctx, captured, "en"); err != nil || other.calls != 1 {
+		t.Errorf("another provider answered from the cache: %d calls, %v", other.calls, err)
+	}
+}
+
+func TestResponseStoreConversationExactOnly(t *testing.T) {
+	store := openTestStore(t)
+	inner := &countingProvider{}
+	p := store.Wrap(inner, "openai", "gpt-4o")
+	var conv llm.Conversation
+	conv.Add("list the files in this directory sorted by size", "ls -lS")
+	ctx := conv.Context(context.Background())
+
+	for _, request := range []string{"include hidden files", "exclude hidden files", "include hidden files"} {
+		if cmd, err := p.GenerateCommand(ctx, conv.Prompt(request), "en"); err != nil || !strings.HasSuffix(cmd, request) {
+			t.Errorf("follow-up %q answered with %q, %v", request, cmd, err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", inner.calls)
+	}
+	if size := store.cache.similarityCache.GetSize(); size != 0 {
+		t.Errorf("follow-ups added to the similarity index: %d entries", size)
+	}
+}
+
+func TestResponseStoreFollowUpExactOnly(t *testing.T) {
+	store := openTestStore(t)
+	inner := &countingProvider{}
+	p := store.Wrap(inner, "openai", "gpt-4o")
+	captured := llm.CapturedContext{Command: "find . -name '*.txt'", Stdout: "./notes.txt\n./todo.txt", ExitCode: 1}
+	var conv llm.Conversation
+	conv.Add("", "find . -type f")
+	ctx := conv.Context(context.Background())
+
+	for _, request := range []string{"only go files", "only python files"} {
+		if _, err := p.GetSuggestion(ctx, conv.FollowUp(captured, request), "en"); err != nil {
+			t.Fatalf("follow-up %q: %v", request, err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", inner.calls)
+	}
+}
+
+func TestResponseStorePersists(t *testing.T) {
+	store := openTestStore(t)
+	captured := llm.CapturedContext{Command: "gti status", ExitCode: 127}
+	if _, err := store.Wrap(&countingProvider{}, "ollama", "llama3").GetSuggestion(context.Background(), captured, "en"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	reopened, err := OpenResponseStore(config.CacheConfig{Enabled: true, EnableSimilarity: false})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	inner := &countingProvider{}
+	if s, err := reopened.Wrap(inner, "ollama", "llama3").GetSuggestion(context.Background(), captured, "en"); err != nil || s.CorrectedCommand != "git status" || inner.calls != 0 {
+		t.Errorf("answer not read back from disk: %+v, %v, %d calls", s, err, inner.calls)
+	}
+}
+
+func TestResponseStoreSkipsErrors(t *testing.T) {
+	store := openTestStore(t)
+	inner := &countingProvider{err: errors.New("rate limited")}
+	p := store.Wrap(inner, "openai", "gpt-4o")
+	captured := llm.CapturedContext{Command: "make", ExitCode: 2}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetSuggestion(context.Background(), captured, "en"); err == nil {
+			t.Fatal("expected the provider error")
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("failed answer was cached: %d calls", inner.calls)
+	}
+}
+
+func TestOpenResponseStoreDisabled(t *testing.T) {
+	store, err := OpenResponseStore(config.CacheConfig{Enabled: false})
+	if err != nil || store != nil {
+		t.Fatalf("got %v, %v", store, err)
+	}
+	inner := &countingProvider{}
+	if p := store.Wrap(inner, "openai", "gpt-4o"); p != inner {
+		t.Errorf("nil store wrapped the provider")
+	}
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// sharedLayer is a system-wide cache directory (e.g. /var/cache/aish) that an administrator
+// fills with 'aish cache publish', so users of a lab machine get instant answers for errors
+// someone already asked about. It uses the on-disk format of the user cache but is never
+// written to, expired entries are skipped rather than deleted, and hits are not promoted.
+type sharedLayer struct {
+	dir   string
+	index map[string]*CacheEntry
+}
+
+// defaultSharedDir returns AISH_SHARED_CACHE_DIR, or DefaultSharedCacheDir when unset.
+// The default only takes effect when an administrator has created it.
+func defaultSharedDir() string {
+	v, ok := os.LookupEnv(config.EnvAISHSharedCacheDir)
+	if !ok {
+		return config.DefaultSharedCacheDir
+	}
+	v = strings.TrimSpace(v)
+	if strings.EqualFold(v, "off") {
+		return ""
+	}
+	return v
+}
+
+// loadSharedLayer opens the shared cache at dir. It returns nil when dir is empty, missing,
+// the same as the user cache, or owned or writable by other users: anyone able to write there could
+// plant suggestions that other users would run.
+func loadSharedLayer(dir, userDir string) *sharedLayer {
+	if dir == "" {
+		return nil
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if userAbs, err := filepath.Abs(userDir); err == nil && userAbs == dir {
+		return nil
+	}
+	if !sharedPathSafe(dir) {
+		return nil
+	}
+	indexFile := filepath.Join(dir, indexFileName)
+	if !sharedPathSafe(indexFile) {
+		return nil
+	}
+	data, err := os.ReadFile(indexFile)
+	if err != nil {
+		return nil
+	}
+	var index map[string]*CacheEntry
+	if json.Unmarshal(data, &index) != nil || len(index) == 0 {
+		return nil
+	}
+	return &sharedLayer{dir: dir, index: index}
+}
+
+// get returns the shared value for hashedKey. A nil layer never hits.
+func (s *sharedLayer) get(hashedKey string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	entry, ok := s.index[hashedKey]
+	if !ok || entry.IsExpired() {
+		return "", false
+	}
+	path := filepath.Join(s.dir, hashedKey)
+	if !sharedPathSafe(path) {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	if data, err = Decompress(data); err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// sharedPathSafe reports whether path exists, is owned by the current user or root and is not
+// writable by group or others.
+func sharedPathSafe(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Mode().Perm()&0o022 == 0 && ownedByUserOrRoot(info)
+}
+
+// Publish copies the unexpired entries of the user cache into the shared cache at dir,
+// merging with entries already there, with permissions that let every user read them.
+// It returns the number of entries published.
+func (c *Cache) Publish(dir string) (int, error) {
+	if !c.config.Enabled {
+		return 0, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	// MkdirAll does not fix the mode of an existing directory, and the umask may have narrowed it
+	if err := os.Chmod(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	index := map[string]*CacheEntry{}
+	if data, err := os.ReadFile(filepath.Join(dir, indexFileName)); err == nil {
+		_ = json.Unmarshal(data, &index)
+	}
+
+	published := 0
+	for hashedKey, entry := range c.index {
+		if entry.IsExpired() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.config.CacheDir, hashedKey))
+		if err != nil {
+			continue
+		}
+		if err := writeShared(filepath.Join(dir, hashedKey), data); err != nil {
+			return published, err
+		}
+		shared := *entry
+		shared.Value = ""
+		shared.HitCount = 0
+		index[hashedKey] = &shared
+		published++
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return published, err
+	}
+	return published, writeShared(filepath.Join(dir, indexFileName), data)
+}
+
+// writeShared writes a world-readable file atomically so readers never see a partial entry.
+func writeShared(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,279 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// similarityFileName is the similarity index, kept next to the cache index so that similar
+// requests match across runs.
+const similarityFileName = "similarity.json"
+
+// maxSimilarityTokens bounds the tokens kept per request; longer output adds little.
+const maxSimilarityTokens = 256
+
+// SimilarityCache finds the cached answer to a request that differs from the one asked only in
+// ways that do not change the answer: case, punctuation and filler words in a prompt, or
+// numbers such as PIDs, line numbers and timestamps in the output of a failed command. It keeps
+// the normalized tokens of each request and the key of its answer in the base cache, so the
+// answers themselves expire and are evicted with the base cache.
+type SimilarityCache struct {
+	entries   []SimilarityCacheEntry
+	maxSize   int
+	threshold float64
+	hits      int64
+	path      string // Where Add and Remove save the index; "" keeps it in memory
+}
+
+// SimilarityCacheEntry is one request in the similarity index.
+type SimilarityCacheEntry struct {
+	Hash        string    `json:"hash"` // LLMCacheKey.Hash of the request, under which its answer is cached
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	Language    string    `json:"language"`
+	RequestType string    `json:"request_type"`
+	Shell       string    `json:"shell,omitempty"`
+	Command     string    `json:"command,omitempty"` // The failed command, whitespace-normalized
+	Numbers     []string  `json:"numbers,omitempty"` // The numbers in a prompt, which must match exactly
+	ExitCode    int       `json:"exit_code,omitempty"`
+	Tokens      []string  `json:"tokens"` // Sorted normalized tokens of the prompt or the command's output
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// NewSimilarityCache 創建新的相似度緩存
+func NewSimilarityCache(maxSize int, threshold float64) *SimilarityCache {
+	return &SimilarityCache{
+		entries:   make([]SimilarityCacheEntry, 0, maxSize),
+		maxSize:   maxSize,
+		threshold: threshold,
+	}
+}
+
+// Load reads the index saved at path, which Add and Remove then keep up to date. A missing or
+// unreadable index starts empty.
+func (sc *SimilarityCache) Load(path string) {
+	sc.path = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var entries []SimilarityCacheEntry
+	if json.Unmarshal(data, &entries) != nil {
+		return
+	}
+	if len(entries) > sc.maxSize {
+		entries = entries[len(entries)-sc.maxSize:]
+	}
+	sc.entries = entries
+}
+
+// save writes the index atomically, through a temporary file of its own so concurrent aish
+// processes do not write into each other's; a failed write only loses similar matches.
+func (sc *SimilarityCache) save() {
+	if sc.path == "" {
+		return
+	}
+	data, err := json.Marshal(sc.entries)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(sc.path), "similarity-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil || os.Rename(tmp.Name(), sc.path) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// Add indexes the request key, whose answer is cached under key.Hash(). A full index drops its
+// oldest request.
+func (sc *SimilarityCache) Add(key LLMCacheKey) {
+	if sc.maxSize <= 0 {
+		return
+	}
+	entry := similarityEntry(key)
+	sc.remove(entry.Hash)
+	if len(sc.entries) >= sc.maxSize {
+		sc.entries = sc.entries[len(sc.entries)-sc.maxSize+1:]
+	}
+	sc.entries = append(sc.entries, entry)
+	sc.save()
+}
+
+// Remove drops the request whose answer is cached under hash, e.g. once that answer expired.
+func (sc *SimilarityCache) Remove(hash string) {
+	if sc.remove(hash) {
+		sc.save()
+	}
+}
+
+func (sc *SimilarityCache) remove(hash string) bool {
+	for i, e := range sc.entries {
+		if e.Hash == hash {
+			sc.entries = append(sc.entries[:i], sc.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// GetSimilar returns the hash of the indexed request most similar to key, or "" when none
+// reaches the threshold. Only requests to the same provider and model in the same language
+// match; a command generation additionally needs the same numbers in its prompt and a
+// suggestion the same command and exit status, since the answer repeats them.
+func (sc *SimilarityCache) GetSimilar(key LLMCacheKey) string {
+	query := similarityEntry(key)
+	best, bestSimilarity := "", 0.0
+	for _, e := range sc.entries {
+		if e.Hash == query.Hash || !sameRequestKind(query, e) {
+			continue
+		}
+		if sim := tokenSimilarity(query.Tokens, e.Tokens); sim >= sc.threshold && sim > bestSimilarity {
+			best, bestSimilarity = e.Hash, sim
+		}
+	}
+	return best
+}
+
+func sameRequestKind(a, b SimilarityCacheEntry) bool {
+	return a.Provider == b.Provider && a.Model == b.Model && a.Language == b.Language &&
+		a.RequestType == b.RequestType && a.Shell == b.Shell && a.Command == b.Command && a.ExitCode == b.ExitCode &&
+		slices.Equal(a.Numbers, b.Numbers)
+}
+
+// similarityEntry normalizes key for comparison: a command generation by its prompt without
+// filler words and the numbers in it, a suggestion by everything the provider sees besides the
+// command. The numbers of a prompt are arguments ("kill 1234", "older than 7 days"), unlike
+// those in the output of a failed command.
+func similarityEntry(key LLMCacheKey) SimilarityCacheEntry {
+	entry := SimilarityCacheEntry{
+		Hash:        key.Hash(),
+		Provider:    key.Provider,
+		Model:       key.Model,
+		Language:    key.Language,
+		RequestType: key.RequestType,
+		Shell:       key.Shell,
+		AddedAt:     time.Now(),
+	}
+	if key.RequestType == "command_generation" {
+		entry.Tokens = normalizeTokens(key.Prompt, promptStopWords)
+		entry.Numbers = volatileWords(key.Prompt)
+		return entry
+	}
+	c := key.Context
+	entry.Command = strings.Join(strings.Fields(c.Command), " ")
+	entry.ExitCode = c.ExitCode
+	text := []string{c.Stderr, c.Stdout, c.Expansion, key.Prompt}
+	text = append(text, c.Notes...)
+	if c.FailedStage != nil {
+		text = append(text, c.FailedStage.Command)
+	}
+	entry.Tokens = normalizeTokens(strings.Join(text, "\n"), nil)
+	return entry
+}
+
+// promptStopWords are words that do not change what a prompt asks for.
+var promptStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "please": true, "me": true, "my": true, "i": true,
+	"you": true, "can": true, "could": true, "would": true, "how": true, "do": true, "does": true,
+	"what": true, "is": true, "are": true, "some": true, "just": true, "want": true, "need": true,
+}
+
+// normalizeTokens splits text into lowercase words, treating everything but letters and digits
+// as separators, replaces numbers with "#" and drops stop words. It returns the distinct
+// tokens, sorted, at most maxSimilarityTokens of them.
+func normalizeTokens(text string, stop map[string]bool) []string {
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if stop[word] {
+			continue
+		}
+		if isVolatile(word) {
+			word = "#"
+		}
+		seen[word] = true
+	}
+	tokens := make([]string, 0, len(seen))
+	for t := range seen {
+		tokens = append(tokens, t)
+	}
+	sort.Strings(tokens)
+	if len(tokens) > maxSimilarityTokens {
+		tokens = tokens[:maxSimilarityTokens]
+	}
+	return tokens
+}
+
+// volatileWords returns the words of text that normalizeTokens replaces with "#", in order.
+func volatileWords(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if isVolatile(word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// isVolatile reports whether word is a number or an identifier made up mostly of digits, such
+// as a hex ID or a temporary file suffix, which differ between runs of the same command.
+func isVolatile(word string) bool {
+	digits := 0
+	for _, r := range word {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	return digits > 0 && (digits == len(word) || (len(word) >= 8 && digits*3 >= len(word)))
+}
+
+// tokenSimilarity is the Jaccard similarity of two sorted token sets; two empty sets are
+// identical.
+func tokenSimilarity(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1.0
+	}
+	common, i, j := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			common++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// Clear 清空相似度緩存
+func (sc *SimilarityCache) Clear() {
+	sc.entries = sc.entries[:0]
+	sc.hits = 0
+	sc.save()
+}
+
+// GetHits 獲取相似度緩存命中次數
+func (sc *SimilarityCache) GetHits() int64 {
+	return sc.hits
+}
+
+// GetSize 獲取相似度緩存大小
+func (sc *SimilarityCache) GetSize() int {
+	return len(sc.entries)
+}
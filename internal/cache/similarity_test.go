@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+func TestNormalizeTokens(t *testing.T) {
+	got := normalizeTokens("Please list ALL the files, in /tmp/build-1234!", promptStopWords)
+	want := []string{"#", "all", "build", "files", "in", "list", "tmp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := normalizeTokens("pid 4242 at 0x7ffee3b4c8a0, job 3f9a2c11", nil); !reflect.DeepEqual(got, []string{"#", "at", "job", "pid"}) {
+		t.Errorf("volatile tokens kept: %v", got)
+	}
+}
+
+func TestSimilarityCacheGetSimilar(t *testing.T) {
+	sc := NewSimilarityCache(10, 0.85)
+	prompt := LLMCacheKey{Provider: "openai", Model: "gpt-4o", Language: "en", RequestType: "command_generation", Prompt: "show disk usage"}
+	failure := LLMCacheKey{Provider: "openai", Model: "gpt-4o", Language: "en", RequestType: "suggestion",
+		Context: llm.CapturedContext{Command: "npm start", Stderr: "Error: listen EADDRINUSE: address already in use :::3000 (pid 4242)", ExitCode: 1}}
+	sc.Add(prompt)
+	sc.Add(failure)
+
+	similarPrompt := prompt
+	similarPrompt.Prompt = "Please show me the disk usage"
+	if got := sc.GetSimilar(similarPrompt); got != prompt.Hash() {
+		t.Errorf("rephrased prompt not matched")
+	}
+	otherPrompt := prompt
+	otherPrompt.Prompt = "delete disk usage logs"
+	if got := sc.GetSimilar(otherPrompt); got != "" {
+		t.Errorf("different prompt matched")
+	}
+	otherModel := similarPrompt
+	otherModel.Model = "gpt-4o-mini"
+	if got := sc.GetSimilar(otherModel); got != "" {
+		t.Errorf("another model matched")
+	}
+
+	rerun := failure
+	rerun.Context.Stderr = "Error: listen EADDRINUSE: address already in use :::3000 (pid 5150)"
+	if got := sc.GetSimilar(rerun); got != failure.Hash() {
+		t.Errorf("rerun with another pid not matched")
+	}
+	otherCommand := rerun
+	otherCommand.Context.Command = "npm run dev"
+	if got := sc.GetSimilar(otherCommand); got != "" {
+		t.Errorf("suggestion for another command matched")
+	}
+}
+
+func TestSimilarityCachePromptNumbers(t *testing.T) {
+	sc := NewSimilarityCache(10, 0.5)
+	kill := LLMCacheKey{Provider: "openai", Model: "gpt-4o", Language: "en", RequestType: "command_generation", Prompt: "kill process 1234"}
+	week := LLMCacheKey{Provider: "openai", Model: "gpt-4o", Language: "en", RequestType: "command_generation", Prompt: "find files modified in the last 7 days"}
+	sc.Add(kill)
+	sc.Add(week)
+
+	for _, prompt := range []string{"kill process 5678", "find files modified in the last 365 days"} {
+		key := kill
+		key.Prompt = prompt
+		if got := sc.GetSimilar(key); got != "" {
+			t.Errorf("%q matched a prompt with other numbers", prompt)
+		}
+	}
+	same := week
+	same.Prompt = "Please find the files modified in the last 7 days"
+	if got := sc.GetSimilar(same); got != week.Hash() {
+		t.Errorf("rephrased prompt with the same numbers not matched")
+	}
+}
+
+func TestSimilarityCacheEvictsOldest(t *testing.T) {
+	sc := NewSimilarityCache(2, 0.85)
+	for _, p := range []string{"list files", "show disk usage", "count lines"} {
+		sc.Add(LLMCacheKey{RequestType: "command_generation", Prompt: p})
+	}
+	if sc.GetSize() != 2 || sc.GetSimilar(LLMCacheKey{RequestType: "command_generation", Prompt: "list the files"}) != "" {
+		t.Errorf("oldest entry kept: %+v", sc.entries)
+	}
+}
+
+func TestResponseStoreSimilarAcrossRuns(t *testing.T) {
+	store := openTestStore(t)
+	if _, err := store.Wrap(&countingProvider{}, "openai", "gpt-4o").GenerateCommand(context.Background(), "list files", "en"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	reopened, err := OpenResponseStore(config.CacheConfig{Enabled: true, EnableSimilarity: true, SimilarityThreshold: 0.85})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	inner := &countingProvider{}
+	cmd, err := reopened.Wrap(inner, "openai", "gpt-4o").GenerateCommand(context.Background(), "Please list the files", "en")
+	if err != nil || cmd != "ls -la list files" || inner.calls != 0 {
+		t.Errorf("similar prompt not answered from the cache: %q, %v, %d calls", cmd, err, inner.calls)
+	}
+
+	dir, _ := config.CacheDir()
+	sc := NewSimilarityCache(10, 0.85)
+	sc.Load(filepath.Join(dir, similarityFileName))
+	if sc.GetSize() != 1 {
+		t.Errorf("similarity index not saved: %d entries", sc.GetSize())
+	}
+}
@@ -0,0 +1,88 @@
+package classification
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/shell"
+)
+
+// GatekeeperBlock describes an executable macOS refused to run because Gatekeeper could not
+// verify it, usually because it carries the com.apple.quarantine attribute set on downloads.
+type GatekeeperBlock struct {
+	Path        string   // Blocked executable or app bundle as named in the error or command
+	Checks      []string // Commands that confirm the diagnosis
+	Remediation []string // Commands that lift the block, most targeted first
+}
+
+var gatekeeperPatterns = []string{
+	"developer cannot be verified",
+	"from an unidentified developer",
+	"is damaged and can't be opened",
+	"is damaged and can’t be opened",
+	"Apple could not verify",
+	"com.apple.quarantine",
+}
+
+// gatekeeperSubject matches the quoted name macOS puts in front of the message, with straight
+// or curly quotes: “tool” cannot be opened because ...
+var gatekeeperSubject = regexp.MustCompile(`["“']([^"”']+)["”'] (?:cannot|can't|can’t|is damaged|Not Opened)`)
+
+// DetectGatekeeperBlock reports whether the output of command shows a Gatekeeper or quarantine
+// refusal, and if so the checks and the precise xattr/spctl remediation for the blocked file.
+func DetectGatekeeperBlock(command, stdout, stderr string) (GatekeeperBlock, bool) {
+	combined := stderr + "\n" + stdout
+	matched := false
+	for _, p := range gatekeeperPatterns {
+		if strings.Contains(combined, p) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return GatekeeperBlock{}, false
+	}
+
+	path := ""
+	if m := gatekeeperSubject.FindStringSubmatch(combined); m != nil {
+		path = m[1]
+	}
+	if fields := strings.Fields(command); len(fields) > 0 {
+		first := fields[0]
+		if first == "open" && len(fields) > 1 {
+			first = fields[len(fields)-1]
+		}
+		// Prefer the command's own path: the message often names only the file, not where it is
+		if strings.Contains(first, "/") && (path == "" || strings.HasSuffix(strings.TrimSuffix(first, "/"), path)) {
+			path = first
+		}
+	}
+	if path == "" {
+		path = "<path-to-app>"
+	}
+	target := shell.Quote(path)
+
+	removeFlags := "-d"
+	if strings.HasSuffix(strings.TrimSuffix(path, "/"), ".app") {
+		// The attribute is set on every file inside a downloaded bundle
+		removeFlags = "-dr"
+	}
+	return GatekeeperBlock{
+		Path: path,
+		Checks: []string{
+			"xattr -p com.apple.quarantine " + target,
+			"spctl --assess --verbose " + target,
+		},
+		Remediation: []string{
+			fmt.Sprintf("xattr %s com.apple.quarantine %s", removeFlags, target),
+			"spctl --add --label 'Approved' " + target,
+		},
+	}, true
+}
+
+// Note summarises the block for an LLM prompt.
+func (g GatekeeperBlock) Note() string {
+	return fmt.Sprintf("macOS Gatekeeper blocked %s (quarantine attribute / unverified developer); only remove the quarantine with '%s' if the user trusts the file",
+		g.Path, g.Remediation[0])
+}
@@ -0,0 +1,43 @@
+package classification
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectGatekeeperBlock(t *testing.T) {
+	stderr := "“mytool” cannot be opened because the developer cannot be verified.\nzsh: killed     ./bin/mytool\n"
+	block, ok := DetectGatekeeperBlock("./bin/mytool --version", "", stderr)
+	if !ok {
+		t.Fatal("expected a Gatekeeper block")
+	}
+	if block.Path != "./bin/mytool" {
+		t.Errorf("path = %q, want the command's path", block.Path)
+	}
+	if block.Remediation[0] != "xattr -d com.apple.quarantine ./bin/mytool" {
+		t.Errorf("unexpected remediation: %q", block.Remediation[0])
+	}
+	if !strings.Contains(block.Note(), "./bin/mytool") {
+		t.Errorf("note should name the file: %q", block.Note())
+	}
+}
+
+func TestDetectGatekeeperBlockAppBundle(t *testing.T) {
+	stderr := `"My App.app" is damaged and can't be opened. You should move it to the Trash.`
+	block, ok := DetectGatekeeperBlock("open /Applications/My\\ App.app", "", stderr)
+	if !ok {
+		t.Fatal("expected a Gatekeeper block")
+	}
+	if block.Path != "My App.app" {
+		t.Errorf("path = %q, want the name from the message", block.Path)
+	}
+	if block.Remediation[0] != "xattr -dr com.apple.quarantine 'My App.app'" {
+		t.Errorf("app bundles need a recursive removal, got %q", block.Remediation[0])
+	}
+}
+
+func TestDetectGatekeeperBlockIgnoresOtherErrors(t *testing.T) {
+	if _, ok := DetectGatekeeperBlock("ls /root", "", "ls: /root: Permission denied"); ok {
+		t.Error("ordinary permission errors are not Gatekeeper blocks")
+	}
+}
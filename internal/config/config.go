@@ -2,17 +2,85 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // ProviderConfig stores the configuration for a single LLM provider.
 type ProviderConfig struct {
 	APIEndpoint  string `json:"api_endpoint"`
 	APIKey       string `json:"api_key"`
+	APIKeyFile   string `json:"api_key_file,omitempty"` // File holding the key, read for every request (OpenAI-compatible); replaces APIKey
 	Model        string `json:"model"`
 	Project      string `json:"project,omitempty"`        // For Gemini-CLI
 	OmitV1Prefix bool   `json:"omit_v1_prefix,omitempty"` // For OpenAI-compatible APIs that do not use the /v1 prefix
+
+	// Token limits overriding the built-in model registry (0 = use the registry)
+	ContextWindow   int `json:"context_window,omitempty"`
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	// Reasoning effort for OpenAI o-series models: low, medium or high (empty = API default)
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+
+	// Request transport for gemini-cli: sdk, http or curl (empty = http, or curl when AISH_GEMINI_USE_CURL is set)
+	Transport string `json:"transport,omitempty"`
+
+	// Azure OpenAI: requests go to this deployment of the resource at APIEndpoint (empty = plain OpenAI API)
+	AzureDeployment string `json:"azure_deployment,omitempty"`
+	AzureAPIVersion string `json:"azure_api_version,omitempty"` // api-version query parameter (empty = DefaultAzureAPIVersion)
+
+	// Headers added to every request, replacing any of the same name aish sets; a User-Agent
+	// here replaces aish's own. For gateways that route or authorize on headers.
+	ExtraHeaders map[string]string `json:"extra_headers,omitempty"`
+
+	// Tolerate the response quirks of gateways such as Cloudflare AI Gateway and the LiteLLM
+	// proxy (OpenAI-compatible): envelopes around the completion, SSE keep-alives, no "object" field
+	GatewayCompat bool `json:"gateway_compat,omitempty"`
+
+	// Vertex AI: region of the endpoint (empty = GOOGLE_CLOUD_LOCATION, else us-central1) and
+	// service account key file (empty = Application Default Credentials)
+	Location        string `json:"location,omitempty"`
+	CredentialsFile string `json:"credentials_file,omitempty"`
+}
+
+// ResolveAPIKey returns the key to send with a request: the contents of APIKeyFile when one is
+// set, or else APIKey. The file is read on every call, so that a short-lived token an OIDC
+// helper keeps rewriting (as internal LLM gateways issue them) is current for each request.
+func (pc ProviderConfig) ResolveAPIKey() (string, error) {
+	path := strings.TrimSpace(pc.APIKeyFile)
+	if path == "" {
+		return pc.APIKey, nil
+	}
+	path = ExpandHome(path)
+	if err := CheckSecretFile(path); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read api_key_file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("api_key_file %s is empty", path)
+	}
+	return key, nil
+}
+
+// ExpandHome replaces a leading ~/ in path with the user's home directory.
+func ExpandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// IsAzure reports whether the provider talks to an Azure OpenAI deployment.
+func (pc ProviderConfig) IsAzure() bool {
+	return strings.TrimSpace(pc.AzureDeployment) != ""
 }
 
 // ContextConfig defines configuration options for the context enhancer.
@@ -45,19 +113,128 @@ type CacheConfig struct {
 	MaxSimilarityCache  int     `json:"max_similarity_cache"` // Max entries for similarity cache
 }
 
+// AccessibilityConfig defines accessibility options for the interactive UI.
+type AccessibilityConfig struct {
+	ScreenReader bool `json:"screen_reader"` // Replace TUI widgets and spinners with linear, prompt-based interactions
+}
+
+// UIConfig holds display preferences.
+type UIConfig struct {
+	Animations     *bool               `json:"animations,omitempty"`      // Spinners and live timers; nil means enabled
+	OutputTemplate string              `json:"output_template,omitempty"` // Go template (or preset name) for suggestions when stdout is not a terminal
+	KeyBindings    string              `json:"key_bindings,omitempty"`    // Settings TUI key style: default, vim or emacs; empty = default
+	Keys           map[string][]string `json:"keys,omitempty"`            // Per-action key overrides on top of KeyBindings, e.g. "up": ["ctrl+p", "up"]
+}
+
+// AnimationsEnabled reports whether spinners and live timers may be shown.
+func (u UIConfig) AnimationsEnabled() bool {
+	return u.Animations == nil || *u.Animations
+}
+
+// UpdatesConfig controls the periodic check for new aish releases.
+type UpdatesConfig struct {
+	Check *bool `json:"check,omitempty"` // Weekly background release check; nil means enabled
+}
+
+// CheckEnabled reports whether aish may look for new releases in the background.
+func (u UpdatesConfig) CheckEnabled() bool {
+	return u.Check == nil || *u.Check
+}
+
+// TelemetryConfig controls the local feature-usage counters.
+type TelemetryConfig struct {
+	Enabled bool `json:"enabled"` // Count which commands are used, on this machine only; off unless the user opts in
+}
+
+// Defaults for CommandLimitsConfig.
+const (
+	DefaultMaxCommandLength    = 400
+	DefaultMaxCommandPipes     = 5
+	DefaultMaxCommandRedirects = 4
+)
+
+// CommandLimitsConfig bounds how long and involved a generated command may be before it needs
+// confirmation: very long or heavily chained output is often risky or hallucinated.
+// For each limit 0 means the default and -1 means unlimited.
+type CommandLimitsConfig struct {
+	MaxLength    int `json:"max_length,omitempty"`    // Characters (0 = 400)
+	MaxPipes     int `json:"max_pipes,omitempty"`     // Pipes between commands (0 = 5)
+	MaxRedirects int `json:"max_redirects,omitempty"` // Redirections such as > file or 2>&1 (0 = 4)
+}
+
+// Effective returns the limits with defaults applied; -1 still means unlimited.
+func (c CommandLimitsConfig) Effective() CommandLimitsConfig {
+	orDefault := func(v, def int) int {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	return CommandLimitsConfig{
+		MaxLength:    orDefault(c.MaxLength, DefaultMaxCommandLength),
+		MaxPipes:     orDefault(c.MaxPipes, DefaultMaxCommandPipes),
+		MaxRedirects: orDefault(c.MaxRedirects, DefaultMaxCommandRedirects),
+	}
+}
+
+// Policies for DangerousCommands: how a suggestion that can wreck the system or run a script
+// from the network (see security.Dangerous) is treated.
+const (
+	DangerousCommandsConfirm = "confirm" // Run only after the user types the confirmation phrase
+	DangerousCommandsBlock   = "block"   // Never run
+)
+
 // UserPreferences stores user-specific settings.
 type UserPreferences struct {
-	Language           string        `json:"language"`
-	EnabledLLMTriggers []string      `json:"enabled_llm_triggers"`
-	AutoExecute        bool          `json:"auto_execute"` // Automatically execute generated commands without user confirmation
-	Context            ContextConfig `json:"context"`
-	Logging            LoggingConfig `json:"logging"`
-	Cache              CacheConfig   `json:"cache"`
-	MaxHistorySize     int           `json:"max_history_size"`
+	Language           string              `json:"language"`
+	LanguageFallback   []string            `json:"language_fallback,omitempty"` // Languages whose prompt templates are used when Language has none, e.g. zh-TW, en; empty = the other Chinese script, then English
+	Locale             string              `json:"locale,omitempty"`            // How dates and numbers are written, e.g. de_DE; empty = LC_ALL/LC_TIME/LANG
+	Timezone           string              `json:"timezone,omitempty"`          // IANA time zone for displayed times, e.g. Europe/Berlin; empty = system
+	EnabledLLMTriggers []string            `json:"enabled_llm_triggers"`
+	AutoExecute        bool                `json:"auto_execute"` // Automatically execute generated commands without user confirmation
+	Context            ContextConfig       `json:"context"`
+	Logging            LoggingConfig       `json:"logging"`
+	Cache              CacheConfig         `json:"cache"`
+	MaxHistorySize     int                 `json:"max_history_size"`
+	Accessibility      AccessibilityConfig `json:"accessibility"`
+	UI                 UIConfig            `json:"ui"`
+	Updates            UpdatesConfig       `json:"updates"`
+	Telemetry          TelemetryConfig     `json:"telemetry"`
 
 	// Core AISH settings
 	ShowTips      bool `json:"show_tips"`      // Display helpful tips during usage
 	VerboseOutput bool `json:"verbose_output"` // Show detailed diagnostic information
+	Warmup        bool `json:"warmup"`         // Open the provider connection in the background before the first request
+
+	CleanupMaxAgeHours int `json:"cleanup_max_age_hours,omitempty"` // Age after which orphaned capture/temp files are removed (0 = 24h)
+
+	FallbackProvider     string   `json:"fallback_provider,omitempty"`       // Provider offered when the default one is slow
+	SlowProviderSeconds  int      `json:"slow_provider_seconds,omitempty"`   // Wait before offering the fallback provider (0 = 8s)
+	FallbackProviders    []string `json:"fallback_providers,omitempty"`      // Providers asked in turn when the chosen one fails or times out
+	RateLimitWaitSeconds int      `json:"rate_limit_wait_seconds,omitempty"` // Longest rate-limit reset to wait out before retrying (0 = don't wait)
+	ContentFilterRetry   bool     `json:"content_filter_retry,omitempty"`    // Retry an error analysis the content filter refused with a sanitized context
+	ConsensusProvider    string   `json:"consensus_provider,omitempty"`      // Second provider asked about destructive suggestions; empty = off
+
+	AllowComplexCommands bool                `json:"allow_complex_commands,omitempty"` // Run suggestions with several lines, backticks or $(...) without confirming
+	CommandLimits        CommandLimitsConfig `json:"command_limits"`                   // Length and chaining beyond which suggestions need confirming
+	DangerousCommands    string              `json:"dangerous_commands,omitempty"`     // confirm or block suggestions such as rm -rf / or curl | sh (empty = confirm)
+	CommandPreview       bool                `json:"command_preview,omitempty"`        // List the files a suggested command would delete, move or overwrite before asking
+
+	FixCredentialPermissions bool `json:"fix_credential_permissions,omitempty"` // Restrict credential files others can access to 0600 at startup instead of only warning
+	TrialRelay               bool `json:"trial_relay,omitempty"`                // Answer with the hosted trial relay while no provider of one's own is configured
+
+	MaxConcurrentRequests int `json:"max_concurrent_requests,omitempty"` // Provider requests in flight across all aish processes (0 = 2, -1 = unlimited)
+
+	Proxy string `json:"proxy,omitempty"` // http(s):// or socks5(h):// proxy for provider requests; empty = HTTPS_PROXY/HTTP_PROXY/ALL_PROXY
+}
+
+// DangerousCommandPolicy returns DangerousCommands, or DangerousCommandsConfirm when it is
+// unset or unknown: a typo must not turn the guard off.
+func (u UserPreferences) DangerousCommandPolicy() string {
+	if strings.EqualFold(strings.TrimSpace(u.DangerousCommands), DangerousCommandsBlock) {
+		return DangerousCommandsBlock
+	}
+	return DangerousCommandsConfirm
 }
 
 // Config is the main configuration structure for the application.
@@ -66,15 +243,73 @@ type Config struct {
 	DefaultProvider string                    `json:"default_provider"`
 	Providers       map[string]ProviderConfig `json:"providers"`
 	UserPreferences UserPreferences           `json:"user_preferences"`
+	Routing         RoutingConfig             `json:"routing,omitempty"`
+}
+
+// Flows whose provider and model can be pinned in RoutingConfig.
+const (
+	FlowCapture = "capture" // explaining a failed command
+	FlowAsk     = "ask"     // aish -p and aish -a
+)
+
+// RoutingConfig pins a provider or model to a flow, so that for example captured errors go to
+// a fast model while questions go to a stronger one. Empty fields follow the default provider.
+type RoutingConfig struct {
+	CaptureProvider string `json:"capture_provider,omitempty"`
+	CaptureModel    string `json:"capture_model,omitempty"`
+	AskProvider     string `json:"ask_provider,omitempty"`
+	AskModel        string `json:"ask_model,omitempty"`
+}
+
+// Route returns the provider flow runs on and the model pinned for it. The provider is the
+// default provider unless the flow has its own; the model is empty unless pinned.
+func (c *Config) Route(flow string) (provider, model string) {
+	switch flow {
+	case FlowCapture:
+		provider, model = c.Routing.CaptureProvider, c.Routing.CaptureModel
+	case FlowAsk:
+		provider, model = c.Routing.AskProvider, c.Routing.AskModel
+	}
+	if provider = strings.TrimSpace(provider); provider == "" {
+		provider = c.DefaultProvider
+	}
+	return provider, strings.TrimSpace(model)
+}
+
+// DefaultProviderConfig returns the settings a new configuration starts with for provider name.
+func DefaultProviderConfig(name string) (ProviderConfig, bool) {
+	pc, ok := newDefaultConfig().Providers[name]
+	return pc, ok
+}
+
+// UseProvider makes name the default provider, adding its default settings when it has none
+// yet, and sets its model unless model is empty.
+func (c *Config) UseProvider(name, model string) error {
+	if !IsValidProvider(name) {
+		return fmt.Errorf("unknown provider %q (use %s)", name, strings.Join(GetSupportedProviders(), ", "))
+	}
+	if c.Providers == nil {
+		c.Providers = map[string]ProviderConfig{}
+	}
+	pc, ok := c.Providers[name]
+	if !ok {
+		pc, _ = DefaultProviderConfig(name)
+	}
+	if model = strings.TrimSpace(model); model != "" {
+		pc.Model = model
+	}
+	c.Providers[name] = pc
+	c.DefaultProvider = name
+	return nil
 }
 
 // GetConfigPath returns the full path to the configuration file.
 func GetConfigPath() (string, error) {
-	home, err := os.UserHomeDir()
+	dir, err := ConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, DefaultConfigDir, DefaultConfigFileName), nil
+	return filepath.Join(dir, DefaultConfigFileName), nil
 }
 
 // Load reads the configuration from the file, or returns a default config.
@@ -88,9 +323,10 @@ func newDefaultConfig() *Config {
 			ProviderGeminiCLI: {APIEndpoint: GeminiCLIAPIEndpoint, Project: "YOUR_GEMINI_PROJECT_ID", Model: DefaultGeminiCLIModel},
 			ProviderClaude:    {APIEndpoint: ClaudeAPIEndpoint, APIKey: "", Model: DefaultClaudeModel},
 			ProviderOllama:    {APIEndpoint: OllamaAPIEndpoint, APIKey: "", Model: DefaultOllamaModel},
+			ProviderVertex:    {Location: DefaultVertexLocation, Model: DefaultVertexModel},
 		},
 		UserPreferences: UserPreferences{
-			Language: "en",
+			Language: "", // Unset: follow the system locale (see EffectiveLanguage)
 			EnabledLLMTriggers: []string{
 				"CommandNotFound",
 				"FileNotFoundOrDirectory",
@@ -147,6 +383,9 @@ func newDefaultConfig() *Config {
 }
 
 func Load() (*Config, error) {
+	if path, err := GetConfigPath(); err == nil {
+		restrictConfigFile(path)
+	}
 	// Use new migration loading system, fallback to legacy format loading for compatibility
 	cfg, migrationResult, err := LoadWithMigration()
 	if err != nil {
@@ -212,8 +451,8 @@ func LoadLegacy() (*Config, error) {
 
 	// Set default log file path (if not set)
 	if cfg.UserPreferences.Logging.LogFile == "" {
-		home, _ := os.UserHomeDir()
-		cfg.UserPreferences.Logging.LogFile = filepath.Join(home, DefaultConfigDir, DefaultLogDir, DefaultLogFileName)
+		logDir, _ := LogDir()
+		cfg.UserPreferences.Logging.LogFile = filepath.Join(logDir, DefaultLogFileName)
 	}
 
 	// If it's a newly created config, save it
@@ -240,5 +479,5 @@ func (c *Config) Save() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return writeSecretFile(path, data)
 }
@@ -58,8 +58,8 @@ func TestNewDefaultConfig(t *testing.T) {
 
 	// Test user preferences
 	prefs := config.UserPreferences
-	if prefs.Language != "en" {
-		t.Errorf("Expected language 'en', got %s", prefs.Language)
+	if prefs.Language != "" {
+		t.Errorf("Expected language to be unset so the locale decides, got %s", prefs.Language)
 	}
 
 	if prefs.AutoExecute {
@@ -253,8 +253,8 @@ func TestLoadNonExistentConfig(t *testing.T) {
 		t.Error("Default config should be enabled")
 	}
 
-	if len(config.Providers) != 5 {
-		t.Errorf("Expected 5 default providers, got %d", len(config.Providers))
+	if len(config.Providers) != 6 {
+		t.Errorf("Expected 6 default providers, got %d", len(config.Providers))
 	}
 
 	// Test that default error triggers are set
@@ -279,7 +279,7 @@ func TestConfigConstants(t *testing.T) {
 
 	// Test providers
 	supportedProviders := GetSupportedProviders()
-	expectedProviders := []string{ProviderOpenAI, ProviderGemini, ProviderGeminiCLI, ProviderClaude, ProviderOllama}
+	expectedProviders := []string{ProviderOpenAI, ProviderGemini, ProviderGeminiCLI, ProviderClaude, ProviderOllama, ProviderVertex}
 
 	if len(supportedProviders) != len(expectedProviders) {
 		t.Errorf("Expected %d supported providers, got %d", len(expectedProviders), len(supportedProviders))
@@ -328,3 +328,63 @@ func TestConfigConstants(t *testing.T) {
 		t.Error("Invalid provider should not be considered valid")
 	}
 }
+
+func TestUIConfigAnimationsDefaultOn(t *testing.T) {
+	var ui UIConfig
+	if !ui.AnimationsEnabled() {
+		t.Error("animations should default to enabled when unset")
+	}
+	off := false
+	ui.Animations = &off
+	if ui.AnimationsEnabled() {
+		t.Error("animations should be disabled when set to false")
+	}
+}
+
+func TestCommandLimitsEffective(t *testing.T) {
+	got := CommandLimitsConfig{MaxPipes: 2, MaxRedirects: -1}.Effective()
+	want := CommandLimitsConfig{MaxLength: DefaultMaxCommandLength, MaxPipes: 2, MaxRedirects: -1}
+	if got != want {
+		t.Errorf("Effective() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUseProvider(t *testing.T) {
+	cfg := &Config{DefaultProvider: ProviderOpenAI, Providers: map[string]ProviderConfig{
+		ProviderOpenAI: {APIEndpoint: "https://example.test/v1", Model: "gpt-4o"},
+	}}
+
+	if err := cfg.UseProvider("nope", ""); err == nil || cfg.DefaultProvider != ProviderOpenAI {
+		t.Fatalf("unknown provider accepted: %v, default %q", err, cfg.DefaultProvider)
+	}
+	if err := cfg.UseProvider(ProviderClaude, ""); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DefaultProvider != ProviderClaude || cfg.Providers[ProviderClaude].Model != DefaultClaudeModel {
+		t.Errorf("claude not set up with defaults: %+v", cfg.Providers[ProviderClaude])
+	}
+	if err := cfg.UseProvider(ProviderOpenAI, "gpt-4.1"); err != nil {
+		t.Fatal(err)
+	}
+	if pc := cfg.Providers[ProviderOpenAI]; pc.Model != "gpt-4.1" || pc.APIEndpoint != "https://example.test/v1" {
+		t.Errorf("existing settings not kept: %+v", pc)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	cfg := &Config{DefaultProvider: ProviderOpenAI, Routing: RoutingConfig{
+		CaptureProvider: ProviderGeminiCLI,
+		CaptureModel:    "gemini-2.5-flash",
+		AskModel:        " gpt-4o ",
+	}}
+
+	for flow, want := range map[string][2]string{
+		FlowCapture: {ProviderGeminiCLI, "gemini-2.5-flash"},
+		FlowAsk:     {ProviderOpenAI, "gpt-4o"},
+		"rpc":       {ProviderOpenAI, ""},
+	} {
+		if provider, model := cfg.Route(flow); provider != want[0] || model != want[1] {
+			t.Errorf("Route(%q) = %q, %q, want %q, %q", flow, provider, model, want[0], want[1])
+		}
+	}
+}
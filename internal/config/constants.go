@@ -13,6 +13,9 @@ const (
 	DefaultStateDir       = ".config/aish"
 	DefaultLogDir         = "logs"
 	DefaultCacheDir       = "cache"
+	DefaultProfilesDir    = "profiles"
+	DefaultProfile        = "default"
+	DefaultSharedCacheDir = "/var/cache/aish"
 	DefaultConfigFileName = "config.json"
 	DefaultLogFileName    = "aish.log"
 
@@ -53,6 +56,9 @@ const (
 	DefaultGeminiCLIModel = "gemini-2.5-flash"
 	DefaultClaudeModel    = "claude-3-5-sonnet-20241022"
 	DefaultOllamaModel    = "llama3.3"
+	DefaultVertexModel    = "gemini-2.5-flash"
+	DefaultRelayModel     = "auto" // The relay picks the model
+	DefaultVertexLocation = "us-central1"
 
 	// Log levels
 	LogLevelTrace = "trace"
@@ -79,9 +85,24 @@ const (
 	// Environment variables
 	EnvAISHDebug               = "AISH_DEBUG"
 	EnvAISHStateDir            = "AISH_STATE_DIR"
+	EnvAISHConfigDir           = "AISH_CONFIG_DIR"
+	EnvAISHProfile             = "AISH_PROFILE"                    // Credential profile, e.g. work or personal
+	EnvAISHShell               = "AISH_SHELL"                      // Shell generated commands are written for and run in
+	EnvAISHSharedCacheDir      = "AISH_SHARED_CACHE_DIR"           // System-wide read-only cache; "off" disables it
+	EnvAISHDemoMode            = "AISH_DEMO_MODE"                  // Classroom/demo mode: mock provider, hidden secrets, watermark
+	EnvAISHAllowInsecureCreds  = "AISH_ALLOW_INSECURE_CREDENTIALS" // "1" sends keys read from files anyone can read
+	EnvAISHRelayURL            = "AISH_RELAY_URL"                  // Trial relay to use instead of the one built in
+	EnvXDGConfigHome           = "XDG_CONFIG_HOME"
+	EnvXDGStateHome            = "XDG_STATE_HOME"
+	EnvXDGCacheHome            = "XDG_CACHE_HOME"
 	EnvAISHStdoutFile          = "AISH_STDOUT_FILE"
 	EnvAISHStderrFile          = "AISH_STDERR_FILE"
+	EnvAISHPipeStatus          = "AISH_PIPESTATUS"        // Exit status of each pipeline stage, set by the hook
+	EnvAISHCommandExpansion    = "AISH_COMMAND_EXPANSION" // Alias or function definition of the failed command, set by the hook
+	EnvAISHHistFile            = "AISH_HISTFILE"          // Shell history file of the shell that ran the failed command, set by the hook
+	EnvAISHSessionHistory      = "AISH_SESSION_HISTORY"   // In-memory history of the bash session, one command per line ('fc -ln'), set by the hook
 	EnvAISHCaptureOff          = "AISH_CAPTURE_OFF"
+	EnvAISHRecordSession       = "AISH_RECORD_SESSION" // File to write the captured context, prompt, raw response and parse result to
 	EnvAISHHookDisabled        = "AISH_HOOK_DISABLED"
 	EnvAISHSkipCommandPatterns = "AISH_SKIP_COMMAND_PATTERNS"
 	EnvAISHSkipAllUserCommands = "AISH_SKIP_ALL_USER_COMMANDS"
@@ -92,17 +113,11 @@ const (
 	EnvAISHGeminiProject       = "AISH_GEMINI_PROJECT"
 	EnvAISHGeminiBearer        = "AISH_GEMINI_BEARER"
 	EnvAISHGeminiUseCURL       = "AISH_GEMINI_USE_CURL"
+	EnvAISHGeminiCurlExec      = "AISH_GEMINI_CURL_EXEC" // Deprecated: shell out to curl instead of the native curl-parity client
 	EnvAISHGeminiTimeout       = "AISH_GEMINI_TIMEOUT"
 	EnvAISHGeminiCAFile        = "AISH_GEMINI_CA_FILE"
 	EnvAISHGeminiSkipTLSVerify = "AISH_GEMINI_SKIP_TLS_VERIFY"
-
-	// Exit codes
-	ExitSuccess         = 0
-	ExitGenericError    = 1
-	ExitPermissionError = 77
-	ExitConfigError     = 78
-	ExitAuthError       = 79
-	ExitUserCancel      = 130
+	EnvAISHGeminiLocation      = "AISH_GEMINI_LOCATION"
 
 	// Provider names
 	ProviderOpenAI    = "openai"
@@ -110,6 +125,17 @@ const (
 	ProviderGeminiCLI = "gemini-cli"
 	ProviderClaude    = "claude"
 	ProviderOllama    = "ollama"
+	ProviderVertex    = "vertex" // Gemini on Vertex AI with a service account or ADC
+	ProviderMock      = "mock"   // Canned responses, forced by demo mode; needs no configuration
+	ProviderRelay     = "relay"  // Hosted trial relay: no key, rate-limited; opted into with 'aish trial'
+
+	// Gemini CLI request transports (providers.gemini-cli.transport)
+	GeminiTransportSDK  = "sdk"
+	GeminiTransportHTTP = "http"
+	GeminiTransportCURL = "curl"
+
+	// Azure OpenAI data-plane API version used when providers.openai.azure_api_version is unset
+	DefaultAzureAPIVersion = "2024-10-21"
 
 	// Default system directory whitelist (colon-separated)
 	DefaultSystemDirWhitelist        = "/bin:/usr/bin:/sbin:/usr/sbin:/usr/libexec:/System/Library:/lib:/usr/lib"
@@ -119,6 +145,7 @@ const (
 	DefaultDirPermissions  = 0755
 	DefaultFilePermissions = 0644
 	DefaultExecPermissions = 0755
+	SecretFilePermissions  = 0600 // Config and credential files: their owner only
 
 	// Validation limits
 	MaxProviderNameLength = 50
@@ -167,6 +194,7 @@ func GetSupportedProviders() []string {
 		ProviderGeminiCLI,
 		ProviderClaude,
 		ProviderOllama,
+		ProviderVertex,
 	}
 }
 
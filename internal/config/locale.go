@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultLanguage is used when neither the configuration nor the locale names a language.
+const DefaultLanguage = "english"
+
+// localeLanguages maps locale language codes to user_preferences.language values.
+var localeLanguages = map[string]string{
+	"en": "english",
+	"ja": "ja",
+	"ko": "ko",
+	"es": "es",
+	"fr": "fr",
+	"de": "de",
+	"ar": "ar",
+	"he": "he",
+}
+
+// LanguageFromLocale derives a response language from the POSIX locale variables, honouring
+// their precedence (LC_ALL, then LC_MESSAGES, then LANG). It returns "" when the locale is
+// unset, C/POSIX, or a language aish has no templates for.
+func LanguageFromLocale(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := strings.TrimSpace(getenv(name)); v != "" {
+			return localeToLanguage(v)
+		}
+	}
+	return ""
+}
+
+// localeToLanguage maps a locale such as "zh_TW.UTF-8" or "de_DE@euro" to a language value.
+func localeToLanguage(locale string) string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ReplaceAll(locale, "-", "_")
+	lang, region, _ := strings.Cut(locale, "_")
+	lang = strings.ToLower(lang)
+	if lang == "zh" {
+		// Traditional script for Taiwan, Hong Kong and Macau; Simplified elsewhere
+		switch strings.ToUpper(region) {
+		case "TW", "HK", "MO", "HANT":
+			return "zh-TW"
+		}
+		return "zh-CN"
+	}
+	return localeLanguages[lang]
+}
+
+// EffectiveLanguage returns the configured response language or, when none is set, the one
+// derived from the system locale, falling back to English.
+func (u UserPreferences) EffectiveLanguage() string {
+	if lang := strings.TrimSpace(u.Language); lang != "" {
+		return lang
+	}
+	if lang := LanguageFromLocale(os.Getenv); lang != "" {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// FormattingLocale returns the locale dates and numbers are written in, from the POSIX
+// locale variables in their precedence (LC_ALL, then LC_TIME, then LANG), without the
+// encoding or modifier ("de_DE.UTF-8" → "de_DE"). It returns "" when the locale is unset or
+// C/POSIX.
+func FormattingLocale(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		v := strings.TrimSpace(getenv(name))
+		if v == "" {
+			continue
+		}
+		if i := strings.IndexAny(v, ".@"); i >= 0 {
+			v = v[:i]
+		}
+		if v == "C" || v == "POSIX" {
+			return ""
+		}
+		return v
+	}
+	return ""
+}
+
+// EffectiveLocale returns the configured formatting locale or, when none is set, the one of
+// the environment.
+func (u UserPreferences) EffectiveLocale() string {
+	if locale := strings.TrimSpace(u.Locale); locale != "" {
+		return locale
+	}
+	return FormattingLocale(os.Getenv)
+}
+
+// TimeLocation returns the configured time zone, or the system one when none is set or the
+// configured name is unknown.
+func (u UserPreferences) TimeLocation() *time.Location {
+	if name := strings.TrimSpace(u.Timezone); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
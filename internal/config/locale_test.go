@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLanguageFromLocale(t *testing.T) {
+	cases := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"LANG": "ja_JP.UTF-8"}, "ja"},
+		{map[string]string{"LANG": "zh_TW.UTF-8"}, "zh-TW"},
+		{map[string]string{"LANG": "zh_HK"}, "zh-TW"},
+		{map[string]string{"LANG": "zh_CN.GB18030"}, "zh-CN"},
+		{map[string]string{"LANG": "de_DE@euro"}, "de"},
+		{map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "fr_FR.UTF-8"}, "fr"},
+		{map[string]string{"LANG": "ko_KR.UTF-8", "LC_MESSAGES": "es_ES"}, "es"},
+		{map[string]string{"LANG": "ja_JP.UTF-8", "LC_ALL": "C"}, ""},
+		{map[string]string{"LANG": "POSIX"}, ""},
+		{map[string]string{"LANG": "sv_SE.UTF-8"}, ""},
+		{map[string]string{}, ""},
+	}
+	for _, tc := range cases {
+		getenv := func(k string) string { return tc.env[k] }
+		if got := LanguageFromLocale(getenv); got != tc.want {
+			t.Errorf("LanguageFromLocale(%v) = %q, want %q", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestEffectiveLanguage(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "ja_JP.UTF-8")
+
+	if got := (UserPreferences{}).EffectiveLanguage(); got != "ja" {
+		t.Errorf("unset language = %q, want the locale's ja", got)
+	}
+	if got := (UserPreferences{Language: "english"}).EffectiveLanguage(); got != "english" {
+		t.Errorf("explicit language = %q, want english", got)
+	}
+	t.Setenv("LANG", "C.UTF-8")
+	if got := (UserPreferences{}).EffectiveLanguage(); got != DefaultLanguage {
+		t.Errorf("C locale = %q, want %q", got, DefaultLanguage)
+	}
+}
+
+func TestFormattingLocale(t *testing.T) {
+	cases := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"LANG": "de_DE.UTF-8"}, "de_DE"},
+		{map[string]string{"LANG": "en_US.UTF-8", "LC_TIME": "en_GB.UTF-8"}, "en_GB"},
+		{map[string]string{"LC_TIME": "en_GB", "LC_ALL": "fr_FR@euro"}, "fr_FR"},
+		{map[string]string{"LANG": "C.UTF-8"}, ""},
+		{map[string]string{}, ""},
+	}
+	for _, tc := range cases {
+		getenv := func(k string) string { return tc.env[k] }
+		if got := FormattingLocale(getenv); got != tc.want {
+			t.Errorf("FormattingLocale(%v) = %q, want %q", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	if got := (UserPreferences{}).TimeLocation(); got != time.Local {
+		t.Errorf("unset timezone = %v, want the system zone", got)
+	}
+	if got := (UserPreferences{Timezone: "No/Such_Zone"}).TimeLocation(); got != time.Local {
+		t.Errorf("unknown timezone = %v, want the system zone", got)
+	}
+	if got := (UserPreferences{Timezone: "UTC"}).TimeLocation(); got.String() != "UTC" {
+		t.Errorf("timezone UTC = %v", got)
+	}
+}
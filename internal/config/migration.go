@@ -264,7 +264,7 @@ func (m *Migrator) saveVersionedConfig(cfg *Config, version string) error {
     }
 
 	// 寫入文件
-	return os.WriteFile(m.configPath, data, 0644)
+	return writeSecretFile(m.configPath, data)
 }
 
 // createBackup 創建配置文件備份
@@ -293,7 +293,7 @@ func (m *Migrator) createBackup() (string, error) {
         return "", aerrors.ErrFileSystemError("backup_read", m.configPath, err)
     }
 
-    if err := os.WriteFile(backupPath, data, 0644); err != nil {
+    if err := writeSecretFile(backupPath, data); err != nil {
         return "", aerrors.ErrFileSystemError("backup_write", backupPath, err)
     }
 
@@ -302,8 +302,8 @@ func (m *Migrator) createBackup() (string, error) {
 
 // getDefaultLogPath 獲取默認日誌文件路徑
 func (m *Migrator) getDefaultLogPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".config", "aish", "logs", "aish.log")
+	dir, _ := LogDir()
+	return filepath.Join(dir, DefaultLogFileName)
 }
 
 // CheckConfigVersion 檢查配置文件版本
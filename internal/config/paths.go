@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SetConfigDir points every aish path at dir for this process (the --config-dir flag).
+// The value is exported through AISH_CONFIG_DIR so child processes such as the shell
+// hook's 'aish capture' inherit it.
+func SetConfigDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	return os.Setenv(EnvAISHConfigDir, abs)
+}
+
+// explicitConfigDir returns the directory set with --config-dir or AISH_CONFIG_DIR, if any.
+func explicitConfigDir() string {
+	return strings.TrimSpace(os.Getenv(EnvAISHConfigDir))
+}
+
+// xdgDir returns $<env>/aish when the XDG variable holds an absolute path, as the
+// specification requires; relative values are ignored.
+func xdgDir(env string) string {
+	if v := strings.TrimSpace(os.Getenv(env)); v != "" && filepath.IsAbs(v) {
+		return filepath.Join(v, AppName)
+	}
+	return ""
+}
+
+// ConfigDir returns the directory holding config.json: --config-dir / AISH_CONFIG_DIR,
+// then $XDG_CONFIG_HOME/aish, then ~/.config/aish.
+func ConfigDir() (string, error) {
+	if dir := explicitConfigDir(); dir != "" {
+		return dir, nil
+	}
+	if dir := xdgDir(EnvXDGConfigHome); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, DefaultConfigDir), nil
+}
+
+// StateDir returns the directory for data aish writes as it runs: history, provider health
+// and logs. An explicit config directory keeps everything together; otherwise
+// $XDG_STATE_HOME/aish is used when set, and the config directory (the historical layout)
+// when not, so existing installs keep their history.
+func StateDir() (string, error) {
+	if dir := explicitConfigDir(); dir != "" {
+		return dir, nil
+	}
+	if dir := xdgDir(EnvXDGStateHome); dir != "" {
+		return dir, nil
+	}
+	return ConfigDir()
+}
+
+// HookStateDir returns the directory the shell hook writes its capture files to: AISH_STATE_DIR
+// when set, otherwise StateDir. The hooks in internal/shell/assets resolve it in the same order,
+// so the janitor cleans up where they write.
+func HookStateDir() (string, error) {
+	if dir := strings.TrimSpace(os.Getenv(EnvAISHStateDir)); dir != "" {
+		return dir, nil
+	}
+	return StateDir()
+}
+
+// CacheDir returns the response cache directory: $XDG_CACHE_HOME/aish when set (and no
+// explicit config directory is), otherwise the "cache" directory under the config directory.
+func CacheDir() (string, error) {
+	if explicitConfigDir() == "" {
+		if dir := xdgDir(EnvXDGCacheHome); dir != "" {
+			return dir, nil
+		}
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, DefaultCacheDir), nil
+}
+
+// LogDir returns the directory for log files and crash reports.
+func LogDir() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, DefaultLogDir), nil
+}
+
+// profileNamePattern limits profile names to ones that are safe as a directory name.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// SetProfile selects the credential profile for this process (the --profile flag). Like
+// SetConfigDir it is exported, through AISH_PROFILE, to child processes.
+func SetProfile(name string) error {
+	name = strings.TrimSpace(name)
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return os.Setenv(EnvAISHProfile, name)
+}
+
+// Profile returns the credential profile set with --profile or AISH_PROFILE, DefaultProfile
+// when none is. A name that is not a valid profile name also yields DefaultProfile.
+func Profile() string {
+	name := strings.TrimSpace(os.Getenv(EnvAISHProfile))
+	if name == "" || !profileNamePattern.MatchString(name) {
+		return DefaultProfile
+	}
+	return name
+}
+
+// ProfileDir returns the directory holding the credentials of profile: the config directory
+// itself for DefaultProfile, so installs from before profiles keep their sign-in, and
+// profiles/<name> under it for any other profile.
+func ProfileDir(profile string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if profile == "" || profile == DefaultProfile {
+		return dir, nil
+	}
+	return filepath.Join(dir, DefaultProfilesDir, profile), nil
+}
+
+// Profiles lists DefaultProfile and every profile with a directory under profiles/, sorted.
+func Profiles() ([]string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, DefaultProfilesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && profileNamePattern.MatchString(e.Name()) && e.Name() != DefaultProfile {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
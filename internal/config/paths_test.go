@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathsDefaultLayout(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvAISHConfigDir, "")
+	t.Setenv(EnvXDGConfigHome, "")
+	t.Setenv(EnvXDGStateHome, "")
+	t.Setenv(EnvXDGCacheHome, "")
+
+	base := filepath.Join(home, DefaultConfigDir)
+	assertDir(t, ConfigDir, base)
+	assertDir(t, StateDir, base)
+	assertDir(t, CacheDir, filepath.Join(base, DefaultCacheDir))
+	assertDir(t, LogDir, filepath.Join(base, DefaultLogDir))
+}
+
+func TestPathsXDG(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(EnvAISHConfigDir, "")
+	t.Setenv(EnvXDGConfigHome, filepath.Join(root, "config"))
+	t.Setenv(EnvXDGStateHome, filepath.Join(root, "state"))
+	t.Setenv(EnvXDGCacheHome, filepath.Join(root, "cache"))
+
+	assertDir(t, ConfigDir, filepath.Join(root, "config", AppName))
+	assertDir(t, StateDir, filepath.Join(root, "state", AppName))
+	assertDir(t, CacheDir, filepath.Join(root, "cache", AppName))
+	assertDir(t, LogDir, filepath.Join(root, "state", AppName, DefaultLogDir))
+
+	// Relative XDG values are invalid per the specification and ignored
+	t.Setenv(EnvXDGStateHome, "relative/state")
+	assertDir(t, StateDir, filepath.Join(root, "config", AppName))
+}
+
+func TestPathsExplicitConfigDirWins(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(EnvXDGConfigHome, filepath.Join(root, "config"))
+	t.Setenv(EnvXDGStateHome, filepath.Join(root, "state"))
+	t.Setenv(EnvXDGCacheHome, filepath.Join(root, "cache"))
+	t.Setenv(EnvAISHConfigDir, "")
+
+	dir := filepath.Join(root, "isolated")
+	if err := SetConfigDir(dir); err != nil {
+		t.Fatalf("SetConfigDir: %v", err)
+	}
+	assertDir(t, ConfigDir, dir)
+	assertDir(t, StateDir, dir)
+	assertDir(t, CacheDir, filepath.Join(dir, DefaultCacheDir))
+
+	path, err := GetConfigPath()
+	if err != nil || path != filepath.Join(dir, DefaultConfigFileName) {
+		t.Fatalf("GetConfigPath = %q, %v", path, err)
+	}
+}
+
+func TestHookStateDir(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(EnvAISHConfigDir, "")
+	t.Setenv(EnvXDGStateHome, filepath.Join(root, "state"))
+	t.Setenv(EnvAISHStateDir, "")
+	assertDir(t, HookStateDir, filepath.Join(root, "state", AppName))
+
+	t.Setenv(EnvAISHStateDir, filepath.Join(root, "hook"))
+	assertDir(t, HookStateDir, filepath.Join(root, "hook"))
+}
+
+func assertDir(t *testing.T, fn func() (string, error), want string) {
+	t.Helper()
+	got, err := fn()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestProfileDirs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvAISHConfigDir, dir)
+	t.Setenv(EnvAISHProfile, "")
+
+	if got := Profile(); got != DefaultProfile {
+		t.Errorf("Profile() = %q, want %q", got, DefaultProfile)
+	}
+	if got, _ := ProfileDir(DefaultProfile); got != dir {
+		t.Errorf("ProfileDir(default) = %q, want the config directory %q", got, dir)
+	}
+	if err := SetProfile("../etc"); err == nil {
+		t.Error("SetProfile accepted a path")
+	}
+	if err := SetProfile("work"); err != nil || Profile() != "work" {
+		t.Fatalf("SetProfile(work) = %v, Profile() = %q", err, Profile())
+	}
+	work, _ := ProfileDir("work")
+	if want := filepath.Join(dir, DefaultProfilesDir, "work"); work != want {
+		t.Errorf("ProfileDir(work) = %q, want %q", work, want)
+	}
+
+	for _, name := range []string{"work", "personal"} {
+		if err := os.MkdirAll(filepath.Join(dir, DefaultProfilesDir, name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := Profiles(); err != nil || strings.Join(got, ",") != "default,personal,work" {
+		t.Errorf("Profiles() = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PermissionIssue is a credential file that users other than its owner can access.
+type PermissionIssue struct {
+	Path string
+	Mode os.FileMode
+}
+
+// WorldReadable reports whether any user on the system can read the file.
+func (i PermissionIssue) WorldReadable() bool {
+	return i.Mode&0o004 != 0
+}
+
+// InsecureFileError is returned for a key or token read from a file any user can read.
+type InsecureFileError struct {
+	Path string
+	Mode os.FileMode
+}
+
+func (e *InsecureFileError) Error() string {
+	return fmt.Sprintf("refusing to send credentials read from %s: it is readable by every user (mode %04o); run 'chmod 600 %s', or set %s=1 to send them anyway",
+		e.Path, e.Mode, e.Path, EnvAISHAllowInsecureCreds)
+}
+
+// CredentialFiles returns the files of this configuration that hold keys: the config file
+// itself and the key files providers name. Missing files are included; AuditPermissions skips
+// them.
+func (c *Config) CredentialFiles() []string {
+	var files []string
+	if path, err := GetConfigPath(); err == nil {
+		files = append(files, path)
+	}
+	for _, pc := range c.Providers {
+		files = append(files, pc.secretFiles()...)
+	}
+	return files
+}
+
+// secretFiles returns the files the provider reads its key or service account from.
+func (pc ProviderConfig) secretFiles() []string {
+	var files []string
+	for _, path := range []string{pc.APIKeyFile, pc.CredentialsFile} {
+		if path = strings.TrimSpace(path); path != "" {
+			files = append(files, ExpandHome(path))
+		}
+	}
+	return files
+}
+
+// AuditPermissions returns the files among paths that exist and can be accessed by others than
+// their owner. File modes carry no such meaning on Windows, where it returns nothing.
+func AuditPermissions(paths []string) []PermissionIssue {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	var issues []PermissionIssue
+	seen := map[string]bool{}
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if mode := info.Mode().Perm(); mode&0o077 != 0 {
+			issues = append(issues, PermissionIssue{Path: path, Mode: mode})
+		}
+	}
+	return issues
+}
+
+// FixPermissions restricts each file to its owner (SecretFilePermissions) and returns the
+// issues it could not fix.
+func FixPermissions(issues []PermissionIssue) []error {
+	var errs []error
+	for _, issue := range issues {
+		if err := os.Chmod(issue.Path, SecretFilePermissions); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// CheckSecretFile returns an *InsecureFileError when path, which a key or token is read from,
+// is readable by every user, unless AISH_ALLOW_INSECURE_CREDENTIALS=1. A missing file is fine:
+// nothing is read from it.
+func CheckSecretFile(path string) error {
+	if os.Getenv(EnvAISHAllowInsecureCreds) == "1" {
+		return nil
+	}
+	for _, issue := range AuditPermissions([]string{path}) {
+		if issue.WorldReadable() {
+			return &InsecureFileError{Path: issue.Path, Mode: issue.Mode}
+		}
+	}
+	return nil
+}
+
+// CheckCredentialFiles returns an *InsecureFileError when a key the provider would send is read
+// from a key file every user can read. The config file is aish's own, which Load restricts to
+// its owner instead.
+func (pc ProviderConfig) CheckCredentialFiles() error {
+	for _, path := range pc.secretFiles() {
+		if err := CheckSecretFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restrictConfigFile makes the config file at path, which holds keys, accessible to its owner
+// only. Versions before credential files were audited wrote it readable by every user.
+func restrictConfigFile(path string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+		_ = os.Chmod(path, SecretFilePermissions)
+	}
+}
+
+// writeSecretFile writes a file only its owner can access, tightening the mode of an existing
+// file, which os.WriteFile keeps.
+func writeSecretFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, SecretFilePermissions); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	return os.Chmod(path, SecretFilePermissions)
+}
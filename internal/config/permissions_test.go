@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCredentialFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes do not restrict access on Windows")
+	}
+	dir := t.TempDir()
+	t.Setenv(EnvAISHConfigDir, dir)
+	t.Setenv(EnvAISHAllowInsecureCreds, "")
+
+	cfg := newDefaultConfig()
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	configPath, _ := GetConfigPath()
+	if info, err := os.Stat(configPath); err != nil || info.Mode().Perm() != SecretFilePermissions {
+		t.Fatalf("config saved with %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	keyFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(keyFile, []byte("sk-file\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(keyFile, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Providers["openai"] = ProviderConfig{APIKeyFile: keyFile}
+	issues := AuditPermissions(cfg.CredentialFiles())
+	if len(issues) != 1 || issues[0].Path != keyFile || issues[0].WorldReadable() {
+		t.Fatalf("issues = %+v, want only the group-readable key file", issues)
+	}
+	// Readable by the group only: warned about, but still sent
+	if key, err := cfg.Providers["openai"].ResolveAPIKey(); err != nil || key != "sk-file" {
+		t.Errorf("ResolveAPIKey = %q, %v", key, err)
+	}
+
+	if err := os.Chmod(keyFile, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var insecure *InsecureFileError
+	if _, err := cfg.Providers["openai"].ResolveAPIKey(); !errors.As(err, &insecure) || insecure.Path != keyFile {
+		t.Errorf("world-readable key file: err = %v", err)
+	}
+	if err := cfg.Providers["openai"].CheckCredentialFiles(); !errors.As(err, &insecure) {
+		t.Errorf("CheckCredentialFiles = %v", err)
+	}
+	t.Setenv(EnvAISHAllowInsecureCreds, "1")
+	if _, err := cfg.Providers["openai"].ResolveAPIKey(); err != nil {
+		t.Errorf("override ignored: %v", err)
+	}
+	t.Setenv(EnvAISHAllowInsecureCreds, "")
+
+	// A config file older versions wrote readable by every user is restricted when loaded, and
+	// the keys stored in it are still sent
+	if err := os.Chmod(configPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(configPath); err != nil || info.Mode().Perm() != SecretFilePermissions {
+		t.Errorf("config left with %v after Load, %v; want 0600", info.Mode().Perm(), err)
+	}
+	if err := os.Chmod(configPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (ProviderConfig{APIKey: "sk-inline"}).CheckCredentialFiles(); err != nil {
+		t.Errorf("key in the config file refused: %v", err)
+	}
+
+	if errs := FixPermissions(AuditPermissions(cfg.CredentialFiles())); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if issues := AuditPermissions(cfg.CredentialFiles()); len(issues) != 0 {
+		t.Errorf("after FixPermissions: %+v", issues)
+	}
+}
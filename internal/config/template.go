@@ -0,0 +1,135 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// maxTemplateSize caps how much of a configuration template is read.
+const maxTemplateSize = 1 << 20
+
+// FetchTemplate reads a team configuration template from an https URL or a local path.
+// Plain http is only accepted for loopback hosts, since the template decides which
+// endpoints the user's API keys are sent to.
+func FetchTemplate(ctx context.Context, src string) ([]byte, error) {
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+		if len(data) > maxTemplateSize {
+			return nil, fmt.Errorf("template %s is larger than %d bytes", src, maxTemplateSize)
+		}
+		return data, nil
+	}
+	if u.Scheme == "http" && !isLoopbackHost(u.Hostname()) {
+		return nil, fmt.Errorf("refusing to fetch template over plain http from %s; use https", u.Host)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch template: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch template: %s returned %s", u.Host, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTemplateSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch template: %w", err)
+	}
+	if len(data) > maxTemplateSize {
+		return nil, fmt.Errorf("template at %s is larger than %d bytes", src, maxTemplateSize)
+	}
+	return data, nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// FromTemplate builds a configuration from template data laid over the defaults, so a
+// template only needs the settings a team wants to pin. Both the plain format and the
+// versioned format written by Save are accepted.
+func FromTemplate(data []byte) (*Config, error) {
+	var versioned VersionedConfig
+	if json.Unmarshal(data, &versioned) == nil && versioned.Version != "" && len(versioned.Data) > 0 {
+		data = versioned.Data
+	}
+	cfg := newDefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	if cfg.Providers == nil {
+		cfg.Providers = newDefaultConfig().Providers
+	}
+	if _, ok := cfg.Providers[cfg.DefaultProvider]; !ok {
+		return nil, fmt.Errorf("template default provider %q has no provider configuration", cfg.DefaultProvider)
+	}
+	return cfg, nil
+}
+
+// SecretField is an API key the user must supply before a provider can be used.
+type SecretField struct {
+	Provider string
+	EnvVars  []string // Environment variables that may already hold the key, in order of preference
+}
+
+// secretEnvVars lists the providers that need an API key and where one is usually found.
+var secretEnvVars = map[string][]string{
+	ProviderOpenAI: {"OPENAI_API_KEY"},
+	ProviderGemini: {"GEMINI_API_KEY", "GOOGLE_API_KEY"},
+	ProviderClaude: {"ANTHROPIC_API_KEY"},
+}
+
+// RequiredSecrets returns the API keys still missing, with no key or key file configured, for the
+// default, routed and fallback providers.
+// Other providers in the configuration are left alone, as they are not used until selected.
+func (c *Config) RequiredSecrets() []SecretField {
+	var secrets []SecretField
+	seen := map[string]bool{}
+	names := []string{c.DefaultProvider, c.Routing.CaptureProvider, c.Routing.AskProvider, c.UserPreferences.FallbackProvider}
+	for _, name := range append(names, c.UserPreferences.FallbackProviders...) {
+		envVars, ok := secretEnvVars[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		// A key file stands in for the key, which is read from it when sent
+		if pc, ok := c.Providers[name]; ok && (!isPlaceholderKey(pc.APIKey) || strings.TrimSpace(pc.APIKeyFile) != "") {
+			continue
+		}
+		secrets = append(secrets, SecretField{Provider: name, EnvVars: envVars})
+	}
+	return secrets
+}
+
+// isPlaceholderKey reports whether key is empty or one of the YOUR_..._API_KEY placeholders.
+func isPlaceholderKey(key string) bool {
+	key = strings.TrimSpace(key)
+	return key == "" || (strings.HasPrefix(key, "YOUR_") && strings.HasSuffix(key, "_API_KEY"))
+}
+
+// SetSecret stores key as the API key of provider.
+func (c *Config) SetSecret(provider, key string) {
+	pc := c.Providers[provider]
+	pc.APIKey = strings.TrimSpace(key)
+	c.Providers[provider] = pc
+}
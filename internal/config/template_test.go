@@ -0,0 +1,102 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const teamTemplate = `{
+  "default_provider": "claude",
+  "providers": {
+    "claude": {"api_endpoint": "https://llm-gateway.example.com", "model": "claude-sonnet-4"}
+  },
+  "user_preferences": {"language": "en", "fallback_provider": "openai"}
+}`
+
+func TestFromTemplateOverlaysDefaults(t *testing.T) {
+	cfg, err := FromTemplate([]byte(teamTemplate))
+	if err != nil {
+		t.Fatalf("FromTemplate: %v", err)
+	}
+	if cfg.DefaultProvider != ProviderClaude || cfg.Providers[ProviderClaude].APIEndpoint != "https://llm-gateway.example.com" {
+		t.Errorf("template settings not applied: %+v", cfg.Providers[ProviderClaude])
+	}
+	if _, ok := cfg.Providers[ProviderOllama]; !ok {
+		t.Error("providers missing from the template should keep their defaults")
+	}
+	if cfg.UserPreferences.Cache.MaxEntries != DefaultCacheEntries {
+		t.Errorf("unset preferences should keep defaults, got max_entries %d", cfg.UserPreferences.Cache.MaxEntries)
+	}
+
+	secrets := cfg.RequiredSecrets()
+	if len(secrets) != 2 || secrets[0].Provider != ProviderClaude || secrets[1].Provider != ProviderOpenAI {
+		t.Fatalf("RequiredSecrets = %+v, want claude then openai", secrets)
+	}
+	cfg.SetSecret(ProviderClaude, " sk-ant-test ")
+	cfg.SetSecret(ProviderOpenAI, "sk-test")
+	if got := cfg.RequiredSecrets(); len(got) != 0 {
+		t.Errorf("secrets still required after setting them: %+v", got)
+	}
+	if cfg.Providers[ProviderClaude].Model != "claude-sonnet-4" || cfg.Providers[ProviderClaude].APIKey != "sk-ant-test" {
+		t.Errorf("SetSecret clobbered the provider: %+v", cfg.Providers[ProviderClaude])
+	}
+}
+
+func TestFromTemplateAcceptsVersionedFormat(t *testing.T) {
+	cfg, err := FromTemplate([]byte(`{"version": "1.1.0", "data": {"default_provider": "ollama"}}`))
+	if err != nil {
+		t.Fatalf("FromTemplate: %v", err)
+	}
+	if cfg.DefaultProvider != ProviderOllama || len(cfg.RequiredSecrets()) != 0 {
+		t.Errorf("unexpected config: provider %s, secrets %+v", cfg.DefaultProvider, cfg.RequiredSecrets())
+	}
+}
+
+func TestFromTemplateRejectsUnknownDefault(t *testing.T) {
+	if _, err := FromTemplate([]byte(`{"default_provider": "nope"}`)); err == nil {
+		t.Error("expected an error for a default provider without configuration")
+	}
+	if _, err := FromTemplate([]byte(`not json`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestFetchTemplate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(teamTemplate))
+	}))
+	defer srv.Close()
+
+	data, err := FetchTemplate(context.Background(), srv.URL)
+	if err != nil || !strings.Contains(string(data), "llm-gateway") {
+		t.Fatalf("FetchTemplate(loopback http) = %q, %v", data, err)
+	}
+
+	if _, err := FetchTemplate(context.Background(), "http://example.com/team.json"); err == nil {
+		t.Error("plain http to a remote host should be refused")
+	}
+
+	path := filepath.Join(t.TempDir(), "team.json")
+	if err := os.WriteFile(path, []byte(teamTemplate), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := FetchTemplate(context.Background(), path); err != nil || len(data) == 0 {
+		t.Errorf("FetchTemplate(local path) = %q, %v", data, err)
+	}
+}
+
+func TestRequiredSecretsCoversFallbackChain(t *testing.T) {
+	cfg := &Config{
+		DefaultProvider: ProviderOllama,
+		Providers:       map[string]ProviderConfig{ProviderOllama: {}, ProviderGemini: {APIKey: "key"}, ProviderClaude: {APIKeyFile: "~/.secrets/anthropic"}},
+		UserPreferences: UserPreferences{FallbackProviders: []string{ProviderGemini, ProviderClaude, ProviderOpenAI, ProviderOpenAI}},
+	}
+	if secrets := cfg.RequiredSecrets(); len(secrets) != 1 || secrets[0].Provider != ProviderOpenAI {
+		t.Errorf("RequiredSecrets = %+v, want only openai", secrets)
+	}
+}
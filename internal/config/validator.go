@@ -151,7 +151,7 @@ func (v *Validator) validateBasicConfigForInit(c *Config) {
 			[]string{
 				"運行 'aish init' 來設置LLM提供商",
 				"手動配置OpenAI: 'aish config set providers.openai.api_key YOUR_KEY'",
-				"使用Gemini CLI (無需API密鑰): 'aish config set default_provider gemini-cli'",
+				"使用Gemini CLI (無需API密鑰): 'aish use gemini-cli'",
 			}, "error")
 	}
 }
@@ -164,7 +164,7 @@ func (v *Validator) validateBasicConfig(c *Config) {
 			"默認提供商不能為空",
 			[]string{
 				"運行 'aish init' 來設置默認提供商",
-				"使用 'aish config set default_provider <provider>' 設置默認提供商",
+				"使用 'aish use <provider>' 設置默認提供商",
 				"可選提供商: openai, gemini, gemini-cli",
 			}, "error")
 	} else {
@@ -180,7 +180,7 @@ func (v *Validator) validateBasicConfig(c *Config) {
 				"運行 'aish init' 重新配置",
 			}
 			if len(availableProviders) > 0 {
-				suggestions = append(suggestions, fmt.Sprintf("使用 'aish config set default_provider %s' 設置為第一個可用提供商", availableProviders[0]))
+				suggestions = append(suggestions, fmt.Sprintf("使用 'aish use %s' 設置為第一個可用提供商", availableProviders[0]))
 			}
 			v.AddErrorWithSuggestions("default_provider", c.DefaultProvider,
 				"默認提供商在提供商配置中不存在",
@@ -195,7 +195,7 @@ func (v *Validator) validateBasicConfig(c *Config) {
 			[]string{
 				"運行 'aish init' 來設置LLM提供商",
 				"手動配置OpenAI: 'aish config set providers.openai.api_key YOUR_KEY'",
-				"使用Gemini CLI (無需API密鑰): 'aish config set default_provider gemini-cli'",
+				"使用Gemini CLI (無需API密鑰): 'aish use gemini-cli'",
 				"查看支持的提供商: openai, gemini, gemini-cli",
 			}, "error")
 	}
@@ -273,6 +273,8 @@ func (v *Validator) validateProviders(c *Config) {
 			v.validateGeminiProvider(fieldPrefix, provider)
 		case ProviderGeminiCLI:
 			v.validateGeminiCLIProvider(fieldPrefix, provider)
+		case ProviderVertex:
+			v.validateVertexProvider(fieldPrefix, provider)
 		}
 
 		// 驗證模型名稱不能為空
@@ -285,7 +287,16 @@ func (v *Validator) validateProviders(c *Config) {
 // validateOpenAIProvider 驗證 OpenAI 提供商配置
 func (v *Validator) validateOpenAIProvider(fieldPrefix string, provider ProviderConfig) {
 	// API Key validation with helpful guidance
-	if provider.APIKey == "" || provider.APIKey == "YOUR_OPENAI_API_KEY" {
+	if provider.APIKeyFile != "" {
+		if _, err := provider.ResolveAPIKey(); err != nil {
+			v.AddWarning(fieldPrefix+".api_key_file", provider.APIKeyFile,
+				"無法讀取 API 密鑰檔案",
+				[]string{
+					"確認檔案存在且可讀取，並由 OIDC 或憑證工具定期寫入",
+					"使用命令設置: 'aish config set providers.openai.api_key_file ~/.cache/llm-gateway/token'",
+				})
+		}
+	} else if provider.APIKey == "" || provider.APIKey == "YOUR_OPENAI_API_KEY" {
 		v.AddWarning(fieldPrefix+".api_key", provider.APIKey,
 			"OpenAI API密鑰未設置或使用預設值",
 			[]string{
@@ -345,7 +356,7 @@ func (v *Validator) validateGeminiProvider(fieldPrefix string, provider Provider
 			[]string{
 				"從 https://aistudio.google.com/app/apikey 獲取API密鑰",
 				"使用命令設置: 'aish config set providers.gemini.api_key YOUR_KEY'",
-				"或使用免費的Gemini CLI: 'aish config set default_provider gemini-cli'",
+				"或使用免費的Gemini CLI: 'aish use gemini-cli'",
 				"Gemini API提供免費額度供測試使用",
 			})
 	}
@@ -416,35 +427,50 @@ func (v *Validator) validateGeminiCLIProvider(fieldPrefix string, provider Provi
 		})
 }
 
+// validateVertexProvider 驗證 Vertex AI 提供商配置
+func (v *Validator) validateVertexProvider(fieldPrefix string, provider ProviderConfig) {
+	if provider.CredentialsFile == "" {
+		return
+	}
+	if _, err := os.Stat(ExpandHome(provider.CredentialsFile)); err != nil {
+		v.AddWarning(fieldPrefix+".credentials_file", provider.CredentialsFile,
+			"無法讀取服務帳戶金鑰檔案",
+			[]string{
+				"確認檔案存在且可讀取",
+				"使用命令設置: 'aish config set providers.vertex.credentials_file ~/keys/vertex-sa.json'",
+				"或清空以使用 Application Default Credentials: 'gcloud auth application-default login'",
+			})
+	}
+}
+
+// SupportedLanguages are the language codes aish has prompts for, which language and --lang
+// accept besides the full names (english, japanese, ...).
+var SupportedLanguages = []string{"en", "zh-TW", "zh-CN", "ja", "ko", "es", "fr", "de", "it", "pt", "ru", "ar", "he"}
+
 // validateUserPreferences 驗證用戶偏好設置
 func (v *Validator) validateUserPreferences(c *Config) {
 	prefs := c.UserPreferences
 
 	// 驗證語言設置
-	validLanguages := []string{
-		"english", "en",
-		"zh-TW", "zh-CN", "zh", "chinese",
-		"ja", "japanese",
-		"ko", "korean",
-		"es", "spanish",
-		"fr", "french",
-		"de", "german",
-	}
+	validLanguages := append([]string{
+		"english", "zh", "chinese", "japanese", "korean", "spanish", "french", "german",
+		"italian", "portuguese", "russian", "arabic", "hebrew",
+	}, SupportedLanguages...)
 	if prefs.Language != "" && !v.contains(validLanguages, prefs.Language) {
 		v.AddWarning("user_preferences.language", prefs.Language,
 			"Unsupported language setting",
 			[]string{
-				"Supported languages: english/en, zh-TW/zh-CN/chinese, ja/japanese, ko/korean, es/spanish, fr/french, de/german",
+				"Supported languages: " + strings.Join(SupportedLanguages, ", "),
 				"Set language: 'aish config set language english' for English",
-				"Use ISO codes (en, zh-TW, ja, ko, es, fr, de) or full names (english, chinese, japanese, etc.)",
+				"Use ISO codes (en, zh-TW, ja, ...) or full names (english, chinese, japanese, etc.)",
 				"Default language is English",
 			})
 	} else if prefs.Language == "" {
 		v.AddInfo("user_preferences.language", "",
-			"Language not specified, using default (English)",
+			"Language not specified, using the system locale (LANG/LC_ALL), or English",
 			[]string{
 				"Set language explicitly: 'aish config set language en'",
-				"Available languages: en, zh, ja",
+				"Available languages: " + strings.Join(SupportedLanguages, ", "),
 			})
 	}
 
@@ -597,8 +623,7 @@ func (v *Validator) contains(slice []string, item string) bool {
 }
 
 func defaultLogFilePath() string {
-	if home, err := os.UserHomeDir(); err == nil {
-		candidateDir := filepath.Join(home, DefaultConfigDir, DefaultLogDir)
+	if candidateDir, err := LogDir(); err == nil {
 		if err := os.MkdirAll(candidateDir, DefaultDirPermissions); err == nil {
 			return filepath.Join(candidateDir, DefaultLogFileName)
 		}
@@ -645,7 +670,7 @@ func (c *Config) ValidateAndFix() ([]string, error) {
 		fixes = append(fixes, "修復日誌備份數量為 5")
 	}
 
-	// 修復語言:若為空或不在允許清單,回退為 english
+	// 修復語言:不在允許清單則回退為 english;留空表示依系統語系自動偵測
 	validLanguages := []string{
 		"english", "en",
 		"zh-tw", "zh-cn", "zh", "chinese",
@@ -663,7 +688,7 @@ func (c *Config) ValidateAndFix() ([]string, error) {
 			break
 		}
 	}
-	if !isValid {
+	if !isValid && lang != "" {
 		c.UserPreferences.Language = "english"
 		fixes = append(fixes, "Fixed language to english (English)")
 	}
@@ -733,7 +758,7 @@ func (v *Validator) addConfigurationHealthChecks(c *Config) {
 	// Check if multiple providers are configured
 	configuredProviders := 0
 	for _, provider := range c.Providers {
-		if (provider.APIKey != "" && provider.APIKey != "YOUR_OPENAI_API_KEY" && provider.APIKey != "YOUR_GEMINI_API_KEY") ||
+		if (provider.APIKey != "" && provider.APIKey != "YOUR_OPENAI_API_KEY" && provider.APIKey != "YOUR_GEMINI_API_KEY") || provider.APIKeyFile != "" ||
 			(provider.Project != "" && provider.Project != "YOUR_GEMINI_PROJECT_ID") {
 			configuredProviders++
 		}
@@ -751,7 +776,7 @@ func (v *Validator) addConfigurationHealthChecks(c *Config) {
 		v.AddInfo("providers", "",
 			"Multiple providers configured - excellent for fallback",
 			[]string{
-				"Switch providers with 'aish config set default_provider <name>'",
+				"Switch providers with 'aish use <name>'",
 				"Use different providers for different use cases",
 			})
 	}
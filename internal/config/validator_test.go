@@ -213,3 +213,15 @@ func TestValidateAndFix(t *testing.T) {
 		t.Error("最大歷史條目數應該被修復為 10")
 	}
 }
+
+func TestValidateSupportedLanguages(t *testing.T) {
+	for _, lang := range SupportedLanguages {
+		validator := NewValidator()
+		validator.validateUserPreferences(&Config{UserPreferences: UserPreferences{Language: lang}})
+		for _, err := range validator.GetErrors() {
+			if err.Field == "user_preferences.language" {
+				t.Errorf("語言 %q 不應產生警告: %s", lang, err.Message)
+			}
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package context
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
@@ -106,51 +105,22 @@ func (e *ContextEnhancer) getRecentCommands(shellType string) ([]string, error)
 	return e.readHistoryFromFile(historyFile)
 }
 
-// readHistoryFromFile 從歷史檔案讀取命令
+// readHistoryFromFile 從歷史檔案讀取命令，最新的在前
 func (e *ContextEnhancer) readHistoryFromFile(filePath string) ([]string, error) {
-	file, err := os.Open(filePath)
+	data, err := readFileTail(filePath, historyTailBytes)
 	if err != nil {
 		// 如果文件不存在，嘗試使用 history 命令
 		return e.getHistoryFromCommand()
 	}
-	defer file.Close()
 
 	var commands []string
-	var allCommands []string
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 處理 zsh 歷史格式 (: timestamp:duration;command)
-		if strings.HasPrefix(line, ":") && strings.Contains(line, ";") {
-			parts := strings.SplitN(line, ";", 2)
-			if len(parts) == 2 {
-				line = parts[1]
-			}
+	entries := parseHistory(data, strings.Contains(filepath.Base(filePath), "zsh"))
+	for i := len(entries) - 1; i >= 0 && len(commands) < e.maxHistoryEntries; i-- {
+		// 過濾敏感命令
+		if !e.filterSensitiveCmd || !e.isSensitiveCommand(entries[i]) {
+			commands = append(commands, entries[i])
 		}
-
-		// 過濾空行和敏感命令
-		if line != "" && (!e.filterSensitiveCmd || !e.isSensitiveCommand(line)) {
-			allCommands = append(allCommands, line)
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	// 返回最近的命令 (從末尾開始)
-	start := len(allCommands) - e.maxHistoryEntries
-	if start < 0 {
-		start = 0
-	}
-
-	// 反轉順序，使最新的命令在前面
-	for i := len(allCommands) - 1; i >= start; i-- {
-		commands = append(commands, allCommands[i])
 	}
-
 	return commands, nil
 }
 
@@ -198,27 +168,7 @@ func (e *ContextEnhancer) getDirectoryListing() ([]string, error) {
 
 // isSensitiveCommand 檢查是否為敏感命令
 func (e *ContextEnhancer) isSensitiveCommand(cmd string) bool {
-	sensitiveKeywords := []string{
-		"password",
-		"passwd",
-		"api_key",
-		"secret",
-		"token",
-		"auth",
-		"credential",
-		"private",
-		"ssh-keygen",
-		"openssl",
-	}
-
-	cmdLower := strings.ToLower(cmd)
-	for _, keyword := range sensitiveKeywords {
-		if strings.Contains(cmdLower, keyword) {
-			return true
-		}
-	}
-
-	return false
+	return isSensitiveCommand(cmd)
 }
 
 // FormatForPrompt 將增強的上下文格式化為適合 LLM 的字符串
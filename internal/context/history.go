@@ -0,0 +1,290 @@
+package context
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/security"
+)
+
+// historyTailBytes is how much of the end of a history file is read: far more than the few
+// commands asked for, without reading a history of many megabytes whole.
+const historyTailBytes = 256 << 10
+
+// historyCommandRunes caps each command in HistoryNote, so a pasted script does not crowd out
+// the rest of the prompt.
+const historyCommandRunes = 200
+
+// zshExtendedLine matches an entry of zsh's EXTENDED_HISTORY format, ": <start>:<elapsed>;<command>".
+var zshExtendedLine = regexp.MustCompile(`^: *\d+:\d+;`)
+
+// bashTimestampLine matches the "#<epoch>" line bash writes before a command when
+// HISTTIMEFORMAT is set.
+var bashTimestampLine = regexp.MustCompile(`^#\d{9,}$`)
+
+// HistoryFile returns the history file of the user's shell: histfile when the hook passed one
+// (bash and zsh only write there once HISTFILE is set), else $HISTFILE, else the default file
+// of the shell named by $SHELL.
+func HistoryFile(histfile string) string {
+	if histfile = strings.TrimSpace(histfile); histfile != "" {
+		return histfile
+	}
+	if histfile = strings.TrimSpace(os.Getenv("HISTFILE")); histfile != "" {
+		return histfile
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "zsh":
+		return filepath.Join(home, ".zsh_history")
+	case "bash":
+		return filepath.Join(home, ".bash_history")
+	case "fish":
+		data := os.Getenv("XDG_DATA_HOME")
+		if data == "" {
+			data = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(data, "fish", "fish_history")
+	}
+	return ""
+}
+
+// RecentCommands returns up to n commands from the end of the shell history in path, oldest
+// first. The failed command is left out when it is the last entry, as it is once zsh, fish or
+// PowerShell saved it. With filterSensitive, commands that look like they handle secrets are
+// dropped; the rest are masked with security.SanitizeCommand either way.
+//
+// bash only writes its history when the shell exits (unless PROMPT_COMMAND runs 'history -a'),
+// so the hook passes the session's commands for SessionCommands instead.
+func RecentCommands(path string, n int, failed string, filterSensitive bool) ([]string, error) {
+	if path == "" || n <= 0 {
+		return nil, nil
+	}
+	data, err := readFileTail(path, historyTailBytes)
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	if isPowerShellHistory(path) {
+		entries = parsePowerShellHistory(data)
+	} else {
+		entries = parseHistory(data, strings.Contains(filepath.Base(path), "zsh"))
+	}
+	return recentCommands(entries, n, failed, filterSensitive), nil
+}
+
+// SessionCommands is RecentCommands for the output of bash's 'fc -ln', which lists the
+// in-memory history of the running bash session, each line indented by a tab.
+func SessionCommands(list string, n int, failed string, filterSensitive bool) []string {
+	if n <= 0 {
+		return nil
+	}
+	var entries []string
+	for _, line := range strings.Split(list, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return recentCommands(entries, n, failed, filterSensitive)
+}
+
+// recentCommands picks up to n of entries, oldest first, as RecentCommands describes.
+func recentCommands(entries []string, n int, failed string, filterSensitive bool) []string {
+	failed = strings.TrimSpace(failed)
+	if last := len(entries) - 1; last >= 0 && failed != "" &&
+		(entries[last] == failed || security.SanitizeCommand(entries[last]) == failed) {
+		entries = entries[:last]
+	}
+
+	var commands []string
+	for i := len(entries) - 1; i >= 0 && len(commands) < n; i-- {
+		if filterSensitive && (isSensitiveCommand(entries[i]) || security.ContainsSensitive(entries[i])) {
+			continue
+		}
+		commands = append(commands, security.SanitizeCommand(entries[i]))
+	}
+	for i, j := 0, len(commands)-1; i < j; i, j = i+1, j-1 {
+		commands[i], commands[j] = commands[j], commands[i]
+	}
+	return commands
+}
+
+// HistoryNote describes commands, as RecentCommands returns them, for the provider.
+func HistoryNote(commands []string) string {
+	quoted := make([]string, 0, len(commands))
+	for _, c := range commands {
+		c = strings.ReplaceAll(c, "\n", `\n`)
+		if r := []rune(c); len(r) > historyCommandRunes {
+			c = string(r[:historyCommandRunes]) + "..."
+		}
+		quoted = append(quoted, "`"+c+"`")
+	}
+	return fmt.Sprintf("Commands the user ran before it, oldest first: %s", strings.Join(quoted, ", "))
+}
+
+// readFileTail returns at most max bytes from the end of path, starting at a line boundary.
+func readFileTail(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - max
+	if offset <= 0 {
+		return io.ReadAll(f)
+	}
+	data := make([]byte, max)
+	if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return data, nil
+}
+
+// parseHistory splits a history file into commands. It reads fish's YAML-like format, zsh's
+// (extended or not, with multi-line commands continued by a trailing backslash) and bash's,
+// skipping the timestamps HISTTIMEFORMAT adds.
+func parseHistory(data []byte, zsh bool) []string {
+	if bytes.HasPrefix(data, []byte("- cmd: ")) || bytes.Contains(data, []byte("\n- cmd: ")) {
+		return parseFishHistory(data)
+	}
+	lines := strings.Split(string(data), "\n")
+	zsh = zsh || (len(lines) > 0 && zshExtendedLine.MatchString(lines[0]))
+	if zsh {
+		lines = strings.Split(string(unmetafy(data)), "\n")
+	}
+
+	var entries []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+		if bashTimestampLine.MatchString(line) {
+			continue
+		}
+		if loc := zshExtendedLine.FindStringIndex(line); loc != nil {
+			line = line[loc[1]:]
+		}
+		for zsh && strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + "\n" + strings.TrimSuffix(lines[i], "\r")
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+// isPowerShellHistory reports whether path is a PSReadLine history file, such as
+// ConsoleHost_history.txt.
+func isPowerShellHistory(path string) bool {
+	return strings.HasSuffix(strings.ToLower(filepath.Base(path)), "_history.txt")
+}
+
+// parsePowerShellHistory reads a PSReadLine history file, which ends every line of a
+// multi-line command but the last with a backtick.
+func parsePowerShellHistory(data []byte) []string {
+	var entries []string
+	var cmd strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if continued, ok := strings.CutSuffix(line, "`"); ok {
+			cmd.WriteString(continued + "\n")
+			continue
+		}
+		cmd.WriteString(line)
+		if c := strings.TrimSpace(cmd.String()); c != "" {
+			entries = append(entries, c)
+		}
+		cmd.Reset()
+	}
+	return entries
+}
+
+// parseFishHistory reads the "- cmd: <command>" entries of a fish history file, in which
+// backslashes and newlines of a command are escaped.
+func parseFishHistory(data []byte) []string {
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		cmd, ok := strings.CutPrefix(line, "- cmd: ")
+		if !ok {
+			continue
+		}
+		var b strings.Builder
+		for i := 0; i < len(cmd); i++ {
+			if cmd[i] == '\\' && i+1 < len(cmd) {
+				switch cmd[i+1] {
+				case 'n':
+					b.WriteByte('\n')
+					i++
+					continue
+				case '\\':
+					b.WriteByte('\\')
+					i++
+					continue
+				}
+			}
+			b.WriteByte(cmd[i])
+		}
+		if c := strings.TrimSpace(b.String()); c != "" {
+			entries = append(entries, c)
+		}
+	}
+	return entries
+}
+
+// unmetafy undoes zsh's escaping of bytes it uses internally: 0x83 (Meta) followed by the
+// byte XOR 0x20. Non-ASCII commands are unreadable without it.
+func unmetafy(data []byte) []byte {
+	if bytes.IndexByte(data, 0x83) < 0 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == 0x83 && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^0x20)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return out
+}
+
+// isSensitiveCommand reports whether cmd mentions passwords, keys or other secrets, so it is
+// better left out of what is sent to a provider.
+func isSensitiveCommand(cmd string) bool {
+	sensitiveKeywords := []string{
+		"password",
+		"passwd",
+		"api_key",
+		"secret",
+		"token",
+		"auth",
+		"credential",
+		"private",
+		"ssh-keygen",
+		"openssl",
+	}
+
+	cmdLower := strings.ToLower(cmd)
+	for _, keyword := range sensitiveKeywords {
+		if strings.Contains(cmdLower, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHistory(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		zsh  bool
+		want []string
+	}{
+		{"bash", "ls -la\ncd project\n\ngit status\n", false, []string{"ls -la", "cd project", "git status"}},
+		{"bash with HISTTIMEFORMAT", "#1700000000\nmake\n#1700000010\nmake test\n", false, []string{"make", "make test"}},
+		{"zsh extended", ": 1700000000:0;cd src\n: 1700000005:2;for f in *; do\\\necho $f\\\ndone\n", false,
+			[]string{"cd src", "for f in *; do\necho $f\ndone"}},
+		{"zsh plain", "echo a \\\nb\nls\n", true, []string{"echo a \nb", "ls"}},
+		{"zsh metafied", ": 1700000000:0;echo \xc4\x83\xbf\n", false, []string{"echo ğ"}},
+		{"fish", "- cmd: cd src\n  when: 1700000000\n- cmd: echo a\\nb \\\\n\n  when: 1700000005\n  paths:\n    - src\n", false,
+			[]string{"cd src", "echo a\nb \\n"}},
+	}
+	for _, tt := range tests {
+		if got := parseHistory([]byte(tt.data), tt.zsh); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecentCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".zsh_history")
+	history := strings.Join([]string{
+		": 1700000000:0;git clone https://example.com/repo.git",
+		": 1700000001:0;export API_KEY=sk-abcdef",
+		": 1700000002:0;cd repo",
+		": 1700000003:0;mysql -u root --password=hunter2 app",
+		": 1700000004:0;make build",
+		": 1700000005:0;make test",
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(history), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed command itself is the last entry once zsh saved it
+	got, err := RecentCommands(path, 3, "make test", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"git clone https://example.com/repo.git", "cd repo", "make build"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered: got %q, want %q", got, want)
+	}
+
+	got, _ = RecentCommands(path, 3, "make test", false)
+	if len(got) != 3 || got[0] != "cd repo" || strings.Contains(got[1], "hunter2") {
+		t.Errorf("unfiltered commands must still be masked, got %q", got)
+	}
+
+	if got, err := RecentCommands(filepath.Join(t.TempDir(), "missing"), 3, "", true); err == nil || got != nil {
+		t.Errorf("missing file: got %q, %v", got, err)
+	}
+}
+
+func TestSessionCommands(t *testing.T) {
+	// 'fc -ln -100 -0' as the bash hook runs it: the failed command is the last line
+	list := "\t cd /srv/app\n\t export GITHUB_TOKEN=ghp_abc\n\t for f in *.log; do   gzip $f; done\n\t ./deploy.sh"
+	got := SessionCommands(list, 5, "./deploy.sh", true)
+	if want := []string{"cd /srv/app", "for f in *.log; do   gzip $f; done"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := SessionCommands("", 5, "ls", true); got != nil {
+		t.Errorf("empty session: got %q", got)
+	}
+}
+
+func TestRecentCommandsPowerShell(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ConsoleHost_history.txt")
+	history := "Set-Location C:\\src\r\nGet-ChildItem |`\r\n  Sort-Object Name\r\n.\\build.ps1\r\n"
+	if err := os.WriteFile(path, []byte(history), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := RecentCommands(path, 5, ".\\build.ps1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Set-Location C:\\src", "Get-ChildItem |\n  Sort-Object Name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReadFileTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("first line\nsecond\nthird\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readFileTail(path, 10)
+	if err != nil || string(got) != "third\n" {
+		t.Errorf("got %q, %v; want the whole lines within the last 10 bytes", got, err)
+	}
+}
+
+func TestHistoryNote(t *testing.T) {
+	got := HistoryNote([]string{"cd src", "for f in *; do\necho $f\ndone", strings.Repeat("x", 300)})
+	want := "Commands the user ran before it, oldest first: `cd src`, `for f in *; do\\necho $f\\ndone`, `" + strings.Repeat("x", historyCommandRunes) + "...`"
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("HISTFILE", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("SHELL", "/usr/bin/fish")
+	if got := HistoryFile(""); got != filepath.Join(home, ".local", "share", "fish", "fish_history") {
+		t.Errorf("fish default = %q", got)
+	}
+	t.Setenv("HISTFILE", "/tmp/h")
+	if got := HistoryFile(" /hook/histfile "); got != "/hook/histfile" {
+		t.Errorf("the hook's file should win, got %q", got)
+	}
+	if got := HistoryFile(""); got != "/tmp/h" {
+		t.Errorf("HISTFILE = %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package context
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MACStatus describes the mandatory access control (SELinux/AppArmor) state of a Linux system,
+// which decides whether a "Permission denied" may come from policy rather than file modes.
+type MACStatus struct {
+	SELinux  string   // "Enforcing", "Permissive", "Disabled" or "" when not present
+	AppArmor bool     // AppArmor is enabled in the kernel
+	Denials  []string // Audit log denials of the command since it started, newest last
+}
+
+// auditLogTailBytes is how much of the end of the audit log is searched for denials.
+const auditLogTailBytes = 256 * 1024
+
+// maxDenials bounds the denial lines handed to the provider.
+const maxDenials = 3
+
+// commNameLen is the length of the process name in the comm field of audit records.
+const commNameLen = 15
+
+// DetectMAC returns the SELinux/AppArmor state and the denials of the process name in the audit
+// log since the time the command started. It returns false on non-Linux systems and when neither
+// is active.
+func DetectMAC(name string, since time.Time) (MACStatus, bool) {
+	if runtime.GOOS != "linux" {
+		return MACStatus{}, false
+	}
+	st := MACStatus{
+		SELinux:  selinuxMode("/sys/fs/selinux"),
+		AppArmor: appArmorEnabled("/sys/module/apparmor/parameters/enabled"),
+	}
+	if st.SELinux == "" || st.SELinux == "Disabled" {
+		if !st.AppArmor {
+			return st, false
+		}
+	}
+	st.Denials = recentDenials("/var/log/audit/audit.log", name, since)
+	return st, true
+}
+
+func selinuxMode(fsDir string) string {
+	if path, err := exec.LookPath("getenforce"); err == nil {
+		c, cancel := stdcontext.WithTimeout(stdcontext.Background(), 2*time.Second)
+		defer cancel()
+		if out, err := exec.CommandContext(c, path).Output(); err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+	data, err := os.ReadFile(fsDir + "/enforce")
+	if err != nil {
+		return ""
+	}
+	if strings.TrimSpace(string(data)) == "1" {
+		return "Enforcing"
+	}
+	return "Permissive"
+}
+
+func appArmorEnabled(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.HasPrefix(strings.TrimSpace(string(data)), "Y")
+}
+
+var (
+	// auditTimestamp matches the "msg=audit(1700000000.123:42)" stamp of an audit record.
+	auditTimestamp = regexp.MustCompile(`msg=audit\((\d+(?:\.\d+)?):`)
+	// auditProcess matches the fields of an audit record naming the denied process.
+	auditProcess = regexp.MustCompile(`\b(comm|exe|profile)="([^"]*)"`)
+)
+
+// recentDenials returns the last few SELinux AVC or AppArmor denial records in the audit log
+// logged since since for the process name, matched by its exact name or executable path. The
+// log is usually readable only by root; then there is nothing to report.
+func recentDenials(path, name string, since time.Time) []string {
+	if name == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > auditLogTailBytes {
+		if _, err := f.Seek(-auditLogTailBytes, io.SeekEnd); err != nil {
+			return nil
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+	var denials []string
+	for _, line := range strings.Split(string(data), "\n") {
+		isDenial := strings.Contains(line, "avc:  denied") || strings.Contains(line, `apparmor="DENIED"`)
+		if isDenial && deniedProcess(line, name) && !auditTime(line).Before(since) {
+			denials = append(denials, strings.TrimSpace(line))
+		}
+	}
+	if len(denials) > maxDenials {
+		denials = denials[len(denials)-maxDenials:]
+	}
+	return denials
+}
+
+// auditTime returns when an audit record was logged, or the zero time when it carries no stamp.
+func auditTime(line string) time.Time {
+	m := auditTimestamp.FindStringSubmatch(line)
+	if m == nil {
+		return time.Time{}
+	}
+	secs, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(secs*float64(time.Second)))
+}
+
+// deniedProcess reports whether an audit record is about the process name: its comm field is
+// name, which the kernel cuts to commNameLen bytes, or its executable or AppArmor profile path
+// ends in name.
+func deniedProcess(line, name string) bool {
+	base := filepath.Base(name)
+	comm := base
+	if len(comm) > commNameLen {
+		comm = comm[:commNameLen]
+	}
+	for _, m := range auditProcess.FindAllStringSubmatch(line, -1) {
+		switch field, value := m[1], m[2]; {
+		case field == "comm" && value == comm:
+			return true
+		case field != "comm" && (value == name || filepath.Base(value) == base):
+			return true
+		}
+	}
+	return false
+}
+
+// Notes describes the state for an LLM prompt, so a suggestion can tell a policy (MAC) denial
+// from a file-permission (DAC) one instead of defaulting to chmod.
+func (m MACStatus) Notes() []string {
+	var active []string
+	if m.SELinux != "" && m.SELinux != "Disabled" {
+		active = append(active, "SELinux is "+m.SELinux)
+	}
+	if m.AppArmor {
+		active = append(active, "AppArmor is enabled")
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	notes := []string{strings.Join(active, ", ") + "; the denial may come from security policy rather than file permissions"}
+	if len(m.Denials) == 0 {
+		notes = append(notes, "No matching denial was found in the audit log (it may not be readable); check with 'sudo ausearch -m avc -ts recent' or 'sudo journalctl -k | grep DENIED'")
+	}
+	for _, d := range m.Denials {
+		notes = append(notes, fmt.Sprintf("Audit log denial: %s", d))
+	}
+	return notes
+}
@@ -0,0 +1,65 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecentDenials(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "audit.log")
+	lines := []string{
+		`type=AVC msg=audit(1700000000.1:1): avc:  denied  { read } for  pid=1 comm="nginx" name="index.html"`,
+		`type=SYSCALL msg=audit(1700000000.1:1): comm="nginx" exe="/usr/sbin/nginx"`,
+		`type=AVC msg=audit(1700000001.1:2): avc:  denied  { write } for  pid=2 comm="cp" name="x"`,
+		`type=AVC msg=audit(1700000002.1:3): apparmor="DENIED" operation="open" profile="/usr/sbin/nginx" name="/srv/a"`,
+		`type=AVC msg=audit(1700000003.1:4): avc:  denied  { read } for  pid=3 comm="nginx-reload" name="nginx.conf"`,
+		`type=AVC msg=audit(1700000004.1:5): avc:  denied  { read } for  pid=4 comm="cat" name="nginx"`,
+	}
+	if err := os.WriteFile(log, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	since := time.Unix(1700000000, 0)
+	got := recentDenials(log, "nginx", since)
+	if len(got) != 2 || !strings.Contains(got[0], "avc:  denied") || !strings.Contains(got[1], `apparmor="DENIED"`) {
+		t.Errorf("unexpected denials: %q", got)
+	}
+	// Denials from before the command started belong to something else
+	if got := recentDenials(log, "nginx", since.Add(2*time.Second)); len(got) != 1 || !strings.Contains(got[0], `apparmor="DENIED"`) {
+		t.Errorf("denials before the command started should be left out, got %q", got)
+	}
+	if got := recentDenials(filepath.Join(t.TempDir(), "missing.log"), "nginx", since); got != nil {
+		t.Errorf("unreadable log should yield nothing, got %q", got)
+	}
+}
+
+func TestSELinuxModeFromSysfs(t *testing.T) {
+	t.Setenv("PATH", "") // keep a real getenforce out of the way
+	dir := t.TempDir()
+	if got := selinuxMode(dir); got != "" {
+		t.Errorf("no selinuxfs should mean no SELinux, got %q", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "enforce"), []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := selinuxMode(dir); got != "Enforcing" {
+		t.Errorf("got %q, want Enforcing", got)
+	}
+}
+
+func TestMACStatusNotes(t *testing.T) {
+	if notes := (MACStatus{SELinux: "Disabled"}).Notes(); notes != nil {
+		t.Errorf("inactive MAC should produce no notes, got %q", notes)
+	}
+	notes := MACStatus{SELinux: "Enforcing", Denials: []string{"avc:  denied { read }"}}.Notes()
+	if len(notes) != 2 || !strings.Contains(notes[0], "SELinux is Enforcing") || !strings.Contains(notes[1], "avc:  denied") {
+		t.Errorf("unexpected notes: %q", notes)
+	}
+	notes = MACStatus{AppArmor: true}.Notes()
+	if len(notes) != 2 || !strings.Contains(notes[1], "ausearch") {
+		t.Errorf("without denials the notes should say how to look for them: %q", notes)
+	}
+}
@@ -0,0 +1,223 @@
+package context
+
+import (
+	stdcontext "context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// NetworkReport is the result of quick local checks against the host a failed command tried to
+// reach, telling DNS, connection and proxy problems apart.
+type NetworkReport struct {
+	Host    string
+	Port    string
+	Proxies []string // Proxy variables set in the environment, credentials masked
+
+	Addrs  []string // Resolved addresses; empty when DNS failed
+	DNSErr error
+
+	Connected  bool
+	ConnectErr error
+	Latency    time.Duration
+
+	ProxyAddr       string // host:port of the proxy that applies to Host, if any
+	ProxyConnectErr error  // nil when the proxy accepted a connection
+}
+
+// Per-check limits: the checks run while the user waits for the suggestion.
+const (
+	dnsCheckTimeout     = 1500 * time.Millisecond
+	connectCheckTimeout = 1500 * time.Millisecond
+)
+
+var (
+	targetURL        = regexp.MustCompile(`\b([a-zA-Z][a-zA-Z0-9+.-]*)://(?:[^@/\s]+@)?(\[[0-9a-fA-F:]+\]|[A-Za-z0-9.-]+)(?::(\d+))?`)
+	resolveHost      = regexp.MustCompile(`(?i)(?:could not resolve (?:host|hostname)|unable to resolve host|name or service not known|temporary failure in name resolution)[:\s]+'?([A-Za-z0-9.-]+\.[A-Za-z]{2,})'?`)
+	connectHostPort  = regexp.MustCompile(`(?i)(?:failed to connect to|connect to host) ([A-Za-z0-9.-]+) port (\d+)`)
+	scpLikeGitRemote = regexp.MustCompile(`\b[A-Za-z0-9._-]+@([A-Za-z0-9.-]+\.[A-Za-z]{2,}):`)
+	hostArgTarget    = regexp.MustCompile(`^(?:ping|ping6|telnet|nc|traceroute|dig|nslookup|host)\s+(?:-\S+\s+)*(?:[A-Za-z0-9._-]+@)?([A-Za-z0-9.-]+\.[A-Za-z]{2,}|\d+\.\d+\.\d+\.\d+)`)
+)
+
+var schemePorts = map[string]string{"http": "80", "https": "443", "ssh": "22", "git": "9418", "ftp": "21", "ws": "80", "wss": "443", "socks5": "1080", "socks5h": "1080"}
+
+// NetworkTarget extracts the host (and port, when it can be told) a failed command tried to
+// reach, from the error output first and then from the command line.
+func NetworkTarget(command, output string) (host, port string) {
+	if m := connectHostPort.FindStringSubmatch(output); m != nil {
+		return m[1], m[2]
+	}
+	if m := resolveHost.FindStringSubmatch(output); m != nil {
+		host = m[1]
+	}
+	for _, text := range []string{command, output} {
+		if m := targetURL.FindStringSubmatch(text); m != nil && (host == "" || strings.EqualFold(strings.Trim(m[2], "[]"), host)) {
+			port = m[3]
+			if port == "" {
+				port = schemePorts[strings.ToLower(m[1])]
+			}
+			return strings.Trim(m[2], "[]"), port
+		}
+	}
+	if host != "" {
+		return host, ""
+	}
+	if m := scpLikeGitRemote.FindStringSubmatch(command); m != nil {
+		return m[1], "22"
+	}
+	if fields := strings.Fields(command); len(fields) > 1 && fields[0] == "ssh" {
+		return sshTarget(fields[1:])
+	}
+	if m := hostArgTarget.FindStringSubmatch(strings.TrimSpace(command)); m != nil {
+		return m[1], ""
+	}
+	return "", ""
+}
+
+// sshTarget finds the destination host and port among ssh's arguments.
+func sshTarget(args []string) (host, port string) {
+	port = "22"
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if strings.HasPrefix(a, "-") {
+			// Options that take a value: -p port, plus the common ones whose value must be skipped
+			if len(a) == 2 && strings.Contains("bcDEeFIiJLlmOopQRSWw", a[1:]) && i+1 < len(args) {
+				if a == "-p" {
+					port = args[i+1]
+				}
+				i++
+			}
+			continue
+		}
+		if _, h, ok := strings.Cut(a, "@"); ok {
+			a = h
+		}
+		return a, port
+	}
+	return "", ""
+}
+
+var proxyVars = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy", "NO_PROXY", "no_proxy"}
+
+// proxyForPort returns the proxy URL that applies to a connection on port, honouring NO_PROXY.
+func proxyForPort(host, port string, getenv func(string) string) string {
+	first := func(names ...string) string {
+		for _, n := range names {
+			if v := getenv(n); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	for _, entry := range strings.Split(first("NO_PROXY", "no_proxy"), ",") {
+		entry = strings.TrimPrefix(strings.TrimSpace(entry), ".")
+		if entry == "*" || (entry != "" && (host == entry || strings.HasSuffix(host, "."+entry))) {
+			return ""
+		}
+	}
+	switch port {
+	case "443":
+		return first("HTTPS_PROXY", "https_proxy", "ALL_PROXY", "all_proxy")
+	case "80":
+		return first("HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy")
+	}
+	return first("ALL_PROXY", "all_proxy")
+}
+
+// maskProxy hides the password of a proxy URL.
+func maskProxy(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "***")
+	}
+	return u.String()
+}
+
+// CheckNetwork resolves host, connects to host:port (when a port is known) and, when a proxy
+// applies, checks that the proxy accepts connections.
+func CheckNetwork(ctx stdcontext.Context, host, port string) NetworkReport {
+	r := NetworkReport{Host: host, Port: port}
+	for _, name := range proxyVars {
+		if v := os.Getenv(name); v != "" {
+			r.Proxies = append(r.Proxies, name+"="+maskProxy(v))
+		}
+	}
+
+	dnsCtx, cancel := stdcontext.WithTimeout(ctx, dnsCheckTimeout)
+	r.Addrs, r.DNSErr = net.DefaultResolver.LookupHost(dnsCtx, host)
+	cancel()
+
+	if port != "" && r.DNSErr == nil {
+		var d net.Dialer
+		dialCtx, cancel := stdcontext.WithTimeout(ctx, connectCheckTimeout)
+		started := time.Now()
+		conn, err := d.DialContext(dialCtx, "tcp", net.JoinHostPort(host, port))
+		cancel()
+		r.Latency = time.Since(started)
+		if err == nil {
+			r.Connected = true
+			_ = conn.Close()
+		} else {
+			r.ConnectErr = err
+		}
+	}
+
+	if proxy := proxyForPort(host, port, os.Getenv); proxy != "" {
+		if u, err := url.Parse(proxy); err == nil && u.Host != "" {
+			r.ProxyAddr = u.Host
+			if u.Port() == "" {
+				r.ProxyAddr = net.JoinHostPort(u.Hostname(), schemePorts[u.Scheme])
+			}
+			var d net.Dialer
+			dialCtx, cancel := stdcontext.WithTimeout(ctx, connectCheckTimeout)
+			conn, err := d.DialContext(dialCtx, "tcp", r.ProxyAddr)
+			cancel()
+			if err == nil {
+				_ = conn.Close()
+			}
+			r.ProxyConnectErr = err
+		}
+	}
+	return r
+}
+
+// Notes describes the results for an LLM prompt.
+func (r NetworkReport) Notes() []string {
+	var notes []string
+	if r.DNSErr != nil {
+		notes = append(notes, fmt.Sprintf("Local check: DNS lookup of %s failed (%v)", r.Host, r.DNSErr))
+	} else {
+		addrs := r.Addrs
+		if len(addrs) > 3 {
+			addrs = addrs[:3]
+		}
+		notes = append(notes, fmt.Sprintf("Local check: DNS resolves %s to %s", r.Host, strings.Join(addrs, ", ")))
+	}
+	switch {
+	case r.Port == "" || r.DNSErr != nil:
+	case r.Connected:
+		notes = append(notes, fmt.Sprintf("Local check: direct TCP connection to %s:%s succeeded in %s", r.Host, r.Port, r.Latency.Round(time.Millisecond)))
+	default:
+		notes = append(notes, fmt.Sprintf("Local check: direct TCP connection to %s:%s failed (%v)", r.Host, r.Port, r.ConnectErr))
+	}
+	if len(r.Proxies) == 0 {
+		notes = append(notes, "Local check: no proxy environment variables are set")
+	} else {
+		notes = append(notes, "Local check: proxy environment: "+strings.Join(r.Proxies, " "))
+	}
+	if r.ProxyAddr != "" {
+		if r.ProxyConnectErr == nil {
+			notes = append(notes, fmt.Sprintf("Local check: the proxy %s accepts connections", r.ProxyAddr))
+		} else {
+			notes = append(notes, fmt.Sprintf("Local check: the proxy %s is unreachable (%v)", r.ProxyAddr, r.ProxyConnectErr))
+		}
+	}
+	return notes
+}
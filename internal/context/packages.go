@@ -0,0 +1,318 @@
+package context
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// PackageManager is a system package manager aish knows how to drive.
+type PackageManager struct {
+	Name    string   // apt, dnf, yum, pacman, zypper, apk or brew
+	Install []string // Install command without the package, e.g. ["apt", "install"]
+	Verify  []string // Command that fails unless the package exists in the repositories
+	Sudo    bool     // Whether installing needs root
+}
+
+var packageManagers = []PackageManager{
+	{Name: "brew", Install: []string{"brew", "install"}, Verify: []string{"brew", "info", "--formula"}},
+	{Name: "apt", Install: []string{"apt", "install"}, Verify: []string{"apt-cache", "show"}, Sudo: true},
+	{Name: "dnf", Install: []string{"dnf", "install"}, Verify: []string{"dnf", "info", "-q"}, Sudo: true},
+	{Name: "yum", Install: []string{"yum", "install"}, Verify: []string{"yum", "info", "-q"}, Sudo: true},
+	{Name: "pacman", Install: []string{"pacman", "-S"}, Verify: []string{"pacman", "-Si"}, Sudo: true},
+	{Name: "zypper", Install: []string{"zypper", "install"}, Verify: []string{"zypper", "--quiet", "info"}, Sudo: true},
+	{Name: "apk", Install: []string{"apk", "add"}, Verify: []string{"apk", "search", "-e"}, Sudo: true},
+}
+
+// commandPackages maps commands whose package name differs between package managers, or from
+// the command itself, to the package providing them. "*" applies to managers not listed.
+var commandPackages = map[string]map[string]string{
+	"rg":         {"*": "ripgrep"},
+	"fd":         {"*": "fd", "apt": "fd-find", "dnf": "fd-find"},
+	"fdfind":     {"apt": "fd-find"},
+	"bat":        {"*": "bat"},
+	"batcat":     {"apt": "bat"},
+	"jq":         {"*": "jq"},
+	"yq":         {"*": "yq"},
+	"fzf":        {"*": "fzf"},
+	"htop":       {"*": "htop"},
+	"tree":       {"*": "tree"},
+	"wget":       {"*": "wget"},
+	"curl":       {"*": "curl"},
+	"git":        {"*": "git"},
+	"make":       {"*": "make"},
+	"gcc":        {"*": "gcc"},
+	"g++":        {"*": "g++", "dnf": "gcc-c++", "yum": "gcc-c++", "pacman": "gcc", "zypper": "gcc-c++", "brew": "gcc"},
+	"cmake":      {"*": "cmake"},
+	"tmux":       {"*": "tmux"},
+	"vim":        {"*": "vim"},
+	"nvim":       {"*": "neovim"},
+	"unzip":      {"*": "unzip"},
+	"zip":        {"*": "zip"},
+	"python3":    {"*": "python3", "pacman": "python", "brew": "python"},
+	"pip3":       {"apt": "python3-pip", "dnf": "python3-pip", "yum": "python3-pip", "pacman": "python-pip", "zypper": "python3-pip", "apk": "py3-pip"},
+	"node":       {"*": "nodejs", "brew": "node"},
+	"npm":        {"*": "npm", "brew": "node"},
+	"go":         {"*": "go", "apt": "golang-go", "dnf": "golang", "yum": "golang"},
+	"ifconfig":   {"*": "net-tools"},
+	"netstat":    {"*": "net-tools"},
+	"dig":        {"apt": "dnsutils", "dnf": "bind-utils", "yum": "bind-utils", "pacman": "bind", "zypper": "bind-utils", "apk": "bind-tools", "brew": "bind"},
+	"nslookup":   {"apt": "dnsutils", "dnf": "bind-utils", "yum": "bind-utils", "pacman": "bind", "zypper": "bind-utils", "apk": "bind-tools", "brew": "bind"},
+	"nc":         {"apt": "netcat-openbsd", "dnf": "nmap-ncat", "yum": "nmap-ncat", "pacman": "openbsd-netcat", "apk": "netcat-openbsd", "brew": "netcat"},
+	"nmap":       {"*": "nmap"},
+	"ssh":        {"apt": "openssh-client", "dnf": "openssh-clients", "yum": "openssh-clients", "pacman": "openssh", "zypper": "openssh", "apk": "openssh-client"},
+	"rsync":      {"*": "rsync"},
+	"convert":    {"*": "imagemagick", "dnf": "ImageMagick", "yum": "ImageMagick", "zypper": "ImageMagick"},
+	"ffmpeg":     {"*": "ffmpeg"},
+	"docker":     {"apt": "docker.io", "dnf": "moby-engine", "pacman": "docker", "apk": "docker", "brew": "docker"},
+	"gh":         {"*": "gh", "pacman": "github-cli", "apk": "github-cli"},
+	"shellcheck": {"*": "shellcheck", "dnf": "ShellCheck", "yum": "ShellCheck", "zypper": "ShellCheck"},
+	"sqlite3":    {"*": "sqlite3", "dnf": "sqlite", "yum": "sqlite", "pacman": "sqlite", "zypper": "sqlite3", "apk": "sqlite", "brew": "sqlite"},
+	"xclip":      {"*": "xclip"},
+	"lsof":       {"*": "lsof"},
+	"strace":     {"*": "strace"},
+}
+
+// shellInstallHint matches the install line printed by distribution command-not-found handlers,
+// e.g. "sudo apt install ripgrep" (Ubuntu) or "sudo dnf install ripgrep".
+var shellInstallHint = regexp.MustCompile(`(?m)^\s*(?:sudo\s+)?(apt|apt-get|dnf|yum|zypper|pacman -S|apk add|brew install)(?:\s+install)?\s+([A-Za-z0-9][A-Za-z0-9+._-]*)\s*(?:#.*)?$`)
+
+// InstallSuggestion is a concrete command that installs the package providing a missing command.
+type InstallSuggestion struct {
+	Manager  string // Package manager name
+	Package  string
+	Command  string // Full install command, with sudo when needed
+	Verified bool   // The package was confirmed to exist in the configured repositories
+}
+
+// DetectPackageManager returns the package manager of this system: Homebrew on macOS, otherwise
+// the first known manager on PATH, preferring the one matching /etc/os-release.
+func DetectPackageManager() (PackageManager, bool) {
+	return detectPackageManager(runtime.GOOS, readOSRelease("/etc/os-release"), exec.LookPath)
+}
+
+func detectPackageManager(goos string, osRelease map[string]string, lookPath func(string) (string, error)) (PackageManager, bool) {
+	available := func(pm PackageManager) bool {
+		_, err := lookPath(pm.Install[0])
+		return err == nil
+	}
+	byName := func(name string) (PackageManager, bool) {
+		for _, pm := range packageManagers {
+			if pm.Name == name && available(pm) {
+				return pm, true
+			}
+		}
+		return PackageManager{}, false
+	}
+
+	if goos == "darwin" {
+		return byName("brew")
+	}
+	distro := " " + osRelease["ID"] + " " + osRelease["ID_LIKE"] + " "
+	for _, hint := range []struct{ id, manager string }{
+		{"debian", "apt"}, {"ubuntu", "apt"}, {"fedora", "dnf"}, {"rhel", "dnf"}, {"centos", "yum"},
+		{"arch", "pacman"}, {"suse", "zypper"}, {"alpine", "apk"},
+	} {
+		if strings.Contains(distro, " "+hint.id+" ") {
+			if pm, ok := byName(hint.manager); ok {
+				return pm, true
+			}
+		}
+	}
+	for _, pm := range packageManagers {
+		if pm.Name != "brew" && available(pm) {
+			return pm, true
+		}
+	}
+	return byName("brew")
+}
+
+func readOSRelease(path string) map[string]string {
+	fields := map[string]string{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fields
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok {
+			fields[key] = strings.Trim(value, `"'`)
+		}
+	}
+	return fields
+}
+
+// DistroNote describes the Linux distribution and its package manager for an LLM prompt, so a
+// suggestion for a missing command installs it the way this system does rather than with
+// Homebrew. ok is false off Linux or when neither is known.
+func DistroNote() (string, bool) {
+	if runtime.GOOS != "linux" {
+		return "", false
+	}
+	osRelease := readOSRelease("/etc/os-release")
+	pm, ok := detectPackageManager(runtime.GOOS, osRelease, exec.LookPath)
+	return distroNote(osRelease, pm, ok, os.Geteuid() == 0)
+}
+
+func distroNote(osRelease map[string]string, pm PackageManager, hasManager, isRoot bool) (string, bool) {
+	distro := osRelease["PRETTY_NAME"]
+	if distro == "" {
+		distro = strings.TrimSpace(osRelease["NAME"] + " " + osRelease["VERSION_ID"])
+	}
+	install := ""
+	if hasManager {
+		install = fmt.Sprintf("packages are installed with %s (%s)", pm.Name, installCommand(pm, "<package>", isRoot))
+		if pm.Name != "brew" {
+			install += ", not Homebrew"
+		}
+	}
+	switch {
+	case distro != "" && install != "":
+		return fmt.Sprintf("The system is %s; %s", distro, install), true
+	case distro != "":
+		return fmt.Sprintf("The system is %s; no known package manager was found", distro), true
+	case install != "":
+		return "On this Linux system " + install, true
+	}
+	return "", false
+}
+
+// PackageFor returns the package that provides command under pm: the one named by the shell's
+// own command-not-found handler in stderr, or else an entry of the built-in map.
+func PackageFor(pm PackageManager, command, stderr string) (string, bool) {
+	if m := shellInstallHint.FindStringSubmatch(stderr); m != nil && managerMatches(pm.Name, m[1]) {
+		return m[2], true
+	}
+	pkgs, ok := commandPackages[command]
+	if !ok {
+		return "", false
+	}
+	if pkg, ok := pkgs[pm.Name]; ok {
+		return pkg, true
+	}
+	pkg, ok := pkgs["*"]
+	return pkg, ok
+}
+
+func managerMatches(name, hinted string) bool {
+	hinted = strings.Fields(hinted)[0]
+	return hinted == name || (name == "apt" && hinted == "apt-get")
+}
+
+// SuggestInstall returns the install command for the package providing the missing command on
+// this system. When the package manager can answer, the package is verified to exist first, and
+// an unknown package yields no suggestion.
+func SuggestInstall(command, stderr string) (InstallSuggestion, bool) {
+	pm, ok := DetectPackageManager()
+	if !ok {
+		return InstallSuggestion{}, false
+	}
+	pkg, ok := PackageFor(pm, command, stderr)
+	if !ok {
+		return InstallSuggestion{}, false
+	}
+	verified, known := cachedVerifyPackage(packageCachePath(), pm, pkg, verifyPackage)
+	if known && !verified {
+		return InstallSuggestion{}, false
+	}
+	return InstallSuggestion{
+		Manager:  pm.Name,
+		Package:  pkg,
+		Command:  installCommand(pm, pkg, os.Geteuid() == 0),
+		Verified: verified,
+	}, true
+}
+
+func installCommand(pm PackageManager, pkg string, isRoot bool) string {
+	parts := append(append([]string(nil), pm.Install...), pkg)
+	if pm.Sudo && !isRoot {
+		parts = append([]string{"sudo"}, parts...)
+	}
+	return strings.Join(parts, " ")
+}
+
+// verifyTimeout bounds a repository lookup; an unanswered lookup leaves the package unverified.
+const verifyTimeout = 5 * time.Second
+
+// verifyPackage asks pm whether pkg exists. known is false when the answer could not be obtained
+// (tool missing or too slow), so the caller can still offer the unverified suggestion.
+func verifyPackage(pm PackageManager, pkg string) (verified, known bool) {
+	if _, err := exec.LookPath(pm.Verify[0]); err != nil {
+		return false, false
+	}
+	c, cancel := stdcontext.WithTimeout(stdcontext.Background(), verifyTimeout)
+	defer cancel()
+	args := append(append([]string(nil), pm.Verify[1:]...), pkg)
+	cmd := exec.CommandContext(c, pm.Verify[0], args...)
+	out, err := cmd.Output()
+	if c.Err() != nil {
+		return false, false
+	}
+	if pm.Name == "apk" {
+		// apk search exits 0 without output for unknown packages
+		return err == nil && len(strings.TrimSpace(string(out))) > 0, true
+	}
+	return err == nil, true
+}
+
+// packageCacheTTL is how long a repository lookup is reused. The lookup runs while the shell waits
+// after a failed command, and the packages in the repositories rarely change.
+const packageCacheTTL = 24 * time.Hour
+
+// packageCacheFile is the file under the cache directory holding the lookup answers.
+const packageCacheFile = "packages.json"
+
+type packageCacheEntry struct {
+	Exists    bool      `json:"exists"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func packageCachePath() string {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, packageCacheFile)
+}
+
+// cachedVerifyPackage is verify with its answers stored in the file at path for packageCacheTTL.
+// Unanswered lookups are not stored, and cache failures only cost the speed-up.
+func cachedVerifyPackage(path string, pm PackageManager, pkg string, verify func(PackageManager, string) (bool, bool)) (verified, known bool) {
+	if path == "" {
+		return verify(pm, pkg)
+	}
+	key := pm.Name + "/" + pkg
+	entries := map[string]packageCacheEntry{}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	if e, ok := entries[key]; ok && time.Since(e.CheckedAt) < packageCacheTTL {
+		return e.Exists, true
+	}
+
+	verified, known = verify(pm, pkg)
+	if !known {
+		return verified, known
+	}
+	for k, e := range entries {
+		if time.Since(e.CheckedAt) >= packageCacheTTL {
+			delete(entries, k)
+		}
+	}
+	entries[key] = packageCacheEntry{Exists: verified, CheckedAt: time.Now()}
+	if data, err := json.Marshal(entries); err == nil {
+		if os.MkdirAll(filepath.Dir(path), config.DefaultDirPermissions) == nil {
+			_ = os.WriteFile(path, data, 0600)
+		}
+	}
+	return verified, known
+}
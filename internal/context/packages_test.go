@@ -0,0 +1,141 @@
+package context
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func fakeLookPath(available ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, a := range available {
+			if a == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name      string
+		goos      string
+		osRelease map[string]string
+		available []string
+		want      string
+	}{
+		{"ubuntu", "linux", map[string]string{"ID": "ubuntu", "ID_LIKE": "debian"}, []string{"apt", "brew"}, "apt"},
+		{"mint via ID_LIKE", "linux", map[string]string{"ID": "linuxmint", "ID_LIKE": "ubuntu debian"}, []string{"apt"}, "apt"},
+		{"fedora", "linux", map[string]string{"ID": "fedora"}, []string{"dnf", "yum"}, "dnf"},
+		{"unknown distro", "linux", nil, []string{"pacman"}, "pacman"},
+		{"linuxbrew only", "linux", nil, []string{"brew"}, "brew"},
+		{"macOS", "darwin", nil, []string{"brew"}, "brew"},
+	}
+	for _, tt := range tests {
+		pm, ok := detectPackageManager(tt.goos, tt.osRelease, fakeLookPath(tt.available...))
+		if !ok || pm.Name != tt.want {
+			t.Errorf("%s: got %q (ok=%v), want %q", tt.name, pm.Name, ok, tt.want)
+		}
+	}
+	if _, ok := detectPackageManager("darwin", nil, fakeLookPath()); ok {
+		t.Error("macOS without Homebrew should have no package manager")
+	}
+}
+
+func TestDistroNote(t *testing.T) {
+	apt := packageManagers[1]
+	ubuntu := map[string]string{"ID": "ubuntu", "PRETTY_NAME": "Ubuntu 22.04.3 LTS"}
+	tests := []struct {
+		name       string
+		osRelease  map[string]string
+		hasManager bool
+		isRoot     bool
+		want       string
+	}{
+		{"ubuntu", ubuntu, true, false, "The system is Ubuntu 22.04.3 LTS; packages are installed with apt (sudo apt install <package>), not Homebrew"},
+		{"as root", ubuntu, true, true, "The system is Ubuntu 22.04.3 LTS; packages are installed with apt (apt install <package>), not Homebrew"},
+		{"name and version", map[string]string{"NAME": "Alpine Linux", "VERSION_ID": "3.19"}, false, false, "The system is Alpine Linux 3.19; no known package manager was found"},
+		{"no os-release", nil, true, false, "On this Linux system packages are installed with apt (sudo apt install <package>), not Homebrew"},
+	}
+	for _, tt := range tests {
+		if got, ok := distroNote(tt.osRelease, apt, tt.hasManager, tt.isRoot); !ok || got != tt.want {
+			t.Errorf("%s: got %q (ok=%v), want %q", tt.name, got, ok, tt.want)
+		}
+	}
+	if _, ok := distroNote(nil, PackageManager{}, false, false); ok {
+		t.Error("nothing known should give no note")
+	}
+}
+
+func TestPackageFor(t *testing.T) {
+	apt := PackageManager{Name: "apt"}
+	brew := PackageManager{Name: "brew"}
+
+	if pkg, _ := PackageFor(apt, "fd", ""); pkg != "fd-find" {
+		t.Errorf("apt fd = %q, want fd-find", pkg)
+	}
+	if pkg, _ := PackageFor(brew, "fd", ""); pkg != "fd" {
+		t.Errorf("brew fd = %q, want fd", pkg)
+	}
+	if _, ok := PackageFor(apt, "some-unknown-tool", ""); ok {
+		t.Error("unknown commands should not map to a package")
+	}
+
+	ubuntu := "Command 'mytool' not found, but can be installed with:\n\nsudo apt install mytool-utils  # version 1.2-1\n"
+	if pkg, ok := PackageFor(apt, "mytool", ubuntu); !ok || pkg != "mytool-utils" {
+		t.Errorf("expected the package from the command-not-found handler, got %q (ok=%v)", pkg, ok)
+	}
+	if pkg, _ := PackageFor(brew, "rg", "sudo apt install something-else\n"); pkg != "ripgrep" {
+		t.Errorf("a hint for another manager should be ignored, got %q", pkg)
+	}
+}
+
+func TestInstallCommand(t *testing.T) {
+	apt := PackageManager{Name: "apt", Install: []string{"apt", "install"}, Sudo: true}
+	if got := installCommand(apt, "ripgrep", false); got != "sudo apt install ripgrep" {
+		t.Errorf("got %q", got)
+	}
+	if got := installCommand(apt, "ripgrep", true); got != "apt install ripgrep" {
+		t.Errorf("root should not need sudo, got %q", got)
+	}
+	brew := PackageManager{Name: "brew", Install: []string{"brew", "install"}}
+	if got := installCommand(brew, "ripgrep", false); got != "brew install ripgrep" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCachedVerifyPackage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packages.json")
+	apt := PackageManager{Name: "apt"}
+	calls := 0
+	verify := func(pm PackageManager, pkg string) (bool, bool) {
+		calls++
+		switch pkg {
+		case "ripgrep":
+			return true, true
+		case "slow":
+			return false, false
+		}
+		return false, true
+	}
+
+	for i := 0; i < 2; i++ {
+		if verified, known := cachedVerifyPackage(path, apt, "ripgrep", verify); !verified || !known {
+			t.Fatalf("ripgrep: got verified=%v known=%v", verified, known)
+		}
+		if verified, known := cachedVerifyPackage(path, apt, "nosuchpkg", verify); verified || !known {
+			t.Fatalf("nosuchpkg: got verified=%v known=%v", verified, known)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("verify ran %d times, want 2: repeated lookups should come from the cache", calls)
+	}
+
+	// Lookups without an answer are asked again next time
+	cachedVerifyPackage(path, apt, "slow", verify)
+	cachedVerifyPackage(path, apt, "slow", verify)
+	if calls != 4 {
+		t.Errorf("verify ran %d times, want 4: unanswered lookups must not be cached", calls)
+	}
+}
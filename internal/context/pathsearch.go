@@ -0,0 +1,168 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// OffPathMatch is an executable named like a missing command, found in a directory that is not
+// on PATH.
+type OffPathMatch struct {
+	Path string // Full path of the executable
+	Dir  string // Directory it lives in
+	// Local is set for project-local directories (node_modules/.bin), which should be run
+	// directly rather than added to PATH
+	Local bool
+}
+
+// commonBinDirs are install locations, relative to the home directory unless absolute, that
+// installers often put binaries in without adding them to PATH.
+var commonBinDirs = []string{
+	".local/bin",
+	"bin",
+	"go/bin",
+	".cargo/bin",
+	".npm-global/bin",
+	".yarn/bin",
+	".bun/bin",
+	".deno/bin",
+	".volta/bin",
+	".pyenv/shims",
+	".rbenv/shims",
+	".rd/bin",
+	".dotnet/tools",
+	"/usr/local/bin",
+	"/usr/local/sbin",
+	"/usr/local/go/bin",
+	"/opt/homebrew/bin",
+	"/opt/homebrew/sbin",
+	"/opt/local/bin",
+	"/snap/bin",
+	"/usr/sbin",
+	"/sbin",
+}
+
+// commonBinGlobs are like commonBinDirs but contain a version component.
+var commonBinGlobs = []string{
+	"Library/Python/*/bin",
+	".gem/ruby/*/bin",
+	".local/share/gem/ruby/*/bin",
+}
+
+var notFoundPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^zsh: command not found: (\S+)`),
+	regexp.MustCompile(`(?m)(?:^|: )([^\s:]+): command not found`),
+	regexp.MustCompile(`(?m)^fish: Unknown command:? '?([^\s']+)`),
+}
+
+// MissingCommandName returns the name of the command the shell could not find, preferring the
+// name in the shell's error message and falling back to the first word of command. Names that
+// are already paths are not looked up and yield "".
+func MissingCommandName(command, stderr string) string {
+	name := ""
+	for _, re := range notFoundPatterns {
+		if m := re.FindStringSubmatch(stderr); m != nil {
+			name = m[1]
+			break
+		}
+	}
+	if name == "" {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return ""
+		}
+		name = fields[0]
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+	return name
+}
+
+// FindOffPath looks for an executable called name in common install directories and in
+// node_modules/.bin of the working directory and its parents, skipping directories already on
+// PATH. Matches are returned in search order, project-local ones first.
+func FindOffPath(name string) []OffPathMatch {
+	home, _ := os.UserHomeDir()
+	cwd, _ := os.Getwd()
+	return findOffPath(name, os.Getenv("PATH"), home, cwd)
+}
+
+func findOffPath(name, pathEnv, home, cwd string) []OffPathMatch {
+	if name == "" {
+		return nil
+	}
+	onPath := map[string]bool{}
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir != "" {
+			onPath[filepath.Clean(dir)] = true
+		}
+	}
+
+	var matches []OffPathMatch
+	seen := map[string]bool{}
+	check := func(dir string, local bool) {
+		dir = filepath.Clean(dir)
+		if seen[dir] || onPath[dir] {
+			return
+		}
+		seen[dir] = true
+		for _, candidate := range executableNames(name) {
+			path := filepath.Join(dir, candidate)
+			if isExecutable(path) {
+				matches = append(matches, OffPathMatch{Path: path, Dir: dir, Local: local})
+				return
+			}
+		}
+	}
+
+	for dir := cwd; dir != ""; {
+		check(filepath.Join(dir, "node_modules", ".bin"), true)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		for _, p := range filepath.SplitList(gopath) {
+			check(filepath.Join(p, "bin"), false)
+		}
+	}
+	for _, dir := range commonBinDirs {
+		if !filepath.IsAbs(dir) {
+			if home == "" {
+				continue
+			}
+			dir = filepath.Join(home, dir)
+		}
+		check(dir, false)
+	}
+	if home != "" {
+		for _, pattern := range commonBinGlobs {
+			dirs, _ := filepath.Glob(filepath.Join(home, pattern))
+			for _, dir := range dirs {
+				check(dir, false)
+			}
+		}
+	}
+	return matches
+}
+
+func executableNames(name string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{name + ".exe", name + ".cmd", name + ".bat", name}
+	}
+	return []string{name}
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,87 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeExecutable(t *testing.T, dir, name string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMissingCommandName(t *testing.T) {
+	tests := []struct {
+		command, stderr, want string
+	}{
+		{"mytool --help", "bash: mytool: command not found\n", "mytool"},
+		{"mytool --help", "zsh: command not found: mytool\n", "mytool"},
+		{"cat x | jqq .", "bash: jqq: command not found\n", "jqq"},
+		{"mytool --help", "", "mytool"},
+		{"./build.sh", "bash: ./build.sh: No such file or directory\n", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := MissingCommandName(tt.command, tt.stderr); got != tt.want {
+			t.Errorf("MissingCommandName(%q, %q) = %q, want %q", tt.command, tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestFindOffPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on unix executable bits")
+	}
+	home := t.TempDir()
+	project := filepath.Join(home, "src", "app")
+	localBin := filepath.Join(home, ".local", "bin")
+	cargoBin := filepath.Join(home, ".cargo", "bin")
+
+	writeExecutable(t, localBin, "mytool")
+	writeExecutable(t, cargoBin, "mytool")
+	nodeTool := writeExecutable(t, filepath.Join(home, "src", "node_modules", ".bin"), "mytool")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	matches := findOffPath("mytool", cargoBin+string(os.PathListSeparator)+"/usr/bin", home, project)
+	if len(matches) != 2 {
+		t.Fatalf("expected node_modules and .local/bin matches, got %+v", matches)
+	}
+	if matches[0].Path != nodeTool || !matches[0].Local {
+		t.Errorf("project-local match should come first: %+v", matches[0])
+	}
+	if matches[1].Dir != localBin || matches[1].Local {
+		t.Errorf("unexpected second match: %+v", matches[1])
+	}
+
+	if got := findOffPath("othertool", "", home, project); len(got) != 0 {
+		t.Errorf("expected no matches, got %+v", got)
+	}
+}
+
+func TestFindOffPathIgnoresNonExecutables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on unix executable bits")
+	}
+	home := t.TempDir()
+	dir := filepath.Join(home, "bin")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes"), []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := findOffPath("notes", "", home, home); len(got) != 0 {
+		t.Errorf("non-executable file should not match: %+v", got)
+	}
+}
@@ -0,0 +1,94 @@
+package context
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// IsWSL reports whether aish runs inside the Windows Subsystem for Linux.
+func IsWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" || os.Getenv("WSL_INTEROP") != "" {
+		return true
+	}
+	return isWSLKernel("/proc/sys/kernel/osrelease")
+}
+
+func isWSLKernel(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	release := strings.ToLower(string(data))
+	return strings.Contains(release, "microsoft") || strings.Contains(release, "wsl")
+}
+
+// WSLNote describes the environment for an LLM prompt.
+func WSLNote() string {
+	note := "Running under WSL (Windows Subsystem for Linux)"
+	if distro := os.Getenv("WSL_DISTRO_NAME"); distro != "" {
+		note += ", distro " + distro
+	}
+	return note + "; Windows drives are mounted at /mnt/<drive letter>, and Windows programs (*.exe) expect Windows paths"
+}
+
+// WindowsToWSLPath converts a Windows path such as C:\Users\me to /mnt/c/Users/me. Other paths
+// are returned unchanged.
+func WindowsToWSLPath(p string) string {
+	if len(p) < 3 || p[1] != ':' || (p[2] != '\\' && p[2] != '/') || !isDriveLetter(p[0]) {
+		return p
+	}
+	rest := strings.Trim(strings.ReplaceAll(p[3:], `\`, "/"), "/")
+	out := "/mnt/" + strings.ToLower(p[:1])
+	if rest != "" {
+		out += "/" + rest
+	}
+	return out
+}
+
+// WSLToWindowsPath converts a path under /mnt/<drive> such as /mnt/c/Users/me to C:\Users\me.
+// Other paths are returned unchanged.
+func WSLToWindowsPath(p string) string {
+	if len(p) < 6 || !strings.HasPrefix(p, "/mnt/") || !isDriveLetter(p[5]) || (len(p) > 6 && p[6] != '/') {
+		return p
+	}
+	rest := strings.Trim(p[6:], "/")
+	return strings.ToUpper(p[5:6]) + `:\` + strings.ReplaceAll(rest, "/", `\`)
+}
+
+func isDriveLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+var (
+	quotedWindowsPath   = regexp.MustCompile(`"[A-Za-z]:[\\/][^"]*"|'[A-Za-z]:[\\/][^']*'`)
+	unquotedWindowsPath = regexp.MustCompile(`(^|[\s=])([A-Za-z]:[\\/][^\s"';|&<>()]*)`)
+	wslDrivePath        = regexp.MustCompile(`(^|[\s="'])(/mnt/[A-Za-z](?:/[^\s"';|&<>()]*)?)`)
+)
+
+// TranslatePathsForWSL rewrites the paths in a suggested command for the side that will read
+// them: arguments to a Windows program (the command is a *.exe) become Windows paths, anything
+// else gets Windows paths turned into their /mnt/<drive> form.
+func TranslatePathsForWSL(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) > 0 && strings.HasSuffix(strings.ToLower(fields[0]), ".exe") {
+		return wslDrivePath.ReplaceAllStringFunc(command, func(m string) string {
+			sub := wslDrivePath.FindStringSubmatch(m)
+			win := WSLToWindowsPath(sub[2])
+			if strings.ContainsAny(sub[1], `"'`) {
+				return sub[1] + win
+			}
+			// Backslashes must be quoted for the Linux shell that starts the program
+			return sub[1] + "'" + win + "'"
+		})
+	}
+
+	command = quotedWindowsPath.ReplaceAllStringFunc(command, func(m string) string {
+		quote := m[:1]
+		return quote + WindowsToWSLPath(m[1:len(m)-1]) + quote
+	})
+	return unquotedWindowsPath.ReplaceAllStringFunc(command, func(m string) string {
+		sub := unquotedWindowsPath.FindStringSubmatch(m)
+		return sub[1] + WindowsToWSLPath(sub[2])
+	})
+}
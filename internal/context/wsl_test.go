@@ -0,0 +1,59 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWindowsWSLPathConversion(t *testing.T) {
+	tests := []struct{ windows, wsl string }{
+		{`C:\Users\me\file.txt`, "/mnt/c/Users/me/file.txt"},
+		{`d:/data/logs`, "/mnt/d/data/logs"},
+		{`C:\`, "/mnt/c"},
+	}
+	for _, tt := range tests {
+		if got := WindowsToWSLPath(tt.windows); got != tt.wsl {
+			t.Errorf("WindowsToWSLPath(%q) = %q, want %q", tt.windows, got, tt.wsl)
+		}
+	}
+	if got := WSLToWindowsPath("/mnt/c/Users/me"); got != `C:\Users\me` {
+		t.Errorf("WSLToWindowsPath = %q", got)
+	}
+	for _, p := range []string{"/home/me", "/mnt/data/x", "relative\\path"} {
+		if WindowsToWSLPath(p) != p || WSLToWindowsPath(p) != p {
+			t.Errorf("%q should be left alone", p)
+		}
+	}
+}
+
+func TestTranslatePathsForWSL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`cat C:\Users\me\notes.txt`, "cat /mnt/c/Users/me/notes.txt"},
+		{`cd "C:\Program Files\App"`, `cd "/mnt/c/Program Files/App"`},
+		{`cp --target=D:\backup a.txt`, "cp --target=/mnt/d/backup a.txt"},
+		{`notepad.exe /mnt/c/Users/me/notes.txt`, `notepad.exe 'C:\Users\me\notes.txt'`},
+		{`explorer.exe "/mnt/c/Program Files"`, `explorer.exe "C:\Program Files"`},
+		{"ls -la /home/me", "ls -la /home/me"},
+	}
+	for _, tt := range tests {
+		if got := TranslatePathsForWSL(tt.in); got != tt.want {
+			t.Errorf("TranslatePathsForWSL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsWSLKernel(t *testing.T) {
+	dir := t.TempDir()
+	wsl := filepath.Join(dir, "wsl")
+	native := filepath.Join(dir, "native")
+	if err := os.WriteFile(wsl, []byte("5.15.153.1-microsoft-standard-WSL2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(native, []byte("6.8.0-45-generic\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !isWSLKernel(wsl) || isWSLKernel(native) || isWSLKernel(filepath.Join(dir, "missing")) {
+		t.Error("WSL kernel detection is wrong")
+	}
+}
@@ -0,0 +1,237 @@
+// Package diagnostics collects sanitized troubleshooting data for bug reports.
+package diagnostics
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/security"
+)
+
+// DefaultLogLines is the number of trailing log lines included when none is requested.
+const DefaultLogLines = 200
+
+// redacted replaces secrets in the bundled configuration.
+const redacted = "***REDACTED***"
+
+// BundleOptions describes what goes into a diagnostic bundle.
+type BundleOptions struct {
+	Version     string         // aish version string
+	Config      *config.Config // loaded configuration; nil when loading failed
+	ConfigError error          // error returned while loading the configuration
+	LogFile     string         // log file to tail; empty skips the log section
+	LogLines    int            // number of trailing log lines to include
+	HookFile    string         // shell config file holding the hook
+	HookSnippet string         // installed hook block, empty when not installed
+	HealthFile  string         // provider health state, with the HTTP timing of the last connection checks
+	Now         time.Time      // bundle timestamp; zero means time.Now()
+}
+
+// bundleFile is a single entry of the tarball.
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// WriteBundle writes a gzip-compressed tarball with the sanitized diagnostics to w.
+func WriteBundle(w io.Writer, opts BundleOptions) error {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.LogLines <= 0 {
+		opts.LogLines = DefaultLogLines
+	}
+
+	files := []bundleFile{
+		{name: "version.txt", data: []byte(versionSection(opts))},
+		{name: "environment.txt", data: []byte(environmentSection())},
+		{name: "config.json", data: configSection(opts)},
+		{name: "aish.log", data: []byte(logSection(opts.LogFile, opts.LogLines))},
+		{name: "hook.txt", data: []byte(hookSection(opts.HookFile, opts.HookSnippet))},
+		{name: "provider_health.json", data: healthSection(opts.HealthFile)},
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    "aish-debug/" + f.name,
+			Mode:    0600,
+			Size:    int64(len(f.data)),
+			ModTime: opts.Now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write %s header: %w", f.name, err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func versionSection(opts BundleOptions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "aish %s\n", opts.Version)
+	fmt.Fprintf(&b, "go %s\n", runtime.Version())
+	fmt.Fprintf(&b, "platform %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "generated %s\n", opts.Now.UTC().Format(time.RFC3339))
+	return b.String()
+}
+
+// environmentSection lists shell-related variables and every AISH_* variable, with values sanitized.
+func environmentSection() string {
+	var aishKeys []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "AISH_") {
+			aishKeys = append(aishKeys, name)
+		}
+	}
+	sort.Strings(aishKeys)
+	keys := append([]string{"SHELL", "TERM", "LANG", "LC_ALL", "NO_COLOR", "ZSH_VERSION", "BASH_VERSION"}, aishKeys...)
+
+	var b strings.Builder
+	for _, key := range keys {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if isSecretName(key) && value != "" {
+			value = redacted
+		}
+		fmt.Fprintf(&b, "%s=%s\n", key, security.SanitizeText(value))
+	}
+	return b.String()
+}
+
+// configSection marshals a copy of the configuration with credentials masked.
+func configSection(opts BundleOptions) []byte {
+	if opts.Config == nil {
+		msg := "configuration unavailable"
+		if opts.ConfigError != nil {
+			msg = fmt.Sprintf("configuration unavailable: %v", opts.ConfigError)
+		}
+		data, _ := json.MarshalIndent(map[string]string{"error": security.SanitizeText(msg)}, "", "  ")
+		return data
+	}
+
+	masked := MaskConfig(opts.Config)
+	data, err := json.MarshalIndent(masked, "", "  ")
+	if err != nil {
+		return []byte(fmt.Sprintf("{\"error\": %q}", err.Error()))
+	}
+	return data
+}
+
+// MaskConfig returns a copy of cfg with API keys, project IDs and extra header values replaced.
+func MaskConfig(cfg *config.Config) *config.Config {
+	masked := *cfg
+	masked.Providers = make(map[string]config.ProviderConfig, len(cfg.Providers))
+	for name, pc := range cfg.Providers {
+		if strings.TrimSpace(pc.APIKey) != "" {
+			pc.APIKey = redacted
+		}
+		if strings.TrimSpace(pc.Project) != "" {
+			pc.Project = redacted
+		}
+		if len(pc.ExtraHeaders) > 0 {
+			headers := make(map[string]string, len(pc.ExtraHeaders))
+			for name := range pc.ExtraHeaders {
+				headers[name] = redacted
+			}
+			pc.ExtraHeaders = headers
+		}
+		masked.Providers[name] = pc
+	}
+	if u, err := url.Parse(cfg.UserPreferences.Proxy); err == nil && u.User != nil {
+		masked.UserPreferences.Proxy = u.Redacted()
+	}
+	return &masked
+}
+
+// logSection returns the sanitized last n lines of the log file.
+func logSection(path string, n int) string {
+	if strings.TrimSpace(path) == "" {
+		return "log file not configured\n"
+	}
+	lines, err := tailLines(path, n)
+	if err != nil {
+		return fmt.Sprintf("log file unavailable: %v\n", err)
+	}
+	if len(lines) == 0 {
+		return "log file is empty\n"
+	}
+	return strings.Join(security.GetDefaultSanitizer().SanitizeLines(lines), "\n") + "\n"
+}
+
+func hookSection(path, snippet string) string {
+	if strings.TrimSpace(snippet) == "" {
+		return fmt.Sprintf("no aish hook found in %s\n", path)
+	}
+	return fmt.Sprintf("# from %s\n%s\n", path, security.SanitizeText(snippet))
+}
+
+// healthSection returns the sanitized provider health state, which holds the last error and
+// HTTP timing of each provider.
+func healthSection(path string) []byte {
+	data, err := os.ReadFile(path)
+	if strings.TrimSpace(path) == "" || err != nil {
+		msg := "no provider health recorded"
+		if err != nil && !os.IsNotExist(err) {
+			msg = fmt.Sprintf("provider health unavailable: %v", err)
+		}
+		data, _ := json.MarshalIndent(map[string]string{"error": msg}, "", "  ")
+		return data
+	}
+	return []byte(sanitize(string(data)))
+}
+
+// tailLines keeps the last n lines of the file in a ring so large logs are never held in memory.
+func tailLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	ring := make([]string, 0, n)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(ring) == n {
+			ring = ring[1:]
+		}
+		ring = append(ring, scanner.Text())
+	}
+	return ring, scanner.Err()
+}
+
+// sanitize redacts secrets with the shared sanitizer.
+func sanitize(text string) string {
+	return security.SanitizeText(text)
+}
+
+func isSecretName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range []string{"KEY", "TOKEN", "SECRET", "BEARER", "PASSWORD"} {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
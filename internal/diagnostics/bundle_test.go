@@ -0,0 +1,129 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("bundle is not gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("bad tar entry: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		files[strings.TrimPrefix(hdr.Name, "aish-debug/")] = string(body)
+	}
+	return files
+}
+
+func TestWriteBundleMasksSecrets(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "aish.log")
+	var log strings.Builder
+	for i := 0; i < 10; i++ {
+		log.WriteString("line\n")
+	}
+	log.WriteString("request failed api_key=sk-abcdefghijklmnopqrstuvwxyz\n")
+	if err := os.WriteFile(logFile, []byte(log.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		DefaultProvider: config.ProviderOpenAI,
+		Providers: map[string]config.ProviderConfig{
+			config.ProviderOpenAI:    {APIKey: "sk-supersecretvalue1234", Model: "gpt-4", ExtraHeaders: map[string]string{"X-Gateway-Token": "gw-supersecret"}},
+			config.ProviderGeminiCLI: {Project: "my-private-project"},
+		},
+	}
+	t.Setenv("AISH_GEMINI_BEARER", "ya29.tokenvalue")
+	healthFile := filepath.Join(dir, "provider_health.json")
+	health := `{"providers":{"openai":{"healthy":false,"error":"401 api_key=sk-abcdefghijklmnopqrstuvwxyz","timing":"dns 12ms, first byte 820ms, total 1.35s"}}}`
+	if err := os.WriteFile(healthFile, []byte(health), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err := WriteBundle(&buf, BundleOptions{
+		Version:     "v1.2.3",
+		Config:      cfg,
+		LogFile:     logFile,
+		LogLines:    3,
+		HookFile:    "/home/u/.bashrc",
+		HookSnippet: config.HookStartMarker + "\n# body\n" + config.HookEndMarker,
+		HealthFile:  healthFile,
+	})
+	if err != nil {
+		t.Fatalf("WriteBundle failed: %v", err)
+	}
+
+	files := readBundle(t, buf.Bytes())
+	for _, name := range []string{"version.txt", "environment.txt", "config.json", "aish.log", "hook.txt", "provider_health.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("bundle missing %s", name)
+		}
+	}
+
+	if !strings.Contains(files["version.txt"], "aish v1.2.3") {
+		t.Errorf("version.txt missing version: %q", files["version.txt"])
+	}
+	if strings.Contains(files["config.json"], "supersecret") || strings.Contains(files["config.json"], "my-private-project") {
+		t.Errorf("config.json leaks secrets: %s", files["config.json"])
+	}
+	if !strings.Contains(files["config.json"], "gpt-4") || !strings.Contains(files["config.json"], "X-Gateway-Token") {
+		t.Errorf("config.json lost non-secret fields: %s", files["config.json"])
+	}
+	if strings.Contains(files["environment.txt"], "ya29.tokenvalue") {
+		t.Errorf("environment.txt leaks token: %s", files["environment.txt"])
+	}
+	if got := strings.Count(files["aish.log"], "\n"); got != 3 {
+		t.Errorf("expected 3 log lines, got %d: %q", got, files["aish.log"])
+	}
+	if strings.Contains(files["aish.log"], "abcdefghijklmnop") {
+		t.Errorf("aish.log leaks api key: %q", files["aish.log"])
+	}
+	if !strings.Contains(files["hook.txt"], config.HookStartMarker) {
+		t.Errorf("hook.txt missing hook block: %q", files["hook.txt"])
+	}
+	if !strings.Contains(files["provider_health.json"], "first byte 820ms") {
+		t.Errorf("provider_health.json missing the timing: %q", files["provider_health.json"])
+	}
+	if strings.Contains(files["provider_health.json"], "abcdefghijklmnop") {
+		t.Errorf("provider_health.json leaks api key: %q", files["provider_health.json"])
+	}
+}
+
+func TestWriteBundleWithoutConfig(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, BundleOptions{Version: "dev", ConfigError: os.ErrNotExist}); err != nil {
+		t.Fatalf("WriteBundle failed: %v", err)
+	}
+	files := readBundle(t, buf.Bytes())
+	if !strings.Contains(files["config.json"], "configuration unavailable") {
+		t.Errorf("expected config error note, got %q", files["config.json"])
+	}
+	if !strings.Contains(files["aish.log"], "not configured") {
+		t.Errorf("expected missing log note, got %q", files["aish.log"])
+	}
+	if !strings.Contains(files["provider_health.json"], "no provider health recorded") {
+		t.Errorf("expected missing health note, got %q", files["provider_health.json"])
+	}
+}
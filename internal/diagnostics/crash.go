@@ -0,0 +1,32 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// WriteCrashReport records a recovered panic with its stack trace in dir and returns the report path.
+// Arguments are sanitized because a panic message may quote the command line being processed.
+func WriteCrashReport(dir, version string, recovered interface{}, stack []byte, args []string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s-%d.log", now.Format("20060102-150405"), os.Getpid()))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "aish %s crashed at %s\n", version, now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "go %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "args: %s\n", sanitize(strings.Join(args, " ")))
+	fmt.Fprintf(&b, "panic: %s\n\n", sanitize(fmt.Sprint(recovered)))
+	b.Write(stack)
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,32 @@
+package diagnostics
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteCrashReport(t *testing.T) {
+	dir := t.TempDir()
+	path, err := WriteCrashReport(dir, "v9.9.9", "index out of range", []byte("goroutine 1 [running]:\nmain.main()\n"),
+		[]string{"aish", "-p", "deploy with api_key=abcdefghijklmnopqrstuvwxyz"})
+	if err != nil {
+		t.Fatalf("WriteCrashReport failed: %v", err)
+	}
+	if !strings.HasPrefix(path, dir) {
+		t.Errorf("report written outside %s: %s", dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{"aish v9.9.9 crashed", "panic: index out of range", "goroutine 1 [running]"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "abcdefghijklmnop") {
+		t.Errorf("report leaks secret from args:\n%s", report)
+	}
+}
@@ -0,0 +1,86 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Process exit codes. They are part of the CLI contract so wrappers can branch on the failure type;
+// never renumber an existing code.
+const (
+	ExitOK         = 0
+	ExitGeneric    = 1
+	ExitConfig     = 2
+	ExitProvider   = 3
+	ExitAuth       = 4
+	ExitNetwork    = 5
+	ExitPermission = 6
+	ExitUserCancel = 130
+)
+
+// ExitCodeFor maps an error code to the process exit code.
+func ExitCodeFor(code ErrorCode) int {
+	switch code {
+	case ErrConfigLoad, ErrConfigSave, ErrConfigValidation, ErrConfigMissing:
+		return ExitConfig
+	case ErrProviderInit, ErrProviderNotFound, ErrProviderRequest, ErrProviderResponse, ErrProviderQuota:
+		return ExitProvider
+	case ErrProviderAuth:
+		return ExitAuth
+	case ErrNetwork, ErrTimeout:
+		return ExitNetwork
+	case ErrPermission:
+		return ExitPermission
+	case ErrUserCancel:
+		return ExitUserCancel
+	default:
+		return ExitGeneric
+	}
+}
+
+// ExitCode returns the process exit code for err.
+// Context cancellation counts as a user cancel, everything unrecognized as a generic failure.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var aishErr *AishError
+	if errors.As(err, &aishErr) {
+		return ExitCodeFor(aishErr.Code)
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitUserCancel
+	}
+	return ExitGeneric
+}
+
+// Report is the machine-readable form of a failure written for '--output json'.
+type Report struct {
+	Code        ErrorCode `json:"code"`
+	ExitCode    int       `json:"exit_code"`
+	Message     string    `json:"message"`
+	Details     string    `json:"details,omitempty"`
+	Suggestions []string  `json:"suggestions,omitempty"`
+}
+
+// NewReport builds a report for err with the given code.
+func NewReport(code ErrorCode, message string, cause error) Report {
+	r := Report{
+		Code:     code,
+		ExitCode: ExitCodeFor(code),
+		Message:  message,
+	}
+	if cause != nil {
+		r.Details = cause.Error()
+	}
+	return r
+}
+
+// WriteJSON writes the report as a single-line JSON object wrapped in an "error" key.
+func (r Report) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(struct {
+		Error Report `json:"error"`
+	}{Error: r})
+}
@@ -55,6 +55,7 @@ const (
 	ErrNetwork    ErrorCode = "NETWORK"
 	ErrPermission ErrorCode = "PERMISSION"
 	ErrTimeout    ErrorCode = "TIMEOUT"
+	ErrInternal   ErrorCode = "INTERNAL"
 )
 
 // AishError represents a structured error for AISH application
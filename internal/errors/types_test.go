@@ -1,7 +1,11 @@
 package errors
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -136,3 +140,53 @@ func TestHasCode(t *testing.T) {
 		t.Error("普通錯誤不應該具有特定錯誤代碼")
 	}
 }
+
+func TestExitCodeFor(t *testing.T) {
+	cases := map[ErrorCode]int{
+		ErrConfigLoad:      ExitConfig,
+		ErrConfigMissing:   ExitConfig,
+		ErrProviderRequest: ExitProvider,
+		ErrProviderAuth:    ExitAuth,
+		ErrNetwork:         ExitNetwork,
+		ErrUserCancel:      ExitUserCancel,
+		ErrInternal:        ExitGeneric,
+	}
+	for code, want := range cases {
+		if got := ExitCodeFor(code); got != want {
+			t.Errorf("ExitCodeFor(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(nil); got != ExitOK {
+		t.Errorf("ExitCode(nil) = %d, want %d", got, ExitOK)
+	}
+	wrapped := fmt.Errorf("outer: %w", NewError(ErrProviderAuth, "bad key"))
+	if got := ExitCode(wrapped); got != ExitAuth {
+		t.Errorf("ExitCode(wrapped auth) = %d, want %d", got, ExitAuth)
+	}
+	if got := ExitCode(context.Canceled); got != ExitUserCancel {
+		t.Errorf("ExitCode(context.Canceled) = %d, want %d", got, ExitUserCancel)
+	}
+	if got := ExitCode(errors.New("boom")); got != ExitGeneric {
+		t.Errorf("ExitCode(plain) = %d, want %d", got, ExitGeneric)
+	}
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	report := NewReport(ErrConfigMissing, "no provider", errors.New("missing key"))
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded struct {
+		Error Report `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if decoded.Error.Code != ErrConfigMissing || decoded.Error.ExitCode != ExitConfig || decoded.Error.Details != "missing key" {
+		t.Errorf("unexpected report: %+v", decoded.Error)
+	}
+}
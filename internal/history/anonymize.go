@@ -0,0 +1,191 @@
+package history
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ipv4Pattern     = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern     = regexp.MustCompile(`(?i)\b(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}\b`)
+	homePathPattern = regexp.MustCompile(`(/home/|/Users/|[A-Za-z]:\\Users\\)([A-Za-z0-9._-]+)`)
+	// The host must end in an alphabetic label or be a bare name, so package@1.2.3 is not a host
+	userHostPattern = regexp.MustCompile(`\b([a-z_][a-z0-9._-]*)@([A-Za-z][A-Za-z0-9-]*(?:\.[A-Za-z0-9-]+)*)\b`)
+)
+
+// commonAccounts are user names too generic to identify anyone; replacing every "root" in an
+// error message would only make it harder to read.
+var commonAccounts = map[string]bool{"root": true, "admin": true, "user": true, "nobody": true, "git": true}
+
+// Anonymizer replaces user names, host names, home paths and IP addresses with placeholders
+// such as <user1>, <host1> and <ip1>. Placeholders are stable: the same value gets the same
+// placeholder across every entry run through one Anonymizer, so a shared corpus still shows
+// that two failures happened on the same machine.
+type Anonymizer struct {
+	users map[string]string
+	hosts map[string]string
+	ips   map[string]string
+	// words are the current user and host names, which are also replaced where they appear
+	// on their own rather than in a path or user@host
+	words map[string]string
+}
+
+// NewAnonymizer returns an Anonymizer that already knows the current user and host, so they
+// are replaced even where they appear on their own.
+func NewAnonymizer() *Anonymizer {
+	a := newAnonymizer()
+	if u, err := user.Current(); err == nil {
+		a.addWord(u.Username, a.user(u.Username))
+	}
+	if name, err := os.Hostname(); err == nil {
+		a.addWord(name, a.host(name))
+		if short, _, ok := strings.Cut(name, "."); ok {
+			a.addWord(short, a.host(short))
+		}
+	}
+	return a
+}
+
+func newAnonymizer() *Anonymizer {
+	return &Anonymizer{
+		users: map[string]string{},
+		hosts: map[string]string{},
+		ips:   map[string]string{},
+		words: map[string]string{},
+	}
+}
+
+func (a *Anonymizer) addWord(value, placeholder string) {
+	if value != "" && value != placeholder {
+		a.words[value] = placeholder
+	}
+}
+
+// Entry returns a copy of e with its command, output and suggestion anonymized.
+func (a *Anonymizer) Entry(e Entry) Entry {
+	e.Command = a.Text(e.Command)
+	e.Stdout = a.Text(e.Stdout)
+	e.Stderr = a.Text(e.Stderr)
+	e.SuggestedCommand = a.Text(e.SuggestedCommand)
+	e.Explanation = a.Text(e.Explanation)
+	return e
+}
+
+// Text anonymizes s.
+func (a *Anonymizer) Text(s string) string {
+	if s == "" {
+		return s
+	}
+	s = ipv4Pattern.ReplaceAllStringFunc(s, func(m string) string {
+		if net.ParseIP(m) == nil {
+			return m
+		}
+		return a.ip(m)
+	})
+	s = ipv6Pattern.ReplaceAllStringFunc(s, func(m string) string {
+		// Require a digit so C++ and Rust paths such as add::f are left alone
+		if !strings.ContainsAny(m, "0123456789") || net.ParseIP(m) == nil {
+			return m
+		}
+		return a.ip(m)
+	})
+	s = homePathPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := homePathPattern.FindStringSubmatch(m)
+		return parts[1] + a.user(parts[2])
+	})
+	s = userHostPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := userHostPattern.FindStringSubmatch(m)
+		return a.user(parts[1]) + "@" + a.host(parts[2])
+	})
+	return replaceWords(s, a.words)
+}
+
+// user returns the placeholder for name, allocating one on first use.
+func (a *Anonymizer) user(name string) string {
+	if commonAccounts[name] {
+		return name
+	}
+	return placeholder(a.users, name, "user")
+}
+
+func (a *Anonymizer) host(name string) string {
+	if name == "localhost" {
+		return name
+	}
+	return placeholder(a.hosts, name, "host")
+}
+
+func (a *Anonymizer) ip(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		return addr
+	}
+	return placeholder(a.ips, addr, "ip")
+}
+
+func placeholder(seen map[string]string, value, kind string) string {
+	if value == "" {
+		return value
+	}
+	if p, ok := seen[value]; ok {
+		return p
+	}
+	p := fmt.Sprintf("<%s%d>", kind, len(seen)+1)
+	seen[value] = p
+	return p
+}
+
+// replaceWords replaces whole-word occurrences of the known values, longest first so a host
+// name is not partially replaced by its short form. A word ends before a dot so alice.example
+// still matches alice, but does not start after one.
+func replaceWords(s string, known map[string]string) string {
+	values := make([]string, 0, len(known))
+	for v := range known {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, v := range values {
+		s = replaceWord(s, v, known[v])
+	}
+	return s
+}
+
+// replaceWord replaces each whole-word occurrence of word in s. Unlike a regexp that matches
+// the delimiters around it, back-to-back occurrences such as alice:alice are all replaced.
+func replaceWord(s, word, replacement string) string {
+	if word == "" {
+		return s
+	}
+	var b strings.Builder
+	rest := s
+	for {
+		i := strings.Index(rest, word)
+		if i < 0 {
+			break
+		}
+		end := i + len(word)
+		before := len(s) - len(rest) + i
+		if (before == 0 || !isWordByte(s[before-1]) && s[before-1] != '.') &&
+			(end == len(rest) || !isWordByte(rest[end])) {
+			b.WriteString(rest[:i])
+			b.WriteString(replacement)
+		} else {
+			b.WriteString(rest[:i+1])
+			end = i + 1
+		}
+		rest = rest[end:]
+	}
+	if b.Len() == 0 {
+		return s
+	}
+	b.WriteString(rest)
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnonymizerReplacesIdentifyingDetails(t *testing.T) {
+	a := newAnonymizer()
+	a.addWord("devbox", a.host("devbox"))
+
+	got := a.Text("ssh: connect to host 203.0.113.7 port 22: alice@devbox.example.com refused; see /home/alice/.ssh/config on devbox")
+	for _, leaked := range []string{"203.0.113.7", "alice", "devbox"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("%q leaked into %q", leaked, got)
+		}
+	}
+	if !strings.Contains(got, "<ip1>") || !strings.Contains(got, "/home/<user1>/.ssh/config") {
+		t.Errorf("unexpected placeholders: %q", got)
+	}
+}
+
+func TestAnonymizerPlaceholdersAreStable(t *testing.T) {
+	a := newAnonymizer()
+	first := a.Entry(Entry{Command: "ping 198.51.100.2", Stderr: "cat /Users/bob/notes: no such file"})
+	second := a.Entry(Entry{Command: "curl http://198.51.100.9", Stdout: "ls /Users/bob"})
+
+	if first.Command != "ping <ip1>" || second.Command != "curl http://<ip2>" {
+		t.Errorf("unexpected IP placeholders: %q, %q", first.Command, second.Command)
+	}
+	if !strings.Contains(first.Stderr, "/Users/<user1>/notes") || second.Stdout != "ls /Users/<user1>" {
+		t.Errorf("same user should map to the same placeholder: %q, %q", first.Stderr, second.Stdout)
+	}
+}
+
+func TestAnonymizerLeavesOrdinaryOutputAlone(t *testing.T) {
+	a := newAnonymizer()
+	for _, s := range []string{
+		"npm ERR! notarget No matching version found for react@18.99.0",
+		"error[E0433]: failed to resolve: use of undeclared crate or module add::f",
+		"connect ECONNREFUSED 127.0.0.1:5432",
+		"root@localhost: permission denied",
+	} {
+		if got := a.Text(s); got != s {
+			t.Errorf("Text(%q) = %q, want it unchanged", s, got)
+		}
+	}
+}
+
+func TestAnonymizerReplacesAdjacentOccurrences(t *testing.T) {
+	a := newAnonymizer()
+	a.addWord("alice", a.user("alice"))
+
+	tests := map[string]string{
+		"chown alice:alice /srv/app": "chown <user1>:<user1> /srv/app",
+		"alice alice":                "<user1> <user1>",
+		"alicea alice.example":       "alicea <user1>.example",
+		"malice x.alice":             "malice x.alice",
+	}
+	for in, want := range tests {
+		if got := a.Text(in); got != want {
+			t.Errorf("Text(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxArchiveSegments is how many rotated segments are kept; older ones are removed when a new
+// segment is written.
+const maxArchiveSegments = 10
+
+// ArchiveCompactStats summarizes a CompactArchive run.
+type ArchiveCompactStats struct {
+	Compressed  int   // Segments rewritten as gzip
+	BytesBefore int64 // Size of the archive directory before compaction
+	BytesAfter  int64 // Size of the archive directory after compaction
+}
+
+// ArchiveDir returns the directory holding rotated history segments.
+func ArchiveDir() (string, error) {
+	path, err := getHistoryPath()
+	if err != nil {
+		return "", err
+	}
+	return archiveDirFor(path), nil
+}
+
+func archiveDirFor(historyPath string) string {
+	return filepath.Join(filepath.Dir(historyPath), "archive")
+}
+
+// writeSegment rotates entries out of the live history into a new gzip segment in dir, in the
+// format ReadArchive reads, and removes the oldest segments beyond maxArchiveSegments.
+func writeSegment(dir string, entries []Entry, now time.Time) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	segment := struct {
+		ArchivedAt time.Time `json:"archived_at"`
+		Entries    []Entry   `json:"entries"`
+	}{ArchivedAt: now.UTC(), Entries: entries}
+	if err := json.NewEncoder(zw).Encode(segment); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, fmt.Sprintf("history_%s.json.gz", now.Format("2006-01-02_15-04-05.000000000")))
+	if err := writeFileAtomic(name, buf.Bytes()); err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "history_*.json*"))
+	if err != nil || len(files) <= maxArchiveSegments {
+		return err
+	}
+	// The timestamp in the name sorts segments oldest first
+	sort.Strings(files)
+	for _, old := range files[:len(files)-maxArchiveSegments] {
+		_ = os.Remove(old)
+	}
+	return nil
+}
+
+// CompactArchive gzips every uncompressed segment in dir. Segments keep their name with a
+// ".gz" suffix added when missing; ReadArchive reads both forms.
+func CompactArchive(dir string) (ArchiveCompactStats, error) {
+	var stats ArchiveCompactStats
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return stats, err
+		}
+		stats.BytesBefore += int64(len(data))
+		if isGzip(data) {
+			stats.BytesAfter += int64(len(data))
+			continue
+		}
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return stats, err
+		}
+		if err := zw.Close(); err != nil {
+			return stats, err
+		}
+		target := path
+		if !strings.HasSuffix(target, ".gz") {
+			target += ".gz"
+		}
+		if err := os.WriteFile(target+".tmp", buf.Bytes(), 0o600); err != nil {
+			return stats, err
+		}
+		if err := os.Rename(target+".tmp", target); err != nil {
+			return stats, err
+		}
+		if target != path {
+			_ = os.Remove(path)
+		}
+		stats.Compressed++
+		stats.BytesAfter += int64(buf.Len())
+	}
+	return stats, nil
+}
+
+// ReadArchive returns the entries of a history segment, compressed or not. Segments hold either
+// an {"entries": [...]} object or one entry per line.
+func ReadArchive(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isGzip(data) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(zr)
+		zr.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data = bytes.TrimSpace(data)
+	var segment struct {
+		Entries []Entry `json:"entries"`
+	}
+	if len(data) > 0 && data[0] == '{' && json.Unmarshal(data, &segment) == nil && segment.Entries != nil {
+		return segment.Entries, nil
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompactArchiveCompressesPlainSegments(t *testing.T) {
+	dir := t.TempDir()
+	segment := struct {
+		ArchivedAt time.Time `json:"archived_at"`
+		Entries    []Entry   `json:"entries"`
+	}{ArchivedAt: time.Now()}
+	for i := 0; i < 20; i++ {
+		segment.Entries = append(segment.Entries, Entry{Command: "git pusj origin main", Stderr: "git: 'pusj' is not a git command", ExitCode: 1})
+	}
+	data, _ := json.Marshal(segment)
+	plain := filepath.Join(dir, "history_2025-01-01_00-00-00.json")
+	if err := os.WriteFile(plain, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := CompactArchive(dir)
+	if err != nil {
+		t.Fatalf("CompactArchive: %v", err)
+	}
+	if stats.Compressed != 1 || stats.BytesAfter >= stats.BytesBefore {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if _, err := os.Stat(plain); !os.IsNotExist(err) {
+		t.Error("plain segment should be replaced by its .gz version")
+	}
+
+	entries, err := ReadArchive(plain + ".gz")
+	if err != nil {
+		t.Fatalf("ReadArchive: %v", err)
+	}
+	if len(entries) != 20 || entries[0].Command != "git pusj origin main" {
+		t.Errorf("unexpected entries after compaction: %d", len(entries))
+	}
+
+	// A second run leaves compressed segments alone
+	if stats, err := CompactArchive(dir); err != nil || stats.Compressed != 0 {
+		t.Errorf("second run: %+v, %v", stats, err)
+	}
+}
+
+func TestCompactArchiveMissingDir(t *testing.T) {
+	if _, err := CompactArchive(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("missing archive dir should not be an error: %v", err)
+	}
+}
+
+func TestWriteSegmentKeepsNewestSegments(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxArchiveSegments+2; i++ {
+		if err := writeSegment(dir, []Entry{{Command: "make"}}, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("writeSegment: %v", err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "history_*"))
+	if len(files) != maxArchiveSegments {
+		t.Fatalf("kept %d segments, want %d", len(files), maxArchiveSegments)
+	}
+	if filepath.Base(files[0]) != "history_2025-01-01_02-00-00.000000000.json.gz" {
+		t.Errorf("oldest kept segment = %s, the two oldest should be removed", files[0])
+	}
+}
@@ -0,0 +1,57 @@
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/classification"
+)
+
+// Filter selects history entries. Every field that is set must match; the zero Filter matches
+// every entry.
+type Filter struct {
+	Query         string                   // Case-insensitive text in the command, its output, the suggestion or the explanation
+	ErrorType     classification.ErrorType // Compared case-insensitively
+	ExitCode      *int
+	CommandPrefix string
+	Since, Until  time.Time // As in Between
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Entry) bool {
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Timestamp.Before(f.Until) {
+		return false
+	}
+	if f.ErrorType != "" && !strings.EqualFold(string(e.ErrorType), string(f.ErrorType)) {
+		return false
+	}
+	if f.ExitCode != nil && e.ExitCode != *f.ExitCode {
+		return false
+	}
+	if f.CommandPrefix != "" && !strings.HasPrefix(strings.TrimSpace(e.Command), f.CommandPrefix) {
+		return false
+	}
+	if q := strings.ToLower(strings.TrimSpace(f.Query)); q != "" {
+		for _, field := range []string{e.Command, e.Stderr, e.Stdout, e.SuggestedCommand, e.Explanation} {
+			if strings.Contains(strings.ToLower(field), q) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Apply returns the entries that pass the filter, keeping their order.
+func (f Filter) Apply(entries []Entry) []Entry {
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/classification"
+)
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: 3, Timestamp: now, Command: "git pusj", ExitCode: 1, ErrorType: classification.GenericError, Stderr: "git: 'pusj' is not a git command"},
+		{ID: 2, Timestamp: now.Add(-time.Hour), Command: "kubectl get pods", ExitCode: 1, ErrorType: classification.NetworkError, Stderr: "connection refused"},
+		{ID: 1, Timestamp: now.Add(-48 * time.Hour), Command: "gti status", ExitCode: 127, ErrorType: classification.CommandNotFound},
+	}
+	one, notFound := 1, 127
+
+	for name, tc := range map[string]struct {
+		filter Filter
+		want   []int64
+	}{
+		"zero":           {Filter{}, []int64{3, 2, 1}},
+		"query":          {Filter{Query: "REFUSED"}, []int64{2}},
+		"error type":     {Filter{ErrorType: "commandnotfound"}, []int64{1}},
+		"exit code":      {Filter{ExitCode: &one}, []int64{3, 2}},
+		"command prefix": {Filter{CommandPrefix: "git"}, []int64{3}},
+		"since":          {Filter{Since: now.Add(-2 * time.Hour)}, []int64{3, 2}},
+		"combined":       {Filter{ExitCode: &notFound, Since: now.Add(-time.Hour)}, nil},
+	} {
+		got := tc.filter.Apply(entries)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %d entries, want %v", name, len(got), tc.want)
+			continue
+		}
+		for i, e := range got {
+			if e.ID != tc.want[i] {
+				t.Errorf("%s: got ID %d at %d, want %v", name, e.ID, i, tc.want)
+			}
+		}
+	}
+}
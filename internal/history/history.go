@@ -2,8 +2,8 @@ package history
 
 import (
 	"log"
-	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/TonnyWong1052/aish/internal/classification"
@@ -12,12 +12,53 @@ import (
 
 // Entry represents a single command record in the history.
 type Entry struct {
-	Timestamp time.Time                `json:"timestamp"`
+	ID        int64                    `json:"id,omitempty"`         // Assigned when the entry is stored
+	Timestamp time.Time                `json:"timestamp"`            // Stored in UTC
+	UTCOffset int                      `json:"utc_offset,omitempty"` // Seconds east of UTC where the entry was captured
 	Command   string                   `json:"command"`
 	Stdout    string                   `json:"stdout"`
 	Stderr    string                   `json:"stderr"`
 	ExitCode  int                      `json:"exit_code"`
 	ErrorType classification.ErrorType `json:"error_type"`
+	// 1-based stage of a failed pipeline that caused the failure, when the hook reported it
+	FailedStage int `json:"failed_stage,omitempty"`
+
+	// Set when a provider analysed the error: who answered, how fast, what was suggested and
+	// whether the user ran it
+	Provider         string `json:"provider,omitempty"`
+	Model            string `json:"model,omitempty"`
+	LatencyMs        int64  `json:"latency_ms,omitempty"`
+	SuggestedCommand string `json:"suggested_command,omitempty"`
+	Explanation      string `json:"explanation,omitempty"`
+	Accepted         bool   `json:"accepted,omitempty"`
+}
+
+// normalizeTimestamp stores the timestamp in UTC, keeping the offset it was taken in, so
+// entries written from different time zones or either side of a DST change compare and sort
+// by the instant they were captured.
+func (e *Entry) normalizeTimestamp() {
+	if e.Timestamp.IsZero() || e.Timestamp.Location() == time.UTC {
+		return
+	}
+	_, e.UTCOffset = e.Timestamp.Zone()
+	e.Timestamp = e.Timestamp.UTC()
+}
+
+// CapturedAt returns the timestamp as the clock where the entry was captured showed it.
+func (e Entry) CapturedAt() time.Time {
+	return e.Timestamp.In(time.FixedZone("", e.UTCOffset))
+}
+
+// sortNewestFirst orders entries by the instant they were captured, newest first. Entries
+// from the same instant, or without a timestamp, keep the order of their IDs, which only grow.
+func sortNewestFirst(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.After(b.Timestamp)
+		}
+		return a.ID > b.ID
+	})
 }
 
 // History holds all the recorded entries.
@@ -34,12 +75,14 @@ func determineHistoryLimit() int {
 	return defaultMaxHistorySize
 }
 
+const historyFileName = "history.jsonl"
+
 func getHistoryPath() (string, error) {
-	home, err := os.UserHomeDir()
+	dir, err := config.StateDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, ".config", "aish", "history.json"), nil
+	return filepath.Join(dir, historyFileName), nil
 }
 
 func Add(entry Entry) error {
@@ -59,6 +102,15 @@ func Load() (*History, error) {
 	return &History{Entries: mgr.Entries()}, nil
 }
 
+// Lookup returns the history entry with the given ID.
+func Lookup(id int64) (Entry, bool, error) {
+	mgr, err := getDefaultManager()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return mgr.Lookup(id)
+}
+
 // Clear clears history file through manager and maintains consistent file format.
 func Clear() error {
 	mgr, err := getDefaultManager()
@@ -68,6 +120,25 @@ func Clear() error {
 	return mgr.Clear()
 }
 
+// Prune removes the entries captured before cutoff and returns how many were removed.
+func Prune(cutoff time.Time) (int, error) {
+	mgr, err := getDefaultManager()
+	if err != nil {
+		return 0, err
+	}
+	return mgr.Prune(cutoff)
+}
+
+// Import adds the entries of an export that are not in the history yet and returns how many
+// were kept within the history limit.
+func Import(entries []Entry) (int, error) {
+	mgr, err := getDefaultManager()
+	if err != nil {
+		return 0, err
+	}
+	return mgr.Import(entries)
+}
+
 // Close forces flush and closes default history manager for resource release when CLI ends.
 func Close() error {
 	if managerInst == nil {
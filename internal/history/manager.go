@@ -5,22 +5,24 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
 )
 
-// Manager maintains persistent write flow for history records, avoiding rewriting the entire file on each operation.
+// Manager keeps the recent history in memory on top of the append-only store, so a capture
+// appends one line instead of rewriting the whole file.
 type Manager struct {
-	mu           sync.RWMutex
-	entries      []Entry // Store with latest records first
-	file         *os.File
-	writer       *bufio.Writer
-	needsRewrite bool
-	maxEntries   int
-	closed       bool
+	mu         sync.RWMutex
+	entries    []Entry // Store with latest records first
+	store      *store
+	maxEntries int
+	closed     bool
 }
 
 var (
@@ -41,46 +43,94 @@ func newManager() (*Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Before XDG_STATE_HOME was honoured the history lived in the config directory
+	if configDir, err := config.ConfigDir(); err == nil {
+		if err := moveHistory(configDir, filepath.Dir(path)); err != nil {
+			log.Printf("aish history: failed to move the history from %s: %v", configDir, err)
+		}
+	}
+	return openManager(path, determineHistoryLimit())
+}
 
+func openManager(path string, maxEntries int) (*Manager, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, err
 	}
 
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
-	if err != nil {
+	mgr := &Manager{
+		store:      newStore(path),
+		maxEntries: maxEntries,
+	}
+	if err := mgr.migrateLegacy(filepath.Join(filepath.Dir(path), legacyHistoryFile)); err != nil {
 		return nil, err
 	}
 
-	entries, needsRewrite, err := loadExistingEntries(path)
+	entries, err := mgr.store.tail(mgr.tailSize())
 	if err != nil {
-		_ = file.Close()
 		return nil, err
 	}
+	sortNewestFirst(entries)
+	mgr.entries = entries
+	return mgr, nil
+}
 
-	mgr := &Manager{
-		entries:      entries,
-		file:         file,
-		writer:       bufio.NewWriter(file),
-		needsRewrite: needsRewrite,
-		maxEntries:   determineHistoryLimit(),
-	}
-
-	mgr.enforceLimitLocked()
+// historyFiles are the files and directories of a history, besides the lock file.
+var historyFiles = []string{historyFileName, "history.idx", legacyHistoryFile, legacyHistoryFile + ".bak", "archive"}
 
-	if mgr.needsRewrite {
-		if err := mgr.rewriteLocked(); err != nil {
-			_ = file.Close()
-			return nil, err
+// moveHistory moves the history in the directory from to the directory to, unless they are the
+// same or to already has a history of its own.
+func moveHistory(from, to string) error {
+	if filepath.Clean(from) == filepath.Clean(to) {
+		return nil
+	}
+	for _, name := range []string{historyFileName, legacyHistoryFile} {
+		if _, err := os.Stat(filepath.Join(to, name)); err == nil {
+			return nil
 		}
-		return mgr, nil
 	}
-
-	if _, err := file.Seek(0, io.SeekEnd); err != nil {
-		_ = file.Close()
-		return nil, err
+	if _, err := os.Stat(filepath.Join(from, historyFileName)); errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(filepath.Join(from, legacyHistoryFile)); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(to, 0o755); err != nil {
+		return err
+	}
+	for _, name := range historyFiles {
+		// Another aish may have moved it first
+		if err := os.Rename(filepath.Join(from, name), filepath.Join(to, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
 	}
+	return nil
+}
 
-	return mgr, nil
+// migrateLegacy moves a history.json written by older versions into the store, once, and
+// keeps the original as history.json.bak.
+func (m *Manager) migrateLegacy(legacyPath string) error {
+	if _, err := os.Stat(m.store.dataPath); err == nil {
+		return nil
+	}
+	unlock, err := m.store.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// Another aish may have migrated it while we waited for the lock
+	if _, err := os.Stat(m.store.dataPath); err == nil {
+		return nil
+	}
+	entries, err := loadLegacyEntries(legacyPath)
+	if err != nil || entries == nil {
+		return err
+	}
+	if m.maxEntries > 0 && len(entries) > m.maxEntries {
+		entries = entries[:m.maxEntries]
+	}
+	if err := m.store.rewrite(entries); err != nil {
+		return err
+	}
+	return os.Rename(legacyPath, legacyPath+".bak")
 }
 
 func (m *Manager) Append(entry Entry) error {
@@ -91,19 +141,16 @@ func (m *Manager) Append(entry Entry) error {
 		return errors.New("history manager closed")
 	}
 
-	m.entries = append([]Entry{entry}, m.entries...)
-	m.enforceLimitLocked()
-
-	if m.needsRewrite {
-		return m.rewriteLocked()
-	}
-
-	if err := m.writeEntry(entry); err != nil {
-		m.needsRewrite = true
+	if err := m.store.append(&entry); err != nil {
 		return err
 	}
-
-	return m.writer.Flush()
+	// Another aish may have appended an entry captured later while this one was being analyzed
+	m.entries = append([]Entry{entry}, m.entries...)
+	sortNewestFirst(m.entries)
+	if m.maxEntries > 0 && len(m.entries) > m.maxEntries {
+		m.entries = m.entries[:m.maxEntries]
+	}
+	return m.compactIfNeededLocked()
 }
 
 func (m *Manager) Entries() []Entry {
@@ -115,6 +162,20 @@ func (m *Manager) Entries() []Entry {
 	return copied
 }
 
+// Lookup returns the entry with the given ID, including entries beyond the in-memory window
+// that have not been compacted away yet.
+func (m *Manager) Lookup(id int64) (Entry, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, entry := range m.entries {
+		if entry.ID == id {
+			return entry, true, nil
+		}
+	}
+	return m.store.lookup(id)
+}
+
 func (m *Manager) Replace(entries []Entry) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -123,105 +184,173 @@ func (m *Manager) Replace(entries []Entry) error {
 		return errors.New("history manager closed")
 	}
 
-	m.entries = cloneEntries(entries)
-	m.enforceLimitLocked()
-	return m.rewriteLocked()
+	entries = cloneEntries(entries)
+	if m.maxEntries > 0 && len(entries) > m.maxEntries {
+		entries = entries[:m.maxEntries]
+	}
+	unlock, err := m.store.lock()
+	if err != nil {
+		return err
+	}
+	err = m.store.rewrite(entries)
+	unlock()
+	if err != nil {
+		return err
+	}
+	reloaded, err := m.store.tail(m.tailSize())
+	if err != nil {
+		return err
+	}
+	sortNewestFirst(reloaded)
+	m.entries = reloaded
+	return nil
 }
 
 func (m *Manager) Clear() error {
 	return m.Replace(nil)
 }
 
-func (m *Manager) Close() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if m.closed {
-		return nil
+// Prune drops the entries captured before cutoff and returns how many were removed.
+func (m *Manager) Prune(cutoff time.Time) (int, error) {
+	entries := m.Entries()
+	kept := Between(entries, cutoff, time.Time{})
+	if len(kept) == len(entries) {
+		return 0, nil
 	}
+	return len(entries) - len(kept), m.Replace(kept)
+}
 
-	var err error
-	if m.needsRewrite {
-		err = m.rewriteLocked()
-	} else {
-		err = m.writer.Flush()
+// Import adds the entries that are not in the history yet, matching them by capture instant
+// and command, and returns how many were kept within the history limit. Imported entries get
+// new IDs, as IDs are only unique within one store.
+func (m *Manager) Import(entries []Entry) (int, error) {
+	type key struct {
+		at      int64
+		command string
+	}
+	merged := m.Entries()
+	seen := make(map[key]bool, len(merged)+len(entries))
+	for _, entry := range merged {
+		seen[key{entry.Timestamp.UnixNano(), entry.Command}] = true
+	}
+	imported := make(map[key]bool, len(entries))
+	for _, entry := range entries {
+		entry.ID = 0
+		entry.normalizeTimestamp()
+		k := key{entry.Timestamp.UnixNano(), entry.Command}
+		if seen[k] {
+			continue
+		}
+		seen[k], imported[k] = true, true
+		merged = append(merged, entry)
+	}
+	if len(imported) == 0 {
+		return 0, nil
 	}
 
-	if cerr := m.file.Close(); err == nil {
-		err = cerr
+	sortNewestFirst(merged)
+	if m.maxEntries > 0 && len(merged) > m.maxEntries {
+		merged = merged[:m.maxEntries]
+	}
+	kept := 0
+	for _, entry := range merged {
+		if imported[key{entry.Timestamp.UnixNano(), entry.Command}] {
+			kept++
+		}
 	}
+	if kept == 0 {
+		return 0, nil
+	}
+	return kept, m.Replace(merged)
+}
+
+// Close marks the manager closed. Every write already reached disk, so there is nothing to flush.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	m.closed = true
-	return err
+	return nil
 }
 
-func (m *Manager) enforceLimitLocked() {
-	if m.maxEntries > 0 && len(m.entries) > m.maxEntries {
-		m.entries = m.entries[:m.maxEntries]
-		m.needsRewrite = true
+// compactIfNeededLocked rewrites the store down to the in-memory window once it holds twice
+// the history limit, so the cost of trimming is paid once every maxEntries captures rather
+// than on each one. The entries trimmed off are rotated into a compressed archive segment.
+func (m *Manager) compactIfNeededLocked() error {
+	if m.maxEntries <= 0 {
+		return nil
 	}
-}
-
-func (m *Manager) writeEntry(entry Entry) error {
-	data, err := json.Marshal(entry)
-	if err != nil {
+	n, err := m.store.count()
+	if err != nil || n <= 2*m.maxEntries {
 		return err
 	}
-	if _, err := m.writer.Write(data); err != nil {
+	// Hold the lock from reading to renaming so an entry the hook appends in between is not lost
+	unlock, err := m.store.lock()
+	if err != nil {
 		return err
 	}
-	if err := m.writer.WriteByte('\n'); err != nil {
+	defer unlock()
+	// Another aish may have appended since we loaded, or compacted already; keep its entries too
+	if n, err = m.store.count(); err != nil || n <= 2*m.maxEntries {
 		return err
 	}
-	return nil
-}
-
-func (m *Manager) rewriteLocked() error {
-	if _, err := m.file.Seek(0, io.SeekStart); err != nil {
+	all, err := m.store.tail(n)
+	if err != nil {
 		return err
 	}
-	if err := m.file.Truncate(0); err != nil {
+	sortNewestFirst(all)
+	latest, rotated := all[:m.maxEntries], all[m.maxEntries:]
+	if err := writeSegment(archiveDirFor(m.store.dataPath), rotated, m.store.nowFunc()); err != nil {
 		return err
 	}
-	m.writer.Reset(m.file)
-
-	for i := len(m.entries) - 1; i >= 0; i-- {
-		if err := m.writeEntry(m.entries[i]); err != nil {
-			return err
-		}
+	if err := m.store.rewrite(latest); err != nil {
+		return err
 	}
+	m.entries = latest
+	return nil
+}
 
-	if err := m.writer.Flush(); err != nil {
-		return err
+func (m *Manager) tailSize() int {
+	if m.maxEntries > 0 {
+		return m.maxEntries
 	}
-	if _, err := m.file.Seek(0, io.SeekEnd); err != nil {
-		return err
+	n, err := m.store.count()
+	if err != nil {
+		return 0
 	}
-
-	m.needsRewrite = false
-	return nil
+	return n
 }
 
-func loadExistingEntries(path string) ([]Entry, bool, error) {
+// legacyHistoryFile is the history file written before the JSONL store: either a JSON
+// document or JSON lines without an index.
+const legacyHistoryFile = "history.json"
+
+// loadLegacyEntries reads a legacy history file, newest first. It returns nil when the file
+// does not exist.
+func loadLegacyEntries(path string) ([]Entry, error) {
 	data, err := os.ReadFile(path)
 	if errors.Is(err, os.ErrNotExist) {
-		return []Entry{}, false, nil
+		return nil, nil
 	}
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
 	data = bytes.TrimSpace(data)
 	if len(data) == 0 {
-		return []Entry{}, false, nil
+		return []Entry{}, nil
 	}
 
 	if data[0] == '[' {
-		var hist History
-		if err := json.Unmarshal(data, &hist); err != nil {
-			return nil, false, err
+		var entries []Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
 		}
-		return cloneEntries(hist.Entries), true, nil
+		return cloneEntries(entries), nil
+	}
+	var hist History
+	if err := json.Unmarshal(data, &hist); err == nil && hist.Entries != nil {
+		return cloneEntries(hist.Entries), nil
 	}
 
 	scanner := bufio.NewScanner(bytes.NewReader(data))
@@ -235,12 +364,12 @@ func loadExistingEntries(path string) ([]Entry, bool, error) {
 		}
 		var entry Entry
 		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			return nil, false, err
+			return nil, err
 		}
 		chronological = append(chronological, entry)
 	}
 	if err := scanner.Err(); err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
 	reversed := make([]Entry, len(chronological))
@@ -248,7 +377,7 @@ func loadExistingEntries(path string) ([]Entry, bool, error) {
 		reversed[i] = chronological[len(chronological)-1-i]
 	}
 
-	return reversed, false, nil
+	return reversed, nil
 }
 
 func cloneEntries(entries []Entry) []Entry {
@@ -364,8 +364,11 @@ func (om *OptimizedManager) loadExistingEntries() error {
 
 func (om *OptimizedManager) compactAndArchive() error {
 	// 創建歸檔文件
-	archiveFile := filepath.Join(om.archiveDir, fmt.Sprintf("history_%s.json.gz",
+	archiveFile := filepath.Join(om.archiveDir, fmt.Sprintf("history_%s.json",
 		time.Now().Format("2006-01-02_15-04-05")))
+	if om.compressionEnabled {
+		archiveFile += ".gz"
+	}
 
 	file, err := os.Create(archiveFile)
 	if err != nil {
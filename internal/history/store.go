@@ -0,0 +1,510 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// indexRecordSize is the size of one index record: id (int64), offset (int64), length (uint32)
+// and four reserved bytes, little endian.
+const indexRecordSize = 24
+
+// indexRecord locates one JSONL line in the data file. length includes the trailing newline.
+type indexRecord struct {
+	id     int64
+	offset int64
+	length uint32
+}
+
+// store is the on-disk history: an append-only JSONL data file plus a fixed-width index of
+// (id, offset, length) records. Captures only ever append to both files, tail reads seek to
+// the last records of the index instead of parsing the whole history, and the data file is
+// only rewritten on compaction or an explicit Replace. Appends and rewrites hold the lock file,
+// so an entry appended by another aish while the history is rewritten is not lost.
+//
+// The index is a cache of the data file and is never trusted blindly: lines appended by another
+// writer (or by an older aish) are indexed on the next sync, and an index that no longer matches
+// the data file (truncated, compacted elsewhere) is rebuilt from scratch.
+type store struct {
+	dataPath  string
+	indexPath string
+	lockPath  string
+	nowFunc   func() time.Time
+}
+
+func newStore(dataPath string) *store {
+	base := dataPath[:len(dataPath)-len(filepath.Ext(dataPath))]
+	return &store{
+		dataPath:  dataPath,
+		indexPath: base + ".idx",
+		lockPath:  base + ".lock",
+		nowFunc:   time.Now,
+	}
+}
+
+// lock blocks until this process holds the store's lock file and returns the function that
+// releases it.
+func (s *store) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.lockPath), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(s.lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+// append writes entry as one line and indexes it, assigning an ID when entry has none.
+func (s *store) append(entry *Entry) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, last, err := s.sync()
+	if err != nil {
+		return err
+	}
+	entry.normalizeTimestamp()
+	if entry.ID == 0 {
+		entry.ID = s.nowFunc().UnixNano()
+		if last != nil && entry.ID <= last.id {
+			entry.ID = last.id + 1
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(s.dataPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	// A single O_APPEND write keeps the line intact even when another aish appends concurrently
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	_, _, err = s.sync()
+	return err
+}
+
+// count returns the number of indexed entries.
+func (s *store) count() (int, error) {
+	n, _, err := s.sync()
+	return n, err
+}
+
+// tail returns up to n of the most recent entries, newest first.
+func (s *store) tail(n int) ([]Entry, error) {
+	entries, err := s.readTail(n)
+	if errors.Is(err, errStaleIndex) {
+		if err := s.rebuild(); err != nil {
+			return nil, err
+		}
+		entries, err = s.readTail(n)
+	}
+	return entries, err
+}
+
+// lookup returns the entry with the given ID. IDs grow with every append, so the index is
+// bisected, reading only the records it visits; an ID it misses is looked for record by record,
+// in case concurrent writers left the index out of order.
+func (s *store) lookup(id int64) (Entry, bool, error) {
+	total, _, err := s.sync()
+	if err != nil || total == 0 {
+		return Entry{}, false, err
+	}
+	rec, ok, err := s.searchIndex(id, total)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if !ok {
+		records, err := s.readIndex(0, total)
+		if err != nil {
+			return Entry{}, false, err
+		}
+		for i := len(records) - 1; i >= 0; i-- {
+			if records[i].id == id {
+				rec, ok = records[i], true
+				break
+			}
+		}
+		if !ok {
+			return Entry{}, false, nil
+		}
+	}
+
+	data, err := os.Open(s.dataPath)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	defer data.Close()
+	entry, err := readRecord(data, rec)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return entry, true, nil
+}
+
+// searchIndex bisects the first total index records for id.
+func (s *store) searchIndex(id int64, total int) (indexRecord, bool, error) {
+	f, err := os.Open(s.indexPath)
+	if err != nil {
+		return indexRecord{}, false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, indexRecordSize)
+	lo, hi := 0, total
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if _, err := f.ReadAt(buf, int64(mid)*indexRecordSize); err != nil {
+			return indexRecord{}, false, err
+		}
+		rec := decodeIndexRecord(buf)
+		switch {
+		case rec.id == id:
+			return rec, true, nil
+		case rec.id < id:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return indexRecord{}, false, nil
+}
+
+// rewrite replaces the data file and index with entries (newest first). Both files are written
+// aside and renamed into place so readers see either the old or the new history. Callers that
+// derive entries from the store hold the lock across reading and rewriting.
+func (s *store) rewrite(entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(s.dataPath), 0o755); err != nil {
+		return err
+	}
+
+	var data, index bytes.Buffer
+	var lastID int64
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		entry.normalizeTimestamp()
+		if entry.ID == 0 || entry.ID <= lastID {
+			entry.ID = lastID + 1
+			if ts := entry.Timestamp.UnixNano(); !entry.Timestamp.IsZero() && ts > entry.ID {
+				entry.ID = ts
+			}
+		}
+		lastID = entry.ID
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		writeIndexRecord(&index, indexRecord{id: entry.ID, offset: int64(data.Len()), length: uint32(len(line))})
+		data.Write(line)
+	}
+
+	if err := writeFileAtomic(s.dataPath, data.Bytes()); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.indexPath, index.Bytes())
+}
+
+var errStaleIndex = errors.New("history index does not match data file")
+
+func (s *store) readTail(n int) ([]Entry, error) {
+	total, _, err := s.sync()
+	if err != nil || total == 0 || n <= 0 {
+		return []Entry{}, err
+	}
+	start := total - n
+	if start < 0 {
+		start = 0
+	}
+	records, err := s.readIndex(start, total)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.Open(s.dataPath)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+
+	entries := make([]Entry, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		entry, err := readRecord(data, records[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// sync brings the index up to date with the data file and returns the number of records and
+// the last one. Lines without an index record are indexed; an index that points past the end
+// of the data file or at the wrong line is rebuilt.
+func (s *store) sync() (int, *indexRecord, error) {
+	dataInfo, err := os.Stat(s.dataPath)
+	if errors.Is(err, os.ErrNotExist) {
+		_ = os.Remove(s.indexPath)
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	total, last, err := s.lastRecord()
+	if err != nil {
+		return 0, nil, err
+	}
+	if last != nil && !s.recordValid(*last, dataInfo.Size()) {
+		if err := os.Remove(s.indexPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, nil, err
+		}
+		total, last = 0, nil
+	}
+
+	covered := int64(0)
+	if last != nil {
+		covered = last.offset + int64(last.length)
+	}
+	if covered >= dataInfo.Size() {
+		return total, last, nil
+	}
+
+	added, err := s.indexFrom(covered, last)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(added) > 0 {
+		total += len(added)
+		last = &added[len(added)-1]
+	}
+	return total, last, nil
+}
+
+// rebuild discards the index and indexes the whole data file again.
+func (s *store) rebuild() error {
+	if err := os.Remove(s.indexPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	_, _, err := s.sync()
+	return err
+}
+
+// lastRecord returns the number of complete index records and the last one. A partially
+// written trailing record is ignored.
+func (s *store) lastRecord() (int, *indexRecord, error) {
+	f, err := os.Open(s.indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+	total := int(info.Size() / indexRecordSize)
+	if total == 0 {
+		return 0, nil, nil
+	}
+	buf := make([]byte, indexRecordSize)
+	if _, err := f.ReadAt(buf, int64(total-1)*indexRecordSize); err != nil {
+		return 0, nil, err
+	}
+	rec := decodeIndexRecord(buf)
+	return total, &rec, nil
+}
+
+// recordValid reports whether rec still describes a line of the data file.
+func (s *store) recordValid(rec indexRecord, dataSize int64) bool {
+	if rec.offset < 0 || rec.offset+int64(rec.length) > dataSize {
+		return false
+	}
+	f, err := os.Open(s.dataPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	entry, err := readRecord(f, rec)
+	return err == nil && entry.ID == rec.id
+}
+
+// indexFrom parses the data file from offset and appends an index record for every complete
+// line. Lines that are not valid entries are skipped; a trailing line without a newline is
+// left for the next sync, as its writer may still be busy.
+func (s *store) indexFrom(offset int64, last *indexRecord) ([]indexRecord, error) {
+	data, err := os.Open(s.dataPath)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	if _, err := data.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var lastID int64
+	if last != nil {
+		lastID = last.id
+	}
+
+	var added []indexRecord
+	reader := bufio.NewReaderSize(data, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec := indexRecord{offset: offset, length: uint32(len(line))}
+		offset += int64(len(line))
+
+		var entry Entry
+		if len(bytes.TrimSpace(line)) == 0 || json.Unmarshal(line, &entry) != nil {
+			continue
+		}
+		rec.id = entry.ID
+		if rec.id == 0 {
+			rec.id = derivedID(rec)
+		}
+		if rec.id <= lastID && entry.ID == 0 {
+			rec.id = lastID + 1
+		}
+		lastID = rec.id
+		added = append(added, rec)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range added {
+		writeIndexRecord(&buf, rec)
+	}
+	f, err := os.OpenFile(s.indexPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return added, f.Close()
+}
+
+// readIndex returns index records [from, to).
+func (s *store) readIndex(from, to int) ([]indexRecord, error) {
+	f, err := os.Open(s.indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, (to-from)*indexRecordSize)
+	if _, err := f.ReadAt(buf, int64(from)*indexRecordSize); err != nil {
+		return nil, err
+	}
+	records := make([]indexRecord, 0, to-from)
+	var prev int64 = -1
+	for i := 0; i < len(buf); i += indexRecordSize {
+		rec := decodeIndexRecord(buf[i : i+indexRecordSize])
+		// Two writers syncing at once may both index the same lines; keep the first copy
+		if rec.offset <= prev {
+			continue
+		}
+		prev = rec.offset
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// readRecord reads and decodes the line rec points at. Lines written without an ID (by the
+// batch manager or an older aish) get the ID they were indexed under.
+func readRecord(data io.ReaderAt, rec indexRecord) (Entry, error) {
+	buf := make([]byte, rec.length)
+	if _, err := data.ReadAt(buf, rec.offset); err != nil {
+		return Entry{}, errStaleIndex
+	}
+	if len(buf) == 0 || buf[len(buf)-1] != '\n' {
+		return Entry{}, errStaleIndex
+	}
+	var entry Entry
+	if err := json.Unmarshal(buf, &entry); err != nil {
+		return Entry{}, errStaleIndex
+	}
+	if entry.ID == 0 {
+		entry.ID = rec.id
+	} else if entry.ID != rec.id {
+		return Entry{}, errStaleIndex
+	}
+	// Lines written before timestamps were stored in UTC carry their offset in the timestamp
+	entry.normalizeTimestamp()
+	return entry, nil
+}
+
+// derivedID is the ID given to a line that was written without one.
+func derivedID(rec indexRecord) int64 {
+	return rec.offset + 1
+}
+
+func writeIndexRecord(buf *bytes.Buffer, rec indexRecord) {
+	var b [indexRecordSize]byte
+	binary.LittleEndian.PutUint64(b[0:8], uint64(rec.id))
+	binary.LittleEndian.PutUint64(b[8:16], uint64(rec.offset))
+	binary.LittleEndian.PutUint32(b[16:20], rec.length)
+	buf.Write(b[:])
+}
+
+func decodeIndexRecord(b []byte) indexRecord {
+	return indexRecord{
+		id:     int64(binary.LittleEndian.Uint64(b[0:8])),
+		offset: int64(binary.LittleEndian.Uint64(b[8:16])),
+		length: binary.LittleEndian.Uint32(b[16:20]),
+	}
+}
+
+// writeFileAtomic writes data next to path and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
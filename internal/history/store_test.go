@@ -0,0 +1,404 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreAppendAndTail(t *testing.T) {
+	s := newStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	for _, cmd := range []string{"ls /nope", "git pusj", "make tset"} {
+		if err := s.append(&Entry{Command: cmd, ExitCode: 1}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	entries, err := s.tail(2)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Command != "make tset" || entries[1].Command != "git pusj" {
+		t.Fatalf("unexpected tail: %+v", entries)
+	}
+	if entries[0].ID <= entries[1].ID {
+		t.Errorf("IDs should increase: %d then %d", entries[1].ID, entries[0].ID)
+	}
+
+	got, ok, err := s.lookup(entries[1].ID)
+	if err != nil || !ok || got.Command != "git pusj" {
+		t.Errorf("lookup: %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, _ := s.lookup(12345); ok {
+		t.Error("lookup of an unknown ID should miss")
+	}
+}
+
+func TestStoreIndexesForeignAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	s := newStore(path)
+	if err := s.append(&Entry{Command: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A line written without going through the store, e.g. by the batch manager
+	line, _ := json.Marshal(Entry{Command: "foreign"})
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write(append(line, '\n'))
+	_ = f.Close()
+
+	entries, err := s.tail(10)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Command != "foreign" || entries[0].ID == 0 {
+		t.Fatalf("foreign line not indexed: %+v", entries)
+	}
+}
+
+func TestStoreRebuildsStaleIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	s := newStore(path)
+	for _, cmd := range []string{"a", "b", "c"} {
+		if err := s.append(&Entry{Command: cmd}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Replace the data file behind the index's back
+	line, _ := json.Marshal(Entry{ID: 7, Command: "rewritten"})
+	if err := os.WriteFile(path, append(line, '\n'), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.tail(10)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Command != "rewritten" {
+		t.Fatalf("stale index not rebuilt: %+v", entries)
+	}
+}
+
+func TestManagerCompactsAtTwiceTheLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	mgr, err := openManager(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		if err := mgr.Append(Entry{Command: string(rune('a' + i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, _ := mgr.store.count(); n != 6 {
+		t.Fatalf("store should keep appending up to twice the limit, has %d", n)
+	}
+	if got := mgr.Entries(); len(got) != 3 || got[0].Command != "f" {
+		t.Fatalf("unexpected entries: %+v", got)
+	}
+
+	if err := mgr.Append(Entry{Command: "g"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := mgr.store.count(); n != 3 {
+		t.Fatalf("store should be compacted to the limit, has %d", n)
+	}
+	segments, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "archive", "history_*.json.gz"))
+	if len(segments) != 1 {
+		t.Fatalf("compaction should rotate the trimmed entries into one gzip segment, got %v", segments)
+	}
+	rotated, err := ReadArchive(segments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 4 || rotated[0].Command != "d" || rotated[3].Command != "a" {
+		t.Fatalf("unexpected rotated entries: %+v", rotated)
+	}
+
+	reopened, err := openManager(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Entries(); len(got) != 3 || got[0].Command != "g" || got[2].Command != "e" {
+		t.Fatalf("unexpected entries after reopen: %+v", got)
+	}
+}
+
+func TestManagerCompactionKeepsConcurrentAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	// Two managers on one file stand in for the capture hook and an interactive aish
+	var managers [2]*Manager
+	for i := range managers {
+		mgr, err := openManager(path, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		managers[i] = mgr
+	}
+
+	const perManager = 300
+	var wg sync.WaitGroup
+	for i, mgr := range managers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perManager; j++ {
+				if err := mgr.Append(Entry{Command: fmt.Sprintf("cmd-%d-%d", i, j)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	live, err := newStore(path).tail(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "archive", "history_*.json.gz"))
+	for _, segment := range segments {
+		rotated, err := ReadArchive(segment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		live = append(live, rotated...)
+	}
+	for _, entry := range live {
+		seen[entry.Command] = true
+	}
+	for i := range managers {
+		for j := 0; j < perManager; j++ {
+			if cmd := fmt.Sprintf("cmd-%d-%d", i, j); !seen[cmd] {
+				t.Errorf("%s was lost by a concurrent compaction", cmd)
+			}
+		}
+	}
+}
+
+func TestManagerMigratesLegacyHistory(t *testing.T) {
+	dir := t.TempDir()
+	legacy := History{Entries: []Entry{
+		{Timestamp: time.Now(), Command: "newest"},
+		{Timestamp: time.Now().Add(-time.Minute), Command: "oldest"},
+	}}
+	data, _ := json.Marshal(legacy)
+	legacyPath := filepath.Join(dir, legacyHistoryFile)
+	if err := os.WriteFile(legacyPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := openManager(filepath.Join(dir, "history.jsonl"), 10)
+	if err != nil {
+		t.Fatalf("openManager: %v", err)
+	}
+	got := mgr.Entries()
+	if len(got) != 2 || got[0].Command != "newest" || got[1].Command != "oldest" {
+		t.Fatalf("unexpected migrated entries: %+v", got)
+	}
+	if _, err := os.Stat(legacyPath + ".bak"); err != nil {
+		t.Errorf("legacy file should be kept as a backup: %v", err)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Error("legacy file should have been moved aside")
+	}
+}
+
+func TestMoveHistoryToStateDir(t *testing.T) {
+	root := t.TempDir()
+	configDir, stateDir := filepath.Join(root, "config"), filepath.Join(root, "state")
+	mgr, err := openManager(filepath.Join(configDir, historyFileName), 10)
+	if err != nil {
+		t.Fatalf("openManager: %v", err)
+	}
+	if err := mgr.Append(Entry{Timestamp: time.Now(), Command: "make build"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := moveHistory(configDir, stateDir); err != nil {
+		t.Fatalf("moveHistory: %v", err)
+	}
+	moved, err := openManager(filepath.Join(stateDir, historyFileName), 10)
+	if err != nil {
+		t.Fatalf("openManager: %v", err)
+	}
+	if got := moved.Entries(); len(got) != 1 || got[0].Command != "make build" {
+		t.Errorf("entries after the move = %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, historyFileName)); !os.IsNotExist(err) {
+		t.Error("the old history should have been moved")
+	}
+
+	// A history already in the state directory is kept
+	if err := os.WriteFile(filepath.Join(configDir, historyFileName), []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveHistory(configDir, stateDir); err != nil {
+		t.Fatalf("moveHistory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, historyFileName)); err != nil {
+		t.Error("the old history should stay when the state directory has one")
+	}
+}
+
+func TestManagerPersistsSuggestionMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	mgr, err := openManager(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Entry{
+		Command:          "gti status",
+		ExitCode:         127,
+		Provider:         "openai",
+		Model:            "gpt-4o-mini",
+		LatencyMs:        1234,
+		SuggestedCommand: "git status",
+		Explanation:      "Typo in git",
+		Accepted:         true,
+	}
+	if err := mgr.Append(want); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := openManager(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reopened.Entries()
+	if len(got) != 1 {
+		t.Fatalf("got %d entries", len(got))
+	}
+	want.ID = got[0].ID
+	if got[0] != want {
+		t.Errorf("got %+v, want %+v", got[0], want)
+	}
+}
+
+func TestManagerOrdersByCaptureInstantAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	// Clocks fell back at 02:00 EDT on 2025-11-02, so 01:10 EST came 40 minutes after 01:30 EDT
+	beforeShift := time.Date(2025, 11, 2, 1, 30, 0, 0, ny)
+	afterShift := beforeShift.Add(40 * time.Minute)
+	if afterShift.Hour() != 1 || afterShift.Minute() != 10 {
+		t.Fatalf("unexpected wall clock after the shift: %v", afterShift)
+	}
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	mgr, err := openManager(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Appended out of order, as when a slower analysis finishes after a later capture
+	for _, e := range []Entry{{Command: "later", Timestamp: afterShift}, {Command: "earlier", Timestamp: beforeShift}} {
+		if err := mgr.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := openManager(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reopened.Entries()
+	if len(got) != 2 || got[0].Command != "later" || got[1].Command != "earlier" {
+		t.Fatalf("entries not newest first: %+v", got)
+	}
+	if got[0].Timestamp.Location() != time.UTC {
+		t.Errorf("timestamp not stored in UTC: %v", got[0].Timestamp)
+	}
+	if _, off := got[0].CapturedAt().Zone(); off != -5*3600 {
+		t.Errorf("after-shift offset = %d, want EST", off)
+	}
+	if _, off := got[1].CapturedAt().Zone(); off != -4*3600 {
+		t.Errorf("before-shift offset = %d, want EDT", off)
+	}
+	if mgr.Entries()[0].Command != "later" {
+		t.Error("in-memory entries not newest first after Append")
+	}
+}
+
+func TestManagerPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	mgr, err := openManager(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, e := range []Entry{
+		{Command: "old", Timestamp: now.Add(-40 * 24 * time.Hour)},
+		{Command: "recent", Timestamp: now.Add(-time.Hour)},
+	} {
+		if err := mgr.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := mgr.Prune(now.Add(-30 * 24 * time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune = %d, %v; want 1 removed", removed, err)
+	}
+	reopened, err := openManager(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Entries(); len(got) != 1 || got[0].Command != "recent" {
+		t.Fatalf("entries after prune: %+v", got)
+	}
+	if removed, err := reopened.Prune(now.Add(-30 * 24 * time.Hour)); err != nil || removed != 0 {
+		t.Errorf("second Prune = %d, %v; want nothing removed", removed, err)
+	}
+}
+
+func TestReadRecordNormalizesLegacyOffsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	line := `{"id":1,"timestamp":"2025-03-07T14:05:09+08:00","command":"ls /nope"}` + "\n"
+	if err := os.WriteFile(path, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := newStore(path).tail(1)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("tail: %v, %v", entries, err)
+	}
+	e := entries[0]
+	if e.Timestamp.Location() != time.UTC || e.Timestamp.Hour() != 6 || e.UTCOffset != 8*3600 {
+		t.Errorf("legacy timestamp read as %v (offset %d)", e.Timestamp, e.UTCOffset)
+	}
+}
+
+func TestStoreLookupBisectsIndex(t *testing.T) {
+	s := newStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	var ids []int64
+	for i := 0; i < 20; i++ {
+		entry := Entry{Command: "false", ExitCode: i}
+		if err := s.append(&entry); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	for i, id := range ids {
+		if got, ok, err := s.lookup(id); err != nil || !ok || got.ExitCode != i {
+			t.Errorf("lookup(%d) = %+v, %v, %v", id, got, ok, err)
+		}
+	}
+
+	// An entry appended with an older ID than its predecessors is still found
+	if err := s.append(&Entry{ID: ids[0] - 1, Command: "late"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, _ := s.lookup(ids[0] - 1); !ok || got.Command != "late" {
+		t.Errorf("out-of-order entry not found: %+v, %v", got, ok)
+	}
+}
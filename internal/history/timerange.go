@@ -0,0 +1,77 @@
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the absolute forms ParseTimeBound accepts besides RFC 3339, read in the
+// caller's time zone.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// ParseTimeBound parses a --since/--until value: a duration back from now ("90m", "24h",
+// "7d", "2w"), "today" or "yesterday" (midnight in loc), a date or date and time in loc
+// ("2025-03-07", "2025-03-07 14:05"), or an RFC 3339 timestamp with its own offset.
+func ParseTimeBound(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if loc == nil {
+		loc = time.Local
+	}
+	switch strings.ToLower(value) {
+	case "":
+		return time.Time{}, fmt.Errorf("empty time")
+	case "today":
+		return midnight(now.In(loc)), nil
+	case "yesterday":
+		return midnight(now.In(loc)).AddDate(0, 0, -1), nil
+	}
+
+	if d, ok := parseAgo(value); ok {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration such as 24h or 7d, a date such as 2025-03-07, or an RFC 3339 timestamp", value)
+}
+
+// parseAgo parses a non-negative duration, adding d (days) and w (weeks) to Go's units.
+func parseAgo(value string) (time.Duration, bool) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil || f < 0 {
+				return 0, false
+			}
+			return time.Duration(f * float64(unit)), true
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Between returns the entries captured at or after since and before until, keeping their
+// order. A zero bound is open.
+func Between(entries []Entry, since, until time.Time) []Entry {
+	return Filter{Since: since, Until: until}.Apply(entries)
+}
@@ -0,0 +1,56 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	now := time.Date(2025, 3, 7, 14, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{"24h", now.Add(-24 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2w", now.AddDate(0, 0, -14)},
+		{"today", time.Date(2025, 3, 7, 0, 0, 0, 0, loc)},
+		{"Yesterday", time.Date(2025, 3, 6, 0, 0, 0, 0, loc)},
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, loc)},
+		{"2025-03-01 08:30", time.Date(2025, 3, 1, 8, 30, 0, 0, loc)},
+		{"2025-03-01T08:30:00Z", time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		got, err := ParseTimeBound(c.in, now, loc)
+		if err != nil || !got.Equal(c.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, %v, want %v", c.in, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "-3d", "2025-13-01", "7x"} {
+		if _, err := ParseTimeBound(bad, now, loc); err == nil {
+			t.Errorf("ParseTimeBound(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	base := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Command: "c", Timestamp: base.Add(2 * time.Hour)},
+		{Command: "b", Timestamp: base.Add(time.Hour)},
+		{Command: "a", Timestamp: base},
+	}
+	got := Between(entries, base.Add(time.Hour), time.Time{})
+	if len(got) != 2 || got[0].Command != "c" || got[1].Command != "b" {
+		t.Errorf("since only: %+v", got)
+	}
+	got = Between(entries, base, base.Add(2*time.Hour))
+	if len(got) != 2 || got[0].Command != "b" || got[1].Command != "a" {
+		t.Errorf("since and until: %+v", got)
+	}
+	if got := Between(entries, time.Time{}, time.Time{}); len(got) != 3 {
+		t.Errorf("open range kept %d entries", len(got))
+	}
+}
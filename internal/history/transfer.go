@@ -0,0 +1,252 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/classification"
+)
+
+// FormatVersion is the version of the export format. Readers ignore fields and columns they do
+// not know, so adding one does not change it; it is only raised when existing ones change
+// meaning, and ReadExport refuses versions newer than its own rather than misread them. Exports
+// without a version predate it and are read as version 1.
+const FormatVersion = 1
+
+// Format is a history export format.
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl" // A version header, then one JSON entry per line
+	FormatCSV   Format = "csv"   // A "# aish_history_version=N" comment, a header row, then one entry per row
+)
+
+// versionKey names the format version in the JSONL header and the CSV comment.
+const versionKey = "aish_history_version"
+
+// ParseFormat returns the format named by s; empty means guess from path as FormatFor does.
+func ParseFormat(s, path string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case "":
+		return FormatFor(path), nil
+	case FormatJSONL, "json", "ndjson":
+		return FormatJSONL, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unknown format %q (use jsonl or csv)", s)
+}
+
+// FormatFor guesses the format of an export file from its extension, defaulting to JSONL.
+func FormatFor(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSONL
+}
+
+// Export writes entries, in the given order, to w.
+func Export(w io.Writer, format Format, entries []Entry) error {
+	if format == FormatCSV {
+		return exportCSV(w, entries)
+	}
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(map[string]int{versionKey: FormatVersion}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadExport reads the entries of an export written by Export, in file order.
+func ReadExport(r io.Reader, format Format) ([]Entry, error) {
+	if format == FormatCSV {
+		return readCSV(r)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if line == 1 {
+			var header map[string]json.RawMessage
+			if err := json.Unmarshal(text, &header); err == nil {
+				if raw, ok := header[versionKey]; ok {
+					if err := checkVersion(string(raw)); err != nil {
+						return nil, err
+					}
+					continue
+				}
+			}
+		}
+		var entry Entry
+		if err := json.Unmarshal(text, &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func checkVersion(raw string) error {
+	version, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", versionKey, raw)
+	}
+	if version > FormatVersion {
+		return fmt.Errorf("export has format version %d, newer than the %d this aish reads; upgrade aish to import it", version, FormatVersion)
+	}
+	return nil
+}
+
+// csvColumn is one column of a CSV export.
+type csvColumn struct {
+	name string
+	get  func(Entry) string
+	set  func(*Entry, string) error
+}
+
+var csvColumns = []csvColumn{
+	{"id", func(e Entry) string { return strconv.FormatInt(e.ID, 10) }, func(e *Entry, v string) (err error) {
+		e.ID, err = strconv.ParseInt(v, 10, 64)
+		return err
+	}},
+	{"timestamp", func(e Entry) string { return e.Timestamp.Format(time.RFC3339Nano) }, func(e *Entry, v string) (err error) {
+		e.Timestamp, err = time.Parse(time.RFC3339Nano, v)
+		return err
+	}},
+	{"utc_offset", func(e Entry) string { return strconv.Itoa(e.UTCOffset) }, func(e *Entry, v string) (err error) {
+		e.UTCOffset, err = strconv.Atoi(v)
+		return err
+	}},
+	{"command", func(e Entry) string { return e.Command }, func(e *Entry, v string) error { e.Command = v; return nil }},
+	{"exit_code", func(e Entry) string { return strconv.Itoa(e.ExitCode) }, func(e *Entry, v string) (err error) {
+		e.ExitCode, err = strconv.Atoi(v)
+		return err
+	}},
+	{"error_type", func(e Entry) string { return string(e.ErrorType) }, func(e *Entry, v string) error {
+		e.ErrorType = classification.ErrorType(v)
+		return nil
+	}},
+	{"failed_stage", func(e Entry) string { return strconv.Itoa(e.FailedStage) }, func(e *Entry, v string) (err error) {
+		e.FailedStage, err = strconv.Atoi(v)
+		return err
+	}},
+	{"stdout", func(e Entry) string { return e.Stdout }, func(e *Entry, v string) error { e.Stdout = v; return nil }},
+	{"stderr", func(e Entry) string { return e.Stderr }, func(e *Entry, v string) error { e.Stderr = v; return nil }},
+	{"provider", func(e Entry) string { return e.Provider }, func(e *Entry, v string) error { e.Provider = v; return nil }},
+	{"model", func(e Entry) string { return e.Model }, func(e *Entry, v string) error { e.Model = v; return nil }},
+	{"latency_ms", func(e Entry) string { return strconv.FormatInt(e.LatencyMs, 10) }, func(e *Entry, v string) (err error) {
+		e.LatencyMs, err = strconv.ParseInt(v, 10, 64)
+		return err
+	}},
+	{"suggested_command", func(e Entry) string { return e.SuggestedCommand }, func(e *Entry, v string) error {
+		e.SuggestedCommand = v
+		return nil
+	}},
+	{"explanation", func(e Entry) string { return e.Explanation }, func(e *Entry, v string) error { e.Explanation = v; return nil }},
+	{"accepted", func(e Entry) string { return strconv.FormatBool(e.Accepted) }, func(e *Entry, v string) (err error) {
+		e.Accepted, err = strconv.ParseBool(v)
+		return err
+	}},
+}
+
+func exportCSV(w io.Writer, entries []Entry) error {
+	if _, err := fmt.Fprintf(w, "# %s=%d\n", versionKey, FormatVersion); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	row := make([]string, len(csvColumns))
+	for i, col := range csvColumns {
+		row[i] = col.name
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		for i, col := range csvColumns {
+			row[i] = col.get(entry)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readCSV maps columns by their header name, so reordered, missing or extra columns are fine.
+// Empty cells leave the field at its zero value.
+func readCSV(r io.Reader) ([]Entry, error) {
+	br := bufio.NewReader(r)
+	skipped := 0
+	if first, err := br.Peek(1); err == nil && first[0] == '#' {
+		skipped = 1
+		comment, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if key, value, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(comment, "#")), "="); ok && strings.TrimSpace(key) == versionKey {
+			if err := checkVersion(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cr := csv.NewReader(br)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]csvColumn, len(csvColumns))
+	for _, col := range csvColumns {
+		known[col.name] = col
+	}
+	columns := make([]*csvColumn, len(header))
+	for i, name := range header {
+		if col, ok := known[strings.TrimSpace(name)]; ok {
+			columns[i] = &col
+		}
+	}
+
+	var entries []Entry
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var entry Entry
+		for i, value := range row {
+			if i >= len(columns) || columns[i] == nil || value == "" {
+				continue
+			}
+			if err := columns[i].set(&entry, value); err != nil {
+				line, _ := cr.FieldPos(i)
+				return nil, fmt.Errorf("line %d: %s: %w", line+skipped, columns[i].name, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
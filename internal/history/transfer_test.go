@@ -0,0 +1,106 @@
+package history
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/classification"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{ID: 1, Timestamp: time.Date(2025, 3, 7, 13, 5, 9, 0, time.UTC), UTCOffset: 3600, Command: "gti status", ExitCode: 127,
+			ErrorType: classification.CommandNotFound, Stderr: "zsh: command not found: gti\n"},
+		{ID: 2, Timestamp: time.Date(2025, 3, 7, 14, 0, 0, 500, time.UTC), Command: `echo "a,b"`, Stdout: "line one\nline two",
+			ExitCode: 1, Provider: "openai", Model: "gpt-4o", LatencyMs: 820, SuggestedCommand: "echo 'a,b'", Explanation: "Quote it.", Accepted: true},
+	}
+	for _, format := range []Format{FormatJSONL, FormatCSV} {
+		var buf bytes.Buffer
+		if err := Export(&buf, format, entries); err != nil {
+			t.Fatalf("%s: export: %v", format, err)
+		}
+		got, err := ReadExport(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: read: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, entries) {
+			t.Errorf("%s: round trip changed entries:\n got %+v\nwant %+v", format, got, entries)
+		}
+	}
+}
+
+func TestReadExportVersions(t *testing.T) {
+	// Exports from before the version header are plain entry lines
+	got, err := ReadExport(strings.NewReader(`{"command":"ls /nope","exit_code":2}`+"\n"), FormatJSONL)
+	if err != nil || len(got) != 1 || got[0].Command != "ls /nope" {
+		t.Errorf("headerless export: %+v, %v", got, err)
+	}
+
+	// Unknown fields and columns are ignored
+	got, err = ReadExport(strings.NewReader("{\"aish_history_version\":1}\n{\"command\":\"make\",\"cwd\":\"/src\"}\n"), FormatJSONL)
+	if err != nil || len(got) != 1 || got[0].Command != "make" {
+		t.Errorf("unknown field: %+v, %v", got, err)
+	}
+	got, err = ReadExport(strings.NewReader("# aish_history_version=1\ncwd,command,exit_code\n/src,make,2\n"), FormatCSV)
+	if err != nil || len(got) != 1 || got[0].Command != "make" || got[0].ExitCode != 2 {
+		t.Errorf("unknown column: %+v, %v", got, err)
+	}
+
+	for format, input := range map[Format]string{
+		FormatJSONL: "{\"aish_history_version\":2}\n{\"command\":\"make\"}\n",
+		FormatCSV:   "# aish_history_version=2\ncommand\nmake\n",
+	} {
+		if _, err := ReadExport(strings.NewReader(input), format); err == nil || !strings.Contains(err.Error(), "newer") {
+			t.Errorf("%s: newer version not refused: %v", format, err)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, tc := range []struct{ flag, path, want string }{
+		{"", "backup.csv", "csv"},
+		{"", "backup.jsonl", "jsonl"},
+		{"", "-", "jsonl"},
+		{"CSV", "backup.jsonl", "csv"},
+		{"json", "", "jsonl"},
+	} {
+		if got, err := ParseFormat(tc.flag, tc.path); err != nil || string(got) != tc.want {
+			t.Errorf("ParseFormat(%q, %q) = %q, %v; want %q", tc.flag, tc.path, got, err, tc.want)
+		}
+	}
+	if _, err := ParseFormat("xml", ""); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestManagerImport(t *testing.T) {
+	mgr, err := openManager(filepath.Join(t.TempDir(), "history.jsonl"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	if err := mgr.Append(Entry{Timestamp: base, Command: "git pusj"}); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := mgr.Import([]Entry{
+		{ID: 99, Timestamp: base, Command: "git pusj"}, // already present
+		{ID: 99, Timestamp: base.Add(-time.Hour), Command: "gti status"},
+		{ID: 99, Timestamp: base.Add(time.Hour), Command: "make tset"},
+		{ID: 99, Timestamp: base.Add(-2 * time.Hour), Command: "ls /nope"}, // beyond the limit
+	})
+	if err != nil || added != 2 {
+		t.Fatalf("Import = %d, %v; want 2", added, err)
+	}
+	entries := mgr.Entries()
+	if len(entries) != 3 || entries[0].Command != "make tset" || entries[2].Command != "gti status" {
+		t.Fatalf("unexpected history %+v", entries)
+	}
+	if entries[0].ID == entries[2].ID {
+		t.Errorf("imported entries share ID %d", entries[0].ID)
+	}
+}
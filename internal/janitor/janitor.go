@@ -0,0 +1,128 @@
+// Package janitor removes stale state left behind by the shell hook and by interrupted runs.
+package janitor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxAge is how old a capture file must be before it counts as orphaned.
+	DefaultMaxAge = 24 * time.Hour
+	// DefaultInterval throttles the opportunistic run at startup.
+	DefaultInterval = time.Hour
+	// markerFileName records when the janitor last ran.
+	markerFileName = ".last_cleanup"
+)
+
+// captureFiles are written by the shell hook on every command and only read by the next
+// 'aish capture'. Old copies hold stale (possibly sensitive) command output.
+var captureFiles = []string{"last_stdout", "last_stderr", "last_command"}
+
+// Options controls a janitor run.
+type Options struct {
+	StateDir string        // Hook state directory, see config.HookStateDir
+	MaxAge   time.Duration // Files older than this are removed
+	DryRun   bool          // Report what would be removed without deleting
+	Now      time.Time
+}
+
+// Result lists the files removed (or, in a dry run, that would be removed).
+type Result struct {
+	Removed []string
+	Bytes   int64
+}
+
+// Run removes orphaned capture files, leftover "*.tmp" files from interrupted atomic writes and
+// daemon sockets nobody listens on any more, when they are older than opts.MaxAge.
+func Run(opts Options) (Result, error) {
+	var res Result
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	entries, err := os.ReadDir(opts.StateDir)
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		path := filepath.Join(opts.StateDir, name)
+		info, err := os.Lstat(path)
+		if err != nil || info.IsDir() || opts.Now.Sub(info.ModTime()) < opts.MaxAge {
+			continue
+		}
+
+		switch {
+		case isCaptureFile(name), strings.HasSuffix(name, ".tmp"):
+			if !info.Mode().IsRegular() {
+				continue
+			}
+		case info.Mode()&os.ModeSocket != 0:
+			if socketAlive(path) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := os.Remove(path); err != nil {
+				continue
+			}
+		}
+		res.Removed = append(res.Removed, path)
+		res.Bytes += info.Size()
+	}
+	return res, nil
+}
+
+// RunIfDue runs the janitor at most once per interval, tracked by a marker file in the state
+// directory, so it can be called on every start without measurable cost.
+func RunIfDue(opts Options, interval time.Duration) (Result, bool, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	marker := filepath.Join(opts.StateDir, markerFileName)
+	if info, err := os.Stat(marker); err == nil && opts.Now.Sub(info.ModTime()) < interval {
+		return Result{}, false, nil
+	}
+	if _, err := os.Stat(opts.StateDir); err != nil {
+		return Result{}, false, nil
+	}
+	res, err := Run(opts)
+	if err == nil {
+		_ = os.WriteFile(marker, nil, 0600)
+		_ = os.Chtimes(marker, opts.Now, opts.Now)
+	}
+	return res, true, err
+}
+
+func isCaptureFile(name string) bool {
+	for _, f := range captureFiles {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// socketAlive reports whether a process still accepts connections on a unix socket.
+func socketAlive(path string) bool {
+	conn, err := net.DialTimeout("unix", path, 200*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
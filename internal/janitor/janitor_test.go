@@ -0,0 +1,91 @@
+package janitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeAged(t *testing.T, dir, name string, age time.Duration, now time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mt := now.Add(-age)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunRemovesOnlyStaleStateFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	staleOut := writeAged(t, dir, "last_stdout", 48*time.Hour, now)
+	staleTmp := writeAged(t, dir, "provider_health.json.tmp", 48*time.Hour, now)
+	freshErr := writeAged(t, dir, "last_stderr", time.Minute, now)
+	config := writeAged(t, dir, "config.json", 48*time.Hour, now)
+
+	res, err := Run(Options{StateDir: dir, MaxAge: 24 * time.Hour, Now: now})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Removed) != 2 {
+		t.Fatalf("removed %v, want the stale capture and tmp files", res.Removed)
+	}
+	for _, p := range []string{staleOut, staleTmp} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", p)
+		}
+	}
+	for _, p := range []string{freshErr, config} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should have been kept: %v", p, err)
+		}
+	}
+}
+
+func TestRunDryRunKeepsFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	path := writeAged(t, dir, "last_command", 48*time.Hour, now)
+
+	res, err := Run(Options{StateDir: dir, MaxAge: time.Hour, DryRun: true, Now: now})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Removed) != 1 || res.Bytes != 4 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("dry run removed %s", path)
+	}
+}
+
+func TestRunIfDueThrottles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeAged(t, dir, "last_stdout", 48*time.Hour, now)
+
+	if _, ran, err := RunIfDue(Options{StateDir: dir, Now: now}, time.Hour); err != nil || !ran {
+		t.Fatalf("first run: ran=%v err=%v", ran, err)
+	}
+	writeAged(t, dir, "last_stderr", 48*time.Hour, now)
+	if _, ran, _ := RunIfDue(Options{StateDir: dir, Now: now.Add(10 * time.Minute)}, time.Hour); ran {
+		t.Fatal("second run within the interval should be skipped")
+	}
+	res, ran, _ := RunIfDue(Options{StateDir: dir, Now: now.Add(2 * time.Hour)}, time.Hour)
+	if !ran || len(res.Removed) != 1 {
+		t.Fatalf("run after the interval: ran=%v removed=%v", ran, res.Removed)
+	}
+}
+
+func TestRunMissingDir(t *testing.T) {
+	res, err := Run(Options{StateDir: filepath.Join(t.TempDir(), "missing")})
+	if err != nil || len(res.Removed) != 0 {
+		t.Fatalf("missing dir: %+v, %v", res, err)
+	}
+}
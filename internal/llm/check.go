@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// CheckConnection asks provider name for its models, which needs working credentials, and
+// records the outcome in the provider health state, along with the HTTP timing of the check:
+// a provider that answers again is no longer skipped by the shell hook.
+func CheckConnection(ctx context.Context, name string, provider Provider) ([]string, error) {
+	ctx, trace := TraceHTTP(ctx)
+	models, err := provider.VerifyConnection(ctx)
+	if path, perr := HealthStatePath(); perr == nil {
+		health := LoadHealthState(path)
+		if err != nil {
+			health.RecordFailure(name, err, time.Now())
+		} else {
+			health.RecordSuccess(name, provider, time.Now())
+		}
+		if timing, ok := trace.Timing(); ok {
+			health.RecordTiming(name, timing)
+		}
+		_ = health.Save()
+	}
+	return models, err
+}
+
+// ModelListed reports whether model is among the models a provider listed. Names are
+// compared without a "models/" or vendor prefix, so "anthropic/claude-x" matches "claude-x".
+func ModelListed(models []string, model string) bool {
+	base := func(name string) string {
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		return strings.ToLower(strings.TrimSpace(name))
+	}
+	want := base(model)
+	for _, m := range models {
+		if base(m) == want {
+			return true
+		}
+	}
+	return false
+}
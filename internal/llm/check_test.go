@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+type verifyingProvider struct {
+	Provider
+	models []string
+	err    error
+}
+
+func (p verifyingProvider) VerifyConnection(ctx context.Context) ([]string, error) {
+	return p.models, p.err
+}
+
+func TestCheckConnectionRecordsHealth(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(config.EnvAISHConfigDir, dir)
+	path := filepath.Join(dir, HealthFileName)
+
+	if _, err := CheckConnection(context.Background(), "openai", verifyingProvider{err: errors.New("401 unauthorized")}); err == nil {
+		t.Fatal("expected the provider's error")
+	}
+	if h := LoadHealthState(path).Providers["openai"]; h.Healthy || h.Failures != 1 {
+		t.Errorf("failure not recorded: %+v", h)
+	}
+
+	models, err := CheckConnection(context.Background(), "openai", verifyingProvider{models: []string{"gpt-4o"}})
+	if err != nil || len(models) != 1 {
+		t.Fatalf("got %v, %v", models, err)
+	}
+	if h := LoadHealthState(path).Providers["openai"]; !h.Healthy {
+		t.Errorf("success not recorded: %+v", h)
+	}
+}
+
+// httpVerifyingProvider checks its connection with a request to url.
+type httpVerifyingProvider struct {
+	Provider
+	url string
+}
+
+func (p httpVerifyingProvider) VerifyConnection(ctx context.Context) ([]string, error) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return []string{"gpt-4o"}, nil
+}
+
+func TestCheckConnectionRecordsTiming(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(config.EnvAISHConfigDir, dir)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ctx, trace := TraceHTTP(context.Background())
+	if _, err := CheckConnection(ctx, "openai", httpVerifyingProvider{url: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	h := LoadHealthState(filepath.Join(dir, HealthFileName)).Providers["openai"]
+	if !strings.Contains(h.Timing, "first byte") {
+		t.Errorf("timing not recorded: %+v", h)
+	}
+	if _, ok := trace.Timing(); !ok {
+		t.Error("the caller's trace did not see the check")
+	}
+}
+
+func TestModelListed(t *testing.T) {
+	models := []string{"models/gemini-2.5-flash", "claude-3-5-haiku-20241022"}
+	for model, want := range map[string]bool{
+		"gemini-2.5-flash":                    true,
+		"anthropic/claude-3-5-haiku-20241022": true,
+		"Claude-3-5-Haiku-20241022":           true,
+		"gpt-4o":                              false,
+	} {
+		if got := ModelListed(models, model); got != want {
+			t.Errorf("ModelListed(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,663 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+// anthropicVersion is the Messages API version the request and response types follow.
+const anthropicVersion = "2023-06-01"
+
+// maxAttempts bounds the requests made for one call when the API is overloaded or rate limited.
+const maxAttempts = 3
+
+// Anthropic Messages API structures
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type MessagesRequest struct {
+	Model       string      `json:"model"`
+	Messages    []Message   `json:"messages"`
+	MaxTokens   int         `json:"max_tokens"`
+	Temperature *float64    `json:"temperature,omitempty"`
+	Tools       []Tool      `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
+	Stream      bool        `json:"stream,omitempty"`
+}
+
+// Tool is a tool definition; aish forces the model to call one so that its answer is an
+// object matching InputSchema rather than free text.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// ContentBlock is a text or tool_use block of a response.
+type ContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type MessagesResponse struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Role       string         `json:"role"`
+	Content    []ContentBlock `json:"content"`
+	Model      string         `json:"model"`
+	StopReason string         `json:"stop_reason"`
+	Usage      MessageUsage   `json:"usage"`
+	Error      *APIError      `json:"error,omitempty"`
+}
+
+// MessageUsage is the token count of a message as the API reports it.
+type MessageUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// StreamEvent is one server-sent event of a streamed message. Only the fields aish reads are
+// declared. message_start carries the model and prompt tokens in Message, message_delta the
+// output tokens so far in Usage.
+type StreamEvent struct {
+	Type         string            `json:"type"`
+	Message      *MessagesResponse `json:"message,omitempty"`
+	Usage        *MessageUsage     `json:"usage,omitempty"`
+	ContentBlock *ContentBlock     `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Error *APIError `json:"error,omitempty"`
+}
+
+type APIError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) String() string {
+	if e.Type == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+type ModelsResponse struct {
+	Data []struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	} `json:"data"`
+	HasMore bool      `json:"has_more"`
+	LastID  string    `json:"last_id"`
+	Error   *APIError `json:"error,omitempty"`
+}
+
+// suggestionTool, commandTool and planTool describe the JSON the prompt templates ask for, so the API
+// enforces the shape instead of aish repairing it afterwards.
+var (
+	suggestionTool = Tool{
+		Name:        "suggest_fix",
+		Description: "Explain why the command failed and give the corrected shell command.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"explanation":{"type":"string"},"command":{"type":"string"}},"required":["explanation","command"]}`),
+	}
+	commandTool = Tool{
+		Name:        "shell_command",
+		Description: "Give the shell command that does what the user asked.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"command":{"type":"string"}},"required":["command"]}`),
+	}
+	planTool = Tool{
+		Name:        "shell_plan",
+		Description: "Give the shell commands that do what the user asked, in the order they run.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"steps":{"type":"array","items":{"type":"object","properties":{"command":{"type":"string"},"explanation":{"type":"string"}},"required":["command","explanation"]}}},"required":["steps"]}`),
+	}
+)
+
+// ClaudeProvider implements the llm.Provider interface for the Anthropic Messages API.
+type ClaudeProvider struct {
+	cfg    config.ProviderConfig
+	pm     *prompt.Manager
+	client *http.Client
+}
+
+// NewProvider creates a new ClaudeProvider.
+func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, error) {
+	return &ClaudeProvider{
+		cfg:    cfg,
+		pm:     pm,
+		client: llm.WithHeaders(llm.NewPooledClient(90*time.Second), cfg.ExtraHeaders),
+	}, nil
+}
+
+func init() {
+	llm.RegisterProvider("claude", NewProvider)
+}
+
+// GetSuggestion implements the llm.Provider interface.
+func (p *ClaudeProvider) GetSuggestion(ctx context.Context, capturedContext llm.CapturedContext, lang string) (*llm.Suggestion, error) {
+	return p.GetSuggestionStream(ctx, capturedContext, lang, nil)
+}
+
+// GetSuggestionStream implements the llm.Provider interface.
+func (p *ClaudeProvider) GetSuggestionStream(ctx context.Context, capturedContext llm.CapturedContext, lang string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
+	promptTemplate, err := p.pm.GetPrompt("get_suggestion", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := struct {
+		Command  string
+		Stdout   string
+		Stderr   string
+		ExitCode int
+	}{
+		Command:  capturedContext.PromptCommand(),
+		Stdout:   capturedContext.Stdout,
+		Stderr:   capturedContext.Stderr,
+		ExitCode: capturedContext.ExitCode,
+	}
+
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, tpl.String(), suggestionTool, onChunk)
+	if err != nil {
+		return nil, fmt.Errorf("Claude API request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	return parseSuggestionResponse(response)
+}
+
+// GetEnhancedSuggestion implements the llm.Provider interface with enhanced context.
+func (p *ClaudeProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx llm.EnhancedCapturedContext, lang string) (*llm.Suggestion, error) {
+	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", prompt.TemplateLanguage(lang))
+	if err != nil {
+		// Fall back to regular suggestion if enhanced template doesn't exist
+		return p.GetSuggestion(ctx, enhancedCtx.CapturedContext, lang)
+	}
+
+	funcMap := template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+	}
+
+	var tpl bytes.Buffer
+	t, err := template.New("prompt").Funcs(funcMap).Parse(promptTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse enhanced template: %w", err)
+	}
+	if err := t.Execute(&tpl, enhancedCtx); err != nil {
+		return nil, fmt.Errorf("failed to execute enhanced template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, tpl.String(), suggestionTool, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Claude API request failed for enhanced suggestion: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	return parseSuggestionResponse(response)
+}
+
+// GenerateCommand implements the llm.Provider interface.
+func (p *ClaudeProvider) GenerateCommand(ctx context.Context, promptText string, lang string) (string, error) {
+	return p.GenerateCommandStream(ctx, promptText, lang, nil)
+}
+
+// GenerateCommandStream implements the llm.Provider interface.
+func (p *ClaudeProvider) GenerateCommandStream(ctx context.Context, promptText string, lang string, onChunk llm.StreamFunc) (string, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_command", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return "", fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), commandTool, onChunk)
+	if err != nil {
+		return "", fmt.Errorf("Claude API request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if cmd, ok := llm.DecodeCommand(ctx, response); ok {
+		return cmd, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return cmd, nil
+	}
+	return "", llm.NoCommandError(response)
+}
+
+// GeneratePlan implements llm.Planner: the commands of a multi-step task, in order.
+func (p *ClaudeProvider) GeneratePlan(ctx context.Context, promptText string, lang string) (*llm.Plan, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_plan", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), planTool, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Claude API request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if plan, ok := llm.DecodePlan(ctx, response); ok {
+		return plan, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return llm.SingleStepPlan(cmd), nil
+	}
+	return nil, llm.NoCommandError(response)
+}
+
+// exchange sends message with tool forced, streaming the response to onChunk when it is set.
+// The response is the tool input as JSON, or the text when the model answered in text.
+func (p *ClaudeProvider) exchange(ctx context.Context, message string, tool Tool, onChunk llm.StreamFunc) (string, error) {
+	send := func(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+		return p.createMessage(ctx, p.buildMessagesRequest(message, tool), onChunk)
+	}
+	if onChunk == nil {
+		return llm.Exchange(ctx, message, func(ctx context.Context, message string) (string, error) {
+			return send(ctx, message, nil)
+		})
+	}
+	return llm.ExchangeStream(ctx, message, send, onChunk)
+}
+
+// buildMessagesRequest creates the request body for message, sizing max_tokens (which the API
+// requires) from the model's context window.
+func (p *ClaudeProvider) buildMessagesRequest(message string, tool Tool) MessagesRequest {
+	message, maxTokens := llm.FitPrompt(llm.ResolveModelLimits(p.cfg), message)
+	temperature := 0.1
+	return MessagesRequest{
+		Model:       p.model(),
+		Messages:    []Message{{Role: "user", Content: message}},
+		MaxTokens:   maxTokens,
+		Temperature: &temperature,
+		Tools:       []Tool{tool},
+		ToolChoice:  &ToolChoice{Type: "tool", Name: tool.Name},
+	}
+}
+
+// model returns the configured model without the "anthropic/" prefix earlier versions used.
+func (p *ClaudeProvider) model() string {
+	return strings.TrimPrefix(strings.TrimSpace(p.cfg.Model), "anthropic/")
+}
+
+// createMessage sends reqBody and returns the response text. With onChunk set the message is
+// streamed and each piece passed on as it arrives; a backend that answers with a single
+// response instead has its content passed on in one piece.
+func (p *ClaudeProvider) createMessage(ctx context.Context, reqBody MessagesRequest, onChunk llm.StreamFunc) (string, error) {
+	reqBody.Stream = onChunk != nil
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	resp, err := p.do(ctx, http.MethodPost, p.resolveURL("/messages"), jsonBody)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if onChunk != nil && resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return p.readMessageStream(ctx, resp.Body, onChunk)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	var msg MessagesResponse
+	_ = json.Unmarshal(body, &msg)
+	p.reportUsage(ctx, msg.Model, msg.Usage)
+	content, err := parseMessageBody(resp.StatusCode, body)
+	if err == nil && onChunk != nil {
+		onChunk(content)
+	}
+	return content, llm.WithRetryAfter(err, resp.Header)
+}
+
+// do sends a request to the API, retrying transport errors and the statuses Anthropic
+// documents as transient (429 rate limited, 529 overloaded, 5xx). The caller closes the
+// response body; the last response is returned whatever its status.
+func (p *ClaudeProvider) do(ctx context.Context, method, apiURL string, body []byte) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, apiURL, reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("x-api-key", p.cfg.APIKey)
+		req.Header.Set("anthropic-version", anthropicVersion)
+		req.Header.Set("content-type", "application/json")
+
+		resp, err := p.client.Do(req)
+		last := attempt == maxAttempts-1
+		switch {
+		case err != nil:
+			lastErr = err
+			if ctx.Err() != nil || last {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+		case !retryableStatus(resp.StatusCode) || last:
+			return resp, nil
+		default:
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		delay := time.Duration(500*(attempt+1)) * time.Millisecond
+		if resp != nil {
+			delay = retryDelay(resp.Header.Get("retry-after"), delay)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil, fmt.Errorf("request failed: %w", lastErr)
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529:
+		return true
+	}
+	return false
+}
+
+// retryDelay honours a retry-after header of a few seconds at most; longer waits are not
+// worth blocking the shell for, so the fallback is used and the last attempt fails.
+func retryDelay(header string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || seconds < 0 || seconds > 5 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// parseMessageBody extracts the content of a Messages API response body: the input of the
+// tool call when there is one, otherwise the text.
+func parseMessageBody(status int, body []byte) (string, error) {
+	var msg MessagesResponse
+	if err := json.Unmarshal(body, &msg); err != nil {
+		if status != http.StatusOK {
+			return "", fmt.Errorf("API request failed with status %d: %s", status, firstN(strings.TrimSpace(string(body)), 512))
+		}
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if msg.Error != nil {
+		return "", fmt.Errorf("API error (status %d): %s", status, msg.Error)
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("API request failed with status %d", status)
+	}
+	if msg.StopReason == "refusal" {
+		return "", llm.RefusalError("the model stopped with stop_reason refusal")
+	}
+
+	var text strings.Builder
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "tool_use":
+			return string(block.Input), nil
+		case "text":
+			text.WriteString(block.Text)
+		}
+	}
+	out := strings.TrimSpace(text.String())
+	if out == "" {
+		return "", errors.New("no response content returned")
+	}
+	return out, nil
+}
+
+// reportUsage passes the model that answered (the configured one when the response names
+// none) and the token counts of usage to llm.ReportUsage.
+func (p *ClaudeProvider) reportUsage(ctx context.Context, model string, usage MessageUsage) {
+	if model == "" {
+		model = p.model()
+	}
+	llm.ReportUsage(ctx, llm.Usage{Model: model, PromptTokens: usage.InputTokens, CompletionTokens: usage.OutputTokens})
+}
+
+// readMessageStream reads a streamed message, passing text and tool input deltas to onChunk,
+// and returns the whole content.
+func (p *ClaudeProvider) readMessageStream(ctx context.Context, r io.Reader, onChunk llm.StreamFunc) (string, error) {
+	var builder strings.Builder
+	err := llm.ReadSSE(r, func(payload string) error {
+		var event StreamEvent
+		if json.Unmarshal([]byte(payload), &event) != nil {
+			return nil
+		}
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				p.reportUsage(ctx, event.Message.Model, event.Message.Usage)
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				p.reportUsage(ctx, "", *event.Usage)
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("API error: %s", event.Error)
+			}
+			return errors.New("API error in response stream")
+		case "content_block_delta":
+			delta := event.Delta.Text
+			if event.Delta.Type == "input_json_delta" {
+				delta = event.Delta.PartialJSON
+			}
+			if delta != "" {
+				builder.WriteString(delta)
+				onChunk(delta)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read response stream: %w", err)
+	}
+	out := strings.TrimSpace(builder.String())
+	if out == "" {
+		return "", errors.New("no content in response stream")
+	}
+	return out, nil
+}
+
+// GetAvailableModels lists the models the API key can use.
+func (p *ClaudeProvider) GetAvailableModels(ctx context.Context) ([]string, error) {
+	if p.cfg.APIKey == "" {
+		return nil, errors.New("API key is missing for Claude")
+	}
+
+	var models []string
+	query := url.Values{"limit": {"1000"}}
+	// The list is paged; a handful of pages covers every model Anthropic has published
+	for page := 0; page < 5; page++ {
+		resp, err := p.do(ctx, http.MethodGet, p.resolveURL("/models")+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		var list ModelsResponse
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, firstN(strings.TrimSpace(string(body)), 200))
+		}
+		if list.Error != nil {
+			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, list.Error)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+		}
+		for _, m := range list.Data {
+			models = append(models, m.ID)
+		}
+		if !list.HasMore || list.LastID == "" {
+			break
+		}
+		query.Set("after_id", list.LastID)
+	}
+	return models, nil
+}
+
+// Warmup opens a connection to the API host ahead of the first request (llm.Warmer).
+func (p *ClaudeProvider) Warmup(ctx context.Context) error {
+	return llm.WarmupEndpoint(ctx, p.client, p.cfg.APIEndpoint)
+}
+
+// VerifyConnection implements the llm.Provider interface.
+func (p *ClaudeProvider) VerifyConnection(ctx context.Context) ([]string, error) {
+	models, err := p.GetAvailableModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Claude connection verification failed: %w", err)
+	}
+	if len(models) == 0 {
+		// Gateways in front of the API may not list models
+		models = []string{config.DefaultClaudeModel}
+	}
+	return models, nil
+}
+
+// resolveURL joins the configured endpoint with subpath, adding the /v1 prefix once whether
+// or not the endpoint already ends with it.
+func (p *ClaudeProvider) resolveURL(subpath string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(p.cfg.APIEndpoint, "/"), "/v1")
+	if base == "" {
+		base = strings.TrimSuffix(config.ClaudeAPIEndpoint, "/v1")
+	}
+	return base + "/v1" + subpath
+}
+
+// firstN returns at most n bytes of s (safe for logging)
+func firstN(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func parseSuggestionResponse(response string) (*llm.Suggestion, error) {
+	response = strings.TrimSpace(response)
+
+	var explanation, correctedCommand string
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(strings.ToLower(line), "explanation") {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) > 1 {
+				explanation = strings.TrimSpace(parts[1])
+			}
+		}
+		if strings.Contains(strings.ToLower(line), "command") {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) > 1 {
+				correctedCommand = strings.Trim(strings.TrimSpace(parts[1]), "`")
+			}
+		}
+	}
+
+	if explanation == "" {
+		explanation = "Please check command syntax and parameters."
+	}
+	if correctedCommand == "" {
+		correctedCommand = "echo 'Unable to auto-correct command'"
+	}
+
+	return &llm.Suggestion{
+		Explanation:      explanation,
+		CorrectedCommand: correctedCommand,
+	}, nil
+}
+
+func extractPlausibleCommand(text string) string {
+	s := strings.TrimSpace(text)
+	if s == "" {
+		return ""
+	}
+
+	// Check for fenced code blocks
+	if idx := strings.Index(s, "```"); idx != -1 {
+		end := strings.Index(s[idx+3:], "```")
+		if end != -1 {
+			block := strings.TrimSpace(s[idx+3 : idx+3+end])
+			for _, line := range strings.Split(block, "\n") {
+				line = strings.TrimSpace(line)
+				if line != "" && !strings.HasPrefix(line, "#") {
+					return line
+				}
+			}
+		}
+	}
+
+	// Return first non-empty line
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
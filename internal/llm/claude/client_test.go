@@ -0,0 +1,159 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *ClaudeProvider {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &ClaudeProvider{
+		cfg:    config.ProviderConfig{APIEndpoint: srv.URL + "/v1", APIKey: "sk-ant-test", Model: "anthropic/claude-3-5-haiku-20241022"},
+		client: srv.Client(),
+	}
+}
+
+func TestCreateMessageForcesTool(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "sk-ant-test" || r.Header.Get("anthropic-version") != anthropicVersion {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var req MessagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "claude-3-5-haiku-20241022" || req.MaxTokens <= 0 || req.ToolChoice == nil || req.ToolChoice.Name != suggestionTool.Name {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		fmt.Fprint(w, `{"type":"message","content":[{"type":"tool_use","name":"suggest_fix","input":{"explanation":"typo","command":"ls"}}]}`)
+	})
+
+	got, err := p.createMessage(context.Background(), p.buildMessagesRequest("fix it", suggestionTool), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != `{"explanation":"typo","command":"ls"}` {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCreateMessageStream(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","name":"shell_command","input":{}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"command\": "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"pwd\"}"}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", e)
+		}
+	})
+
+	var pieces []string
+	got, err := p.createMessage(context.Background(), p.buildMessagesRequest("where am i", commandTool), func(d string) { pieces = append(pieces, d) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != `{"command": "pwd"}` || len(pieces) != 2 {
+		t.Errorf("got %q in %d pieces", got, len(pieces))
+	}
+}
+
+func TestCreateMessageStreamReportsUsage(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"model":"claude-3-5-haiku-20241022","usage":{"input_tokens":310,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","name":"shell_command","input":{}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"command\": \"pwd\"}"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":14}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", e)
+		}
+	})
+
+	var usage llm.Usage
+	ctx := llm.WithUsageReporter(context.Background(), func(u llm.Usage) { usage = u })
+	_, err := llm.ExchangeStream(ctx, "where am i", func(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+		return p.createMessage(ctx, p.buildMessagesRequest(message, commandTool), onChunk)
+	}, func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Model != "claude-3-5-haiku-20241022" || usage.PromptTokens != 310 || usage.CompletionTokens != 14 {
+		t.Errorf("reported %+v", usage)
+	}
+}
+
+func TestCreateMessageRetriesOverloaded(t *testing.T) {
+	calls := 0
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("retry-after", "0")
+			w.WriteHeader(529)
+			fmt.Fprint(w, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"type":"message","content":[{"type":"text","text":"ls -la"}]}`)
+	})
+
+	got, err := p.createMessage(context.Background(), p.buildMessagesRequest("list", commandTool), nil)
+	if err != nil || got != "ls -la" || calls != 2 {
+		t.Errorf("got %q, %v after %d calls", got, err, calls)
+	}
+}
+
+func TestCreateMessageAPIError(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	})
+
+	_, err := p.createMessage(context.Background(), p.buildMessagesRequest("list", commandTool), nil)
+	if err == nil || err.Error() != "API error (status 401): authentication_error: invalid x-api-key" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCreateMessageRefusal(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"message","role":"assistant","content":[],"stop_reason":"refusal"}`)
+	})
+
+	_, err := p.createMessage(context.Background(), p.buildMessagesRequest("scan my network", commandTool), nil)
+	var llmErr *llm.LLMError
+	if !errors.As(err, &llmErr) || llmErr.Type != llm.ContentFilterError {
+		t.Errorf("expected a content filter error, got %v", err)
+	}
+}
+
+func TestGetAvailableModelsPages(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after_id") == "" {
+			fmt.Fprint(w, `{"data":[{"id":"claude-a"}],"has_more":true,"last_id":"claude-a"}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"claude-b"}],"has_more":false,"last_id":"claude-b"}`)
+	})
+
+	models, err := p.VerifyConnection(context.Background())
+	if err != nil || len(models) != 2 || models[1] != "claude-b" {
+		t.Errorf("got %v, %v", models, err)
+	}
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/security"
+)
+
+// Limits on what of a conversation is sent along with each request, so a long session does not
+// grow the prompt without bound.
+const (
+	conversationTurns       = 6    // Most recent turns included
+	conversationOutputBytes = 1500 // Tail of each command's output included
+)
+
+// Turn is one exchange of an 'aish chat' session or a refinement loop: a request, the command
+// generated for it and, once the user ran it, how that went. The first turn of a refinement of
+// a failed command has no request.
+type Turn struct {
+	Request  string
+	Command  string
+	Ran      bool
+	ExitCode int
+	Output   string // Tail of what the command printed
+}
+
+// Conversation is the state of an 'aish chat' session or of refining a suggestion. Providers
+// answer one request at a time, so Prompt and FollowUp fold the earlier turns into the next
+// request; a follow-up such as "same but recursive" then refines the previous command.
+type Conversation struct {
+	Turns []Turn
+}
+
+type conversationKey struct{}
+
+// Context returns ctx marked as carrying a turn of c once c has turns, so a response cache
+// answers the request only from the same request (see InConversation).
+func (c *Conversation) Context(ctx context.Context) context.Context {
+	if len(c.Turns) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, conversationKey{}, true)
+}
+
+// InConversation reports whether ctx carries a follow-up in a conversation. Follow-ups that
+// differ by a word, "include hidden files" and "exclude hidden files", ask for different
+// commands, so they must not be matched by similarity.
+func InConversation(ctx context.Context) bool {
+	v, _ := ctx.Value(conversationKey{}).(bool)
+	return v
+}
+
+// Add records that command was generated for request.
+func (c *Conversation) Add(request, command string) {
+	c.Turns = append(c.Turns, Turn{Request: strings.TrimSpace(request), Command: strings.TrimSpace(command)})
+}
+
+// Last returns the most recent turn, or nil before the first one.
+func (c *Conversation) Last() *Turn {
+	if len(c.Turns) == 0 {
+		return nil
+	}
+	return &c.Turns[len(c.Turns)-1]
+}
+
+// RecordRun records that command ran with exitCode and printed output. A command the user
+// edited before running replaces the generated one of the last turn.
+func (c *Conversation) RecordRun(command string, exitCode int, output string) {
+	t := c.Last()
+	if t == nil {
+		c.Turns = append(c.Turns, Turn{})
+		t = c.Last()
+	}
+	t.Command = strings.TrimSpace(command)
+	t.Ran = true
+	t.ExitCode = exitCode
+	if len(output) > conversationOutputBytes {
+		output = strings.ToValidUTF8(output[len(output)-conversationOutputBytes:], "")
+	}
+	t.Output = output
+}
+
+// Reset forgets every turn.
+func (c *Conversation) Reset() {
+	c.Turns = nil
+}
+
+// Prompt returns the text to send for request: request itself at the start of a session, and
+// otherwise request after a summary of the recent turns. Command output is masked with
+// security.SanitizeText first.
+func (c *Conversation) Prompt(request string) string {
+	request = strings.TrimSpace(request)
+	turns := c.Turns
+	if len(turns) == 0 {
+		return request
+	}
+	if len(turns) > conversationTurns {
+		turns = turns[len(turns)-conversationTurns:]
+	}
+
+	var b strings.Builder
+	b.WriteString("Earlier in this session (oldest first):\n")
+	for i, t := range turns {
+		if t.Request != "" {
+			fmt.Fprintf(&b, "%d. Request: %s\n", i+1, t.Request)
+		} else {
+			fmt.Fprintf(&b, "%d. Request: (typed by the user)\n", i+1)
+		}
+		fmt.Fprintf(&b, "   Command: %s\n", t.Command)
+		if !t.Ran {
+			b.WriteString("   Not run.\n")
+			continue
+		}
+		fmt.Fprintf(&b, "   Ran with exit status %d", t.ExitCode)
+		if out := strings.TrimSpace(security.SanitizeText(t.Output)); out != "" {
+			b.WriteString("; output (end):\n")
+			for _, line := range strings.Split(out, "\n") {
+				b.WriteString("     " + line + "\n")
+			}
+		} else {
+			b.WriteString(".\n")
+		}
+	}
+	b.WriteString("\nCurrent request (it may refine or build on the commands above): ")
+	b.WriteString(request)
+	return b.String()
+}
+
+// FollowUp returns captured with the recent turns and request added to its notes, for refining
+// the suggestion for a failed command: the failure stays what the prompt is about, and request
+// says what to change about the last suggestion.
+func (c *Conversation) FollowUp(captured CapturedContext, request string) CapturedContext {
+	turns := c.Turns
+	if len(turns) > conversationTurns {
+		turns = turns[len(turns)-conversationTurns:]
+	}
+	notes := append([]string(nil), captured.Notes...)
+	for _, t := range turns {
+		if t.Request == "" {
+			notes = append(notes, "Suggested earlier: "+t.Command)
+		} else {
+			notes = append(notes, fmt.Sprintf("Then the user asked %q and was suggested: %s", t.Request, t.Command))
+		}
+	}
+	notes = append(notes, "The user's follow-up on the last suggestion: "+strings.TrimSpace(request))
+	captured.Notes = notes
+	return captured
+}
@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConversationPrompt(t *testing.T) {
+	var c Conversation
+	if got := c.Prompt(" find large files "); got != "find large files" {
+		t.Errorf("first request = %q, want it unchanged", got)
+	}
+
+	c.Add("find large files", "find . -size +100M")
+	c.RecordRun("find . -size +100M -type f", 0, "./video.mp4\n")
+	c.Add("only logs", "find . -name '*.log' -size +100M")
+	got := c.Prompt("only the last day")
+	for _, want := range []string{
+		"1. Request: find large files",
+		"Command: find . -size +100M -type f", // The command as the user ran it
+		"Ran with exit status 0; output (end):\n     ./video.mp4",
+		"2. Request: only logs",
+		"Not run.",
+		"Current request (it may refine or build on the commands above): only the last day",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, got)
+		}
+	}
+
+	c.Reset()
+	if got := c.Prompt("list files"); got != "list files" {
+		t.Errorf("after Reset = %q", got)
+	}
+}
+
+func TestConversationPromptBounds(t *testing.T) {
+	var c Conversation
+	for i := 0; i < conversationTurns+2; i++ {
+		c.Add("request "+string(rune('a'+i)), "true")
+	}
+	c.RecordRun("true", 1, strings.Repeat("x", 2*conversationOutputBytes)+"END")
+	got := c.Prompt("next")
+	if strings.Contains(got, "request a\n") || !strings.Contains(got, "request h\n") {
+		t.Errorf("prompt should keep only the last %d turns:\n%s", conversationTurns, got)
+	}
+	if n := len(c.Last().Output); n != conversationOutputBytes || !strings.HasSuffix(c.Last().Output, "END") {
+		t.Errorf("kept %d bytes of output, want the last %d", n, conversationOutputBytes)
+	}
+}
+
+func TestConversationFollowUp(t *testing.T) {
+	var c Conversation
+	captured := CapturedContext{Command: "rm build", Stderr: "rm: build: is a directory", ExitCode: 1, Notes: []string{"a local fact"}}
+	c.Add("", "rm -r build")
+	c.Add("keep the directory", "rm -r build/*")
+	got := c.FollowUp(captured, " same but verbose ")
+	want := []string{
+		"a local fact",
+		"Suggested earlier: rm -r build",
+		`Then the user asked "keep the directory" and was suggested: rm -r build/*`,
+		"The user's follow-up on the last suggestion: same but verbose",
+	}
+	if got.Command != captured.Command || got.Stderr != captured.Stderr || strings.Join(got.Notes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("FollowUp = %+v", got)
+	}
+	if len(captured.Notes) != 1 {
+		t.Errorf("FollowUp changed the notes of captured: %q", captured.Notes)
+	}
+}
@@ -0,0 +1,220 @@
+package llm
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCache remembers resolved host addresses for a short time so repeated provider calls
+// skip the resolver. Failed lookups are never cached.
+type dnsCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]dnsEntry
+	lookup  func(ctx context.Context, host string) ([]string, error)
+	nowFunc func() time.Time
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:     ttl,
+		entries: make(map[string]dnsEntry),
+		lookup:  net.DefaultResolver.LookupHost,
+		nowFunc: time.Now,
+	}
+}
+
+// resolve returns the addresses of host, from the cache when still fresh.
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	now := c.nowFunc()
+	c.mu.Lock()
+	if e, ok := c.entries[host]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		return e.addrs, nil
+	}
+	c.mu.Unlock()
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// forget drops host so the next dial resolves it again, e.g. after every cached address failed.
+func (c *dnsCache) forget(host string) {
+	c.mu.Lock()
+	delete(c.entries, host)
+	c.mu.Unlock()
+}
+
+// dialContext wraps dialer so host names are resolved through the cache. Literal IPs and a
+// disabled cache (ttl <= 0) dial directly. The cached addresses are dialed the way net.Dialer
+// dials a resolved name: addresses of the first family in turn, each with a share of the
+// deadline, and those of the other family racing them after FallbackDelay (Happy Eyeballs), so
+// one dead IPv6 address does not stall every request.
+func (c *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || c == nil || c.ttl <= 0 || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := c.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		if dialer.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, dialer.Timeout)
+			defer cancel()
+		}
+		conn, err := dialParallel(ctx, dialer, network, addrs, port)
+		if err != nil {
+			c.forget(host)
+		}
+		return conn, err
+	}
+}
+
+// minDialShare is the least time dialSerial gives one address, as net.Dialer does.
+const minDialShare = 2 * time.Second
+
+// defaultFallbackDelay is how long the first address family gets before the other one races
+// it when dialer.FallbackDelay is zero, as in net.Dialer.
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// dialParallel dials the addresses of the first address family in addrs and, unless the
+// first connects or fails within the fallback delay, those of the other family at the same
+// time. The first connection wins; the other attempt is cancelled.
+func dialParallel(ctx context.Context, dialer *net.Dialer, network string, addrs []string, port string) (net.Conn, error) {
+	primaries, fallbacks := partitionAddrs(addrs)
+	if len(fallbacks) == 0 || dialer.FallbackDelay < 0 {
+		return dialSerial(ctx, dialer, network, addrs, port)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	// Buffered for both attempts, so the loser never blocks once the winner returned
+	results := make(chan result, 2)
+	start := func(addrs []string, primary bool) {
+		go func() {
+			conn, err := dialSerial(ctx, dialer, network, addrs, port)
+			results <- result{conn, err, primary}
+		}()
+	}
+
+	start(primaries, true)
+	delay := dialer.FallbackDelay
+	if delay == 0 {
+		delay = defaultFallbackDelay
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending, fallbackStarted := 1, false
+	var primaryErr, fallbackErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				start(fallbacks, false)
+				pending, fallbackStarted = pending+1, true
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if pending > 0 {
+					go func() {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+			if res.primary {
+				primaryErr = res.err
+			} else {
+				fallbackErr = res.err
+			}
+			if !fallbackStarted {
+				start(fallbacks, false)
+				pending, fallbackStarted = pending+1, true
+			}
+			if pending == 0 {
+				if primaryErr != nil {
+					return nil, primaryErr
+				}
+				return nil, fallbackErr
+			}
+		}
+	}
+}
+
+// dialSerial dials addrs one after another until one connects. Each gets an equal share of
+// the time left before ctx's deadline, but at least minDialShare.
+func dialSerial(ctx context.Context, dialer *net.Dialer, network string, addrs []string, port string) (net.Conn, error) {
+	var lastErr error
+	for i, ip := range addrs {
+		conn, err := dialShare(ctx, dialer, network, net.JoinHostPort(ip, port), len(addrs)-i)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// dialShare dials addr with 1/left of the time remaining before ctx's deadline.
+func dialShare(ctx context.Context, dialer *net.Dialer, network, addr string, left int) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		share := remaining / time.Duration(left)
+		if share < minDialShare {
+			share = min(minDialShare, remaining)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, share)
+		defer cancel()
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// partitionAddrs splits addrs into those of the first address's family and the rest, keeping
+// their order.
+func partitionAddrs(addrs []string) (primaries, fallbacks []string) {
+	isV4 := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		return ip != nil && ip.To4() != nil
+	}
+	firstV4 := isV4(addrs[0])
+	for _, addr := range addrs {
+		if isV4(addr) == firstV4 {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+	return primaries, fallbacks
+}
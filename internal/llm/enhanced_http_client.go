@@ -88,7 +88,7 @@ func NewEnhancedHTTPClient(config *EnhancedHTTPConfig) *EnhancedHTTPClient {
 		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
 		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
 		ForceAttemptHTTP2:     true,
-		Proxy:                 http.ProxyFromEnvironment,
+		Proxy:                 Proxy,
 		TLSClientConfig:       config.TLSConfig,
 		DialContext: (&net.Dialer{
 			Timeout:   10 * time.Second,
@@ -4,7 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
 )
 
 // LLMError represents different types of LLM-related errors
@@ -21,14 +25,18 @@ const (
 	// Network-related errors
 	NetworkError ErrorType = "network_error"
 	TimeoutError ErrorType = "timeout_error"
+	ServerError  ErrorType = "server_error" // The provider failed or is overloaded (5xx)
 
 	// Authentication and authorization errors
 	AuthError          ErrorType = "auth_error"
-	QuotaExceededError ErrorType = "quota_exceeded_error"
+	QuotaExceededError ErrorType = "quota_exceeded_error" // Billing quota or credits used up
+	RateLimitError     ErrorType = "rate_limit_error"     // Too many requests for now; clears by itself
 
 	// Request-related errors
 	InvalidRequestError ErrorType = "invalid_request_error"
 	ModelNotFoundError  ErrorType = "model_not_found_error"
+	ContextTooLongError ErrorType = "context_too_long_error" // The prompt exceeds the model's context window
+	ContentFilterError  ErrorType = "content_filter_error"   // The provider's safety filter blocked the request or answer
 
 	// Response-related errors
 	InvalidResponseError ErrorType = "invalid_response_error"
@@ -58,13 +66,68 @@ func (e *LLMError) Unwrap() error {
 // IsRetryable returns true if the error is potentially recoverable with retry
 func (e *LLMError) IsRetryable() bool {
 	switch e.Type {
-	case NetworkError, TimeoutError, QuotaExceededError:
+	case NetworkError, TimeoutError, ServerError, QuotaExceededError, RateLimitError:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRequestSpecific reports whether the failure lies with this particular request rather than
+// with the provider: the same provider will answer other requests, so it should not be treated
+// as unavailable, while another provider may well handle this one.
+func (e *LLMError) IsRequestSpecific() bool {
+	switch e.Type {
+	case InvalidRequestError, ContextTooLongError, ContentFilterError, InvalidResponseError, EmptyResponseError:
 		return true
 	default:
 		return false
 	}
 }
 
+// Code maps the LLM error type onto the stable application error code.
+func (e *LLMError) Code() aerrors.ErrorCode {
+	switch e.Type {
+	case NetworkError:
+		return aerrors.ErrNetwork
+	case TimeoutError:
+		return aerrors.ErrTimeout
+	case AuthError:
+		return aerrors.ErrProviderAuth
+	case QuotaExceededError, RateLimitError:
+		return aerrors.ErrProviderQuota
+	case InvalidResponseError, EmptyResponseError, ContentFilterError:
+		return aerrors.ErrProviderResponse
+	case ConfigError:
+		return aerrors.ErrConfigValidation
+	default:
+		return aerrors.ErrProviderRequest
+	}
+}
+
+// ErrorCodeOf returns the application error code for a provider error.
+// Errors that are not LLMErrors are classified by ClassifyProviderError first.
+func ErrorCodeOf(providerName string, err error) aerrors.ErrorCode {
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr.Code()
+	}
+	var aishErr *aerrors.AishError
+	if errors.As(err, &aishErr) {
+		return aishErr.Code
+	}
+	return ClassifyProviderError(providerName, err).Code()
+}
+
+// Classify returns the LLMError in err's chain, or classifies err with ClassifyProviderError.
+func Classify(providerName string, err error) *LLMError {
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr
+	}
+	return ClassifyProviderError(providerName, err)
+}
+
 // NewLLMError creates a new LLM error
 func NewLLMError(errorType ErrorType, message string, cause error) *LLMError {
 	return &LLMError{
@@ -91,12 +154,15 @@ func ClassifyHTTPError(resp *http.Response, err error) *LLMError {
 	case http.StatusNotFound:
 		return NewLLMError(ModelNotFoundError, "Model or endpoint not found", nil)
 	case http.StatusTooManyRequests:
-		return NewLLMError(QuotaExceededError, "Rate limit or quota exceeded", nil)
+		return NewLLMError(RateLimitError, "Rate limit exceeded", nil)
+	case http.StatusRequestEntityTooLarge:
+		return NewLLMError(ContextTooLongError, "Request too large for the model", nil)
 	case http.StatusBadRequest:
 		return NewLLMError(InvalidRequestError, "Bad request - check request parameters", nil)
-	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable:
-		return NewLLMError(NetworkError, fmt.Sprintf("Server error (status: %d)", resp.StatusCode), nil)
 	default:
+		if resp.StatusCode >= 500 {
+			return NewLLMError(ServerError, fmt.Sprintf("Server error (status: %d)", resp.StatusCode), nil)
+		}
 		if resp.StatusCode >= 400 {
 			return NewLLMError(UnknownError, fmt.Sprintf("HTTP error (status: %d)", resp.StatusCode), nil)
 		}
@@ -105,16 +171,72 @@ func ClassifyHTTPError(resp *http.Response, err error) *LLMError {
 	return nil
 }
 
-// ClassifyProviderError classifies provider-specific errors
+// statusPattern finds the HTTP status the provider clients put in their error messages, e.g.
+// "API request failed with status 503" or "HTTP 429 error".
+var statusPattern = regexp.MustCompile(`\b(?:status|http|error)\s*(?:code)?\s*:?\s*([1-5]\d\d)\b`)
+
+// statusOf returns the HTTP status mentioned in a lowercased error message.
+func statusOf(errMsg string) (int, bool) {
+	m := statusPattern.FindStringSubmatch(errMsg)
+	if m == nil {
+		return 0, false
+	}
+	status, err := strconv.Atoi(m[1])
+	return status, err == nil
+}
+
+// Phrases the providers use for prompts beyond the context window and for blocked content.
+var (
+	contextTooLongPhrases = []string{
+		"context_length_exceeded", "context length", "context window", "maximum context",
+		"prompt is too long", "input is too long", "too many tokens", "exceeds the maximum number of tokens",
+	}
+	contentFilterPhrases = []string{
+		"content_filter", "content filter", "content management policy", "responsible ai",
+		"blocked due to safety", "blockreason", "finishreason: safety", "prohibited_content",
+	}
+	rateLimitPhrases = []string{"rate limit", "rate_limit", "too many requests", "resource_exhausted"}
+	quotaPhrases     = []string{"quota", "billing", "credit balance"}
+)
+
+func containsAny(s string, phrases []string) bool {
+	for _, p := range phrases {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyProviderError classifies provider-specific errors. Phrases naming the failure win over
+// the HTTP status in the message, which in turn wins over looser phrases and the provider's
+// own error codes.
 func ClassifyProviderError(providerName string, err error) *LLMError {
 	errMsg := strings.ToLower(err.Error())
 
 	// Common error patterns across providers
 	switch {
+	case containsAny(errMsg, contextTooLongPhrases):
+		return NewLLMError(ContextTooLongError, "Prompt exceeds the model's context window", err)
+	case containsAny(errMsg, contentFilterPhrases):
+		return NewLLMError(ContentFilterError, "Blocked by the provider's content filter", err)
 	case strings.Contains(errMsg, "api key"):
 		return NewLLMError(AuthError, "Invalid or missing API key", err)
-	case strings.Contains(errMsg, "quota") || strings.Contains(errMsg, "rate limit"):
-		return NewLLMError(QuotaExceededError, "API quota or rate limit exceeded", err)
+	case containsAny(errMsg, rateLimitPhrases):
+		return NewLLMError(RateLimitError, "API rate limit exceeded", err)
+	case containsAny(errMsg, quotaPhrases):
+		return NewLLMError(QuotaExceededError, "API quota exceeded", err)
+	}
+
+	// 400 and 404 say little on their own, so they only decide when nothing more specific does
+	status, hasStatus := statusOf(errMsg)
+	if hasStatus && status != http.StatusBadRequest && status != http.StatusNotFound {
+		if c := ClassifyHTTPError(&http.Response{StatusCode: status}, nil); c != nil {
+			return NewLLMError(c.Type, c.Message, err)
+		}
+	}
+
+	switch {
 	case strings.Contains(errMsg, "timeout"):
 		return NewLLMError(TimeoutError, "Request timeout", err)
 	case strings.Contains(errMsg, "model"):
@@ -128,16 +250,25 @@ func ClassifyProviderError(providerName string, err error) *LLMError {
 	}
 
 	// Provider-specific error patterns
+	var classified *LLMError
 	switch providerName {
 	case "openai":
-		return classifyOpenAIError(err)
+		classified = classifyOpenAIError(err)
 	case "gemini":
-		return classifyGeminiError(err)
+		classified = classifyGeminiError(err)
 	case "gemini-cli":
-		return classifyGeminiCLIError(err)
+		classified = classifyGeminiCLIError(err)
+	case "claude":
+		classified = classifyClaudeError(err)
+	default:
+		classified = NewLLMError(UnknownError, "Unknown error occurred", err)
 	}
-
-	return NewLLMError(UnknownError, "Unknown error occurred", err)
+	if hasStatus && (classified.Type == ProviderError || classified.Type == UnknownError) {
+		if c := ClassifyHTTPError(&http.Response{StatusCode: status}, nil); c != nil {
+			return NewLLMError(c.Type, c.Message, err)
+		}
+	}
+	return classified
 }
 
 // classifyOpenAIError handles OpenAI-specific error classification
@@ -151,6 +282,10 @@ func classifyOpenAIError(err error) *LLMError {
 		return NewLLMError(AuthError, "Invalid OpenAI API key", err)
 	case strings.Contains(errMsg, "model_not_found"):
 		return NewLLMError(ModelNotFoundError, "OpenAI model not found", err)
+	case strings.Contains(errMsg, "deploymentnotfound"):
+		return NewLLMError(ModelNotFoundError, "Azure OpenAI deployment not found", err)
+	case strings.Contains(errMsg, "access denied due to invalid subscription key"):
+		return NewLLMError(AuthError, "Invalid Azure OpenAI API key", err)
 	default:
 		return NewLLMError(ProviderError, "OpenAI provider error", err)
 	}
@@ -188,6 +323,27 @@ func classifyGeminiCLIError(err error) *LLMError {
 	}
 }
 
+// classifyClaudeError handles Anthropic-specific error classification by the error type the
+// Messages API reports
+func classifyClaudeError(err error) *LLMError {
+	errMsg := strings.ToLower(err.Error())
+
+	switch {
+	case strings.Contains(errMsg, "authentication_error") || strings.Contains(errMsg, "permission_error"):
+		return NewLLMError(AuthError, "Invalid Anthropic API key", err)
+	case strings.Contains(errMsg, "rate_limit_error"):
+		return NewLLMError(RateLimitError, "Anthropic rate limit exceeded", err)
+	case strings.Contains(errMsg, "not_found_error"):
+		return NewLLMError(ModelNotFoundError, "Claude model not found", err)
+	case strings.Contains(errMsg, "overloaded_error"):
+		return NewLLMError(ServerError, "Anthropic API is overloaded", err)
+	case strings.Contains(errMsg, "api_error"):
+		return NewLLMError(ServerError, "Anthropic API internal error", err)
+	default:
+		return NewLLMError(ProviderError, "Claude provider error", err)
+	}
+}
+
 // WrapError wraps an existing error with LLM error context
 func WrapError(errorType ErrorType, message string, cause error) *LLMError {
 	// If the cause is already an LLMError, don't double-wrap
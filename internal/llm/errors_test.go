@@ -51,6 +51,10 @@ func TestLLMError_IsRetryable(t *testing.T) {
 		{"Network error is retryable", NetworkError, true},
 		{"Timeout error is retryable", TimeoutError, true},
 		{"Quota exceeded is retryable", QuotaExceededError, true},
+		{"Rate limit is retryable", RateLimitError, true},
+		{"Server error is retryable", ServerError, true},
+		{"Context too long is not retryable", ContextTooLongError, false},
+		{"Content filter is not retryable", ContentFilterError, false},
 		{"Auth error is not retryable", AuthError, false},
 		{"Invalid request is not retryable", InvalidRequestError, false},
 		{"Config error is not retryable", ConfigError, false},
@@ -76,11 +80,13 @@ func TestClassifyHTTPError(t *testing.T) {
 		{"401 Unauthorized", http.StatusUnauthorized, AuthError},
 		{"403 Forbidden", http.StatusForbidden, AuthError},
 		{"404 Not Found", http.StatusNotFound, ModelNotFoundError},
-		{"429 Too Many Requests", http.StatusTooManyRequests, QuotaExceededError},
+		{"429 Too Many Requests", http.StatusTooManyRequests, RateLimitError},
+		{"413 Request Entity Too Large", http.StatusRequestEntityTooLarge, ContextTooLongError},
 		{"400 Bad Request", http.StatusBadRequest, InvalidRequestError},
-		{"500 Internal Server Error", http.StatusInternalServerError, NetworkError},
-		{"502 Bad Gateway", http.StatusBadGateway, NetworkError},
-		{"503 Service Unavailable", http.StatusServiceUnavailable, NetworkError},
+		{"500 Internal Server Error", http.StatusInternalServerError, ServerError},
+		{"502 Bad Gateway", http.StatusBadGateway, ServerError},
+		{"503 Service Unavailable", http.StatusServiceUnavailable, ServerError},
+		{"529 Overloaded", 529, ServerError},
 	}
 
 	for _, tc := range testCases {
@@ -131,6 +137,14 @@ func TestClassifyProviderError(t *testing.T) {
 		{"Network error", "any", "network connection failed", NetworkError},
 		{"Parse error", "any", "failed to parse response", InvalidResponseError},
 		{"Empty response", "any", "no response received", EmptyResponseError},
+		{"Rate limit", "openai", "API error: Rate limit reached for gpt-4o", RateLimitError},
+		{"Context too long", "openai", "API returned status 400: This model's maximum context length is 128000 tokens (context_length_exceeded)", ContextTooLongError},
+		{"Claude prompt too long", "claude", "API error (status 400): invalid_request_error: prompt is too long: 210000 tokens > 200000 maximum", ContextTooLongError},
+		{"Azure content filter", "openai", `API returned status 400: {"error":{"code":"content_filter"}}`, ContentFilterError},
+		{"Server error by status", "ollama", "API request failed with status 503: upstream unavailable", ServerError},
+		{"Auth by status", "any", "HTTP 401 error: unauthenticated", AuthError},
+		{"Bad request falls back to status", "openai", "API request failed with status 400", InvalidRequestError},
+		{"Claude overloaded", "claude", "API error (status 529): overloaded_error: Overloaded", ServerError},
 	}
 
 	for _, tc := range testCases {
@@ -154,6 +168,8 @@ func TestClassifyOpenAIError(t *testing.T) {
 		{"Insufficient quota", "insufficient_quota", QuotaExceededError},
 		{"Invalid API key", "invalid_api_key", AuthError},
 		{"Model not found", "model_not_found", ModelNotFoundError},
+		{"Azure deployment not found", `{"error":{"code":"DeploymentNotFound"}}`, ModelNotFoundError},
+		{"Azure invalid key", "Access denied due to invalid subscription key or wrong API endpoint", AuthError},
 		{"Generic OpenAI error", "some other error", ProviderError},
 	}
 
@@ -292,6 +308,10 @@ func TestErrorTypeConstants(t *testing.T) {
 		ModelNotFoundError:   "model_not_found_error",
 		InvalidResponseError: "invalid_response_error",
 		EmptyResponseError:   "empty_response_error",
+		ServerError:          "server_error",
+		RateLimitError:       "rate_limit_error",
+		ContextTooLongError:  "context_too_long_error",
+		ContentFilterError:   "content_filter_error",
 		ConfigError:          "config_error",
 		ProviderError:        "provider_error",
 		UnknownError:         "unknown_error",
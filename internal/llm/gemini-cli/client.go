@@ -3,8 +3,6 @@ package geminicli
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +13,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -31,37 +31,18 @@ type GeminiCLIProvider struct {
 	cfg                  config.ProviderConfig
 	pm                   *prompt.Manager
 	client               *http.Client
+	curlClient           *http.Client // curl-parity transport, see curlparity.go
 	confirmFunc          func(prompt string) (bool, error)
 	startWebAuthFlowFunc func(ctx context.Context) error
 }
 
 // NewProvider creates a new GeminiCLIProvider.
 func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, error) {
-	// Create configurable HTTP Client (supports custom CA and optional skip verification)
-	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-	}
-
-	// Environment variable control: AISH_GEMINI_CA_FILE specifies CA certificate; AISH_GEMINI_SKIP_TLS_VERIFY skips verification (test only)
-	caFile := strings.TrimSpace(os.Getenv("AISH_GEMINI_CA_FILE"))
-	skipVerify := func() bool {
-		v := strings.TrimSpace(strings.ToLower(os.Getenv("AISH_GEMINI_SKIP_TLS_VERIFY")))
-		return v == "1" || v == "true" || v == "yes"
-	}()
-	if caFile != "" || skipVerify {
-		tlsCfg := &tls.Config{}
-		if caFile != "" {
-			if pem, err := os.ReadFile(caFile); err == nil {
-				pool := x509.NewCertPool()
-				if pool.AppendCertsFromPEM(pem) {
-					tlsCfg.RootCAs = pool
-				}
-			}
-		}
-		if skipVerify {
-			tlsCfg.InsecureSkipVerify = true
-		}
-		tr.TLSClientConfig = tlsCfg
+	// Environment variable control: AISH_GEMINI_CA_FILE specifies CA certificate; AISH_GEMINI_SKIP_TLS_VERIFY skips verification (test only).
+	// Custom TLS settings get their own pooled transport so they never leak into other providers.
+	transport := llm.GetDefaultPool().Transport(llm.SharedTransportKey, nil)
+	if tlsCfg := geminiTLSConfig(); tlsCfg != nil {
+		transport = llm.GetDefaultPool().Transport("gemini-cli-tls", tlsCfg)
 	}
 
 	// Allow timeout override through environment variables (seconds)
@@ -71,12 +52,13 @@ func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, e
 			timeout = n
 		}
 	}
-	client := &http.Client{Timeout: timeout, Transport: tr}
+	client := &http.Client{Timeout: timeout, Transport: transport}
 
 	return &GeminiCLIProvider{
 		cfg:                  cfg,
 		pm:                   pm,
-		client:               client,
+		client:               llm.WithHeaders(client, cfg.ExtraHeaders),
+		curlClient:           llm.WithHeaders(newCurlParityClient(timeout), cfg.ExtraHeaders),
 		confirmFunc:          ui.Confirm,
 		startWebAuthFlowFunc: auth.StartWebAuthFlow,
 	}, nil
@@ -86,10 +68,22 @@ func init() {
 	llm.RegisterProvider("gemini-cli", NewProvider)
 }
 
+// AuthExpiry reports when the cached OAuth access token expires (llm.AuthExpiryReporter).
+func (p *GeminiCLIProvider) AuthExpiry() (time.Time, bool) {
+	exp, err := auth.TokenExpiry()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return exp, true
+}
+
 // ensureProject 於執行期解析/補全專案 ID：
-// 1) 僅讀取 AISH 憑證檔 ~/.config/aish/gemini_oauth_creds.json 的 project_id
+// 1) 僅讀取目前 profile 的 AISH 憑證檔 gemini_oauth_creds.json 的 project_id
 // 2) 若仍無，嘗試本機自動偵測（GCE/GKE Metadata 或 gcloud 目前設定）
 func (p *GeminiCLIProvider) ensureProject(ctx context.Context) error {
+	if llm.IsReplay(ctx) {
+		return nil
+	}
 	// 0) 最高優先：環境變數（允許使用者快速覆蓋）
 	if s := strings.TrimSpace(os.Getenv(config.EnvAISHGeminiProject)); s != "" {
 		p.cfg.Project = s
@@ -157,7 +151,7 @@ func (p *GeminiCLIProvider) ensureProject(ctx context.Context) error {
 		}
 	}
 
-	return fmt.Errorf("project ID not found in ~/.config/aish/gemini_oauth_creds.json and auto-detection failed; set it with 'aish config set providers.gemini-cli.project <PROJECT_ID>'")
+	return fmt.Errorf("project ID not found in the credentials of profile %q and auto-detection failed; set it with 'aish config set providers.gemini-cli.project <PROJECT_ID>'", config.Profile())
 }
 
 // canAccessProject 以目前可用 OAuth token 檢查專案是否可存取（存在且有讀權限）
@@ -172,14 +166,12 @@ func canAccessProject(ctx context.Context, pid string) bool {
 	return false
 }
 
-// readProjectIDFromAishConfig 嘗試從 AISH 設定目錄的 gemini_oauth_creds.json 讀取 project_id 欄位。
+// readProjectIDFromAishConfig 嘗試從目前 profile 的 gemini_oauth_creds.json 讀取 project_id 欄位。
 func readProjectIDFromAishConfig() string {
-	cfgPath, err := config.GetConfigPath()
+	path, err := auth.CredentialsPath()
 	if err != nil {
 		return ""
 	}
-	dir := filepath.Dir(cfgPath)
-	path := filepath.Join(dir, "gemini_oauth_creds.json")
 	b, err := os.ReadFile(path)
 	if err != nil || len(b) == 0 {
 		return ""
@@ -297,12 +289,17 @@ func buildCloudCodeRequestBody(message, model, project string) map[string]any {
 
 // GetSuggestion implements the llm.Provider interface using HTTP API.
 func (p *GeminiCLIProvider) GetSuggestion(ctx context.Context, capturedContext llm.CapturedContext, lang string) (*llm.Suggestion, error) {
+	return p.GetSuggestionStream(ctx, capturedContext, lang, nil)
+}
+
+// GetSuggestionStream implements the llm.Provider interface.
+func (p *GeminiCLIProvider) GetSuggestionStream(ctx context.Context, capturedContext llm.CapturedContext, lang string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
 	// Ensure project is resolved at runtime
 	if err := p.ensureProject(ctx); err != nil {
 		return nil, fmt.Errorf("gemini-cli project resolution failed: %w", err)
 	}
 	// Get the prompt template
-	promptTemplate, err := p.pm.GetPrompt("get_suggestion", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("get_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prompt template: %w", err)
 	}
@@ -314,7 +311,7 @@ func (p *GeminiCLIProvider) GetSuggestion(ctx context.Context, capturedContext l
 		Stderr   string
 		ExitCode int
 	}{
-		Command:  capturedContext.Command,
+		Command:  capturedContext.PromptCommand(),
 		Stdout:   capturedContext.Stdout,
 		Stderr:   capturedContext.Stderr,
 		ExitCode: capturedContext.ExitCode,
@@ -329,77 +326,19 @@ func (p *GeminiCLIProvider) GetSuggestion(ctx context.Context, capturedContext l
 		return nil, fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	var (
-		response string
-		httpErr  error
-		cliErr   error
-	)
-	if shouldUseCURL() {
-		// Prefer cURL parity first
-		response, cliErr = p.generateContentCURL(ctx, tpl.String())
-		if cliErr != nil {
-			response, httpErr = p.generateContentHTTP(ctx, tpl.String())
-			if httpErr != nil {
-				// CLI fallback
-				if resp, cliBinErr := p.generateContentCLI(ctx, tpl.String()); cliBinErr == nil {
-					response = resp
-				} else if (isAuthError(cliErr) || isAuthError(httpErr)) && allowOfficialFallback() {
-					// Optional fallback to official API (requires explicit opt-in)
-					if resp, offErr := p.generateContentOfficialAPI(ctx, tpl.String()); offErr == nil {
-						response = resp
-					} else {
-						return nil, fmt.Errorf("HTTP/CURL auth failed; CLI fallback failed; official API fallback failed: %v | curl: %v | http: %v | cli: %v", offErr, cliErr, httpErr, cliBinErr)
-					}
-				} else {
-					return nil, fmt.Errorf("both CURL and HTTP failed (curl: %v) (http: %v)", cliErr, httpErr)
-				}
-			}
-		}
-	} else {
-		// Default: HTTP first then cURL
-		response, httpErr = p.generateContentHTTP(ctx, tpl.String())
-		if httpErr != nil {
-			response, cliErr = p.generateContentCURL(ctx, tpl.String())
-			if cliErr != nil {
-				// CLI fallback
-				if resp, cliBinErr := p.generateContentCLI(ctx, tpl.String()); cliBinErr == nil {
-					response = resp
-				} else if (isAuthError(httpErr) || isAuthError(cliErr)) && allowOfficialFallback() {
-					// Optional fallback to official API (requires explicit opt-in)
-					if resp, offErr := p.generateContentOfficialAPI(ctx, tpl.String()); offErr == nil {
-						response = resp
-					} else {
-						return nil, fmt.Errorf("HTTP/CURL auth failed; CLI fallback failed; official API fallback failed: %v | http: %v | curl: %v | cli: %v", offErr, httpErr, cliErr, cliBinErr)
-					}
-				} else {
-					return nil, fmt.Errorf("both HTTP and CURL failed (http: %v) (curl: %v)", httpErr, cliErr)
-				}
-			}
-		}
+	response, err := p.exchange(ctx, tpl.String(), onChunk)
+	if err != nil {
+		return nil, err
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Prefer JSON output
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Explanation      string `json:"explanation"`
-		Command          string `json:"command"`
-		CorrectedCommand string `json:"corrected_command"`
-		CorrectedCamel   string `json:"correctedCommand"`
-	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil {
-		cmd := obj.Command
-		if cmd == "" {
-			cmd = obj.CorrectedCommand
-		}
-		if cmd == "" {
-			cmd = obj.CorrectedCamel
-		}
-		if strings.TrimSpace(cmd) != "" && strings.TrimSpace(obj.Explanation) != "" {
-			return &llm.Suggestion{Explanation: strings.TrimSpace(obj.Explanation), CorrectedCommand: strings.TrimSpace(cmd)}, nil
-		}
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
 	}
 
-	// Fallback: heuristic parser
+	// Fallback: heuristic parsing
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return p.parseSuggestionResponse(response)
 }
 
@@ -410,7 +349,7 @@ func (p *GeminiCLIProvider) GetEnhancedSuggestion(ctx context.Context, enhancedC
 		return nil, fmt.Errorf("gemini-cli project resolution failed: %w", err)
 	}
 	// Get the enhanced prompt template
-	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
 		// Fall back to regular suggestion if enhanced template doesn't exist
 		return p.GetSuggestion(ctx, enhancedCtx.CapturedContext, lang)
@@ -434,150 +373,162 @@ func (p *GeminiCLIProvider) GetEnhancedSuggestion(ctx context.Context, enhancedC
 		return nil, fmt.Errorf("failed to execute enhanced template: %w", err)
 	}
 
-	var (
-		response string
-		httpErr  error
-		cliErr   error
-	)
-	if shouldUseCURL() {
-		response, cliErr = p.generateContentCURL(ctx, tpl.String())
-		if cliErr != nil {
-			response, httpErr = p.generateContentHTTP(ctx, tpl.String())
-			if httpErr != nil {
-				if resp, cliBinErr := p.generateContentCLI(ctx, tpl.String()); cliBinErr == nil {
-					response = resp
-				} else if (isAuthError(cliErr) || isAuthError(httpErr)) && allowOfficialFallback() {
-					if resp, offErr := p.generateContentOfficialAPI(ctx, tpl.String()); offErr == nil {
-						response = resp
-					} else {
-						return nil, fmt.Errorf("both CURL and HTTP failed for enhanced suggestion; CLI fallback failed; official API fallback failed: %v | curl: %v | http: %v | cli: %v", offErr, cliErr, httpErr, cliBinErr)
-					}
-				} else {
-					return nil, fmt.Errorf("both CURL and HTTP failed for enhanced suggestion (curl: %v) (http: %v)", cliErr, httpErr)
-				}
-			}
-		}
-	} else {
-		response, httpErr = p.generateContentHTTP(ctx, tpl.String())
-		if httpErr != nil {
-			response, cliErr = p.generateContentCURL(ctx, tpl.String())
-			if cliErr != nil {
-				if resp, cliBinErr := p.generateContentCLI(ctx, tpl.String()); cliBinErr == nil {
-					response = resp
-				} else if (isAuthError(httpErr) || isAuthError(cliErr)) && allowOfficialFallback() {
-					if resp, offErr := p.generateContentOfficialAPI(ctx, tpl.String()); offErr == nil {
-						response = resp
-					} else {
-						return nil, fmt.Errorf("both HTTP and CURL failed for enhanced suggestion; CLI fallback failed; official API fallback failed: %v | http: %v | curl: %v | cli: %v", offErr, httpErr, cliErr, cliBinErr)
-					}
-				} else {
-					return nil, fmt.Errorf("both HTTP and CURL failed for enhanced suggestion (http: %v) (curl: %v)", httpErr, cliErr)
-				}
-			}
-		}
+	response, err := llm.Exchange(ctx, tpl.String(), p.generateContent)
+	if err != nil {
+		return nil, fmt.Errorf("enhanced suggestion: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Prefer JSON output (same parsing logic as regular GetSuggestion)
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Explanation      string `json:"explanation"`
-		Command          string `json:"command"`
-		CorrectedCommand string `json:"corrected_command"`
-		CorrectedCamel   string `json:"correctedCommand"`
-	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil {
-		cmd := obj.Command
-		if cmd == "" {
-			cmd = obj.CorrectedCommand
-		}
-		if cmd == "" {
-			cmd = obj.CorrectedCamel
-		}
-		if strings.TrimSpace(cmd) != "" && strings.TrimSpace(obj.Explanation) != "" {
-			return &llm.Suggestion{Explanation: strings.TrimSpace(obj.Explanation), CorrectedCommand: strings.TrimSpace(cmd)}, nil
-		}
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
 	}
 
-	// Fallback: heuristic parser
+	// Fallback: heuristic parsing
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return p.parseSuggestionResponse(response)
 }
 
 // GenerateCommand implements the llm.Provider interface.
 func (p *GeminiCLIProvider) GenerateCommand(ctx context.Context, promptText string, lang string) (string, error) {
+	return p.GenerateCommandStream(ctx, promptText, lang, nil)
+}
+
+// GenerateCommandStream implements the llm.Provider interface.
+func (p *GeminiCLIProvider) GenerateCommandStream(ctx context.Context, promptText string, lang string, onChunk llm.StreamFunc) (string, error) {
 	// Ensure project is resolved at runtime
 	if err := p.ensureProject(ctx); err != nil {
 		return "", fmt.Errorf("gemini-cli project resolution failed: %w", err)
 	}
-	promptTemplate, err := p.pm.GetPrompt("generate_command", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("generate_command", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt template: %w", err)
 	}
 
-	data := struct{ Prompt string }{Prompt: promptText}
+	data := llm.NewCommandPromptData(ctx, promptText)
 	var tpl bytes.Buffer
 	t := template.Must(template.New("prompt").Parse(promptTemplate))
 	if err := t.Execute(&tpl, data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
-	finalPrompt := tpl.String()
+	finalPrompt := prompt.WithCommentLanguage(tpl.String(), lang)
+
+	response, err := p.exchange(ctx, finalPrompt, onChunk)
+	if err != nil {
+		return "", err
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	// Prefer JSON output, repaired if need be
+	if cmd, ok := llm.DecodeCommand(ctx, response); ok {
+		return cmd, nil
+	}
+
+	// Fallback: extract plausible shell command; if not found, return empty to avoid executing prose
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return cmd, nil
+	}
+	return "", llm.NoCommandError(response)
+}
+
+// GeneratePlan implements llm.Planner: the commands of a multi-step task, in order.
+func (p *GeminiCLIProvider) GeneratePlan(ctx context.Context, promptText string, lang string) (*llm.Plan, error) {
+	if err := p.ensureProject(ctx); err != nil {
+		return nil, fmt.Errorf("gemini-cli project resolution failed: %w", err)
+	}
+	promptTemplate, err := p.pm.GetPrompt("generate_plan", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), nil)
+	if err != nil {
+		return nil, err
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if plan, ok := llm.DecodePlan(ctx, response); ok {
+		return plan, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return llm.SingleStepPlan(cmd), nil
+	}
+	return nil, llm.NoCommandError(response)
+}
+
+// exchange sends message, streaming the response to onChunk when it is set.
+func (p *GeminiCLIProvider) exchange(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	if onChunk == nil {
+		return llm.Exchange(ctx, message, p.generateContent)
+	}
+	return llm.ExchangeStream(ctx, message, p.streamContent, onChunk)
+}
+
+// generateContent sends message over the configured transport and walks the fallback chain:
+// SDK (when selected), then HTTP and cURL in the preferred order, the gemini CLI binary and,
+// for auth failures with explicit opt-in, the official API.
+func (p *GeminiCLIProvider) generateContent(ctx context.Context, message string) (string, error) {
+	transport := p.transport()
+	llm.ReportUsage(ctx, llm.Usage{Model: p.cfg.Model})
+
+	var sdkErr error
+	if transport == config.GeminiTransportSDK {
+		response, err := p.generateContentSDK(ctx, message)
+		if err == nil {
+			return response, nil
+		}
+		sdkErr = err
+	}
 
 	var (
 		response string
 		httpErr  error
 		cliErr   error
 	)
-	if shouldUseCURL() {
-		response, cliErr = p.generateContentCURL(ctx, finalPrompt)
-		if cliErr != nil {
-			response, httpErr = p.generateContentHTTP(ctx, finalPrompt)
-			if httpErr != nil {
-				if resp, cliBinErr := p.generateContentCLI(ctx, finalPrompt); cliBinErr == nil {
-					response = resp
-				} else if (isAuthError(cliErr) || isAuthError(httpErr)) && allowOfficialFallback() {
-					if resp, offErr := p.generateContentOfficialAPI(ctx, finalPrompt); offErr == nil {
-						response = resp
-					} else {
-						return "", fmt.Errorf("HTTP/CURL auth failed; CLI fallback failed; official API fallback failed: %v | curl: %v | http: %v | cli: %v", offErr, cliErr, httpErr, cliBinErr)
-					}
-				} else {
-					return "", fmt.Errorf("both CURL and HTTP failed (curl: %v) (http: %v)", cliErr, httpErr)
-				}
-			}
+	if transport == config.GeminiTransportCURL {
+		response, cliErr = p.generateContentCURL(ctx, message)
+		if cliErr == nil {
+			return response, nil
 		}
+		response, httpErr = p.generateContentHTTP(ctx, message)
 	} else {
-		response, httpErr = p.generateContentHTTP(ctx, finalPrompt)
-		if httpErr != nil {
-			response, cliErr = p.generateContentCURL(ctx, finalPrompt)
-			if cliErr != nil {
-				if resp, cliBinErr := p.generateContentCLI(ctx, finalPrompt); cliBinErr == nil {
-					response = resp
-				} else if (isAuthError(httpErr) || isAuthError(cliErr)) && allowOfficialFallback() {
-					if resp, offErr := p.generateContentOfficialAPI(ctx, finalPrompt); offErr == nil {
-						response = resp
-					} else {
-						return "", fmt.Errorf("HTTP/CURL auth failed; CLI fallback failed; official API fallback failed: %v | http: %v | curl: %v | cli: %v", offErr, httpErr, cliErr, cliBinErr)
-					}
-				} else {
-					return "", fmt.Errorf("both HTTP and CURL failed (http: %v) (curl: %v)", httpErr, cliErr)
-				}
-			}
+		response, httpErr = p.generateContentHTTP(ctx, message)
+		if httpErr == nil {
+			return response, nil
 		}
+		response, cliErr = p.generateContentCURL(ctx, message)
+	}
+	if httpErr == nil || cliErr == nil {
+		return response, nil
 	}
 
-	// Prefer JSON output
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Command string `json:"command"`
+	// CLI fallback
+	resp, cliBinErr := p.generateContentCLI(ctx, message)
+	if cliBinErr == nil {
+		return resp, nil
 	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil && strings.TrimSpace(obj.Command) != "" {
-		return strings.TrimSpace(obj.Command), nil
+	failures := fmt.Sprintf("http: %v | curl: %v", httpErr, cliErr)
+	if sdkErr != nil {
+		failures = fmt.Sprintf("sdk: %v | %s", sdkErr, failures)
 	}
-
-	// Fallback: extract plausible shell command; if not found, return empty to avoid executing prose
-	if cmd := extractPlausibleCommand(response); cmd != "" {
-		return cmd, nil
+	if (isAuthError(sdkErr) || isAuthError(httpErr) || isAuthError(cliErr)) && allowOfficialFallback() {
+		// Optional fallback to official API (requires explicit opt-in)
+		resp, offErr := p.generateContentOfficialAPI(ctx, message)
+		if offErr == nil {
+			return resp, nil
+		}
+		return "", fmt.Errorf("HTTP/CURL auth failed; CLI fallback failed; official API fallback failed: %v | %s | cli: %v", offErr, failures, cliBinErr)
 	}
-	return "", fmt.Errorf("no plausible command found in provider response")
+	return "", fmt.Errorf("all gemini-cli transports failed (%s)", failures)
 }
 
 // extractPlausibleCommand tries to extract a shell-like command from free-form text.
@@ -651,6 +602,26 @@ func plausibleCommand(line string) bool {
 	return true
 }
 
+// Warmup opens a connection to the Cloud Code host through the client the configured transport
+// will use (llm.Warmer). The SDK transport manages its own client and is not warmed up.
+func (p *GeminiCLIProvider) Warmup(ctx context.Context) error {
+	client := p.client
+	switch p.transport() {
+	case config.GeminiTransportSDK:
+		return nil
+	case config.GeminiTransportCURL:
+		if useCURLExec() {
+			return nil
+		}
+		client = p.curlClient
+	}
+	targetURL, err := buildGenerateContentURL(p.cfg.APIEndpoint)
+	if err != nil || client == nil {
+		return err
+	}
+	return llm.WarmupEndpoint(ctx, client, targetURL)
+}
+
 // VerifyConnection implements the llm.Provider interface.
 func (p *GeminiCLIProvider) VerifyConnection(ctx context.Context) ([]string, error) {
 	// Resolve project at runtime instead of failing early
@@ -659,7 +630,7 @@ func (p *GeminiCLIProvider) VerifyConnection(ctx context.Context) ([]string, err
 	}
 
 	// Try to verify using the HTTP API
-	err := p.verifyGeminiCLIEndpoint()
+	err := p.verifyGeminiCLIEndpoint(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Gemini CLI verification failed: %w", err)
 	}
@@ -731,6 +702,7 @@ func (p *GeminiCLIProvider) generateContentHTTP(ctx context.Context, message str
 		if err := json.Unmarshal(data, &response); err != nil {
 			return "", 200, string(data), fmt.Errorf("failed to decode response: %w", err)
 		}
+		reportAPIUsage(ctx, data)
 		// If API returns an explicit error object, surface it as an error instead of
 		// attempting to heuristically extract text (prevents showing auth error as suggestion)
 		if errObj, ok := response["error"].(map[string]any); ok {
@@ -804,8 +776,9 @@ func getStringFromAny(v any) string {
 	return ""
 }
 
-// generateContentCURL uses cURL to send requests, format aligned with user-provided examples
-func (p *GeminiCLIProvider) generateContentCURL(ctx context.Context, message string) (string, error) {
+// generateContentCURLExec shells out to the curl binary. Deprecated: kept behind
+// AISH_GEMINI_CURL_EXEC for one release; generateContentCURL uses the native curl-parity client.
+func (p *GeminiCLIProvider) generateContentCURLExec(ctx context.Context, message string) (string, error) {
 	// Ensure token is valid
 	if err := auth.EnsureValidToken(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: token refresh check failed: %v\n", err)
@@ -833,15 +806,23 @@ func (p *GeminiCLIProvider) generateContentCURL(ctx context.Context, message str
 	if _, err := exec.LookPath("curl"); err != nil {
 		return "", fmt.Errorf("curl not found in PATH")
 	}
+	// The token, the extra headers and the body stay off the command line, which other users
+	// can read from the process list: the headers go on stdin, the body in a private file
+	bodyFile, err := writeCurlBody(jb)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(bodyFile)
 	cmd := exec.CommandContext(ctx, "curl",
 		"--silent", "--show-error",
 		"--request", "POST",
 		"--url", targetURL,
-		"--header", "Authorization: Bearer "+token,
-		"--header", "Content-Type: application/json",
-		"--data", string(jb),
+		"--header", "@-",
+		"--data-binary", "@"+bodyFile,
+		"--write-out", curlTimingFormat,
 	)
 	// Do not set x-goog-user-project header to match user's working sample
+	cmd.Stdin = strings.NewReader(curlHeaders(token, p.cfg.ExtraHeaders))
 	// SSL verification control: consistent with HTTP client
 	if v := strings.TrimSpace(strings.ToLower(os.Getenv("AISH_GEMINI_SKIP_TLS_VERIFY"))); v == "1" || v == "true" || v == "yes" {
 		cmd.Args = append(cmd.Args, "--insecure")
@@ -849,11 +830,21 @@ func (p *GeminiCLIProvider) generateContentCURL(ctx context.Context, message str
 	if caf := strings.TrimSpace(os.Getenv("AISH_GEMINI_CA_FILE")); caf != "" {
 		cmd.Args = append(cmd.Args, "--cacert", caf)
 	}
+	// curl reads the proxy variables itself but not aish's config
+	if proxy := llm.ProxyURL(); proxy != "" {
+		cmd.Args = append(cmd.Args, "--proxy", proxy)
+	}
 	var out bytes.Buffer
 	var errb bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &errb
-	if err := cmd.Run(); err != nil {
+	err = cmd.Run()
+	if timing, rest, ok := parseCurlTiming(errb.String()); ok {
+		llm.RecordHTTPTiming(ctx, timing)
+		errb.Reset()
+		errb.WriteString(rest)
+	}
+	if err != nil {
 		return "", fmt.Errorf("curl request failed: %v | %s", err, errb.String())
 	}
 	if shouldDebug() {
@@ -865,10 +856,125 @@ func (p *GeminiCLIProvider) generateContentCURL(ctx context.Context, message str
 		}
 	}
 
-	raw := out.Bytes()
+	reportAPIUsage(ctx, out.Bytes())
+	return decodeGenerateContentResponse(out.Bytes(), "curl")
+}
+
+// curlHeaders returns the request headers for curl's "--header @-", one per line.
+func curlHeaders(token string, extra map[string]string) string {
+	var b strings.Builder
+	b.WriteString("Authorization: Bearer " + token + "\n")
+	b.WriteString("Content-Type: application/json\n")
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// A line break would start a header of its own
+		value := strings.NewReplacer("\r", "", "\n", "").Replace(extra[name])
+		b.WriteString(name + ": " + value + "\n")
+	}
+	return b.String()
+}
+
+// curlTimingFormat makes curl write its timing to stderr as one line for parseCurlTiming. The
+// times are in seconds from the start of the request, zero for the steps that did not happen.
+const curlTimingFormat = "%{stderr}\naish-timing %{time_namelookup} %{time_connect} %{time_appconnect} " +
+	"%{time_starttransfer} %{time_total}\n"
+
+// parseCurlTiming takes the line curlTimingFormat produced out of curl's stderr and returns
+// it as an HTTPTiming along with the rest of stderr.
+func parseCurlTiming(stderr string) (llm.HTTPTiming, string, bool) {
+	before, line, found := strings.Cut(stderr, "\naish-timing ")
+	if !found {
+		return llm.HTTPTiming{}, stderr, false
+	}
+	line, after, _ := strings.Cut(line, "\n")
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return llm.HTTPTiming{}, stderr, false
+	}
+	var secs [5]time.Duration
+	for i := range secs {
+		f, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return llm.HTTPTiming{}, stderr, false
+		}
+		secs[i] = time.Duration(f * float64(time.Second))
+	}
+	dns, connect, tlsDone, firstByte, total := secs[0], secs[1], secs[2], secs[3], secs[4]
+	// Each curl process opens a connection of its own, so none is reused
+	timing := llm.HTTPTiming{DNS: dns, TTFB: firstByte, Total: total}
+	if connect > dns {
+		timing.Connect = connect - dns
+	}
+	if tlsDone > connect {
+		timing.TLS = tlsDone - connect
+	}
+	return timing, before + after, true
+}
+
+// writeCurlBody writes the request body to a file only the current user can read and returns
+// its path.
+func writeCurlBody(body []byte) (string, error) {
+	f, err := os.CreateTemp("", "aish-curl-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to write curl request body: %w", err)
+	}
+	_, err = f.Write(body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write curl request body: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// apiUsage is the usage part of a generateContent response, top-level or, from Cloud Code,
+// wrapped under "response".
+type apiUsage struct {
+	UsageMetadata *usageMetadata `json:"usageMetadata"`
+	ModelVersion  string         `json:"modelVersion"`
+	Response      *apiUsage      `json:"response"`
+}
+
+type usageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+}
+
+// reportAPIUsage passes the token counts of a raw generateContent response to llm.ReportUsage.
+func reportAPIUsage(ctx context.Context, raw []byte) {
+	if !llm.UsageRequested(ctx) {
+		return
+	}
+	var u apiUsage
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return
+	}
+	if u.Response != nil {
+		u = *u.Response
+	}
+	if u.UsageMetadata == nil {
+		return
+	}
+	llm.ReportUsage(ctx, llm.Usage{
+		Model:            u.ModelVersion,
+		PromptTokens:     u.UsageMetadata.PromptTokenCount,
+		CompletionTokens: u.UsageMetadata.CandidatesTokenCount + u.UsageMetadata.ThoughtsTokenCount,
+	})
+}
+
+// decodeGenerateContentResponse extracts the model text from a raw generateContent response.
+// An explicit error object is returned as an error so it is never shown as a suggestion.
+func decodeGenerateContentResponse(raw []byte, via string) (string, error) {
 	var response map[string]any
 	if err := json.Unmarshal(raw, &response); err != nil {
-		return "", fmt.Errorf("failed to decode curl response: %v | %s", err, out.String())
+		return "", fmt.Errorf("failed to decode %s response: %v | %s", via, err, string(raw))
 	}
 	// 若回傳為錯誤物件，直接回傳錯誤，避免將錯誤訊息誤判為模型輸出
 	if errObj, ok := response["error"].(map[string]any); ok {
@@ -890,8 +996,7 @@ func (p *GeminiCLIProvider) generateContentCURL(ctx context.Context, message str
 	if txt, ok := findKnownTextFields(response); ok {
 		return txt, nil
 	}
-	return "", errors.New("invalid response format (curl)")
-
+	return "", fmt.Errorf("invalid response format (%s)", via)
 }
 
 // parseTextFromAPIResponse parses API response structure, supports top-level or candidates structure wrapped under "response"
@@ -979,14 +1084,16 @@ func (p *GeminiCLIProvider) generateContentCLI(ctx context.Context, message stri
 	return out.String(), nil
 }
 
-// getOAuthToken reads OAuth token from the aish config directory first, then falls back to the system's .gemini directory.
+// getOAuthToken reads OAuth token from the active profile's credentials first, then, for the
+// default profile, falls back to the system's .gemini directory.
 // It will prompt the user to choose an authentication method if no valid token is found.
 func (p *GeminiCLIProvider) getOAuthToken() (string, error) {
 	// 1. Try aish-specific token first
-	aishConfigPath, err := config.GetConfigPath()
+	aishTokenPath, err := auth.CredentialsPath()
 	if err == nil {
-		aishConfigDir := filepath.Dir(aishConfigPath)
-		aishTokenPath := filepath.Join(aishConfigDir, "gemini_oauth_creds.json")
+		if err := config.CheckSecretFile(aishTokenPath); err != nil {
+			return "", err
+		}
 		if token, err := readTokenFromFile(aishTokenPath); err == nil {
 			if shouldDebug() {
 				fmt.Fprintln(os.Stderr, "DEBUG aish/gemini-cli token_source=aish_config")
@@ -995,7 +1102,10 @@ func (p *GeminiCLIProvider) getOAuthToken() (string, error) {
 		}
 	}
 
-	// 2. Fallback to system-wide .gemini directory
+	// 2. Fallback to system-wide .gemini directory; a named profile only uses its own sign-in
+	if !auth.SystemCredentialsAllowed() {
+		return p.promptAndAuthenticate()
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get user home directory: %w", err)
@@ -1003,13 +1113,18 @@ func (p *GeminiCLIProvider) getOAuthToken() (string, error) {
 	geminiDir := filepath.Join(home, ".gemini")
 	if _, err := os.Stat(geminiDir); err == nil {
 		oauthPath := filepath.Join(geminiDir, "oauth_creds.json")
+		accessTokenPath := filepath.Join(geminiDir, "access_token")
+		for _, path := range []string{oauthPath, accessTokenPath} {
+			if err := config.CheckSecretFile(path); err != nil {
+				return "", err
+			}
+		}
 		if token, err := readTokenFromFile(oauthPath); err == nil {
 			if shouldDebug() {
 				fmt.Fprintln(os.Stderr, "DEBUG aish/gemini-cli token_source=system_gemini_dir")
 			}
 			return token, nil
 		}
-		accessTokenPath := filepath.Join(geminiDir, "access_token")
 		if data, err := os.ReadFile(accessTokenPath); err == nil {
 			token := sanitizeToken(strings.TrimSpace(string(data)))
 			if token != "" {
@@ -1093,8 +1208,8 @@ func (p *GeminiCLIProvider) promptAndAuthenticate() (string, error) {
 }
 
 // verifyGeminiCLIEndpoint verifies connection to Gemini CLI API
-func (p *GeminiCLIProvider) verifyGeminiCLIEndpoint() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (p *GeminiCLIProvider) verifyGeminiCLIEndpoint(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	// Ensure the token is valid before making a verification call
@@ -1170,8 +1285,11 @@ func (p *GeminiCLIProvider) verifyGeminiCLIEndpoint() error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	// Reuse provider's HTTP client for consistent timeouts
+	// Reuse the client of the configured transport for consistent timeouts
 	client := p.client
+	if p.transport() == config.GeminiTransportCURL && p.curlClient != nil {
+		client = p.curlClient
+	}
 	if client == nil {
 		client = &http.Client{Timeout: 20 * time.Second}
 	}
@@ -1295,34 +1413,6 @@ func (p *GeminiCLIProvider) parseSuggestionResponse(response string) (*llm.Sugge
 	}, nil
 }
 
-// mapLanguage maps user language preferences to template language codes
-func mapLanguage(lang string) string {
-	switch strings.ToLower(lang) {
-	case "chinese", "zh", "zh-TW", "zh-CN":
-		return "zh-TW"
-	case "english", "en":
-		return "en"
-	default:
-		return "en"
-	}
-}
-
-// stripCodeFences removes common markdown code fences and json hints.
-func stripCodeFences(s string) string {
-	s = strings.TrimSpace(s)
-	if strings.HasPrefix(s, "```") {
-		s = strings.TrimPrefix(s, "```")
-		s = strings.TrimSpace(s)
-		if strings.HasPrefix(strings.ToLower(s), "json") {
-			s = strings.TrimSpace(s[4:])
-		}
-		if idx := strings.LastIndex(s, "```"); idx != -1 {
-			s = s[:idx]
-		}
-	}
-	return strings.TrimSpace(strings.Trim(s, "`"))
-}
-
 // allowOfficialFallback 需顯式設定環境變數 AISH_GEMINI_ALLOW_OFFICIAL_FALLBACK=true 才會啟用官方 API 回退
 func allowOfficialFallback() bool {
 	v := strings.TrimSpace(strings.ToLower(os.Getenv("AISH_GEMINI_ALLOW_OFFICIAL_FALLBACK")))
@@ -1391,6 +1481,7 @@ func (p *GeminiCLIProvider) generateContentOfficialAPI(ctx context.Context, mess
 	if err := json.Unmarshal(data, &m); err != nil {
 		return "", fmt.Errorf("fallback decode error: %v", err)
 	}
+	reportAPIUsage(ctx, data)
 	if txt, ok := parseTextFromAPIResponse(m); ok {
 		return txt, nil
 	}
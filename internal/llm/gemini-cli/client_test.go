@@ -9,11 +9,15 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	cloudauth "cloud.google.com/go/auth"
+	"google.golang.org/genai"
+
 	"github.com/TonnyWong1052/aish/internal/config"
 	"github.com/TonnyWong1052/aish/internal/llm"
 	"github.com/TonnyWong1052/aish/internal/llm/gemini/auth"
@@ -54,7 +58,7 @@ func createMockTokenFile(t *testing.T, content string) string {
 		t.Fatalf("Failed to create .gemini dir: %v", err)
 	}
 	tokenFile := filepath.Join(geminiDir, "oauth_creds.json")
-	if err := os.WriteFile(tokenFile, []byte(content), 0644); err != nil {
+	if err := os.WriteFile(tokenFile, []byte(content), 0600); err != nil {
 		t.Fatalf("Failed to write token file: %v", err)
 	}
 	return home
@@ -172,7 +176,7 @@ func TestGeminiCLIProvider_GetSuggestion_TokenExpired(t *testing.T) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(response)
+		_ = json.NewEncoder(w).Encode(response)
 	}))
 	defer mockServer.Close()
 
@@ -197,11 +201,10 @@ func TestGeminiCLIProvider_GetSuggestion_TokenExpired(t *testing.T) {
 	// but it should fall back to the access_token file if oauth_creds.json is expired.
 	// Let's create a fallback token file.
 	fallbackTokenFile := filepath.Join(home, ".gemini", "access_token")
-	if err := os.WriteFile(fallbackTokenFile, []byte("expired-token"), 0644); err != nil {
+	if err := os.WriteFile(fallbackTokenFile, []byte("expired-token"), 0600); err != nil {
 		t.Fatalf("Failed to write fallback token file: %v", err)
 	}
 
-
 	suggestion, err := provider.GetSuggestion(context.Background(), capturedContext, "en")
 	// Depending on the exact logic of token refresh/fallback, this might succeed or fail.
 	// For now, we'll assume it might succeed if the fallback token is somehow still valid
@@ -468,7 +471,7 @@ func TestGeminiCLIProvider_getOAuthToken_PromptAndAuthenticateWebSuccess(t *test
 		}
 
 		if expiresIn, ok := tokens["expires_in"].(float64); ok {
-			tokens["expiry_date"] = time.Now().Add(time.Duration(expiresIn)*time.Second).UnixMilli()
+			tokens["expiry_date"] = time.Now().Add(time.Duration(expiresIn) * time.Second).UnixMilli()
 		}
 
 		credsPath := filepath.Join(geminiDir, "oauth_creds.json")
@@ -534,4 +537,277 @@ func TestGeminiCLIProvider_getOAuthToken_PromptAndAuthenticateWebSuccess(t *test
 	if string(fileToken) != expectedToken {
 		t.Errorf("access_token file contains incorrect token: %s", string(fileToken))
 	}
-}
\ No newline at end of file
+}
+func TestGeminiCLIProvider_Transport(t *testing.T) {
+	tests := []struct {
+		configured string
+		useCURL    string
+		want       string
+	}{
+		{"", "", config.GeminiTransportHTTP},
+		{"", "true", config.GeminiTransportCURL},
+		{"SDK", "true", config.GeminiTransportSDK},
+		{"http", "true", config.GeminiTransportHTTP},
+		{"curl", "", config.GeminiTransportCURL},
+		{"grpc", "", config.GeminiTransportHTTP},
+	}
+	for _, tt := range tests {
+		t.Setenv(config.EnvAISHGeminiUseCURL, tt.useCURL)
+		p := &GeminiCLIProvider{cfg: config.ProviderConfig{Transport: tt.configured}}
+		if got := p.transport(); got != tt.want {
+			t.Errorf("transport(%q, AISH_GEMINI_USE_CURL=%q) = %q, want %q", tt.configured, tt.useCURL, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateContentSDK_RequiresProject(t *testing.T) {
+	p := &GeminiCLIProvider{cfg: config.ProviderConfig{Project: "YOUR_GEMINI_PROJECT_ID"}}
+	if _, err := p.generateContentSDK(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "project") {
+		t.Fatalf("expected project error, got %v", err)
+	}
+}
+
+// countingTransport records the requests that reach it before passing them on.
+type countingTransport struct {
+	base     http.RoundTripper
+	requests []*http.Request
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+	return t.base.RoundTrip(req)
+}
+
+func TestNewSDKClient_UsesProviderTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer adc-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.Header.Get("X-Gateway"); got != "corp" {
+			t.Errorf("extra header X-Gateway = %q", got)
+		}
+		if got := r.Header.Get("User-Agent"); !strings.HasPrefix(got, "aish/") {
+			t.Errorf("User-Agent = %q, want aish's", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"ls -la"}]}}]}`)
+	}))
+	defer server.Close()
+	t.Setenv("GOOGLE_VERTEX_BASE_URL", server.URL+"/")
+
+	transport := &countingTransport{base: http.DefaultTransport}
+	p := &GeminiCLIProvider{
+		client: llm.WithHeaders(&http.Client{Timeout: 5 * time.Second, Transport: transport}, map[string]string{"X-Gateway": "corp"}),
+	}
+	creds := cloudauth.NewCredentials(&cloudauth.CredentialsOptions{
+		TokenProvider: tokenProviderFunc(func(context.Context) (*cloudauth.Token, error) {
+			return &cloudauth.Token{Value: "adc-token", Type: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+		}),
+	})
+
+	client, err := p.newSDKClient(context.Background(), "test-project", creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Models.GenerateContent(context.Background(), "gemini-2.5-flash", genai.Text("list files"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "ls -la" {
+		t.Errorf("response = %q", resp.Text())
+	}
+	if len(transport.requests) != 1 {
+		t.Errorf("%d requests went through the provider transport, want 1", len(transport.requests))
+	}
+}
+
+type tokenProviderFunc func(context.Context) (*cloudauth.Token, error)
+
+func (f tokenProviderFunc) Token(ctx context.Context) (*cloudauth.Token, error) { return f(ctx) }
+
+func TestCurlParityClient_MatchesCurlDefaults(t *testing.T) {
+	var gotUA, gotAcceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+		gotUA = r.Header.Get("User-Agent")
+		gotAcceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Write([]byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"ls -la"}]}}]}}`))
+	}))
+	defer server.Close()
+
+	client := newCurlParityClient(5 * time.Second)
+
+	resp, err := client.Get(server.URL + "/redirect")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("expected redirect not to be followed, got status %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader("{}"))
+	req.Header.Set("User-Agent", curlParityUserAgent)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if gotAcceptEncoding != "" {
+		t.Errorf("expected no Accept-Encoding header, got %q", gotAcceptEncoding)
+	}
+	if gotUA != curlParityUserAgent {
+		t.Errorf("expected User-Agent %q, got %q", curlParityUserAgent, gotUA)
+	}
+	if txt, err := decodeGenerateContentResponse(raw, "curl"); err != nil || txt != "ls -la" {
+		t.Errorf("decodeGenerateContentResponse() = %q, %v", txt, err)
+	}
+}
+
+func TestDecodeGenerateContentResponse_ErrorObject(t *testing.T) {
+	raw := []byte(`{"error":{"code":401,"message":"Invalid token","status":"UNAUTHENTICATED"}}`)
+	_, err := decodeGenerateContentResponse(raw, "curl")
+	if err == nil || err.Error() != "UNAUTHENTICATED: Invalid token" {
+		t.Fatalf("expected API error to surface, got %v", err)
+	}
+	if !isAuthError(err) {
+		t.Error("expected error to be classified as an auth error")
+	}
+}
+
+func TestBuildStreamGenerateContentURL(t *testing.T) {
+	tests := map[string]string{
+		"":                                       "https://cloudcode-pa.googleapis.com/v1internal:streamGenerateContent?alt=sse",
+		"https://example.com":                    "https://example.com/v1internal:streamGenerateContent?alt=sse",
+		"https://example.com/v1:generateContent": "https://example.com/v1:streamGenerateContent?alt=sse",
+		"https://example.com/v1:generateContent?key=k": "https://example.com/v1:streamGenerateContent?key=k&alt=sse",
+	}
+	for endpoint, want := range tests {
+		got, err := buildStreamGenerateContentURL(endpoint)
+		if err != nil || got != want {
+			t.Errorf("buildStreamGenerateContentURL(%q) = %q, %v, want %q", endpoint, got, err, want)
+		}
+	}
+}
+
+func TestReadCloudCodeStream(t *testing.T) {
+	body := `data: {"response":{"candidates":[{"content":{"parts":[{"thought":true,"text":"hmm"},{"text":"ls "}]}}]}}
+
+data: {"response":{"candidates":[{"content":{"parts":[{"text":"-la"}]}}]}}
+
+`
+	var chunks []string
+	got, err := readCloudCodeStream(context.Background(), strings.NewReader(body), func(delta string) {
+		chunks = append(chunks, delta)
+	})
+	if err != nil || got != "ls -la" {
+		t.Fatalf("readCloudCodeStream() = %q, %v, want %q", got, err, "ls -la")
+	}
+	if strings.Join(chunks, "|") != "ls |-la" {
+		t.Errorf("chunks = %q, want the text of each event in order", chunks)
+	}
+
+	body = `data: {"error":{"code":401,"message":"Invalid token","status":"UNAUTHENTICATED"}}
+
+`
+	_, err = readCloudCodeStream(context.Background(), strings.NewReader(body), func(string) {})
+	if err == nil || !isAuthError(err) {
+		t.Errorf("expected the error event to surface as an auth error, got %v", err)
+	}
+}
+
+func TestGenerateContentCURLExec_KeepsSecretsOffCommandLine(t *testing.T) {
+	realCurl, err := exec.LookPath("curl")
+	if err != nil {
+		t.Skip("curl not installed")
+	}
+	// A curl in front of the real one records its command line
+	bin := t.TempDir()
+	argsFile := filepath.Join(bin, "args")
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %q\nexec %q \"$@\"\n", argsFile, realCurl)
+	if err := os.WriteFile(filepath.Join(bin, "curl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AISH_GEMINI_BEARER", "env-token")
+
+	var gotAuth, gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotHeader = r.Header.Get("X-Gateway-Key")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Write([]byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"ls -la"}]}}]}}`))
+	}))
+	defer server.Close()
+
+	provider, err := createTestProvider(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	provider.cfg.ExtraHeaders = map[string]string{"X-Gateway-Key": "gateway-secret"}
+
+	// A replay context skips the project lookup, which would go to Google
+	ctx, _ := llm.WithSessionReplay(context.Background(), &llm.SessionRecord{})
+	ctx, trace := llm.TraceHTTP(ctx)
+	got, err := provider.generateContentCURLExec(ctx, "list files")
+	if err != nil || got != "ls -la" {
+		t.Fatalf("generateContentCURLExec() = %q, %v", got, err)
+	}
+	if gotAuth != "Bearer env-token" || gotHeader != "gateway-secret" {
+		t.Errorf("headers = %q, %q, want the token and the extra header", gotAuth, gotHeader)
+	}
+	if !strings.Contains(gotBody, "list files") {
+		t.Errorf("body = %q, want the request", gotBody)
+	}
+	if timing, ok := trace.Timing(); !ok || timing.TTFB <= 0 || timing.Total < timing.TTFB {
+		t.Errorf("curl timing = %+v, %v, want the request timed", timing, ok)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("curl was not run through PATH: %v", err)
+	}
+	for _, secret := range []string{"env-token", "gateway-secret", "list files"} {
+		if strings.Contains(string(args), secret) {
+			t.Errorf("curl command line %q contains %q", args, secret)
+		}
+	}
+}
+
+func TestCurlHeaders(t *testing.T) {
+	got := curlHeaders("tok", map[string]string{"X-B": "2", "X-A": "1\r\nX-Injected: 3"})
+	want := "Authorization: Bearer tok\nContent-Type: application/json\nX-A: 1X-Injected: 3\nX-B: 2\n"
+	if got != want {
+		t.Errorf("curlHeaders() = %q, want %q", got, want)
+	}
+}
+
+func TestParseCurlTiming(t *testing.T) {
+	stderr := "curl: (6) warning\naish-timing 0.012 0.043 0.091 0.820 1.350\n"
+	timing, rest, ok := parseCurlTiming(stderr)
+	want := llm.HTTPTiming{DNS: 12 * time.Millisecond, Connect: 31 * time.Millisecond, TLS: 48 * time.Millisecond,
+		TTFB: 820 * time.Millisecond, Total: 1350 * time.Millisecond}
+	if !ok || rest != "curl: (6) warning" {
+		t.Fatalf("parseCurlTiming() = %+v, %q, %v", timing, rest, ok)
+	}
+	round := func(d time.Duration) time.Duration { return d.Round(time.Millisecond) }
+	if round(timing.DNS) != want.DNS || round(timing.Connect) != want.Connect || round(timing.TLS) != want.TLS ||
+		round(timing.TTFB) != want.TTFB || round(timing.Total) != want.Total {
+		t.Errorf("parseCurlTiming() = %+v, want %+v", timing, want)
+	}
+
+	// A plain-HTTP request that never connected
+	timing, _, ok = parseCurlTiming("\naish-timing 0.002 0.000 0.000 0.000 0.004\n")
+	if !ok || timing.Connect != 0 || timing.TLS != 0 || timing.TTFB != 0 {
+		t.Errorf("failed request: %+v, %v", timing, ok)
+	}
+
+	if _, rest, ok := parseCurlTiming("curl: (7) Failed to connect"); ok || rest != "curl: (7) Failed to connect" {
+		t.Errorf("stderr without timing = %q, %v", rest, ok)
+	}
+}
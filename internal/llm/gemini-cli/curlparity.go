@@ -0,0 +1,152 @@
+package geminicli
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/llm/gemini/auth"
+)
+
+// curlParityUserAgent is sent by the curl-parity client. Some Cloud Code frontends have treated
+// unknown Go clients differently from curl, which is what the exec fallback originally worked around.
+const curlParityUserAgent = "curl/8.7.1"
+
+var curlExecDeprecationOnce sync.Once
+
+// geminiTLSConfig builds the TLS settings shared by the HTTP and curl-parity clients from
+// AISH_GEMINI_CA_FILE and AISH_GEMINI_SKIP_TLS_VERIFY. It returns nil when neither is set.
+func geminiTLSConfig() *tls.Config {
+	caFile := strings.TrimSpace(os.Getenv(config.EnvAISHGeminiCAFile))
+	v := strings.TrimSpace(strings.ToLower(os.Getenv(config.EnvAISHGeminiSkipTLSVerify)))
+	skipVerify := v == "1" || v == "true" || v == "yes"
+	if caFile == "" && !skipVerify {
+		return nil
+	}
+	tlsCfg := &tls.Config{}
+	if caFile != "" {
+		if pem, err := os.ReadFile(caFile); err == nil {
+			pool := x509.NewCertPool()
+			if pool.AppendCertsFromPEM(pem) {
+				tlsCfg.RootCAs = pool
+			}
+		}
+	}
+	if skipVerify {
+		tlsCfg.InsecureSkipVerify = true
+	}
+	return tlsCfg
+}
+
+// newCurlParityClient returns an HTTP client that behaves like the curl invocation it replaces:
+// the proxy of the config or the environment, HTTP/2 negotiated via ALPN even with a custom TLS config,
+// no transparent gzip (curl sends no Accept-Encoding without --compressed) and no redirects
+// (curl does not follow them without --location).
+func newCurlParityClient(timeout time.Duration) *http.Client {
+	tr := &http.Transport{
+		Proxy:              llm.Proxy,
+		TLSClientConfig:    geminiTLSConfig(),
+		ForceAttemptHTTP2:  true,
+		DisableCompression: true,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: tr,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// useCURLExec reports whether the deprecated curl subprocess should be used (AISH_GEMINI_CURL_EXEC=true).
+func useCURLExec() bool {
+	v := strings.TrimSpace(strings.ToLower(os.Getenv(config.EnvAISHGeminiCurlExec)))
+	return v == "1" || v == "true" || v == "yes"
+}
+
+// generateContentCURL sends the request the way the curl fallback always has, natively.
+// The curl subprocess is still available through AISH_GEMINI_CURL_EXEC for one release.
+func (p *GeminiCLIProvider) generateContentCURL(ctx context.Context, message string) (string, error) {
+	if useCURLExec() {
+		curlExecDeprecationOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "Warning: %s is deprecated and will be removed in the next release; the native curl-parity client is used otherwise.\n", config.EnvAISHGeminiCurlExec)
+		})
+		return p.generateContentCURLExec(ctx, message)
+	}
+	return p.generateContentCurlParity(ctx, message)
+}
+
+// generateContentCurlParity is the native replacement for generateContentCURLExec: same URL,
+// body and headers as the curl command line, sent through the curl-parity client.
+func (p *GeminiCLIProvider) generateContentCurlParity(ctx context.Context, message string) (string, error) {
+	if err := auth.EnsureValidToken(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: token refresh check failed: %v\n", err)
+	}
+
+	if err := p.ensureProject(ctx); err != nil {
+		return "", fmt.Errorf("gemini-cli project resolution failed: %w", err)
+	}
+
+	targetURL, err := buildGenerateContentURL(p.cfg.APIEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve API endpoint: %w", err)
+	}
+
+	token, err := p.getBearerToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OAuth token: %w", err)
+	}
+
+	body := buildCloudCodeRequestBody(message, p.cfg.Model, p.cfg.Project)
+	jb, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", targetURL, bytes.NewReader(jb))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", curlParityUserAgent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	if shouldDebug() {
+		fmt.Fprintf(os.Stderr, "DEBUG aish/gemini-cli CURL url=%s\n", targetURL)
+		fmt.Fprintf(os.Stderr, "DEBUG aish/gemini-cli CURL body=%s\n", string(jb))
+		fmt.Fprintf(os.Stderr, "DEBUG aish/gemini-cli CURL token=%s\n", maskToken(token))
+	}
+
+	client := p.curlClient
+	if client == nil {
+		timeout := 30 * time.Second
+		if p.client != nil {
+			timeout = p.client.Timeout
+		}
+		client = newCurlParityClient(timeout)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("curl request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Like curl without --fail, the body is decoded regardless of status so API error objects surface.
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("curl request failed: %w", err)
+	}
+	reportAPIUsage(ctx, raw)
+	return decodeGenerateContentResponse(raw, "curl")
+}
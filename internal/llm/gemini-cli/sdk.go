@@ -0,0 +1,115 @@
+package geminicli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+	"google.golang.org/genai"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+// defaultSDKLocation is the Vertex AI region used when neither AISH_GEMINI_LOCATION nor
+// GOOGLE_CLOUD_LOCATION is set.
+const defaultSDKLocation = "us-central1"
+
+// sdkScope is the OAuth scope Application Default Credentials are requested with.
+const sdkScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// transport returns the configured request transport. An unset transport keeps the previous
+// behaviour: HTTP first, or cURL first when AISH_GEMINI_USE_CURL is set.
+func (p *GeminiCLIProvider) transport() string {
+	switch t := strings.ToLower(strings.TrimSpace(p.cfg.Transport)); t {
+	case config.GeminiTransportSDK, config.GeminiTransportHTTP, config.GeminiTransportCURL:
+		return t
+	}
+	if shouldUseCURL() {
+		return config.GeminiTransportCURL
+	}
+	return config.GeminiTransportHTTP
+}
+
+// sdkLocation resolves the Vertex AI region for the SDK transport.
+func sdkLocation() string {
+	for _, k := range []string{config.EnvAISHGeminiLocation, "GOOGLE_CLOUD_LOCATION", "GOOGLE_CLOUD_REGION"} {
+		if s := strings.TrimSpace(os.Getenv(k)); s != "" {
+			return s
+		}
+	}
+	return defaultSDKLocation
+}
+
+// generateContentSDK sends the prompt through the official genai SDK on the Vertex AI backend,
+// authenticating with Application Default Credentials (gcloud auth application-default login,
+// GOOGLE_APPLICATION_CREDENTIALS or the metadata server).
+func (p *GeminiCLIProvider) generateContentSDK(ctx context.Context, message string) (string, error) {
+	project := strings.TrimSpace(p.cfg.Project)
+	if project == "" || project == "YOUR_GEMINI_PROJECT_ID" {
+		return "", errors.New("sdk transport requires a Google Cloud project (set providers.gemini-cli.project)")
+	}
+	model := strings.TrimSpace(p.cfg.Model)
+	if model == "" {
+		model = config.DefaultGeminiCLIModel
+	}
+
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: []string{sdkScope}})
+	if err != nil {
+		return "", fmt.Errorf("failed to find Application Default Credentials (run 'gcloud auth application-default login'): %w", err)
+	}
+	client, err := p.newSDKClient(ctx, project, creds)
+	if err != nil {
+		return "", fmt.Errorf("failed to create genai client: %w", err)
+	}
+
+	resp, err := client.Models.GenerateContent(ctx, model, genai.Text(message), nil)
+	if err != nil {
+		return "", fmt.Errorf("genai request failed: %w", err)
+	}
+	if usage := resp.UsageMetadata; usage != nil {
+		llm.ReportUsage(ctx, llm.Usage{
+			Model:            resp.ModelVersion,
+			PromptTokens:     int(usage.PromptTokenCount),
+			CompletionTokens: int(usage.CandidatesTokenCount + usage.ThoughtsTokenCount),
+		})
+	}
+	text := strings.TrimSpace(resp.Text())
+	if text == "" {
+		return "", errors.New("genai returned an empty response")
+	}
+	return text, nil
+}
+
+// newSDKClient returns a genai client that authorizes with creds the way the SDK's own client
+// would, but sends through the provider's pooled transport, so the proxy, custom TLS settings
+// and extra headers apply as they do on the REST path.
+func (p *GeminiCLIProvider) newSDKClient(ctx context.Context, project string, creds *auth.Credentials) (*genai.Client, error) {
+	opts := &httptransport.Options{Credentials: creds, BaseRoundTripper: p.client.Transport}
+	if quotaProject, err := creds.QuotaProjectID(ctx); err == nil && quotaProject != "" {
+		opts.Headers = http.Header{"X-Goog-User-Project": []string{quotaProject}}
+	}
+	httpClient, err := httptransport.NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Timeout = p.client.Timeout
+
+	// The SDK adds its own User-Agent after this one; only the first is sent, and the SDK
+	// version still goes out in x-goog-api-client
+	headers := http.Header{"User-Agent": []string{llm.UserAgent()}}
+	return genai.NewClient(ctx, &genai.ClientConfig{
+		Backend:     genai.BackendVertexAI,
+		Project:     project,
+		Location:    sdkLocation(),
+		Credentials: creds,
+		HTTPClient:  httpClient,
+		HTTPOptions: genai.HTTPOptions{Headers: headers},
+	})
+}
@@ -0,0 +1,168 @@
+package geminicli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/llm/gemini/auth"
+)
+
+// streamContent sends message and passes the response to onChunk as it arrives. Over the HTTP
+// transport the response is requested as server-sent events. The other transports, and the
+// fallback chain when streaming fails before any text arrived, answer in one piece, which is
+// passed on once it is complete.
+func (p *GeminiCLIProvider) streamContent(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	if p.transport() == config.GeminiTransportHTTP {
+		streamed := false
+		response, err := p.streamGenerateContentHTTP(ctx, message, func(delta string) {
+			streamed = true
+			onChunk(delta)
+		})
+		// Text already on screen cannot be taken back, so a stream that broke off is not retried
+		if err == nil || streamed || ctx.Err() != nil {
+			return response, err
+		}
+		if shouldDebug() {
+			fmt.Fprintf(os.Stderr, "DEBUG aish/gemini-cli streaming failed, trying without: %v\n", err)
+		}
+	}
+	response, err := p.generateContent(ctx, message)
+	if err == nil && response != "" {
+		onChunk(response)
+	}
+	return response, err
+}
+
+// streamGenerateContentHTTP is generateContentHTTP over streamGenerateContent?alt=sse, passing
+// the text of each event to onChunk.
+func (p *GeminiCLIProvider) streamGenerateContentHTTP(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	llm.ReportUsage(ctx, llm.Usage{Model: p.cfg.Model})
+	if err := auth.EnsureValidToken(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: token refresh check failed: %v\n", err)
+	}
+	if err := p.ensureProject(ctx); err != nil {
+		return "", fmt.Errorf("gemini-cli project resolution failed: %w", err)
+	}
+	targetURL, err := buildStreamGenerateContentURL(p.cfg.APIEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve API endpoint: %w", err)
+	}
+	token, err := p.getBearerToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OAuth token: %w", err)
+	}
+
+	jsonBody, err := json.Marshal(buildCloudCodeRequestBody(message, p.cfg.Model, p.cfg.Project))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if shouldDebug() {
+		fmt.Fprintf(os.Stderr, "DEBUG aish/gemini-cli HTTP stream url=%s\n", targetURL)
+		fmt.Fprintf(os.Stderr, "DEBUG aish/gemini-cli HTTP token=%s\n", maskToken(token))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return readCloudCodeStream(ctx, resp.Body, onChunk)
+}
+
+// buildStreamGenerateContentURL returns the streamGenerateContent?alt=sse form of the endpoint
+// buildGenerateContentURL resolves.
+func buildStreamGenerateContentURL(endpoint string) (string, error) {
+	target, err := buildGenerateContentURL(endpoint)
+	if err != nil {
+		return "", err
+	}
+	target, query, _ := strings.Cut(target, "?")
+	target = strings.Replace(target, ":generateContent", ":streamGenerateContent", 1)
+	if query != "" {
+		return target + "?" + query + "&alt=sse", nil
+	}
+	return target + "?alt=sse", nil
+}
+
+// readCloudCodeStream reads the server-sent events of a streamGenerateContent response, each a
+// generateContent response holding the next piece of text, and returns the whole text.
+func readCloudCodeStream(ctx context.Context, body io.Reader, onChunk llm.StreamFunc) (string, error) {
+	var builder strings.Builder
+	err := llm.ReadSSE(body, func(payload string) error {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			return fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if errObj, ok := event["error"].(map[string]any); ok {
+			msg := strings.TrimSpace(getStringFromAny(errObj["message"]))
+			sts := strings.TrimSpace(getStringFromAny(errObj["status"]))
+			if msg == "" {
+				msg = "unknown error"
+			}
+			if sts != "" {
+				msg = fmt.Sprintf("%s: %s", sts, msg)
+			}
+			return fmt.Errorf("%s", msg)
+		}
+		reportAPIUsage(ctx, []byte(payload))
+		for _, text := range eventText(event) {
+			builder.WriteString(text)
+			onChunk(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if builder.Len() == 0 {
+		return "", errors.New("invalid response format (stream)")
+	}
+	return builder.String(), nil
+}
+
+// eventText returns the text parts of the first candidate of a stream event, leaving out the
+// model's thoughts.
+func eventText(event map[string]any) []string {
+	root := event
+	if r, ok := event["response"].(map[string]any); ok {
+		root = r
+	}
+	candidates, _ := root["candidates"].([]any)
+	if len(candidates) == 0 {
+		return nil
+	}
+	candidate, _ := candidates[0].(map[string]any)
+	content, _ := candidate["content"].(map[string]any)
+	parts, _ := content["parts"].([]any)
+	var texts []string
+	for _, p := range parts {
+		part, _ := p.(map[string]any)
+		if thought, _ := part["thought"].(bool); thought {
+			continue
+		}
+		if text, ok := part["text"].(string); ok && text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
@@ -18,6 +18,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
 )
 
 // Google OAuth public client for desktop/native apps (well-known, non-confidential)
@@ -80,10 +83,22 @@ func refreshThreshold() time.Duration {
 
 // EnsureValidToken is the main entry point. It checks the token's validity
 // using an efficient cache and delegates to `gemini-cli auth refresh` if necessary.
+// Credentials of the active profile, which the provider prefers, are refreshed over HTTP
+// instead; a named profile has no others.
 func EnsureValidToken(ctx context.Context) error {
 	mu.Lock()
 	defer mu.Unlock()
 
+	profilePath, err := CredentialsPath()
+	if err != nil && !SystemCredentialsAllowed() {
+		return fmt.Errorf("auth: %v", err)
+	}
+	if err == nil {
+		if _, serr := os.Stat(profilePath); serr == nil || !SystemCredentialsAllowed() {
+			return ensureProfileToken(profilePath)
+		}
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("auth: failed to get user home directory: %v", err)
@@ -175,11 +190,58 @@ func EnsureValidToken(ctx context.Context) error {
 	return nil
 }
 
+// ensureProfileToken refreshes the profile credentials at credsPath when they expire within
+// refreshThreshold. A profile that is not signed in has nothing to refresh.
+func ensureProfileToken(credsPath string) error {
+	creds, err := loadCredentials(credsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("auth: failed to load credentials: %v", err)
+	}
+	if creds.ExpiryDate > 0 && time.Now().Add(refreshThreshold()).Before(time.UnixMilli(creds.ExpiryDate)) {
+		return nil
+	}
+	if err := httpRefreshToken(credsPath); err != nil {
+		return fmt.Errorf("auth: token refresh of profile %s failed: %v", config.Profile(), err)
+	}
+	debugf("auth: Token of profile %s refreshed via HTTP\n", config.Profile())
+	return nil
+}
+
+// TokenExpiry returns the expiry of the access token in ~/.gemini/oauth_creds.json, or in
+// the credentials of the active profile when it is a named one.
+func TokenExpiry() (time.Time, error) {
+	if !SystemCredentialsAllowed() {
+		st, err := StatusOf(config.Profile())
+		if err != nil {
+			return time.Time{}, err
+		}
+		if st.Expiry.IsZero() {
+			return time.Time{}, fmt.Errorf("%s has no expiry_date", st.Path)
+		}
+		return st.Expiry, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return time.Time{}, err
+	}
+	creds, err := loadCredentials(filepath.Join(homeDir, ".gemini", "oauth_creds.json"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if creds.ExpiryDate <= 0 {
+		return time.Time{}, errors.New("oauth_creds.json has no expiry_date")
+	}
+	return time.Unix(creds.ExpiryDate/1000, 0), nil
+}
+
 // loadCredentials reads the expiry date from oauth_creds.json
 func loadCredentials(credsPath string) (*OAuthCredentials, error) {
 	data, err := os.ReadFile(credsPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read %s: %v", credsPath, err)
+		return nil, fmt.Errorf("failed to read %s: %w", credsPath, err)
 	}
 	var creds OAuthCredentials
 	if err := json.Unmarshal(data, &creds); err != nil {
@@ -190,6 +252,9 @@ func loadCredentials(credsPath string) (*OAuthCredentials, error) {
 
 // httpRefreshToken 嘗試讀取 oauth_creds.json 並使用 refresh_token 走標準 OAuth2 Refresh Token 流程
 func httpRefreshToken(credsPath string) error {
+	if err := config.CheckSecretFile(credsPath); err != nil {
+		return err
+	}
 	// 讀取現有 oauth_creds.json 作為 map，保留未知欄位
 	raw := map[string]any{}
 	if b, err := os.ReadFile(credsPath); err == nil && len(b) > 0 {
@@ -235,7 +300,7 @@ func httpRefreshToken(credsPath string) error {
 	// If the token endpoint requires a secret, it will respond with a descriptive error
 	// which will be surfaced by formatTokenEndpointError below.
 
-	httpClient := &http.Client{Timeout: 20 * time.Second}
+	httpClient := llm.NewPooledClient(20 * time.Second)
 
 	// 先嘗試符合用戶提供樣例的 JSON 請求體
 	jsonBody := map[string]any{
@@ -310,14 +375,12 @@ func httpRefreshToken(credsPath string) error {
 	}
 	expiryDate := time.Now().Add(time.Duration(expiresIn)*time.Second - time.Minute).UnixMilli()
 
-	// 寫 access_token
-	home, _ := os.UserHomeDir()
-	accessPath := filepath.Join(home, ".gemini", "access_token")
-	if err := os.MkdirAll(filepath.Dir(accessPath), 0o755); err != nil {
-		return fmt.Errorf("failed to create ~/.gemini dir: %v", err)
-	}
-	if err := os.WriteFile(accessPath, []byte(access+"\n"), 0o600); err != nil {
-		return fmt.Errorf("failed to write access_token: %v", err)
+	// 寫 access_token：gemini-cli 在 oauth_creds.json 旁另存一份；profile 的憑證不需要
+	if filepath.Base(credsPath) != CredentialsFileName {
+		accessPath := filepath.Join(filepath.Dir(credsPath), "access_token")
+		if err := os.WriteFile(accessPath, []byte(access+"\n"), 0o600); err != nil {
+			return fmt.Errorf("failed to write access_token: %v", err)
+		}
 	}
 
 	// 依用戶提供的格式更新 oauth_creds.json：保留原欄位，覆寫 access_token、refresh_token、expiry_date，移除 expires_in
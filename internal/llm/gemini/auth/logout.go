@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+// LogoutResult describes what Logout did.
+type LogoutResult struct {
+	SignedIn bool     // The profile had credentials; false leaves nothing to do
+	Email    string   // Account that was signed out, if recorded
+	Revoked  bool     // Google revoked the token; false when it was already invalid or force skipped it
+	Removed  []string // Files deleted
+}
+
+// revokeEndpoint is where revokeToken sends tokens; tests point it at a local server.
+var revokeEndpoint = REVOKE_ENDPOINT
+
+// errTokenInvalid is returned by revokeToken for a token Google no longer knows, e.g. one
+// already revoked or expired.
+var errTokenInvalid = errors.New("token already invalid")
+
+// Logout signs the active profile out: it revokes the profile's refresh token with Google,
+// which also invalidates the access tokens issued from it, and deletes the credentials file.
+// When the token cannot be revoked (e.g. offline), the credentials are kept so that logging
+// out can be retried, unless force is set. The credentials of a gemini-cli install
+// (~/.gemini/oauth_creds.json) are gemini-cli's own and are left alone.
+func Logout(ctx context.Context, force bool) (LogoutResult, error) {
+	var res LogoutResult
+	path, err := CredentialsPath()
+	if err != nil {
+		return res, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	res.SignedIn = true
+	m := map[string]any{}
+	_ = json.Unmarshal(data, &m)
+	res.Email = strings.TrimSpace(getString(m, "email"))
+	access := strings.TrimSpace(getString(m, "access_token"))
+
+	// The refresh token is the long-lived one; an access token only matters without it
+	token := strings.TrimSpace(getString(m, "refresh_token"))
+	if token == "" {
+		token = access
+	}
+	if token != "" {
+		switch err := revokeToken(ctx, token); {
+		case err == nil:
+			res.Revoked = true
+		case errors.Is(err, errTokenInvalid):
+		case !force:
+			return res, err
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return res, fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	res.Removed = append(res.Removed, path)
+
+	// Refreshing these credentials in older versions also wrote the access token for gemini-cli
+	if access != "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			accessPath := filepath.Join(home, ".gemini", "access_token")
+			if b, err := os.ReadFile(accessPath); err == nil && strings.TrimSpace(string(b)) == access {
+				if os.Remove(accessPath) == nil {
+					res.Removed = append(res.Removed, accessPath)
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// revokeToken asks Google to revoke token.
+func revokeToken(ctx context.Context, token string) error {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := llm.NewPooledClient(20 * time.Second).Do(req)
+	if err != nil {
+		return fmt.Errorf("token revocation failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	var errObj struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal(body, &errObj) == nil && errObj.Error == "invalid_token" {
+		return errTokenInvalid
+	}
+	desc := strings.TrimSpace(errObj.ErrorDescription)
+	if desc == "" {
+		desc = strings.TrimSpace(string(body))
+	}
+	return fmt.Errorf("token revocation failed: HTTP %d - %s", resp.StatusCode, desc)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// signedInProfile writes credentials for the default profile in a temporary config dir and
+// sends revocations to a server answering with status and body.
+func signedInProfile(t *testing.T, status int, body string) (credsPath string, revoked *[]string) {
+	t.Helper()
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	path, err := CredentialsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	creds := `{"access_token":"ya29.access","refresh_token":"1//refresh","email":"dev@example.com"}`
+	if err := os.WriteFile(path, []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		tokens = append(tokens, r.PostForm.Get("token"))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	old := revokeEndpoint
+	revokeEndpoint = srv.URL
+	t.Cleanup(func() { revokeEndpoint = old })
+	return path, &tokens
+}
+
+func TestLogoutRevokesAndRemoves(t *testing.T) {
+	path, tokens := signedInProfile(t, http.StatusOK, "")
+	home, _ := os.UserHomeDir()
+	accessPath := filepath.Join(home, ".gemini", "access_token")
+	if err := os.MkdirAll(filepath.Dir(accessPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(accessPath, []byte("ya29.access\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Logout(t.Context(), false)
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if !res.SignedIn || !res.Revoked || res.Email != "dev@example.com" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if len(*tokens) != 1 || (*tokens)[0] != "1//refresh" {
+		t.Errorf("revoked %v, want the refresh token", *tokens)
+	}
+	for _, p := range []string{path, accessPath} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", p, err)
+		}
+	}
+	if len(res.Removed) != 2 {
+		t.Errorf("removed %v", res.Removed)
+	}
+}
+
+func TestLogoutAlreadyInvalidToken(t *testing.T) {
+	path, _ := signedInProfile(t, http.StatusBadRequest, `{"error":"invalid_token","error_description":"Token expired or revoked"}`)
+	res, err := Logout(t.Context(), false)
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if res.Revoked || len(res.Removed) != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("credentials file left behind: %v", err)
+	}
+}
+
+func TestLogoutRevocationFailure(t *testing.T) {
+	path, _ := signedInProfile(t, http.StatusInternalServerError, "backend error")
+	if _, err := Logout(t.Context(), false); err == nil {
+		t.Fatal("expected the failed revocation to be reported")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("credentials deleted although the token was not revoked: %v", err)
+	}
+
+	res, err := Logout(t.Context(), true)
+	if err != nil {
+		t.Fatalf("forced Logout: %v", err)
+	}
+	if res.Revoked || len(res.Removed) != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("credentials file left behind by --force: %v", err)
+	}
+}
+
+func TestLogoutNotSignedIn(t *testing.T) {
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	res, err := Logout(t.Context(), false)
+	if err != nil || res.SignedIn {
+		t.Errorf("got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// CredentialsFileName is the file the web sign-in writes in a profile's directory.
+const CredentialsFileName = "gemini_oauth_creds.json"
+
+// CredentialsPath returns the OAuth credentials file of the active profile (config.Profile).
+func CredentialsPath() (string, error) {
+	return CredentialsPathFor(config.Profile())
+}
+
+// CredentialsPathFor returns the OAuth credentials file of profile.
+func CredentialsPathFor(profile string) (string, error) {
+	dir, err := config.ProfileDir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, CredentialsFileName), nil
+}
+
+// SystemCredentialsAllowed reports whether the credentials of a gemini-cli install
+// (~/.gemini) may stand in for missing aish credentials. Only the default profile uses them:
+// a named profile is bound to one Google account and must not borrow another's sign-in.
+func SystemCredentialsAllowed() bool {
+	return config.Profile() == config.DefaultProfile
+}
+
+// ProfileStatus describes the sign-in of one profile.
+type ProfileStatus struct {
+	Profile   string
+	Path      string
+	SignedIn  bool      // The credentials file exists and holds a token
+	Email     string    // Account bound at sign-in; empty for credentials saved before it was recorded
+	ProjectID string    // Project detected at sign-in or chosen with 'aish config project'
+	Expiry    time.Time // Zero when the file records none
+}
+
+// StatusOf reads the credentials of profile without contacting Google.
+func StatusOf(profile string) (ProfileStatus, error) {
+	path, err := CredentialsPathFor(profile)
+	if err != nil {
+		return ProfileStatus{}, err
+	}
+	st := ProfileStatus{Profile: profile, Path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return st, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	st.SignedIn = strings.TrimSpace(getString(m, "access_token")) != "" || strings.TrimSpace(getString(m, "refresh_token")) != ""
+	st.Email = strings.TrimSpace(getString(m, "email"))
+	st.ProjectID = strings.TrimSpace(getString(m, "project_id"))
+	if ms := getNumber(m, "expiry_date"); ms > 0 {
+		st.Expiry = time.UnixMilli(int64(ms))
+	}
+	return st, nil
+}
+
+// BindEmail looks up the account of the active profile's token and records it in the
+// credentials file, for credentials saved before sign-in recorded it.
+func BindEmail(ctx context.Context) (string, error) {
+	email, err := GetAuthenticatedEmail(ctx)
+	if err != nil {
+		return "", err
+	}
+	path, err := CredentialsPath()
+	if err != nil {
+		return email, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return email, nil
+	}
+	m := map[string]any{}
+	if json.Unmarshal(data, &m) != nil {
+		return email, nil
+	}
+	m["email"] = email
+	if out, err := json.MarshalIndent(m, "", "  "); err == nil {
+		_ = os.WriteFile(path, out, 0600)
+	}
+	return email, nil
+}
@@ -15,6 +15,7 @@ import (
     "time"
 
     "github.com/TonnyWong1052/aish/internal/config"
+    "github.com/TonnyWong1052/aish/internal/llm"
 )
 
 // GCPProject 表示來自 Cloud Resource Manager v1 的專案資料
@@ -41,7 +42,7 @@ func ListActiveProjects(ctx context.Context) ([]GCPProject, error) {
     query := url.Values{}
     query.Set("filter", "lifecycleState:ACTIVE")
 
-    client := &http.Client{}
+    client := llm.NewPooledClient(20 * time.Second)
     var projects []GCPProject
     nextToken := ""
 
@@ -106,7 +107,7 @@ func SearchProjectsV3(ctx context.Context) ([]GCPProject, error) {
 
     endpoint := "https://cloudresourcemanager.googleapis.com/v3/projects:search"
 
-    client := &http.Client{Timeout: 20 * time.Second}
+    client := llm.NewPooledClient(20 * time.Second)
     var projects []GCPProject
     pageToken := ""
 
@@ -162,19 +163,30 @@ func SearchProjectsV3(ctx context.Context) ([]GCPProject, error) {
 // 若不存在，再回退至使用者家目錄的 ~/.gemini/oauth_creds.json 或 access_token。
 func getAccessTokenForGCP() (string, error) {
     // 1) 先找 AISH 設定下的 gemini_oauth_creds.json
+    if path, err := CredentialsPath(); err == nil {
+        if err := config.CheckSecretFile(path); err != nil {
+            return "", err
+        }
+    }
     if token, ok := readAccessTokenFromAishConfig(); ok {
         return token, nil
     }
 
-    // 2) 回退 ~/.gemini/oauth_creds.json
+    // 2) 回退 ~/.gemini/oauth_creds.json（僅限預設 profile，具名 profile 不借用其他帳號）
     home, _ := os.UserHomeDir()
-    if home != "" {
+    if home != "" && SystemCredentialsAllowed() {
         oauthPath := filepath.Join(home, ".gemini", "oauth_creds.json")
+        accessPath := filepath.Join(home, ".gemini", "access_token")
+        for _, path := range []string{oauthPath, accessPath} {
+            if err := config.CheckSecretFile(path); err != nil {
+                return "", err
+            }
+        }
         if token, ok := readAccessTokenFromJSON(oauthPath); ok {
             return token, nil
         }
         // 3) 最後嘗試 ~/.gemini/access_token 純文字
-        if b, err := os.ReadFile(filepath.Join(home, ".gemini", "access_token")); err == nil {
+        if b, err := os.ReadFile(accessPath); err == nil {
             s := strings.TrimSpace(string(b))
             if s != "" {
                 return s, nil
@@ -186,12 +198,10 @@ func getAccessTokenForGCP() (string, error) {
 }
 
 func readAccessTokenFromAishConfig() (string, bool) {
-    cfgPath, err := config.GetConfigPath()
+    path, err := CredentialsPath()
     if err != nil {
         return "", false
     }
-    dir := filepath.Dir(cfgPath)
-    path := filepath.Join(dir, "gemini_oauth_creds.json")
     return readAccessTokenFromJSON(path)
 }
 
@@ -219,7 +229,7 @@ func AutoDetectProjectID(ctx context.Context) (string, error) {
     {
         req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/project/project-id", nil)
         req.Header.Set("Metadata-Flavor", "Google")
-        client := &http.Client{Timeout: 1 * time.Second}
+        client := &http.Client{Timeout: 1 * time.Second} // The metadata server is local to the VM, never behind user_preferences.proxy
         if resp, err := client.Do(req); err == nil {
             b, _ := io.ReadAll(resp.Body)
             _ = resp.Body.Close()
@@ -258,7 +268,7 @@ func GetProject(ctx context.Context, projectID string) (*GCPProject, error) {
         return nil, err
     }
     req.Header.Set("Authorization", "Bearer "+token)
-    resp, err := (&http.Client{}).Do(req)
+    resp, err := llm.NewPooledClient(20 * time.Second).Do(req)
     if err != nil {
         return nil, err
     }
@@ -304,7 +314,7 @@ func EnableRequiredAPIs(ctx context.Context, projectID string, services []string
     req.Header.Set("Authorization", "Bearer "+token)
     req.Header.Set("Content-Type", "application/json")
 
-    resp, err := (&http.Client{}).Do(req)
+    resp, err := llm.NewPooledClient(30 * time.Second).Do(req)
     if err != nil {
         return err
     }
@@ -8,6 +8,8 @@ import (
     "net/http"
     "strings"
     "time"
+
+    "github.com/TonnyWong1052/aish/internal/llm"
 )
 
 // GetAuthenticatedEmail returns the Google account email associated with the
@@ -18,11 +20,15 @@ func GetAuthenticatedEmail(ctx context.Context) (string, error) {
     if err != nil {
         return "", err
     }
+    return emailForToken(ctx, token)
+}
 
+// emailForToken asks Google's UserInfo endpoint for the account of an access token.
+func emailForToken(ctx context.Context, token string) (string, error) {
     req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.googleapis.com/oauth2/v3/userinfo", nil)
     req.Header.Set("Authorization", "Bearer "+token)
 
-    client := &http.Client{Timeout: 10 * time.Second}
+    client := llm.NewPooledClient(10 * time.Second)
     resp, err := client.Do(req)
     if err != nil {
         return "", err
@@ -16,7 +16,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
 	"github.com/google/uuid"
 )
 
@@ -27,6 +27,8 @@ const (
 	AUTH_ENDPOINT = "https://accounts.google.com/o/oauth2/auth"
 	// TOKEN_ENDPOINT is the Google OAuth 2.0 token endpoint.
 	TOKEN_ENDPOINT = "https://oauth2.googleapis.com/token"
+	// REVOKE_ENDPOINT is the Google OAuth 2.0 token revocation endpoint.
+	REVOKE_ENDPOINT = "https://oauth2.googleapis.com/revoke"
 )
 
 var (
@@ -152,7 +154,7 @@ func exchangeCodeForToken(ctx context.Context, code string, port int) error {
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := llm.NewPooledClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send token request: %w", err)
@@ -192,15 +194,15 @@ func exchangeCodeForToken(ctx context.Context, code string, port int) error {
 	return nil
 }
 
-// saveTokens saves the access and refresh tokens to the aish config directory.
+// saveTokens saves the access and refresh tokens to the credentials file of the active
+// profile (see CredentialsPath), with the account they belong to.
 func saveTokens(tokens map[string]interface{}) error {
-	configPath, err := config.GetConfigPath()
+	credsPath, err := CredentialsPath()
 	if err != nil {
 		return fmt.Errorf("failed to get aish config path: %w", err)
 	}
-	configDir := filepath.Dir(configPath)
 
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(credsPath), 0700); err != nil {
 		return fmt.Errorf("failed to create aish config directory: %w", err)
 	}
 
@@ -224,8 +226,14 @@ func saveTokens(tokens map[string]interface{}) error {
 		fmt.Fprintf(os.Stderr, "          • Or: gcloud config set project YOUR_PROJECT_ID\n")
 	}
 
+	// Bind the account, so 'aish auth status' can tell the profiles apart
+	if accessToken, ok := tokens["access_token"].(string); ok && accessToken != "" {
+		if email, err := emailForToken(ctx, accessToken); err == nil {
+			tokens["email"] = email
+		}
+	}
+
 	// Write to gemini_oauth_creds.json.
-	credsPath := filepath.Join(configDir, "gemini_oauth_creds.json")
 	credsData, err := json.MarshalIndent(tokens, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal gemini_oauth_creds.json: %w", err)
@@ -308,7 +316,7 @@ func detectAndSaveProjectID(ctx context.Context, tokens map[string]interface{})
 
 // listProjectsWithToken lists projects using a specific access token
 func listProjectsWithToken(ctx context.Context, accessToken string) ([]GCPProject, error) {
-	client := &http.Client{Timeout: 20 * time.Second}
+	client := llm.NewPooledClient(20 * time.Second)
 
 	// Use Cloud Resource Manager v1 API with lifecycleState filter
 	endpoint := "https://cloudresourcemanager.googleapis.com/v1/projects?filter=lifecycleState:ACTIVE"
@@ -354,7 +362,7 @@ func enableGeminiAPIs(ctx context.Context, projectID string, accessToken string)
 	fmt.Fprintf(os.Stderr, " INFO  Enabling required Google Cloud APIs for project %s...\n", projectID)
 
 	// Use Google Service Usage API to enable services
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := llm.NewPooledClient(30 * time.Second)
 
 	for _, api := range requiredAPIs {
 		fmt.Fprintf(os.Stderr, "       • Enabling %s...\n", api)
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"text/template"
@@ -45,14 +46,27 @@ type GeminiApiResponse struct {
 }
 
 type GeminiGenerationResponse struct {
-	Candidates []GeminiCandidate `json:"candidates"`
-	Error      *struct {
+	Candidates     []GeminiCandidate `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"` // Set when the prompt itself was blocked
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *UsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string         `json:"modelVersion,omitempty"`
+	Error         *struct {
 		Code    int    `json:"code"`
 		Message string `json:"message"`
 		Status  string `json:"status"`
 	} `json:"error,omitempty"`
 }
 
+// UsageMetadata is the token count of a generation; a streamed one carries the running total
+// in each event.
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"` // Billed as output
+}
+
 type GeminiModelsResponse struct {
 	Models []struct {
 		Name                       string   `json:"name"`
@@ -81,9 +95,7 @@ type GeminiProvider struct {
 
 // NewProvider creates a new GeminiProvider.
 func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	client := llm.WithHeaders(llm.NewPooledClient(30*time.Second), cfg.ExtraHeaders)
 
 	return &GeminiProvider{
 		cfg:    cfg,
@@ -98,8 +110,13 @@ func init() {
 
 // GetSuggestion implements the llm.Provider interface.
 func (p *GeminiProvider) GetSuggestion(ctx context.Context, capturedContext llm.CapturedContext, lang string) (*llm.Suggestion, error) {
+	return p.GetSuggestionStream(ctx, capturedContext, lang, nil)
+}
+
+// GetSuggestionStream implements the llm.Provider interface.
+func (p *GeminiProvider) GetSuggestionStream(ctx context.Context, capturedContext llm.CapturedContext, lang string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
 	// Get the prompt template
-	promptTemplate, err := p.pm.GetPrompt("get_suggestion", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("get_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prompt template: %w", err)
 	}
@@ -111,7 +128,7 @@ func (p *GeminiProvider) GetSuggestion(ctx context.Context, capturedContext llm.
 		Stderr   string
 		ExitCode int
 	}{
-		Command:  capturedContext.Command,
+		Command:  capturedContext.PromptCommand(),
 		Stdout:   capturedContext.Stdout,
 		Stderr:   capturedContext.Stderr,
 		ExitCode: capturedContext.ExitCode,
@@ -124,40 +141,26 @@ func (p *GeminiProvider) GetSuggestion(ctx context.Context, capturedContext llm.
 	}
 
 	// Make API request
-	response, err := p.generateContent(ctx, tpl.String())
+	response, err := p.exchange(ctx, tpl.String(), onChunk)
 	if err != nil {
 		return nil, fmt.Errorf("Gemini API request failed: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Prefer JSON output
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Explanation      string `json:"explanation"`
-		Command          string `json:"command"`
-		CorrectedCommand string `json:"corrected_command"`
-		CorrectedCamel   string `json:"correctedCommand"`
-	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil {
-		cmd := obj.Command
-		if cmd == "" {
-			cmd = obj.CorrectedCommand
-		}
-		if cmd == "" {
-			cmd = obj.CorrectedCamel
-		}
-		if strings.TrimSpace(cmd) != "" && strings.TrimSpace(obj.Explanation) != "" {
-			return &llm.Suggestion{Explanation: strings.TrimSpace(obj.Explanation), CorrectedCommand: strings.TrimSpace(cmd)}, nil
-		}
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
 	}
 
-	// Fallback: heuristic parser
+	// Fallback: heuristic parsing
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return p.parseSuggestionResponse(response)
 }
 
 // GetEnhancedSuggestion implements the llm.Provider interface with enhanced context.
 func (p *GeminiProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx llm.EnhancedCapturedContext, lang string) (*llm.Suggestion, error) {
 	// Get the enhanced prompt template
-	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
 		// Fall back to regular suggestion if enhanced template doesn't exist
 		return p.GetSuggestion(ctx, enhancedCtx.CapturedContext, lang)
@@ -182,46 +185,37 @@ func (p *GeminiProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx
 	}
 
 	// Make API request
-	response, err := p.generateContent(ctx, tpl.String())
+	response, err := llm.Exchange(ctx, tpl.String(), p.generateContent)
 	if err != nil {
 		return nil, fmt.Errorf("Gemini API request failed for enhanced suggestion: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Prefer JSON output (same parsing logic as regular GetSuggestion)
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Explanation      string `json:"explanation"`
-		Command          string `json:"command"`
-		CorrectedCommand string `json:"corrected_command"`
-		CorrectedCamel   string `json:"correctedCommand"`
-	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil {
-		cmd := obj.Command
-		if cmd == "" {
-			cmd = obj.CorrectedCommand
-		}
-		if cmd == "" {
-			cmd = obj.CorrectedCamel
-		}
-		if strings.TrimSpace(cmd) != "" && strings.TrimSpace(obj.Explanation) != "" {
-			return &llm.Suggestion{Explanation: strings.TrimSpace(obj.Explanation), CorrectedCommand: strings.TrimSpace(cmd)}, nil
-		}
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
 	}
 
-	// Fallback: heuristic parser
+	// Fallback: heuristic parsing
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return p.parseSuggestionResponse(response)
 }
 
 // GenerateCommand implements the llm.Provider interface.
 func (p *GeminiProvider) GenerateCommand(ctx context.Context, promptText string, lang string) (string, error) {
+	return p.GenerateCommandStream(ctx, promptText, lang, nil)
+}
+
+// GenerateCommandStream implements the llm.Provider interface.
+func (p *GeminiProvider) GenerateCommandStream(ctx context.Context, promptText string, lang string, onChunk llm.StreamFunc) (string, error) {
 	// Get the prompt template
-	promptTemplate, err := p.pm.GetPrompt("generate_command", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("generate_command", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt template: %w", err)
 	}
 
 	// Execute template with prompt data
-	data := struct{ Prompt string }{Prompt: promptText}
+	data := llm.NewCommandPromptData(ctx, promptText)
 	var tpl bytes.Buffer
 	t := template.Must(template.New("prompt").Parse(promptTemplate))
 	if err := t.Execute(&tpl, data); err != nil {
@@ -229,21 +223,19 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, promptText string,
 	}
 
 	// Make API request
-	response, err := p.generateContent(ctx, tpl.String())
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), onChunk)
 	if err != nil {
 		return "", fmt.Errorf("Gemini API request failed: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Prefer JSON output
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Command string `json:"command"`
-	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil && strings.TrimSpace(obj.Command) != "" {
-		return strings.TrimSpace(obj.Command), nil
+	// Prefer JSON output, repaired if need be
+	if cmd, ok := llm.DecodeCommand(ctx, response); ok {
+		return cmd, nil
 	}
 
 	// Fallback: previous heuristics
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	command := strings.TrimSpace(response)
 	command = strings.TrimPrefix(command, "`")
 	command = strings.TrimSuffix(command, "`")
@@ -252,6 +244,37 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, promptText string,
 	return command, nil
 }
 
+// GeneratePlan implements llm.Planner: the commands of a multi-step task, in order.
+func (p *GeminiProvider) GeneratePlan(ctx context.Context, promptText string, lang string) (*llm.Plan, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_plan", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Gemini API request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if plan, ok := llm.DecodePlan(ctx, response); ok {
+		return plan, nil
+	}
+	return nil, llm.NoCommandError(response)
+}
+
+// Warmup opens a connection to the API host ahead of the first request (llm.Warmer).
+func (p *GeminiProvider) Warmup(ctx context.Context) error {
+	return llm.WarmupEndpoint(ctx, p.client, p.cfg.APIEndpoint)
+}
+
 // VerifyConnection implements the llm.Provider interface.
 func (p *GeminiProvider) VerifyConnection(ctx context.Context) ([]string, error) {
 	if p.cfg.APIKey == "" || p.cfg.APIKey == "YOUR_GEMINI_API_KEY" {
@@ -307,8 +330,103 @@ func (p *GeminiProvider) VerifyConnection(ctx context.Context) ([]string, error)
 	return models, nil
 }
 
+// exchange sends message, streaming the response to onChunk when it is set.
+func (p *GeminiProvider) exchange(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	if onChunk == nil {
+		return llm.Exchange(ctx, message, p.generateContent)
+	}
+	return llm.ExchangeStream(ctx, message, p.streamGenerateContent, onChunk)
+}
+
 // generateContent makes a content generation request to Gemini API
 func (p *GeminiProvider) generateContent(ctx context.Context, message string) (string, error) {
+	resp, err := p.postGenerate(ctx, message, "generateContent", "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var apiResponse GeminiApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	p.reportUsage(ctx, apiResponse.Response)
+	text, err := responseText(apiResponse.Response)
+	return text, llm.WithRetryAfter(err, resp.Header)
+}
+
+// streamGenerateContent requests the response as server-sent events and passes the text of
+// each event to onChunk as it arrives.
+func (p *GeminiProvider) streamGenerateContent(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	resp, err := p.postGenerate(ctx, message, "streamGenerateContent", "alt=sse")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", llm.WithRetryAfter(fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body))), resp.Header)
+	}
+
+	var builder strings.Builder
+	err = llm.ReadSSE(resp.Body, func(payload string) error {
+		var event geminiStreamEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		generation := event.generation()
+		if generation.Error != nil {
+			return fmt.Errorf("API error: %s", generation.Error.Message)
+		}
+		p.reportUsage(ctx, generation)
+		for _, c := range generation.Candidates {
+			for _, part := range c.Content.Parts {
+				if part.Text != "" {
+					builder.WriteString(part.Text)
+					onChunk(part.Text)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if builder.Len() == 0 {
+		return "", errors.New("no response candidates returned")
+	}
+	return builder.String(), nil
+}
+
+// reportUsage passes the model and token counts of generation to llm.ReportUsage.
+func (p *GeminiProvider) reportUsage(ctx context.Context, generation GeminiGenerationResponse) {
+	u := llm.Usage{Model: generation.ModelVersion}
+	if u.Model == "" {
+		u.Model = p.cfg.Model
+	}
+	if m := generation.UsageMetadata; m != nil {
+		u.PromptTokens, u.CompletionTokens = m.PromptTokenCount, m.CandidatesTokenCount+m.ThoughtsTokenCount
+	}
+	llm.ReportUsage(ctx, u)
+}
+
+// geminiStreamEvent is one event of a streamed response, with or without the "response"
+// wrapper around the generation.
+type geminiStreamEvent struct {
+	GeminiGenerationResponse
+	Response *GeminiGenerationResponse `json:"response"`
+}
+
+func (e geminiStreamEvent) generation() GeminiGenerationResponse {
+	if e.Response != nil {
+		return *e.Response
+	}
+	return e.GeminiGenerationResponse
+}
+
+// postGenerate sends message to the model's method endpoint (generateContent or
+// streamGenerateContent) with the extra query parameters. The caller closes the body.
+func (p *GeminiProvider) postGenerate(ctx context.Context, message, method, query string) (*http.Response, error) {
 	// Construct the API URL
 	modelName := p.cfg.Model
 	if modelName == "" {
@@ -318,11 +436,17 @@ func (p *GeminiProvider) generateContent(ctx context.Context, message string) (s
 	var apiURL string
 	endpoint := strings.TrimSuffix(p.cfg.APIEndpoint, "/")
 	if p.cfg.Project != "" {
-		apiURL = fmt.Sprintf("%s/projects/%s/models/%s:generateContent",
-			endpoint, p.cfg.Project, modelName)
+		apiURL = fmt.Sprintf("%s/projects/%s/models/%s:%s",
+			endpoint, p.cfg.Project, modelName, method)
+		if query != "" {
+			apiURL += "?" + query
+		}
 	} else {
-		apiURL = fmt.Sprintf("%s/models/%s:generateContent?key=%s",
-			endpoint, modelName, p.cfg.APIKey)
+		apiURL = fmt.Sprintf("%s/models/%s:%s?key=%s",
+			endpoint, modelName, method, p.cfg.APIKey)
+		if query != "" {
+			apiURL += "&" + query
+		}
 	}
 
 	reqBody := GeminiGenerationRequest{
@@ -339,35 +463,41 @@ func (p *GeminiProvider) generateContent(ctx context.Context, message string) (s
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	return resp, nil
+}
 
-	var apiResponse GeminiApiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
+// blockedFinishReasons are the finish reasons of a candidate withheld by Gemini's safety filters.
+var blockedFinishReasons = map[string]bool{"SAFETY": true, "PROHIBITED_CONTENT": true, "BLOCKLIST": true, "SPII": true}
 
-	completion := apiResponse.Response
+// responseText returns the text of the first candidate of completion.
+func responseText(completion GeminiGenerationResponse) (string, error) {
 	if completion.Error != nil {
 		return "", fmt.Errorf("API error: %s", completion.Error.Message)
 	}
+	if f := completion.PromptFeedback; f != nil && f.BlockReason != "" {
+		return "", llm.RefusalError("prompt blocked (blockReason " + f.BlockReason + ")")
+	}
 
 	if len(completion.Candidates) == 0 {
 		return "", errors.New("no response candidates returned")
 	}
+	if reason := completion.Candidates[0].FinishReason; blockedFinishReasons[reason] {
+		return "", llm.RefusalError("response blocked (finishReason " + reason + ")")
+	}
 
 	if len(completion.Candidates[0].Content.Parts) == 0 {
 		return "", errors.New("no content parts in response")
@@ -481,31 +611,3 @@ func (p *GeminiProvider) parseSuggestionResponse(response string) (*llm.Suggesti
 		CorrectedCommand: correctedCommand,
 	}, nil
 }
-
-// mapLanguage maps user language preferences to template language codes
-func mapLanguage(lang string) string {
-	switch strings.ToLower(lang) {
-	case "chinese", "zh", "中文", "繁體中文":
-		return "zh-TW"
-	case "english", "en":
-		return "en"
-	default:
-		return "en"
-	}
-}
-
-// stripCodeFences removes common markdown code fences and json hints.
-func stripCodeFences(s string) string {
-	s = strings.TrimSpace(s)
-	if strings.HasPrefix(s, "```") {
-		s = strings.TrimPrefix(s, "```")
-		s = strings.TrimSpace(s)
-		if strings.HasPrefix(strings.ToLower(s), "json") {
-			s = strings.TrimSpace(s[4:])
-		}
-		if idx := strings.LastIndex(s, "```"); idx != -1 {
-			s = s[:idx]
-		}
-	}
-	return strings.TrimSpace(strings.Trim(s, "`"))
-}
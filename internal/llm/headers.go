@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// version is the aish version reported in the User-Agent; see SetVersion.
+var version = "dev"
+
+// SetVersion sets the aish version UserAgent reports. The command sets it once at startup,
+// before any provider is built.
+func SetVersion(v string) {
+	if v = strings.TrimSpace(v); v != "" {
+		version = v
+	}
+}
+
+// UserAgent returns the User-Agent aish sends to providers, e.g. "aish/v0.0.2 (darwin; arm64)",
+// so gateways and provider dashboards can tell its traffic apart from other clients.
+func UserAgent() string {
+	return fmt.Sprintf("aish/%s (%s; %s)", version, runtime.GOOS, runtime.GOARCH)
+}
+
+// WithHeaders returns a copy of client whose requests carry UserAgent, unless a request sets
+// its own, and the extra headers, which replace any of the same name a request sets. Corporate
+// gateways route and authorize on such headers (providers.<name>.extra_headers).
+func WithHeaders(client *http.Client, extra map[string]string) *http.Client {
+	c := *client
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.Transport = &headerTransport{base: base, extra: extra}
+	return &c
+}
+
+// headerTransport adds the headers of WithHeaders to each request.
+type headerTransport struct {
+	base  http.RoundTripper
+	extra map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent())
+	}
+	for name, value := range t.extra {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// ValidateHeader checks a header for providers.<name>.extra_headers: the name must be an HTTP
+// token and the value must not break the header block.
+func ValidateHeader(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("empty header name")
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("header %s: value contains a line break", name)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	SetVersion("v1.2.3")
+	defer SetVersion("dev")
+
+	client := WithHeaders(srv.Client(), map[string]string{"X-Team": "infra", "Authorization": "Bearer gateway"})
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer sk-provider")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if ua := got.Get("User-Agent"); !strings.HasPrefix(ua, "aish/v1.2.3 (") {
+		t.Errorf("unexpected User-Agent %q", ua)
+	}
+	if got.Get("X-Team") != "infra" || got.Get("Authorization") != "Bearer gateway" {
+		t.Errorf("extra headers not applied: %v", got)
+	}
+	if req.Header.Get("Authorization") != "Bearer sk-provider" {
+		t.Error("the caller's request was modified")
+	}
+
+	// A request's own User-Agent is kept unless the extra headers name one
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("User-Agent", "curl/8.7.1")
+	if resp, err = WithHeaders(srv.Client(), nil).Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ua := got.Get("User-Agent"); ua != "curl/8.7.1" {
+		t.Errorf("request User-Agent replaced: %q", ua)
+	}
+	if resp, err = WithHeaders(srv.Client(), map[string]string{"User-Agent": "corp-proxy/1"}).Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ua := got.Get("User-Agent"); ua != "corp-proxy/1" {
+		t.Errorf("configured User-Agent not applied: %q", ua)
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	for _, h := range [][2]string{{"X-Team", "infra"}, {"Ocp-Apim-Subscription-Key", "abc=="}} {
+		if err := ValidateHeader(h[0], h[1]); err != nil {
+			t.Errorf("%s: %v", h[0], err)
+		}
+	}
+	for _, h := range [][2]string{{"", "x"}, {"X Team", "x"}, {"X-Team:", "x"}, {"X-Team", "a\r\nInjected: 1"}} {
+		if err := ValidateHeader(h[0], h[1]); err == nil {
+			t.Errorf("%q: %q accepted", h[0], h[1])
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+)
+
+const (
+	// HealthFileName is the provider health state file inside the aish config directory.
+	HealthFileName = "provider_health.json"
+	// UnhealthyTTL is how long a failed provider is skipped by the shell hook before it is tried again.
+	UnhealthyTTL = 5 * time.Minute
+	// AuthFailureTTL is longer: expired credentials rarely fix themselves within minutes.
+	AuthFailureTTL = time.Hour
+	// RateLimitTTL is shorter: rate limits are per minute for most providers.
+	RateLimitTTL = time.Minute
+)
+
+// ProviderHealth is the last-known state of one provider.
+type ProviderHealth struct {
+	Healthy       bool      `json:"healthy"`
+	CheckedAt     time.Time `json:"checked_at"`
+	Code          string    `json:"code,omitempty"`
+	Kind          string    `json:"kind,omitempty"` // ErrorType of the failure
+	Error         string    `json:"error,omitempty"`
+	Failures      int       `json:"consecutive_failures,omitempty"`
+	AuthExpiresAt time.Time `json:"auth_expires_at,omitempty"`
+	ResetAt       time.Time `json:"reset_at,omitempty"` // When the rate limit or quota that failed resets, if the provider said
+	Timing        string    `json:"timing,omitempty"`   // HTTP timing of the last connection check (FormatHTTPTiming)
+}
+
+// HealthState records provider health across invocations so the capture hook can avoid
+// waiting on a provider that just failed.
+type HealthState struct {
+	Providers map[string]ProviderHealth `json:"providers"`
+	path      string
+}
+
+// AuthExpiryReporter is implemented by providers whose credentials expire (OAuth tokens).
+type AuthExpiryReporter interface {
+	AuthExpiry() (time.Time, bool)
+}
+
+// HealthStatePath returns the location of the provider health state file.
+func HealthStatePath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, HealthFileName), nil
+}
+
+// LoadHealthState reads the health state from path. A missing or corrupt file yields an empty
+// state: health is only an optimization and must never block a request.
+func LoadHealthState(path string) *HealthState {
+	s := &HealthState{Providers: map[string]ProviderHealth{}, path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	if json.Unmarshal(data, s) != nil || s.Providers == nil {
+		s.Providers = map[string]ProviderHealth{}
+	}
+	return s
+}
+
+// Save writes the state back to the file it was loaded from.
+func (s *HealthState) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// RecordSuccess marks a provider healthy and remembers when its credentials expire, if known.
+func (s *HealthState) RecordSuccess(name string, provider Provider, now time.Time) {
+	h := ProviderHealth{Healthy: true, CheckedAt: now}
+	if r, ok := provider.(AuthExpiryReporter); ok {
+		if exp, ok := r.AuthExpiry(); ok {
+			h.AuthExpiresAt = exp
+		}
+	}
+	s.Providers[name] = h
+}
+
+// RecordFailure marks a provider unavailable because of err.
+func (s *HealthState) RecordFailure(name string, err error, now time.Time) {
+	prev := s.Providers[name]
+	h := ProviderHealth{
+		Healthy:       false,
+		CheckedAt:     now,
+		Code:          string(ErrorCodeOf(name, err)),
+		Failures:      prev.Failures + 1,
+		AuthExpiresAt: prev.AuthExpiresAt,
+	}
+	if err != nil {
+		h.Kind = string(Classify(name, err).Type)
+		h.Error = err.Error()
+		if h.Kind == string(RateLimitError) || h.Kind == string(QuotaExceededError) {
+			h.ResetAt, _ = resetAt(err, now)
+		}
+	}
+	s.Providers[name] = h
+}
+
+// ShouldSkip reports whether a provider was marked unavailable recently enough that a request
+// would most likely fail again, and returns the recorded health for messaging. A failure of
+// the request itself (see LLMError.IsRequestSpecific) says nothing about availability, so it
+// never causes a skip.
+func (s *HealthState) ShouldSkip(name string, now time.Time) (ProviderHealth, bool) {
+	h, ok := s.Providers[name]
+	if !ok || h.Healthy || h.Code == string(aerrors.ErrProviderResponse) {
+		return h, false
+	}
+	if h.Kind != "" && (&LLMError{Type: ErrorType(h.Kind)}).IsRequestSpecific() {
+		return h, false
+	}
+	return h, now.Sub(h.CheckedAt) < h.SkipTTL()
+}
+
+// SkipTTL returns how long after a failure like h's the provider is skipped: until the reset
+// the provider named, if it did.
+func (h ProviderHealth) SkipTTL() time.Duration {
+	switch {
+	case !h.ResetAt.IsZero():
+		return h.ResetAt.Sub(h.CheckedAt)
+	case h.Code == string(aerrors.ErrProviderAuth):
+		return AuthFailureTTL
+	case h.Kind == string(RateLimitError):
+		return RateLimitTTL
+	default:
+		return UnhealthyTTL
+	}
+}
+
+// RecordTiming adds the HTTP timing of the check just recorded for a provider.
+func (s *HealthState) RecordTiming(name string, timing HTTPTiming) {
+	h, ok := s.Providers[name]
+	if !ok {
+		return
+	}
+	h.Timing = FormatHTTPTiming(timing)
+	s.Providers[name] = h
+}
+
+// Forget drops the recorded health of a provider, e.g. after its configuration changed,
+// and reports whether there was anything to drop.
+func (s *HealthState) Forget(name string) bool {
+	if _, ok := s.Providers[name]; !ok {
+		return false
+	}
+	delete(s.Providers, name)
+	return true
+}
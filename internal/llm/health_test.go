@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHealthStateSkipAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HealthFileName)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	s := LoadHealthState(path)
+	if _, skip := s.ShouldSkip("openai", now); skip {
+		t.Fatal("unknown provider must not be skipped")
+	}
+
+	s.RecordFailure("openai", errors.New("dial tcp: connection refused"), now)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := LoadHealthState(path)
+	h, skip := reloaded.ShouldSkip("openai", now.Add(time.Minute))
+	if !skip {
+		t.Fatal("recently failed provider should be skipped")
+	}
+	if h.Failures != 1 || h.Error == "" {
+		t.Errorf("unexpected recorded health: %+v", h)
+	}
+	if _, skip := reloaded.ShouldSkip("openai", now.Add(UnhealthyTTL+time.Second)); skip {
+		t.Error("provider should be retried once the TTL has passed")
+	}
+
+	reloaded.RecordSuccess("openai", nil, now.Add(2*time.Minute))
+	if _, skip := reloaded.ShouldSkip("openai", now.Add(2*time.Minute)); skip {
+		t.Error("healthy provider must not be skipped")
+	}
+}
+
+func TestHealthStateAuthFailuresLastLonger(t *testing.T) {
+	s := LoadHealthState("")
+	now := time.Now()
+	s.RecordFailure("openai", errors.New("status 401: invalid api key"), now)
+
+	h, skip := s.ShouldSkip("openai", now.Add(UnhealthyTTL+time.Minute))
+	if h.Code != "PROVIDER_AUTH" {
+		t.Fatalf("expected auth failure code, got %q", h.Code)
+	}
+	if !skip {
+		t.Error("auth failures should be skipped for AuthFailureTTL")
+	}
+	if !s.Forget("openai") || s.Forget("openai") {
+		t.Error("Forget should report whether an entry was removed")
+	}
+}
+
+func TestLoadHealthStateCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HealthFileName)
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := LoadHealthState(path)
+	if len(s.Providers) != 0 {
+		t.Errorf("corrupt state should load empty, got %+v", s.Providers)
+	}
+}
+
+func TestHealthStateBadAnswersDoNotSkip(t *testing.T) {
+	s := LoadHealthState("")
+	now := time.Now()
+	s.RecordFailure("ollama", NewLLMError(EmptyResponseError, "provider returned an empty command", nil), now)
+
+	if h, skip := s.ShouldSkip("ollama", now); skip || h.Healthy {
+		t.Errorf("an empty answer should be recorded but not skipped: %+v, skip %v", h, skip)
+	}
+}
+
+func TestHealthStateUsesFailureKind(t *testing.T) {
+	s := LoadHealthState("")
+	now := time.Now()
+
+	s.RecordFailure("openai", errors.New("API returned status 400: maximum context length is 128000 tokens"), now)
+	if h, skip := s.ShouldSkip("openai", now); skip || h.Kind != string(ContextTooLongError) {
+		t.Errorf("an overlong prompt should not make the provider skipped: %+v, skip %v", h, skip)
+	}
+
+	s.RecordFailure("claude", errors.New("API error (status 429): rate_limit_error"), now)
+	if _, skip := s.ShouldSkip("claude", now); !skip {
+		t.Error("a rate-limited provider should be skipped at first")
+	}
+	if _, skip := s.ShouldSkip("claude", now.Add(RateLimitTTL+time.Second)); skip {
+		t.Error("a rate limit should be skipped for RateLimitTTL only")
+	}
+}
+
+func TestHealthStateSkipsUntilRateLimitResets(t *testing.T) {
+	s := LoadHealthState("")
+	now := time.Now()
+	s.RecordFailure("openai", errors.New("API error: Rate limit reached for gpt-4o. Please try again in 6m0s."), now)
+
+	h, skip := s.ShouldSkip("openai", now.Add(5*time.Minute))
+	if !skip || h.ResetAt.Sub(now) != 6*time.Minute {
+		t.Fatalf("rate-limited provider should be skipped until its reset: %+v, %v", h, skip)
+	}
+	if _, skip := s.ShouldSkip("openai", now.Add(6*time.Minute+time.Second)); skip {
+		t.Error("provider should be tried again once the limit reset")
+	}
+}
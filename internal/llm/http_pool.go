@@ -10,9 +10,11 @@ import (
 
 // HTTPPool is a high-performance HTTP client connection pool manager
 type HTTPPool struct {
-	mu      sync.RWMutex
-	clients map[string]*http.Client
-	config  HTTPPoolConfig
+	mu         sync.RWMutex
+	clients    map[string]*http.Client
+	transports map[string]*http.Transport
+	dns        *dnsCache
+	config     HTTPPoolConfig
 }
 
 // HTTPPoolConfig HTTP connection pool configuration
@@ -24,6 +26,7 @@ type HTTPPoolConfig struct {
 	ResponseHeaderTimeout time.Duration // Response header timeout
 	RequestTimeout        time.Duration // Total request timeout
 	MaxRetries            int           // Maximum retry count
+	DNSCacheTTL           time.Duration // How long resolved addresses are reused (0 disables the cache)
 }
 
 // DefaultHTTPPoolConfig returns default HTTP pool configuration
@@ -33,9 +36,10 @@ func DefaultHTTPPoolConfig() HTTPPoolConfig {
 		MaxIdleConnsPerHost:   10,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 10 * time.Second,
+		ResponseHeaderTimeout: 0, // LLM responses send headers only after generation; each client's Timeout bounds the request
 		RequestTimeout:        30 * time.Second,
 		MaxRetries:            3,
+		DNSCacheTTL:           5 * time.Minute,
 	}
 }
 
@@ -55,27 +59,48 @@ func GetDefaultPool() *HTTPPool {
 // NewHTTPPool 創建新的 HTTP 連接池
 func NewHTTPPool(config HTTPPoolConfig) *HTTPPool {
 	return &HTTPPool{
-		clients: make(map[string]*http.Client),
-		config:  config,
+		clients:    make(map[string]*http.Client),
+		transports: make(map[string]*http.Transport),
+		dns:        newDNSCache(config.DNSCacheTTL),
+		config:     config,
 	}
 }
 
-// GetClient 獲取或創建 HTTP 客戶端
-// key 用於區分不同的配置，例如不同的 CA 證書或 TLS 設置
-func (p *HTTPPool) GetClient(key string, tlsConfig *tls.Config) *http.Client {
+// SharedTransportKey identifies the transport shared by providers without custom TLS settings.
+const SharedTransportKey = "shared"
+
+// NewPooledClient returns a client with its own timeout on top of the default pool's shared
+// transport, so every provider reuses the same idle connections, HTTP/2 sessions and DNS cache.
+func NewPooledClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: GetDefaultPool().Transport(SharedTransportKey, nil),
+	}
+}
+
+// Transport 獲取或創建指定 key 的共享傳輸層
+// Transport returns the transport registered under key, creating it with tlsConfig on first use.
+func (p *HTTPPool) Transport(key string, tlsConfig *tls.Config) *http.Transport {
 	p.mu.RLock()
-	if client, exists := p.clients[key]; exists {
+	if tr, exists := p.transports[key]; exists {
 		p.mu.RUnlock()
-		return client
+		return tr
 	}
 	p.mu.RUnlock()
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return p.transportLocked(key, tlsConfig)
+}
 
-	// 雙重檢查鎖定模式
-	if client, exists := p.clients[key]; exists {
-		return client
+func (p *HTTPPool) transportLocked(key string, tlsConfig *tls.Config) *http.Transport {
+	if tr, exists := p.transports[key]; exists {
+		return tr
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
 	}
 
 	// 創建優化的傳輸層
@@ -89,19 +114,39 @@ func (p *HTTPPool) GetClient(key string, tlsConfig *tls.Config) *http.Client {
 		// 啟用 HTTP/2
 		ForceAttemptHTTP2: true,
 
-		// 使用系統代理設置
-		Proxy: http.ProxyFromEnvironment,
+		// 使用設定或系統代理設置（含 SOCKS5）
+		Proxy: Proxy,
 
 		// 自定義 TLS 配置
 		TLSClientConfig: tlsConfig,
 
-		// 連接超時設置
-		DialContext: (&net.Dialer{
-			Timeout:   10 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		// 連接超時設置，並透過 DNS 快取解析主機
+		DialContext:           p.dns.dialContext(dialer),
 		ExpectContinueTimeout: 1 * time.Second,
 	}
+	p.transports[key] = transport
+	return transport
+}
+
+// GetClient 獲取或創建 HTTP 客戶端
+// key 用於區分不同的配置，例如不同的 CA 證書或 TLS 設置
+func (p *HTTPPool) GetClient(key string, tlsConfig *tls.Config) *http.Client {
+	p.mu.RLock()
+	if client, exists := p.clients[key]; exists {
+		p.mu.RUnlock()
+		return client
+	}
+	p.mu.RUnlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// 雙重檢查鎖定模式
+	if client, exists := p.clients[key]; exists {
+		return client
+	}
+
+	transport := p.transportLocked(key, tlsConfig)
 
 	client := &http.Client{
 		Transport: transport,
@@ -158,18 +203,18 @@ func (p *HTTPPool) Close() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	for _, client := range p.clients {
-		if transport, ok := client.Transport.(*http.Transport); ok {
-			transport.CloseIdleConnections()
-		}
+	for _, transport := range p.transports {
+		transport.CloseIdleConnections()
 	}
 	p.clients = make(map[string]*http.Client)
+	p.transports = make(map[string]*http.Transport)
 }
 
 // Stats 返回連接池統計信息
 type PoolStats struct {
-	ActiveClients int
-	TotalRequests int64
+	ActiveClients    int
+	ActiveTransports int
+	TotalRequests    int64
 }
 
 // GetStats 獲取連接池統計信息
@@ -178,6 +223,7 @@ func (p *HTTPPool) GetStats() PoolStats {
 	defer p.mu.RUnlock()
 
 	return PoolStats{
-		ActiveClients: len(p.clients),
+		ActiveClients:    len(p.clients),
+		ActiveTransports: len(p.transports),
 	}
 }
@@ -0,0 +1,176 @@
+package llm
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewPooledClientSharesTransport(t *testing.T) {
+	a := NewPooledClient(30 * time.Second)
+	b := NewPooledClient(90 * time.Second)
+	if a.Transport != b.Transport {
+		t.Error("pooled clients should share one transport")
+	}
+	if a.Timeout == b.Timeout {
+		t.Error("pooled clients should keep their own timeouts")
+	}
+	tr := a.Transport.(*http.Transport)
+	if !tr.ForceAttemptHTTP2 {
+		t.Error("shared transport should attempt HTTP/2")
+	}
+}
+
+func TestHTTPPoolTransportPerKey(t *testing.T) {
+	p := NewHTTPPool(DefaultHTTPPoolConfig())
+	shared := p.Transport(SharedTransportKey, nil)
+	custom := p.Transport("custom-tls", &tls.Config{InsecureSkipVerify: true})
+	if shared == custom {
+		t.Fatal("transports with different keys must not be shared")
+	}
+	if p.Transport("custom-tls", nil) != custom {
+		t.Error("transport should be created once per key")
+	}
+	if got := p.GetStats().ActiveTransports; got != 2 {
+		t.Errorf("ActiveTransports = %d, want 2", got)
+	}
+}
+
+func TestPooledClientReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	p := NewHTTPPool(DefaultHTTPPoolConfig())
+	client := &http.Client{Transport: p.Transport(SharedTransportKey, nil)}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("expected one reused connection, got %d", n)
+	}
+}
+
+func TestDNSCacheResolvesOnceWithinTTL(t *testing.T) {
+	now := time.Now()
+	c := newDNSCache(time.Minute)
+	c.nowFunc = func() time.Time { return now }
+	lookups := 0
+	c.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		return []string{"127.0.0.1"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.resolve(context.Background(), "api.example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected 1 lookup within TTL, got %d", lookups)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := c.resolve(context.Background(), "api.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 2 {
+		t.Errorf("expected a fresh lookup after TTL, got %d lookups", lookups)
+	}
+}
+
+func TestDNSCacheDialUsesCachedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	c := newDNSCache(time.Minute)
+	c.lookup = func(ctx context.Context, host string) ([]string, error) {
+		if host != "llm.invalid" {
+			return nil, errors.New("unexpected host " + host)
+		}
+		return []string{"127.0.0.1"}, nil
+	}
+	dial := c.dialContext(&net.Dialer{Timeout: time.Second})
+	conn, err := dial(context.Background(), "tcp", net.JoinHostPort("llm.invalid", port))
+	if err != nil {
+		t.Fatalf("dial through cache failed: %v", err)
+	}
+	conn.Close()
+}
+
+// hangingDialer is a dialer whose connection attempts to dead never complete, like a
+// blackholed address, until their context ends.
+func hangingDialer(dead ...string) *net.Dialer {
+	return &net.Dialer{
+		Timeout: 10 * time.Second,
+		ControlContext: func(ctx context.Context, network, address string, _ syscall.RawConn) error {
+			host, _, _ := net.SplitHostPort(address)
+			if slices.Contains(dead, host) {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+}
+
+func TestDNSCacheDialFallsBackToOtherFamily(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	c := newDNSCache(time.Minute)
+	c.lookup = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"2001:db8::1", "127.0.0.1"}, nil
+	}
+	start := time.Now()
+	conn, err := c.dialContext(hangingDialer("2001:db8::1"))(context.Background(), "tcp", net.JoinHostPort("llm.invalid", port))
+	if err != nil {
+		t.Fatalf("dial should fall back to the IPv4 address: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fallback took %v, want about the fallback delay", elapsed)
+	}
+}
+
+func TestDNSCacheDialSplitsDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	c := newDNSCache(time.Minute)
+	c.lookup = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.2", "127.0.0.1"}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*minDialShare)
+	defer cancel()
+	conn, err := c.dialContext(hangingDialer("127.0.0.2"))(ctx, "tcp", net.JoinHostPort("llm.invalid", port))
+	if err != nil {
+		t.Fatalf("the dead first address should not use up the whole deadline: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,159 @@
+package llm
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPTiming is where the time of a provider's HTTP request went. A slow DNS lookup, connect
+// or TLS handshake points at the network; a long wait for the first byte after them points at
+// the provider, which only answers once the model has (begun to) run.
+type HTTPTiming struct {
+	DNS     time.Duration // Zero when no lookup was made, e.g. on a reused connection
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration // From the start of the request to the first byte of the response; zero without one
+	Total   time.Duration // From the start of the request until it was read
+	Reused  bool          // The request went over a connection kept from an earlier one
+	// Requests is how many HTTP requests were made, retries and token refreshes included;
+	// the durations are those of the last one.
+	Requests int
+}
+
+// FormatHTTPTiming renders t as "dns 12ms, connect 31ms, tls 48ms, first byte 820ms, total
+// 1.35s", leaving out the steps that did not happen. A request that timed out or was cut off
+// before the provider answered shows "no response".
+func FormatHTTPTiming(t HTTPTiming) string {
+	var parts []string
+	if t.Reused {
+		parts = append(parts, "reused connection")
+	}
+	for _, step := range []struct {
+		name string
+		d    time.Duration
+	}{{"dns", t.DNS}, {"connect", t.Connect}, {"tls", t.TLS}} {
+		if step.d > 0 {
+			parts = append(parts, step.name+" "+step.d.Round(time.Millisecond).String())
+		}
+	}
+	if t.TTFB > 0 {
+		parts = append(parts, "first byte "+t.TTFB.Round(time.Millisecond).String())
+	} else {
+		parts = append(parts, "no response")
+	}
+	parts = append(parts, "total "+t.Total.Round(time.Millisecond).String())
+	s := strings.Join(parts, ", ")
+	if t.Requests > 1 {
+		s += fmt.Sprintf(" (last of %d requests)", t.Requests)
+	}
+	return s
+}
+
+// HTTPTrace collects the timing of the HTTP requests made with the context TraceHTTP returns.
+type HTTPTrace struct {
+	mu                           sync.Mutex
+	start, dns, connect, tlsTime time.Time
+	timing                       HTTPTiming
+	now                          func() time.Time
+	parent                       *HTTPTrace // The trace of an enclosing TraceHTTP, which sees the same requests
+}
+
+type httpTraceKey struct{}
+
+// TraceHTTP returns a context whose HTTP requests are timed by the returned HTTPTrace. The
+// requests need nothing but the context, so every provider is covered as it is.
+func TraceHTTP(ctx context.Context) (context.Context, *HTTPTrace) {
+	parent, _ := ctx.Value(httpTraceKey{}).(*HTTPTrace)
+	t := &HTTPTrace{now: time.Now, parent: parent}
+	ctx = context.WithValue(ctx, httpTraceKey{}, t)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mark(func(now time.Time) {
+				t.start = now
+				t.timing = HTTPTiming{Requests: t.timing.Requests + 1}
+			})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mark(func(time.Time) { t.timing.Reused = info.Reused })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(func(now time.Time) { t.dns = now }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mark(func(now time.Time) { t.timing.DNS = now.Sub(t.dns) })
+		},
+		ConnectStart: func(string, string) { t.mark(func(now time.Time) { t.connect = now }) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.mark(func(now time.Time) { t.timing.Connect = now.Sub(t.connect) })
+			}
+		},
+		TLSHandshakeStart: func() { t.mark(func(now time.Time) { t.tlsTime = now }) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.mark(func(now time.Time) { t.timing.TLS = now.Sub(t.tlsTime) })
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.mark(func(now time.Time) { t.timing.TTFB = now.Sub(t.start) })
+		},
+	}), t
+}
+
+func (t *HTTPTrace) mark(update func(now time.Time)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	update(t.now())
+}
+
+// Timing returns the timing of the last request, counting its total up to now, so it is
+// called once the response has been read. It reports false when no request was made.
+func (t *HTTPTrace) Timing() (HTTPTiming, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timing.Requests == 0 {
+		return HTTPTiming{}, false
+	}
+	timing := t.timing
+	timing.Total = t.now().Sub(t.start)
+	return timing, true
+}
+
+// RecordHTTPTiming adds a request timed outside net/http, such as by a curl subprocess, to the
+// traces of ctx. Its Total is taken as ending now.
+func RecordHTTPTiming(ctx context.Context, timing HTTPTiming) {
+	for t, _ := ctx.Value(httpTraceKey{}).(*HTTPTrace); t != nil; t = t.parent {
+		t.mark(func(now time.Time) {
+			requests := t.timing.Requests + 1
+			t.start = now.Add(-timing.Total)
+			t.timing = timing
+			t.timing.Requests = requests
+		})
+	}
+}
+
+type httpTimingReporterKey struct{}
+
+// WithHTTPTimingReporter makes every provider request under ctx call report with its HTTP
+// timing once it has finished, e.g. to show it with --debug.
+func WithHTTPTimingReporter(ctx context.Context, report func(HTTPTiming)) context.Context {
+	return context.WithValue(ctx, httpTimingReporterKey{}, report)
+}
+
+// trackHTTPTiming runs send for prompt and passes the timing of its HTTP requests to the
+// reporter attached to ctx, if there is one.
+func trackHTTPTiming(ctx context.Context, prompt string, send func(context.Context, string) (string, error)) (string, error) {
+	report, ok := ctx.Value(httpTimingReporterKey{}).(func(HTTPTiming))
+	if !ok || report == nil {
+		return send(ctx, prompt)
+	}
+	ctx, trace := TraceHTTP(ctx)
+	response, err := send(ctx, prompt)
+	if timing, ok := trace.Timing(); ok {
+		report(timing)
+	}
+	return response, err
+}
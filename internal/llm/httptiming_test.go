@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFormatHTTPTiming(t *testing.T) {
+	cases := []struct {
+		timing HTTPTiming
+		want   string
+	}{
+		{
+			HTTPTiming{DNS: 12 * time.Millisecond, Connect: 31 * time.Millisecond, TLS: 48 * time.Millisecond, TTFB: 820 * time.Millisecond, Total: 1350 * time.Millisecond, Requests: 1},
+			"dns 12ms, connect 31ms, tls 48ms, first byte 820ms, total 1.35s",
+		},
+		{
+			HTTPTiming{Reused: true, TTFB: 400 * time.Millisecond, Total: 2 * time.Second, Requests: 2},
+			"reused connection, first byte 400ms, total 2s (last of 2 requests)",
+		},
+		{
+			HTTPTiming{DNS: 3 * time.Millisecond, Connect: 5 * time.Second, Total: 5 * time.Second, Requests: 1},
+			"dns 3ms, connect 5s, no response, total 5s",
+		},
+	}
+	for _, c := range cases {
+		if got := FormatHTTPTiming(c.timing); got != c.want {
+			t.Errorf("FormatHTTPTiming(%+v)\n got %q\nwant %q", c.timing, got, c.want)
+		}
+	}
+}
+
+func TestTraceHTTP(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	ctx, trace := TraceHTTP(context.Background())
+	if _, ok := trace.Timing(); ok {
+		t.Fatal("Timing reported a request before any was made")
+	}
+	get := func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	get()
+	first, ok := trace.Timing()
+	if !ok || first.Requests != 1 || first.Reused || first.Connect <= 0 || first.TLS <= 0 {
+		t.Errorf("first request: %+v", first)
+	}
+	if first.TTFB < 20*time.Millisecond || first.Total < first.TTFB {
+		t.Errorf("first byte %v and total %v do not cover the server's 20ms", first.TTFB, first.Total)
+	}
+
+	get()
+	second, _ := trace.Timing()
+	if second.Requests != 2 || !second.Reused || second.Connect != 0 || second.TLS != 0 {
+		t.Errorf("second request: %+v", second)
+	}
+}
+
+func TestExchangeReportsHTTPTiming(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ls")
+	}))
+	defer srv.Close()
+
+	var reported []HTTPTiming
+	ctx := WithHTTPTimingReporter(context.Background(), func(t HTTPTiming) { reported = append(reported, t) })
+	_, err := Exchange(ctx, "list files", func(ctx context.Context, _ string) (string, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, nil)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 1 || reported[0].Requests != 1 || reported[0].Total <= 0 {
+		t.Errorf("reported %+v", reported)
+	}
+
+	// A provider that made no HTTP request, such as a CLI binary, reports nothing
+	_, _ = Exchange(ctx, "list files", func(context.Context, string) (string, error) { return "ls", nil })
+	if len(reported) != 1 {
+		t.Errorf("reported a request without HTTP: %+v", reported[1:])
+	}
+}
+
+func TestRecordHTTPTiming(t *testing.T) {
+	ctx, outer := TraceHTTP(context.Background())
+	ctx, inner := TraceHTTP(ctx)
+	RecordHTTPTiming(ctx, HTTPTiming{DNS: time.Millisecond, TTFB: 80 * time.Millisecond, Total: 100 * time.Millisecond})
+
+	for name, trace := range map[string]*HTTPTrace{"inner": inner, "outer": outer} {
+		got, ok := trace.Timing()
+		if !ok || got.Requests != 1 || got.DNS != time.Millisecond || got.TTFB != 80*time.Millisecond {
+			t.Errorf("%s trace: %+v, %v", name, got, ok)
+		}
+		if got.Total < 100*time.Millisecond {
+			t.Errorf("%s trace total %v, want at least the recorded 100ms", name, got.Total)
+		}
+	}
+
+	// Without a trace there is nothing to record into
+	RecordHTTPTiming(context.Background(), HTTPTiming{Total: time.Second})
+}
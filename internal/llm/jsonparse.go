@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// ParseMethod is how a provider response was turned into a suggestion or command.
+type ParseMethod string
+
+const (
+	ParseStrict    ParseMethod = "strict"    // Valid JSON as asked for, possibly in a code fence
+	ParseRepaired  ParseMethod = "repaired"  // JSON that only parsed after RepairJSON
+	ParseHeuristic ParseMethod = "heuristic" // No usable JSON; picked out of free-form text
+)
+
+// ParseReport remembers how the response of a provider call was parsed, so callers can
+// count it without knowing how the provider parses.
+type ParseReport struct {
+	mu     sync.Mutex
+	method ParseMethod
+}
+
+type parseReportKey struct{}
+
+// WithParseReport attaches r to ctx so providers can report how they parsed their response.
+func WithParseReport(ctx context.Context, r *ParseReport) context.Context {
+	return context.WithValue(ctx, parseReportKey{}, r)
+}
+
+// ReportParse records method on the report attached to ctx, if there is one.
+func ReportParse(ctx context.Context, method ParseMethod) {
+	if r, ok := ctx.Value(parseReportKey{}).(*ParseReport); ok && r != nil {
+		r.mu.Lock()
+		r.method = method
+		r.mu.Unlock()
+	}
+}
+
+// Method returns the reported parse method, or "" when the provider reported none.
+func (r *ParseReport) Method() ParseMethod {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.method
+}
+
+// suggestionJSON accepts the spellings providers use for the corrected command.
+type suggestionJSON struct {
+	Explanation      string `json:"explanation"`
+	Command          string `json:"command"`
+	CorrectedCommand string `json:"corrected_command"`
+	CorrectedCamel   string `json:"correctedCommand"`
+}
+
+func (s suggestionJSON) command() string {
+	for _, c := range []string{s.Command, s.CorrectedCommand, s.CorrectedCamel} {
+		if strings.TrimSpace(c) != "" {
+			return strings.TrimSpace(c)
+		}
+	}
+	return ""
+}
+
+// DecodeSuggestion parses a {"explanation": ..., "command": ...} response, repairing common
+// defects when it is not valid JSON, and reports the method on ctx. ok is false when the
+// response holds no complete suggestion, and the caller falls back to heuristic parsing.
+func DecodeSuggestion(ctx context.Context, response string) (*Suggestion, bool) {
+	obj, method, ok := decodeJSON(response, func(s suggestionJSON) bool {
+		return s.command() != "" && strings.TrimSpace(s.Explanation) != ""
+	})
+	if !ok {
+		return nil, false
+	}
+	ReportParse(ctx, method)
+	return &Suggestion{Explanation: strings.TrimSpace(obj.Explanation), CorrectedCommand: obj.command()}, true
+}
+
+// DecodeCommand parses a {"command": ...} response like DecodeSuggestion.
+func DecodeCommand(ctx context.Context, response string) (string, bool) {
+	obj, method, ok := decodeJSON(response, func(c struct{ Command string }) bool {
+		return strings.TrimSpace(c.Command) != ""
+	})
+	if !ok {
+		return "", false
+	}
+	ReportParse(ctx, method)
+	return strings.TrimSpace(obj.Command), true
+}
+
+// decodeJSON unmarshals response into a T that valid accepts: first as it is (without a
+// surrounding code fence), then repaired.
+func decodeJSON[T any](response string, valid func(T) bool) (T, ParseMethod, bool) {
+	var v T
+	if json.Unmarshal([]byte(stripJSONFence(response)), &v) == nil && valid(v) {
+		return v, ParseStrict, true
+	}
+	if repaired, ok := RepairJSON(response); ok {
+		var r T
+		if json.Unmarshal([]byte(repaired), &r) == nil && valid(r) {
+			return r, ParseRepaired, true
+		}
+	}
+	var zero T
+	return zero, "", false
+}
+
+// stripJSONFence removes a markdown code fence (``` or ```json) around s.
+func stripJSONFence(s string) string {
+	s = strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(s, "```"); ok {
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(strings.ToLower(rest), "json") {
+			rest = rest[len("json"):]
+		}
+		if i := strings.LastIndex(rest, "```"); i != -1 {
+			rest = rest[:i]
+		}
+		s = strings.TrimSpace(rest)
+	}
+	return s
+}
+
+// smartQuotes maps typographic quotes, which models sometimes emit as JSON delimiters, to
+// their ASCII forms.
+var smartQuotes = strings.NewReplacer("“", `"`, "”", `"`, "„", `"`, "″", `"`, "‘", "'", "’", "'")
+
+// RepairJSON fixes the defects that most often keep a model's JSON from parsing: it normalizes
+// smart quotes, extracts the first balanced {...} object from surrounding prose or code
+// fences, and drops trailing commas before } and ]. ok is false when s holds no object.
+func RepairJSON(s string) (string, bool) {
+	s = smartQuotes.Replace(s)
+	start := strings.IndexByte(s, '{')
+	if start == -1 {
+		return "", false
+	}
+
+	var b strings.Builder
+	depth := 0
+	inString, escaped := false, false
+	pendingComma := -1 // Offset in b of a comma that may turn out to be trailing
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			b.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+			pendingComma = -1
+		case ',':
+			pendingComma = b.Len()
+		case '}', ']':
+			if pendingComma != -1 {
+				out := b.String()
+				b.Reset()
+				b.WriteString(out[:pendingComma] + out[pendingComma+1:])
+				pendingComma = -1
+			}
+			if c == '}' {
+				depth--
+			}
+		case '{':
+			depth++
+			pendingComma = -1
+		case ' ', '\t', '\n', '\r':
+		default:
+			pendingComma = -1
+		}
+		b.WriteByte(c)
+		if depth == 0 {
+			return b.String(), true
+		}
+	}
+	// Unbalanced: the response was cut off; close what is open unless it stopped inside a string
+	if inString {
+		return "", false
+	}
+	out := b.String()
+	if pendingComma != -1 {
+		out = out[:pendingComma] + out[pendingComma+1:]
+	}
+	return out + strings.Repeat("}", depth), true
+}
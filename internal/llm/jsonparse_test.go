@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRepairJSON(t *testing.T) {
+	cases := map[string]string{
+		"prose around":    "Sure, here you go:\n{\"command\": \"ls\"}\nLet me know!",
+		"trailing comma":  `{"explanation": "typo", "command": "git status",}`,
+		"nested trailing": `{"a": [1, 2,], "b": {"c": 1,},}`,
+		"smart quotes":    `{“command”: “echo ‘hi’”}`,
+		"braces in value": `{"command": "awk '{print $1}' file", "explanation": "x"} trailing {junk}`,
+		"comma in value":  `{"command": "echo a,}"}`,
+		"cut off":         `{"explanation": "missing close", "command": "ls",`,
+	}
+	for name, in := range cases {
+		out, ok := RepairJSON(in)
+		if !ok {
+			t.Errorf("%s: RepairJSON(%q) failed", name, in)
+			continue
+		}
+		var v map[string]any
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			t.Errorf("%s: repaired %q is not valid JSON: %v", name, out, err)
+		}
+	}
+
+	out, _ := RepairJSON(`{"command": "echo a,}"}`)
+	if out != `{"command": "echo a,}"}` {
+		t.Errorf("RepairJSON changed a string value: %q", out)
+	}
+	for _, in := range []string{"no json here", `{"command": "unterminated`} {
+		if out, ok := RepairJSON(in); ok {
+			t.Errorf("RepairJSON(%q) = %q, want failure", in, out)
+		}
+	}
+}
+
+func TestDecodeSuggestionReportsMethod(t *testing.T) {
+	cases := []struct {
+		response string
+		method   ParseMethod
+		ok       bool
+	}{
+		{`{"explanation": "typo", "command": "git status"}`, ParseStrict, true},
+		{"```json\n{\"explanation\": \"typo\", \"corrected_command\": \"git status\"}\n```", ParseStrict, true},
+		{`The fix: {"explanation": "typo", "correctedCommand": "git status",}`, ParseRepaired, true},
+		{`{"command": "git status"}`, "", false}, // No explanation: left to the heuristics
+		{"Explanation: typo\nCommand: git status", "", false},
+	}
+	for _, c := range cases {
+		report := &ParseReport{}
+		s, ok := DecodeSuggestion(WithParseReport(context.Background(), report), c.response)
+		if ok != c.ok || report.Method() != c.method {
+			t.Errorf("DecodeSuggestion(%q) ok=%v method=%q, want %v %q", c.response, ok, report.Method(), c.ok, c.method)
+			continue
+		}
+		if ok && (s.CorrectedCommand != "git status" || s.Explanation != "typo") {
+			t.Errorf("DecodeSuggestion(%q) = %+v", c.response, s)
+		}
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	report := &ParseReport{}
+	ctx := WithParseReport(context.Background(), report)
+	if cmd, ok := DecodeCommand(ctx, `{“command”: “du -sh .”}`); !ok || cmd != "du -sh ." || report.Method() != ParseRepaired {
+		t.Errorf("DecodeCommand = %q, %v, %q", cmd, ok, report.Method())
+	}
+	if _, ok := DecodeCommand(ctx, "du -sh ."); ok {
+		t.Error("DecodeCommand accepted a plain command")
+	}
+}
+
+func TestParseStatsRecordAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ParseStatsFileName)
+	stats := LoadParseStats(path)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	stats.Record("openai", ParseStrict, now)
+	stats.Record("openai", ParseRepaired, now)
+	stats.Record("ollama", ParseHeuristic, now)
+	stats.Record("mock", "", now) // Unreported methods are not counted
+	if err := stats.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := LoadParseStats(path)
+	if got := loaded.Providers["openai"]; got != (ParseCounts{Strict: 1, Repaired: 1}) {
+		t.Errorf("openai counts = %+v", got)
+	}
+	if got := loaded.Names(); len(got) != 2 || got[0] != "ollama" || got[1] != "openai" {
+		t.Errorf("Names() = %v", got)
+	}
+	if !loaded.Since.Equal(now) {
+		t.Errorf("Since = %v, want %v", loaded.Since, now)
+	}
+	if err := loaded.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if len(LoadParseStats(path).Providers) != 0 {
+		t.Error("counters survived Reset")
+	}
+}
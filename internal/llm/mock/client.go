@@ -0,0 +1,200 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+// MockProvider returns canned, deterministic responses without any network access or
+// credentials. It backs demo mode (classrooms, recorded demos) and offline testing.
+type MockProvider struct {
+	cfg config.ProviderConfig
+}
+
+// NewProvider creates a new MockProvider. The configuration is accepted for interface
+// compatibility and otherwise ignored.
+func NewProvider(cfg config.ProviderConfig, _ *prompt.Manager) (llm.Provider, error) {
+	return &MockProvider{cfg: cfg}, nil
+}
+
+func init() {
+	llm.RegisterProvider(config.ProviderMock, NewProvider)
+}
+
+// suggestionRule maps an error fingerprint to a canned explanation and fix.
+type suggestionRule struct {
+	patterns    []string
+	explanation string
+	fix         func(command string) string
+}
+
+var suggestionRules = []suggestionRule{
+	{
+		patterns:    []string{"command not found", "is not recognized as"},
+		explanation: "The shell could not find this program. It is either not installed or not on your PATH.",
+		fix: func(command string) string {
+			return fmt.Sprintf("command -v %s || echo '%s is not installed'", firstWord(command), firstWord(command))
+		},
+	},
+	{
+		patterns:    []string{"permission denied", "operation not permitted"},
+		explanation: "You do not have permission to perform this operation. Check the file permissions, or run it with elevated privileges if that is really intended.",
+		fix: func(command string) string {
+			// Re-run the failed command itself; one already run with sudo has nothing to add
+			if command == "" || firstWord(command) == "sudo" {
+				return command
+			}
+			return "sudo " + command
+		},
+	},
+	{
+		patterns:    []string{"no such file or directory", "cannot find the path"},
+		explanation: "A file or directory named in the command does not exist. List the current directory to check the name and location.",
+		fix:         func(string) string { return "ls -la" },
+	},
+	{
+		patterns:    []string{"not a git repository"},
+		explanation: "This directory is not inside a Git repository, so Git commands have nothing to work on.",
+		fix:         func(string) string { return "git init" },
+	},
+	{
+		patterns:    []string{"address already in use"},
+		explanation: "Another process is already listening on the port this program wants to use.",
+		fix:         func(string) string { return "lsof -i -P -n | grep LISTEN" },
+	},
+}
+
+// commandRules map keywords of a natural-language request to canned commands.
+var commandRules = []struct {
+	keywords []string
+	command  string
+}{
+	{[]string{"disk", "space"}, "df -h"},
+	{[]string{"process", "running"}, "ps aux"},
+	{[]string{"memory", "ram"}, "free -h"},
+	{[]string{"large", "biggest"}, "du -sh * | sort -h | tail -n 10"},
+	{[]string{"find", "search"}, "find . -name '*.txt'"},
+	{[]string{"list", "files"}, "ls -la"},
+}
+
+// planRules map keywords of a multi-step request to canned plans.
+var planRules = []struct {
+	keywords []string
+	steps    []llm.PlanStep
+}{
+	{[]string{"venv", "virtualenv"}, []llm.PlanStep{
+		{Command: "python3 -m venv .venv", Explanation: "Create a virtual environment in .venv."},
+		{Command: ".venv/bin/pip install --upgrade pip", Explanation: "Update pip inside the environment."},
+		{Command: ".venv/bin/pip install -r requirements.txt", Explanation: "Install the project's requirements."},
+	}},
+	{[]string{"git repo", "repository"}, []llm.PlanStep{
+		{Command: "git init", Explanation: "Create an empty repository here."},
+		{Command: "git add -A", Explanation: "Stage every file."},
+		{Command: "git commit -m 'Initial commit'", Explanation: "Record the first commit."},
+	}},
+}
+
+// GetSuggestion implements the llm.Provider interface.
+func (p *MockProvider) GetSuggestion(_ context.Context, capturedContext llm.CapturedContext, _ string) (*llm.Suggestion, error) {
+	haystack := strings.ToLower(capturedContext.Stderr + "\n" + capturedContext.Stdout)
+	for _, rule := range suggestionRules {
+		for _, pattern := range rule.patterns {
+			if strings.Contains(haystack, pattern) {
+				return &llm.Suggestion{
+					Explanation:      rule.explanation,
+					CorrectedCommand: rule.fix(strings.TrimSpace(capturedContext.Command)),
+				}, nil
+			}
+		}
+	}
+	return &llm.Suggestion{
+		Explanation:      fmt.Sprintf("The command exited with status %d. This is a canned demo response; a configured provider would analyze the actual output.", capturedContext.ExitCode),
+		CorrectedCommand: strings.TrimSpace(capturedContext.Command),
+	}, nil
+}
+
+// GetEnhancedSuggestion implements the llm.Provider interface; the extra context is not used.
+func (p *MockProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx llm.EnhancedCapturedContext, lang string) (*llm.Suggestion, error) {
+	return p.GetSuggestion(ctx, enhancedCtx.CapturedContext, lang)
+}
+
+// GenerateCommand implements the llm.Provider interface.
+func (p *MockProvider) GenerateCommand(_ context.Context, prompt string, _ string) (string, error) {
+	lower := strings.ToLower(prompt)
+	for _, rule := range commandRules {
+		for _, kw := range rule.keywords {
+			if strings.Contains(lower, kw) {
+				return rule.command, nil
+			}
+		}
+	}
+	return fmt.Sprintf("echo %q", "Demo mode: no canned command for this request"), nil
+}
+
+// GeneratePlan implements llm.Planner with canned plans; other requests get a one-step plan
+// of the canned command.
+func (p *MockProvider) GeneratePlan(ctx context.Context, prompt string, lang string) (*llm.Plan, error) {
+	lower := strings.ToLower(prompt)
+	for _, rule := range planRules {
+		for _, kw := range rule.keywords {
+			if strings.Contains(lower, kw) {
+				return &llm.Plan{Steps: append([]llm.PlanStep(nil), rule.steps...)}, nil
+			}
+		}
+	}
+	cmd, err := p.GenerateCommand(ctx, prompt, lang)
+	if err != nil {
+		return nil, err
+	}
+	return llm.SingleStepPlan(cmd), nil
+}
+
+// GetSuggestionStream implements the llm.Provider interface, streaming the explanation word by
+// word.
+func (p *MockProvider) GetSuggestionStream(ctx context.Context, capturedContext llm.CapturedContext, lang string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
+	s, err := p.GetSuggestion(ctx, capturedContext, lang)
+	if err == nil && onChunk != nil {
+		streamWords(s.Explanation, onChunk)
+	}
+	return s, err
+}
+
+// GenerateCommandStream implements the llm.Provider interface, streaming the command word by
+// word.
+func (p *MockProvider) GenerateCommandStream(ctx context.Context, prompt string, lang string, onChunk llm.StreamFunc) (string, error) {
+	command, err := p.GenerateCommand(ctx, prompt, lang)
+	if err == nil && onChunk != nil {
+		streamWords(command, onChunk)
+	}
+	return command, err
+}
+
+// streamWords passes text to onChunk a word at a time, each with the space that follows it.
+func streamWords(text string, onChunk llm.StreamFunc) {
+	for text != "" {
+		i := strings.IndexByte(text, ' ')
+		if i < 0 {
+			onChunk(text)
+			return
+		}
+		onChunk(text[:i+1])
+		text = text[i+1:]
+	}
+}
+
+// VerifyConnection implements the llm.Provider interface.
+func (p *MockProvider) VerifyConnection(_ context.Context) ([]string, error) {
+	return []string{config.ProviderMock}, nil
+}
+
+func firstWord(command string) string {
+	if fields := strings.Fields(command); len(fields) > 0 {
+		return fields[0]
+	}
+	return command
+}
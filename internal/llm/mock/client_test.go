@@ -0,0 +1,71 @@
+package mock
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+func TestMockProviderSuggestion(t *testing.T) {
+	p, err := llm.GetProvider(config.ProviderMock, config.ProviderConfig{}, nil)
+	if err != nil {
+		t.Fatalf("GetProvider: %v", err)
+	}
+
+	s, err := p.GetSuggestion(context.Background(), llm.CapturedContext{
+		Command:  "gti status",
+		Stderr:   "zsh: command not found: gti",
+		ExitCode: 127,
+	}, "en")
+	if err != nil {
+		t.Fatalf("GetSuggestion: %v", err)
+	}
+	if !strings.Contains(s.CorrectedCommand, "command -v gti") {
+		t.Errorf("unexpected command: %q", s.CorrectedCommand)
+	}
+
+	for command, want := range map[string]string{
+		"cat /etc/shadow":      "sudo cat /etc/shadow",
+		"sudo cat /etc/shadow": "sudo cat /etc/shadow",
+	} {
+		s, _ = p.GetSuggestion(context.Background(), llm.CapturedContext{
+			Command:  command,
+			Stderr:   "cat: /etc/shadow: Permission denied",
+			ExitCode: 1,
+		}, "en")
+		if s.CorrectedCommand != want {
+			t.Errorf("permission fix for %q = %q, want %q", command, s.CorrectedCommand, want)
+		}
+	}
+
+	s, _ = p.GetSuggestion(context.Background(), llm.CapturedContext{Command: "make", Stderr: "boom", ExitCode: 2}, "en")
+	if s.CorrectedCommand != "make" || s.Explanation == "" {
+		t.Errorf("unexpected fallback suggestion: %+v", s)
+	}
+}
+
+func TestMockProviderGenerateCommand(t *testing.T) {
+	p, _ := NewProvider(config.ProviderConfig{}, nil)
+	got, err := p.GenerateCommand(context.Background(), "show free disk space", "en")
+	if err != nil || got != "df -h" {
+		t.Fatalf("GenerateCommand = %q, %v", got, err)
+	}
+	if got, _ := p.GenerateCommand(context.Background(), "write a poem", "en"); !strings.HasPrefix(got, "echo ") {
+		t.Errorf("expected an echo fallback, got %q", got)
+	}
+}
+
+func TestMockProviderGeneratePlan(t *testing.T) {
+	p, _ := NewProvider(config.ProviderConfig{}, nil)
+	plan, err := llm.GeneratePlan(context.Background(), p, "set up a python venv and install requirements", "en")
+	if err != nil || len(plan.Steps) != 3 || plan.Steps[0].Command != "python3 -m venv .venv" {
+		t.Fatalf("GeneratePlan = %+v, %v", plan, err)
+	}
+	plan, err = llm.GeneratePlan(context.Background(), p, "show free disk space", "en")
+	if err != nil || len(plan.Steps) != 1 || plan.Steps[0].Command != "df -h" {
+		t.Errorf("GeneratePlan = %+v, %v; want the canned command as one step", plan, err)
+	}
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// ModelLimits describes the token budget of a model.
+type ModelLimits struct {
+	ContextWindow   int // Total tokens (prompt + completion) the model accepts
+	MaxOutputTokens int // Largest completion the model can produce
+}
+
+const (
+	// ResponseTokenCap bounds the completion we ask for; answers are a short JSON object,
+	// so requesting a model's full output limit only slows down failure cases.
+	ResponseTokenCap = 4096
+	// ReasoningResponseTokenCap is the completion budget for reasoning models, whose hidden
+	// reasoning tokens count against max_completion_tokens (OpenAI suggests reserving ~25k).
+	ReasoningResponseTokenCap = 25000
+	// MinResponseTokens is the smallest completion budget worth sending a request for.
+	MinResponseTokens = 256
+	// promptSafetyMargin absorbs the error of the character-based token estimate.
+	promptSafetyMargin = 64
+	// truncationMarker replaces the middle of prompts that do not fit the context window.
+	truncationMarker = "\n...[truncated]...\n"
+)
+
+// DefaultModelLimits applies to models missing from the registry; it matches the previous fixed budget.
+var DefaultModelLimits = ModelLimits{ContextWindow: 8192, MaxOutputTokens: 1000}
+
+// DefaultReasoningModelLimits applies to reasoning models missing from the registry: their
+// hidden reasoning tokens would use up DefaultModelLimits' output budget before any answer.
+var DefaultReasoningModelLimits = ModelLimits{ContextWindow: 200000, MaxOutputTokens: ReasoningResponseTokenCap}
+
+// knownModelLimits is matched by prefix in order, so more specific names come first.
+var knownModelLimits = []struct {
+	prefix string
+	limits ModelLimits
+}{
+	{"gpt-4o-mini", ModelLimits{128000, 16384}},
+	{"gpt-4o", ModelLimits{128000, 16384}},
+	{"gpt-4.1", ModelLimits{1047576, 32768}},
+	{"gpt-4-turbo", ModelLimits{128000, 4096}},
+	{"gpt-4-32k", ModelLimits{32768, 4096}},
+	{"gpt-4", ModelLimits{8192, 4096}},
+	{"gpt-3.5-turbo", ModelLimits{16385, 4096}},
+	{"gpt-5-chat", ModelLimits{128000, 16384}},
+	{"gpt-5-nano", ModelLimits{400000, 128000}},
+	{"gpt-5-mini", ModelLimits{400000, 128000}},
+	{"gpt-5", ModelLimits{400000, 128000}},
+	{"o1-mini", ModelLimits{128000, 65536}},
+	{"o1", ModelLimits{200000, 100000}},
+	{"o3", ModelLimits{200000, 100000}},
+	{"o4-mini", ModelLimits{200000, 100000}},
+	{"gemini-2.5", ModelLimits{1048576, 65536}},
+	{"gemini-2.0", ModelLimits{1048576, 8192}},
+	{"gemini-1.5", ModelLimits{1048576, 8192}},
+	{"gemini-pro", ModelLimits{32760, 8192}},
+	{"claude-opus-4", ModelLimits{200000, 32000}},
+	{"claude-sonnet-4", ModelLimits{200000, 64000}},
+	{"claude-haiku-4", ModelLimits{200000, 64000}},
+	{"claude-3-5", ModelLimits{200000, 8192}},
+	{"claude-3-7", ModelLimits{200000, 64000}},
+	{"claude-3", ModelLimits{200000, 4096}},
+	{"llama3.1", ModelLimits{128000, 4096}},
+	{"llama3.3", ModelLimits{128000, 4096}},
+	{"llama3", ModelLimits{8192, 2048}},
+	{"codellama", ModelLimits{16384, 4096}},
+}
+
+// LookupModelLimits returns the registry entry for a model name.
+// Vendor prefixes such as "openai/" used by gateways are ignored.
+func LookupModelLimits(model string) (ModelLimits, bool) {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, entry := range knownModelLimits {
+		if strings.HasPrefix(name, entry.prefix) {
+			return entry.limits, true
+		}
+	}
+	return DefaultModelLimits, false
+}
+
+// ResolveModelLimits combines the registry with the per-provider overrides from the config.
+func ResolveModelLimits(cfg config.ProviderConfig) ModelLimits {
+	limits, _ := LookupModelLimits(cfg.Model)
+	if cfg.ContextWindow > 0 {
+		limits.ContextWindow = cfg.ContextWindow
+	}
+	if cfg.MaxOutputTokens > 0 {
+		limits.MaxOutputTokens = cfg.MaxOutputTokens
+	}
+	return limits
+}
+
+// ResolveReasoningModelLimits is ResolveModelLimits for reasoning models, starting from
+// DefaultReasoningModelLimits when the model is missing from the registry.
+func ResolveReasoningModelLimits(cfg config.ProviderConfig) ModelLimits {
+	limits, known := LookupModelLimits(cfg.Model)
+	if !known {
+		limits = DefaultReasoningModelLimits
+	}
+	if cfg.ContextWindow > 0 {
+		limits.ContextWindow = cfg.ContextWindow
+	}
+	if cfg.MaxOutputTokens > 0 {
+		limits.MaxOutputTokens = cfg.MaxOutputTokens
+	}
+	return limits
+}
+
+// EstimateTokens approximates the token count of text (about four characters per token).
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// FitPrompt sizes the completion budget for prompt and, when the prompt leaves less than
+// MinResponseTokens of room in the context window, truncates its middle so the instructions
+// at the start and the answer cue at the end survive.
+func FitPrompt(limits ModelLimits, prompt string) (string, int) {
+	return fitPrompt(limits, prompt, ResponseTokenCap)
+}
+
+// FitReasoningPrompt is FitPrompt for reasoning models, leaving room for reasoning tokens.
+// limits should come from ResolveReasoningModelLimits.
+func FitReasoningPrompt(limits ModelLimits, prompt string) (string, int) {
+	return fitPrompt(limits, prompt, ReasoningResponseTokenCap)
+}
+
+func fitPrompt(limits ModelLimits, prompt string, responseCap int) (string, int) {
+	want := limits.MaxOutputTokens
+	if want <= 0 || want > responseCap {
+		want = responseCap
+	}
+	if limits.ContextWindow <= 0 {
+		return prompt, want
+	}
+
+	available := limits.ContextWindow - EstimateTokens(prompt) - promptSafetyMargin
+	switch {
+	case available >= want:
+		return prompt, want
+	case available >= MinResponseTokens:
+		return prompt, available
+	}
+
+	budget := limits.ContextWindow - MinResponseTokens - promptSafetyMargin
+	if budget <= 0 {
+		return prompt, MinResponseTokens
+	}
+	return truncateMiddle(prompt, budget*4), MinResponseTokens
+}
+
+// truncateMiddle keeps the head and tail of s so that the result has at most maxRunes runes.
+func truncateMiddle(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	keep := maxRunes - utf8.RuneCountInString(truncationMarker)
+	if keep <= 0 {
+		return string(runes[:maxRunes])
+	}
+	head := keep / 2
+	tail := keep - head
+	return string(runes[:head]) + truncationMarker + string(runes[len(runes)-tail:])
+}
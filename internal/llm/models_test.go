@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func TestLookupModelLimits(t *testing.T) {
+	cases := []struct {
+		model string
+		want  ModelLimits
+		known bool
+	}{
+		{"gpt-4o-mini-2024-07-18", ModelLimits{128000, 16384}, true},
+		{"gpt-4o", ModelLimits{128000, 16384}, true},
+		{"gpt-4", ModelLimits{8192, 4096}, true},
+		{"openai/gpt-4-turbo", ModelLimits{128000, 4096}, true},
+		{"claude-3-5-sonnet-20241022", ModelLimits{200000, 8192}, true},
+		{"claude-sonnet-4-20250514", ModelLimits{200000, 64000}, true},
+		{"claude-sonnet-4-5", ModelLimits{200000, 64000}, true},
+		{"claude-opus-4-1-20250805", ModelLimits{200000, 32000}, true},
+		{"anthropic/claude-haiku-4-5", ModelLimits{200000, 64000}, true},
+		{"gpt-5", ModelLimits{400000, 128000}, true},
+		{"gpt-5-mini-2025-08-07", ModelLimits{400000, 128000}, true},
+		{"gpt-5-chat-latest", ModelLimits{128000, 16384}, true},
+		{"my-custom-model", DefaultModelLimits, false},
+	}
+	for _, c := range cases {
+		got, known := LookupModelLimits(c.model)
+		if got != c.want || known != c.known {
+			t.Errorf("LookupModelLimits(%q) = %+v, %v; want %+v, %v", c.model, got, known, c.want, c.known)
+		}
+	}
+}
+
+func TestResolveModelLimitsOverrides(t *testing.T) {
+	limits := ResolveModelLimits(config.ProviderConfig{Model: "gpt-4", ContextWindow: 32000})
+	if limits.ContextWindow != 32000 || limits.MaxOutputTokens != 4096 {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+	limits = ResolveModelLimits(config.ProviderConfig{Model: "unknown", MaxOutputTokens: 512})
+	if limits.ContextWindow != DefaultModelLimits.ContextWindow || limits.MaxOutputTokens != 512 {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+}
+
+func TestFitReasoningPromptBudget(t *testing.T) {
+	for _, model := range []string{"gpt-5", "gpt-5-mini", "gpt-5-nano"} {
+		if _, maxTokens := FitReasoningPrompt(ResolveReasoningModelLimits(config.ProviderConfig{Model: model}), "list files"); maxTokens <= 1000 {
+			t.Errorf("%s: completion budget %d leaves no room for reasoning", model, maxTokens)
+		}
+	}
+	// A reasoning model missing from the registry gets a reasoning-sized budget too
+	if _, maxTokens := FitReasoningPrompt(ResolveReasoningModelLimits(config.ProviderConfig{Model: "o9-preview"}), "list files"); maxTokens != ReasoningResponseTokenCap {
+		t.Errorf("unknown model: got %d tokens, want %d", maxTokens, ReasoningResponseTokenCap)
+	}
+	limits := ResolveReasoningModelLimits(config.ProviderConfig{Model: "o9-preview", ContextWindow: 64000})
+	if limits.ContextWindow != 64000 || limits.MaxOutputTokens != ReasoningResponseTokenCap {
+		t.Errorf("unknown reasoning model: unexpected limits %+v", limits)
+	}
+}
+
+func TestFitPrompt(t *testing.T) {
+	short := "Prompt: list files\nJSON:"
+	got, maxTokens := FitPrompt(ModelLimits{ContextWindow: 128000, MaxOutputTokens: 16384}, short)
+	if got != short || maxTokens != ResponseTokenCap {
+		t.Errorf("short prompt: got %d tokens, prompt changed=%v", maxTokens, got != short)
+	}
+
+	// Prompt leaves less room than the model's output limit but enough for an answer
+	medium := strings.Repeat("a", 4*7000)
+	got, maxTokens = FitPrompt(ModelLimits{ContextWindow: 8192, MaxOutputTokens: 4096}, medium)
+	if got != medium || maxTokens >= 4096 || maxTokens < MinResponseTokens {
+		t.Errorf("medium prompt: unexpected budget %d", maxTokens)
+	}
+
+	// Prompt larger than the context window is truncated in the middle
+	long := "HEAD" + strings.Repeat("x", 4*10000) + "JSON:"
+	got, maxTokens = FitPrompt(ModelLimits{ContextWindow: 8192, MaxOutputTokens: 4096}, long)
+	if maxTokens != MinResponseTokens {
+		t.Errorf("long prompt: expected minimum budget, got %d", maxTokens)
+	}
+	if !strings.HasPrefix(got, "HEAD") || !strings.HasSuffix(got, "JSON:") || !strings.Contains(got, "[truncated]") {
+		t.Errorf("long prompt should keep head and tail around a marker")
+	}
+	if EstimateTokens(got)+maxTokens > 8192 {
+		t.Errorf("truncated prompt still exceeds the context window: %d tokens", EstimateTokens(got))
+	}
+}
@@ -1,53 +1,99 @@
 package ollama
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/TonnyWong1052/aish/internal/config"
 	"github.com/TonnyWong1052/aish/internal/llm"
 	"github.com/TonnyWong1052/aish/internal/prompt"
-	"github.com/firebase/genkit/go/genkit"
-	ollamaPlugin "github.com/firebase/genkit/go/plugins/ollama"
 )
 
-// OllamaProvider implements the llm.Provider interface using Genkit.
+// Ollama API structures
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is the body of /api/chat.
+type ChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []Message       `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  *Options        `json:"options,omitempty"`
+}
+
+// GenerateRequest is the body of /api/generate, used with servers that predate /api/chat.
+type GenerateRequest struct {
+	Model   string          `json:"model"`
+	Prompt  string          `json:"prompt"`
+	Stream  bool            `json:"stream"`
+	Format  json.RawMessage `json:"format,omitempty"`
+	Options *Options        `json:"options,omitempty"`
+}
+
+// Options are the model parameters aish sets.
+type Options struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+	NumCtx      int     `json:"num_ctx,omitempty"`
+}
+
+// Response is one object of a response: the whole answer, or one line of a streamed one.
+// /api/chat sets Message, /api/generate sets Response.
+type Response struct {
+	Message  *Message `json:"message,omitempty"`
+	Response string   `json:"response,omitempty"`
+	Done     bool     `json:"done"`
+	Error    string   `json:"error,omitempty"`
+	// Token counts, in the last object of a response
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+type TagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+	Error string `json:"error,omitempty"`
+}
+
+// suggestionFormat, commandFormat and planFormat are JSON schemas of the answers the prompt templates ask
+// for; Ollama constrains the output to them.
+var (
+	suggestionFormat = json.RawMessage(`{"type":"object","properties":{"explanation":{"type":"string"},"command":{"type":"string"}},"required":["explanation","command"]}`)
+	commandFormat    = json.RawMessage(`{"type":"object","properties":{"command":{"type":"string"}},"required":["command"]}`)
+	planFormat       = json.RawMessage(`{"type":"object","properties":{"steps":{"type":"array","items":{"type":"object","properties":{"command":{"type":"string"},"explanation":{"type":"string"}},"required":["command","explanation"]}}},"required":["steps"]}`)
+)
+
+// errChatUnsupported reports a server without /api/chat (Ollama before 0.1.14).
+var errChatUnsupported = errors.New("server does not support /api/chat")
+
+// OllamaProvider implements the llm.Provider interface for a local Ollama server.
 type OllamaProvider struct {
-	cfg     config.ProviderConfig
-	pm      *prompt.Manager
-	genkit  *genkit.Genkit
-	adapter *llm.GenkitAdapter
+	cfg    config.ProviderConfig
+	pm     *prompt.Manager
+	client *http.Client
 }
 
-// NewProvider creates a new OllamaProvider using Genkit.
+// NewProvider creates a new OllamaProvider.
 func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, error) {
-	ctx := context.Background()
-
-	// Initialize Genkit with Ollama plugin
-	g := genkit.Init(ctx,
-		genkit.WithPlugins(&ollamaPlugin.Ollama{
-			ServerAddress: cfg.APIEndpoint, // http://localhost:11434
-		}),
-	)
-
-	// Create adapter with model name
-	// User can specify full model name with prefix (e.g., "ollama/llama3.3")
-	// or just base name (will default to ollama/ prefix)
-	modelName := cfg.Model
-	if !strings.Contains(modelName, "/") {
-		// No prefix provided, use ollama/ as default
-		modelName = "ollama/" + modelName
-	}
-	// If user explicitly provided a prefix (contains /), use it as-is
-	adapter := llm.NewGenkitAdapter(g, modelName)
-
 	return &OllamaProvider{
-		cfg:     cfg,
-		pm:      pm,
-		genkit:  g,
-		adapter: adapter,
+		cfg: cfg,
+		pm:  pm,
+		// Local models can take a while to load before they answer
+		client: llm.WithHeaders(llm.NewPooledClient(2*time.Minute), cfg.ExtraHeaders),
 	}, nil
 }
 
@@ -57,43 +103,53 @@ func init() {
 
 // GetSuggestion implements the llm.Provider interface.
 func (p *OllamaProvider) GetSuggestion(ctx context.Context, capturedContext llm.CapturedContext, lang string) (*llm.Suggestion, error) {
-	promptTemplate, err := p.pm.GetPrompt("get_suggestion", mapLanguage(lang))
+	return p.GetSuggestionStream(ctx, capturedContext, lang, nil)
+}
+
+// GetSuggestionStream implements the llm.Provider interface.
+func (p *OllamaProvider) GetSuggestionStream(ctx context.Context, capturedContext llm.CapturedContext, lang string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
+	promptTemplate, err := p.pm.GetPrompt("get_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prompt template: %w", err)
 	}
 
-	// Execute template
 	data := struct {
 		Command  string
 		Stdout   string
 		Stderr   string
 		ExitCode int
 	}{
-		Command:  capturedContext.Command,
+		Command:  capturedContext.PromptCommand(),
 		Stdout:   capturedContext.Stdout,
 		Stderr:   capturedContext.Stderr,
 		ExitCode: capturedContext.ExitCode,
 	}
 
-	var tpl strings.Builder
+	var tpl bytes.Buffer
 	t := template.Must(template.New("prompt").Parse(promptTemplate))
 	if err := t.Execute(&tpl, data); err != nil {
 		return nil, fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	// Use Genkit adapter to generate
-	response, err := p.adapter.Generate(ctx, tpl.String())
+	response, err := p.exchange(ctx, tpl.String(), suggestionFormat, onChunk)
 	if err != nil {
-		return nil, fmt.Errorf("Ollama generation failed: %w", err)
+		return nil, fmt.Errorf("Ollama request failed: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return parseSuggestionResponse(response)
 }
 
 // GetEnhancedSuggestion implements the llm.Provider interface with enhanced context.
 func (p *OllamaProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx llm.EnhancedCapturedContext, lang string) (*llm.Suggestion, error) {
-	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
+		// Fall back to regular suggestion if enhanced template doesn't exist
 		return p.GetSuggestion(ctx, enhancedCtx.CapturedContext, lang)
 	}
 
@@ -101,85 +157,340 @@ func (p *OllamaProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx
 		"add": func(a, b int) int { return a + b },
 	}
 
-	var tpl strings.Builder
+	var tpl bytes.Buffer
 	t, err := template.New("prompt").Funcs(funcMap).Parse(promptTemplate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to parse enhanced template: %w", err)
 	}
-
 	if err := t.Execute(&tpl, enhancedCtx); err != nil {
-		return nil, fmt.Errorf("failed to execute template: %w", err)
+		return nil, fmt.Errorf("failed to execute enhanced template: %w", err)
 	}
 
-	response, err := p.adapter.Generate(ctx, tpl.String())
+	response, err := p.exchange(ctx, tpl.String(), suggestionFormat, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Ollama enhanced generation failed: %w", err)
+		return nil, fmt.Errorf("Ollama request failed for enhanced suggestion: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return parseSuggestionResponse(response)
 }
 
 // GenerateCommand implements the llm.Provider interface.
 func (p *OllamaProvider) GenerateCommand(ctx context.Context, promptText string, lang string) (string, error) {
-	promptTemplate, err := p.pm.GetPrompt("generate_command", mapLanguage(lang))
+	return p.GenerateCommandStream(ctx, promptText, lang, nil)
+}
+
+// GenerateCommandStream implements the llm.Provider interface.
+func (p *OllamaProvider) GenerateCommandStream(ctx context.Context, promptText string, lang string, onChunk llm.StreamFunc) (string, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_command", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt template: %w", err)
 	}
 
-	data := struct{ Prompt string }{Prompt: promptText}
-	var tpl strings.Builder
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
 	t := template.Must(template.New("prompt").Parse(promptTemplate))
 	if err := t.Execute(&tpl, data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	response, err := p.adapter.Generate(ctx, tpl.String())
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), commandFormat, onChunk)
 	if err != nil {
-		return "", fmt.Errorf("Ollama command generation failed: %w", err)
+		return "", fmt.Errorf("Ollama request failed: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Extract command from response
+	// Prefer JSON output, repaired if need be; otherwise extract the command from the text
+	if cmd, ok := llm.DecodeCommand(ctx, response); ok {
+		return cmd, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	if cmd := extractPlausibleCommand(response); cmd != "" {
 		return cmd, nil
 	}
-	return "", fmt.Errorf("no plausible command found in response")
+	return "", llm.NoCommandError(response)
+}
+
+// GeneratePlan implements llm.Planner: the commands of a multi-step task, in order.
+func (p *OllamaProvider) GeneratePlan(ctx context.Context, promptText string, lang string) (*llm.Plan, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_plan", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), planFormat, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Ollama request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if plan, ok := llm.DecodePlan(ctx, response); ok {
+		return plan, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return llm.SingleStepPlan(cmd), nil
+	}
+	return nil, llm.NoCommandError(response)
+}
+
+// exchange sends message with its output constrained to format, streaming the response to
+// onChunk when it is set.
+func (p *OllamaProvider) exchange(ctx context.Context, message string, format json.RawMessage, onChunk llm.StreamFunc) (string, error) {
+	send := func(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+		return p.generate(ctx, message, format, onChunk)
+	}
+	if onChunk == nil {
+		return llm.Exchange(ctx, message, func(ctx context.Context, message string) (string, error) {
+			return send(ctx, message, nil)
+		})
+	}
+	return llm.ExchangeStream(ctx, message, send, onChunk)
+}
+
+// generate asks the model about message through /api/chat, or /api/generate when the server
+// has no chat endpoint.
+func (p *OllamaProvider) generate(ctx context.Context, message string, format json.RawMessage, onChunk llm.StreamFunc) (string, error) {
+	message, options := p.options(message)
+	out, err := p.post(ctx, "/api/chat", ChatRequest{
+		Model:    p.model(),
+		Messages: []Message{{Role: "user", Content: message}},
+		Stream:   onChunk != nil,
+		Format:   format,
+		Options:  options,
+	}, onChunk)
+	if !errors.Is(err, errChatUnsupported) {
+		return out, err
+	}
+	return p.post(ctx, "/api/generate", GenerateRequest{
+		Model:   p.model(),
+		Prompt:  message,
+		Stream:  onChunk != nil,
+		Format:  format,
+		Options: options,
+	}, onChunk)
+}
+
+// options fits message to the model's context window and returns the model parameters. The
+// context size is only sent when configured: Ollama allocates memory for all of it, so the
+// registry's (often very large) windows would be a poor default.
+func (p *OllamaProvider) options(message string) (string, *Options) {
+	message, maxTokens := llm.FitPrompt(llm.ResolveModelLimits(p.cfg), message)
+	return message, &Options{Temperature: 0.1, NumPredict: maxTokens, NumCtx: p.cfg.ContextWindow}
+}
+
+// model returns the configured model without the "ollama/" prefix earlier versions used.
+func (p *OllamaProvider) model() string {
+	return strings.TrimPrefix(strings.TrimSpace(p.cfg.Model), "ollama/")
+}
+
+// post sends reqBody to path and returns the response text. With onChunk set the response is
+// streamed as newline-delimited JSON and each piece passed on as it arrives.
+func (p *OllamaProvider) post(ctx context.Context, path string, reqBody any, onChunk llm.StreamFunc) (string, error) {
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	resp, err := p.do(ctx, http.MethodPost, path, jsonBody)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", responseError(path, resp.StatusCode, body)
+	}
+	if onChunk != nil {
+		return p.readStream(ctx, resp.Body, onChunk)
+	}
+
+	var r Response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	p.reportUsage(ctx, r)
+	if r.Error != "" {
+		return "", fmt.Errorf("Ollama error: %s", r.Error)
+	}
+	out := strings.TrimSpace(r.text())
+	if out == "" {
+		return "", errors.New("no response content returned")
+	}
+	return out, nil
+}
+
+// reportUsage passes the token counts of the last object of a response to llm.ReportUsage.
+func (p *OllamaProvider) reportUsage(ctx context.Context, r Response) {
+	llm.ReportUsage(ctx, llm.Usage{Model: p.model(), Local: true, PromptTokens: r.PromptEvalCount, CompletionTokens: r.EvalCount})
 }
 
-// VerifyConnection implements the llm.Provider interface.
+// do sends a request to the server. A refused connection almost always means Ollama is not
+// running, so the error says so.
+func (p *OllamaProvider) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.resolveURL(path), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Ollama itself has no keys, but a proxy in front of it may
+	if key := strings.TrimSpace(p.cfg.APIKey); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil && strings.Contains(err.Error(), "connection refused") {
+			return nil, fmt.Errorf("connection refused by %s; is Ollama running ('ollama serve')? %w", p.resolveURL(""), err)
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// responseError describes a failed request. A 404 without an error message means the endpoint
+// itself is missing, which for /api/chat is an old server.
+func responseError(path string, status int, body []byte) error {
+	var r Response
+	if json.Unmarshal(body, &r) == nil && r.Error != "" {
+		return fmt.Errorf("Ollama error (status %d): %s", status, r.Error)
+	}
+	if status == http.StatusNotFound && path == "/api/chat" {
+		return errChatUnsupported
+	}
+	return fmt.Errorf("API request failed with status %d: %s", status, firstN(strings.TrimSpace(string(body)), 512))
+}
+
+// readStream reads a streamed response, one JSON object per line, passing the text of each to
+// onChunk, and returns the whole text.
+func (p *OllamaProvider) readStream(ctx context.Context, r io.Reader, onChunk llm.StreamFunc) (string, error) {
+	var builder strings.Builder
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk Response
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return "", fmt.Errorf("failed to decode response stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return "", fmt.Errorf("Ollama error: %s", chunk.Error)
+		}
+		if delta := chunk.text(); delta != "" {
+			builder.WriteString(delta)
+			onChunk(delta)
+		}
+		if chunk.Done {
+			p.reportUsage(ctx, chunk)
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read response stream: %w", err)
+	}
+	out := strings.TrimSpace(builder.String())
+	if out == "" {
+		return "", errors.New("no content in response stream")
+	}
+	return out, nil
+}
+
+func (r Response) text() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return r.Response
+}
+
+// GetAvailableModels lists the models pulled on the server. The implicit ":latest" tag is
+// dropped, since that is how models are usually configured.
+func (p *OllamaProvider) GetAvailableModels(ctx context.Context) ([]string, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("/api/tags", resp.StatusCode, body)
+	}
+
+	var tags TagsResponse
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		models = append(models, strings.TrimSuffix(name, ":latest"))
+	}
+	return models, nil
+}
+
+// Warmup opens a connection to the server ahead of the first request (llm.Warmer).
+func (p *OllamaProvider) Warmup(ctx context.Context) error {
+	return llm.WarmupEndpoint(ctx, p.client, p.resolveURL(""))
+}
+
+// VerifyConnection implements the llm.Provider interface. It fails when the configured model
+// has not been pulled, since every request would fail the same way.
 func (p *OllamaProvider) VerifyConnection(ctx context.Context) ([]string, error) {
-	// Test generation using Genkit
-	if err := p.adapter.TestGeneration(ctx); err != nil {
-		return nil, fmt.Errorf("Ollama connection verification failed (ensure Ollama is running at %s): %w", p.cfg.APIEndpoint, err)
+	models, err := p.GetAvailableModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Ollama connection verification failed: %w", err)
 	}
+	if model := p.model(); model != "" && !llm.ModelListed(models, strings.TrimSuffix(model, ":latest")) {
+		return nil, fmt.Errorf("Ollama connection verification failed: model %q is not pulled; run 'ollama pull %s'", model, model)
+	}
+	return models, nil
+}
 
-	// Return common Ollama models
-	return []string{
-		"llama3.3",
-		"llama3.1",
-		"codellama",
-		"mistral",
-	}, nil
+// resolveURL joins the configured endpoint with path, dropping an /api suffix from the
+// endpoint so that both "http://host:11434" and "http://host:11434/api" work.
+func (p *OllamaProvider) resolveURL(path string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(p.cfg.APIEndpoint), "/"), "/api")
+	if base == "" {
+		base = config.OllamaAPIEndpoint
+	}
+	return base + path
 }
 
-// Helper functions
-func mapLanguage(lang string) string {
-	switch strings.ToLower(lang) {
-	case "chinese", "zh", "zh-TW", "zh-CN":
-		return "zh-TW"
-	case "english", "en":
-		return "en"
-	default:
-		return "en"
+// firstN returns at most n bytes of s (safe for logging)
+func firstN(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
+	return s[:n]
 }
 
 func parseSuggestionResponse(response string) (*llm.Suggestion, error) {
 	response = strings.TrimSpace(response)
 
 	var explanation, correctedCommand string
-	lines := strings.Split(response, "\n")
-
-	for _, line := range lines {
+	for _, line := range strings.Split(response, "\n") {
 		line = strings.TrimSpace(line)
 		if strings.Contains(strings.ToLower(line), "explanation") {
 			parts := strings.SplitN(line, ":", 2)
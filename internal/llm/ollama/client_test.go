@@ -0,0 +1,123 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func newTestProvider(t *testing.T, model string, handler http.HandlerFunc) *OllamaProvider {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &OllamaProvider{
+		cfg:    config.ProviderConfig{APIEndpoint: srv.URL, Model: model},
+		client: srv.Client(),
+	}
+}
+
+func TestGenerateChat(t *testing.T) {
+	p := newTestProvider(t, "ollama/llama3.3", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "llama3.3" || req.Stream || len(req.Messages) != 1 || string(req.Format) != string(commandFormat) || req.Options.NumCtx != 0 {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		fmt.Fprint(w, `{"model":"llama3.3","message":{"role":"assistant","content":"{\"command\":\"ls\"}"},"done":true}`)
+	})
+
+	got, err := p.generate(context.Background(), "list files", commandFormat, nil)
+	if err != nil || got != `{"command":"ls"}` {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestGenerateChatStream(t *testing.T) {
+	p := newTestProvider(t, "llama3.3", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, piece := range []string{`{\"command\": `, `\"pwd\"}`} {
+			fmt.Fprintf(w, `{"message":{"role":"assistant","content":"%s"},"done":false}`+"\n", piece)
+		}
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":""},"done":true}`+"\n")
+	})
+
+	var pieces []string
+	got, err := p.generate(context.Background(), "where am i", commandFormat, func(d string) { pieces = append(pieces, d) })
+	if err != nil || got != `{"command": "pwd"}` || len(pieces) != 2 {
+		t.Errorf("got %q, %v in %d pieces", got, err, len(pieces))
+	}
+}
+
+func TestGenerateFallsBackToGenerateEndpoint(t *testing.T) {
+	p := newTestProvider(t, "llama2", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/chat":
+			http.NotFound(w, r)
+		case "/api/generate":
+			var req GenerateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Prompt != "list files" {
+				t.Errorf("unexpected request body %+v (%v)", req, err)
+			}
+			fmt.Fprint(w, `{"response":"ls -la","done":true}`)
+		}
+	})
+
+	got, err := p.generate(context.Background(), "list files", commandFormat, nil)
+	if err != nil || got != "ls -la" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestGenerateModelError(t *testing.T) {
+	p := newTestProvider(t, "nope", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"nope\" not found, try pulling it first"}`)
+	})
+
+	_, err := p.generate(context.Background(), "list files", commandFormat, nil)
+	if err == nil || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestVerifyConnection(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"models":[{"name":"llama3.3:latest"},{"name":"qwen2.5-coder:7b"}]}`)
+	}
+
+	models, err := newTestProvider(t, "llama3.3", handler).VerifyConnection(context.Background())
+	if err != nil || len(models) != 2 || models[0] != "llama3.3" {
+		t.Errorf("got %v, %v", models, err)
+	}
+	if _, err := newTestProvider(t, "mistral", handler).VerifyConnection(context.Background()); err == nil || !strings.Contains(err.Error(), "ollama pull mistral") {
+		t.Errorf("missing model not reported: %v", err)
+	}
+}
+
+func TestResolveURL(t *testing.T) {
+	for endpoint, want := range map[string]string{
+		"":                             "http://localhost:11434/api/tags",
+		"http://gpu-box:11434/":        "http://gpu-box:11434/api/tags",
+		"http://gpu-box:11434/api":     "http://gpu-box:11434/api/tags",
+		"https://proxy.example/ollama": "https://proxy.example/ollama/api/tags",
+	} {
+		p := &OllamaProvider{cfg: config.ProviderConfig{APIEndpoint: endpoint}}
+		if got := p.resolveURL("/api/tags"); got != want {
+			t.Errorf("resolveURL with %q = %q, want %q", endpoint, got, want)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/TonnyWong1052/aish/internal/prompt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"text/template"
 	"time"
@@ -26,11 +27,27 @@ type ChatMessage struct {
 type ChatCompletionRequest struct {
 	Model       string        `json:"model"`
 	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
+	// Reasoning (o-series) models reject temperature and max_tokens; they take these instead.
+	MaxCompletionTokens int    `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string `json:"reasoning_effort,omitempty"`
 	// Some OpenAI-compatible proxies may default to streaming when the field is omitted.
 	// Explicitly include stream:false to force a single JSON response and avoid long-lived connections.
 	Stream bool `json:"stream"`
+	// Asks for the usage of a streamed completion, which is otherwise left out; only sent
+	// with --debug, as some compatible backends reject the field.
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// CompletionUsage is the token count of a completion as the API reports it.
+type CompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 type ChatCompletionResponse struct {
@@ -43,10 +60,25 @@ type ChatCompletionResponse struct {
 		Message struct {
 			Role    string `json:"role"`
 			Content string `json:"content"`
+			Refusal string `json:"refusal,omitempty"` // Set instead of Content when the model declines
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Error interface{} `json:"error,omitempty"`
+	Usage *CompletionUsage `json:"usage,omitempty"`
+	Error interface{}      `json:"error,omitempty"`
+}
+
+// ChatCompletionChunk is one event of a streamed chat completion. With include_usage the last
+// one carries the usage of the whole completion and no choices.
+type ChatCompletionChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *CompletionUsage `json:"usage,omitempty"`
+	Error interface{}      `json:"error,omitempty"`
 }
 
 type ModelsResponse struct {
@@ -70,9 +102,7 @@ type OpenAIProvider struct {
 // NewProvider creates a new OpenAIProvider.
 func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, error) {
 	// Increase timeout to better tolerate slower backends or proxies that buffer/stream
-	client := &http.Client{
-		Timeout: 90 * time.Second,
-	}
+	client := llm.WithHeaders(llm.NewPooledClient(90*time.Second), cfg.ExtraHeaders)
 
 	return &OpenAIProvider{
 		cfg:    cfg,
@@ -87,8 +117,13 @@ func init() {
 
 // GetSuggestion implements the llm.Provider interface.
 func (p *OpenAIProvider) GetSuggestion(ctx context.Context, capturedContext llm.CapturedContext, lang string) (*llm.Suggestion, error) {
+	return p.GetSuggestionStream(ctx, capturedContext, lang, nil)
+}
+
+// GetSuggestionStream implements the llm.Provider interface.
+func (p *OpenAIProvider) GetSuggestionStream(ctx context.Context, capturedContext llm.CapturedContext, lang string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
 	// Get the prompt template
-	promptTemplate, err := p.pm.GetPrompt("get_suggestion", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("get_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prompt template: %w", err)
 	}
@@ -100,7 +135,7 @@ func (p *OpenAIProvider) GetSuggestion(ctx context.Context, capturedContext llm.
 		Stderr   string
 		ExitCode int
 	}{
-		Command:  capturedContext.Command,
+		Command:  capturedContext.PromptCommand(),
 		Stdout:   capturedContext.Stdout,
 		Stderr:   capturedContext.Stderr,
 		ExitCode: capturedContext.ExitCode,
@@ -113,40 +148,26 @@ func (p *OpenAIProvider) GetSuggestion(ctx context.Context, capturedContext llm.
 	}
 
 	// Make API request
-	response, err := p.chatCompletion(ctx, tpl.String())
+	response, err := p.exchange(ctx, tpl.String(), onChunk)
 	if err != nil {
 		return nil, fmt.Errorf("OpenAI API request failed: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Prefer JSON output
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Explanation      string `json:"explanation"`
-		Command          string `json:"command"`
-		CorrectedCommand string `json:"corrected_command"`
-		CorrectedCamel   string `json:"correctedCommand"`
-	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil {
-		cmd := obj.Command
-		if cmd == "" {
-			cmd = obj.CorrectedCommand
-		}
-		if cmd == "" {
-			cmd = obj.CorrectedCamel
-		}
-		if strings.TrimSpace(cmd) != "" && strings.TrimSpace(obj.Explanation) != "" {
-			return &llm.Suggestion{Explanation: strings.TrimSpace(obj.Explanation), CorrectedCommand: strings.TrimSpace(cmd)}, nil
-		}
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
 	}
 
 	// Fallback: heuristic parsing
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return p.parseSuggestionResponse(response)
 }
 
 // GetEnhancedSuggestion implements the llm.Provider interface with enhanced context.
 func (p *OpenAIProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx llm.EnhancedCapturedContext, lang string) (*llm.Suggestion, error) {
 	// Get the enhanced prompt template
-	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", prompt.TemplateLanguage(lang))
 	if err != nil {
 		// Fall back to regular suggestion if enhanced template doesn't exist
 		return p.GetSuggestion(ctx, enhancedCtx.CapturedContext, lang)
@@ -171,46 +192,37 @@ func (p *OpenAIProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx
 	}
 
 	// Make API request
-	response, err := p.chatCompletion(ctx, tpl.String())
+	response, err := llm.Exchange(ctx, tpl.String(), p.chatCompletion)
 	if err != nil {
 		return nil, fmt.Errorf("OpenAI API request failed for enhanced suggestion: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-	// Prefer JSON output (same parsing logic as regular GetSuggestion)
-	cleaned := stripCodeFences(response)
-	var obj struct {
-		Explanation      string `json:"explanation"`
-		Command          string `json:"command"`
-		CorrectedCommand string `json:"corrected_command"`
-		CorrectedCamel   string `json:"correctedCommand"`
-	}
-	if err := json.Unmarshal([]byte(cleaned), &obj); err == nil {
-		cmd := obj.Command
-		if cmd == "" {
-			cmd = obj.CorrectedCommand
-		}
-		if cmd == "" {
-			cmd = obj.CorrectedCamel
-		}
-		if strings.TrimSpace(cmd) != "" && strings.TrimSpace(obj.Explanation) != "" {
-			return &llm.Suggestion{Explanation: strings.TrimSpace(obj.Explanation), CorrectedCommand: strings.TrimSpace(cmd)}, nil
-		}
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
 	}
 
 	// Fallback: heuristic parsing
+	llm.ReportParse(ctx, llm.ParseHeuristic)
 	return p.parseSuggestionResponse(response)
 }
 
 // GenerateCommand implements the llm.Provider interface.
 func (p *OpenAIProvider) GenerateCommand(ctx context.Context, promptText string, lang string) (string, error) {
+	return p.GenerateCommandStream(ctx, promptText, lang, nil)
+}
+
+// GenerateCommandStream implements the llm.Provider interface.
+func (p *OpenAIProvider) GenerateCommandStream(ctx context.Context, promptText string, lang string, onChunk llm.StreamFunc) (string, error) {
 	// Get the prompt template
-	promptTemplate, err := p.pm.GetPrompt("generate_command", mapLanguage(lang))
+	promptTemplate, err := p.pm.GetPrompt("generate_command", prompt.TemplateLanguage(lang))
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt template: %w", err)
 	}
 
 	// Execute template with prompt data
-	data := struct{ Prompt string }{Prompt: promptText}
+	data := llm.NewCommandPromptData(ctx, promptText)
 	var tpl bytes.Buffer
 	t := template.Must(template.New("prompt").Parse(promptTemplate))
 	if err := t.Execute(&tpl, data); err != nil {
@@ -218,25 +230,53 @@ func (p *OpenAIProvider) GenerateCommand(ctx context.Context, promptText string,
 	}
 
 	// Make API request
-	response, err := p.chatCompletion(ctx, tpl.String())
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), onChunk)
 	if err != nil {
 		return "", fmt.Errorf("OpenAI API request failed: %w", err)
 	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
 
-    // Prefer JSON output
-    cleaned := stripCodeFences(response)
-    var obj struct {
-        Command string `json:"command"`
-    }
-    if err := json.Unmarshal([]byte(cleaned), &obj); err == nil && strings.TrimSpace(obj.Command) != "" {
-        return strings.TrimSpace(obj.Command), nil
+    // Prefer JSON output, repaired if need be
+    if cmd, ok := llm.DecodeCommand(ctx, response); ok {
+        return cmd, nil
     }
 
     // Fallback: extract plausible shell command; if not found, return empty to avoid executing prose
+    llm.ReportParse(ctx, llm.ParseHeuristic)
     if cmd := extractPlausibleCommand(response); cmd != "" {
         return cmd, nil
     }
-    return "", fmt.Errorf("no plausible command found in provider response")
+    return "", llm.NoCommandError(response)
+}
+
+// GeneratePlan implements llm.Planner: the commands of a multi-step task, in order.
+func (p *OpenAIProvider) GeneratePlan(ctx context.Context, promptText string, lang string) (*llm.Plan, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_plan", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), nil)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if plan, ok := llm.DecodePlan(ctx, response); ok {
+		return plan, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return llm.SingleStepPlan(cmd), nil
+	}
+	return nil, llm.NoCommandError(response)
 }
 
 // extractPlausibleCommand tries to extract a shell-like command from free-form text.
@@ -312,9 +352,12 @@ func plausibleCommand(line string) bool {
 
 // GetAvailableModels fetches all available models from the OpenAI API
 func (p *OpenAIProvider) GetAvailableModels(ctx context.Context) ([]string, error) {
-	if p.cfg.APIKey == "" {
+	if p.cfg.APIKey == "" && p.cfg.APIKeyFile == "" {
 		return nil, errors.New("API key is missing for OpenAI")
 	}
+	if p.cfg.IsAzure() {
+		return p.verifyDeployment(ctx)
+	}
 
 	// 嘗試兩組 URL 變體：
 	// 1) 受管 /v1 前綴（預設） 2) 直接使用基底端點（不追加 /v1）
@@ -340,7 +383,9 @@ func (p *OpenAIProvider) GetAvailableModels(ctx context.Context) ([]string, erro
 			lastErr = firstErr
 			continue
 		}
-		postReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+		if err := p.setAuth(postReq); err != nil {
+			return nil, err
+		}
 		postReq.Header.Set("Content-Type", "application/json")
 
 		resp, err := p.client.Do(postReq)
@@ -363,7 +408,9 @@ func (p *OpenAIProvider) GetAvailableModels(ctx context.Context) ([]string, erro
 				lastErr = firstErr
 				continue
 			}
-			getReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+			if err := p.setAuth(getReq); err != nil {
+				return nil, err
+			}
 			getReq.Header.Set("Content-Type", "application/json")
 			resp, err = p.client.Do(getReq)
 			if err != nil {
@@ -399,6 +446,9 @@ func (p *OpenAIProvider) GetAvailableModels(ctx context.Context) ([]string, erro
 			continue
 		}
 
+		if p.cfg.GatewayCompat {
+			bodyBytes = unwrapGatewayEnvelope(bodyBytes)
+		}
 		// 嘗試解析 JSON
 		var modelsResp ModelsResponse
 		if err := json.Unmarshal(bodyBytes, &modelsResp); err != nil {
@@ -461,12 +511,48 @@ func (p *OpenAIProvider) GetAvailableModels(ctx context.Context) ([]string, erro
 	return nil, fmt.Errorf("failed to fetch models from all endpoint variants")
 }
 
+// verifyDeployment checks an Azure OpenAI deployment with a tiny completion: the data-plane API
+// lists the models of a resource but not its deployments. A deployment serves one model, so
+// that model (or, when none is configured, the deployment) is the one returned.
+func (p *OpenAIProvider) verifyDeployment(ctx context.Context) ([]string, error) {
+	reqBody := p.buildChatRequest("Reply with OK.")
+	// Reasoning models need their budget to think before answering; others need only a word
+	if reqBody.MaxTokens > 0 {
+		reqBody.MaxTokens = 5
+	}
+	resp, err := p.postChatCompletion(ctx, reqBody, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, err := parseCompletionBody(resp.StatusCode, body, p.cfg.GatewayCompat)
+		return nil, fmt.Errorf("Azure deployment %q failed: %w", p.cfg.AzureDeployment, err)
+	}
+	if model := strings.TrimSpace(p.cfg.Model); model != "" {
+		return []string{model}, nil
+	}
+	return []string{p.cfg.AzureDeployment}, nil
+}
+
+// Warmup opens a connection to the API host ahead of the first request (llm.Warmer).
+func (p *OpenAIProvider) Warmup(ctx context.Context) error {
+	return llm.WarmupEndpoint(ctx, p.client, p.cfg.APIEndpoint)
+}
+
 // VerifyConnection implements the llm.Provider interface.
 func (p *OpenAIProvider) VerifyConnection(ctx context.Context) ([]string, error) {
 	models, err := p.GetAvailableModels(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if p.cfg.IsAzure() {
+		return models, nil
+	}
 
 	// Filter for relevant models for verification
 	var filteredModels []string
@@ -484,40 +570,191 @@ func (p *OpenAIProvider) VerifyConnection(ctx context.Context) ([]string, error)
 	return filteredModels, nil
 }
 
+// buildChatRequest creates the request body for message, sizing the completion budget from the
+// model's context window and using the reasoning-model parameters for o-series models.
+func (p *OpenAIProvider) buildChatRequest(message string) ChatCompletionRequest {
+	req := ChatCompletionRequest{
+		Model:  p.cfg.Model,
+		Stream: false, // Explicitly disable streaming to get a single JSON response
+	}
+
+	if isReasoningModel(p.cfg.Model) {
+		message, req.MaxCompletionTokens = llm.FitReasoningPrompt(llm.ResolveReasoningModelLimits(p.cfg), message)
+		req.ReasoningEffort = strings.ToLower(strings.TrimSpace(p.cfg.ReasoningEffort))
+	} else {
+		temperature := 0.1
+		req.Temperature = &temperature
+		message, req.MaxTokens = llm.FitPrompt(llm.ResolveModelLimits(p.cfg), message)
+	}
+	req.Messages = []ChatMessage{{Role: "user", Content: message}}
+	return req
+}
+
+// isReasoningModel reports whether model is an OpenAI reasoning model (o1, o3, o4-mini, gpt-5 families),
+// which reject temperature and max_tokens with HTTP 400. The gpt-5-chat models are chat models and
+// take the usual parameters.
+func isReasoningModel(model string) bool {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if strings.HasPrefix(name, "gpt-5-chat") {
+		return false
+	}
+	for _, family := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if name == family || strings.HasPrefix(name, family+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// exchange sends message, streaming the response to onChunk when it is set.
+func (p *OpenAIProvider) exchange(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	if onChunk == nil {
+		return llm.Exchange(ctx, message, p.chatCompletion)
+	}
+	return llm.ExchangeStream(ctx, message, p.chatCompletionStream, onChunk)
+}
+
 // chatCompletion makes a chat completion request to OpenAI API
 func (p *OpenAIProvider) chatCompletion(ctx context.Context, message string) (string, error) {
-	apiURL := p.resolveURL("/chat/completions")
+	// Request non-streaming JSON responses explicitly (some proxies respect Accept)
+	resp, err := p.postChatCompletion(ctx, p.buildChatRequest(message), "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
 
-	reqBody := ChatCompletionRequest{
-		Model: p.cfg.Model,
-		Messages: []ChatMessage{
-			{
-				Role:    "user",
-				Content: message,
-			},
-		},
-		Temperature: 0.1,
-		MaxTokens:   1000,
-		Stream:      false, // Explicitly disable streaming to get a single JSON response
+	// Read entire body so we can both parse JSON or present helpful text on failure
+	body, readErr := io.ReadAll(resp.Body)
+	if readErr != nil {
+		return "", fmt.Errorf("failed to read response: %w", readErr)
+	}
+	p.reportBodyUsage(ctx, body)
+	content, err := parseCompletionBody(resp.StatusCode, body, p.cfg.GatewayCompat)
+	return content, llm.WithRetryAfter(err, resp.Header)
+}
+
+// chatCompletionStream requests a streamed chat completion and passes each piece of content to
+// onChunk as it arrives. A backend that answers with a single response instead is handled like
+// chatCompletion, its content passed on in one piece.
+func (p *OpenAIProvider) chatCompletionStream(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	reqBody := p.buildChatRequest(message)
+	reqBody.Stream = true
+	if llm.UsageRequested(ctx) && !p.cfg.GatewayCompat {
+		reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	resp, err := p.postChatCompletion(ctx, reqBody, "text/event-stream")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	p.reportUsage(ctx, "", nil) // The model, until a usage event names the one that answered
+	eventStream := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	if resp.StatusCode != http.StatusOK || !eventStream && !p.cfg.GatewayCompat {
+		body, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			return "", fmt.Errorf("failed to read response: %w", readErr)
+		}
+		p.reportBodyUsage(ctx, body)
+		return p.singleResponse(resp, body, onChunk)
+	}
+
+	// Gateways may stream without the text/event-stream type; keep a copy of such a body in
+	// case it turns out to be a single response after all
+	var raw bytes.Buffer
+	stream := io.Reader(resp.Body)
+	if !eventStream {
+		stream = io.TeeReader(resp.Body, &raw)
+	}
+	var builder strings.Builder
+	events := 0
+	err = llm.ReadSSE(stream, func(payload string) error {
+		var chunk ChatCompletionChunk
+		if json.Unmarshal([]byte(payload), &chunk) != nil {
+			return nil
+		}
+		events++
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", apiErrorMessage(chunk.Error))
+		}
+		if chunk.Usage != nil {
+			p.reportUsage(ctx, chunk.Model, chunk.Usage)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			builder.WriteString(chunk.Choices[0].Delta.Content)
+			onChunk(chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+	if !eventStream && events == 0 {
+		if _, readErr := io.Copy(io.Discard, stream); readErr != nil {
+			return "", fmt.Errorf("failed to read response: %w", readErr)
+		}
+		p.reportBodyUsage(ctx, raw.Bytes())
+		return p.singleResponse(resp, raw.Bytes(), onChunk)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read response stream: %w", err)
+	}
+	out := strings.TrimSpace(builder.String())
+	if out == "" {
+		return "", errors.New("no content in response stream")
+	}
+	return out, nil
+}
+
+// reportBodyUsage reports the model and usage of a chat completion response body.
+func (p *OpenAIProvider) reportBodyUsage(ctx context.Context, body []byte) {
+	var r ChatCompletionResponse
+	_ = json.Unmarshal(body, &r)
+	p.reportUsage(ctx, r.Model, r.Usage)
+}
+
+// reportUsage passes the model that answered (the configured one when the response names
+// none) and the token counts of usage, which may be nil, to llm.ReportUsage.
+func (p *OpenAIProvider) reportUsage(ctx context.Context, model string, usage *CompletionUsage) {
+	u := llm.Usage{Model: model}
+	if u.Model == "" {
+		u.Model = p.cfg.Model
+	}
+	if usage != nil {
+		u.PromptTokens, u.CompletionTokens = usage.PromptTokens, usage.CompletionTokens
+	}
+	llm.ReportUsage(ctx, u)
+}
+
+// singleResponse handles a response to a streaming request that came back in one piece,
+// passing its content to onChunk.
+func (p *OpenAIProvider) singleResponse(resp *http.Response, body []byte, onChunk llm.StreamFunc) (string, error) {
+	content, err := parseCompletionBody(resp.StatusCode, body, p.cfg.GatewayCompat)
+	if err == nil && content != "" {
+		onChunk(content)
+	}
+	return content, llm.WithRetryAfter(err, resp.Header)
+}
+
+// postChatCompletion sends reqBody to the chat completions endpoint, retrying transient
+// upstream failures. The caller closes the response body.
+func (p *OpenAIProvider) postChatCompletion(ctx context.Context, reqBody ChatCompletionRequest, accept string) (*http.Response, error) {
+	apiURL := p.resolveURL("/chat/completions")
+	if p.cfg.IsAzure() {
+		apiURL = p.azureURL("/chat/completions")
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Build the request (non-streaming)
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-	// Only set Authorization if we actually have a key; some proxies reject empty Bearer tokens.
-	if strings.TrimSpace(p.cfg.APIKey) != "" {
-		req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	// Request non-streaming JSON responses explicitly (some proxies respect Accept)
-	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept", accept)
 
 	// Basic retry for transient upstream failures (e.g., 502/503/504) or transport errors
 	var resp *http.Response
@@ -528,6 +765,10 @@ func (p *OpenAIProvider) chatCompletion(ctx context.Context, message string) (st
 			// Re-create the request body reader since it is single-use
 			req.Body = io.NopCloser(bytes.NewReader(jsonBody))
 		}
+		// Set on every attempt: a key file may hold a fresher token by now
+		if err := p.setAuth(req); err != nil {
+			return nil, err
+		}
 		resp, doErr = p.client.Do(req)
 		if doErr != nil {
 			// Backoff and retry on network errors
@@ -536,72 +777,59 @@ func (p *OpenAIProvider) chatCompletion(ctx context.Context, message string) (st
 		}
 		// Retry on common transient upstream errors
 		if resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout {
-			// Drain and close body before retry to avoid leaks
-			_, _ = io.Copy(io.Discard, resp.Body)
-			resp.Body.Close()
-			time.Sleep(time.Duration(250*(attempt+1)) * time.Millisecond)
-			continue
+			// Drain and close body before retry to avoid leaks, unless this was the last attempt
+			if attempt < 2 {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				time.Sleep(time.Duration(250*(attempt+1)) * time.Millisecond)
+				continue
+			}
 		}
 		break
 	}
 	if doErr != nil {
-		return "", fmt.Errorf("request failed: %w", doErr)
+		return nil, fmt.Errorf("request failed: %w", doErr)
 	}
-	defer resp.Body.Close()
+	return resp, nil
+}
 
-	// Read entire body so we can both parse JSON or present helpful text on failure
-	body, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		return "", fmt.Errorf("failed to read response: %w", readErr)
+// parseCompletionBody extracts the content of a chat completion response body. With compat
+// (gateway_compat) it also accepts a completion inside a gateway's envelope or without an
+// "object" field, and an event stream that opens with keep-alives.
+func parseCompletionBody(status int, body []byte, compat bool) (string, error) {
+	if compat {
+		body = unwrapGatewayEnvelope(body)
 	}
-
 	// Attempt JSON decode first (non-streaming)
 	var completion ChatCompletionResponse
-	if err := json.Unmarshal(body, &completion); err == nil && completion.Object != "" {
+	if err := json.Unmarshal(body, &completion); err == nil && (completion.Object != "" || compat && (len(completion.Choices) > 0 || completion.Error != nil)) {
 		if completion.Error != nil {
-			var errMsg string
-			if errMap, ok := completion.Error.(map[string]interface{}); ok {
-				if msg, ok := errMap["message"].(string); ok {
-					errMsg = msg
-				}
-			}
-			if errMsg == "" {
-				errMsg = fmt.Sprintf("%v", completion.Error)
-			}
-			return "", fmt.Errorf("API error: %s", errMsg)
+			return "", fmt.Errorf("API error: %s", apiErrorMessage(completion.Error))
 		}
 		if len(completion.Choices) == 0 {
 			return "", errors.New("no response choices returned")
 		}
-		return completion.Choices[0].Message.Content, nil
+		choice := completion.Choices[0]
+		switch {
+		case choice.Message.Refusal != "":
+			return "", llm.RefusalError(choice.Message.Refusal)
+		case choice.FinishReason == "content_filter" && strings.TrimSpace(choice.Message.Content) == "":
+			return "", llm.RefusalError("blocked by the content filter (finish_reason content_filter)")
+		}
+		return choice.Message.Content, nil
 	}
 
 	// Attempt to parse Server-Sent Events (streaming) format if present
 	trimmed := strings.TrimSpace(string(body))
-	if strings.HasPrefix(trimmed, "data:") || strings.Contains(trimmed, "chat.completion.chunk") {
+	if strings.HasPrefix(trimmed, "data:") || strings.Contains(trimmed, "chat.completion.chunk") || compat && hasSSEData(trimmed) {
 		var builder strings.Builder
-		for _, line := range strings.Split(trimmed, "\n") {
-			line = strings.TrimSpace(line)
-			if !strings.HasPrefix(line, "data:") {
-				continue
-			}
-			payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-			if payload == "[DONE]" || payload == "" {
-				continue
-			}
-			var chunk struct {
-				Choices []struct {
-					Delta struct {
-						Content string `json:"content"`
-					} `json:"delta"`
-				} `json:"choices"`
-			}
-			if err := json.Unmarshal([]byte(payload), &chunk); err == nil {
-				if len(chunk.Choices) > 0 {
-					builder.WriteString(chunk.Choices[0].Delta.Content)
-				}
+		_ = llm.ReadSSE(strings.NewReader(trimmed), func(payload string) error {
+			var chunk ChatCompletionChunk
+			if err := json.Unmarshal([]byte(payload), &chunk); err == nil && len(chunk.Choices) > 0 {
+				builder.WriteString(chunk.Choices[0].Delta.Content)
 			}
-		}
+			return nil
+		})
 		out := strings.TrimSpace(builder.String())
 		if out != "" {
 			return out, nil
@@ -609,15 +837,62 @@ func (p *OpenAIProvider) chatCompletion(ctx context.Context, message string) (st
 	}
 
 	// If non-200 or not JSON, return body as a plain string so callers can try heuristic parsing
-	if resp.StatusCode != 200 {
+	if status != 200 {
 		if trimmed == "" {
-			return "", fmt.Errorf("API request failed with status %d", resp.StatusCode)
+			return "", fmt.Errorf("API request failed with status %d", status)
 		}
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, firstN(trimmed, 512))
+		return "", fmt.Errorf("API request failed with status %d: %s", status, firstN(trimmed, 512))
 	}
 
 	// 200 but not standard JSON; treat as plain text content
-	return strings.TrimSpace(string(body)), nil
+	return trimmed, nil
+}
+
+// unwrapGatewayEnvelope returns the response a gateway wrapped in an envelope, such as
+// Cloudflare's {"success":true,"result":{...}} or a {"data":{...}} wrapper, or else body.
+func unwrapGatewayEnvelope(body []byte) []byte {
+	for depth := 0; depth < 3; depth++ {
+		var envelope map[string]json.RawMessage
+		if json.Unmarshal(body, &envelope) != nil {
+			return body
+		}
+		if _, ok := envelope["choices"]; ok {
+			return body
+		}
+		inner := body
+		for _, key := range []string{"result", "data", "response"} {
+			if v := bytes.TrimSpace(envelope[key]); bytes.HasPrefix(v, []byte("{")) {
+				inner = v
+				break
+			}
+		}
+		if bytes.Equal(inner, body) {
+			return body
+		}
+		body = inner
+	}
+	return body
+}
+
+// hasSSEData reports whether body holds a server-sent event with data, even when keep-alive
+// comments or other fields come first.
+func hasSSEData(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "data:") {
+			return true
+		}
+	}
+	return false
+}
+
+// apiErrorMessage returns the message of an API error object, or the object itself.
+func apiErrorMessage(apiErr interface{}) string {
+	if errMap, ok := apiErr.(map[string]interface{}); ok {
+		if msg, ok := errMap["message"].(string); ok && msg != "" {
+			return msg
+		}
+	}
+	return fmt.Sprintf("%v", apiErr)
 }
 
 // parseSuggestionResponse parses the OpenAI response to extract explanation and command
@@ -726,33 +1001,6 @@ func (p *OpenAIProvider) parseSuggestionResponse(response string) (*llm.Suggesti
 	}, nil
 }
 
-// mapLanguage maps user language preferences to template language codes
-func mapLanguage(lang string) string {
-	switch strings.ToLower(lang) {
-	case "chinese", "zh", "中文", "繁體中文":
-		return "zh-TW"
-	case "english", "en":
-		return "en"
-	default:
-		return "en"
-	}
-}
-
-// stripCodeFences removes common markdown code fences and json hints.
-func stripCodeFences(s string) string {
-	s = strings.TrimSpace(s)
-	// Remove triple backticks block, optionally starting with ```json
-	if _, ok := strings.CutPrefix(s, "```"); ok {
-		s = strings.TrimSpace(s)
-		if strings.HasPrefix(strings.ToLower(s), "json") {
-			s = strings.TrimSpace(s[4:])
-		}
-		if idx := strings.LastIndex(s, "```"); idx != -1 {
-			s = s[:idx]
-		}
-	}
-	return strings.TrimSpace(strings.Trim(s, "`"))
-}
 
 // firstN returns at most n bytes of s (safe for logging)
 func firstN(s string, n int) string {
@@ -762,6 +1010,38 @@ func firstN(s string, n int) string {
 	return s[:n]
 }
 
+// setAuth adds the API key to req: Azure OpenAI takes it in an api-key header, everything
+// else as a Bearer token. The key of api_key_file is read anew each time; no key at all sends
+// no header, as some proxies reject empty Bearer tokens.
+func (p *OpenAIProvider) setAuth(req *http.Request) error {
+	key, err := p.cfg.ResolveAPIKey()
+	if err != nil {
+		return llm.NewLLMError(llm.AuthError, "cannot read the API key", err)
+	}
+	if strings.TrimSpace(key) == "" {
+		return nil
+	}
+	if p.cfg.IsAzure() {
+		req.Header.Set("api-key", key)
+		return nil
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	return nil
+}
+
+// azureURL returns the URL of subpath under the configured Azure deployment, with the API
+// version as a query parameter. The endpoint is the resource URL
+// (https://<resource>.openai.azure.com); a trailing /openai is tolerated.
+func (p *OpenAIProvider) azureURL(subpath string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(p.cfg.APIEndpoint), "/"), "/openai")
+	version := strings.TrimSpace(p.cfg.AzureAPIVersion)
+	if version == "" {
+		version = config.DefaultAzureAPIVersion
+	}
+	return base + "/openai/deployments/" + url.PathEscape(strings.TrimSpace(p.cfg.AzureDeployment)) + subpath +
+		"?api-version=" + url.QueryEscape(version)
+}
+
 // resolveURL intelligently joins the configured API endpoint with a subpath.
 func (p *OpenAIProvider) resolveURL(subpath string) string {
 	base := strings.TrimSuffix(p.cfg.APIEndpoint, "/")
@@ -0,0 +1,317 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+func TestIsReasoningModel(t *testing.T) {
+	cases := map[string]bool{
+		"o1":                true,
+		"o1-mini":           true,
+		"o3-mini":           true,
+		"o4-mini":           true,
+		"openai/o3":         true,
+		"gpt-5-mini":        true,
+		"gpt-5-chat-latest": false,
+		"openai/gpt-5-chat": false,
+		"gpt-4o":            false,
+		"gpt-4o-mini":       false,
+		"gpt-3.5-turbo":     false,
+		"ollama/llama3":     false,
+		"o10-something-new": false,
+	}
+	for model, want := range cases {
+		if got := isReasoningModel(model); got != want {
+			t.Errorf("isReasoningModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestBuildChatRequestReasoningModel(t *testing.T) {
+	p := &OpenAIProvider{cfg: config.ProviderConfig{Model: "o3-mini", ReasoningEffort: "High"}}
+	body, err := json.Marshal(p.buildChatRequest("list files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(body)
+	for _, unsupported := range []string{`"temperature"`, `"max_tokens"`} {
+		if strings.Contains(s, unsupported) {
+			t.Errorf("reasoning request must not contain %s: %s", unsupported, s)
+		}
+	}
+	for _, want := range []string{`"max_completion_tokens":25000`, `"reasoning_effort":"high"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("reasoning request missing %s: %s", want, s)
+		}
+	}
+}
+
+func TestBuildChatRequestGPT5Budget(t *testing.T) {
+	p := &OpenAIProvider{cfg: config.ProviderConfig{Model: "gpt-5"}}
+	req := p.buildChatRequest("list files")
+	if req.MaxCompletionTokens <= 1000 || req.MaxTokens != 0 {
+		t.Errorf("gpt-5 request: max_completion_tokens=%d, max_tokens=%d", req.MaxCompletionTokens, req.MaxTokens)
+	}
+}
+
+func TestBuildChatRequestChatModel(t *testing.T) {
+	p := &OpenAIProvider{cfg: config.ProviderConfig{Model: "gpt-4o", ReasoningEffort: "high"}}
+	body, err := json.Marshal(p.buildChatRequest("list files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(body)
+	for _, want := range []string{`"temperature":0.1`, `"max_tokens":4096`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("chat request missing %s: %s", want, s)
+		}
+	}
+	for _, unsupported := range []string{`"max_completion_tokens"`, `"reasoning_effort"`} {
+		if strings.Contains(s, unsupported) {
+			t.Errorf("chat request must not contain %s: %s", unsupported, s)
+		}
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Stream {
+			t.Errorf("expected a streaming request, got %+v (%v)", req, err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, piece := range []string{`{"command":`, ` "ls"}`} {
+			chunk, _ := json.Marshal(map[string]interface{}{
+				"choices": []map[string]interface{}{{"delta": map[string]string{"content": piece}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{cfg: config.ProviderConfig{APIEndpoint: srv.URL, Model: "gpt-4o"}, client: srv.Client()}
+	var pieces []string
+	got, err := p.chatCompletionStream(context.Background(), "list files", func(delta string) { pieces = append(pieces, delta) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != `{"command": "ls"}` || len(pieces) != 2 {
+		t.Errorf("got %q in %d pieces", got, len(pieces))
+	}
+}
+
+func TestChatCompletionStreamReportsUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Errorf("expected a request for the usage, got %+v (%v)", req, err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"model":"gpt-4o-2024-08-06","choices":[{"delta":{"content":"{\"command\": \"ls\"}"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"model":"gpt-4o-2024-08-06","choices":[],"usage":{"prompt_tokens":120,"completion_tokens":9}}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	var usage llm.Usage
+	ctx := llm.WithUsageReporter(context.Background(), func(u llm.Usage) { usage = u })
+	p := &OpenAIProvider{cfg: config.ProviderConfig{APIEndpoint: srv.URL, Model: "gpt-4o"}, client: srv.Client()}
+	if _, err := llm.ExchangeStream(ctx, "list files", p.chatCompletionStream, func(string) {}); err != nil {
+		t.Fatal(err)
+	}
+	if usage.Model != "gpt-4o-2024-08-06" || usage.PromptTokens != 120 || usage.CompletionTokens != 9 {
+		t.Errorf("reported %+v", usage)
+	}
+}
+
+func TestChatCompletionStreamWholeResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"chat.completion","choices":[{"message":{"content":"hello"}}]}`)
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{cfg: config.ProviderConfig{APIEndpoint: srv.URL, Model: "gpt-4o"}, client: srv.Client()}
+	var pieces []string
+	got, err := p.chatCompletionStream(context.Background(), "hi", func(delta string) { pieces = append(pieces, delta) })
+	if err != nil || got != "hello" || len(pieces) != 1 || pieces[0] != "hello" {
+		t.Errorf("got %q, %v, pieces %q", got, err, pieces)
+	}
+}
+
+func TestAzureChatCompletion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/prod-gpt4o/chat/completions" || r.URL.Query().Get("api-version") != config.DefaultAzureAPIVersion {
+			t.Errorf("unexpected URL %s", r.URL)
+		}
+		if r.Header.Get("api-key") != "azure-key" || r.Header.Get("Authorization") != "" {
+			t.Errorf("unexpected auth headers %v", r.Header)
+		}
+		fmt.Fprint(w, `{"object":"chat.completion","choices":[{"message":{"content":"ls"}}]}`)
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{
+		cfg:    config.ProviderConfig{APIEndpoint: srv.URL + "/openai/", APIKey: "azure-key", Model: "gpt-4o", AzureDeployment: "prod-gpt4o"},
+		client: srv.Client(),
+	}
+	if got, err := p.chatCompletion(context.Background(), "list files"); err != nil || got != "ls" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if models, err := p.VerifyConnection(context.Background()); err != nil || len(models) != 1 || models[0] != "gpt-4o" {
+		t.Errorf("got %v, %v", models, err)
+	}
+}
+
+func TestAPIKeyFileIsReadPerRequest(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"object":"chat.completion","choices":[{"message":{"content":"ls"}}]}`)
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	p := &OpenAIProvider{
+		cfg:    config.ProviderConfig{APIEndpoint: srv.URL, APIKey: "sk-static", APIKeyFile: tokenFile, Model: "gpt-4o"},
+		client: srv.Client(),
+	}
+	for _, token := range []string{"oidc-token-1", "oidc-token-2"} {
+		// An OIDC helper rotates the short-lived token between requests
+		if err := os.WriteFile(tokenFile, []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.chatCompletion(context.Background(), "list files"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 2 || got[0] != "Bearer oidc-token-1" || got[1] != "Bearer oidc-token-2" {
+		t.Errorf("Authorization headers = %q", got)
+	}
+
+	os.Remove(tokenFile)
+	_, err := p.chatCompletion(context.Background(), "list files")
+	if llmErr := llm.Classify("openai", err); err == nil || llmErr.Type != llm.AuthError {
+		t.Errorf("missing key file: got %v, want an auth error", err)
+	}
+	if len(got) != 2 {
+		t.Error("a request was sent without the key")
+	}
+}
+
+func TestAzureDeploymentNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":"DeploymentNotFound","message":"The API deployment for this resource does not exist."}}`)
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{
+		cfg:    config.ProviderConfig{APIEndpoint: srv.URL, APIKey: "azure-key", AzureDeployment: "missing", AzureAPIVersion: "2025-01-01-preview"},
+		client: srv.Client(),
+	}
+	if _, err := p.VerifyConnection(context.Background()); err == nil || !strings.Contains(err.Error(), "DeploymentNotFound") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestChatCompletionRateLimitReset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`)
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{cfg: config.ProviderConfig{APIEndpoint: srv.URL, Model: "gpt-4o"}, client: srv.Client()}
+	_, err := p.chatCompletion(context.Background(), "list files")
+	if wait, ok := llm.RetryAfter(err); !ok || wait < 41*time.Second || wait > 42*time.Second {
+		t.Errorf("reset not kept: %v, %v (%v)", wait, ok, err)
+	}
+}
+
+func TestParseCompletionBodyRefusal(t *testing.T) {
+	for name, body := range map[string]string{
+		"refusal":        `{"object":"chat.completion","choices":[{"message":{"role":"assistant","content":"","refusal":"I can't help with that."},"finish_reason":"stop"}]}`,
+		"content_filter": `{"object":"chat.completion","choices":[{"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`,
+	} {
+		var llmErr *llm.LLMError
+		if _, err := parseCompletionBody(http.StatusOK, []byte(body), false); !errors.As(err, &llmErr) || llmErr.Type != llm.ContentFilterError {
+			t.Errorf("%s: expected a content filter error, got %v", name, err)
+		}
+	}
+}
+
+func TestParseCompletionBodyGatewayCompat(t *testing.T) {
+	for name, body := range map[string]string{
+		"no object":  `{"id":"x","choices":[{"message":{"content":"ls -la"}}]}`,
+		"cloudflare": `{"success":true,"errors":[],"messages":[],"result":{"choices":[{"message":{"content":"ls -la"}}]}}`,
+		"data":       `{"data":{"object":"chat.completion","choices":[{"message":{"content":"ls -la"}}]},"litellm_call_id":"abc"}`,
+		"keep-alive": ": keep-alive\n\nevent: ping\ndata: {\"choices\":[{\"delta\":{\"content\":\"ls \"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"-la\"}}]}\n\ndata: [DONE]\n",
+	} {
+		if got, err := parseCompletionBody(http.StatusOK, []byte(body), true); err != nil || got != "ls -la" {
+			t.Errorf("%s: got %q, %v", name, got, err)
+		}
+		// The strict path hands such bodies back as text
+		if got, _ := parseCompletionBody(http.StatusOK, []byte(body), false); got == "ls -la" {
+			t.Errorf("%s: decoded without gateway_compat", name)
+		}
+	}
+}
+
+func TestChatCompletionStreamGatewayCompat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A gateway that drops the text/event-stream type and sends keep-alives first
+		w.Header().Set("Content-Type", "application/octet-stream")
+		fmt.Fprint(w, ": keep-alive\n\ndata: \n\ndata: {\"choices\":[{\"delta\":{\"content\":\"ls \"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"-la\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{cfg: config.ProviderConfig{APIEndpoint: srv.URL, Model: "gpt-4o", GatewayCompat: true}, client: srv.Client()}
+	var pieces []string
+	got, err := p.chatCompletionStream(context.Background(), "list files", func(delta string) { pieces = append(pieces, delta) })
+	if err != nil || got != "ls -la" || len(pieces) != 2 {
+		t.Errorf("got %q, %v in pieces %q", got, err, pieces)
+	}
+
+	// A single response without the object field still arrives in one piece
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"choices":[{"message":{"content":"hello"}}]},"success":true}`)
+	})
+	pieces = nil
+	got, err = p.chatCompletionStream(context.Background(), "hi", func(delta string) { pieces = append(pieces, delta) })
+	if err != nil || got != "hello" || len(pieces) != 1 {
+		t.Errorf("got %q, %v in pieces %q", got, err, pieces)
+	}
+}
+
+func TestGeneratePlan(t *testing.T) {
+	content := `{"steps":[{"command":"python3 -m venv .venv","explanation":"Create the environment."},{"command":".venv/bin/pip install -r requirements.txt","explanation":"Install the requirements."}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(map[string]any{"object": "chat.completion", "choices": []any{map[string]any{"message": map[string]string{"content": content}}}})
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{cfg: config.ProviderConfig{APIEndpoint: srv.URL, Model: "gpt-4o"}, pm: prompt.NewDefaultManager(), client: srv.Client()}
+	plan, err := p.GeneratePlan(context.Background(), "set up a python venv and install requirements", "en")
+	if err != nil || len(plan.Steps) != 2 || plan.Steps[1].Command != ".venv/bin/pip install -r requirements.txt" || plan.Steps[0].Explanation != "Create the environment." {
+		t.Errorf("GeneratePlan = %+v, %v", plan, err)
+	}
+}
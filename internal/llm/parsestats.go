@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// ParseStatsFileName is the per-provider parse counter file inside the state directory.
+const ParseStatsFileName = "parse_stats.json"
+
+// ParseCounts counts how one provider's responses were parsed.
+type ParseCounts struct {
+	Strict    int `json:"strict"`
+	Repaired  int `json:"repaired"`
+	Heuristic int `json:"heuristic"`
+}
+
+// Total is the number of responses counted.
+func (c ParseCounts) Total() int {
+	return c.Strict + c.Repaired + c.Heuristic
+}
+
+// ParseStats counts, per provider, how often responses were valid JSON, needed RepairJSON or
+// fell back to heuristics, which shows how well each provider follows the prompt's format.
+type ParseStats struct {
+	Since     time.Time              `json:"since"`
+	Providers map[string]ParseCounts `json:"providers"`
+	path      string
+}
+
+// ParseStatsPath returns the location of the parse counter file.
+func ParseStatsPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ParseStatsFileName), nil
+}
+
+// LoadParseStats reads the counters from path. A missing or corrupt file yields empty counters.
+func LoadParseStats(path string) *ParseStats {
+	s := &ParseStats{Providers: map[string]ParseCounts{}, path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	if json.Unmarshal(data, s) != nil || s.Providers == nil {
+		s.Providers = map[string]ParseCounts{}
+	}
+	return s
+}
+
+// Record counts one response of provider parsed with method. An empty method (the provider
+// does not report one) is ignored.
+func (s *ParseStats) Record(provider string, method ParseMethod, now time.Time) {
+	c := s.Providers[provider]
+	switch method {
+	case ParseStrict:
+		c.Strict++
+	case ParseRepaired:
+		c.Repaired++
+	case ParseHeuristic:
+		c.Heuristic++
+	default:
+		return
+	}
+	if s.Since.IsZero() {
+		s.Since = now
+	}
+	s.Providers[provider] = c
+}
+
+// Names returns the providers with counts, sorted.
+func (s *ParseStats) Names() []string {
+	names := make([]string, 0, len(s.Providers))
+	for name := range s.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the counters back to the file they were loaded from.
+func (s *ParseStats) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Reset clears the counters.
+func (s *ParseStats) Reset() error {
+	s.Since = time.Time{}
+	s.Providers = map[string]ParseCounts{}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase is one step of producing a suggestion, reported to the loading UI and debug output.
+type Phase string
+
+const (
+	PhaseCollecting Phase = "collecting context"
+	PhaseContacting Phase = "contacting provider"
+	PhaseParsing    Phase = "parsing response"
+)
+
+// PhaseTiming is the time spent in one phase.
+type PhaseTiming struct {
+	Phase    Phase
+	Duration time.Duration
+}
+
+// PhaseTracker records how long each phase takes. Providers advance it through ReportPhase,
+// so callers see the parsing step without knowing how a provider parses.
+type PhaseTracker struct {
+	mu       sync.Mutex
+	timings  []PhaseTiming
+	current  Phase
+	started  time.Time
+	onChange func(Phase)
+	nowFunc  func() time.Time
+}
+
+// NewPhaseTracker returns a tracker that calls onChange (if non-nil) whenever a phase starts.
+func NewPhaseTracker(onChange func(Phase)) *PhaseTracker {
+	return &PhaseTracker{onChange: onChange, nowFunc: time.Now}
+}
+
+// Start ends the current phase, if any, and begins phase. Restarting the current phase is a no-op.
+func (t *PhaseTracker) Start(phase Phase) {
+	t.mu.Lock()
+	if phase == t.current {
+		t.mu.Unlock()
+		return
+	}
+	now := t.nowFunc()
+	t.closeLocked(now)
+	t.current = phase
+	t.started = now
+	onChange := t.onChange
+	t.mu.Unlock()
+
+	if onChange != nil {
+		onChange(phase)
+	}
+}
+
+// Finish ends the current phase and returns the timings recorded since the previous Finish,
+// in the order the phases ran.
+func (t *PhaseTracker) Finish() []PhaseTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeLocked(t.nowFunc())
+	t.current = ""
+	timings := t.timings
+	t.timings = nil
+	return timings
+}
+
+func (t *PhaseTracker) closeLocked(now time.Time) {
+	if t.current == "" {
+		return
+	}
+	t.timings = append(t.timings, PhaseTiming{Phase: t.current, Duration: now.Sub(t.started)})
+}
+
+// FormatPhaseTimings renders timings as "collecting context 12ms → contacting provider 1.4s → ...".
+func FormatPhaseTimings(timings []PhaseTiming) string {
+	parts := make([]string, 0, len(timings))
+	for _, pt := range timings {
+		d := pt.Duration.Round(time.Millisecond)
+		if pt.Duration < time.Millisecond {
+			d = pt.Duration.Round(time.Microsecond)
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", pt.Phase, d))
+	}
+	return strings.Join(parts, " → ")
+}
+
+type phaseTrackerKey struct{}
+
+// WithPhaseTracker attaches t to ctx so providers can report their phases.
+func WithPhaseTracker(ctx context.Context, t *PhaseTracker) context.Context {
+	return context.WithValue(ctx, phaseTrackerKey{}, t)
+}
+
+// ReportPhase starts phase on the tracker attached to ctx, if there is one.
+func ReportPhase(ctx context.Context, phase Phase) {
+	if t, ok := ctx.Value(phaseTrackerKey{}).(*PhaseTracker); ok && t != nil {
+		t.Start(phase)
+	}
+}
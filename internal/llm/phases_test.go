@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPhaseTrackerTimings(t *testing.T) {
+	now := time.Unix(0, 0)
+	var seen []Phase
+	tr := NewPhaseTracker(func(p Phase) { seen = append(seen, p) })
+	tr.nowFunc = func() time.Time { return now }
+
+	tr.Start(PhaseCollecting)
+	now = now.Add(20 * time.Millisecond)
+	ctx := WithPhaseTracker(context.Background(), tr)
+	ReportPhase(ctx, PhaseContacting)
+	ReportPhase(ctx, PhaseContacting) // repeated reports do not split the phase
+	now = now.Add(1500 * time.Millisecond)
+	ReportPhase(ctx, PhaseParsing)
+	now = now.Add(3 * time.Millisecond)
+
+	timings := tr.Finish()
+	want := []PhaseTiming{
+		{PhaseCollecting, 20 * time.Millisecond},
+		{PhaseContacting, 1500 * time.Millisecond},
+		{PhaseParsing, 3 * time.Millisecond},
+	}
+	if len(timings) != len(want) {
+		t.Fatalf("got %v, want %v", timings, want)
+	}
+	for i := range want {
+		if timings[i] != want[i] {
+			t.Errorf("timing %d = %v, want %v", i, timings[i], want[i])
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("onChange called for %v", seen)
+	}
+	if got := FormatPhaseTimings(timings); got != "collecting context 20ms → contacting provider 1.5s → parsing response 3ms" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if again := tr.Finish(); len(again) != 0 {
+		t.Errorf("second Finish should be empty, got %v", again)
+	}
+}
+
+func TestReportPhaseWithoutTracker(t *testing.T) {
+	ReportPhase(context.Background(), PhaseParsing) // must not panic
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PipelineStage identifies the stage of a failed pipeline (a | b | c) that caused the failure.
+type PipelineStage struct {
+	Index    int    `json:"index"`   // 1-based position in the pipeline
+	Total    int    `json:"total"`   // Number of stages
+	Command  string `json:"command"` // The stage's command; empty when the pipeline could not be split
+	ExitCode int    `json:"exitCode"`
+}
+
+// sigpipeStatus is the exit status of a stage killed by SIGPIPE because a later stage stopped
+// reading; it is a symptom of another stage's failure, never the cause.
+const sigpipeStatus = 141
+
+// ParsePipeStatus parses the space-separated exit statuses the hook reports from
+// PIPESTATUS (bash) or pipestatus (zsh). Invalid input yields nil.
+func ParsePipeStatus(s string) []int {
+	fields := strings.Fields(s)
+	statuses := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil
+		}
+		statuses = append(statuses, n)
+	}
+	return statuses
+}
+
+// FailedPipelineStage returns the stage of command that failed, given the exit status of every
+// stage. The first failing stage is chosen, since later stages usually fail only because they
+// got no input; stages killed by SIGPIPE are skipped. It reports false for a single command or
+// when no stage failed.
+func FailedPipelineStage(command string, statuses []int) (PipelineStage, bool) {
+	if len(statuses) < 2 {
+		return PipelineStage{}, false
+	}
+	idx := -1
+	for i, st := range statuses {
+		if st != 0 && st != sigpipeStatus {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return PipelineStage{}, false
+	}
+	stage := PipelineStage{Index: idx + 1, Total: len(statuses), ExitCode: statuses[idx]}
+	if stages := SplitPipeline(command); len(stages) == len(statuses) {
+		stage.Command = stages[idx]
+	}
+	return stage, true
+}
+
+// SplitPipeline splits command on the pipe operators | and |& that are outside quotes,
+// parentheses and braces, returning the trimmed stages. Logical || is not a pipe.
+func SplitPipeline(command string) []string {
+	var stages []string
+	var quote rune
+	depth, start := 0, 0
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && quote != '\'':
+			i++
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(' || r == '{':
+			depth++
+		case (r == ')' || r == '}') && depth > 0:
+			depth--
+		case r == '|' && depth == 0:
+			if i+1 < len(runes) && runes[i+1] == '|' {
+				i++
+				continue
+			}
+			stages = append(stages, strings.TrimSpace(string(runes[start:i])))
+			if i+1 < len(runes) && runes[i+1] == '&' {
+				i++
+			}
+			start = i + 1
+		}
+	}
+	return append(stages, strings.TrimSpace(string(runes[start:])))
+}
+
+// PromptCommand returns the command as it should appear in a prompt: for a failed pipeline it
+// names the failing stage, so the suggestion targets that command rather than the whole pipeline,
+// and when the command is an alias or function it adds what that actually runs. Notes follow last.
+func (c CapturedContext) PromptCommand() string {
+	cmd := c.Command
+	if s := c.FailedStage; s != nil {
+		if s.Command == "" {
+			cmd += fmt.Sprintf("\n(Pipeline stage %d of %d failed with exit code %d)", s.Index, s.Total, s.ExitCode)
+		} else {
+			cmd += fmt.Sprintf("\n(Pipeline stage %d of %d failed with exit code %d: %s)", s.Index, s.Total, s.ExitCode, s.Command)
+		}
+	}
+	if exp := strings.TrimSpace(c.Expansion); exp != "" {
+		cmd += "\n(Defined in the user's shell as: " + exp + ")"
+	}
+	for _, note := range c.Notes {
+		cmd += "\n(" + note + ")"
+	}
+	return cmd
+}
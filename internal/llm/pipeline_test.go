@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitPipeline(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls", []string{"ls"}},
+		{"cat a.txt | grep foo | wc -l", []string{"cat a.txt", "grep foo", "wc -l"}},
+		{"make |& tee build.log", []string{"make", "tee build.log"}},
+		{`grep "a|b" file | sort`, []string{`grep "a|b" file`, "sort"}},
+		{"test -f x || echo missing | cat", []string{"test -f x || echo missing", "cat"}},
+		{"(a | b) | c", []string{"(a | b)", "c"}},
+		{`echo a\|b | cat`, []string{`echo a\|b`, "cat"}},
+	}
+	for _, tt := range tests {
+		if got := SplitPipeline(tt.command); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitPipeline(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestFailedPipelineStage(t *testing.T) {
+	stage, ok := FailedPipelineStage("cat missing.txt | grep foo", ParsePipeStatus("1 1"))
+	if !ok {
+		t.Fatal("expected a failed stage")
+	}
+	want := PipelineStage{Index: 1, Total: 2, Command: "cat missing.txt", ExitCode: 1}
+	if stage != want {
+		t.Errorf("got %+v, want %+v", stage, want)
+	}
+
+	// A SIGPIPE'd producer is a symptom of the consumer failing
+	stage, ok = FailedPipelineStage("yes | head -n x", []int{141, 1})
+	if !ok || stage.Index != 2 || stage.Command != "head -n x" {
+		t.Errorf("expected the head stage, got %+v (ok=%v)", stage, ok)
+	}
+
+	// Stage count does not match the command: keep the index, drop the command
+	stage, ok = FailedPipelineStage("cat missing.txt", []int{0, 2})
+	if !ok || stage.Index != 2 || stage.Command != "" {
+		t.Errorf("unexpected stage for mismatched command: %+v (ok=%v)", stage, ok)
+	}
+
+	for _, statuses := range [][]int{nil, {1}, {0, 0}, ParsePipeStatus("1 x")} {
+		if _, ok := FailedPipelineStage("a | b", statuses); ok {
+			t.Errorf("statuses %v should not report a failed stage", statuses)
+		}
+	}
+}
+
+func TestPromptCommandNamesFailedStage(t *testing.T) {
+	c := CapturedContext{Command: "cat missing.txt | grep foo"}
+	if c.PromptCommand() != c.Command {
+		t.Errorf("without a failed stage the command is unchanged, got %q", c.PromptCommand())
+	}
+	c.FailedStage = &PipelineStage{Index: 1, Total: 2, Command: "cat missing.txt", ExitCode: 1}
+	got := c.PromptCommand()
+	if !strings.HasPrefix(got, c.Command) || !strings.Contains(got, "stage 1 of 2") || !strings.Contains(got, ": cat missing.txt") {
+		t.Errorf("unexpected prompt command: %q", got)
+	}
+}
+
+func TestPromptCommandIncludesExpansion(t *testing.T) {
+	c := CapturedContext{Command: "ll /missing", Expansion: "alias ll='ls -l --colour'"}
+	got := c.PromptCommand()
+	if !strings.HasPrefix(got, "ll /missing\n") || !strings.Contains(got, "alias ll='ls -l --colour'") {
+		t.Errorf("unexpected prompt command: %q", got)
+	}
+}
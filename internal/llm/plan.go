@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MaxPlanSteps bounds a plan. A task that needs more steps is better written as a script the
+// user can read as a whole than confirmed one command at a time.
+const MaxPlanSteps = 12
+
+// PlanStep is one command of a plan, with what it does.
+type PlanStep struct {
+	Command     string `json:"command"`
+	Explanation string `json:"explanation"`
+}
+
+// Plan is an ordered list of commands that together do what the user asked, e.g. "set up a
+// python venv and install requirements". Each step runs after the one before it succeeded.
+type Plan struct {
+	Steps []PlanStep `json:"steps"`
+}
+
+// Planner is implemented by providers that can answer a prompt with a multi-step Plan.
+// Providers without it get a one-step plan from GenerateCommand (see GeneratePlan).
+type Planner interface {
+	GeneratePlan(ctx context.Context, prompt string, language string) (*Plan, error)
+}
+
+// GeneratePlan asks provider for a plan for prompt, or for a single command it turns into a
+// one-step plan when the provider is no Planner. The plan is validated (see ValidatePlan).
+func GeneratePlan(ctx context.Context, provider Provider, prompt string, language string) (*Plan, error) {
+	if p, ok := provider.(Planner); ok {
+		return validatedPlan(p.GeneratePlan(ctx, prompt, language))
+	}
+	cmd, err := provider.GenerateCommand(ctx, prompt, language)
+	if err != nil {
+		return nil, err
+	}
+	return ValidatePlan(SingleStepPlan(cmd))
+}
+
+// SingleStepPlan is the plan of a provider that answered with one command.
+func SingleStepPlan(command string) *Plan {
+	return &Plan{Steps: []PlanStep{{Command: command}}}
+}
+
+// ValidatePlan checks every step of a plan like ValidateCommand and trims its explanation.
+// A plan needs at least one step and at most MaxPlanSteps; steps without a command are
+// dropped first, since models sometimes close a plan with a prose-only "done" step.
+func ValidatePlan(plan *Plan) (*Plan, error) {
+	if plan == nil {
+		return nil, NewLLMError(EmptyResponseError, "provider returned no plan", nil)
+	}
+	var steps []PlanStep
+	for _, s := range plan.Steps {
+		if strings.TrimSpace(s.Command) == "" {
+			continue
+		}
+		cmd, err := ValidateCommand(s.Command)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, PlanStep{Command: cmd, Explanation: truncateExplanation(s.Explanation)})
+	}
+	switch {
+	case len(steps) == 0:
+		return nil, NewLLMError(EmptyResponseError, "provider returned a plan without commands", nil)
+	case len(steps) > MaxPlanSteps:
+		return nil, NewLLMError(InvalidResponseError, fmt.Sprintf("provider returned a %d-step plan (limit %d)", len(steps), MaxPlanSteps), nil)
+	}
+	return &Plan{Steps: steps}, nil
+}
+
+func validatedPlan(plan *Plan, err error) (*Plan, error) {
+	if err != nil {
+		return plan, err
+	}
+	return ValidatePlan(plan)
+}
+
+// DecodePlan parses a {"steps": [{"command": ..., "explanation": ...}]} response like
+// DecodeSuggestion. A {"command": ...} answer, which models give for tasks of one step,
+// becomes a one-step plan.
+func DecodePlan(ctx context.Context, response string) (*Plan, bool) {
+	obj, method, ok := decodeJSON(response, func(p Plan) bool {
+		for _, s := range p.Steps {
+			if strings.TrimSpace(s.Command) != "" {
+				return true
+			}
+		}
+		return false
+	})
+	if ok {
+		ReportParse(ctx, method)
+		return &obj, true
+	}
+	if cmd, ok := DecodeCommand(ctx, response); ok {
+		return SingleStepPlan(cmd), true
+	}
+	return nil, false
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDecodePlan(t *testing.T) {
+	plan, ok := DecodePlan(context.Background(), "```json\n"+`{"steps": [
+		{"command": "python3 -m venv .venv", "explanation": "Create the environment"},
+		{"command": ".venv/bin/pip install -r requirements.txt", "explanation": "Install the requirements",},
+	]}`+"\n```")
+	if !ok || len(plan.Steps) != 2 || plan.Steps[1].Command != ".venv/bin/pip install -r requirements.txt" {
+		t.Fatalf("DecodePlan = %+v, %v", plan, ok)
+	}
+
+	// A plain command answer is a plan of one step
+	plan, ok = DecodePlan(context.Background(), `{"command": "mkdir logs"}`)
+	if !ok || len(plan.Steps) != 1 || plan.Steps[0].Command != "mkdir logs" {
+		t.Errorf("DecodePlan(command) = %+v, %v", plan, ok)
+	}
+
+	if _, ok := DecodePlan(context.Background(), `{"steps": [{"explanation": "nothing to run"}]}`); ok {
+		t.Error("DecodePlan accepted a plan without commands")
+	}
+}
+
+func TestValidatePlan(t *testing.T) {
+	plan, err := ValidatePlan(&Plan{Steps: []PlanStep{
+		{Command: "  make build ", Explanation: " Build it "},
+		{Explanation: "All done"},
+	}})
+	if err != nil || len(plan.Steps) != 1 || plan.Steps[0] != (PlanStep{Command: "make build", Explanation: "Build it"}) {
+		t.Errorf("ValidatePlan = %+v, %v", plan, err)
+	}
+
+	if _, err := ValidatePlan(&Plan{}); err == nil {
+		t.Error("ValidatePlan accepted an empty plan")
+	}
+	long := &Plan{}
+	for i := 0; i <= MaxPlanSteps; i++ {
+		long.Steps = append(long.Steps, PlanStep{Command: "true"})
+	}
+	if _, err := ValidatePlan(long); err == nil {
+		t.Error("ValidatePlan accepted a plan beyond MaxPlanSteps")
+	}
+}
+
+func TestGeneratePlanFallsBackToCommand(t *testing.T) {
+	plan, err := GeneratePlan(context.Background(), &MockProvider{command: "ls -la"}, "list files", "en")
+	if err != nil || len(plan.Steps) != 1 || plan.Steps[0].Command != "ls -la" {
+		t.Errorf("GeneratePlan = %+v, %v", plan, err)
+	}
+	failure := errors.New("boom")
+	if _, err := GeneratePlan(context.Background(), &MockProvider{commandErr: failure}, "list files", "en"); !errors.Is(err, failure) {
+		t.Errorf("GeneratePlan error = %v", err)
+	}
+}
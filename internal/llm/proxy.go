@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// configuredProxy is the proxy of SetProxy; nil leaves the choice to the environment.
+var configuredProxy atomic.Pointer[proxySetting]
+
+type proxySetting struct {
+	raw   string
+	proxy func(*url.URL) (*url.URL, error)
+}
+
+// environmentProxy reads the proxy variables once, like http.ProxyFromEnvironment, but falls
+// back to ALL_PROXY, which SSH dynamic forwarding setups often set alone
+// (ALL_PROXY=socks5h://localhost:1080) and which net/http ignores.
+var environmentProxy = sync.OnceValue(func() func(*url.URL) (*url.URL, error) {
+	cfg := httpproxy.FromEnvironment()
+	all := firstEnv("ALL_PROXY", "all_proxy")
+	if cfg.HTTPProxy == "" {
+		cfg.HTTPProxy = all
+	}
+	if cfg.HTTPSProxy == "" {
+		cfg.HTTPSProxy = all
+	}
+	return cfg.ProxyFunc()
+})
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// SetProxy sends provider requests through raw (user_preferences.proxy), an http://,
+// https://, socks5:// or socks5h:// URL, instead of the proxy the environment names. NO_PROXY
+// and loopback addresses are still exempt. An empty raw restores the environment's proxy.
+func SetProxy(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		configuredProxy.Store(nil)
+		return nil
+	}
+	if err := ValidateProxy(raw); err != nil {
+		return err
+	}
+	configuredProxy.Store(&proxySetting{raw: raw, proxy: (&httpproxy.Config{
+		HTTPProxy:  raw,
+		HTTPSProxy: raw,
+		NoProxy:    firstEnv("NO_PROXY", "no_proxy"),
+	}).ProxyFunc()})
+	return nil
+}
+
+// Proxy is the Proxy function of every transport aish builds for providers: the proxy of
+// SetProxy, or else HTTPS_PROXY, HTTP_PROXY and ALL_PROXY. net/http speaks SOCKS5 itself, so
+// socks5:// and socks5h:// proxies work like HTTP ones; both let the proxy resolve host names.
+func Proxy(req *http.Request) (*url.URL, error) {
+	if p := configuredProxy.Load(); p != nil {
+		return p.proxy(req.URL)
+	}
+	return environmentProxy()(req.URL)
+}
+
+// ProxyURL returns the proxy set with SetProxy, or "" when the environment decides. It is for
+// tools aish runs that take the proxy as an argument, such as curl's --proxy.
+func ProxyURL() string {
+	if p := configuredProxy.Load(); p != nil {
+		return p.raw
+	}
+	return ""
+}
+
+// ValidateProxy checks a proxy URL for user_preferences.proxy: it needs a supported scheme
+// and a host.
+func ValidateProxy(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy %q: use http://, https://, socks5:// or socks5h://, e.g. socks5://127.0.0.1:1080", raw)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("proxy %q has no host", raw)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestValidateProxy(t *testing.T) {
+	for _, raw := range []string{"socks5://127.0.0.1:1080", "socks5h://user:pw@localhost:1080", "http://proxy.corp:3128", "https://proxy.corp"} {
+		if err := ValidateProxy(raw); err != nil {
+			t.Errorf("ValidateProxy(%q): %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"socks4://127.0.0.1:1080", "127.0.0.1:1080", "socks5://", "ftp://proxy"} {
+		if err := ValidateProxy(raw); err == nil {
+			t.Errorf("ValidateProxy(%q) should fail", raw)
+		}
+	}
+}
+
+func TestSetProxy(t *testing.T) {
+	defer SetProxy("")
+	if err := SetProxy("socks5h://127.0.0.1:1080"); err != nil {
+		t.Fatal(err)
+	}
+	if ProxyURL() != "socks5h://127.0.0.1:1080" {
+		t.Errorf("ProxyURL() = %q", ProxyURL())
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.openai.com/v1/chat/completions", nil)
+	u, err := Proxy(req)
+	if err != nil || u == nil || u.Scheme != "socks5h" || u.Host != "127.0.0.1:1080" {
+		t.Errorf("Proxy() = %v, %v", u, err)
+	}
+	// A local Ollama is never sent through the proxy
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:11434/api/chat", nil)
+	if u, _ := Proxy(req); u != nil {
+		t.Errorf("loopback request proxied through %v", u)
+	}
+
+	if err := SetProxy("socks4://127.0.0.1:1080"); err == nil {
+		t.Error("SetProxy accepted an unsupported scheme")
+	}
+	if err := SetProxy(""); err != nil || ProxyURL() != "" {
+		t.Errorf("SetProxy(\"\") = %v, ProxyURL() = %q", err, ProxyURL())
+	}
+}
+
+// serveSOCKS5 accepts one connection on ln as a SOCKS5 proxy without authentication and
+// connects it to target whatever address the client asks for, reporting that address.
+func serveSOCKS5(t *testing.T, ln net.Listener, target string, requested chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	buf := make([]byte, 262)
+	// Greeting: version, method count, methods; answer "no authentication"
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 0})
+	// Request: version, CONNECT, reserved, address type, address, port
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1:
+		io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 3:
+		io.ReadFull(conn, buf[:1])
+		n := int(buf[0])
+		io.ReadFull(conn, buf[:n])
+		host = string(buf[:n])
+	default:
+		t.Errorf("unexpected address type %d", buf[3])
+		return
+	}
+	io.ReadFull(conn, buf[:2])
+	requested <- net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2]))))
+
+	backend, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer backend.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(backend, conn)
+	io.Copy(conn, backend)
+}
+
+func TestPooledClientUsesSOCKS5Proxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("via proxy"))
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	requested := make(chan string, 1)
+	go serveSOCKS5(t, ln, srv.Listener.Addr().String(), requested)
+
+	defer SetProxy("")
+	if err := SetProxy("socks5h://" + ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	resp, err := NewPooledClient(5 * time.Second).Get("http://provider.invalid:" + port + "/")
+	if err != nil {
+		t.Fatalf("request through the SOCKS5 proxy failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "via proxy" {
+		t.Errorf("body = %q", body)
+	}
+	// The host name reaches the proxy unresolved, as SSH dynamic forwarding needs
+	if got := <-requested; got != "provider.invalid:"+port {
+		t.Errorf("proxy asked for %q", got)
+	}
+}
@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resetError carries the time a provider said its rate limit or quota resets, taken from the
+// headers of the response that failed.
+type resetError struct {
+	err     error
+	resetAt time.Time
+}
+
+func (e *resetError) Error() string { return e.err.Error() }
+func (e *resetError) Unwrap() error { return e.err }
+
+// WithRetryAfter attaches the reset time found in the headers of a failed response to err, so
+// that RetryAfter can report it. It returns err unchanged when err is nil or the headers name
+// no reset.
+func WithRetryAfter(err error, header http.Header) error {
+	if err == nil {
+		return nil
+	}
+	if at, ok := resetFromHeader(header, time.Now()); ok {
+		return &resetError{err: err, resetAt: at}
+	}
+	return err
+}
+
+// RetryAfter returns how long until the rate limit or quota that made err fail resets: from the
+// response headers when the provider attached them with WithRetryAfter, otherwise from a reset
+// the error message names ("Please try again in 20s", "retryDelay": "42s").
+func RetryAfter(err error) (time.Duration, bool) {
+	at, ok := resetAt(err, time.Now())
+	if !ok {
+		return 0, false
+	}
+	return time.Until(at), true
+}
+
+// resetAt returns when the limit behind err resets, as RetryAfter describes, with the message
+// read relative to now. A reset already past is reported as now.
+func resetAt(err error, now time.Time) (time.Time, bool) {
+	if err == nil {
+		return time.Time{}, false
+	}
+	var re *resetError
+	if errors.As(err, &re) {
+		if re.resetAt.Before(now) {
+			return now, true
+		}
+		return re.resetAt, true
+	}
+	if d, ok := resetFromMessage(strings.ToLower(err.Error())); ok {
+		return now.Add(d), true
+	}
+	return time.Time{}, false
+}
+
+// rateLimitHeaders are the remaining/reset header pairs of the limits OpenAI and Anthropic
+// report on every response.
+var rateLimitHeaders = [][2]string{
+	{"x-ratelimit-remaining-requests", "x-ratelimit-reset-requests"},
+	{"x-ratelimit-remaining-tokens", "x-ratelimit-reset-tokens"},
+	{"anthropic-ratelimit-requests-remaining", "anthropic-ratelimit-requests-reset"},
+	{"anthropic-ratelimit-tokens-remaining", "anthropic-ratelimit-tokens-reset"},
+	{"anthropic-ratelimit-input-tokens-remaining", "anthropic-ratelimit-input-tokens-reset"},
+	{"anthropic-ratelimit-output-tokens-remaining", "anthropic-ratelimit-output-tokens-reset"},
+}
+
+// resetFromHeader reads the reset time from retry-after-ms or retry-after, and otherwise from
+// the latest reset of the limits the response reports as used up.
+func resetFromHeader(header http.Header, now time.Time) (time.Time, bool) {
+	if header == nil {
+		return time.Time{}, false
+	}
+	if ms, err := strconv.ParseFloat(strings.TrimSpace(header.Get("retry-after-ms")), 64); err == nil && ms >= 0 {
+		return now.Add(time.Duration(ms * float64(time.Millisecond))), true
+	}
+	if v := strings.TrimSpace(header.Get("retry-after")); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds >= 0 {
+			return now.Add(time.Duration(seconds * float64(time.Second))), true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return at, true
+		}
+	}
+
+	var latest time.Time
+	for _, pair := range rateLimitHeaders {
+		if strings.TrimSpace(header.Get(pair[0])) != "0" {
+			continue
+		}
+		if at, ok := parseReset(header.Get(pair[1]), now); ok && at.After(latest) {
+			latest = at
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// parseReset reads a reset header: an RFC 3339 time (Anthropic) or a duration such as "1s",
+// "6m0s" or "20ms" (OpenAI).
+func parseReset(v string, now time.Time) (time.Time, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, false
+	}
+	if at, err := time.Parse(time.RFC3339, v); err == nil {
+		return at, true
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(d), true
+	}
+	return time.Time{}, false
+}
+
+// Resets named in error messages: Gemini's RetryInfo ("retryDelay": "42s"), OpenAI's "Please
+// try again in 6m0s" and Azure's "Please retry after 42 seconds".
+var (
+	retryDelayPattern = regexp.MustCompile(`"?retrydelay"?\s*:\s*"(\d+(?:\.\d+)?s)"`)
+	tryAgainInPattern = regexp.MustCompile(`(?:try again|retry) in ((?:\d+(?:\.\d+)?(?:ms|h|m|s))+)\b`)
+	retryAfterPattern = regexp.MustCompile(`(?:try again|retry) (?:after|in) (\d+(?:\.\d+)?) ?(sec|min)`)
+)
+
+// resetFromMessage returns the wait a lowercased error message names.
+func resetFromMessage(msg string) (time.Duration, bool) {
+	for _, pattern := range []*regexp.Regexp{retryDelayPattern, tryAgainInPattern} {
+		if m := pattern.FindStringSubmatch(msg); m != nil {
+			if d, err := time.ParseDuration(m[1]); err == nil {
+				return d, true
+			}
+		}
+	}
+	if m := retryAfterPattern.FindStringSubmatch(msg); m != nil {
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		unit := time.Second
+		if m[2] == "min" {
+			unit = time.Minute
+		}
+		return time.Duration(n * float64(unit)), true
+	}
+	return 0, false
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResetFromHeader(t *testing.T) {
+	now := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		header http.Header
+		want   time.Duration
+	}{
+		"retry-after seconds": {http.Header{"Retry-After": {"42"}}, 42 * time.Second},
+		"retry-after date":    {http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}, time.Minute},
+		"retry-after-ms":      {http.Header{"Retry-After-Ms": {"1500"}, "Retry-After": {"2"}}, 1500 * time.Millisecond},
+		"openai exhausted limit": {http.Header{
+			"X-Ratelimit-Remaining-Requests": {"0"}, "X-Ratelimit-Reset-Requests": {"6m0s"},
+			"X-Ratelimit-Remaining-Tokens": {"1200"}, "X-Ratelimit-Reset-Tokens": {"20h"},
+		}, 6 * time.Minute},
+		"anthropic exhausted limit": {http.Header{
+			"Anthropic-Ratelimit-Tokens-Remaining": {"0"}, "Anthropic-Ratelimit-Tokens-Reset": {now.Add(30 * time.Second).Format(time.RFC3339)},
+		}, 30 * time.Second},
+	} {
+		at, ok := resetFromHeader(tc.header, now)
+		if !ok || at.Sub(now) != tc.want {
+			t.Errorf("%s: got %v (%v), want %v", name, at.Sub(now), ok, tc.want)
+		}
+	}
+
+	if _, ok := resetFromHeader(http.Header{"X-Ratelimit-Remaining-Requests": {"3"}, "X-Ratelimit-Reset-Requests": {"1s"}}, now); ok {
+		t.Error("a limit that is not used up names no reset")
+	}
+}
+
+func TestResetAtFromMessage(t *testing.T) {
+	now := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	for msg, want := range map[string]time.Duration{
+		"API error: Rate limit reached for gpt-4o on requests per min (RPM): Limit 3. Please try again in 20s.":        20 * time.Second,
+		"API error: You exceeded your current quota. Please retry in 42.5s.":                                           42500 * time.Millisecond,
+		`{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "17s"}`:                                   17 * time.Second,
+		"Requests to the ChatCompletions_Create Operation have exceeded the rate limit. Please retry after 8 seconds.": 8 * time.Second,
+		"Rate limit exceeded, try again in 2 minutes":                                                                  2 * time.Minute,
+	} {
+		at, ok := resetAt(errors.New(msg), now)
+		if !ok || at.Sub(now) != want {
+			t.Errorf("%q: got %v (%v), want %v", msg, at.Sub(now), ok, want)
+		}
+	}
+	if _, ok := resetAt(errors.New("API returned status 429: too many requests"), now); ok {
+		t.Error("a message without a reset names none")
+	}
+}
+
+func TestWithRetryAfter(t *testing.T) {
+	if WithRetryAfter(nil, http.Header{"Retry-After": {"5"}}) != nil {
+		t.Error("nil error must stay nil")
+	}
+	base := errors.New("API returned status 429")
+	err := WithRetryAfter(base, http.Header{"Retry-After": {"30"}})
+	if !errors.Is(err, base) || err.Error() != base.Error() {
+		t.Errorf("wrapped error changed: %v", err)
+	}
+	if wait, ok := RetryAfter(err); !ok || wait <= 29*time.Second || wait > 30*time.Second {
+		t.Errorf("RetryAfter = %v, %v", wait, ok)
+	}
+	if WithRetryAfter(base, http.Header{}) != base {
+		t.Error("headers without a reset must leave the error alone")
+	}
+}
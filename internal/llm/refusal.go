@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/security"
+)
+
+// refusalPhrases open the answers models give instead of a command when their safety training
+// or the provider's filter declines a request, e.g. one that names security tools.
+var refusalPhrases = []string{
+	"i can't help", "i cannot help", "i can’t help",
+	"i can't assist", "i cannot assist", "i can’t assist",
+	"i can't provide", "i cannot provide", "i can’t provide",
+	"i won't help", "i won't provide", "i'm not able to help", "i am not able to help",
+	"i'm unable to help", "i am unable to help", "i'm unable to assist", "i am unable to assist",
+	"i must decline", "i have to decline",
+}
+
+// IsRefusal reports whether text, an answer in place of a command or explanation, declines the
+// request rather than answering it.
+func IsRefusal(text string) bool {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	for _, apology := range []string{"sorry, ", "i'm sorry, but ", "i’m sorry, but ", "i am sorry, but "} {
+		lower = strings.TrimPrefix(lower, apology)
+	}
+	for _, p := range refusalPhrases {
+		if strings.HasPrefix(lower, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RefusalError is the error for a request the provider or model declined; detail says how the
+// refusal was detected.
+func RefusalError(detail string) error {
+	return NewLLMError(ContentFilterError, "provider declined the request: "+detail, nil)
+}
+
+// NoCommandError is returned by providers when no command could be extracted from response:
+// a RefusalError when the response declines the request, a plain error otherwise.
+func NoCommandError(response string) error {
+	if IsRefusal(response) {
+		return RefusalError(firstLine(response))
+	}
+	return errors.New("no plausible command found in provider response")
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// sanitizedStderrLines is how much of the error output SanitizeContext keeps.
+const sanitizedStderrLines = 10
+
+// SanitizeContext returns c reduced for a retry after a content-filter refusal: the command
+// output that usually trips the filter (scanner results, exploit names, payloads) is dropped
+// except for the last lines of stderr, secrets are masked, and a note frames the request as
+// what it is, the user fixing their own command.
+func SanitizeContext(c CapturedContext) CapturedContext {
+	lines := strings.Split(strings.TrimRight(c.Stderr, "\n"), "\n")
+	if len(lines) > sanitizedStderrLines {
+		lines = lines[len(lines)-sanitizedStderrLines:]
+	}
+	return CapturedContext{
+		Command:     security.SanitizeCommand(c.Command),
+		Stderr:      security.SanitizeText(strings.Join(lines, "\n")),
+		ExitCode:    c.ExitCode,
+		FailedStage: c.FailedStage,
+		Notes:       []string{"The user is troubleshooting a command that failed in their own shell; only the end of its error output is included"},
+	}
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIsRefusal(t *testing.T) {
+	for _, text := range []string{
+		"I can't help with that.",
+		"  Sorry, I cannot assist with scanning networks you do not own.",
+		"I'm sorry, but I can’t provide commands for that tool.",
+		"I must decline this request.",
+	} {
+		if !IsRefusal(text) {
+			t.Errorf("%q not detected as a refusal", text)
+		}
+	}
+	for _, text := range []string{
+		"",
+		"nmap -sV localhost",
+		"The command failed because the file does not exist; I can't help noticing the typo.",
+		"Sorry for the wait: run git status",
+	} {
+		if IsRefusal(text) {
+			t.Errorf("%q detected as a refusal", text)
+		}
+	}
+}
+
+func TestNoCommandError(t *testing.T) {
+	var llmErr *LLMError
+	if err := NoCommandError("I cannot help with that.\nIt could be misused."); !errors.As(err, &llmErr) || llmErr.Type != ContentFilterError {
+		t.Errorf("refusal not reported as a content filter error: %v", err)
+	} else if strings.Contains(err.Error(), "misused") {
+		t.Errorf("detail should keep only the first line: %v", err)
+	}
+	if err := NoCommandError("here is some prose"); errors.As(err, &llmErr) {
+		t.Errorf("plain response reported as %v", llmErr.Type)
+	}
+}
+
+func TestSanitizeContext(t *testing.T) {
+	var stderr strings.Builder
+	for i := 1; i <= 15; i++ {
+		fmt.Fprintf(&stderr, "line %d\n", i)
+	}
+	c := SanitizeContext(CapturedContext{
+		Command:     "nmap -sV 10.0.0.1",
+		Stdout:      "PORT STATE SERVICE\n22/tcp open ssh",
+		Stderr:      stderr.String(),
+		ExitCode:    1,
+		FailedStage: &PipelineStage{Index: 2, Total: 2, Command: "grep open", ExitCode: 1},
+	})
+	if c.Stdout != "" {
+		t.Errorf("stdout kept: %q", c.Stdout)
+	}
+	lines := strings.Split(c.Stderr, "\n")
+	if len(lines) != sanitizedStderrLines || lines[0] != "line 6" || lines[len(lines)-1] != "line 15" {
+		t.Errorf("stderr not cut to its last lines: %q", c.Stderr)
+	}
+	if c.Command == "" || c.ExitCode != 1 || c.FailedStage == nil || len(c.Notes) != 1 {
+		t.Errorf("unexpected context %+v", c)
+	}
+}
@@ -0,0 +1,195 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/llm/openai"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+// defaultEndpoint is the hosted relay of trial mode. Release builds inject it with
+// -X github.com/TonnyWong1052/aish/internal/llm/relay.defaultEndpoint=...; other builds have
+// none, so trial mode is unavailable unless AISH_RELAY_URL names a relay.
+var defaultEndpoint string
+
+// DailyLimit is how many requests a day trial mode sends. The relay enforces its own limits as
+// well; this one keeps a single user from using up the shared quota.
+const DailyLimit = 20
+
+// quotaFileName records the requests sent today, in the state directory.
+const quotaFileName = "relay_quota.json"
+
+// Endpoint returns the URL of the trial relay: AISH_RELAY_URL, else the one built in, else "".
+func Endpoint() string {
+	if v := strings.TrimSpace(os.Getenv(config.EnvAISHRelayURL)); v != "" {
+		return v
+	}
+	return strings.TrimSpace(defaultEndpoint)
+}
+
+// Available reports whether trial mode has a relay to talk to.
+func Available() bool {
+	return Endpoint() != ""
+}
+
+// RelayProvider answers through the hosted trial relay, which speaks the OpenAI chat completions
+// API without a key, and stops for the day after DailyLimit requests.
+type RelayProvider struct {
+	llm.Provider
+	model string
+	quota quota
+}
+
+// NewProvider creates a RelayProvider for providers.relay.api_endpoint, or else Endpoint().
+func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, error) {
+	endpoint := strings.TrimSpace(cfg.APIEndpoint)
+	if endpoint == "" {
+		endpoint = Endpoint()
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("this build of aish has no trial relay; set %s or configure a provider with 'aish init'", config.EnvAISHRelayURL)
+	}
+	if strings.TrimSpace(cfg.Model) == "" {
+		cfg.Model = config.DefaultRelayModel
+	}
+	// The relay needs no key, and must not be sent one meant for another service
+	inner, err := openai.NewProvider(config.ProviderConfig{APIEndpoint: endpoint, Model: cfg.Model}, pm)
+	if err != nil {
+		return nil, err
+	}
+	q := quota{now: time.Now}
+	if dir, err := config.StateDir(); err == nil {
+		q.path = filepath.Join(dir, quotaFileName)
+	}
+	return &RelayProvider{Provider: inner, model: cfg.Model, quota: q}, nil
+}
+
+func init() {
+	llm.RegisterProvider(config.ProviderRelay, NewProvider)
+}
+
+// Remaining returns how many trial requests are left today.
+func Remaining() int {
+	dir, err := config.StateDir()
+	if err != nil {
+		return DailyLimit
+	}
+	q := quota{path: filepath.Join(dir, quotaFileName), now: time.Now}
+	return DailyLimit - q.used()
+}
+
+func (p *RelayProvider) GetSuggestion(ctx context.Context, c llm.CapturedContext, language string) (*llm.Suggestion, error) {
+	if err := p.quota.take(); err != nil {
+		return nil, err
+	}
+	return p.Provider.GetSuggestion(ctx, c, language)
+}
+
+func (p *RelayProvider) GetEnhancedSuggestion(ctx context.Context, c llm.EnhancedCapturedContext, language string) (*llm.Suggestion, error) {
+	if err := p.quota.take(); err != nil {
+		return nil, err
+	}
+	return p.Provider.GetEnhancedSuggestion(ctx, c, language)
+}
+
+func (p *RelayProvider) GetSuggestionStream(ctx context.Context, c llm.CapturedContext, language string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
+	if err := p.quota.take(); err != nil {
+		return nil, err
+	}
+	return p.Provider.GetSuggestionStream(ctx, c, language, onChunk)
+}
+
+func (p *RelayProvider) GenerateCommand(ctx context.Context, prompt string, language string) (string, error) {
+	if err := p.quota.take(); err != nil {
+		return "", err
+	}
+	return p.Provider.GenerateCommand(ctx, prompt, language)
+}
+
+func (p *RelayProvider) GenerateCommandStream(ctx context.Context, prompt string, language string, onChunk llm.StreamFunc) (string, error) {
+	if err := p.quota.take(); err != nil {
+		return "", err
+	}
+	return p.Provider.GenerateCommandStream(ctx, prompt, language, onChunk)
+}
+
+// GeneratePlan implements llm.Planner; a plan takes one request of the quota.
+func (p *RelayProvider) GeneratePlan(ctx context.Context, prompt string, language string) (*llm.Plan, error) {
+	if err := p.quota.take(); err != nil {
+		return nil, err
+	}
+	return llm.GeneratePlan(ctx, p.Provider, prompt, language)
+}
+
+// VerifyConnection reports the model without contacting the relay, which lists none and would
+// count the check against the quota of the day.
+func (p *RelayProvider) VerifyConnection(ctx context.Context) ([]string, error) {
+	return []string{p.model}, nil
+}
+
+// quota counts the requests sent on the current local day in a file shared by all aish
+// processes. Without a state directory it counts nothing.
+type quota struct {
+	path string
+	now  func() time.Time
+}
+
+type quotaState struct {
+	Day  string `json:"day"`
+	Used int    `json:"used"`
+}
+
+func (q quota) today() string {
+	return q.now().Format("2006-01-02")
+}
+
+func (q quota) used() int {
+	if q.path == "" {
+		return 0
+	}
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		return 0
+	}
+	var st quotaState
+	if json.Unmarshal(data, &st) != nil || st.Day != q.today() {
+		return 0
+	}
+	return st.Used
+}
+
+// take counts one request, or returns a rate limit error that resets at midnight once the day's
+// requests are used up.
+func (q quota) take() error {
+	used := q.used()
+	if used >= DailyLimit {
+		now := q.now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		err := llm.NewLLMError(llm.RateLimitError,
+			fmt.Sprintf("the trial relay allows %d requests a day; configure your own provider with 'aish init' to continue", DailyLimit),
+			errors.New("trial quota used up"))
+		return llm.WithRetryAfter(err, http.Header{"Retry-After": {strconv.Itoa(int(midnight.Sub(now).Seconds()) + 1)}})
+	}
+	if q.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(quotaState{Day: q.today(), Used: used + 1})
+	if err != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o700); err == nil {
+		_ = os.WriteFile(q.path, data, 0o600)
+	}
+	return nil
+}
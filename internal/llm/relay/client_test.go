@@ -0,0 +1,74 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+func TestQuota(t *testing.T) {
+	now := time.Date(2026, 10, 15, 23, 0, 0, 0, time.UTC)
+	q := quota{path: filepath.Join(t.TempDir(), quotaFileName), now: func() time.Time { return now }}
+	for i := 0; i < DailyLimit; i++ {
+		if err := q.take(); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	err := q.take()
+	var llmErr *llm.LLMError
+	if !errors.As(err, &llmErr) || llmErr.Type != llm.RateLimitError {
+		t.Fatalf("request %d: got %v, want a rate limit error", DailyLimit+1, err)
+	}
+	if wait, ok := llm.RetryAfter(err); !ok || wait < time.Hour || wait > time.Hour+time.Second {
+		t.Errorf("RetryAfter = %v, %v; want the hour until midnight", wait, ok)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := q.take(); err != nil || q.used() != 1 {
+		t.Errorf("next day: take() = %v with %d used", err, q.used())
+	}
+}
+
+func TestNewProviderWithoutRelay(t *testing.T) {
+	t.Setenv(config.EnvAISHRelayURL, "")
+	if Available() {
+		t.Skip("this build has a relay")
+	}
+	if _, err := NewProvider(config.ProviderConfig{}, prompt.NewDefaultManager()); err == nil {
+		t.Error("NewProvider succeeded without a relay")
+	}
+}
+
+func TestRelaySendsNoKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("relay was sent Authorization %q", auth)
+		}
+		body, _ := json.Marshal(map[string]any{"object": "chat.completion", "choices": []any{map[string]any{"message": map[string]string{"content": `{"command":"ls -la"}`}}}})
+		w.Write(body)
+	}))
+	defer srv.Close()
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	t.Setenv(config.EnvAISHRelayURL, srv.URL)
+
+	// A key configured for the relay must not reach it
+	p, err := NewProvider(config.ProviderConfig{APIKey: "sk-secret"}, prompt.NewDefaultManager())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.GenerateCommand(context.Background(), "list all files", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Remaining(); got != DailyLimit-1 {
+		t.Errorf("Remaining() = %d after one request, want %d", got, DailyLimit-1)
+	}
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// DefaultMaxConcurrentRequests caps simultaneous provider requests across all aish processes
+// when user_preferences.max_concurrent_requests is unset.
+const DefaultMaxConcurrentRequests = 2
+
+// slotPollInterval is how often a waiting process retries the request slots.
+const slotPollInterval = 100 * time.Millisecond
+
+// RequestSlot is one of the slots of the machine-wide request semaphore. Slots are lock files
+// held with an advisory lock, so a crashed process releases its slot automatically.
+type RequestSlot struct {
+	f *os.File
+}
+
+// RequestSlotDir returns the directory holding the request slot lock files.
+func RequestSlotDir() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "locks"), nil
+}
+
+// AcquireRequestSlot blocks until one of limit slots in dir is free or ctx is done. Parallel
+// terminals failing at once therefore queue up instead of all calling the provider together.
+// A limit of zero or less disables the limit and returns a nil slot, which is safe to Release.
+func AcquireRequestSlot(ctx context.Context, dir string, limit int) (*RequestSlot, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	for {
+		for i := 0; i < limit; i++ {
+			path := filepath.Join(dir, fmt.Sprintf("llm-request-%d.lock", i))
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+			if err != nil {
+				return nil, err
+			}
+			if tryLockFile(f) {
+				return &RequestSlot{f: f}, nil
+			}
+			_ = f.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(slotPollInterval):
+		}
+	}
+}
+
+// Release frees the slot.
+func (s *RequestSlot) Release() {
+	if s == nil || s.f == nil {
+		return
+	}
+	unlockFile(s.f)
+	_ = s.f.Close()
+	s.f = nil
+}
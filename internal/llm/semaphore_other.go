@@ -0,0 +1,13 @@
+//go:build !unix
+
+package llm
+
+import "os"
+
+// tryLockFile always succeeds where advisory file locks are unavailable, so the request cap
+// is not enforced across processes on these platforms.
+func tryLockFile(*os.File) bool {
+	return true
+}
+
+func unlockFile(*os.File) {}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireRequestSlotLimitsConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	first, err := AcquireRequestSlot(ctx, dir, 2)
+	if err != nil {
+		t.Fatalf("first slot: %v", err)
+	}
+	second, err := AcquireRequestSlot(ctx, dir, 2)
+	if err != nil {
+		t.Fatalf("second slot: %v", err)
+	}
+
+	// Both slots taken: a third request waits until its context gives up
+	short, cancel := context.WithTimeout(ctx, 250*time.Millisecond)
+	defer cancel()
+	if _, err := AcquireRequestSlot(short, dir, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the third request to wait, got %v", err)
+	}
+
+	first.Release()
+	third, err := AcquireRequestSlot(ctx, dir, 2)
+	if err != nil {
+		t.Fatalf("slot after release: %v", err)
+	}
+	third.Release()
+	second.Release()
+}
+
+func TestAcquireRequestSlotUnlimited(t *testing.T) {
+	slot, err := AcquireRequestSlot(context.Background(), t.TempDir(), 0)
+	if err != nil || slot != nil {
+		t.Fatalf("unlimited: %v, %v", slot, err)
+	}
+	slot.Release() // nil slots are safe to release
+}
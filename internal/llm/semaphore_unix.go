@@ -0,0 +1,17 @@
+//go:build unix
+
+package llm
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive advisory lock on f without blocking.
+func tryLockFile(f *os.File) bool {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB) == nil
+}
+
+func unlockFile(f *os.File) {
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
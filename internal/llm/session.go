@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SessionRecordVersion is the format version written to session recordings.
+const SessionRecordVersion = 1
+
+// Session kinds: which provider call a recording holds.
+const (
+	SessionSuggestion = "suggestion"
+	SessionCommand    = "command"
+)
+
+// SessionRecord is everything needed to reproduce how a provider answer was parsed and shown:
+// the captured context, the prompt exactly as rendered, the provider's raw response and what
+// aish made of it. Users attach it to bug reports; 'aish replay' re-runs it without the network.
+type SessionRecord struct {
+	Version     int       `json:"version"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	AishVersion string    `json:"aish_version,omitempty"`
+	Kind        string    `json:"kind"`
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Shell       string    `json:"shell,omitempty"` // Dialect commands were generated for
+	OS          string    `json:"os,omitempty"`
+
+	Context *CapturedContext `json:"context,omitempty"` // Input of a suggestion
+	Prompt  string           `json:"prompt,omitempty"`  // Input of a command generation
+
+	RenderedPrompt string      `json:"rendered_prompt"`
+	RawResponse    string      `json:"raw_response"`
+	Suggestion     *Suggestion `json:"suggestion,omitempty"`
+	Command        string      `json:"command,omitempty"`
+	Error          string      `json:"error,omitempty"`
+}
+
+// SessionRecorder collects the prompt and raw response of the provider exchange made with a
+// context it is attached to. When several exchanges happen (e.g. after switching to the
+// fallback provider), the last one wins.
+type SessionRecorder struct {
+	mu       sync.Mutex
+	prompt   string
+	response string
+	err      error
+}
+
+// SessionReplay answers provider exchanges with a recorded response instead of the network.
+type SessionReplay struct {
+	mu       sync.Mutex
+	response string
+	prompt   string // The prompt rendered during the replay, to compare with the recording
+}
+
+type sessionRecorderKey struct{}
+type sessionReplayKey struct{}
+
+// WithSessionRecorder attaches r to ctx so the provider exchange is recorded. A nil r leaves
+// ctx unchanged.
+func WithSessionRecorder(ctx context.Context, r *SessionRecorder) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionRecorderKey{}, r)
+}
+
+// WithSessionReplay makes provider exchanges under ctx return rec's raw response.
+func WithSessionReplay(ctx context.Context, rec *SessionRecord) (context.Context, *SessionReplay) {
+	r := &SessionReplay{response: rec.RawResponse}
+	return context.WithValue(ctx, sessionReplayKey{}, r), r
+}
+
+// IsReplay reports whether ctx replays a recorded session, so providers can skip network
+// work that happens outside Exchange (e.g. resolving a project).
+func IsReplay(ctx context.Context) bool {
+	r, ok := ctx.Value(sessionReplayKey{}).(*SessionReplay)
+	return ok && r != nil
+}
+
+// Exchange sends prompt to the provider through send. Providers route every request through it
+// so a session can be recorded (AISH_RECORD_SESSION) or replayed without the network.
+func Exchange(ctx context.Context, prompt string, send func(context.Context, string) (string, error)) (string, error) {
+	if r, ok := ctx.Value(sessionReplayKey{}).(*SessionReplay); ok && r != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.prompt = prompt
+		return r.response, nil
+	}
+	response, err := trackHTTPTiming(ctx, prompt, func(ctx context.Context, prompt string) (string, error) {
+		return trackUsage(ctx, prompt, send)
+	})
+	if rec, ok := ctx.Value(sessionRecorderKey{}).(*SessionRecorder); ok && rec != nil {
+		rec.mu.Lock()
+		rec.prompt, rec.response, rec.err = prompt, response, err
+		rec.mu.Unlock()
+	}
+	return response, err
+}
+
+// Fill copies the recorded exchange into rec.
+func (r *SessionRecorder) Fill(rec *SessionRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec.RenderedPrompt = r.prompt
+	rec.RawResponse = r.response
+	if r.err != nil && rec.Error == "" {
+		rec.Error = r.err.Error()
+	}
+}
+
+// Prompt returns the prompt rendered during the replay.
+func (r *SessionReplay) Prompt() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.prompt
+}
+
+// SaveSessionRecord writes rec to path, readable only by the user since it holds command output.
+func SaveSessionRecord(path string, rec *SessionRecord) error {
+	rec.Version = SessionRecordVersion
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// LoadSessionRecord reads a recording written by SaveSessionRecord.
+func LoadSessionRecord(path string) (*SessionRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec SessionRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parse session recording: %w", err)
+	}
+	if rec.Version > SessionRecordVersion {
+		return nil, fmt.Errorf("session recording version %d is newer than this aish supports (%d)", rec.Version, SessionRecordVersion)
+	}
+	switch rec.Kind {
+	case SessionSuggestion:
+		if rec.Context == nil {
+			return nil, fmt.Errorf("session recording has no captured context")
+		}
+	case SessionCommand:
+	default:
+		return nil, fmt.Errorf("unknown session kind %q", rec.Kind)
+	}
+	return &rec, nil
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExchangeRecordsAndReplays(t *testing.T) {
+	recorder := &SessionRecorder{}
+	ctx := WithSessionRecorder(context.Background(), recorder)
+	got, err := Exchange(ctx, "the prompt", func(_ context.Context, prompt string) (string, error) {
+		return "raw answer to " + prompt, nil
+	})
+	if err != nil || got != "raw answer to the prompt" {
+		t.Fatalf("Exchange = %q, %v", got, err)
+	}
+	rec := SessionRecord{Kind: SessionCommand, Provider: "openai", Prompt: "p"}
+	recorder.Fill(&rec)
+	if rec.RenderedPrompt != "the prompt" || rec.RawResponse != "raw answer to the prompt" || rec.Error != "" {
+		t.Fatalf("recorded %+v", rec)
+	}
+
+	ctx, replay := WithSessionReplay(context.Background(), &rec)
+	if !IsReplay(ctx) {
+		t.Fatal("IsReplay = false for a replay context")
+	}
+	got, err = Exchange(ctx, "the new prompt", func(context.Context, string) (string, error) {
+		t.Fatal("replay must not send the request")
+		return "", nil
+	})
+	if err != nil || got != rec.RawResponse {
+		t.Fatalf("replayed Exchange = %q, %v", got, err)
+	}
+	if replay.Prompt() != "the new prompt" {
+		t.Errorf("replay prompt = %q", replay.Prompt())
+	}
+}
+
+func TestSessionRecorderKeepsError(t *testing.T) {
+	recorder := &SessionRecorder{}
+	ctx := WithSessionRecorder(context.Background(), recorder)
+	_, _ = Exchange(ctx, "p", func(context.Context, string) (string, error) {
+		return "", errors.New("rate limited")
+	})
+	var rec SessionRecord
+	recorder.Fill(&rec)
+	if rec.Error != "rate limited" {
+		t.Errorf("Error = %q, want the request error", rec.Error)
+	}
+	if IsReplay(ctx) {
+		t.Error("IsReplay = true for a recording context")
+	}
+}
+
+func TestSaveAndLoadSessionRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bug", "session.json")
+	rec := &SessionRecord{
+		Kind:        SessionSuggestion,
+		Provider:    "gemini",
+		Context:     &CapturedContext{Command: "gti status", Stderr: "gti: command not found", ExitCode: 127},
+		RawResponse: `{"explanation":"typo","command":"git status"}`,
+		Suggestion:  &Suggestion{Explanation: "typo", CorrectedCommand: "git status"},
+	}
+	if err := SaveSessionRecord(path, rec); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600 since the recording holds command output", info.Mode().Perm())
+	}
+
+	loaded, err := LoadSessionRecord(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version != SessionRecordVersion || loaded.Context.Stderr != rec.Context.Stderr ||
+		loaded.Suggestion.CorrectedCommand != "git status" || loaded.RawResponse != rec.RawResponse {
+		t.Errorf("loaded %+v", loaded)
+	}
+}
+
+func TestLoadSessionRecordRejectsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"newer":      `{"version": 99, "kind": "command"}`,
+		"kind":       `{"version": 1, "kind": "chat"}`,
+		"no context": `{"version": 1, "kind": "suggestion"}`,
+		"not json":   `raw provider output`,
+	}
+	for name, data := range cases {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSessionRecord(path); err == nil {
+			t.Errorf("%s: LoadSessionRecord succeeded, want an error", name)
+		}
+	}
+}
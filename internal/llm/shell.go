@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/TonnyWong1052/aish/internal/shell"
+)
+
+// ShellEnvironment is the shell and operating system generated commands are written for.
+type ShellEnvironment struct {
+	Shell string // Dialect as named by shell.Dialect, e.g. "zsh" or "pwsh"
+	OS    string // e.g. "macOS"
+}
+
+// DetectShellEnvironment returns the user's shell (see shell.Dialect) and operating system.
+func DetectShellEnvironment() ShellEnvironment {
+	return ShellEnvironment{Shell: shell.Dialect(), OS: shell.OSName()}
+}
+
+type shellEnvironmentKey struct{}
+
+// WithShellEnvironment makes providers write commands for env instead of the detected shell,
+// e.g. to replay a session recorded under another shell.
+func WithShellEnvironment(ctx context.Context, env ShellEnvironment) context.Context {
+	return context.WithValue(ctx, shellEnvironmentKey{}, env)
+}
+
+// ShellEnvironmentFrom returns the environment set with WithShellEnvironment, or the detected one.
+func ShellEnvironmentFrom(ctx context.Context) ShellEnvironment {
+	if env, ok := ctx.Value(shellEnvironmentKey{}).(ShellEnvironment); ok && env.Shell != "" {
+		return env
+	}
+	return DetectShellEnvironment()
+}
+
+// CommandPromptData is what the generate_command and generate_plan templates are executed
+// with.
+type CommandPromptData struct {
+	Prompt    string
+	ShellType string // e.g. "zsh", "fish" or "PowerShell 7 (pwsh)"
+	OS        string
+}
+
+// NewCommandPromptData returns the template data for prompt under the shell environment of ctx.
+func NewCommandPromptData(ctx context.Context, prompt string) CommandPromptData {
+	env := ShellEnvironmentFrom(ctx)
+	return CommandPromptData{Prompt: prompt, ShellType: shell.DialectName(env.Shell), OS: env.OS}
+}
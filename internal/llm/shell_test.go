@@ -0,0 +1,27 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func TestNewCommandPromptData(t *testing.T) {
+	t.Setenv(config.EnvAISHShell, "/usr/local/bin/fish")
+	data := NewCommandPromptData(context.Background(), "list files")
+	if data.Prompt != "list files" || data.ShellType != "fish" || data.OS == "" {
+		t.Errorf("detected: %+v", data)
+	}
+
+	ctx := WithShellEnvironment(context.Background(), ShellEnvironment{Shell: "pwsh", OS: "Windows"})
+	if data := NewCommandPromptData(ctx, "list files"); data.ShellType != "PowerShell 7 (pwsh)" || data.OS != "Windows" {
+		t.Errorf("overridden: %+v", data)
+	}
+
+	// A recording made before the shell was kept replays with the detected one
+	ctx = WithShellEnvironment(context.Background(), ShellEnvironment{})
+	if data := NewCommandPromptData(ctx, "list files"); data.ShellType != "fish" {
+		t.Errorf("empty override: %+v", data)
+	}
+}
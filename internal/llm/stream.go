@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// StreamFunc receives each piece of a provider's raw response as it arrives. Pieces are in
+// order and concatenate to the whole response.
+type StreamFunc func(delta string)
+
+// ExchangeStream is Exchange for a streaming request: send passes the response to onChunk as
+// it arrives and returns all of it. A replayed session returns the recorded response without
+// streaming it.
+func ExchangeStream(ctx context.Context, prompt string, send func(context.Context, string, StreamFunc) (string, error), onChunk StreamFunc) (string, error) {
+	return Exchange(ctx, prompt, func(ctx context.Context, prompt string) (string, error) {
+		return send(ctx, prompt, onChunk)
+	})
+}
+
+// ReadSSE reads a server-sent event stream from r and calls onData with the payload of each
+// "data:" line, stopping at "[DONE]", at the end of the stream or when onData fails.
+func ReadSSE(r io.Reader, onData func(payload string) error) error {
+	scanner := bufio.NewScanner(r)
+	// A single event can carry a long piece of text
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		payload = strings.TrimSpace(payload)
+		if payload == "[DONE]" {
+			return nil
+		}
+		if payload == "" {
+			continue
+		}
+		if err := onData(payload); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// StreamedText returns the part of a partial response worth showing while it streams: the
+// value of field decoded so far when the response is a JSON object (possibly in a code fence),
+// otherwise the response itself.
+func StreamedText(partial, field string) string {
+	s := strings.TrimSpace(partial)
+	if rest, ok := strings.CutPrefix(s, "```"); ok {
+		// Wait for the fence's language tag and line break before deciding
+		i := strings.IndexByte(rest, '\n')
+		if i < 0 {
+			return ""
+		}
+		s = strings.TrimSpace(rest[i+1:])
+	}
+	if !strings.HasPrefix(s, "{") {
+		return s
+	}
+	return partialJSONString(s, field)
+}
+
+// partialJSONString decodes the string value of key in the possibly incomplete JSON object s,
+// up to where s ends. An escape sequence cut off by the end is left out.
+func partialJSONString(s, key string) string {
+	i := strings.Index(s, `"`+key+`"`)
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimLeft(s[i+len(key)+2:], " \t\r\n")
+	rest, ok := strings.CutPrefix(rest, ":")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimLeft(rest, " \t\r\n")
+	rest, ok = strings.CutPrefix(rest, `"`)
+	if !ok {
+		return ""
+	}
+
+	var b strings.Builder
+	for len(rest) > 0 {
+		c := rest[0]
+		switch {
+		case c == '"':
+			return b.String()
+		case c != '\\':
+			b.WriteByte(c)
+			rest = rest[1:]
+			continue
+		case len(rest) < 2:
+			return b.String()
+		}
+		switch rest[1] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b', 'f':
+		case 'u':
+			if len(rest) < 6 {
+				return b.String()
+			}
+			r, ok := hexRune(rest[2:6])
+			if !ok {
+				return b.String()
+			}
+			n := 6
+			if utf16.IsSurrogate(r) {
+				// The second half of a surrogate pair follows as another \u escape
+				if len(rest) < 12 {
+					return b.String()
+				}
+				low, _ := hexRune(rest[8:12])
+				r = utf16.DecodeRune(r, low)
+				n = 12
+			}
+			b.WriteRune(r)
+			rest = rest[n:]
+			continue
+		default: // \" \\ \/
+			b.WriteByte(rest[1])
+		}
+		rest = rest[2:]
+	}
+	return b.String()
+}
+
+func hexRune(h string) (rune, bool) {
+	var r rune
+	for i := 0; i < len(h); i++ {
+		c := h[i]
+		switch {
+		case c >= '0' && c <= '9':
+			r = r<<4 | rune(c-'0')
+		case c >= 'a' && c <= 'f':
+			r = r<<4 | rune(c-'a'+10)
+		case c >= 'A' && c <= 'F':
+			r = r<<4 | rune(c-'A'+10)
+		default:
+			return 0, false
+		}
+	}
+	return r, true
+}
@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStreamedText(t *testing.T) {
+	cases := []struct {
+		name    string
+		partial string
+		want    string
+	}{
+		{"plain text", "The file is missing", "The file is missing"},
+		{"before the field", `{"corrected_command": "ls", `, ""},
+		{"field in progress", `{"explanation": "The path is wro`, "The path is wro"},
+		{"field complete", `{"explanation": "Done.", "corrected_command": "ls"}`, "Done."},
+		{"escapes", `{"explanation": "say \"hi\"\nthen é 😀"}`, "say \"hi\"\nthen é 😀"},
+		{"cut escape", `{"explanation": "a\`, "a"},
+		{"cut unicode escape", `{"explanation": "a\u00`, "a"},
+		{"fence without newline", "```js", ""},
+		{"fenced json", "```json\n{\"explanation\": \"x", "x"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := StreamedText(tc.partial, "explanation"); got != tc.want {
+				t.Errorf("StreamedText(%q) = %q, want %q", tc.partial, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	stream := "event: message\ndata: one\n\n: comment\ndata:two\n\ndata: [DONE]\n\ndata: after\n"
+	var got []string
+	err := ReadSSE(strings.NewReader(stream), func(payload string) error {
+		got = append(got, payload)
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "one,two" {
+		t.Fatalf("ReadSSE = %q, %v", got, err)
+	}
+
+	stop := errors.New("stop")
+	err = ReadSSE(strings.NewReader("data: a\ndata: b\n"), func(string) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("ReadSSE error = %v, want %v", err, stop)
+	}
+}
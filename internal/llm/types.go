@@ -19,6 +19,10 @@ type CapturedContext struct {
 	Stdout   string `json:"stdout"`   // Standard output
 	Stderr   string `json:"stderr"`   // Standard error
 	ExitCode int    `json:"exitCode"` // Exit code
+
+	FailedStage *PipelineStage `json:"failedStage,omitempty"` // Set when Command is a pipeline whose stage statuses are known
+	Expansion   string         `json:"expansion,omitempty"`   // Alias or function definition behind the command's name
+	Notes       []string       `json:"notes,omitempty"`       // Facts aish established locally, e.g. where a missing command is installed
 }
 
 // EnhancedCapturedContext represents enhanced command context with more background information
@@ -41,6 +45,12 @@ type Provider interface {
 	// GenerateCommand generates command from natural language prompt
 	GenerateCommand(ctx context.Context, prompt string, language string) (string, error)
 
+	// GetSuggestionStream is GetSuggestion that passes the raw response to onChunk as it arrives
+	GetSuggestionStream(ctx context.Context, capturedCtx CapturedContext, language string, onChunk StreamFunc) (*Suggestion, error)
+
+	// GenerateCommandStream is GenerateCommand that passes the raw response to onChunk as it arrives
+	GenerateCommandStream(ctx context.Context, prompt string, language string, onChunk StreamFunc) (string, error)
+
 	// VerifyConnection verifies connection and gets available models
 	VerifyConnection(ctx context.Context) ([]string, error)
 }
@@ -55,11 +65,16 @@ func RegisterProvider(name string, factory ProviderFactory) {
 	providerFactories[name] = factory
 }
 
-// GetProvider creates a new provider by name
+// GetProvider creates a new provider by name. Its answers are validated (see ValidateSuggestion),
+// so callers never see a nil suggestion or an empty command without an error.
 func GetProvider(name string, cfg config.ProviderConfig, pm *prompt.Manager) (Provider, error) {
 	factory, ok := providerFactories[name]
 	if !ok {
 		return nil, fmt.Errorf("unknown provider: %s", name)
 	}
-	return factory(cfg, pm)
+	p, err := factory(cfg, pm)
+	if err != nil {
+		return nil, err
+	}
+	return validatingProvider{p}, nil
 }
@@ -3,6 +3,8 @@ package llm
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/TonnyWong1052/aish/internal/config"
@@ -174,6 +176,14 @@ func (m *MockProvider) GenerateCommand(ctx context.Context, prompt string, langu
 	return m.command, m.commandErr
 }
 
+func (m *MockProvider) GetSuggestionStream(ctx context.Context, capturedCtx CapturedContext, language string, onChunk StreamFunc) (*Suggestion, error) {
+	return m.GetSuggestion(ctx, capturedCtx, language)
+}
+
+func (m *MockProvider) GenerateCommandStream(ctx context.Context, prompt string, language string, onChunk StreamFunc) (string, error) {
+	return m.GenerateCommand(ctx, prompt, language)
+}
+
 func (m *MockProvider) VerifyConnection(ctx context.Context) ([]string, error) {
 	return m.models, m.connectionErr
 }
@@ -311,3 +321,24 @@ func TestProviderWithError(t *testing.T) {
 		t.Error("Expected nil provider when factory returns error")
 	}
 }
+
+// TestEnhancedPromptVariablesMatchContext keeps the prompt linter's view of the enhanced call
+// site in step with the struct the providers execute those templates with.
+func TestEnhancedPromptVariablesMatchContext(t *testing.T) {
+	typ := reflect.TypeOf(EnhancedCapturedContext{})
+	var names []string
+	for _, f := range reflect.VisibleFields(typ) {
+		if f.IsExported() && !f.Anonymous {
+			names = append(names, f.Name)
+		}
+	}
+	for i := 0; i < typ.NumMethod(); i++ {
+		names = append(names, typ.Method(i).Name)
+	}
+	want := append([]string(nil), prompt.CallSiteVariables["get_enhanced_suggestion"]...)
+	sort.Strings(names)
+	sort.Strings(want)
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("EnhancedCapturedContext exposes %v, prompt linter allows %v", names, want)
+	}
+}
@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage is the token count of one provider request. Providers report what the usage fields of
+// their response say through ReportUsage; Exchange adds estimates from the prompt and response
+// text, which stand in for counts a provider does not report.
+type Usage struct {
+	Model              string
+	Local              bool // A self-hosted model (Ollama), which costs nothing per token
+	PromptEstimate     int  // EstimateTokens of the prompt as sent
+	PromptTokens       int  // As counted by the provider; 0 when it reported none
+	CompletionEstimate int  // EstimateTokens of the response
+	CompletionTokens   int  // As counted by the provider, reasoning included; 0 when it reported none
+	Duration           time.Duration
+}
+
+// Prompt returns the prompt token count: the provider's, else the estimate.
+func (u Usage) Prompt() (tokens int, estimated bool) {
+	if u.PromptTokens > 0 {
+		return u.PromptTokens, false
+	}
+	return u.PromptEstimate, true
+}
+
+// Completion returns the completion token count: the provider's, else the estimate.
+func (u Usage) Completion() (tokens int, estimated bool) {
+	if u.CompletionTokens > 0 {
+		return u.CompletionTokens, false
+	}
+	return u.CompletionEstimate, true
+}
+
+// Cost returns the price of the request in US dollars at the list price of its model, and
+// whether the price is known.
+func (u Usage) Cost() (float64, bool) {
+	if u.Local {
+		return 0, true
+	}
+	price, ok := LookupModelPrice(u.Model)
+	if !ok {
+		return 0, false
+	}
+	prompt, _ := u.Prompt()
+	completion, _ := u.Completion()
+	return (float64(prompt)*price.Input + float64(completion)*price.Output) / 1e6, true
+}
+
+// FormatUsage renders u as "gpt-4o-mini: prompt 812 tokens (~790 estimated), completion 45
+// tokens, $0.000149, 1.4s". Counts marked ~ are estimates.
+func FormatUsage(u Usage) string {
+	model := u.Model
+	if model == "" {
+		model = "unknown model"
+	}
+	parts := []string{}
+
+	prompt, promptEstimated := u.Prompt()
+	if promptEstimated {
+		parts = append(parts, fmt.Sprintf("prompt ~%d tokens", prompt))
+	} else {
+		parts = append(parts, fmt.Sprintf("prompt %d tokens (~%d estimated)", prompt, u.PromptEstimate))
+	}
+	completion, completionEstimated := u.Completion()
+	if completionEstimated {
+		parts = append(parts, fmt.Sprintf("completion ~%d tokens", completion))
+	} else {
+		parts = append(parts, fmt.Sprintf("completion %d tokens", completion))
+	}
+
+	switch cost, ok := u.Cost(); {
+	case u.Local:
+		parts = append(parts, "free (self-hosted model)")
+	case !ok:
+		parts = append(parts, "cost unknown")
+	case promptEstimated || completionEstimated:
+		parts = append(parts, fmt.Sprintf("~$%.6f", cost))
+	default:
+		parts = append(parts, fmt.Sprintf("$%.6f", cost))
+	}
+	parts = append(parts, u.Duration.Round(time.Millisecond).String())
+	return model + ": " + strings.Join(parts, ", ")
+}
+
+// ModelPrice is the list price of a model in US dollars per million tokens.
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// knownModelPrices is matched by prefix in order, so more specific names come first. Prices
+// change; these are meant for the order of magnitude shown with --debug, not for billing.
+var knownModelPrices = []struct {
+	prefix string
+	price  ModelPrice
+}{
+	{"gpt-4o-mini", ModelPrice{0.15, 0.60}},
+	{"gpt-4o", ModelPrice{2.50, 10.00}},
+	{"gpt-4.1-nano", ModelPrice{0.10, 0.40}},
+	{"gpt-4.1-mini", ModelPrice{0.40, 1.60}},
+	{"gpt-4.1", ModelPrice{2.00, 8.00}},
+	{"gpt-4-turbo", ModelPrice{10.00, 30.00}},
+	{"gpt-4", ModelPrice{30.00, 60.00}},
+	{"gpt-3.5-turbo", ModelPrice{0.50, 1.50}},
+	{"gpt-5-nano", ModelPrice{0.05, 0.40}},
+	{"gpt-5-mini", ModelPrice{0.25, 2.00}},
+	{"gpt-5", ModelPrice{1.25, 10.00}},
+	{"o1-mini", ModelPrice{1.10, 4.40}},
+	{"o1", ModelPrice{15.00, 60.00}},
+	{"o3-mini", ModelPrice{1.10, 4.40}},
+	{"o3", ModelPrice{2.00, 8.00}},
+	{"o4-mini", ModelPrice{1.10, 4.40}},
+	{"gemini-2.5-flash-lite", ModelPrice{0.10, 0.40}},
+	{"gemini-2.5-flash", ModelPrice{0.30, 2.50}},
+	{"gemini-2.5-pro", ModelPrice{1.25, 10.00}},
+	{"gemini-2.0-flash-lite", ModelPrice{0.075, 0.30}},
+	{"gemini-2.0-flash", ModelPrice{0.10, 0.40}},
+	{"gemini-1.5-flash", ModelPrice{0.075, 0.30}},
+	{"gemini-1.5-pro", ModelPrice{1.25, 5.00}},
+	{"claude-3-5-haiku", ModelPrice{0.80, 4.00}},
+	{"claude-3-haiku", ModelPrice{0.25, 1.25}},
+	{"claude-haiku-4", ModelPrice{1.00, 5.00}},
+	{"claude-3-opus", ModelPrice{15.00, 75.00}},
+	{"claude-opus-4", ModelPrice{15.00, 75.00}},
+	{"claude-3-5-sonnet", ModelPrice{3.00, 15.00}},
+	{"claude-3-7-sonnet", ModelPrice{3.00, 15.00}},
+	{"claude-sonnet-4", ModelPrice{3.00, 15.00}},
+}
+
+// LookupModelPrice returns the list price of a model. Vendor prefixes such as "openai/" used
+// by gateways are ignored.
+func LookupModelPrice(model string) (ModelPrice, bool) {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, entry := range knownModelPrices {
+		if strings.HasPrefix(name, entry.prefix) {
+			return entry.price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+type usageReporterKey struct{}
+type usageCallKey struct{}
+
+// usageCall collects what the provider reports during one Exchange.
+type usageCall struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// WithUsageReporter makes every provider request under ctx call report with its usage once it
+// has finished, e.g. to show token counts with --debug.
+func WithUsageReporter(ctx context.Context, report func(Usage)) context.Context {
+	return context.WithValue(ctx, usageReporterKey{}, report)
+}
+
+// UsageRequested reports whether a usage reporter is attached to ctx, so providers can ask for
+// usage fields their API only sends on request.
+func UsageRequested(ctx context.Context) bool {
+	report, ok := ctx.Value(usageReporterKey{}).(func(Usage))
+	return ok && report != nil
+}
+
+// ReportUsage records the model and token counts from a provider response for the request
+// being made with ctx. Zero fields leave what was reported before, so a streamed response can
+// report the prompt and completion counts as they arrive.
+func ReportUsage(ctx context.Context, u Usage) {
+	call, ok := ctx.Value(usageCallKey{}).(*usageCall)
+	if !ok || call == nil {
+		return
+	}
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	if u.Model != "" {
+		call.usage.Model = u.Model
+	}
+	call.usage.Local = call.usage.Local || u.Local
+	if u.PromptTokens > 0 {
+		call.usage.PromptTokens = u.PromptTokens
+	}
+	if u.CompletionTokens > 0 {
+		call.usage.CompletionTokens = u.CompletionTokens
+	}
+}
+
+// trackUsage runs send for prompt and passes its usage to the reporter attached to ctx, if
+// there is one.
+func trackUsage(ctx context.Context, prompt string, send func(context.Context, string) (string, error)) (string, error) {
+	report, ok := ctx.Value(usageReporterKey{}).(func(Usage))
+	if !ok || report == nil {
+		return send(ctx, prompt)
+	}
+	call := &usageCall{}
+	start := time.Now()
+	response, err := send(context.WithValue(ctx, usageCallKey{}, call), prompt)
+
+	call.mu.Lock()
+	u := call.usage
+	call.mu.Unlock()
+	if err != nil && response == "" && u.PromptTokens == 0 {
+		// Nothing came back, e.g. the provider could not be reached
+		return response, err
+	}
+	u.PromptEstimate = EstimateTokens(prompt)
+	u.CompletionEstimate = EstimateTokens(response)
+	u.Duration = time.Since(start)
+	report(u)
+	return response, err
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupModelPrice(t *testing.T) {
+	cases := map[string]float64{
+		"gpt-4o-mini-2024-07-18":      0.15,
+		"gpt-4o":                      2.50,
+		"openai/gpt-4.1-mini":         0.40,
+		"claude-3-5-haiku-20241022":   0.80,
+		"anthropic/claude-sonnet-4-0": 3.00,
+		"gemini-2.5-flash-lite":       0.10,
+		"models/gemini-2.5-flash":     0.30,
+	}
+	for model, want := range cases {
+		price, ok := LookupModelPrice(model)
+		if !ok || price.Input != want {
+			t.Errorf("LookupModelPrice(%q) = %v, %v; want input %v", model, price, ok, want)
+		}
+	}
+	if _, ok := LookupModelPrice("llama3.1:8b"); ok {
+		t.Error("LookupModelPrice knows a price for llama3.1:8b")
+	}
+}
+
+func TestFormatUsage(t *testing.T) {
+	cases := []struct {
+		usage Usage
+		want  string
+	}{
+		{
+			Usage{Model: "gpt-4o-mini", PromptEstimate: 790, PromptTokens: 812, CompletionEstimate: 40, CompletionTokens: 45, Duration: 1400 * time.Millisecond},
+			"gpt-4o-mini: prompt 812 tokens (~790 estimated), completion 45 tokens, $0.000149, 1.4s",
+		},
+		{
+			Usage{Model: "gpt-4o-mini", PromptEstimate: 1000, CompletionEstimate: 1000, Duration: time.Second},
+			"gpt-4o-mini: prompt ~1000 tokens, completion ~1000 tokens, ~$0.000750, 1s",
+		},
+		{
+			Usage{Model: "llama3.1", Local: true, PromptEstimate: 10, PromptTokens: 12, CompletionTokens: 3},
+			"llama3.1: prompt 12 tokens (~10 estimated), completion 3 tokens, free (self-hosted model), 0s",
+		},
+		{
+			Usage{PromptEstimate: 10, CompletionEstimate: 2},
+			"unknown model: prompt ~10 tokens, completion ~2 tokens, cost unknown, 0s",
+		},
+	}
+	for _, c := range cases {
+		if got := FormatUsage(c.usage); got != c.want {
+			t.Errorf("FormatUsage(%+v)\n got %q\nwant %q", c.usage, got, c.want)
+		}
+	}
+}
+
+func TestExchangeReportsUsage(t *testing.T) {
+	var reported []Usage
+	ctx := WithUsageReporter(context.Background(), func(u Usage) { reported = append(reported, u) })
+	if !UsageRequested(ctx) || UsageRequested(context.Background()) {
+		t.Fatal("UsageRequested does not match the reporter")
+	}
+
+	prompt := strings.Repeat("a", 400)
+	_, err := Exchange(ctx, prompt, func(ctx context.Context, _ string) (string, error) {
+		ReportUsage(ctx, Usage{Model: "gpt-4o", PromptTokens: 95})
+		ReportUsage(ctx, Usage{CompletionTokens: 7})
+		return "ls -la", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("reported %d times, want once", len(reported))
+	}
+	u := reported[0]
+	if u.Model != "gpt-4o" || u.PromptTokens != 95 || u.CompletionTokens != 7 || u.PromptEstimate != 100 || u.CompletionEstimate != 2 {
+		t.Errorf("reported %+v", u)
+	}
+
+	// A request that never reached the provider is not reported
+	_, _ = Exchange(ctx, prompt, func(context.Context, string) (string, error) {
+		return "", errors.New("connection refused")
+	})
+	if len(reported) != 1 {
+		t.Errorf("reported a failed request: %+v", reported[1:])
+	}
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Bounds on what a provider may answer. A command beyond MaxCommandLength is a model that
+// rambled or echoed its input rather than something to run; an explanation beyond
+// MaxExplanationLength is cut short instead, as it is only read.
+const (
+	MaxCommandLength     = 16 * 1024
+	MaxExplanationLength = 8000
+)
+
+// ValidateSuggestion checks a suggestion before any flow shows or runs it: it must exist and
+// carry a command within MaxCommandLength. The command and explanation are trimmed and an
+// overlong explanation is truncated. Unusable suggestions yield an EmptyResponseError or
+// InvalidResponseError, and a refusal in place of the command (see IsRefusal) a
+// ContentFilterError.
+func ValidateSuggestion(s *Suggestion) (*Suggestion, error) {
+	if s == nil {
+		return nil, NewLLMError(EmptyResponseError, "provider returned no suggestion", nil)
+	}
+	if strings.TrimSpace(s.CorrectedCommand) == "" && IsRefusal(s.Explanation) {
+		return nil, RefusalError(firstLine(s.Explanation))
+	}
+	cmd, err := ValidateCommand(s.CorrectedCommand)
+	if err != nil {
+		return nil, err
+	}
+	return &Suggestion{Explanation: truncateExplanation(s.Explanation), CorrectedCommand: cmd}, nil
+}
+
+// ValidateCommand checks a generated command the same way and returns it trimmed.
+func ValidateCommand(cmd string) (string, error) {
+	cmd = strings.TrimSpace(cmd)
+	switch {
+	case cmd == "":
+		return "", NewLLMError(EmptyResponseError, "provider returned an empty command", nil)
+	case len(cmd) > MaxCommandLength:
+		return "", NewLLMError(InvalidResponseError, fmt.Sprintf("provider returned a %d-byte command (limit %d)", len(cmd), MaxCommandLength), nil)
+	case strings.ContainsRune(cmd, 0) || !utf8.ValidString(cmd):
+		return "", NewLLMError(InvalidResponseError, "provider returned a command with binary data", nil)
+	case IsRefusal(cmd):
+		return "", RefusalError(firstLine(cmd))
+	}
+	return cmd, nil
+}
+
+// truncateExplanation trims explanation and cuts it to MaxExplanationLength runes.
+func truncateExplanation(explanation string) string {
+	explanation = strings.TrimSpace(explanation)
+	if utf8.RuneCountInString(explanation) <= MaxExplanationLength {
+		return explanation
+	}
+	runes := []rune(explanation)
+	return strings.TrimSpace(string(runes[:MaxExplanationLength])) + "…"
+}
+
+// validatingProvider passes every answer of the wrapped provider through ValidateSuggestion or
+// ValidateCommand, so no flow has to guard against nil or empty results itself.
+type validatingProvider struct {
+	Provider
+}
+
+func (p validatingProvider) GetSuggestion(ctx context.Context, capturedCtx CapturedContext, language string) (*Suggestion, error) {
+	return validated(p.Provider.GetSuggestion(ctx, capturedCtx, language))
+}
+
+func (p validatingProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx EnhancedCapturedContext, language string) (*Suggestion, error) {
+	return validated(p.Provider.GetEnhancedSuggestion(ctx, enhancedCtx, language))
+}
+
+func (p validatingProvider) GetSuggestionStream(ctx context.Context, capturedCtx CapturedContext, language string, onChunk StreamFunc) (*Suggestion, error) {
+	return validated(p.Provider.GetSuggestionStream(ctx, capturedCtx, language, onChunk))
+}
+
+func (p validatingProvider) GenerateCommand(ctx context.Context, prompt string, language string) (string, error) {
+	return validatedCommand(p.Provider.GenerateCommand(ctx, prompt, language))
+}
+
+func (p validatingProvider) GenerateCommandStream(ctx context.Context, prompt string, language string, onChunk StreamFunc) (string, error) {
+	return validatedCommand(p.Provider.GenerateCommandStream(ctx, prompt, language, onChunk))
+}
+
+// GeneratePlan, Warmup and AuthExpiry forward the optional interfaces of the wrapped provider.
+func (p validatingProvider) GeneratePlan(ctx context.Context, prompt string, language string) (*Plan, error) {
+	return GeneratePlan(ctx, p.Provider, prompt, language)
+}
+
+func (p validatingProvider) Warmup(ctx context.Context) error {
+	if w, ok := p.Provider.(Warmer); ok {
+		return w.Warmup(ctx)
+	}
+	return nil
+}
+
+func (p validatingProvider) AuthExpiry() (time.Time, bool) {
+	if r, ok := p.Provider.(AuthExpiryReporter); ok {
+		return r.AuthExpiry()
+	}
+	return time.Time{}, false
+}
+
+func validated(s *Suggestion, err error) (*Suggestion, error) {
+	if err != nil {
+		return s, err
+	}
+	return ValidateSuggestion(s)
+}
+
+func validatedCommand(cmd string, err error) (string, error) {
+	if err != nil {
+		return cmd, err
+	}
+	return ValidateCommand(cmd)
+}
@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidateSuggestion(t *testing.T) {
+	s, err := ValidateSuggestion(&Suggestion{Explanation: "  typo \n", CorrectedCommand: " git status\n"})
+	if err != nil || s.CorrectedCommand != "git status" || s.Explanation != "typo" {
+		t.Errorf("got %+v, %v", s, err)
+	}
+
+	for name, bad := range map[string]*Suggestion{
+		"nil":     nil,
+		"empty":   {Explanation: "no idea"},
+		"blank":   {CorrectedCommand: " \n\t"},
+		"huge":    {CorrectedCommand: strings.Repeat("x", MaxCommandLength+1)},
+		"binary":  {CorrectedCommand: "ls\x00"},
+		"invalid": {CorrectedCommand: "ls \xff"},
+	} {
+		var llmErr *LLMError
+		if _, err := ValidateSuggestion(bad); !errors.As(err, &llmErr) || llmErr.Code() != "PROVIDER_RESPONSE" {
+			t.Errorf("%s: expected a provider response error, got %v", name, err)
+		}
+	}
+
+	for name, refusal := range map[string]*Suggestion{
+		"explanation": {Explanation: "I'm sorry, but I can't help with that."},
+		"command":     {Explanation: "n/a", CorrectedCommand: "I cannot assist with this request."},
+	} {
+		var llmErr *LLMError
+		if _, err := ValidateSuggestion(refusal); !errors.As(err, &llmErr) || llmErr.Type != ContentFilterError {
+			t.Errorf("%s: expected a content filter error, got %v", name, err)
+		}
+	}
+
+	long, _ := ValidateSuggestion(&Suggestion{Explanation: strings.Repeat("é", MaxExplanationLength+10), CorrectedCommand: "ls"})
+	if n := utf8.RuneCountInString(long.Explanation); n != MaxExplanationLength+1 || !strings.HasSuffix(long.Explanation, "…") {
+		t.Errorf("explanation not truncated: %d runes", n)
+	}
+}
+
+type emptyProvider struct {
+	Provider
+}
+
+func (emptyProvider) GetSuggestion(ctx context.Context, c CapturedContext, lang string) (*Suggestion, error) {
+	return nil, nil
+}
+
+func (emptyProvider) GenerateCommandStream(ctx context.Context, prompt, lang string, onChunk StreamFunc) (string, error) {
+	return "  ", nil
+}
+
+func TestValidatingProvider(t *testing.T) {
+	p := validatingProvider{emptyProvider{}}
+	if s, err := p.GetSuggestion(context.Background(), CapturedContext{}, "en"); err == nil || s != nil {
+		t.Errorf("nil suggestion passed through: %+v, %v", s, err)
+	}
+	if _, err := p.GenerateCommandStream(context.Background(), "x", "en", nil); err == nil {
+		t.Error("empty command passed through")
+	}
+	if _, ok := p.AuthExpiry(); ok {
+		t.Error("AuthExpiry reported for a provider without one")
+	}
+}
@@ -0,0 +1,588 @@
+package vertex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+// cloudPlatformScope is the OAuth scope Vertex AI accepts for service accounts and ADC.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Vertex AI generateContent structures. Unlike the public Gemini API, Vertex AI requires the
+// role of each content.
+type Content struct {
+	Role  string `json:"role"`
+	Parts []Part `json:"parts"`
+}
+
+type Part struct {
+	Text string `json:"text"`
+}
+
+type GenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+}
+
+type GenerateRequest struct {
+	Contents         []Content        `json:"contents"`
+	GenerationConfig GenerationConfig `json:"generationConfig"`
+}
+
+type Candidate struct {
+	Content struct {
+		Parts []Part `json:"parts"`
+	} `json:"content"`
+	FinishReason string `json:"finishReason"`
+}
+
+type GenerateResponse struct {
+	Candidates     []Candidate `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"` // Set when the prompt itself was blocked
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *UsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string         `json:"modelVersion,omitempty"`
+	Error         *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+// UsageMetadata is the token count of a generation; a streamed one carries the running total
+// in each event.
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"` // Billed as output
+}
+
+// VertexProvider implements the llm.Provider interface for Gemini models on Vertex AI,
+// authenticating with a service account key file or Application Default Credentials.
+type VertexProvider struct {
+	cfg    config.ProviderConfig
+	pm     *prompt.Manager
+	client *http.Client
+
+	credsOnce sync.Once
+	creds     *auth.Credentials
+	credsErr  error
+}
+
+// NewProvider creates a new VertexProvider.
+func NewProvider(cfg config.ProviderConfig, pm *prompt.Manager) (llm.Provider, error) {
+	return &VertexProvider{
+		cfg:    cfg,
+		pm:     pm,
+		client: llm.WithHeaders(llm.NewPooledClient(90*time.Second), cfg.ExtraHeaders),
+	}, nil
+}
+
+func init() {
+	llm.RegisterProvider(config.ProviderVertex, NewProvider)
+}
+
+// GetSuggestion implements the llm.Provider interface.
+func (p *VertexProvider) GetSuggestion(ctx context.Context, capturedContext llm.CapturedContext, lang string) (*llm.Suggestion, error) {
+	return p.GetSuggestionStream(ctx, capturedContext, lang, nil)
+}
+
+// GetSuggestionStream implements the llm.Provider interface.
+func (p *VertexProvider) GetSuggestionStream(ctx context.Context, capturedContext llm.CapturedContext, lang string, onChunk llm.StreamFunc) (*llm.Suggestion, error) {
+	promptTemplate, err := p.pm.GetPrompt("get_suggestion", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := struct {
+		Command  string
+		Stdout   string
+		Stderr   string
+		ExitCode int
+	}{
+		Command:  capturedContext.PromptCommand(),
+		Stdout:   capturedContext.Stdout,
+		Stderr:   capturedContext.Stderr,
+		ExitCode: capturedContext.ExitCode,
+	}
+
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, tpl.String(), onChunk)
+	if err != nil {
+		return nil, fmt.Errorf("Vertex AI request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	// Prefer JSON output, repaired if need be
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	return parseSuggestionResponse(response), nil
+}
+
+// GetEnhancedSuggestion implements the llm.Provider interface with enhanced context.
+func (p *VertexProvider) GetEnhancedSuggestion(ctx context.Context, enhancedCtx llm.EnhancedCapturedContext, lang string) (*llm.Suggestion, error) {
+	promptTemplate, err := p.pm.GetPrompt("get_enhanced_suggestion", prompt.TemplateLanguage(lang))
+	if err != nil {
+		// Fall back to regular suggestion if enhanced template doesn't exist
+		return p.GetSuggestion(ctx, enhancedCtx.CapturedContext, lang)
+	}
+
+	funcMap := template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+	}
+
+	var tpl bytes.Buffer
+	t, err := template.New("prompt").Funcs(funcMap).Parse(promptTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse enhanced template: %w", err)
+	}
+	if err := t.Execute(&tpl, enhancedCtx); err != nil {
+		return nil, fmt.Errorf("failed to execute enhanced template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, tpl.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Vertex AI request failed for enhanced suggestion: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if s, ok := llm.DecodeSuggestion(ctx, response); ok {
+		return s, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	return parseSuggestionResponse(response), nil
+}
+
+// GenerateCommand implements the llm.Provider interface.
+func (p *VertexProvider) GenerateCommand(ctx context.Context, promptText string, lang string) (string, error) {
+	return p.GenerateCommandStream(ctx, promptText, lang, nil)
+}
+
+// GenerateCommandStream implements the llm.Provider interface.
+func (p *VertexProvider) GenerateCommandStream(ctx context.Context, promptText string, lang string, onChunk llm.StreamFunc) (string, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_command", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return "", fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), onChunk)
+	if err != nil {
+		return "", fmt.Errorf("Vertex AI request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if cmd, ok := llm.DecodeCommand(ctx, response); ok {
+		return cmd, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return cmd, nil
+	}
+	return "", llm.NoCommandError(response)
+}
+
+// GeneratePlan implements llm.Planner: the commands of a multi-step task, in order.
+func (p *VertexProvider) GeneratePlan(ctx context.Context, promptText string, lang string) (*llm.Plan, error) {
+	promptTemplate, err := p.pm.GetPrompt("generate_plan", prompt.TemplateLanguage(lang))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	data := llm.NewCommandPromptData(ctx, promptText)
+	var tpl bytes.Buffer
+	t := template.Must(template.New("prompt").Parse(promptTemplate))
+	if err := t.Execute(&tpl, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	response, err := p.exchange(ctx, prompt.WithCommentLanguage(tpl.String(), lang), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Vertex AI request failed: %w", err)
+	}
+	llm.ReportPhase(ctx, llm.PhaseParsing)
+
+	if plan, ok := llm.DecodePlan(ctx, response); ok {
+		return plan, nil
+	}
+	llm.ReportParse(ctx, llm.ParseHeuristic)
+	if cmd := extractPlausibleCommand(response); cmd != "" {
+		return llm.SingleStepPlan(cmd), nil
+	}
+	return nil, llm.NoCommandError(response)
+}
+
+// Warmup opens a connection to the API host ahead of the first request (llm.Warmer).
+func (p *VertexProvider) Warmup(ctx context.Context) error {
+	return llm.WarmupEndpoint(ctx, p.client, p.endpoint())
+}
+
+// VerifyConnection implements the llm.Provider interface. Vertex AI has no model list for
+// publisher models under a project, so a one-token request checks the credentials, project,
+// region and model together.
+func (p *VertexProvider) VerifyConnection(ctx context.Context) ([]string, error) {
+	reqBody := p.buildRequest("ping")
+	reqBody.GenerationConfig.MaxOutputTokens = 5
+	resp, err := p.postGenerate(ctx, reqBody, "generateContent", "")
+	if err != nil {
+		return nil, fmt.Errorf("Vertex AI connection verification failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("Vertex AI connection verification failed: %w", statusError(resp.StatusCode, body))
+	}
+	return []string{p.model()}, nil
+}
+
+// exchange sends message, streaming the response to onChunk when it is set.
+func (p *VertexProvider) exchange(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	if onChunk == nil {
+		return llm.Exchange(ctx, message, p.generateContent)
+	}
+	return llm.ExchangeStream(ctx, message, p.streamGenerateContent, onChunk)
+}
+
+// buildRequest creates the request body for message, sizing the output budget from the
+// model's context window.
+func (p *VertexProvider) buildRequest(message string) GenerateRequest {
+	message, maxTokens := llm.FitPrompt(llm.ResolveModelLimits(p.cfg), message)
+	temperature := 0.1
+	return GenerateRequest{
+		Contents:         []Content{{Role: "user", Parts: []Part{{Text: message}}}},
+		GenerationConfig: GenerationConfig{Temperature: &temperature, MaxOutputTokens: maxTokens},
+	}
+}
+
+// generateContent makes a generateContent request and returns the text of the response.
+func (p *VertexProvider) generateContent(ctx context.Context, message string) (string, error) {
+	resp, err := p.postGenerate(ctx, p.buildRequest(message), "generateContent", "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", llm.WithRetryAfter(statusError(resp.StatusCode, body), resp.Header)
+	}
+	var generation GenerateResponse
+	if err := json.Unmarshal(body, &generation); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	p.reportUsage(ctx, generation)
+	return responseText(generation)
+}
+
+// streamGenerateContent requests the response as server-sent events and passes the text of
+// each event to onChunk as it arrives.
+func (p *VertexProvider) streamGenerateContent(ctx context.Context, message string, onChunk llm.StreamFunc) (string, error) {
+	resp, err := p.postGenerate(ctx, p.buildRequest(message), "streamGenerateContent", "alt=sse")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", llm.WithRetryAfter(statusError(resp.StatusCode, body), resp.Header)
+	}
+
+	var builder strings.Builder
+	err = llm.ReadSSE(resp.Body, func(payload string) error {
+		var generation GenerateResponse
+		if err := json.Unmarshal([]byte(payload), &generation); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		if generation.Error != nil {
+			return fmt.Errorf("API error: %s", generation.Error.Message)
+		}
+		p.reportUsage(ctx, generation)
+		for _, c := range generation.Candidates {
+			if blockedFinishReasons[c.FinishReason] {
+				return llm.RefusalError("response blocked (finishReason " + c.FinishReason + ")")
+			}
+			for _, part := range c.Content.Parts {
+				if part.Text != "" {
+					builder.WriteString(part.Text)
+					onChunk(part.Text)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if builder.Len() == 0 {
+		return "", errors.New("no response candidates returned")
+	}
+	return builder.String(), nil
+}
+
+// reportUsage passes the model and token counts of generation to llm.ReportUsage.
+func (p *VertexProvider) reportUsage(ctx context.Context, generation GenerateResponse) {
+	u := llm.Usage{Model: generation.ModelVersion}
+	if u.Model == "" {
+		u.Model = p.model()
+	}
+	if m := generation.UsageMetadata; m != nil {
+		u.PromptTokens, u.CompletionTokens = m.PromptTokenCount, m.CandidatesTokenCount+m.ThoughtsTokenCount
+	}
+	llm.ReportUsage(ctx, u)
+}
+
+// postGenerate sends reqBody to the model's method endpoint (generateContent or
+// streamGenerateContent) with an access token for the configured credentials. The caller
+// closes the body.
+func (p *VertexProvider) postGenerate(ctx context.Context, reqBody GenerateRequest, method, query string) (*http.Response, error) {
+	creds, err := p.credentials()
+	if err != nil {
+		return nil, err
+	}
+	project, err := p.project(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	token, err := creds.Token(ctx)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.AuthError, "cannot get a Google Cloud access token", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/google/models/%s:%s",
+		p.endpoint(), project, p.location(), p.model(), method)
+	if query != "" {
+		apiURL += "?" + query
+	}
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tokenType := token.Type
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+token.Value)
+	// Bill the quota project of user credentials rather than the API's own project
+	if quota, err := creds.QuotaProjectID(ctx); err == nil && quota != "" {
+		req.Header.Set("X-Goog-User-Project", quota)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// credentials loads the service account key of providers.vertex.credentials_file, or else
+// Application Default Credentials (GOOGLE_APPLICATION_CREDENTIALS, gcloud auth
+// application-default login, or the metadata server). Tokens are fetched through the
+// provider's client, so they honour the configured proxy too.
+func (p *VertexProvider) credentials() (*auth.Credentials, error) {
+	p.credsOnce.Do(func() {
+		if p.creds != nil {
+			return
+		}
+		opts := &credentials.DetectOptions{
+			Scopes: []string{cloudPlatformScope},
+			Client: llm.NewPooledClient(30 * time.Second),
+		}
+		if path := strings.TrimSpace(p.cfg.CredentialsFile); path != "" {
+			opts.CredentialsFile = config.ExpandHome(path)
+		}
+		p.creds, p.credsErr = credentials.DetectDefault(opts)
+		if p.credsErr != nil {
+			p.credsErr = llm.NewLLMError(llm.AuthError,
+				"no Google Cloud credentials: set providers.vertex.credentials_file to a service account key, or run 'gcloud auth application-default login'",
+				p.credsErr)
+		}
+	})
+	return p.creds, p.credsErr
+}
+
+// project returns providers.vertex.project, or else the project of the credentials (a
+// service account key names its own) or GOOGLE_CLOUD_PROJECT.
+func (p *VertexProvider) project(ctx context.Context, creds *auth.Credentials) (string, error) {
+	if project := strings.TrimSpace(p.cfg.Project); project != "" {
+		return project, nil
+	}
+	if project, err := creds.ProjectID(ctx); err == nil && project != "" {
+		return project, nil
+	}
+	if project := strings.TrimSpace(os.Getenv("GOOGLE_CLOUD_PROJECT")); project != "" {
+		return project, nil
+	}
+	return "", llm.NewLLMError(llm.ConfigError, "no Google Cloud project: set providers.vertex.project", nil)
+}
+
+// location returns the configured Vertex AI region, or else GOOGLE_CLOUD_LOCATION or
+// config.DefaultVertexLocation.
+func (p *VertexProvider) location() string {
+	if location := strings.TrimSpace(p.cfg.Location); location != "" {
+		return location
+	}
+	for _, k := range []string{"GOOGLE_CLOUD_LOCATION", "GOOGLE_CLOUD_REGION"} {
+		if s := strings.TrimSpace(os.Getenv(k)); s != "" {
+			return s
+		}
+	}
+	return config.DefaultVertexLocation
+}
+
+// endpoint returns providers.vertex.api_endpoint (e.g. a Private Service Connect endpoint), or
+// else the regional endpoint of the location; the global location has its own host.
+func (p *VertexProvider) endpoint() string {
+	if endpoint := strings.TrimSuffix(strings.TrimSpace(p.cfg.APIEndpoint), "/"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/v1")
+	}
+	if location := p.location(); location != "global" {
+		return "https://" + location + "-aiplatform.googleapis.com"
+	}
+	return "https://aiplatform.googleapis.com"
+}
+
+// model returns the configured model without the "models/" or "google/" prefix.
+func (p *VertexProvider) model() string {
+	model := strings.TrimSpace(p.cfg.Model)
+	model = strings.TrimPrefix(strings.TrimPrefix(model, "models/"), "google/")
+	if model == "" {
+		return config.DefaultVertexModel
+	}
+	return model
+}
+
+// statusError describes a failed request, with the API's message when the body has one.
+func statusError(status int, body []byte) error {
+	var generation GenerateResponse
+	if json.Unmarshal(body, &generation) == nil && generation.Error != nil && generation.Error.Message != "" {
+		return fmt.Errorf("API request failed with status %d: %s", status, generation.Error.Message)
+	}
+	return fmt.Errorf("API request failed with status %d: %s", status, firstN(strings.TrimSpace(string(body)), 512))
+}
+
+// blockedFinishReasons are the finish reasons of a candidate withheld by Gemini's safety filters.
+var blockedFinishReasons = map[string]bool{"SAFETY": true, "PROHIBITED_CONTENT": true, "BLOCKLIST": true, "SPII": true}
+
+// responseText returns the text of the first candidate of generation.
+func responseText(generation GenerateResponse) (string, error) {
+	if generation.Error != nil {
+		return "", fmt.Errorf("API error: %s", generation.Error.Message)
+	}
+	if f := generation.PromptFeedback; f != nil && f.BlockReason != "" {
+		return "", llm.RefusalError("prompt blocked (blockReason " + f.BlockReason + ")")
+	}
+	if len(generation.Candidates) == 0 {
+		return "", errors.New("no response candidates returned")
+	}
+	candidate := generation.Candidates[0]
+	if blockedFinishReasons[candidate.FinishReason] {
+		return "", llm.RefusalError("response blocked (finishReason " + candidate.FinishReason + ")")
+	}
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		text.WriteString(part.Text)
+	}
+	if text.Len() == 0 {
+		return "", errors.New("no content parts in response")
+	}
+	return text.String(), nil
+}
+
+// firstN returns at most n bytes of s (safe for logging)
+func firstN(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func parseSuggestionResponse(response string) *llm.Suggestion {
+	response = strings.TrimSpace(response)
+
+	var explanation, correctedCommand string
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(strings.ToLower(line), "explanation") {
+			if parts := strings.SplitN(line, ":", 2); len(parts) > 1 {
+				explanation = strings.TrimSpace(parts[1])
+			}
+		}
+		if strings.Contains(strings.ToLower(line), "command") {
+			if parts := strings.SplitN(line, ":", 2); len(parts) > 1 {
+				correctedCommand = strings.Trim(strings.TrimSpace(parts[1]), "`")
+			}
+		}
+	}
+
+	if explanation == "" {
+		explanation = "Please check command syntax and parameters."
+	}
+	if correctedCommand == "" {
+		correctedCommand = extractPlausibleCommand(response)
+	}
+	if correctedCommand == "" {
+		correctedCommand = "echo 'Unable to auto-correct command'"
+	}
+	return &llm.Suggestion{Explanation: explanation, CorrectedCommand: correctedCommand}
+}
+
+// extractPlausibleCommand returns the first line of the first fenced code block of text, or
+// else its first line that is not a comment.
+func extractPlausibleCommand(text string) string {
+	s := strings.TrimSpace(text)
+	if idx := strings.Index(s, "```"); idx != -1 {
+		if end := strings.Index(s[idx+3:], "```"); end != -1 {
+			block := s[idx+3 : idx+3+end]
+			// Drop the language tag of the fence
+			if nl := strings.IndexByte(block, '\n'); nl != -1 {
+				block = block[nl+1:]
+			}
+			s = block
+		}
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package vertex
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// writeServiceAccountKey writes a service account key whose tokens are issued by tokenURL.
+func writeServiceAccountKey(t *testing.T, tokenURL string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "sa-project",
+		"private_key_id": "key-1",
+		"private_key":    string(keyPEM),
+		"client_email":   "aish@sa-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURL,
+	})
+	path := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGenerateContentWithServiceAccount(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv("GOOGLE_CLOUD_QUOTA_PROJECT", "")
+	var tokens int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokens++
+			fmt.Fprint(w, `{"access_token":"ya29.test","token_type":"Bearer","expires_in":3600}`)
+			return
+		}
+		if r.URL.Path != "/v1/projects/sa-project/locations/europe-west4/publishers/google/models/gemini-2.5-flash:generateContent" {
+			t.Errorf("unexpected URL %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "Bearer ya29.test" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		var req GenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if len(req.Contents) != 1 || req.Contents[0].Role != "user" || req.GenerationConfig.MaxOutputTokens <= 0 {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"{\"command\": "},{"text":"\"ls\"}"}]},"finishReason":"STOP"}]}`)
+	}))
+	defer srv.Close()
+
+	p := &VertexProvider{
+		cfg: config.ProviderConfig{
+			APIEndpoint:     srv.URL,
+			Location:        "europe-west4",
+			Model:           "google/gemini-2.5-flash",
+			CredentialsFile: writeServiceAccountKey(t, srv.URL+"/token"),
+		},
+		client: srv.Client(),
+	}
+	for i := 0; i < 2; i++ {
+		got, err := p.generateContent(context.Background(), "list files")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != `{"command": "ls"}` {
+			t.Errorf("got %q", got)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("token requested %d times, want once and cached", tokens)
+	}
+}
+
+func TestStreamGenerateContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token":"ya29.test","token_type":"Bearer","expires_in":3600}`)
+			return
+		}
+		if r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected URL %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"git \"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"status\"}]},\"finishReason\":\"STOP\"}]}\n\n")
+	}))
+	defer srv.Close()
+
+	p := &VertexProvider{
+		cfg:    config.ProviderConfig{APIEndpoint: srv.URL, Project: "my-project", CredentialsFile: writeServiceAccountKey(t, srv.URL+"/token")},
+		client: srv.Client(),
+	}
+	var pieces []string
+	got, err := p.streamGenerateContent(context.Background(), "show status", func(s string) { pieces = append(pieces, s) })
+	if err != nil || got != "git status" || len(pieces) != 2 {
+		t.Errorf("got %q, %v in pieces %q", got, err, pieces)
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_LOCATION", "")
+	t.Setenv("GOOGLE_CLOUD_REGION", "")
+	for _, c := range []struct {
+		cfg  config.ProviderConfig
+		want string
+	}{
+		{config.ProviderConfig{}, "https://us-central1-aiplatform.googleapis.com"},
+		{config.ProviderConfig{Location: "asia-east1"}, "https://asia-east1-aiplatform.googleapis.com"},
+		{config.ProviderConfig{Location: "global"}, "https://aiplatform.googleapis.com"},
+		{config.ProviderConfig{APIEndpoint: "https://vertex.psc.internal/v1/", Location: "us-east1"}, "https://vertex.psc.internal"},
+	} {
+		if got := (&VertexProvider{cfg: c.cfg}).endpoint(); got != c.want {
+			t.Errorf("endpoint(%+v) = %q, want %q", c.cfg, got, c.want)
+		}
+	}
+}
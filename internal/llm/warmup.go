@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WarmupTimeout bounds the background warmup request.
+const WarmupTimeout = 3 * time.Second
+
+// Warmer is implemented by providers that can open their connection ahead of the first real
+// request, so DNS, TCP and TLS setup do not add to the latency of the first suggestion.
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// StartWarmup fires provider's warmup in the background when it supports one. The result is
+// ignored: a failed warmup only means the first request pays the connection cost as before.
+func StartWarmup(ctx context.Context, provider Provider) {
+	w, ok := provider.(Warmer)
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, WarmupTimeout)
+		defer cancel()
+		_ = w.Warmup(ctx)
+	}()
+}
+
+// WarmupEndpoint sends a HEAD request to the host of endpoint through client, leaving an idle
+// connection in the client's pool. Any HTTP response counts as success, since only the
+// connection matters.
+func WarmupEndpoint(ctx context.Context, client *http.Client, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	root := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, root, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	// Drain the body so the connection returns to the pool
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
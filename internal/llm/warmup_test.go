@@ -0,0 +1,24 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWarmupEndpointSendsHeadToHostRoot(t *testing.T) {
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if err := WarmupEndpoint(context.Background(), server.Client(), server.URL+"/v1/chat/completions"); err != nil {
+		t.Fatalf("WarmupEndpoint: %v", err)
+	}
+	if method != http.MethodHead || path != "/" {
+		t.Errorf("expected HEAD /, got %s %s", method, path)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/TonnyWong1052/aish/internal/config"
 	"github.com/sirupsen/logrus"
 )
 
@@ -48,12 +49,12 @@ type Config struct {
 
 // DefaultConfig returns default configuration
 func DefaultConfig() Config {
-	home, _ := os.UserHomeDir()
+	logDir, _ := config.LogDir()
 	return Config{
 		Level:      InfoLevel,
 		Format:     "text",
 		Output:     "file",
-		LogFile:    filepath.Join(home, ".config", "aish", "logs", "aish.log"),
+		LogFile:    filepath.Join(logDir, config.DefaultLogFileName),
 		MaxSize:    10, // 10MB
 		MaxBackups: 5,
 	}
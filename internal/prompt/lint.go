@@ -0,0 +1,152 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// CallSiteVariables lists the fields each prompt's template is executed with. A template that
+// references anything else would only fail once a provider executes it, so Validate checks
+// templates against these sets when they are loaded.
+var CallSiteVariables = map[string][]string{
+	"generate_command": {"Prompt", "ShellType", "OS"},
+	"generate_plan":    {"Prompt", "ShellType", "OS"},
+	"get_suggestion":   {"Command", "Stdout", "Stderr", "ExitCode"},
+	"get_enhanced_suggestion": {
+		"Command", "Stdout", "Stderr", "ExitCode", "FailedStage", "Expansion", "Notes", "PromptCommand",
+		"RecentCommands", "DirectoryListing", "WorkingDirectory", "ShellType",
+	},
+}
+
+// callSiteFuncs are the template functions a call site registers beyond the builtins.
+var callSiteFuncs = map[string]template.FuncMap{
+	"get_enhanced_suggestion": {"add": func(a, b int) int { return a + b }},
+}
+
+// LintError reports a prompt template that cannot run at its call site.
+type LintError struct {
+	Key  string
+	Lang string
+	Msg  string
+}
+
+func (e *LintError) Error() string {
+	return fmt.Sprintf("prompt %q (%s): %s", e.Key, e.Lang, e.Msg)
+}
+
+// LintTemplate checks that source parses and references only the variables available to the
+// call site key. Keys without a known call site are not checked.
+func LintTemplate(key, lang, source string) error {
+	allowed, ok := CallSiteVariables[key]
+	if !ok {
+		return nil
+	}
+	t, err := template.New(key).Funcs(callSiteFuncs[key]).Parse(source)
+	if err != nil {
+		return &LintError{Key: key, Lang: lang, Msg: err.Error()}
+	}
+
+	known := make(map[string]bool, len(allowed))
+	for _, v := range allowed {
+		known[v] = true
+	}
+	unknown := map[string]bool{}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			collectRootFields(tmpl.Tree.Root, true, func(name string) {
+				if !known[name] {
+					unknown[name] = true
+				}
+			})
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(unknown))
+	for name := range unknown {
+		names = append(names, "."+name)
+	}
+	sort.Strings(names)
+	return &LintError{Key: key, Lang: lang, Msg: fmt.Sprintf("references %s, which this prompt is not given (available: %s)",
+		strings.Join(names, ", "), "."+strings.Join(allowed, ", ."))}
+}
+
+// collectRootFields reports the top-level data fields node refers to. atRoot is whether dot
+// is still the template data; inside range and with bodies it is not, and only $.Field
+// references reach the data.
+func collectRootFields(node parse.Node, atRoot bool, report func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectRootFields(child, atRoot, report)
+		}
+	case *parse.ActionNode:
+		collectRootFields(n.Pipe, atRoot, report)
+	case *parse.TemplateNode:
+		collectRootFields(n.Pipe, atRoot, report)
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, atRoot, atRoot, report)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, atRoot, false, report)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, atRoot, false, report)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectRootFields(arg, atRoot, report)
+			}
+		}
+	case *parse.FieldNode:
+		if atRoot && len(n.Ident) > 0 {
+			report(n.Ident[0])
+		}
+	case *parse.ChainNode:
+		collectRootFields(n.Node, atRoot, report)
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			report(n.Ident[1])
+		}
+	}
+}
+
+func collectBranch(b *parse.BranchNode, atRoot, bodyAtRoot bool, report func(string)) {
+	collectRootFields(b.Pipe, atRoot, report)
+	collectRootFields(b.List, bodyAtRoot, report)
+	collectRootFields(b.ElseList, atRoot, report)
+}
+
+// Validate lints every template the manager holds, so a bad prompts file is rejected when it
+// is loaded instead of failing later inside a provider.
+func (m *Manager) Validate() error {
+	keys := make([]string, 0, len(m.prompts))
+	for key := range m.prompts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		langs := make([]string, 0, len(m.prompts[key]))
+		for lang := range m.prompts[key] {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		for _, lang := range langs {
+			if err := LintTemplate(key, lang, m.prompts[key][lang]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
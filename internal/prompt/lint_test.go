@@ -0,0 +1,74 @@
+package prompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPromptsLint(t *testing.T) {
+	if err := NewDefaultManager().Validate(); err != nil {
+		t.Fatalf("built-in prompts fail lint: %v", err)
+	}
+}
+
+func TestLintTemplate(t *testing.T) {
+	cases := []struct {
+		key, source string
+		wantErr     string
+	}{
+		{"generate_command", "Prompt: {{.Prompt}}", ""},
+		{"generate_command", "Prompt: {{.Prompt}} in {{.WorkingDirectory}}", ".WorkingDirectory"},
+		{"get_suggestion", "{{.Command}} {{.ExitCode}} {{.Stderr | printf \"%q\"}}", ""},
+		{"get_suggestion", "{{.Command}} {{.Prompt}}", ".Prompt"},
+		{"get_suggestion", "{{range .RecentCommands}}{{.}}{{end}}", ".RecentCommands"},
+		{"get_enhanced_suggestion", "{{range $i, $c := .RecentCommands}}{{add $i 1}}. {{$c}} {{.Whatever}}{{end}}", ""},
+		{"get_enhanced_suggestion", "{{with .ShellType}}{{$.Bogus}}{{end}}", ".Bogus"},
+		{"get_enhanced_suggestion", "{{if .Notes}}{{.Note}}{{end}}", ".Note"},
+		{"get_suggestion", "{{add 1 2}}", "function \"add\" not defined"},
+		{"get_suggestion", "{{.Command", "unclosed action"},
+		{"custom_key", "{{.Anything}}", ""},
+	}
+	for _, tc := range cases {
+		err := LintTemplate(tc.key, "en", tc.source)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("LintTemplate(%s, %q) = %v, want nil", tc.key, tc.source, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("LintTemplate(%s, %q) = %v, want error mentioning %s", tc.key, tc.source, err, tc.wantErr)
+		}
+	}
+}
+
+func TestNewManagerRejectsBadTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	data := `{"get_suggestion": {"en": "{{.Command}}", "ja": "{{.Prompt}}"}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewManager(path)
+	var lintErr *LintError
+	if !errors.As(err, &lintErr) || lintErr.Lang != "ja" {
+		t.Fatalf("NewManager = %v, want a LintError for the ja template", err)
+	}
+}
+
+func TestNewManagerKeepsDefaultsForMissingPrompts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	data := `{"generate_command": {"en": "Custom: {{.Prompt}}"}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl, err := m.GetPrompt("generate_command", "en"); err != nil || tpl != "Custom: {{.Prompt}}" {
+		t.Errorf("generate_command = %v, %v; want the file's template", tpl, err)
+	}
+	if _, err := m.GetPrompt("generate_plan", "en"); err != nil {
+		t.Errorf("generate_plan missing from the file should use the default, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package prompt
+
+import "strings"
+
+// languageNames maps the language preferences accepted by aish to names an LLM understands.
+var languageNames = map[string]string{
+	"en":         "English",
+	"english":    "English",
+	"zh":         "Traditional Chinese",
+	"zh-tw":      "Traditional Chinese",
+	"chinese":    "Traditional Chinese",
+	"繁體中文":       "Traditional Chinese",
+	"中文":         "Traditional Chinese",
+	"zh-cn":      "Simplified Chinese",
+	"ja":         "Japanese",
+	"japanese":   "Japanese",
+	"ko":         "Korean",
+	"korean":     "Korean",
+	"es":         "Spanish",
+	"spanish":    "Spanish",
+	"fr":         "French",
+	"french":     "French",
+	"de":         "German",
+	"german":     "German",
+	"it":         "Italian",
+	"italian":    "Italian",
+	"pt":         "Portuguese",
+	"portuguese": "Portuguese",
+	"ru":         "Russian",
+	"russian":    "Russian",
+	"ar":         "Arabic",
+	"arabic":     "Arabic",
+	"he":         "Hebrew",
+	"hebrew":     "Hebrew",
+}
+
+// templateLanguages maps language names to the keys the prompt templates are stored under.
+// Hebrew has no templates of its own and uses the English ones.
+var templateLanguages = map[string]string{
+	"English":             "en",
+	"Traditional Chinese": "zh-TW",
+	"Simplified Chinese":  "zh-CN",
+	"Japanese":            "japanese",
+	"Korean":              "korean",
+	"Spanish":             "spanish",
+	"French":              "french",
+	"German":              "german",
+	"Italian":             "italian",
+	"Portuguese":          "portuguese",
+	"Russian":             "russian",
+	"Arabic":              "arabic",
+}
+
+// TemplateLanguage returns the template key for a language preference, "en" when there are
+// no templates in that language.
+func TemplateLanguage(lang string) string {
+	if key, ok := templateLanguages[LanguageName(lang)]; ok {
+		return key
+	}
+	return "en"
+}
+
+// DefaultFallbackChain returns the template languages tried, before English, when there is no
+// template in lang: the other Chinese script for Chinese, none otherwise.
+func DefaultFallbackChain(lang string) []string {
+	switch lang {
+	case "zh-CN":
+		return []string{"zh-TW"}
+	case "zh-TW":
+		return []string{"zh-CN"}
+	}
+	return []string{}
+}
+
+// templateKey returns the template key for a language preference, or lang itself when it is
+// not a preference aish knows, so fallback chains may also name template keys directly.
+func templateKey(lang string) string {
+	if key, ok := templateLanguages[LanguageName(lang)]; ok {
+		return key
+	}
+	return lang
+}
+
+// LanguageName returns the English name of a language preference, or "" when it is unknown.
+func LanguageName(lang string) string {
+	return languageNames[strings.ToLower(strings.TrimSpace(lang))]
+}
+
+// WithCommentLanguage asks the model to write shell comments and step descriptions in the
+// user's language while leaving commands untouched. English (and unknown languages) leave
+// the prompt unchanged, since every template already defaults to English.
+// The instruction is placed before a trailing "JSON:" answer cue so the cue stays last.
+func WithCommentLanguage(rendered, lang string) string {
+	name := LanguageName(lang)
+	if name == "" || name == "English" {
+		return rendered
+	}
+	instruction := "Write any shell comments (# ...) and step descriptions in " + name +
+		". Keep commands, flags, paths and variable names exactly as they must be typed; never translate them."
+
+	trimmed := strings.TrimRight(rendered, " \n")
+	for _, cue := range []string{"JSON:", "JSON："} {
+		if strings.HasSuffix(trimmed, cue) {
+			return strings.TrimSuffix(trimmed, cue) + instruction + "\n" + cue
+		}
+	}
+	return rendered + "\n" + instruction
+}
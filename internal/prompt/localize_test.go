@@ -0,0 +1,88 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithCommentLanguageEnglishUnchanged(t *testing.T) {
+	in := "Prompt: list files\nJSON:"
+	for _, lang := range []string{"en", "english", "", "klingon"} {
+		if got := WithCommentLanguage(in, lang); got != in {
+			t.Errorf("lang %q changed prompt: %q", lang, got)
+		}
+	}
+}
+
+func TestWithCommentLanguageKeepsAnswerCueLast(t *testing.T) {
+	got := WithCommentLanguage("Prompt: list files\nJSON:", "japanese")
+	if !strings.Contains(got, "in Japanese") {
+		t.Errorf("missing language instruction: %q", got)
+	}
+	if !strings.HasSuffix(got, "\nJSON:") {
+		t.Errorf("answer cue should stay last: %q", got)
+	}
+
+	got = WithCommentLanguage("提示：列出檔案\nJSON：", "zh-TW")
+	if !strings.Contains(got, "Traditional Chinese") || !strings.HasSuffix(got, "JSON：") {
+		t.Errorf("unexpected zh-TW prompt: %q", got)
+	}
+}
+
+func TestWithCommentLanguageWithoutCue(t *testing.T) {
+	got := WithCommentLanguage("Generate a backup script", "de")
+	if !strings.HasPrefix(got, "Generate a backup script\n") || !strings.Contains(got, "German") {
+		t.Errorf("unexpected prompt: %q", got)
+	}
+}
+
+func TestTemplateLanguageCoversEveryPreference(t *testing.T) {
+	pm := NewDefaultManager()
+	for lang, name := range languageNames {
+		key := TemplateLanguage(lang)
+		if name != "English" && name != "Hebrew" && key == "en" {
+			t.Errorf("TemplateLanguage(%q) fell back to English although %s templates exist", lang, name)
+		}
+		for _, prompt := range []string{"generate_command", "get_suggestion", "get_enhanced_suggestion"} {
+			if _, ok := pm.prompts[prompt][key]; !ok {
+				t.Errorf("TemplateLanguage(%q) = %q, which has no %s template", lang, key, prompt)
+			}
+		}
+	}
+	for lang, want := range map[string]string{"zh-CN": "zh-CN", "zh-TW": "zh-TW", "JA": "japanese", " de ": "german", "he": "en", "klingon": "en", "": "en"} {
+		if got := TemplateLanguage(lang); got != want {
+			t.Errorf("TemplateLanguage(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	m := &Manager{prompts: map[string]map[string]string{
+		"generate_command": {"zh-TW": "繁體", "en": "english", "japanese": "日本語"},
+		"get_suggestion":   {"zh-TW": "繁體"},
+	}}
+
+	// Default chain: the other Chinese script, then English
+	if _, used, err := m.Resolve("generate_command", "zh-CN"); err != nil || used != "zh-TW" {
+		t.Errorf("zh-CN used %q, %v; want zh-TW", used, err)
+	}
+	if _, used, err := m.Resolve("generate_command", "korean"); err != nil || used != "en" {
+		t.Errorf("korean used %q, %v; want en", used, err)
+	}
+	if _, _, err := m.Resolve("get_suggestion", "german"); err == nil || !strings.Contains(err.Error(), "german, en") {
+		t.Errorf("missing template error = %v, want it to list the languages tried", err)
+	}
+
+	// A configured chain, given as preferences or template keys
+	m.SetFallbackChain([]string{"ja", " zh-TW "})
+	if _, used, _ := m.Resolve("generate_command", "korean"); used != "japanese" {
+		t.Errorf("configured chain used %q, want japanese", used)
+	}
+	if _, used, err := m.Resolve("get_suggestion", "german"); err != nil || used != "zh-TW" {
+		t.Errorf("configured chain used %q, %v; want zh-TW", used, err)
+	}
+
+	if _, _, err := m.Resolve("nope", "en"); err == nil {
+		t.Error("unknown key should be an error")
+	}
+}
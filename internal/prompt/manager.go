@@ -4,14 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
 )
 
 // Manager handles loading and accessing prompts.
 type Manager struct {
-	prompts map[string]map[string]string
+	prompts  map[string]map[string]string
+	fallback []string // Template languages tried when the requested one has no template; nil = DefaultFallbackChain
 }
 
-// NewManager creates a prompt manager from a file.
+// NewManager creates a prompt manager from a file, rejecting templates that reference
+// variables their call site does not provide.
 func NewManager(path string) (*Manager, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -23,25 +28,45 @@ func NewManager(path string) (*Manager, error) {
 		return nil, err
 	}
 
-	return &Manager{prompts: prompts}, nil
+	// Prompts the file leaves out keep their built-in templates, so a file written before a
+	// prompt existed goes on working
+	if prompts == nil {
+		prompts = map[string]map[string]string{}
+	}
+	for key, langPrompts := range NewDefaultManager().prompts {
+		if _, ok := prompts[key]; !ok {
+			prompts[key] = langPrompts
+		}
+	}
+	m := &Manager{prompts: prompts}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
 }
 
 // NewDefaultManager creates a prompt manager with built-in default prompts.
 func NewDefaultManager() *Manager {
 	defaultPrompts := map[string]map[string]string{
 		"generate_command": {
-			"en": "You are a shell command generator for macOS. Output ONLY a single-line JSON object with the exact schema: {\"command\":\"<shell>\"}. No prose, no markdown, no extra keys. Use a safe, single command. The command MUST be a valid macOS shell command. If the prompt is a general question or cannot be performed, return an echo command that prints a concise answer, e.g., {\"command\":\"echo '...simple answer...'\"}. The command should be directly usable, not like `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
-            "zh-TW":      "你是 macOS 的指令產生器。僅輸出一行 JSON，結構嚴格為：{\"command\":\"<shell>\"}。不要輸出說明、Markdown 或多餘鍵。必須輸出有效的 macOS Shell 指令。若使用者的提示屬一般問答或無法執行，請輸出 echo 指令將簡短答案印出，例如：{\"command\":\"echo '...簡短答案...'\"}。指令需可直接使用，避免產生如 `ls -a \"<path_to_directory_or_file>\"` 的佔位符。\n提示：{{.Prompt}}\nJSON：",
-			"zh-CN":      "你是 macOS 的命令生成器。只输出一行 JSON，结构严格为：{\"command\":\"<shell>\"}。不要输出说明、Markdown 或多余键。请生成安全且可执行的单一命令，命令需可直接使用，避免生成如 `ls -a \"<path_to_directory_or_file>\"` 的占位符。\n提示：{{.Prompt}}\nJSON：",
-			"japanese":   "あなたは macOS のシェルコマンド生成器です。正確なスキーマ {\"command\":\"<shell>\"} で単一行の JSON オブジェクトのみを出力してください。散文、Markdown、余分なキーは含めないでください。安全で単一のコマンドを使用してください。コマンドは直接使用可能である必要があり、`ls -a \"<path_to_directory_or_file>\"` のようなプレースホルダーを生成しないでください。\nプロンプト：{{.Prompt}}\nJSON：",
-			"korean":     "당신은 macOS용 셸 명령어 생성기입니다. 정확한 스키마 {\"command\":\"<shell>\"}로 단일 라인 JSON 객체만 출력하세요. 산문, 마크다운, 추가 키는 포함하지 마세요. 안전하고 단일 명령어를 사용하세요. 명령어는 직접 사용 가능해야 하며, `ls -a \"<path_to_directory_or_file>\"`와 같은 플레이스홀더를 생성하지 마세요.\n프롬프트：{{.Prompt}}\nJSON：",
-			"spanish":    "Eres un generador de comandos de shell para macOS. Solo emite un objeto JSON de una línea con el esquema exacto: {\"command\":\"<shell>\"}. Sin prosa, sin markdown, sin claves extra. Usa un comando seguro y único. El comando debe ser directamente utilizable, no como `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
-			"french":     "Vous êtes un générateur de commandes shell pour macOS. Ne sortez qu'un objet JSON d'une ligne avec le schéma exact : {\"command\":\"<shell>\"}. Pas de prose, pas de markdown, pas de clés supplémentaires. Utilisez une commande sûre et unique. La commande doit être directement utilisable, pas comme `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
-			"german":     "Sie sind ein Shell-Befehl-Generator für macOS. Geben Sie nur ein einzeiliges JSON-Objekt mit dem exakten Schema aus: {\"command\":\"<shell>\"}. Keine Prosa, kein Markdown, keine zusätzlichen Schlüssel. Verwenden Sie einen sicheren, einzelnen Befehl. Der Befehl sollte direkt verwendbar sein, nicht wie `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
-			"italian":    "Sei un generatore di comandi shell per macOS. Emetti solo un oggetto JSON a riga singola con lo schema esatto: {\"command\":\"<shell>\"}. Niente prosa, niente markdown, niente chiavi extra. Usa un comando sicuro e singolo. Il comando dovrebbe essere direttamente utilizzabile, non come `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
-			"portuguese": "Você é um gerador de comandos shell para macOS. Emita apenas um objeto JSON de linha única com o esquema exato: {\"command\":\"<shell>\"}. Sem prosa, sem markdown, sem chaves extras. Use um comando seguro e único. O comando deve ser diretamente utilizável, não como `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
-			"russian":    "Вы генератор команд оболочки для macOS. Выводите только однострочный JSON объект с точной схемой: {\"command\":\"<shell>\"}. Без прозы, без markdown, без лишних ключей. Используйте безопасную, единственную команду. Команда должна быть непосредственно применимой, не как `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
-			"arabic":     "أنت مولد أوامر shell لـ macOS. أخرج فقط كائن JSON بسطر واحد بالمخطط الدقيق: {\"command\":\"<shell>\"}. بدون نثر، بدون markdown، بدون مفاتيح إضافية. استخدم أمرًا آمنًا واحدًا. يجب أن يكون الأمر قابلاً للاستخدام مباشرة، وليس مثل `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+			"en": "You are a {{.ShellType}} command generator for {{.OS}}. Output ONLY a single-line JSON object with the exact schema: {\"command\":\"<shell>\"}. No prose, no markdown, no extra keys. Use a safe, single command. The command MUST be valid {{.ShellType}} syntax on {{.OS}}, e.g. fish or PowerShell syntax when that is the shell. If the prompt is a general question or cannot be performed, return an echo command that prints a concise answer, e.g., {\"command\":\"echo '...simple answer...'\"}. The command should be directly usable, not like `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+            "zh-TW":      "你是 {{.OS}} ({{.ShellType}}) 的指令產生器。僅輸出一行 JSON，結構嚴格為：{\"command\":\"<shell>\"}。不要輸出說明、Markdown 或多餘鍵。必須輸出有效的 {{.ShellType}} 語法指令（於 {{.OS}}）。若使用者的提示屬一般問答或無法執行，請輸出 echo 指令將簡短答案印出，例如：{\"command\":\"echo '...簡短答案...'\"}。指令需可直接使用，避免產生如 `ls -a \"<path_to_directory_or_file>\"` 的佔位符。\n提示：{{.Prompt}}\nJSON：",
+			"zh-CN":      "你是 {{.OS}} ({{.ShellType}}) 的命令生成器。只输出一行 JSON，结构严格为：{\"command\":\"<shell>\"}。不要输出说明、Markdown 或多余键。请生成安全且可执行的单一命令，命令需可直接使用，避免生成如 `ls -a \"<path_to_directory_or_file>\"` 的占位符。\n提示：{{.Prompt}}\nJSON：",
+			"japanese":   "あなたは {{.OS}} ({{.ShellType}}) のシェルコマンド生成器です。正確なスキーマ {\"command\":\"<shell>\"} で単一行の JSON オブジェクトのみを出力してください。散文、Markdown、余分なキーは含めないでください。安全で単一のコマンドを使用してください。コマンドは直接使用可能である必要があり、`ls -a \"<path_to_directory_or_file>\"` のようなプレースホルダーを生成しないでください。\nプロンプト：{{.Prompt}}\nJSON：",
+			"korean":     "당신은 {{.OS}} ({{.ShellType}})용 셸 명령어 생성기입니다. 정확한 스키마 {\"command\":\"<shell>\"}로 단일 라인 JSON 객체만 출력하세요. 산문, 마크다운, 추가 키는 포함하지 마세요. 안전하고 단일 명령어를 사용하세요. 명령어는 직접 사용 가능해야 하며, `ls -a \"<path_to_directory_or_file>\"`와 같은 플레이스홀더를 생성하지 마세요.\n프롬프트：{{.Prompt}}\nJSON：",
+			"spanish":    "Eres un generador de comandos de shell para {{.OS}} ({{.ShellType}}). Solo emite un objeto JSON de una línea con el esquema exacto: {\"command\":\"<shell>\"}. Sin prosa, sin markdown, sin claves extra. Usa un comando seguro y único. El comando debe ser directamente utilizable, no como `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+			"french":     "Vous êtes un générateur de commandes shell pour {{.OS}} ({{.ShellType}}). Ne sortez qu'un objet JSON d'une ligne avec le schéma exact : {\"command\":\"<shell>\"}. Pas de prose, pas de markdown, pas de clés supplémentaires. Utilisez une commande sûre et unique. La commande doit être directement utilisable, pas comme `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+			"german":     "Sie sind ein Shell-Befehl-Generator für {{.OS}} ({{.ShellType}}). Geben Sie nur ein einzeiliges JSON-Objekt mit dem exakten Schema aus: {\"command\":\"<shell>\"}. Keine Prosa, kein Markdown, keine zusätzlichen Schlüssel. Verwenden Sie einen sicheren, einzelnen Befehl. Der Befehl sollte direkt verwendbar sein, nicht wie `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+			"italian":    "Sei un generatore di comandi shell per {{.OS}} ({{.ShellType}}). Emetti solo un oggetto JSON a riga singola con lo schema esatto: {\"command\":\"<shell>\"}. Niente prosa, niente markdown, niente chiavi extra. Usa un comando sicuro e singolo. Il comando dovrebbe essere direttamente utilizzabile, non come `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+			"portuguese": "Você é um gerador de comandos shell para {{.OS}} ({{.ShellType}}). Emita apenas um objeto JSON de linha única com o esquema exato: {\"command\":\"<shell>\"}. Sem prosa, sem markdown, sem chaves extras. Use um comando seguro e único. O comando deve ser diretamente utilizável, não como `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+			"russian":    "Вы генератор команд оболочки для {{.OS}} ({{.ShellType}}). Выводите только однострочный JSON объект с точной схемой: {\"command\":\"<shell>\"}. Без прозы, без markdown, без лишних ключей. Используйте безопасную, единственную команду. Команда должна быть непосредственно применимой, не как `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+			"arabic":     "أنت مولد أوامر shell لـ {{.OS}} ({{.ShellType}}). أخرج فقط كائن JSON بسطر واحد بالمخطط الدقيق: {\"command\":\"<shell>\"}. بدون نثر، بدون markdown، بدون مفاتيح إضافية. استخدم أمرًا آمنًا واحدًا. يجب أن يكون الأمر قابلاً للاستخدام مباشرة، وليس مثل `ls -a \"<path_to_directory_or_file>\"`.\nPrompt: {{.Prompt}}\nJSON:",
+		},
+		"generate_plan": {
+			"en":       "You are a {{.ShellType}} command planner for {{.OS}}. Every command must be valid {{.ShellType}} syntax. Break the task into the few shell commands that do it, in the order they must run; each runs only after the previous one succeeded. Output ONLY one JSON object with the exact schema: {\"steps\":[{\"command\":\"<shell>\",\"explanation\":\"...\"}]}. No prose, no markdown, no extra keys. Use one step when one command is enough and at most 12 steps. Every command must be directly usable, without placeholders like `<path>`; a cd does not carry over to the next step, so repeat the directory or use paths.\nTask: {{.Prompt}}\nJSON:",
+			"zh-TW":    "你是 {{.OS}} ({{.ShellType}}) 的指令規劃器。將任務拆成完成它所需的少數幾個 Shell 指令，依必須執行的順序排列；每一步只在前一步成功後執行。僅輸出一個 JSON 物件，結構嚴格為：{\"steps\":[{\"command\":\"<shell>\",\"explanation\":\"...\"}]}。不要輸出說明文字、Markdown 或多餘鍵。一個指令足夠時只用一步，最多 12 步。每個指令都需可直接使用，不可含 `<path>` 之類的佔位符；cd 不會延續到下一步，請重複目錄或使用路徑。\n任務：{{.Prompt}}\nJSON：",
+			"zh-CN":    "你是 {{.OS}} ({{.ShellType}}) 的命令规划器。将任务拆成完成它所需的少数几个 Shell 命令，按必须执行的顺序排列；每一步只在前一步成功后执行。只输出一个 JSON 对象，结构严格为：{\"steps\":[{\"command\":\"<shell>\",\"explanation\":\"...\"}]}。不要输出说明文字、Markdown 或多余键。一个命令足够时只用一步，最多 12 步。每个命令都需可直接使用，不可含 `<path>` 之类的占位符；cd 不会延续到下一步，请重复目录或使用路径。\n任务：{{.Prompt}}\nJSON：",
+			"japanese": "あなたは {{.OS}} ({{.ShellType}}) のシェルコマンドプランナーです。タスクを、実行すべき順序に並べた少数のシェルコマンドに分割してください。各ステップは前のステップが成功した後にのみ実行されます。正確なスキーマ {\"steps\":[{\"command\":\"<shell>\",\"explanation\":\"...\"}]} の JSON オブジェクトを一つだけ出力してください。散文、Markdown、余分なキーは含めないでください。一つのコマンドで足りる場合は一ステップ、最大 12 ステップです。各コマンドは `<path>` のようなプレースホルダーなしで直接使用できる必要があります。cd は次のステップに引き継がれないため、ディレクトリを繰り返すかパスを使ってください。\nタスク：{{.Prompt}}\nJSON：",
 		},
 		"get_suggestion": {
 			"en":         "You are a shell debugging assistant on macOS. Output ONLY one JSON object with schema: {\"explanation\":\"...\",\"command\":\"<shell>\"}. Do not include markdown or extra keys.\nCommand: {{.Command}}\nExit Code: {{.ExitCode}}\nStdout:\n{{.Stdout}}\nStderr:\n{{.Stderr}}\nJSON:",
@@ -75,16 +100,56 @@ func NewDefaultManager() *Manager {
 	return &Manager{prompts: defaultPrompts}
 }
 
-// GetPrompt returns a prompt by key.
+// SetFallbackChain sets the languages (preferences such as "zh-TW" or "japanese", or template
+// keys) whose templates are used, in order, when the requested language has none. English is
+// always tried last. An empty chain restores DefaultFallbackChain.
+func (m *Manager) SetFallbackChain(chain []string) {
+	m.fallback = nil
+	for _, lang := range chain {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			m.fallback = append(m.fallback, templateKey(lang))
+		}
+	}
+}
+
+// GetPrompt returns a prompt by key in the template language lang, falling back through the
+// fallback chain when there is none. The fallback used is reported on stderr in debug mode.
 func (m *Manager) GetPrompt(key string, lang string) (string, error) {
-	if langPrompts, ok := m.prompts[key]; ok {
-		if prompt, ok := langPrompts[lang]; ok {
-			return prompt, nil
+	prompt, used, err := m.Resolve(key, lang)
+	if err == nil && used != lang && os.Getenv(config.EnvAISHDebug) != "" {
+		fmt.Fprintf(os.Stderr, "DEBUG aish prompt: no %q template in %s, using %s\n", key, lang, used)
+	}
+	return prompt, err
+}
+
+// Resolve returns the template for key in lang or, failing that, in the first language of
+// the fallback chain that has one, along with the language used.
+func (m *Manager) Resolve(key, lang string) (string, string, error) {
+	langPrompts, ok := m.prompts[key]
+	if !ok {
+		return "", "", fmt.Errorf("prompt with key '%s' not found", key)
+	}
+	chain := m.fallback
+	if chain == nil {
+		chain = DefaultFallbackChain(lang)
+	}
+	tried := []string{lang}
+	for _, candidate := range append(append([]string{lang}, chain...), "en") {
+		if prompt, ok := langPrompts[candidate]; ok {
+			return prompt, candidate, nil
 		}
-		// Fallback to English if the specified language is not found
-		if prompt, ok := langPrompts["en"]; ok {
-			return prompt, nil
+		if !contains(tried, candidate) {
+			tried = append(tried, candidate)
+		}
+	}
+	return "", "", fmt.Errorf("prompt with key '%s' has no template in %s", key, strings.Join(tried, ", "))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
 		}
 	}
-	return "", fmt.Errorf("prompt with key '%s' not found", key)
+	return false
 }
@@ -0,0 +1,244 @@
+// Package rpc implements 'aish rpc', a small JSON-RPC 2.0 server on stdio that lets editor
+// plugins keep one aish process (and its provider connection) alive instead of shelling out
+// for every request. Messages use LSP framing (a Content-Length header, a blank line and the
+// JSON body), so existing LSP client libraries can talk to it.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC and LSP error codes.
+const (
+	CodeParseError       = -32700
+	CodeInvalidRequest   = -32600
+	CodeMethodNotFound   = -32601
+	CodeInvalidParams    = -32602
+	CodeInternalError    = -32603
+	CodeRequestCancelled = -32800
+)
+
+// maxMessageSize bounds a single message so a broken client cannot make aish buffer forever.
+const maxMessageSize = 8 << 20
+
+// Error is a JSON-RPC error object. Handlers return it to choose the code; any other error
+// becomes an internal error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// InvalidParams returns an invalid-params error.
+func InvalidParams(format string, args ...any) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+// HandlerFunc handles one method. ctx is cancelled by $/cancelRequest or when the server stops.
+type HandlerFunc func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server dispatches framed JSON-RPC messages to handlers. Requests run concurrently, so a slow
+// analysis does not hold up a cancellation or a quick request behind it.
+type Server struct {
+	handlers map[string]HandlerFunc
+
+	writeMu sync.Mutex
+	w       io.Writer
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a server with no methods registered.
+func NewServer() *Server {
+	return &Server{handlers: map[string]HandlerFunc{}, inflight: map[string]context.CancelFunc{}}
+}
+
+// Handle registers h for method.
+func (s *Server) Handle(method string, h HandlerFunc) {
+	s.handlers[method] = h
+}
+
+// Methods returns the registered method names.
+func (s *Server) Methods() []string {
+	names := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Serve reads messages from r until EOF, an "exit" notification or ctx is done, writing
+// responses to w. It waits for running requests before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	// Deferred in this order so running requests are cancelled before waiting for them
+	defer s.wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.w = w
+
+	reader := bufio.NewReader(r)
+	for {
+		body, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.reply(nil, nil, &Error{Code: CodeParseError, Message: err.Error()})
+			continue
+		}
+		if msg.Method == "" {
+			if msg.ID != nil {
+				s.reply(msg.ID, nil, &Error{Code: CodeInvalidRequest, Message: "missing method"})
+			}
+			continue
+		}
+
+		switch msg.Method {
+		case "exit":
+			return nil
+		case "$/cancelRequest":
+			s.cancel(msg.Params)
+			continue
+		}
+
+		h, ok := s.handlers[msg.Method]
+		if !ok {
+			if msg.ID != nil {
+				s.reply(msg.ID, nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method})
+			}
+			continue
+		}
+		s.dispatch(ctx, msg, h)
+	}
+}
+
+func (s *Server) dispatch(ctx context.Context, msg message, h HandlerFunc) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	key := ""
+	if msg.ID != nil {
+		key = string(*msg.ID)
+		s.mu.Lock()
+		s.inflight[key] = cancel
+		s.mu.Unlock()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		result, err := h(reqCtx, msg.Params)
+		if msg.ID == nil {
+			return // notification: no response
+		}
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+
+		if err != nil {
+			var rpcErr *Error
+			switch {
+			case errors.As(err, &rpcErr):
+			case reqCtx.Err() != nil:
+				rpcErr = &Error{Code: CodeRequestCancelled, Message: "request cancelled"}
+			default:
+				rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+			}
+			s.reply(msg.ID, nil, rpcErr)
+			return
+		}
+		if result == nil {
+			result = struct{}{}
+		}
+		s.reply(msg.ID, result, nil)
+	}()
+}
+
+// cancel handles $/cancelRequest {"id": ...}.
+func (s *Server) cancel(params json.RawMessage) {
+	var p struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(params, &p) != nil || len(p.ID) == 0 {
+		return
+	}
+	s.mu.Lock()
+	cancel, ok := s.inflight[string(p.ID)]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result any, rpcErr *Error) {
+	msg := message{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		body, _ = json.Marshal(message{JSONRPC: "2.0", ID: msg.ID, Error: &Error{Code: CodeInternalError, Message: err.Error()}})
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = writeMessage(s.w, body)
+}
+
+// readMessage reads one Content-Length framed message.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	tp := textproto.NewReader(r)
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) || (errors.Is(err, io.ErrUnexpectedEOF) && len(header) == 0) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds the %d byte limit", length, maxMessageSize)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return body, nil
+}
+
+func writeMessage(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
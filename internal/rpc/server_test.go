@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+type fakeProvider struct {
+	block chan struct{} // GenerateCommand waits on it (or ctx) when set
+}
+
+func (f *fakeProvider) GetSuggestion(ctx context.Context, c llm.CapturedContext, lang string) (*llm.Suggestion, error) {
+	return &llm.Suggestion{Explanation: "typo", CorrectedCommand: strings.Replace(c.Command, "gti", "git", 1)}, nil
+}
+
+func (f *fakeProvider) GetEnhancedSuggestion(ctx context.Context, c llm.EnhancedCapturedContext, lang string) (*llm.Suggestion, error) {
+	return nil, nil
+}
+
+func (f *fakeProvider) GenerateCommand(ctx context.Context, prompt, lang string) (string, error) {
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return "ls -la # " + lang, nil
+}
+
+func (f *fakeProvider) GetSuggestionStream(ctx context.Context, c llm.CapturedContext, lang string, _ llm.StreamFunc) (*llm.Suggestion, error) {
+	return f.GetSuggestion(ctx, c, lang)
+}
+
+func (f *fakeProvider) GenerateCommandStream(ctx context.Context, prompt, lang string, _ llm.StreamFunc) (string, error) {
+	return f.GenerateCommand(ctx, prompt, lang)
+}
+
+func (f *fakeProvider) VerifyConnection(ctx context.Context) ([]string, error) { return nil, nil }
+
+// client drives a Server over in-memory pipes.
+type client struct {
+	in  *io.PipeWriter
+	out *bufio.Reader
+}
+
+func startServer(t *testing.T, provider llm.Provider, created *int32) *client {
+	t.Helper()
+	session := NewSession(SessionOptions{
+		Version:         "v1.0.0",
+		DefaultProvider: "fake",
+		Language:        "en",
+		NewProvider: func(name string) (llm.Provider, error) {
+			atomic.AddInt32(created, 1)
+			return provider, nil
+		},
+	})
+	srv := NewServer()
+	session.Register(srv)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		_ = srv.Serve(context.Background(), inR, outW)
+		_ = outW.Close()
+	}()
+	t.Cleanup(func() { _ = inW.Close() })
+	return &client{in: inW, out: bufio.NewReader(outR)}
+}
+
+func (c *client) send(t *testing.T, id any, method string, params any) {
+	t.Helper()
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != nil {
+		msg["id"] = id
+	}
+	body, _ := json.Marshal(msg)
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func (c *client) recv(t *testing.T) response {
+	t.Helper()
+	body, err := readMessage(c.out)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return r
+}
+
+func TestSessionMethods(t *testing.T) {
+	var created int32
+	c := startServer(t, &fakeProvider{}, &created)
+
+	c.send(t, 1, "initialize", map[string]string{"language": "zh-TW"})
+	if r := c.recv(t); r.Error != nil || !strings.Contains(string(r.Result), `"generateCommand"`) {
+		t.Fatalf("initialize: %s %+v", r.Result, r.Error)
+	}
+
+	c.send(t, 2, "generateCommand", map[string]string{"prompt": "list files"})
+	if r := c.recv(t); string(r.Result) != `{"command":"ls -la # zh-TW"}` {
+		t.Errorf("generateCommand: %s %+v", r.Result, r.Error)
+	}
+
+	c.send(t, 3, "analyze", map[string]any{"command": "gti status", "exitCode": 1})
+	if r := c.recv(t); string(r.Result) != `{"explanation":"typo","correctedCommand":"git status"}` {
+		t.Errorf("analyze: %s %+v", r.Result, r.Error)
+	}
+
+	c.send(t, 4, "generateCommand", map[string]string{})
+	if r := c.recv(t); r.Error == nil || r.Error.Code != CodeInvalidParams {
+		t.Errorf("empty prompt: %+v", r.Error)
+	}
+
+	c.send(t, 5, "nope", nil)
+	if r := c.recv(t); r.Error == nil || r.Error.Code != CodeMethodNotFound {
+		t.Errorf("unknown method: %+v", r.Error)
+	}
+
+	if n := atomic.LoadInt32(&created); n != 1 {
+		t.Errorf("provider should be created once per session, got %d", n)
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	var created int32
+	c := startServer(t, &fakeProvider{block: make(chan struct{})}, &created)
+
+	// The server registers a request before reading the next message, so the cancel cannot overtake it
+	c.send(t, "slow", "generateCommand", map[string]string{"prompt": "wait"})
+	c.send(t, nil, "$/cancelRequest", map[string]string{"id": "slow"})
+
+	r := c.recv(t)
+	if string(r.ID) != `"slow"` || r.Error == nil || r.Error.Code != CodeRequestCancelled {
+		t.Fatalf("expected a cancelled response for slow, got %s %+v", r.ID, r.Error)
+	}
+}
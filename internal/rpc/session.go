@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+// SessionOptions connects a Session to the rest of aish.
+type SessionOptions struct {
+	Version         string
+	DefaultProvider string
+	Language        string
+	// NewProvider creates a ready-to-use provider by name, or fails if it is not configured.
+	NewProvider func(name string) (llm.Provider, error)
+	// Answer turns GenerateCommand output for a question into plain text, as 'aish -a' does.
+	Answer func(generated string) string
+	// AcquireSlot waits for the machine-wide request cap and returns the release function.
+	AcquireSlot func(ctx context.Context) func()
+}
+
+// Session is the state kept for the lifetime of one 'aish rpc' process: the chosen provider
+// and language, and the provider instance itself, so its connection and credentials are
+// reused across requests.
+type Session struct {
+	opts SessionOptions
+
+	mu           sync.Mutex
+	providerName string
+	language     string
+	provider     llm.Provider
+}
+
+// NewSession returns a session using the configured default provider and language.
+func NewSession(opts SessionOptions) *Session {
+	return &Session{opts: opts, providerName: opts.DefaultProvider, language: opts.Language}
+}
+
+// Register adds the session's methods to srv.
+func (s *Session) Register(srv *Server) {
+	srv.Handle("initialize", func(ctx context.Context, params json.RawMessage) (any, error) {
+		if _, err := s.configure(params); err != nil {
+			return nil, err
+		}
+		methods := srv.Methods()
+		sort.Strings(methods)
+		return map[string]any{
+			"serverInfo": map[string]string{"name": "aish", "version": s.opts.Version},
+			"methods":    methods,
+		}, nil
+	})
+	srv.Handle("shutdown", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	srv.Handle("session/configure", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return s.configure(params)
+	})
+	srv.Handle("generateCommand", s.generateCommand)
+	srv.Handle("explain", s.explain)
+	srv.Handle("analyze", s.analyze)
+}
+
+type sessionState struct {
+	Provider string `json:"provider"`
+	Language string `json:"language"`
+}
+
+// configure applies {"provider", "language"} (both optional) and returns the resulting state.
+func (s *Session) configure(params json.RawMessage) (sessionState, error) {
+	var p sessionState
+	if len(params) > 0 && string(params) != "null" {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return sessionState{}, InvalidParams("%v", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name := strings.TrimSpace(p.Provider); name != "" && name != s.providerName {
+		s.providerName = name
+		s.provider = nil
+	}
+	if lang := strings.TrimSpace(p.Language); lang != "" {
+		s.language = lang
+	}
+	return sessionState{Provider: s.providerName, Language: s.language}, nil
+}
+
+// current returns the session provider, creating it on first use, and the language.
+func (s *Session) current() (llm.Provider, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider == nil {
+		p, err := s.opts.NewProvider(s.providerName)
+		if err != nil {
+			return nil, "", &Error{Code: CodeInternalError, Message: "provider " + s.providerName + ": " + err.Error()}
+		}
+		s.provider = p
+	}
+	return s.provider, s.language, nil
+}
+
+func (s *Session) acquire(ctx context.Context) func() {
+	if s.opts.AcquireSlot == nil {
+		return func() {}
+	}
+	return s.opts.AcquireSlot(ctx)
+}
+
+func (s *Session) generateCommand(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || strings.TrimSpace(p.Prompt) == "" {
+		return nil, InvalidParams("generateCommand needs a non-empty prompt")
+	}
+	provider, lang, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	release := s.acquire(ctx)
+	cmd, err := provider.GenerateCommand(ctx, p.Prompt, lang)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"command": strings.TrimSpace(cmd)}, nil
+}
+
+// explain answers a question, or explains a command when given {"command": ...}.
+func (s *Session) explain(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Question string `json:"question"`
+		Command  string `json:"command"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, InvalidParams("%v", err)
+	}
+	question := strings.TrimSpace(p.Question)
+	if question == "" && strings.TrimSpace(p.Command) != "" {
+		question = "Explain what this shell command does: " + p.Command
+	}
+	if question == "" {
+		return nil, InvalidParams("explain needs a question or a command")
+	}
+
+	provider, lang, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	release := s.acquire(ctx)
+	generated, err := provider.GenerateCommand(ctx, question, lang)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	answer := strings.TrimSpace(generated)
+	if s.opts.Answer != nil {
+		answer = s.opts.Answer(generated)
+	}
+	return map[string]string{"answer": answer}, nil
+}
+
+// analyze explains a failed command and suggests a fix, like the shell hook does.
+func (s *Session) analyze(ctx context.Context, params json.RawMessage) (any, error) {
+	var p llm.CapturedContext
+	if err := json.Unmarshal(params, &p); err != nil || strings.TrimSpace(p.Command) == "" {
+		return nil, InvalidParams("analyze needs at least a command")
+	}
+	provider, lang, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	release := s.acquire(ctx)
+	suggestion, err := provider.GetSuggestion(ctx, p, lang)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	if suggestion == nil {
+		return nil, errors.New("provider returned no suggestion")
+	}
+	return suggestion, nil
+}
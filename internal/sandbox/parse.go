@@ -0,0 +1,342 @@
+package sandbox
+
+import (
+	"os"
+	"strings"
+)
+
+// word is one shell word after quote removal. raw keeps it as written, for messages.
+type word struct {
+	text    string
+	raw     string
+	glob    bool // Holds an unquoted *, ? or [
+	dynamic bool // Depends on a variable, command substitution or process substitution
+}
+
+// redirect is an output redirection: op is >, >>, >|, &> or &>>.
+type redirect struct {
+	op     string
+	target word
+}
+
+// simpleCommand is one command of a pipeline or list, e.g. each side of "a && b | c".
+type simpleCommand struct {
+	words     []word
+	redirects []redirect
+}
+
+// parse splits command into simple commands, handling the quoting, escapes and operators
+// of POSIX shells well enough to find the words and redirections of each. Control structures
+// (if, for, functions) are not understood; their keywords show up as ordinary commands.
+func parse(command string) []simpleCommand {
+	p := &parser{src: command}
+	p.run()
+	p.endCommand()
+	return p.commands
+}
+
+type parser struct {
+	src      string
+	pos      int
+	commands []simpleCommand
+	cur      simpleCommand
+	word     *word
+	// pendingOp is a redirection operator waiting for its target word
+	pendingOp string
+	// input is the < operator (<, << or <<<) whose word, a file read or a delimiter, comes next
+	input string
+	// delims are the here-document delimiters whose bodies start at the next newline
+	delims []string
+}
+
+func (p *parser) startWord() *word {
+	if p.word == nil {
+		p.word = &word{}
+	}
+	return p.word
+}
+
+func (p *parser) endWord() {
+	w := p.word
+	if w == nil {
+		return
+	}
+	p.word = nil
+	switch {
+	case p.input != "":
+		if p.input == "<<" {
+			p.delims = append(p.delims, w.text)
+		}
+		p.input = ""
+	case p.pendingOp != "":
+		p.cur.redirects = append(p.cur.redirects, redirect{op: p.pendingOp, target: *w})
+		p.pendingOp = ""
+	default:
+		p.cur.words = append(p.cur.words, *w)
+	}
+}
+
+func (p *parser) endCommand() {
+	p.endWord()
+	p.pendingOp, p.input = "", ""
+	if len(p.cur.words) > 0 || len(p.cur.redirects) > 0 {
+		p.commands = append(p.commands, p.cur)
+	}
+	p.cur = simpleCommand{}
+}
+
+// add appends c to the current word, as written and (when it reaches the word) as text.
+func (p *parser) add(c byte) {
+	w := p.startWord()
+	w.text += string(c)
+	w.raw += string(c)
+}
+
+func (p *parser) run() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\':
+			w := p.startWord()
+			if p.pos+1 < len(p.src) {
+				if p.src[p.pos+1] != '\n' {
+					w.text += string(p.src[p.pos+1])
+				}
+				w.raw += p.src[p.pos : p.pos+2]
+				p.pos++
+			}
+		case c == '\'':
+			end := strings.IndexByte(p.src[p.pos+1:], '\'')
+			if end < 0 {
+				end = len(p.src) - p.pos - 1
+			}
+			w := p.startWord()
+			w.text += p.src[p.pos+1 : p.pos+1+end]
+			w.raw += p.src[p.pos:min(len(p.src), p.pos+end+2)]
+			p.pos += end + 1
+		case c == '"':
+			p.doubleQuoted()
+		case c == '$' || c == '`':
+			p.dollar()
+		case c == '~' && p.word == nil:
+			w := p.startWord()
+			w.raw += "~"
+			if p.pos+1 == len(p.src) || strings.IndexByte("/ \t\n;&|)", p.src[p.pos+1]) >= 0 {
+				if home, err := os.UserHomeDir(); err == nil {
+					w.text += home
+					break
+				}
+			}
+			w.text += "~"
+		case c == '*' || c == '?' || c == '[':
+			p.startWord().glob = true
+			p.add(c)
+		case c == ' ' || c == '\t':
+			p.endWord()
+		case c == '\n' || c == ';' || c == '(' || c == ')' || c == '{' && p.word == nil || c == '}' && p.word == nil:
+			p.endCommand()
+			if c == '\n' {
+				p.skipHeredocs()
+			}
+		case c == '#' && p.word == nil:
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		case c == '|' || c == '&':
+			if c == '&' && p.peek(1) == '>' {
+				p.endWord()
+				p.redirection("&")
+				continue
+			}
+			p.endCommand()
+			if p.peek(1) == c {
+				p.pos++
+			}
+		case c == '>' || c == '<':
+			// A number right before the operator is the file descriptor, not an argument
+			if w := p.word; w != nil && w.raw != "" && strings.Trim(w.raw, "0123456789") == "" {
+				p.word = nil
+			}
+			p.endWord()
+			p.redirection("")
+			continue
+		default:
+			p.add(c)
+		}
+		p.pos++
+	}
+}
+
+// skipHeredocs moves pos, on a newline, past the bodies of the pending here-documents, so
+// their lines are not read as commands.
+func (p *parser) skipHeredocs() {
+	for _, delim := range p.delims {
+		for p.pos < len(p.src) {
+			start := p.pos + 1
+			end := strings.IndexByte(p.src[min(start, len(p.src)):], '\n')
+			if end < 0 {
+				end = len(p.src)
+			} else {
+				end += start
+			}
+			p.pos = end
+			if strings.TrimLeft(p.src[min(start, len(p.src)):end], "\t") == delim {
+				break
+			}
+		}
+	}
+	p.delims = nil
+}
+
+func (p *parser) peek(n int) byte {
+	if p.pos+n < len(p.src) {
+		return p.src[p.pos+n]
+	}
+	return 0
+}
+
+// redirection reads a redirection operator at pos (after the & of &> when prefix is "&").
+func (p *parser) redirection(prefix string) {
+	p.pos += len(prefix)
+	c := p.src[p.pos]
+	p.pos++
+	if p.peek(0) == '(' {
+		// Process substitution: its own commands, not a file
+		n := expansionEnd("$"+p.src[p.pos:]) - 1
+		w := p.startWord()
+		w.dynamic = true
+		w.raw += string(c) + p.src[p.pos:p.pos+n]
+		w.text = w.raw
+		p.pos += n
+		return
+	}
+	if c == '<' {
+		// The word after < is only read, after <<< it is data, after << it ends a here-document
+		p.input = "<"
+		for p.peek(0) == '<' {
+			p.input += "<"
+			p.pos++
+		}
+		for p.peek(0) == '-' || p.peek(0) == '&' {
+			p.pos++
+		}
+		return
+	}
+	op := prefix + ">"
+	switch p.peek(0) {
+	case '>':
+		op += ">"
+		p.pos++
+	case '|':
+		p.pos++
+	case '&':
+		// >&2 duplicates a descriptor; >&file is bash for &>file
+		p.pos++
+		if d := p.peek(0); d >= '0' && d <= '9' || d == '-' {
+			for d := p.peek(0); d >= '0' && d <= '9' || d == '-'; d = p.peek(0) {
+				p.pos++
+			}
+			return
+		}
+		op = "&>"
+	}
+	p.pendingOp = op
+}
+
+// doubleQuoted reads a "..." string at pos: no globbing, but $ and ` still expand.
+func (p *parser) doubleQuoted() {
+	w := p.startWord()
+	w.raw += `"`
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] != '"' {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src) && strings.IndexByte("$`\"\\\n", p.src[p.pos+1]) >= 0:
+			w.text += string(p.src[p.pos+1])
+			w.raw += p.src[p.pos : p.pos+2]
+			p.pos += 2
+		case c == '$' || c == '`':
+			p.dollar()
+			p.pos++
+		default:
+			w.text += string(c)
+			w.raw += string(c)
+			p.pos++
+		}
+	}
+	w.raw += `"`
+}
+
+// dollar reads an expansion at pos, leaving pos on its last byte. $HOME and ${HOME} are known;
+// anything else makes the word dynamic. A lone $ is literal.
+func (p *parser) dollar() {
+	w := p.startWord()
+	rest := p.src[p.pos:]
+	for _, home := range []string{"${HOME}", "$HOME"} {
+		if strings.HasPrefix(rest, home) && (home[1] == '{' || len(rest) == len(home) || !isNameByte(rest[len(home)])) {
+			if dir, err := os.UserHomeDir(); err == nil {
+				w.text += dir
+				w.raw += home
+				p.pos += len(home) - 1
+				return
+			}
+		}
+	}
+	end := expansionEnd(rest)
+	if end == 1 {
+		w.text += "$"
+		w.raw += "$"
+		return
+	}
+	w.dynamic = true
+	w.text += rest[:end]
+	w.raw += rest[:end]
+	p.pos += end - 1
+}
+
+// expansionEnd returns the length of the expansion s starts with: a name, ${...}, $(...) or
+// `...`, matching nested brackets. It returns 1 for a $ that starts none of them.
+func expansionEnd(s string) int {
+	if s[0] == '`' {
+		if i := strings.IndexByte(s[1:], '`'); i >= 0 {
+			return i + 2
+		}
+		return len(s)
+	}
+	if len(s) < 2 {
+		return 1
+	}
+	switch open := s[1]; open {
+	case '(', '{':
+		close := byte(')')
+		if open == '{' {
+			close = '}'
+		}
+		depth := 0
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case open:
+				depth++
+			case close:
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(s)
+	}
+	i := 1
+	if strings.IndexByte("@*#?$!-0123456789", s[1]) >= 0 {
+		return 2
+	}
+	for i < len(s) && isNameByte(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,535 @@
+// Package sandbox works out what a suggested shell command would do to the file system without
+// running it: which paths an rm, mv, cp or redirection touches once its globs are expanded, so
+// the user can see them before confirming. It reads the file system but never changes it.
+//
+// The analysis is static and deliberately modest. Commands whose targets depend on variables,
+// command substitution or a search (find -delete, xargs rm) are reported as notes instead of
+// guessed at.
+package sandbox
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Action is what a command does to a path.
+type Action string
+
+const (
+	Delete    Action = "delete"
+	Move      Action = "move"
+	Copy      Action = "copy"
+	Overwrite Action = "overwrite"
+	Create    Action = "create"
+	Modify    Action = "modify"
+)
+
+// Effect is one change a command would make to one path.
+type Effect struct {
+	Action Action
+	Path   string // As written in the command, or the match of a glob
+	Dest   string // Where Move and Copy put Path
+	Detail string // E.g. "append" for >>, "mode 755" for chmod
+	Exists bool   // Path exists now
+	Dir    bool   // Path is a directory
+	Files  int    // Files under a directory that a recursive Delete, Copy or Modify reaches
+	// Replaces is set when Dest (or Path for Create and Overwrite) already exists and is lost
+	Replaces bool
+}
+
+// maxCountedFiles stops counting the files under a directory; a preview does not need more.
+const maxCountedFiles = 10000
+
+func (e Effect) String() string {
+	var b strings.Builder
+	b.WriteString(string(e.Action))
+	b.WriteString(" ")
+	b.WriteString(e.Path)
+	if e.Dest != "" {
+		b.WriteString(" → ")
+		b.WriteString(e.Dest)
+	}
+	var notes []string
+	if e.Detail != "" {
+		notes = append(notes, e.Detail)
+	}
+	switch {
+	case !e.Exists && e.Action != Create:
+		notes = append(notes, "does not exist")
+	case e.Dir && e.Files >= maxCountedFiles:
+		notes = append(notes, fmt.Sprintf("directory, %d+ files", maxCountedFiles))
+	case e.Dir && e.Files > 0:
+		notes = append(notes, fmt.Sprintf("directory, %d files", e.Files))
+	case e.Dir:
+		notes = append(notes, "directory")
+	}
+	if e.Replaces {
+		notes = append(notes, "replaces an existing file")
+	}
+	if len(notes) > 0 {
+		b.WriteString(" (")
+		b.WriteString(strings.Join(notes, ", "))
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// Preview is what Analyze found out about a command.
+type Preview struct {
+	Effects []Effect
+	Notes   []string // Changes it could not work out, e.g. targets named by $(...)
+}
+
+// Empty reports whether the command changes no files that Analyze knows of.
+func (p Preview) Empty() bool {
+	return len(p.Effects) == 0 && len(p.Notes) == 0
+}
+
+// Analyze previews the file changes of command as run in dir. A cd in the command moves the
+// directory later parts are resolved against.
+func Analyze(command, dir string) Preview {
+	a := &analyzer{dir: dir}
+	for _, c := range parse(command) {
+		a.simple(c)
+	}
+	return a.preview
+}
+
+type analyzer struct {
+	dir     string
+	preview Preview
+}
+
+func (a *analyzer) note(format string, args ...any) {
+	a.preview.Notes = append(a.preview.Notes, fmt.Sprintf(format, args...))
+}
+
+func (a *analyzer) add(e Effect) {
+	a.preview.Effects = append(a.preview.Effects, e)
+}
+
+// abs resolves path against the current directory.
+func (a *analyzer) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(a.dir, path)
+}
+
+// stat describes path, counting the files below it when it is a directory and count is set.
+func (a *analyzer) stat(path string, count bool) (exists, dir bool, files int) {
+	info, err := os.Lstat(a.abs(path))
+	if err != nil {
+		return false, false, 0
+	}
+	if !info.IsDir() {
+		return true, false, 0
+	}
+	if count {
+		_ = filepath.WalkDir(a.abs(path), func(_ string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				files++
+			}
+			if files >= maxCountedFiles {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+	}
+	return true, true, files
+}
+
+// paths expands words into the paths they name: globs become their matches, or stay as
+// written when nothing matches, as in bash. ok is false when a word cannot be known statically.
+func (a *analyzer) paths(name string, words []word) ([]string, bool) {
+	var out []string
+	for _, w := range words {
+		if w.dynamic {
+			a.note("%s: cannot tell which files %s names", name, w.raw)
+			return nil, false
+		}
+		if !w.glob {
+			out = append(out, w.text)
+			continue
+		}
+		matches, _ := filepath.Glob(a.abs(w.text))
+		var kept []string
+		for _, m := range matches {
+			// Like bash, * and ? do not match a leading dot
+			if strings.HasPrefix(filepath.Base(m), ".") && !strings.HasPrefix(filepath.Base(w.text), ".") {
+				continue
+			}
+			if !filepath.IsAbs(w.text) {
+				if rel, err := filepath.Rel(a.dir, m); err == nil {
+					m = rel
+				}
+			}
+			kept = append(kept, m)
+		}
+		if len(kept) == 0 {
+			out = append(out, w.text)
+		}
+		out = append(out, kept...)
+	}
+	return out, true
+}
+
+// mutating are the commands whose file changes Analyze previews.
+var mutating = map[string]bool{
+	"rm": true, "unlink": true, "rmdir": true, "mv": true, "cp": true, "ln": true, "mkdir": true, "touch": true,
+	"chmod": true, "chown": true, "chgrp": true, "truncate": true, "shred": true, "tee": true, "sed": true, "perl": true, "dd": true,
+}
+
+// wrappers run the command that follows them, after their own options.
+var wrappers = map[string]bool{"sudo": true, "doas": true, "env": true, "nohup": true, "time": true, "command": true, "exec": true, "nice": true}
+
+// simple previews one simple command: its redirections, then the command itself.
+func (a *analyzer) simple(c simpleCommand) {
+	for _, r := range c.redirects {
+		a.redirect(r)
+	}
+	words := unwrap(c.words)
+	if len(words) == 0 {
+		return
+	}
+	name := filepath.Base(words[0].text)
+	args := words[1:]
+	switch name {
+	case "cd":
+		if len(args) == 0 {
+			if home, err := os.UserHomeDir(); err == nil {
+				a.dir = home
+			}
+		} else if !args[0].dynamic && args[0].text != "-" {
+			a.dir = a.abs(args[0].text)
+		}
+	case "rm", "unlink":
+		a.remove(name, args)
+	case "rmdir":
+		a.apply(name, args, Delete, "", false)
+	case "mv":
+		a.transfer(name, args, Move)
+	case "cp":
+		a.transfer(name, args, Copy)
+	case "ln":
+		a.transfer(name, args, Create)
+	case "mkdir":
+		a.apply(name, args, Create, "directory", false)
+	case "touch":
+		a.apply(name, args, Create, "", false)
+	case "chmod", "chown", "chgrp":
+		a.chmod(name, args)
+	case "truncate":
+		a.truncate(name, args)
+	case "shred":
+		a.apply(name, args, Overwrite, "with random data", false)
+	case "tee":
+		detail := ""
+		if hasFlag(args, 'a', "--append") {
+			detail = "append"
+		}
+		a.apply(name, args, Overwrite, detail, false)
+	case "sed", "perl":
+		a.inPlace(name, args)
+	case "find":
+		for i, w := range args {
+			if w.text == "-delete" || (w.text == "-exec" || w.text == "-execdir" || w.text == "-ok") && i+1 < len(args) && mutating[filepath.Base(args[i+1].text)] {
+				a.note("find %s: the files it changes depend on the search; run the find without it first to list them", w.text)
+				break
+			}
+		}
+	case "xargs", "parallel":
+		for _, w := range args {
+			if mutating[filepath.Base(w.text)] {
+				a.note("%s %s: the files it changes depend on its input", name, w.text)
+				break
+			}
+		}
+	case "dd":
+		for _, w := range args {
+			if path, ok := strings.CutPrefix(w.text, "of="); ok {
+				a.apply(name, []word{{text: path, raw: path}}, Overwrite, "", false)
+			}
+		}
+	}
+}
+
+// unwrap drops the variable assignments and wrappers such as sudo, with their options, in
+// front of the command that actually runs.
+func unwrap(words []word) []word {
+	for len(words) > 0 {
+		w := words[0]
+		switch {
+		case isAssignment(w.text):
+			words = words[1:]
+		case wrappers[w.text]:
+			words = words[1:]
+			for len(words) > 0 && (strings.HasPrefix(words[0].text, "-") || w.text == "env" && isAssignment(words[0].text)) {
+				if takesValue(w.text, words[0].text) && len(words) > 1 {
+					words = words[1:]
+				}
+				words = words[1:]
+			}
+		default:
+			return words
+		}
+	}
+	return nil
+}
+
+// isAssignment reports whether text is a NAME=value prefix of a command.
+func isAssignment(text string) bool {
+	name, _, ok := strings.Cut(text, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// takesValue reports whether option of a wrapper command is followed by its own argument.
+func takesValue(wrapper, option string) bool {
+	switch wrapper {
+	case "sudo":
+		return option == "-u" || option == "-g" || option == "-C" || option == "-D"
+	case "nice":
+		return option == "-n"
+	case "env":
+		return option == "-u" || option == "-C"
+	}
+	return false
+}
+
+// operands splits args into options and operands; everything after "--" is an operand.
+func operands(args []word) (options []string, rest []word) {
+	for i, w := range args {
+		if w.text == "--" {
+			return options, append(rest, args[i+1:]...)
+		}
+		if strings.HasPrefix(w.text, "-") && len(w.text) > 1 && !w.dynamic {
+			options = append(options, w.text)
+			continue
+		}
+		rest = append(rest, w)
+	}
+	return options, rest
+}
+
+// hasFlag reports whether args hold the short flag (alone or combined, as in -rf) or long.
+func hasFlag(args []word, short byte, long string) bool {
+	options, _ := operands(args)
+	for _, o := range options {
+		if o == long || !strings.HasPrefix(o, "--") && strings.IndexByte(o[1:], short) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *analyzer) remove(name string, args []word) {
+	recursive := hasFlag(args, 'r', "--recursive") || hasFlag(args, 'R', "--recursive")
+	_, targets := operands(args)
+	paths, ok := a.paths(name, targets)
+	if !ok {
+		return
+	}
+	for _, p := range paths {
+		exists, dir, files := a.stat(p, recursive)
+		if dir && !recursive {
+			a.note("%s: %s is a directory and is kept without -r", name, p)
+			continue
+		}
+		a.add(Effect{Action: Delete, Path: p, Exists: exists, Dir: dir, Files: files})
+	}
+}
+
+// apply records action on every operand of a command that changes its operands in place.
+func (a *analyzer) apply(name string, args []word, action Action, detail string, count bool) {
+	_, targets := operands(args)
+	paths, ok := a.paths(name, targets)
+	if !ok {
+		return
+	}
+	for _, p := range paths {
+		exists, dir, files := a.stat(p, count)
+		e := Effect{Action: action, Path: p, Detail: detail, Exists: exists, Dir: dir, Files: files}
+		switch action {
+		case Create:
+			if exists {
+				if name != "touch" {
+					continue // mkdir -p, ln onto a directory: nothing new
+				}
+				e.Action, e.Detail = Modify, "timestamp"
+			}
+		case Overwrite:
+			if detail == "append" {
+				e.Action, e.Detail = Modify, detail
+			}
+			if !exists {
+				e.Action, e.Detail = Create, ""
+			}
+		}
+		a.add(e)
+	}
+}
+
+// transfer previews mv, cp and ln: every source goes to the target, or into it when the
+// target is a directory, there are several sources or -t names it.
+func (a *analyzer) transfer(name string, args []word, action Action) {
+	options, targets := operands(args)
+	var dest string
+	intoDir := false
+	for _, o := range options {
+		if v, ok := strings.CutPrefix(o, "--target-directory="); ok {
+			dest, intoDir = v, true
+		} else if o == "-t" {
+			// The directory is the operand that followed -t
+			if len(targets) > 0 {
+				dest, intoDir = targets[0].text, true
+				targets = targets[1:]
+			}
+		}
+	}
+	if dest == "" {
+		if len(targets) < 2 {
+			return
+		}
+		last := targets[len(targets)-1]
+		if last.dynamic {
+			a.note("%s: cannot tell where %s points", name, last.raw)
+			return
+		}
+		dest, targets = last.text, targets[:len(targets)-1]
+	}
+	sources, ok := a.paths(name, targets)
+	if !ok {
+		return
+	}
+	if _, dir, _ := a.stat(dest, false); dir && !hasFlag(args, 'T', "--no-target-directory") || len(sources) > 1 {
+		intoDir = true
+	}
+	recursive := action == Copy && (hasFlag(args, 'r', "--recursive") || hasFlag(args, 'R', "--recursive") || hasFlag(args, 'a', "--archive"))
+	for _, src := range sources {
+		target := dest
+		if intoDir {
+			target = filepath.Join(dest, filepath.Base(src))
+		}
+		exists, dir, files := a.stat(src, recursive)
+		replaced, _, _ := a.stat(target, false)
+		e := Effect{Action: action, Path: src, Dest: target, Exists: exists, Dir: dir, Files: files, Replaces: replaced}
+		if action == Create {
+			// ln: the link is what gets created, pointing at the source
+			e = Effect{Action: Create, Path: target, Detail: "link to " + src, Replaces: replaced}
+			if replaced && !hasFlag(args, 'f', "--force") {
+				a.note("ln: %s exists and is kept without -f", target)
+				continue
+			}
+		}
+		a.add(e)
+	}
+}
+
+func (a *analyzer) chmod(name string, args []word) {
+	recursive := hasFlag(args, 'R', "--recursive")
+	options, targets := operands(args)
+	detail := ""
+	reference := false
+	for _, o := range options {
+		if strings.HasPrefix(o, "--reference=") {
+			reference = true
+		}
+		// chmod -w is a mode, not an option
+		if name == "chmod" && len(o) > 1 && strings.Trim(o[1:], "rwxXst") == "" {
+			targets = append([]word{{text: o, raw: o}}, targets...)
+		}
+	}
+	if !reference {
+		if len(targets) == 0 {
+			return
+		}
+		switch name {
+		case "chmod":
+			detail = "mode " + targets[0].text
+		case "chown":
+			detail = "owner " + targets[0].text
+		case "chgrp":
+			detail = "group " + targets[0].text
+		}
+		targets = targets[1:]
+	}
+	a.apply(name, targets, Modify, detail, recursive)
+}
+
+func (a *analyzer) truncate(name string, args []word) {
+	var files []word
+	for i := 0; i < len(args); i++ {
+		t := args[i].text
+		switch {
+		case t == "-s" || t == "-r" || t == "-o":
+			i++ // The size or reference file
+		case strings.HasPrefix(t, "-"):
+		default:
+			files = append(files, args[i])
+		}
+	}
+	a.apply(name, files, Overwrite, "truncate", false)
+}
+
+// inPlace previews sed -i and perl -i, which rewrite the files they are given.
+func (a *analyzer) inPlace(name string, args []word) {
+	inPlace := false
+	script := true // The first operand is the script unless -e or -f gives it
+	var files []word
+	for i := 0; i < len(args); i++ {
+		t := args[i].text
+		switch {
+		case strings.HasPrefix(t, "-i") || strings.HasPrefix(t, "--in-place") || name == "perl" && strings.HasPrefix(t, "-p") && strings.Contains(t, "i"):
+			inPlace = true
+		case t == "-e" || t == "-f" || t == "--expression" || t == "--file":
+			script = false
+			i++
+		case strings.HasPrefix(t, "-") && len(t) > 1:
+			if name == "perl" && strings.HasSuffix(t, "e") {
+				script = false
+				i++
+			}
+		case script:
+			script = false
+		default:
+			files = append(files, args[i])
+		}
+	}
+	if inPlace {
+		a.apply(name, files, Modify, "edit in place", false)
+	}
+}
+
+// devices are redirection targets that are not files.
+var devices = map[string]bool{"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true}
+
+func (a *analyzer) redirect(r redirect) {
+	if r.target.dynamic {
+		a.note("%s %s: cannot tell which file it writes", r.op, r.target.raw)
+		return
+	}
+	path := r.target.text
+	if devices[path] || strings.HasPrefix(path, "/dev/fd/") {
+		return
+	}
+	exists, dir, _ := a.stat(path, false)
+	e := Effect{Action: Overwrite, Path: path, Exists: exists, Dir: dir}
+	switch {
+	case !exists:
+		e.Action = Create
+	case r.op == ">>" || r.op == "&>>":
+		e.Action, e.Detail = Modify, "append"
+	}
+	a.add(e)
+}
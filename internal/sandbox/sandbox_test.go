@@ -0,0 +1,79 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tree creates files (and their directories) under a temporary directory.
+func tree(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func effects(p Preview) []string {
+	var out []string
+	for _, e := range p.Effects {
+		out = append(out, e.String())
+	}
+	return out
+}
+
+func TestAnalyze(t *testing.T) {
+	dir := tree(t, "a.log", "b.log", ".hidden.log", "keep.txt", "build/x.o", "build/sub/y.o", "docs/readme.md")
+	for _, c := range []struct {
+		command string
+		want    []string
+	}{
+		{"rm *.log", []string{"delete a.log", "delete b.log"}},
+		{"sudo rm -rf build 'no such'", []string{"delete build (directory, 2 files)", "delete no such (does not exist)"}},
+		{"mv keep.txt docs", []string{"move keep.txt → docs/keep.txt"}},
+		{"cp a.log b.log", []string{"copy a.log → b.log (replaces an existing file)"}},
+		{"mv -t docs a.log b.log", []string{"move a.log → docs/a.log", "move b.log → docs/b.log"}},
+		{`grep error *.log > keep.txt 2>/dev/null`, []string{"overwrite keep.txt"}},
+		{"echo done >> new.txt && chmod 600 keep.txt", []string{"create new.txt", "modify keep.txt (mode 600)"}},
+		{"cd build && rm *.o sub/*.o", []string{"delete x.o", "delete sub/y.o"}},
+		{"sed -i 's/a/b/' keep.txt docs/readme.md", []string{"modify keep.txt (edit in place)", "modify docs/readme.md (edit in place)"}},
+		{"ls -la | grep log; echo \"rm -rf build\"", nil},
+		{"cat <<EOF > out.txt\nrm -rf build\nEOF", []string{"create out.txt"}},
+		{`rm \*.log`, []string{"delete *.log (does not exist)"}},
+	} {
+		got := effects(Analyze(c.command, dir))
+		if strings.Join(got, "\n") != strings.Join(c.want, "\n") {
+			t.Errorf("Analyze(%q) = %q, want %q", c.command, got, c.want)
+		}
+	}
+}
+
+func TestAnalyzeNotes(t *testing.T) {
+	dir := tree(t, "build/x.o")
+	for command, want := range map[string]string{
+		"rm -rf $(cat list.txt)":             "rm: cannot tell which files $(cat list.txt) names",
+		`rm "$TARGET"/*`:                     `rm: cannot tell which files "$TARGET"/* names`,
+		"find . -name '*.o' -delete":         "find -delete: the files it changes depend on the search; run the find without it first to list them",
+		"git ls-files -z | xargs -0 rm":      "xargs rm: the files it changes depend on its input",
+		"rm build":                           "rm: build is a directory and is kept without -r",
+		"echo hi > \"$LOG\"":                 `> "$LOG": cannot tell which file it writes`,
+		"find . -name '*.o' -exec rm {} \\;": "find -exec: the files it changes depend on the search; run the find without it first to list them",
+	} {
+		p := Analyze(command, dir)
+		if len(p.Notes) != 1 || p.Notes[0] != want || len(p.Effects) != 0 {
+			t.Errorf("Analyze(%q) = %q, %q; want note %q", command, effects(p), p.Notes, want)
+		}
+	}
+	if p := Analyze("find . -name '*.go' -exec grep -l TODO {} +", dir); !p.Empty() {
+		t.Errorf("a read-only find should preview nothing, got %q %q", effects(p), p.Notes)
+	}
+}
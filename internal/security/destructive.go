@@ -0,0 +1,67 @@
+package security
+
+import "regexp"
+
+// destructivePattern recognises one kind of command whose effect is hard to undo.
+type destructivePattern struct {
+	re     *regexp.Regexp
+	reason string
+}
+
+var destructivePatterns = []destructivePattern{
+	{regexp.MustCompile(`(^|[\s;&|(])rm\s+(.*\s)?(-[a-zA-Z]*[rRf]|--recursive|--force)`), "deletes files recursively or without confirmation"},
+	{regexp.MustCompile(`(^|[\s;&|(])(mkfs(\.\w+)?|wipefs|shred)\s`), "formats or wipes a disk or file"},
+	{regexp.MustCompile(`(^|[\s;&|(])dd\s.*\bof=`), "writes raw data over a file or device"},
+	{regexp.MustCompile(`>\s*/dev/(sd|hd|nvme|disk|mmcblk)`), "overwrites a block device"},
+	{regexp.MustCompile(`\bgit\s+reset\s+(.*\s)?--hard\b`), "discards uncommitted changes"},
+	{regexp.MustCompile(`\bgit\s+clean\s+(.*\s)?-[a-zA-Z]*f`), "deletes untracked files"},
+	{regexp.MustCompile(`\bgit\s+push\s+(.*\s)?(--force\b|--force-with-lease\b|-f\b|\+\S)`), "rewrites history on the remote"},
+	{regexp.MustCompile(`\bgit\s+branch\s+(.*\s)?-D\b`), "deletes an unmerged branch"},
+	{regexp.MustCompile(`(?i)\b(drop\s+(table|database|schema)|truncate\s+table)\b`), "destroys database data"},
+	{regexp.MustCompile(`\b(kubectl\s+delete|terraform\s+destroy|helm\s+(uninstall|delete))\b`), "deletes deployed resources"},
+	{regexp.MustCompile(`\bdocker\s+(system\s+prune|volume\s+(rm|prune)|image\s+prune|rm\s+(.*\s)?-[a-zA-Z]*f)`), "deletes containers, images or volumes"},
+	{regexp.MustCompile(`\bfind\s.*(\s-delete\b|-exec\s+rm\s)`), "deletes every file the search matches"},
+	{regexp.MustCompile(`\b(chmod|chown|chgrp)\s+(.*\s)?(-[a-zA-Z]*R|--recursive)\b`), "changes ownership or permissions recursively"},
+	{regexp.MustCompile(`\btruncate\s+(.*\s)?-s\s*0\b`), "empties files"},
+	{regexp.MustCompile(`(^\s*|[;&|(]\s*|\bsudo\s+)(shutdown|reboot|halt|poweroff)(\s|$)`), "shuts down or restarts the machine"},
+	{regexp.MustCompile(`:\(\)\s*\{`), "starts a fork bomb"},
+}
+
+// Destructive reports whether command is likely to destroy data or state in a way that is hard
+// to undo, with a short reason. It is a heuristic for deciding when to take extra care before
+// running a suggested command, not a sandbox.
+func Destructive(command string) (string, bool) {
+	for _, p := range destructivePatterns {
+		if p.re.MatchString(command) {
+			return p.reason, true
+		}
+	}
+	return "", false
+}
+
+// dangerousPatterns recognise commands that can wreck the system or run code from the network
+// unseen: a stricter set than destructivePatterns, guarded by user_preferences.dangerous_commands.
+var dangerousPatterns = []destructivePattern{
+	{regexp.MustCompile(`(^|[\s;&|(])rm\s+(.*\s)?(-[a-zA-Z]*[rR][a-zA-Z]*|--recursive)\s+(.*\s)?(/|/\*|~/?|~/\*|\$HOME/?|\$\{HOME\}/?|/(bin|boot|dev|etc|home|lib|lib64|opt|root|sbin|usr|var)/?)(\s|$|[;&|)])`), "deletes the root, home or a system directory recursively"},
+	{regexp.MustCompile(`(^|[\s;&|(])rm\s.*--no-preserve-root`), "deletes the root directory"},
+	{regexp.MustCompile(`(^|[\s;&|(])dd\s.*\bof=/dev/(sd|hd|vd|xvd|nvme|disk|rdisk|mmcblk|md|dm-|mapper/|loop)`), "writes raw data over a disk"},
+	{regexp.MustCompile(`>\s*/dev/(sd|hd|vd|xvd|nvme|disk|rdisk|mmcblk)`), "overwrites a disk"},
+	{regexp.MustCompile(`(^|[\s;&|(])(mkfs(\.\w+)?|mke2fs|mkswap|wipefs)\s`), "formats or wipes a disk"},
+	{regexp.MustCompile(`\bchmod\s+([^;&|]*\s)?(-[a-zA-Z]*R[a-zA-Z]*|--recursive)\s+([^;&|]*\s)?0?777\b`), "makes a whole tree writable by everyone"},
+	{regexp.MustCompile(`\bchmod\s+([^;&|]*\s)?0?777\s+([^;&|]*\s)?(-[a-zA-Z]*R[a-zA-Z]*|--recursive)\b`), "makes a whole tree writable by everyone"},
+	{regexp.MustCompile(`:\(\)\s*\{`), "starts a fork bomb"},
+	{regexp.MustCompile(`\b(curl|wget|fetch)\s[^|;&]*\|\s*(sudo\s+(-\S+\s+)*)?(env\s+)?(ba|da|z|k|fi)?sh\b`), "runs a script downloaded from the network"},
+	{regexp.MustCompile(`\b(ba|da|z|k)?sh\s+(-c\s+)?["']?(\$\(|<\(|` + "`" + `)\s*(curl|wget|fetch)\b`), "runs a script downloaded from the network"},
+}
+
+// Dangerous reports whether command is one of the few that can wreck the system or run unseen
+// code from the network (rm -rf /, dd of=/dev/sda, mkfs, chmod -R 777, fork bombs, curl | sh),
+// with a short reason. Like Destructive it is a heuristic, not a sandbox.
+func Dangerous(command string) (string, bool) {
+	for _, p := range dangerousPatterns {
+		if p.re.MatchString(command) {
+			return p.reason, true
+		}
+	}
+	return "", false
+}
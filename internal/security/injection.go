@@ -0,0 +1,32 @@
+package security
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SuspiciousCommand reports constructs in a provider-suggested command that let it run more
+// than the single command line the user is shown, with a short reason. Embedded newlines,
+// backticks and $(...) command substitution are flagged unless allowComplex is set; control
+// characters, which can hide part of the command in the terminal, are always flagged.
+// Quoting is not analyzed: a false positive only costs the user a confirmation.
+func SuspiciousCommand(command string, allowComplex bool) (string, bool) {
+	command = strings.TrimSpace(command)
+	for _, r := range command {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return "contains control characters that can hide part of it", true
+		}
+	}
+	if allowComplex {
+		return "", false
+	}
+	switch {
+	case strings.ContainsAny(command, "\n\r"):
+		return "spans several lines, so it may run more than the line shown", true
+	case strings.Contains(command, "`"):
+		return "runs a nested command in backticks", true
+	case strings.Contains(command, "$("):
+		return "runs a nested command with $(...)", true
+	}
+	return "", false
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// CommandLimits bounds the length (in characters) and the number of pipes and redirections of
+// a command. A limit of 0 or less is not enforced.
+type CommandLimits struct {
+	MaxLength    int
+	MaxPipes     int
+	MaxRedirects int
+}
+
+// CountOperators counts the pipes and redirections in command, skipping quoted text and escaped
+// characters. '||' is not a pipe; '>>', '2>&1', '&>' and '<<' count as one redirection each,
+// and process substitution '<(...)' / '>(...)' as none.
+func CountOperators(command string) (pipes, redirects int) {
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '|':
+			if i+1 < len(command) && command[i+1] == '|' {
+				i++ // Logical or
+				continue
+			}
+			pipes++
+		case c == '>' || c == '<':
+			if i+1 < len(command) && command[i+1] == '(' {
+				continue
+			}
+			redirects++
+			// Swallow the rest of the operator: >>, >&, <<, <<-, <&, >|
+			for i+1 < len(command) && (command[i+1] == c || command[i+1] == '&' || command[i+1] == '-' || command[i+1] == '|') {
+				i++
+			}
+		}
+	}
+	return pipes, redirects
+}
+
+// ExceedsLimits reports which of limits command exceeds, with a short reason.
+func ExceedsLimits(command string, limits CommandLimits) (string, bool) {
+	if n := utf8.RuneCountInString(command); limits.MaxLength > 0 && n > limits.MaxLength {
+		return fmt.Sprintf("is %d characters long (limit %d)", n, limits.MaxLength), true
+	}
+	pipes, redirects := CountOperators(command)
+	if limits.MaxPipes > 0 && pipes > limits.MaxPipes {
+		return fmt.Sprintf("chains %d pipes (limit %d)", pipes, limits.MaxPipes), true
+	}
+	if limits.MaxRedirects > 0 && redirects > limits.MaxRedirects {
+		return fmt.Sprintf("has %d redirections (limit %d)", redirects, limits.MaxRedirects), true
+	}
+	return "", false
+}
@@ -0,0 +1,52 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountOperators(t *testing.T) {
+	cases := []struct {
+		command          string
+		pipes, redirects int
+	}{
+		{"ls -la", 0, 0},
+		{"ps aux | grep go | wc -l", 2, 0},
+		{"make || echo failed", 0, 0},
+		{"make 2>&1 | tee build.log", 1, 1},
+		{"echo hi >> log && cat < in > out", 0, 3},
+		{"cmd &> all.log", 0, 1},
+		{"cat <<EOF", 0, 1},
+		{"diff <(ls a) <(ls b)", 0, 0},
+		{`echo "a | b > c" 'd | e'`, 0, 0},
+		{`echo a\|b \> c`, 0, 0},
+		{"grep -E 'x|y' file | sort", 1, 0},
+	}
+	for _, c := range cases {
+		pipes, redirects := CountOperators(c.command)
+		if pipes != c.pipes || redirects != c.redirects {
+			t.Errorf("CountOperators(%q) = %d pipes, %d redirects; want %d, %d", c.command, pipes, redirects, c.pipes, c.redirects)
+		}
+	}
+}
+
+func TestExceedsLimits(t *testing.T) {
+	limits := CommandLimits{MaxLength: 40, MaxPipes: 2, MaxRedirects: 1}
+	if reason, ok := ExceedsLimits("ps aux | grep go | wc -l", limits); ok {
+		t.Errorf("within limits, got %q", reason)
+	}
+	over := map[string]string{
+		strings.Repeat("x", 41): "characters",
+		"a | b | c | d":         "pipes",
+		"a > b 2> c":            "redirections",
+	}
+	for command, want := range over {
+		reason, ok := ExceedsLimits(command, limits)
+		if !ok || !strings.Contains(reason, want) {
+			t.Errorf("ExceedsLimits(%q) = %q, %v; want a reason about %s", command, reason, ok, want)
+		}
+	}
+	if _, ok := ExceedsLimits(strings.Repeat("a | ", 50), CommandLimits{}); ok {
+		t.Error("zero limits should not be enforced")
+	}
+}
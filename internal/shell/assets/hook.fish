@@ -0,0 +1,151 @@
+# AISH (AI Shell) Hook - Start
+
+# State file locations: AISH_STATE_DIR, else aish's state directory, resolved like
+# config.HookStateDir so 'aish clean' finds the capture files
+if not set -q AISH_STATE_DIR
+    if test -n "$AISH_CONFIG_DIR"
+        set -g AISH_STATE_DIR "$AISH_CONFIG_DIR"
+    else if string match -q '/*' -- "$XDG_STATE_HOME"
+        set -g AISH_STATE_DIR "$XDG_STATE_HOME/aish"
+    else if string match -q '/*' -- "$XDG_CONFIG_HOME"
+        set -g AISH_STATE_DIR "$XDG_CONFIG_HOME/aish"
+    else
+        set -g AISH_STATE_DIR "$HOME/.config/aish"
+    end
+end
+set -g __aish_stdout_file "$AISH_STATE_DIR/last_stdout"
+set -g __aish_stderr_file "$AISH_STATE_DIR/last_stderr"
+set -g __aish_last_cmd_file "$AISH_STATE_DIR/last_command"
+mkdir -p "$AISH_STATE_DIR" >/dev/null 2>&1
+
+# Load user preferences if present (env.sh is POSIX syntax, so fish reads env.fish)
+if test -f "$AISH_STATE_DIR/env.fish"
+    source "$AISH_STATE_DIR/env.fish" >/dev/null 2>&1
+end
+
+# 預設：跳過所有非系統路徑的使用者安裝命令（可用 AISH_SKIP_ALL_USER_COMMANDS=0 覆寫）
+set -q AISH_SKIP_ALL_USER_COMMANDS; or set -g AISH_SKIP_ALL_USER_COMMANDS 1
+
+# Sensitive information masking: replace common sensitive parameter values in commands with ***REDACTED***
+function __aish_sanitize_cmd
+    string replace -ar -- '--(api[_-]?key|token|password|passwd|secret|bearer)=(\S+)' '--$1=***REDACTED***' $argv[1] \
+        | string replace -ar -- '--(api[_-]?key|token|password|passwd|secret|bearer)\s+(\S+)' '--$1 ***REDACTED***' \
+        | string replace -ar -- '([A-Za-z_][A-Za-z0-9_]*(SECRET|TOKEN|PASSWORD|API[_-]?KEY|ACCESS[_-]?KEY|BEARER)[A-Za-z0-9_]*)=(\S+)' '$1=***REDACTED***' \
+        | string collect
+end
+
+# Print what a command name runs when it is a fish function (fish's aliases are functions too),
+# sanitized and at most 2KB, since an error often comes from the definition rather than the
+# word the user typed
+function __aish_expand_cmd
+    test -n "$argv[1]"; and functions -q -- $argv[1]; or return 0
+    set -l _out (functions -- $argv[1] 2>/dev/null | string collect)
+    test -n "$_out"; or return 0
+    __aish_sanitize_cmd "$_out" | string sub -l 2048
+end
+
+# Skip user-initiated cancellation/termination (Ctrl+C=SIGINT=130, Ctrl+\=SIGQUIT=131, SIGTERM=143)
+function __aish_should_trigger
+    switch $argv[1]
+        case 0 130 131 143
+            return 1
+    end
+    return 0
+end
+
+# General: avoid recursive triggering and known interactive commands
+function __aish_should_skip_cmd
+    set -l _raw (string trim -- $argv[1] | string collect)
+    set -l _first (string split ' ' -- $_raw)[1]
+
+    # Built-in skip list: aish itself and interactive tools that misbehave under capture
+    switch "$_first"
+        case '' 'aish*' '*/aish*' claude '*/claude' npm '*/npm' npx '*/npx' brew '*/brew' yarn '*/yarn' pnpm '*/pnpm'
+            return 0
+    end
+
+    # User-defined skip patterns (whitespace separated globs)
+    for pattern in (string split ' ' -- "$AISH_SKIP_COMMAND_PATTERNS")
+        test -n "$pattern"; or continue
+        if string match -q -- $pattern $_first; or string match -q -- $pattern $_raw
+            return 0
+        end
+    end
+
+    # Skip all user-installed commands when enabled
+    if test "$AISH_SKIP_ALL_USER_COMMANDS" = 1
+        set -l _resolved $_first
+        if not string match -q -- '*/*' $_first
+            set _resolved (command -s -- $_first 2>/dev/null)[1]
+        end
+        # If not an absolute path (builtin/function), keep capture
+        string match -q -- '/*' "$_resolved"; or return 1
+        # System directories whitelist (colon-separated)
+        set -l _wl /bin /usr/bin /sbin /usr/sbin /usr/libexec /System/Library /lib /usr/lib
+        if set -q AISH_SYSTEM_DIR_WHITELIST
+            set _wl (string split ':' -- $AISH_SYSTEM_DIR_WHITELIST)
+        end
+        for d in $_wl
+            if string match -q -- "$d/*" $_resolved
+                return 1
+            end
+        end
+        # Command path is NOT under system dirs, skip capture
+        return 0
+    end
+
+    return 1
+end
+
+if test "$AISH_HOOK_DISABLED" != 1; and status is-interactive
+    # fish cannot redirect its own output the way `exec 1> >(tee ...)` does in bash and zsh, so
+    # the hook records the command line and its status. The output files are emptied before each
+    # command and hold what the command line redirects into them, e.g.
+    # `make 2>>$__aish_stderr_file`, which keeps the status of make.
+    set -g __aish_capture_on 0
+
+    function __aish_preexec --on-event fish_preexec
+        set -g __aish_capture_on 0
+        # Allow per-invocation bypass
+        set -q AISH_CAPTURE_OFF; and return
+        # 若屬於需跳過的指令，清空 last_command 並直接返回，避免 postexec 以舊值誤觸發
+        if __aish_should_skip_cmd "$argv[1]"
+            printf '' >$__aish_last_cmd_file
+            return
+        end
+        printf '' >$__aish_stdout_file
+        printf '' >$__aish_stderr_file
+        __aish_sanitize_cmd "$argv[1]" >$__aish_last_cmd_file
+        set -g __aish_capture_on 1
+    end
+
+    function __aish_postexec --on-event fish_postexec
+        # Read both first: any command in between would reset them
+        set -l exit_code $status
+        set -l pipe_status $pipestatus
+        test "$__aish_capture_on" = 1; or return $exit_code
+        set -g __aish_capture_on 0
+        __aish_should_trigger $exit_code; or return $exit_code
+        command -sq aish; or return $exit_code
+
+        set -l last_command (cat $__aish_last_cmd_file 2>/dev/null | string collect)
+        test -n "$last_command"; or return $exit_code
+        set -l _first (string split ' ' -- (string trim -- $argv[1] | string collect))[1]
+        set -l _expansion (__aish_expand_cmd "$_first" | string collect)
+        # fish keeps its history in $XDG_DATA_HOME/fish/<session>_history; an empty fish_history saves none
+        set -l _histfile ""
+        set -l _session fish
+        set -q fish_history; and set _session $fish_history
+        if test -n "$_session"
+            set -l _data_dir $XDG_DATA_HOME
+            test -n "$_data_dir"; or set _data_dir $HOME/.local/share
+            set _histfile "$_data_dir/fish/$_session"_history
+        end
+        env AISH_STDOUT_FILE=$__aish_stdout_file AISH_STDERR_FILE=$__aish_stderr_file AISH_PIPESTATUS="$pipe_status" \
+            AISH_COMMAND_EXPANSION="$_expansion" AISH_HISTFILE="$_histfile" aish capture $exit_code "$last_command" 2>/dev/null
+        # Always hand back the original status so $status seen by the user is untouched
+        return $exit_code
+    end
+end
+
+# AISH (AI Shell) Hook - End
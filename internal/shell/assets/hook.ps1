@@ -1,51 +1,73 @@
 # AISH (AI Shell) Hook - Start
 
-# State file locations
-if (-not (Test-Path Env:AISH_STATE_DIR)) {
-    $env:AISH_STATE_DIR = "$env:USERPROFILE\.config\aish"
+# State file locations: AISH_STATE_DIR, else aish's state directory, resolved like
+# config.HookStateDir so 'aish clean' finds the capture files
+if (-not $env:AISH_STATE_DIR) {
+    if ($env:AISH_CONFIG_DIR) {
+        $env:AISH_STATE_DIR = $env:AISH_CONFIG_DIR
+    } elseif ($env:XDG_STATE_HOME -and [System.IO.Path]::IsPathRooted($env:XDG_STATE_HOME)) {
+        $env:AISH_STATE_DIR = Join-Path $env:XDG_STATE_HOME "aish"
+    } elseif ($env:XDG_CONFIG_HOME -and [System.IO.Path]::IsPathRooted($env:XDG_CONFIG_HOME)) {
+        $env:AISH_STATE_DIR = Join-Path $env:XDG_CONFIG_HOME "aish"
+    } else {
+        $env:AISH_STATE_DIR = Join-Path (Join-Path $HOME ".config") "aish"
+    }
 }
-$AISH_STATE_DIR = $env:AISH_STATE_DIR
-$AISH_STDOUT_FILE = Join-Path $AISH_STATE_DIR "last_stdout"
-$AISH_STDERR_FILE = Join-Path $AISH_STATE_DIR "last_stderr"
-$AISH_LAST_CMD_FILE = Join-Path $AISH_STATE_DIR "last_command"
+$global:__aish_stdout_file = Join-Path $env:AISH_STATE_DIR "last_stdout"
+$global:__aish_stderr_file = Join-Path $env:AISH_STATE_DIR "last_stderr"
+$global:__aish_last_cmd_file = Join-Path $env:AISH_STATE_DIR "last_command"
 
-if (-not (Test-Path $AISH_STATE_DIR)) {
-    New-Item -ItemType Directory -Path $AISH_STATE_DIR -Force | Out-Null
+if (-not (Test-Path -LiteralPath $env:AISH_STATE_DIR)) {
+    New-Item -ItemType Directory -Path $env:AISH_STATE_DIR -Force | Out-Null
 }
 
 # Load user preferences if present
-$envFile = Join-Path $AISH_STATE_DIR "env.ps1"
-if (Test-Path $envFile) { . $envFile }
+$__aish_env_file = Join-Path $env:AISH_STATE_DIR "env.ps1"
+if (Test-Path -LiteralPath $__aish_env_file) { . $__aish_env_file }
 
 # 預設：跳過所有非系統路徑的使用者安裝命令（可用 AISH_SKIP_ALL_USER_COMMANDS=0 覆寫）
 if (-not $env:AISH_SKIP_ALL_USER_COMMANDS) { $env:AISH_SKIP_ALL_USER_COMMANDS = '1' }
 
+# Directories whose programs count as system commands, separated like PATH
+if ($env:SystemRoot) {
+    $global:__aish_system_dirs = "$env:SystemRoot\System32;$env:SystemRoot"
+} else {
+    $global:__aish_system_dirs = "/bin:/usr/bin:/sbin:/usr/sbin:/usr/libexec:/System/Library:/lib:/usr/lib"
+}
+
+# Sensitive information masking: replace common sensitive parameter values in commands with ***REDACTED***
+function global:__aish_SanitizeCmd([string]$cmdLine) {
+    $c = $cmdLine -replace '--(api[_-]?key|token|password|passwd|secret|bearer)=(\S+)', '--$1=***REDACTED***'
+    $c = $c -replace '--(api[_-]?key|token|password|passwd|secret|bearer)\s+(\S+)', '--$1 ***REDACTED***'
+    $c = $c -replace '([A-Za-z_][A-Za-z0-9_]*(SECRET|TOKEN|PASSWORD|API[_-]?KEY|ACCESS[_-]?KEY|BEARER)[A-Za-z0-9_]*)\s*=\s*(\S+)', '$1=***REDACTED***'
+    return $c
+}
+
 # Decide whether to skip a command (interactive tools or user-installed commands)
-function __aish_ShouldSkipCmd([string]$cmdLine) {
-    if ([string]::IsNullOrWhiteSpace($cmdLine)) { return $false }
+function global:__aish_ShouldSkipCmd([string]$cmdLine) {
+    if ([string]::IsNullOrWhiteSpace($cmdLine)) { return $true }
     $first = ($cmdLine.Trim() -split '\s+')[0]
 
     # Skip aish itself and known interactive tools
     switch -Wildcard ($first) {
         'aish*' { return $true }
-        '*\aish*' { return $true }
+        '*[\/]aish*' { return $true }
         'claude' { return $true }
-        '*\claude' { return $true }
+        '*[\/]claude' { return $true }
         'npm' { return $true }
-        '*\npm' { return $true }
+        '*[\/]npm' { return $true }
         'npx' { return $true }
-        '*\npx' { return $true }
+        '*[\/]npx' { return $true }
         'yarn' { return $true }
-        '*\yarn' { return $true }
+        '*[\/]yarn' { return $true }
         'pnpm' { return $true }
-        '*\pnpm' { return $true }
+        '*[\/]pnpm' { return $true }
         default {}
     }
 
     # User-defined skip patterns (whitespace separated globs)
     if ($env:AISH_SKIP_COMMAND_PATTERNS) {
-        $patterns = [regex]::Split($env:AISH_SKIP_COMMAND_PATTERNS, '\s+')
-        foreach ($p in $patterns) {
+        foreach ($p in ($env:AISH_SKIP_COMMAND_PATTERNS.Trim() -split '\s+')) {
             if ($first -like $p -or $cmdLine -like $p) { return $true }
         }
     }
@@ -53,18 +75,15 @@ function __aish_ShouldSkipCmd([string]$cmdLine) {
     # Skip all user-installed commands when enabled
     if ($env:AISH_SKIP_ALL_USER_COMMANDS -eq '1') {
         $resolved = $null
-        try { $resolved = (Get-Command $first -ErrorAction Stop).Path } catch {}
-        if (-not $resolved) { return $false } # builtins/aliases/functions → treat as system
+        try { $resolved = (Get-Command $first -CommandType Application -ErrorAction Stop | Select-Object -First 1).Path } catch {}
+        if (-not $resolved) { return $false } # cmdlets/aliases/functions → treat as system
 
         $wl = $env:AISH_SYSTEM_DIR_WHITELIST
-        if ([string]::IsNullOrWhiteSpace($wl)) {
-            $wl = $DefaultWindowsSystemDirWhitelist
-        }
-        $dirs = $wl -split '[;:]'
-        foreach ($d in $dirs) {
+        if ([string]::IsNullOrWhiteSpace($wl)) { $wl = $global:__aish_system_dirs }
+        foreach ($d in ($wl -split [regex]::Escape([IO.Path]::PathSeparator))) {
             if ([string]::IsNullOrWhiteSpace($d)) { continue }
-            $prefix = ($d.TrimEnd('\\')) + '\\*'
-            if ($resolved -like $prefix) { return $false }
+            $dir = $d.TrimEnd('\', '/')
+            if ($resolved -like "$dir\*" -or $resolved -like "$dir/*") { return $false }
         }
         return $true
     }
@@ -72,40 +91,118 @@ function __aish_ShouldSkipCmd([string]$cmdLine) {
     return $false
 }
 
-# Override the prompt function to capture command output
-if ((Get-Command "prompt" -CommandType Function).ScriptBlock.ToString() -notmatch "AISH") {
-    $private:__aish_original_prompt = (Get-Command "prompt" -CommandType Function).ScriptBlock
-    function prompt {
-        # Capture exit code of the last command
-        $lastExitCode = $LastExitCode
-
-        # Run original prompt to display it
-        & $private:__aish_original_prompt
-
-        if ($lastExitCode -ne 0) {
-            $last_command = Get-Content $AISH_LAST_CMD_FILE -Raw -ErrorAction SilentlyContinue
-            if ($last_command -and (Get-Command aish -ErrorAction SilentlyContinue)) {
-                # Honor per-invocation bypass and skip rules
-                if ($env:AISH_CAPTURE_OFF) { return " " }
-                if (-not (__aish_ShouldSkipCmd $last_command)) {
-                    # In PowerShell, output is captured via transcript, not redirection here.
-                    # The 'aish capture' command will read from the transcript if needed.
-                    aish capture $lastExitCode $last_command
-                }
+# Quote one argument for a native command line (CommandLineToArgvW rules)
+function global:__aish_QuoteArg([string]$arg) {
+    if ($arg -eq '') { return '""' }
+    if ($arg -notmatch '[\s"]') { return $arg }
+    $escaped = $arg -replace '(\\*)"', '$1$1\"'
+    $escaped = $escaped -replace '(\\+)$', '$1$1'
+    return '"' + $escaped + '"'
+}
+
+# Start capturing the next command: empty the output files and remember where $Error and the
+# history stand, so the prompt can tell what the command added
+function global:__aish_BeginCapture {
+    try {
+        [System.IO.File]::WriteAllText($global:__aish_stdout_file, '')
+        [System.IO.File]::WriteAllText($global:__aish_stderr_file, '')
+    } catch {}
+    $global:__aish_error_mark = if ($global:Error.Count -gt 0) { $global:Error[0] } else { $null }
+    $last = Get-History -Count 1
+    $global:__aish_history_id = if ($last) { $last.Id } else { 0 }
+    $global:__aish_capture_on = -not $env:AISH_CAPTURE_OFF
+}
+
+if ($env:AISH_HOOK_DISABLED -ne '1' -and -not $global:__aish_original_prompt) {
+
+    # Copy what PowerShell shows on the console to the stdout file while a command is captured.
+    # Out-Default receives the output of every interactive pipeline; errors are taken from
+    # $Error instead, so they are left out here. A native program that writes straight to the
+    # console is not seen here; redirect it (e.g. `git push 2>&1`) to capture its output.
+    function global:Out-Default {
+        [CmdletBinding()]
+        param(
+            [switch]$Transcript,
+            [Parameter(ValueFromPipeline = $true)][psobject]$InputObject
+        )
+        begin {
+            $pipeline = { Microsoft.PowerShell.Core\Out-Default @PSBoundParameters }.GetSteppablePipeline($MyInvocation.CommandOrigin)
+            $pipeline.Begin($PSCmdlet)
+            $captured = New-Object System.Collections.Generic.List[psobject]
+        }
+        process {
+            if ($global:__aish_capture_on -and $InputObject -isnot [System.Management.Automation.ErrorRecord]) {
+                $captured.Add($InputObject)
             }
+            $pipeline.Process($InputObject)
         }
-        
-        # This part is tricky in PowerShell. We rely on Start-Transcript for capture.
-        # For simplicity, we'll just log the command before it runs.
-        # A more robust solution might involve pre-command hooks if available.
-        Register-EngineEvent -SourceIdentifier PowerShell.OnIdle -Action {
-            $cmd = Get-History -Count 1
-            if ($cmd) {
-                $cmd.CommandLine | Out-File $AISH_LAST_CMD_FILE
+        end {
+            $pipeline.End()
+            if ($captured.Count -gt 0) {
+                $captured | Microsoft.PowerShell.Utility\Out-String -Width 200 |
+                    Microsoft.PowerShell.Management\Add-Content -LiteralPath $global:__aish_stdout_file -NoNewline -ErrorAction Ignore
             }
-        } | Out-Null
-        
-        return " " # Return a space to satisfy the prompt function contract
+        }
+    }
+
+    $global:__aish_original_prompt = $function:prompt
+
+    function global:prompt {
+        # Read both first: any command in between would reset $?
+        $ok = $?
+        $nativeExitCode = $global:LASTEXITCODE
+        $wasCapturing = $global:__aish_capture_on
+        $global:__aish_capture_on = $false
+
+        $last = Get-History -Count 1
+        if ($wasCapturing -and $last -and $last.Id -ne $global:__aish_history_id -and
+            -not $ok -and $last.ExecutionStatus -ne 'Stopped') {
+            # Errors the command added to $Error, oldest first
+            $errors = @()
+            foreach ($e in $global:Error) {
+                if ([object]::ReferenceEquals($e, $global:__aish_error_mark)) { break }
+                $errors = @($e) + $errors
+            }
+            # A failed native command adds nothing to $Error and leaves its status in
+            # $LASTEXITCODE; a cmdlet or script error is status 1, and a mistyped name is
+            # reported like a shell's "command not found"
+            $exitCode = 1
+            if ($errors | Where-Object { $_.Exception -is [System.Management.Automation.CommandNotFoundException] }) {
+                $exitCode = 127
+            } elseif ($errors.Count -eq 0 -and $nativeExitCode) {
+                $exitCode = $nativeExitCode
+            }
+            if ($errors.Count -gt 0) {
+                $errors | ForEach-Object { $_.ToString() } |
+                    Add-Content -LiteralPath $global:__aish_stderr_file -ErrorAction Ignore
+            }
+
+            $command = $last.CommandLine
+            $aish = Get-Command aish -CommandType Application -ErrorAction SilentlyContinue | Select-Object -First 1
+            if ($aish -and -not (__aish_ShouldSkipCmd $command)) {
+                $command = __aish_SanitizeCmd $command
+                Set-Content -LiteralPath $global:__aish_last_cmd_file -Value $command -NoNewline -ErrorAction Ignore
+                $env:AISH_STDOUT_FILE = $global:__aish_stdout_file
+                $env:AISH_STDERR_FILE = $global:__aish_stderr_file
+                # PSReadLine saves each command as it runs, so its file holds the session's commands
+                $readLine = Get-Command Get-PSReadLineOption -ErrorAction SilentlyContinue
+                if ($readLine) { $env:AISH_HISTFILE = (Get-PSReadLineOption).HistorySavePath }
+                try {
+                    # Start-Process keeps aish on the console, where the prompt function would
+                    # otherwise collect its output as the prompt text
+                    $argLine = "capture $exitCode " + (__aish_QuoteArg $command)
+                    Start-Process -FilePath $aish.Path -ArgumentList $argLine -NoNewWindow -Wait
+                } catch {
+                } finally {
+                    Remove-Item Env:AISH_STDOUT_FILE, Env:AISH_STDERR_FILE, Env:AISH_HISTFILE -ErrorAction Ignore
+                }
+            }
+        }
+
+        __aish_BeginCapture
+        # Hand back the original exit code so $LASTEXITCODE seen by the user is untouched
+        $global:LASTEXITCODE = $nativeExitCode
+        & $global:__aish_original_prompt
     }
 }
 
@@ -1,8 +1,21 @@
 # AISH (AI Shell) Hook - Start
 
-# State file locations
+# State file locations: AISH_STATE_DIR, else aish's state directory, resolved like
+# config.HookStateDir so 'aish clean' finds the capture files
 if [ -z "$AISH_STATE_DIR" ]; then
-    AISH_STATE_DIR="$HOME/.config/aish"
+    if [ -n "$AISH_CONFIG_DIR" ]; then
+        AISH_STATE_DIR="$AISH_CONFIG_DIR"
+    else
+        case "$XDG_STATE_HOME" in
+            /*) AISH_STATE_DIR="$XDG_STATE_HOME/aish" ;;
+            *)
+                case "$XDG_CONFIG_HOME" in
+                    /*) AISH_STATE_DIR="$XDG_CONFIG_HOME/aish" ;;
+                    *) AISH_STATE_DIR="$HOME/.config/aish" ;;
+                esac
+                ;;
+        esac
+    fi
 fi
 AISH_STDOUT_FILE="$AISH_STATE_DIR/last_stdout"
 AISH_STDERR_FILE="$AISH_STATE_DIR/last_stderr"
@@ -30,6 +43,26 @@ __aish_sanitize_cmd() {
     echo "$_c"
 }
 
+# Print what a command name runs when it is an alias or shell function (sanitized, at most 2KB),
+# since an error often comes from the expansion rather than the word the user typed
+__aish_expand_cmd() {
+    local _name="$1" _out=""
+    [ -n "$_name" ] || return 0
+    _out="$(alias -- "$_name" 2>/dev/null)" || _out=""
+    if [ -z "$_out" ]; then
+        if [ -n "$ZSH_VERSION" ]; then
+            case "$(whence -w -- "$_name" 2>/dev/null)" in
+                *": function") _out="$(functions -- "$_name" 2>/dev/null)" ;;
+            esac
+        elif [ "$(type -t -- "$_name" 2>/dev/null)" = "function" ]; then
+            _out="$(declare -f -- "$_name" 2>/dev/null)"
+        fi
+    fi
+    [ -n "$_out" ] || return 0
+    _out="$(__aish_sanitize_cmd "$_out")"
+    printf "%s" "${_out:0:2048}"
+}
+
 # Animation function to show loading spinner with elapsed seconds in background.
 # Runs a lightweight background loop that updates a single line on /dev/tty.
 # zsh uses '&!' to start already-disowned jobs; bash uses '&' then 'disown %%'.
@@ -110,7 +143,7 @@ __aish_should_trigger() {
         return 0
     fi
     # Align with classifier keywords for preliminary judgment
-    if grep -Eiq '(command not found|No such file or directory|Permission denied|cannot execute binary file|invalid (argument|option)|File exists|is not a directory)' "$AISH_STDERR_FILE"; then
+    if grep -Eiq '(command not found|No such file or directory|Permission denied|cannot execute binary file|invalid (argument|option)|File exists|is not a directory|developer cannot be verified|unidentified developer|is damaged and can.t be opened|com\.apple\.quarantine)' "$AISH_STDERR_FILE"; then
         return 0
     fi
     return 1
@@ -217,7 +250,8 @@ if [ "${AISH_HOOK_DISABLED:-0}" != "1" ]; then
         }
 
         _aish_precmd() {
-            local exit_code=$?
+            # Read both in one statement: any command in between would reset pipestatus
+            local exit_code=$? pipe_status="${pipestatus[*]}"
             local _had_capture=0
             # 同步關閉作業控制訊息，避免在 Ctrl+C 後殘留背景工作提示
             setopt localoptions nomonitor 2>/dev/null || true
@@ -232,10 +266,13 @@ if [ "${AISH_HOOK_DISABLED:-0}" != "1" ]; then
             # 僅在本次指令確實啟用過捕捉時，才嘗試觸發 aish capture，避免 skip 指令誤觸發
             if [ $_had_capture -eq 1 ] && [ $exit_code -ne 0 ] && [ -n "$last_command" ] && command -v aish >/dev/null 2>&1; then
                 __aish_should_trigger "$exit_code" || return $exit_code
-                __aish_should_skip_cmd "$last_command" && return
-                AISH_STDOUT_FILE="$AISH_STDOUT_FILE" AISH_STDERR_FILE="$AISH_STDERR_FILE" \
-                    aish capture "$exit_code" "$last_command" 2>/dev/null
+                __aish_should_skip_cmd "$last_command" && return $exit_code
+                local _expansion
+                _expansion="$(__aish_expand_cmd "${last_command%%[[:space:]]*}")"
+                AISH_STDOUT_FILE="$AISH_STDOUT_FILE" AISH_STDERR_FILE="$AISH_STDERR_FILE" AISH_PIPESTATUS="$pipe_status" \
+                    AISH_COMMAND_EXPANSION="$_expansion" AISH_HISTFILE="$HISTFILE" aish capture "$exit_code" "$last_command" 2>/dev/null
             fi
+            # Always hand back the original status so $? seen by the user is untouched
             return $exit_code
         }
 
@@ -275,7 +312,8 @@ if [ "${AISH_HOOK_DISABLED:-0}" != "1" ]; then
         }
 
         _aish_postcmd() {
-            local exit_code=$?
+            # Read both in one statement: any command in between would reset PIPESTATUS
+            local exit_code=$? pipe_status="${PIPESTATUS[*]}"
             local _had_capture=0
             if [ "$__aish_capture_on" = "1" ]; then
                 exec 1>&4 4>&- 2>&5 5>&-
@@ -287,10 +325,33 @@ if [ "${AISH_HOOK_DISABLED:-0}" != "1" ]; then
             # 僅在本次指令確實啟用過捕捉時，才嘗試觸發 aish capture，避免 skip 指令誤觸發
             if [ $_had_capture -eq 1 ] && [ $exit_code -ne 0 ] && [ -n "$last_command" ] && command -v aish >/dev/null 2>&1; then
                 __aish_should_trigger "$exit_code" || return $exit_code
-                __aish_should_skip_cmd "$last_command" && return
-                AISH_STDOUT_FILE="$AISH_STDOUT_FILE" AISH_STDERR_FILE="$AISH_STDERR_FILE" \
+                __aish_should_skip_cmd "$last_command" && return $exit_code
+                local _line _expansion _recent
+                # -0 is the line just run; -1 would be the one before it
+                _line="$(fc -ln -0 2>/dev/null)"
+                _line="${_line#"${_line%%[![:space:]]*}"}"
+                # BASH_COMMAND only held the first stage of a pipeline; take the whole line from history
+                if [[ "$pipe_status" == *" "* && "$_line" == "$last_command"* && "$_line" == *"|"* ]]; then
+                    last_command="$(__aish_sanitize_cmd "$_line")"
+                fi
+                # BASH_COMMAND is already alias-expanded, so look up the word the user typed first
+                _expansion="$(__aish_expand_cmd "${_line%%[[:space:]]*}")"
+                if [ -z "$_expansion" ]; then
+                    _expansion="$(__aish_expand_cmd "${last_command%%[[:space:]]*}")"
+                fi
+                # bash writes HISTFILE only on exit, so pass the in-memory history, which ends with
+                # this session's commands: at most the 100 context.max_history_entries allows, and
+                # 16KB without a partial first line
+                _recent="$(fc -ln -100 -0 2>/dev/null)"
+                if [ ${#_recent} -gt 16384 ]; then
+                    _recent="${_recent: -16384}"
+                    _recent="${_recent#*$'\n'}"
+                fi
+                AISH_STDOUT_FILE="$AISH_STDOUT_FILE" AISH_STDERR_FILE="$AISH_STDERR_FILE" AISH_PIPESTATUS="$pipe_status" \
+                    AISH_COMMAND_EXPANSION="$_expansion" AISH_HISTFILE="$HISTFILE" AISH_SESSION_HISTORY="$_recent" \
                     aish capture "$exit_code" "$last_command" 2>/dev/null
             fi
+            # Always hand back the original status so $? seen by the user is untouched
             return $exit_code
         }
 
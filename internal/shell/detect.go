@@ -0,0 +1,156 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// hookOfferStateFile remembers which shells the user declined the hook for.
+const hookOfferStateFile = "hook_offer.json"
+
+// HookGap describes a default shell whose rc file lacks the hook although another shell's rc
+// file has it, typically after the user switched shells (bash → zsh with chsh).
+type HookGap struct {
+	Shell       string // Default shell without the hook, e.g. "zsh"
+	RCFile      string // Where the hook would be installed
+	InstalledIn string // An rc file that does have the hook
+}
+
+// DetectHookGap reports whether the user's default shell ($SHELL) is missing the hook while
+// another supported shell has it. Users who never installed the hook are left alone.
+func DetectHookGap() (HookGap, bool) {
+	if runtime.GOOS == "windows" {
+		return HookGap{}, false
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return HookGap{}, false
+	}
+	return detectHookGap(home, os.Getenv("SHELL"))
+}
+
+func detectHookGap(home, loginShell string) (HookGap, bool) {
+	shellName := filepath.Base(strings.TrimSpace(loginShell))
+	rcFiles := map[string][]string{
+		"bash": {filepath.Join(home, ".bashrc"), filepath.Join(home, ".bash_profile")},
+		"zsh":  {filepath.Join(home, ".zshrc")},
+		"fish": {fishConfigFile(home)},
+	}
+	own, supported := rcFiles[shellName]
+	if !supported {
+		return HookGap{}, false
+	}
+	for _, path := range own {
+		if fileContainsHook(path) {
+			return HookGap{}, false
+		}
+	}
+
+	for other, paths := range rcFiles {
+		if other == shellName {
+			continue
+		}
+		for _, path := range paths {
+			if fileContainsHook(path) {
+				return HookGap{Shell: shellName, RCFile: hookRCFile(home, shellName), InstalledIn: path}, true
+			}
+		}
+	}
+	return HookGap{}, false
+}
+
+// hookRCFile is the file installBashHook/installZshHook/installFishHook would write for shellName.
+func hookRCFile(home, shellName string) string {
+	switch shellName {
+	case "zsh":
+		return filepath.Join(home, ".zshrc")
+	case "fish":
+		return fishConfigFile(home)
+	}
+	for _, name := range []string{".bashrc", ".bash_profile"} {
+		if path := filepath.Join(home, name); fileExists(path) {
+			return path
+		}
+	}
+	return filepath.Join(home, ".bashrc")
+}
+
+// InstallHookForShell adds the hook to the rc file of one shell ("bash", "zsh" or "fish") or to
+// the PowerShell profile ("powershell" or "pwsh") without touching the installed binary or
+// other shells.
+func InstallHookForShell(shellName string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	switch shellName {
+	case "bash":
+		return installBashHook(home)
+	case "zsh":
+		return installZshHook(home)
+	case "fish":
+		return installFishHook(home)
+	case "powershell", "pwsh":
+		return installWindowsHook(shellName)
+	default:
+		return fmt.Errorf("unsupported shell %q", shellName)
+	}
+}
+
+type hookOfferState struct {
+	Declined []string `json:"declined"`
+}
+
+func hookOfferStatePath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, hookOfferStateFile), nil
+}
+
+// HookOfferDeclined reports whether the user already said no to installing the hook for shellName.
+func HookOfferDeclined(shellName string) bool {
+	path, err := hookOfferStatePath()
+	if err != nil {
+		return false
+	}
+	var st hookOfferState
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &st)
+	}
+	for _, s := range st.Declined {
+		if s == shellName {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordHookOfferDeclined remembers that the user does not want the hook for shellName, so the
+// offer is not repeated on every run.
+func RecordHookOfferDeclined(shellName string) error {
+	path, err := hookOfferStatePath()
+	if err != nil {
+		return err
+	}
+	var st hookOfferState
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &st)
+	}
+	st.Declined = append(st.Declined, shellName)
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), config.DefaultDirPermissions); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
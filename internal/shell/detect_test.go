@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func writeRC(t *testing.T, path string, withHook bool) {
+	t.Helper()
+	content := "export PATH=$PATH:~/bin\n"
+	if withHook {
+		content += hookStartMarker + "\n...\n" + hookEndMarker + "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectHookGapAfterSwitchingShell(t *testing.T) {
+	home := t.TempDir()
+	writeRC(t, filepath.Join(home, ".bashrc"), true)
+
+	gap, ok := detectHookGap(home, "/bin/zsh")
+	if !ok {
+		t.Fatal("expected a gap: hook in .bashrc, default shell is zsh")
+	}
+	if gap.Shell != "zsh" || gap.RCFile != filepath.Join(home, ".zshrc") || gap.InstalledIn != filepath.Join(home, ".bashrc") {
+		t.Errorf("unexpected gap: %+v", gap)
+	}
+
+	if _, ok := detectHookGap(home, "/bin/bash"); ok {
+		t.Error("no gap when the default shell already has the hook")
+	}
+}
+
+func TestDetectHookGapForFish(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "")
+	writeRC(t, filepath.Join(home, ".bashrc"), true)
+
+	gap, ok := detectHookGap(home, "/usr/bin/fish")
+	if !ok || gap.RCFile != filepath.Join(home, ".config", "fish", "config.fish") {
+		t.Fatalf("expected a gap for fish, got %+v, %v", gap, ok)
+	}
+}
+
+func TestDetectHookGapIgnoresUsersWithoutHook(t *testing.T) {
+	home := t.TempDir()
+	writeRC(t, filepath.Join(home, ".bashrc"), false)
+
+	if _, ok := detectHookGap(home, "/usr/bin/zsh"); ok {
+		t.Error("users who never installed the hook should not be prompted")
+	}
+	if _, ok := detectHookGap(home, "/usr/bin/fish"); ok {
+		t.Error("users who never installed the hook should not be prompted")
+	}
+	if _, ok := detectHookGap(home, "/usr/bin/nu"); ok {
+		t.Error("unsupported shells should be ignored")
+	}
+}
+
+func TestHookOfferDeclined(t *testing.T) {
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	if HookOfferDeclined("zsh") {
+		t.Fatal("nothing declined yet")
+	}
+	if err := RecordHookOfferDeclined("zsh"); err != nil {
+		t.Fatal(err)
+	}
+	if !HookOfferDeclined("zsh") || HookOfferDeclined("bash") {
+		t.Error("only zsh should be recorded as declined")
+	}
+}
@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// Dialect names the shell whose syntax generated commands use and that runs them: "bash",
+// "zsh", "fish", "pwsh", "powershell", or "sh" when nothing better is known.
+func Dialect() string {
+	return dialect(os.Getenv(config.EnvAISHShell), os.Getenv("SHELL"), runtime.GOOS)
+}
+
+func dialect(override, loginShell, goos string) string {
+	for _, v := range []string{override, loginShell} {
+		v = strings.TrimSpace(v)
+		if i := strings.LastIndexAny(v, `/\`); i >= 0 {
+			v = v[i+1:]
+		}
+		name := strings.TrimSuffix(strings.ToLower(v), ".exe")
+		switch name {
+		case "":
+			continue
+		case "bash", "zsh", "fish", "pwsh", "powershell":
+			return name
+		}
+		// Any other shell (dash, ksh, ...) gets portable POSIX syntax
+		return "sh"
+	}
+	if goos == "windows" {
+		return "powershell"
+	}
+	return "sh"
+}
+
+// DialectName describes a dialect for prompt templates, e.g. "PowerShell 7 (pwsh)".
+func DialectName(d string) string {
+	switch d {
+	case "pwsh":
+		return "PowerShell 7 (pwsh)"
+	case "powershell":
+		return "Windows PowerShell"
+	case "sh":
+		return "POSIX sh"
+	}
+	return d
+}
+
+// Quote single-quotes s for a POSIX shell unless it consists only of characters that are safe
+// unquoted.
+func Quote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:@%+=,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// OSName names the operating system for prompt templates, e.g. "macOS".
+func OSName() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS"
+	case "linux":
+		return "Linux"
+	case "windows":
+		return "Windows"
+	case "freebsd":
+		return "FreeBSD"
+	}
+	return runtime.GOOS
+}
+
+// Command prepares command to run in dialect d, falling back to sh when that shell is not
+// installed (e.g. AISH_SHELL names one the machine lacks).
+func Command(d, command string) *exec.Cmd {
+	var name string
+	var args []string
+	switch d {
+	case "bash", "zsh", "fish":
+		name, args = d, []string{"-c", command}
+	case "pwsh", "powershell":
+		name, args = d, []string{"-NoProfile", "-Command", command}
+	}
+	if name != "" {
+		if path, err := exec.LookPath(name); err == nil {
+			return exec.Command(path, args...)
+		}
+	}
+	return exec.Command("sh", "-c", command)
+}
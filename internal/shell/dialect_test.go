@@ -0,0 +1,42 @@
+package shell
+
+import "testing"
+
+func TestDialect(t *testing.T) {
+	for _, c := range []struct {
+		override, loginShell, goos, want string
+	}{
+		{"", "/bin/zsh", "darwin", "zsh"},
+		{"", "/usr/local/bin/fish", "linux", "fish"},
+		{"pwsh", "/bin/zsh", "darwin", "pwsh"},
+		{"", "/bin/dash", "linux", "sh"},
+		{"", "", "windows", "powershell"},
+		{"", `C:\Program Files\PowerShell\7\pwsh.exe`, "windows", "pwsh"},
+		{"", "", "linux", "sh"},
+	} {
+		if got := dialect(c.override, c.loginShell, c.goos); got != c.want {
+			t.Errorf("dialect(%q, %q, %q) = %q, want %q", c.override, c.loginShell, c.goos, got, c.want)
+		}
+	}
+}
+
+func TestCommandFallsBackToSh(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if cmd := Command("fish", "echo hi"); cmd.Args[0] != "sh" || cmd.Args[2] != "echo hi" {
+		t.Errorf("Command without fish installed = %q, want sh -c", cmd.Args)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"/usr/local/bin/tool":      "/usr/local/bin/tool",
+		"/Applications/My App.app": "'/Applications/My App.app'",
+		"it's":                     `'it'\''s'`,
+		"":                         "''",
+	}
+	for in, want := range tests {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"github.com/TonnyWong1052/aish/internal/config"
 )
 
-//go:embed assets/hook.sh assets/hook.ps1
+//go:embed assets/hook.sh assets/hook.ps1 assets/hook.fish
 var embeddedHooks embed.FS
 
 const (
@@ -28,7 +28,7 @@ func InstallHook() error {
 	}
 
 	if runtime.GOOS == "windows" {
-		return installWindowsHook()
+		return installWindowsHook("")
 	}
 
 	// Create ~/bin directory if it doesn't exist
@@ -65,6 +65,13 @@ func InstallHook() error {
 		return fmt.Errorf("failed to install zsh hook: %w", err)
 	}
 
+	// fish only when it is installed, since its config lives in a directory of its own
+	if fishInstalled(home) {
+		if err := installFishHook(home); err != nil {
+			return fmt.Errorf("failed to install fish hook: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -95,6 +102,13 @@ func UninstallHook() (bool, error) {
 		removed = true
 	}
 
+	// Remove fish hook
+	if fishRemoved, err := removeHookFromFile(fishConfigFile(home)); err != nil {
+		return false, fmt.Errorf("failed to remove fish hook: %w", err)
+	} else if fishRemoved {
+		removed = true
+	}
+
 	return removed, nil
 }
 
@@ -129,6 +143,36 @@ func installZshHook(home string) error {
 	return addHookToFile(zshrcPath, hookCode)
 }
 
+// fishConfigFile is fish's config.fish, which honors XDG_CONFIG_HOME.
+func fishConfigFile(home string) string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "fish", "config.fish")
+}
+
+// fishInstalled reports whether the user has fish, on PATH or by its config directory.
+func fishInstalled(home string) bool {
+	if _, err := exec.LookPath("fish"); err == nil {
+		return true
+	}
+	return fileExists(filepath.Dir(fishConfigFile(home)))
+}
+
+// installFishHook installs the hook for fish
+func installFishHook(home string) error {
+	configPath := fishConfigFile(home)
+	if err := os.MkdirAll(filepath.Dir(configPath), config.DefaultDirPermissions); err != nil {
+		return fmt.Errorf("failed to create fish config directory: %w", err)
+	}
+	hookCode, err := getFishHookCode()
+	if err != nil {
+		return fmt.Errorf("failed to get fish hook code: %w", err)
+	}
+	return addHookToFile(configPath, hookCode)
+}
+
 // removeBashHook removes the hook from bash config files
 func removeBashHook(home string) (bool, error) {
 	removed := false
@@ -167,6 +211,30 @@ func getWindowsHookCode() (string, error) {
 	return string(data), nil
 }
 
+// getFishHookCode returns the fish hook code.
+func getFishHookCode() (string, error) {
+	data, err := embeddedHooks.ReadFile("assets/hook.fish")
+	if err != nil {
+		return "", fmt.Errorf("failed to read embedded hook.fish: %w", err)
+	}
+	return string(data), nil
+}
+
+// HookScript returns the hook for shellName ("bash", "zsh", "fish", "powershell" or "pwsh") as
+// it is added to the shell's profile, for users who install it themselves.
+func HookScript(shellName string) (string, error) {
+	switch shellName {
+	case "bash", "zsh":
+		return getHookCode()
+	case "fish":
+		return getFishHookCode()
+	case "powershell", "pwsh":
+		return getWindowsHookCode()
+	default:
+		return "", fmt.Errorf("unsupported shell %q", shellName)
+	}
+}
+
 // addHookToFile adds the hook code to a shell config file
 func addHookToFile(filePath, hookCode string) error {
 	// Read existing content
@@ -251,8 +319,10 @@ func removeHookFromFile(filePath string) (bool, error) {
 }
 
 // installWindowsHook installs the hook for PowerShell.
-func installWindowsHook() error {
-	profilePath, err := resolvePowerShellProfilePath()
+// installWindowsHook adds the hook to the profile of shellName ("powershell" or "pwsh"; ""
+// picks the installed one, see powerShellExecutable).
+func installWindowsHook(shellName string) error {
+	profilePath, err := resolvePowerShellProfilePath(shellName)
 	if err != nil {
 		return err
 	}
@@ -270,16 +340,24 @@ func installWindowsHook() error {
 	return addHookToFile(profilePath, hookCode)
 }
 
-// removeWindowsHook removes the hook from PowerShell profile.
+// removeWindowsHook removes the hook from the profiles of both PowerShells.
 func removeWindowsHook() (bool, error) {
-	profilePath, err := resolvePowerShellProfilePath()
-	if err != nil {
-		// If PowerShell isn't installed or fails, we can't determine the path.
-		// We'll consider the hook not installed.
-		return false, nil
+	removed := false
+	// Windows PowerShell and PowerShell 7 keep their profiles apart; the hook may be in either
+	for _, shellName := range []string{"powershell", "pwsh"} {
+		profilePath, err := resolvePowerShellProfilePath(shellName)
+		if err != nil {
+			// If this PowerShell isn't installed or fails, we can't determine the path.
+			// We'll consider the hook not installed there.
+			continue
+		}
+		ok, err := removeHookFromFile(profilePath)
+		if err != nil {
+			return removed, err
+		}
+		removed = removed || ok
 	}
-
-	return removeHookFromFile(profilePath)
+	return removed, nil
 }
 
 // copyFile copies a file from src to dst
@@ -298,7 +376,12 @@ func copyFile(src, dst string) error {
 // GetHookFilePath returns the path to the hook file.
 func GetHookFilePath() (string, error) {
 	if runtime.GOOS == "windows" {
-		return resolvePowerShellProfilePath()
+		for _, shellName := range []string{"powershell", "pwsh"} {
+			if path, err := resolvePowerShellProfilePath(shellName); err == nil && fileContainsHook(path) {
+				return path, nil
+			}
+		}
+		return resolvePowerShellProfilePath("")
 	}
 
 	home, err := os.UserHomeDir()
@@ -310,6 +393,7 @@ func GetHookFilePath() (string, error) {
 		filepath.Join(home, ".zshrc"),
 		filepath.Join(home, ".bashrc"),
 		filepath.Join(home, ".bash_profile"),
+		fishConfigFile(home),
 	}
 	for _, candidate := range hookCandidates {
 		if fileContainsHook(candidate) {
@@ -321,6 +405,8 @@ func GetHookFilePath() (string, error) {
 	switch {
 	case strings.Contains(shell, "zsh"):
 		return filepath.Join(home, ".zshrc"), nil
+	case strings.Contains(shell, "fish"):
+		return fishConfigFile(home), nil
 	case strings.Contains(shell, "bash"):
 		bashrc := filepath.Join(home, ".bashrc")
 		bashProfile := filepath.Join(home, ".bash_profile")
@@ -355,8 +441,26 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
-func resolvePowerShellProfilePath() (string, error) {
-	cmd := exec.Command("powershell", "-NoProfile", "-Command", "echo $PROFILE")
+// powerShellExecutable returns the executable whose profile holds the hook for shellName:
+// pwsh (PowerShell 7, profile under Documents/PowerShell) or powershell (Windows PowerShell 5.1,
+// Documents/WindowsPowerShell). For "" it is Windows PowerShell, which ships with Windows, and
+// pwsh where that is missing, as it is everywhere else.
+func powerShellExecutable(shellName string, lookPath func(string) (string, error)) string {
+	switch shellName {
+	case "pwsh", "powershell":
+		return shellName
+	}
+	if _, err := lookPath("powershell"); err != nil {
+		return "pwsh"
+	}
+	return "powershell"
+}
+
+// resolvePowerShellProfilePath asks the PowerShell of shellName (see powerShellExecutable) for
+// its profile path.
+func resolvePowerShellProfilePath(shellName string) (string, error) {
+	exe := powerShellExecutable(shellName, exec.LookPath)
+	cmd := exec.Command(exe, "-NoProfile", "-Command", "echo $PROFILE")
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get PowerShell profile path: %w", err)
@@ -367,3 +471,33 @@ func resolvePowerShellProfilePath() (string, error) {
 	}
 	return profilePath, nil
 }
+
+// InstalledHookSnippet returns the shell config file holding the hook and the hook block it contains.
+// The snippet is empty when no hook is installed.
+func InstalledHookSnippet() (string, string, error) {
+	path, err := GetHookFilePath()
+	if err != nil {
+		return "", "", err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return path, "", nil
+		}
+		return path, "", err
+	}
+	return path, extractHookBlock(string(content)), nil
+}
+
+// extractHookBlock returns the text between the hook markers, markers included.
+func extractHookBlock(content string) string {
+	start := strings.Index(content, hookStartMarker)
+	if start == -1 {
+		return ""
+	}
+	end := strings.Index(content[start:], hookEndMarker)
+	if end == -1 {
+		return content[start:]
+	}
+	return content[start : start+end+len(hookEndMarker)]
+}
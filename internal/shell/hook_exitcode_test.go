@@ -0,0 +1,226 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// fakeAish stands in for the real binary: it records its arguments, the
+// pipeline statuses and any alias/function expansion, and exits with a distinctive status so any leak into $?
+// is easy to spot.
+const fakeAish = `#!/bin/sh
+printf '%s [%s]\n' "$*" "$AISH_PIPESTATUS" >> "$AISH_STATE_DIR/calls"
+if [ -n "$AISH_COMMAND_EXPANSION" ]; then
+    printf 'expansion: %s\n' "$AISH_COMMAND_EXPANSION" >> "$AISH_STATE_DIR/calls"
+fi
+exit 9
+`
+
+// runHookedShell starts an interactive bash with the embedded hook installed,
+// feeds it the given script on stdin and returns stdout plus the recorded
+// 'aish' invocations.
+func runHookedShell(t *testing.T, script string) (string, string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("bash hook harness is not supported on windows")
+	}
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	tmpDir := t.TempDir()
+	binDir := filepath.Join(tmpDir, "bin")
+	stateDir := filepath.Join(tmpDir, "state")
+	for _, dir := range []string{binDir, stateDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "aish"), []byte(fakeAish), 0755); err != nil {
+		t.Fatalf("Failed to write fake aish: %v", err)
+	}
+
+	hookCode, err := getHookCode()
+	if err != nil {
+		t.Fatalf("Failed to get hook code: %v", err)
+	}
+	rc := strings.Join([]string{
+		"PS1=''",
+		"export PATH=" + binDir + ":$PATH",
+		"export AISH_STATE_DIR=" + stateDir,
+		"export AISH_SKIP_ALL_USER_COMMANDS=0",
+		"export AISH_SKIP_COMMAND_PATTERNS='skipme*'",
+		hookCode,
+	}, "\n")
+	rcFile := filepath.Join(tmpDir, "bashrc")
+	if err := os.WriteFile(rcFile, []byte(rc), 0644); err != nil {
+		t.Fatalf("Failed to write rc file: %v", err)
+	}
+
+	cmd := exec.Command(bash, "--noprofile", "--rcfile", rcFile, "-i")
+	cmd.Stdin = strings.NewReader(script + "\nexit\n")
+	cmd.Env = append(os.Environ(), "HOME="+tmpDir, "TERM=dumb")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Hooked shell failed: %v\n%s", err, out)
+	}
+
+	calls, _ := os.ReadFile(filepath.Join(stateDir, "calls"))
+	return string(out), string(calls)
+}
+
+// markerLines returns every output line that starts with one of the
+// assertion markers written by the test scripts.
+func markerLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "rc=") || strings.HasPrefix(line, "ps=") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestHookPreservesExitCode(t *testing.T) {
+	script := `ls /nonexistent_aish_exitcode_dir
+echo "rc=$?"
+(exit 3)
+echo "rc=$?"
+true
+echo "rc=$?"
+false | (exit 4)
+echo "ps=${PIPESTATUS[*]}"
+skipme_cmd() { echo "No such file or directory" >&2; return 6; }
+skipme_cmd
+echo "rc=$?"
+(exit 130)
+echo "rc=$?"`
+
+	out, calls := runHookedShell(t, script)
+
+	expected := []string{"rc=2", "rc=3", "rc=0", "ps=1 4", "rc=6", "rc=130"}
+	got := markerLines(out)
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Exit status changed by hook\nexpected: %v\ngot:      %v\noutput:\n%s", expected, got, out)
+	}
+
+	// The hook must actually have fired, otherwise the assertions above prove nothing.
+	if !strings.Contains(calls, "capture 2 ls /nonexistent_aish_exitcode_dir") {
+		t.Errorf("Expected aish capture to be invoked for the failing ls, got calls:\n%s", calls)
+	}
+	if strings.Contains(calls, "skipme_cmd") {
+		t.Errorf("Skipped command should not trigger aish capture, got calls:\n%s", calls)
+	}
+	if strings.Contains(calls, "capture 130") {
+		t.Errorf("User cancellation should not trigger aish capture, got calls:\n%s", calls)
+	}
+}
+
+func TestHookPreservesExitCodeWhenAishMissing(t *testing.T) {
+	script := `rm -f "$AISH_STATE_DIR/../bin/aish"
+hash -r
+ls /nonexistent_aish_exitcode_dir
+echo "rc=$?"`
+
+	out, calls := runHookedShell(t, script)
+
+	got := markerLines(out)
+	if len(got) != 1 || got[0] != "rc=2" {
+		t.Fatalf("Expected rc=2 without aish on PATH, got %v\noutput:\n%s", got, out)
+	}
+	if calls != "" {
+		t.Errorf("Expected no aish invocations, got:\n%s", calls)
+	}
+}
+
+func TestHookReportsPipelineStatuses(t *testing.T) {
+	script := `ls /nonexistent_aish_pipe_dir | grep -q zzz
+echo "rc=$?"`
+
+	out, calls := runHookedShell(t, script)
+
+	got := markerLines(out)
+	if len(got) != 1 || got[0] != "rc=1" {
+		t.Fatalf("Expected rc=1 from the pipeline, got %v\noutput:\n%s", got, out)
+	}
+	if !strings.Contains(calls, "capture 1 ls /nonexistent_aish_pipe_dir | grep -q zzz [2 1]") {
+		t.Errorf("Expected the whole pipeline and its stage statuses, got calls:\n%s", calls)
+	}
+}
+
+func TestHookReportsAliasAndFunctionExpansion(t *testing.T) {
+	script := `alias lsx='ls /nonexistent_aish_alias_dir'
+lsx
+broken() { ls /nonexistent_aish_func_dir; }
+broken
+echo "rc=$?"`
+
+	_, calls := runHookedShell(t, script)
+
+	if !strings.Contains(calls, "expansion: alias lsx='ls /nonexistent_aish_alias_dir'") {
+		t.Errorf("Expected the alias expansion to be passed to aish, got calls:\n%s", calls)
+	}
+	if !strings.Contains(calls, "capture 2 broken") || !strings.Contains(calls, "ls /nonexistent_aish_func_dir") {
+		t.Errorf("Expected the function body to be passed to aish, got calls:\n%s", calls)
+	}
+}
+
+// TestHookStateDirMatchesConfig checks that the hook writes its capture files where
+// config.HookStateDir, and with it 'aish clean', looks for them.
+func TestHookStateDirMatchesConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash hook harness is not supported on windows")
+	}
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	hookCode, err := getHookCode()
+	if err != nil {
+		t.Fatalf("Failed to get hook code: %v", err)
+	}
+
+	root := t.TempDir()
+	cases := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"default", nil},
+		{"xdg state", map[string]string{"XDG_STATE_HOME": filepath.Join(root, "state")}},
+		{"xdg config", map[string]string{"XDG_CONFIG_HOME": filepath.Join(root, "config")}},
+		{"relative xdg", map[string]string{"XDG_STATE_HOME": "relative", "XDG_CONFIG_HOME": filepath.Join(root, "config")}},
+		{"config dir", map[string]string{"AISH_CONFIG_DIR": filepath.Join(root, "isolated"), "XDG_STATE_HOME": filepath.Join(root, "state")}},
+		{"state dir", map[string]string{"AISH_STATE_DIR": filepath.Join(root, "hook"), "AISH_CONFIG_DIR": filepath.Join(root, "isolated")}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("HOME", filepath.Join(root, "home"))
+			for _, name := range []string{"AISH_STATE_DIR", "AISH_CONFIG_DIR", "XDG_STATE_HOME", "XDG_CONFIG_HOME"} {
+				t.Setenv(name, c.env[name])
+			}
+			want, err := config.HookStateDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(bash, "--norc", "--noprofile", "-c", hookCode+"\nprintf '%s' \"$AISH_STATE_DIR\"")
+			cmd.Env = append(os.Environ(), "AISH_HOOK_DISABLED=1")
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("bash failed: %v", err)
+			}
+			if got := string(out); got != want {
+				t.Errorf("hook writes to %q, config.HookStateDir() = %q", got, want)
+			}
+		})
+	}
+}
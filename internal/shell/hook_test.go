@@ -36,6 +36,70 @@ func TestGetHookCode(t *testing.T) {
 	}
 }
 
+func TestHookScriptPowerShell(t *testing.T) {
+	for _, name := range []string{"powershell", "pwsh"} {
+		script, err := HookScript(name)
+		if err != nil {
+			t.Fatalf("HookScript(%q): %v", name, err)
+		}
+		for _, want := range []string{
+			hookStartMarker,
+			hookEndMarker,
+			"$env:AISH_STDOUT_FILE",
+			"$env:AISH_STDERR_FILE",
+			"$global:LASTEXITCODE",
+			"Get-History",
+			"function global:Out-Default",
+			"capture $exitCode",
+			"__aish_QuoteArg $command", // the command line must reach aish as one argument
+		} {
+			if !strings.Contains(script, want) {
+				t.Errorf("HookScript(%q) missing %q", name, want)
+			}
+		}
+	}
+
+	if _, err := HookScript("nu"); err == nil {
+		t.Error("HookScript(nu) should fail")
+	}
+}
+
+func TestInstallFishHook(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if err := installFishHook(home); err != nil {
+		t.Fatalf("installFishHook: %v", err)
+	}
+	configPath := filepath.Join(home, ".config", "fish", "config.fish")
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("config.fish not written: %v", err)
+	}
+	for _, want := range []string{
+		hookStartMarker,
+		"--on-event fish_preexec",
+		"--on-event fish_postexec",
+		"AISH_STDOUT_FILE=$__aish_stdout_file",
+		"AISH_STDERR_FILE=$__aish_stderr_file",
+		"aish capture $exit_code",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("fish hook missing %q", want)
+		}
+	}
+	// POSIX-only syntax would make fish refuse the whole config file
+	for _, bad := range []string{"${", "$(", "[ ", "export "} {
+		if strings.Contains(extractHookBlock(string(content)), bad) {
+			t.Errorf("fish hook contains POSIX syntax %q", bad)
+		}
+	}
+
+	removed, err := removeHookFromFile(configPath)
+	if err != nil || !removed {
+		t.Fatalf("removeHookFromFile = %v, %v", removed, err)
+	}
+}
+
 func TestAddHookToFile(t *testing.T) {
 	// Create a temporary file
 	tmpDir, err := os.MkdirTemp("", "aish_test")
@@ -200,3 +264,23 @@ func TestRemoveHookFromFileWithoutHook(t *testing.T) {
 		t.Error("Expected removed=false for file without hook")
 	}
 }
+
+func TestPowerShellExecutable(t *testing.T) {
+	found := func(string) (string, error) { return "powershell.exe", nil }
+	missing := func(name string) (string, error) { return "", os.ErrNotExist }
+
+	for _, c := range []struct {
+		shell    string
+		lookPath func(string) (string, error)
+		want     string
+	}{
+		{"pwsh", found, "pwsh"},
+		{"powershell", missing, "powershell"},
+		{"", found, "powershell"},
+		{"", missing, "pwsh"},
+	} {
+		if got := powerShellExecutable(c.shell, c.lookPath); got != c.want {
+			t.Errorf("powerShellExecutable(%q) = %q, want %q", c.shell, got, c.want)
+		}
+	}
+}
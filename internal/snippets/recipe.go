@@ -0,0 +1,127 @@
+package snippets
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// paramRE matches a <param> placeholder in a recipe pattern or command.
+var paramRE = regexp.MustCompile(`<([A-Za-z][A-Za-z0-9_-]*)>`)
+
+// Params returns the names of the <param> placeholders in s, in order.
+func Params(s string) []string {
+	var names []string
+	for _, m := range paramRE.FindAllStringSubmatch(s, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// checkRecipe verifies that a recipe's pattern names each parameter once and that its command
+// only uses parameters the pattern provides.
+func checkRecipe(sn Snippet) error {
+	if sn.Pattern == "" {
+		return nil
+	}
+	provided := map[string]bool{}
+	for _, p := range Params(sn.Pattern) {
+		if provided[p] {
+			return fmt.Errorf("pattern uses <%s> more than once", p)
+		}
+		provided[p] = true
+	}
+	for _, p := range Params(sn.Command) {
+		if !provided[p] {
+			return fmt.Errorf("command uses <%s>, which the pattern %q does not provide", p, sn.Pattern)
+		}
+	}
+	return nil
+}
+
+// patternRegexp turns a recipe pattern into an anchored, case-insensitive expression in which
+// runs of spaces match any whitespace and each <param> captures at least one character.
+func patternRegexp(pattern string) (*regexp.Regexp, []string) {
+	var b strings.Builder
+	b.WriteString(`(?i)^\s*`)
+	last := 0
+	for _, loc := range paramRE.FindAllStringIndex(pattern, -1) {
+		b.WriteString(literalRegexp(pattern[last:loc[0]]))
+		b.WriteString(`(.+?)`)
+		last = loc[1]
+	}
+	b.WriteString(literalRegexp(pattern[last:]))
+	b.WriteString(`\s*$`)
+	return regexp.MustCompile(b.String()), Params(pattern)
+}
+
+func literalRegexp(text string) string {
+	if text != "" && strings.TrimSpace(text) == "" {
+		return `\s+`
+	}
+	parts := strings.Fields(text)
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	re := strings.Join(parts, `\s+`)
+	if strings.TrimLeft(text, " \t") != text {
+		re = `\s+` + re
+	}
+	if strings.TrimRight(text, " \t") != text {
+		re += `\s+`
+	}
+	return re
+}
+
+// Expand returns the first recipe whose pattern matches prompt and its command with the
+// parameters filled in. Values are shell-quoted, so a prompt cannot inject shell syntax
+// through a parameter.
+func (s *Store) Expand(prompt string) (Snippet, string, bool) {
+	for _, sn := range s.Snippets {
+		if sn.Pattern == "" {
+			continue
+		}
+		if command, ok := sn.expand(prompt); ok {
+			return sn, command, true
+		}
+	}
+	return Snippet{}, "", false
+}
+
+func (sn Snippet) expand(prompt string) (string, bool) {
+	re, names := patternRegexp(sn.Pattern)
+	m := re.FindStringSubmatch(prompt)
+	if m == nil {
+		return "", false
+	}
+	values := map[string]string{}
+	for i, name := range names {
+		values[name] = unquote(strings.TrimSpace(m[i+1]))
+	}
+	return paramRE.ReplaceAllStringFunc(sn.Command, func(ph string) string {
+		return quoteArg(values[ph[1:len(ph)-1]])
+	}), true
+}
+
+// unquote removes one pair of matching quotes around a value typed in a prompt.
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// quoteArg single-quotes v for a POSIX shell unless it only holds characters that are safe
+// unquoted. A leading "~/" stays unquoted so the shell still expands it.
+func quoteArg(v string) string {
+	if rest, ok := strings.CutPrefix(v, "~/"); ok {
+		if rest == "" {
+			return "~/"
+		}
+		return "~/" + quoteArg(rest)
+	}
+	if v != "" && strings.Trim(v, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:@%+=,") == "" {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
@@ -0,0 +1,54 @@
+package snippets
+
+import "testing"
+
+func TestRecipeValidation(t *testing.T) {
+	s := &Store{}
+	bad := []Snippet{
+		{Name: "backup", Command: "rsync -a <dir> <dest>", Pattern: "backup <dir>"},
+		{Name: "twice", Command: "cp <a> <a>", Pattern: "copy <a> to <a>"},
+	}
+	for _, sn := range bad {
+		if err := s.Add(sn, false); err == nil {
+			t.Errorf("Add(%+v) succeeded, want an error", sn)
+		}
+	}
+	if err := s.Add(Snippet{Name: "backup", Command: "rsync -a <dir>/ <dest>", Pattern: "backup <dir> to <dest>"}, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	s := &Store{}
+	_ = s.Add(Snippet{Name: "backup", Command: "rsync -a <dir>/ <dest>", Pattern: "backup <dir> to <dest>"}, false)
+	_ = s.Add(Snippet{Name: "tail-log", Command: "tail -f /var/log/<service>.log", Pattern: "follow the <service> log"}, false)
+	_ = s.Add(Snippet{Name: "deploy the app", Command: "make deploy"}, false)
+
+	cases := []struct {
+		prompt string
+		want   string
+	}{
+		{"backup src to /mnt/backup", "rsync -a src/ /mnt/backup"},
+		{"  Backup   ~/my docs  to  /mnt/usb ", "rsync -a ~/'my docs'/ /mnt/usb"},
+		{`backup "a b" to 'c'`, "rsync -a 'a b'/ c"},
+		{"backup x;rm -rf / to y", `rsync -a 'x;rm -rf /'/ y`},
+		{"backup it's to there", `rsync -a 'it'\''s'/ there`},
+		{"follow the nginx log", "tail -f /var/log/nginx.log"},
+		{"backup src", ""},
+		{"please backup src to dst now", ""},
+		{"deploy the app", ""}, // Plain snippets are not recipes
+	}
+	for _, c := range cases {
+		sn, got, ok := s.Expand(c.prompt)
+		switch {
+		case c.want == "" && ok:
+			t.Errorf("Expand(%q) = %q from %s, want no match", c.prompt, got, sn.Name)
+		case c.want != "" && got != c.want:
+			t.Errorf("Expand(%q) = %q, %v, want %q", c.prompt, got, ok, c.want)
+		}
+	}
+
+	if _, _, ok := s.Match("backup src to dst"); ok {
+		t.Error("Match offered a recipe, whose command still has placeholders")
+	}
+}
@@ -0,0 +1,288 @@
+// Package snippets stores commands the user wants to keep under a name, with optional tags,
+// so they can be listed, searched and offered again when a prompt asks for the same thing.
+// A snippet with a pattern is a recipe: a parameterized command such as "backup <dir> to
+// <dest>" that is expanded locally, without a provider, when a prompt matches the pattern.
+package snippets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+const (
+	snippetsFile      = "snippets.json"
+	lastGeneratedFile = "last_generated.json"
+)
+
+// MatchThreshold is the similarity (0..1) from which a prompt counts as asking for a snippet.
+const MatchThreshold = 0.75
+
+// ErrExists is returned by Add when a snippet with the name already exists.
+var ErrExists = errors.New("a snippet with this name already exists")
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Snippet is a saved command. Description is usually the prompt that generated it. When
+// Pattern is set the snippet is a recipe: <param> placeholders in Command are filled in from
+// the matching parts of a prompt.
+type Snippet struct {
+	Name        string    `json:"name"`
+	Command     string    `json:"command"`
+	Pattern     string    `json:"pattern,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store is the snippet library, kept in the config directory since it is curated by the user.
+type Store struct {
+	Snippets []Snippet `json:"snippets"`
+	path     string
+}
+
+// Path returns where the snippet library is kept.
+func Path() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, snippetsFile), nil
+}
+
+// Load reads the library from path. A missing file is an empty library; a corrupt one is an
+// error, so saving does not overwrite snippets the user may want to recover.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the library back to the file it was loaded from.
+func (s *Store) Save() error {
+	return writeJSON(s.path, s)
+}
+
+// Add saves sn, replacing a snippet of the same name only when replace is set.
+func (s *Store) Add(sn Snippet, replace bool) error {
+	if !validName.MatchString(sn.Name) {
+		return fmt.Errorf("invalid snippet name %q: use letters, digits, '.', '_' and '-'", sn.Name)
+	}
+	if strings.TrimSpace(sn.Command) == "" {
+		return errors.New("snippet command is empty")
+	}
+	sn.Command = strings.TrimSpace(sn.Command)
+	sn.Pattern = strings.TrimSpace(sn.Pattern)
+	sn.Tags = normalizeTags(sn.Tags)
+	if err := checkRecipe(sn); err != nil {
+		return err
+	}
+	for i := range s.Snippets {
+		if s.Snippets[i].Name == sn.Name {
+			if !replace {
+				return ErrExists
+			}
+			s.Snippets[i] = sn
+			return nil
+		}
+	}
+	s.Snippets = append(s.Snippets, sn)
+	sort.Slice(s.Snippets, func(i, j int) bool { return s.Snippets[i].Name < s.Snippets[j].Name })
+	return nil
+}
+
+// Get returns the snippet called name.
+func (s *Store) Get(name string) (Snippet, bool) {
+	for _, sn := range s.Snippets {
+		if sn.Name == name {
+			return sn, true
+		}
+	}
+	return Snippet{}, false
+}
+
+// Remove deletes the snippet called name and reports whether it existed.
+func (s *Store) Remove(name string) bool {
+	for i, sn := range s.Snippets {
+		if sn.Name == name {
+			s.Snippets = append(s.Snippets[:i], s.Snippets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// WithTag returns the snippets tagged tag, or all of them when tag is empty.
+func (s *Store) WithTag(tag string) []Snippet {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	var out []Snippet
+	for _, sn := range s.Snippets {
+		if tag == "" || contains(sn.Tags, tag) {
+			out = append(out, sn)
+		}
+	}
+	return out
+}
+
+// Search returns the snippets whose name, tags, description or command contain every word
+// of query, case-insensitively.
+func (s *Store) Search(query string) []Snippet {
+	words := strings.Fields(strings.ToLower(query))
+	var out []Snippet
+	for _, sn := range s.Snippets {
+		text := strings.ToLower(strings.Join([]string{sn.Name, strings.Join(sn.Tags, " "), sn.Description, sn.Command}, " "))
+		matched := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			out = append(out, sn)
+		}
+	}
+	return out
+}
+
+// Match returns the snippet that prompt most closely asks for, with its similarity, when
+// that reaches MatchThreshold. A prompt that is exactly a snippet's name always matches.
+// Recipes are not considered: their commands need parameters (see Expand).
+func (s *Store) Match(prompt string) (Snippet, float64, bool) {
+	var best Snippet
+	bestScore := 0.0
+	for _, sn := range s.Snippets {
+		if sn.Pattern != "" {
+			continue
+		}
+		if score := similarity(prompt, sn); score > bestScore {
+			best, bestScore = sn, score
+		}
+	}
+	return best, bestScore, bestScore >= MatchThreshold
+}
+
+// similarity scores how well prompt describes sn: 1 for its exact name, otherwise the Dice
+// coefficient between the prompt's words and those of the snippet's description, name and tags.
+func similarity(prompt string, sn Snippet) float64 {
+	if strings.EqualFold(strings.TrimSpace(prompt), sn.Name) {
+		return 1
+	}
+	a := wordSet(prompt)
+	b := wordSet(strings.Join([]string{sn.Description, sn.Name, strings.Join(sn.Tags, " ")}, " "))
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// stopWords carry no meaning for matching a prompt to a snippet.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "to": true, "of": true, "in": true, "on": true, "for": true,
+	"and": true, "or": true, "with": true, "my": true, "me": true, "all": true, "this": true,
+	"please": true, "how": true, "do": true, "i": true, "can": true,
+}
+
+func wordSet(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !stopWords[w] {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Generated is the most recent command aish generated, which 'aish snippet save' keeps when
+// no command is given.
+type Generated struct {
+	Prompt  string    `json:"prompt"`
+	Command string    `json:"command"`
+	At      time.Time `json:"at"`
+}
+
+// LastGeneratedPath returns where the most recent generated command is remembered.
+func LastGeneratedPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, lastGeneratedFile), nil
+}
+
+// RememberGenerated records g as the most recent generated command.
+func RememberGenerated(path string, g Generated) error {
+	return writeJSON(path, g)
+}
+
+// LastGenerated returns the most recent generated command, if one was recorded.
+func LastGenerated(path string) (Generated, bool) {
+	var g Generated
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &g) != nil || strings.TrimSpace(g.Command) == "" {
+		return Generated{}, false
+	}
+	return g, true
+}
+
+func writeJSON(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
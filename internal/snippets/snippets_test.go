@@ -0,0 +1,111 @@
+package snippets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreAddSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippets.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deploy := Snippet{Name: "deploy-prod", Command: " kubectl apply -f k8s/prod ", Tags: []string{"K8s", "prod", "k8s"}}
+	if err := s.Add(deploy, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Snippet{Name: "disk", Command: "du -sh ."}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Snippet{Name: "deploy-prod", Command: "true"}, false); !errors.Is(err, ErrExists) {
+		t.Errorf("duplicate Add err = %v, want ErrExists", err)
+	}
+	for _, bad := range []Snippet{{Name: "has space", Command: "ls"}, {Name: "-x", Command: "ls"}, {Name: "empty", Command: " "}} {
+		if err := s.Add(bad, true); err == nil {
+			t.Errorf("Add(%+v) succeeded, want an error", bad)
+		}
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := loaded.Get("deploy-prod")
+	if !ok || got.Command != "kubectl apply -f k8s/prod" || len(got.Tags) != 2 || got.Tags[0] != "k8s" {
+		t.Errorf("loaded snippet = %+v, %v", got, ok)
+	}
+	if names := []string{loaded.Snippets[0].Name, loaded.Snippets[1].Name}; names[0] != "deploy-prod" || names[1] != "disk" {
+		t.Errorf("snippets not sorted by name: %v", names)
+	}
+	if n := len(loaded.WithTag("PROD")); n != 1 {
+		t.Errorf("WithTag(PROD) = %d snippets, want 1", n)
+	}
+	if !loaded.Remove("disk") || loaded.Remove("disk") {
+		t.Error("Remove should report whether the snippet existed")
+	}
+}
+
+func TestLoadCorruptIsAnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippets.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load of a corrupt library succeeded; saving would overwrite it")
+	}
+}
+
+func TestSearchAndMatch(t *testing.T) {
+	s := &Store{}
+	_ = s.Add(Snippet{Name: "deploy-prod", Command: "kubectl apply -f k8s/prod", Description: "deploy the app to production", Tags: []string{"k8s"}}, false)
+	_ = s.Add(Snippet{Name: "big-files", Command: "du -ah . | sort -rh | head -20", Description: "list the biggest files in this directory"}, false)
+
+	if got := s.Search("K8S apply"); len(got) != 1 || got[0].Name != "deploy-prod" {
+		t.Errorf("Search = %v", got)
+	}
+	if got := s.Search("nothing-like-this"); len(got) != 0 {
+		t.Errorf("Search = %v, want none", got)
+	}
+
+	cases := []struct {
+		prompt string
+		want   string
+	}{
+		{"deploy-prod", "deploy-prod"},
+		{"Deploy the app to production", "deploy-prod"},
+		{"list biggest files in this directory", "big-files"},
+		{"list files", ""},
+		{"deploy the app to staging with helm", ""},
+	}
+	for _, c := range cases {
+		sn, score, ok := s.Match(c.prompt)
+		switch {
+		case c.want == "" && ok:
+			t.Errorf("Match(%q) = %s (%.2f), want no match", c.prompt, sn.Name, score)
+		case c.want != "" && (!ok || sn.Name != c.want):
+			t.Errorf("Match(%q) = %s (%.2f, %v), want %s", c.prompt, sn.Name, score, ok, c.want)
+		}
+	}
+}
+
+func TestLastGenerated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "last_generated.json")
+	if _, ok := LastGenerated(path); ok {
+		t.Error("LastGenerated reported a command before any was recorded")
+	}
+	g := Generated{Prompt: "disk usage", Command: "du -sh .", At: time.Now()}
+	if err := RememberGenerated(path, g); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := LastGenerated(path)
+	if !ok || got.Command != g.Command || got.Prompt != g.Prompt {
+		t.Errorf("LastGenerated = %+v, %v", got, ok)
+	}
+}
@@ -0,0 +1,124 @@
+// Package telemetry keeps opt-in, anonymous feature-usage counters. Counts are aggregated in a
+// file in the state directory and never leave the machine: the package has no network code, and
+// the only way to see them is 'aish stats --features'.
+package telemetry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+const countersFile = "feature_usage.json"
+
+// Counters is the aggregated usage: how often each feature ran since counting started. Only
+// feature names are recorded, never arguments, commands, paths or output.
+type Counters struct {
+	Since    time.Time        `json:"since"`
+	Features map[string]int64 `json:"features"`
+}
+
+// FeatureCount is one row of Counters.Sorted.
+type FeatureCount struct {
+	Feature string
+	Count   int64
+}
+
+// Enabled reports whether the user opted in to feature counters.
+func Enabled(cfg *config.Config) bool {
+	return cfg != nil && cfg.UserPreferences.Telemetry.Enabled
+}
+
+// Path returns where the counters are kept.
+func Path() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, countersFile), nil
+}
+
+// Load reads the counters; a missing or unreadable file is empty.
+func Load(path string) Counters {
+	var c Counters
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &c)
+	}
+	if c.Features == nil {
+		c.Features = map[string]int64{}
+	}
+	return c
+}
+
+// Increment adds one use of feature. Concurrent aish processes may occasionally lose a count,
+// which is acceptable for usage statistics and avoids taking a lock on every command.
+func Increment(path, feature string, now time.Time) error {
+	feature = normalizeFeature(feature)
+	if feature == "" {
+		return nil
+	}
+	c := Load(path)
+	if c.Since.IsZero() {
+		c.Since = now
+	}
+	c.Features[feature]++
+	return save(path, c)
+}
+
+// Reset removes all counters.
+func Reset(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Sorted returns the counters from most to least used, ties by name.
+func (c Counters) Sorted() []FeatureCount {
+	rows := make([]FeatureCount, 0, len(c.Features))
+	for f, n := range c.Features {
+		rows = append(rows, FeatureCount{Feature: f, Count: n})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Feature < rows[j].Feature
+	})
+	return rows
+}
+
+// normalizeFeature keeps feature names to a short, fixed vocabulary of lower-case words so a
+// caller cannot accidentally record free-form user input.
+func normalizeFeature(feature string) string {
+	feature = strings.ToLower(strings.TrimSpace(feature))
+	if len(feature) > 64 {
+		return ""
+	}
+	for _, r := range feature {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == ' ' || r == '-' || r == '_' || r == '.') {
+			return ""
+		}
+	}
+	return feature
+}
+
+func save(path string, c Counters) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func TestIncrementAggregatesLocally(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", countersFile)
+	now := time.Now()
+
+	for _, f := range []string{"history", "capture", "capture", "Config Set"} {
+		if err := Increment(path, f, now); err != nil {
+			t.Fatalf("Increment(%q): %v", f, err)
+		}
+	}
+	c := Load(path)
+	if c.Features["capture"] != 2 || c.Features["history"] != 1 || c.Features["config set"] != 1 {
+		t.Fatalf("unexpected counters: %v", c.Features)
+	}
+	if !c.Since.Equal(now) {
+		t.Errorf("Since = %v, want %v", c.Since, now)
+	}
+	rows := c.Sorted()
+	if rows[0].Feature != "capture" || rows[1].Feature != "config set" {
+		t.Errorf("Sorted = %v", rows)
+	}
+
+	if err := Reset(path); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if len(Load(path).Features) != 0 {
+		t.Error("counters survived Reset")
+	}
+}
+
+func TestIncrementIgnoresFreeFormInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), countersFile)
+	for _, f := range []string{"", "rm -rf /home/alice", "curl https://x", strings.Repeat("a", 65)} {
+		_ = Increment(path, f, time.Now())
+	}
+	if got := Load(path).Features; len(got) != 0 {
+		t.Errorf("free-form input was recorded: %v", got)
+	}
+}
+
+func TestDisabledByDefault(t *testing.T) {
+	cfg, err := config.FromTemplate([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Enabled(cfg) || Enabled(nil) {
+		t.Error("feature counters must be off unless the user opts in")
+	}
+}
+
+// TestNoNetworkAccess keeps the package free of anything that could send the counters
+// anywhere: aggregation is strictly local.
+func TestNoNetworkAccess(t *testing.T) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", nil, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		for name, file := range pkg.Files {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			for _, imp := range file.Imports {
+				path, _ := strconv.Unquote(imp.Path.Value)
+				if path == "net" || strings.HasPrefix(path, "net/") || path == "os/exec" || strings.Contains(path, "internal/llm") {
+					t.Errorf("%s imports %s; telemetry must not send data anywhere", name, path)
+				}
+			}
+		}
+	}
+}
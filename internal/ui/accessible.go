@@ -0,0 +1,400 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// screenReaderMode replaces cursor-driven widgets (pterm interactive printers, bubbletea)
+// with linear prompts that read one line at a time and describe every choice in words.
+var screenReaderMode bool
+
+// animationsDisabled replaces spinners and live elapsed-time counters with a single static
+// line, for users with vestibular disorders and terminals that cannot redraw in place.
+var animationsDisabled bool
+
+// linearReader is shared by all linear prompts so buffered stdin input is never lost between questions.
+var linearReader *bufio.Reader
+
+// SetScreenReaderMode enables or disables the screen-reader friendly UI.
+// Enabling it also turns on plain output, since spinners and colors are noise to a screen reader.
+func SetScreenReaderMode(enabled bool) {
+	screenReaderMode = enabled
+	if enabled {
+		SetPlainOutput(true)
+	}
+}
+
+// SetAnimations enables or disables spinners and live timers.
+func SetAnimations(enabled bool) {
+	animationsDisabled = !enabled
+}
+
+// AnimationsEnabled reports whether spinners and live timers are shown.
+func AnimationsEnabled() bool {
+	return !animationsDisabled && !plainOutput
+}
+
+// IsScreenReaderMode reports whether the screen-reader friendly UI is active.
+func IsScreenReaderMode() bool {
+	return screenReaderMode
+}
+
+// AskConfirm asks a yes/no question.
+func AskConfirm(question string, defaultValue bool) (bool, error) {
+	if !screenReaderMode {
+		return pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(defaultValue).
+			Show(question)
+	}
+
+	hint := "yes or no, default is no"
+	if defaultValue {
+		hint = "yes or no, default is yes"
+	}
+	for {
+		answer, err := readLinearLine(fmt.Sprintf("%s (%s): ", question, hint))
+		if err != nil {
+			return defaultValue, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return defaultValue, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Println("Please answer yes or no.")
+	}
+}
+
+// AskSelect asks the user to pick one option from a list.
+func AskSelect(question string, options []string, defaultOption string) (string, error) {
+	if !screenReaderMode {
+		return pterm.DefaultInteractiveSelect.
+			WithOptions(options).
+			WithDefaultOption(defaultOption).
+			Show(question)
+	}
+	if len(options) == 0 {
+		return "", fmt.Errorf("no options provided")
+	}
+
+	defaultIndex := indexOf(options, defaultOption)
+	fmt.Printf("%s. %d options:\n", question, len(options))
+	for i, opt := range options {
+		suffix := ""
+		if i == defaultIndex {
+			suffix = " (default)"
+		}
+		fmt.Printf("  Option %d: %s%s\n", i+1, opt, suffix)
+	}
+
+	prompt := fmt.Sprintf("Enter a number from 1 to %d: ", len(options))
+	if defaultIndex >= 0 {
+		prompt = fmt.Sprintf("Enter a number from 1 to %d, or press Enter for %s: ", len(options), options[defaultIndex])
+	}
+	for {
+		answer, err := readLinearLine(prompt)
+		if err != nil {
+			return defaultOption, err
+		}
+		if answer == "" && defaultIndex >= 0 {
+			fmt.Printf("Selected %s.\n", options[defaultIndex])
+			return options[defaultIndex], nil
+		}
+		if idx := parseChoice(answer, options); idx >= 0 {
+			fmt.Printf("Selected %s.\n", options[idx])
+			return options[idx], nil
+		}
+		fmt.Printf("%q is not a valid choice.\n", answer)
+	}
+}
+
+// AskText asks for a line of free text. Masked input is not echoed.
+func AskText(question, defaultValue string, masked bool) (string, error) {
+	if !screenReaderMode {
+		input := pterm.DefaultInteractiveTextInput.WithDefaultValue(defaultValue)
+		if masked {
+			input = input.WithMask("*")
+		}
+		return input.Show(question)
+	}
+
+	prompt := question + ": "
+	if defaultValue != "" && !masked {
+		prompt = fmt.Sprintf("%s (press Enter to keep %s): ", question, defaultValue)
+	}
+	var (
+		answer string
+		err    error
+	)
+	if masked && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Print(prompt + "(input is hidden) ")
+		var raw []byte
+		raw, err = term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		answer = strings.TrimSpace(string(raw))
+	} else {
+		answer, err = readLinearLine(prompt)
+	}
+	if err != nil {
+		return defaultValue, err
+	}
+	if answer == "" {
+		return defaultValue, nil
+	}
+	return answer, nil
+}
+
+// askMultiSelectLinear lists options with their state and accepts a comma separated list of numbers.
+func askMultiSelectLinear(question string, options []string, defaultOptions []string) ([]string, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no options provided")
+	}
+
+	fmt.Printf("%s. %d options:\n", question, len(options))
+	for i, opt := range options {
+		state := "not selected"
+		if indexOf(defaultOptions, opt) >= 0 {
+			state = "selected"
+		}
+		fmt.Printf("  Option %d: %s, %s\n", i+1, opt, state)
+	}
+	for {
+		answer, err := readLinearLine("Enter the numbers to select, separated by commas, 'none' to clear, or press Enter to keep the current selection: ")
+		if err != nil {
+			return defaultOptions, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return defaultOptions, nil
+		case "none":
+			fmt.Println("Selection cleared.")
+			return []string{}, nil
+		}
+
+		var result []string
+		valid := true
+		for _, part := range strings.Split(answer, ",") {
+			idx := parseChoice(strings.TrimSpace(part), options)
+			if idx < 0 {
+				fmt.Printf("%q is not a valid choice.\n", strings.TrimSpace(part))
+				valid = false
+				break
+			}
+			if indexOf(result, options[idx]) < 0 {
+				result = append(result, options[idx])
+			}
+		}
+		if valid {
+			fmt.Printf("Selected %d of %d: %s.\n", len(result), len(options), strings.Join(result, ", "))
+			return result, nil
+		}
+	}
+}
+
+// runLinearSettings is the screen-reader replacement for the settings TUI.
+// Every interactive item is listed with its current value; the user picks one by number until done.
+func runLinearSettings(cfg *config.Config) error {
+	for {
+		settings := GetSettingsDefinition(cfg)
+		var items []*SettingItem
+		fmt.Println("Settings. Enter a number to change a setting, or press Enter to save and exit.")
+		for _, item := range settings {
+			if item.Type == SettingTypeGroup {
+				if name := strings.TrimSpace(item.DisplayName); name != "" && name != "Settings" {
+					fmt.Printf("Section: %s\n", name)
+				}
+				continue
+			}
+			items = append(items, item)
+			fmt.Printf("  %d. %s: %s\n", len(items), item.DisplayName, describeSettingValue(cfg, item))
+		}
+
+		answer, err := readLinearLine("Setting number: ")
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if answer == "" || strings.EqualFold(answer, "q") {
+			return nil
+		}
+		n, convErr := strconv.Atoi(answer)
+		if convErr != nil || n < 1 || n > len(items) {
+			fmt.Printf("%q is not a valid setting number.\n", answer)
+			continue
+		}
+		if err := editSettingLinear(cfg, items[n-1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+	}
+}
+
+// editSettingLinear changes a single setting through linear prompts.
+func editSettingLinear(cfg *config.Config, item *SettingItem) error {
+	switch item.Type {
+	case SettingTypeBoolean:
+		current, _ := item.GetValue(cfg).(bool)
+		value, err := AskConfirm(fmt.Sprintf("Enable %s? Currently %s", item.DisplayName, onOff(current)), current)
+		if err != nil {
+			return err
+		}
+		item.SetValue(cfg, value)
+		fmt.Printf("%s is now %s.\n", item.DisplayName, onOff(value))
+	case SettingTypeSelect:
+		current, _ := item.GetValue(cfg).(string)
+		var names []string
+		defaultName := ""
+		for _, opt := range item.Options {
+			names = append(names, opt.DisplayName)
+			if opt.Value == current {
+				defaultName = opt.DisplayName
+			}
+		}
+		chosen, err := AskSelect(item.DisplayName, names, defaultName)
+		if err != nil {
+			return err
+		}
+		item.SetValue(cfg, item.Options[indexOf(names, chosen)].Value)
+	case SettingTypeText:
+		if item.SetValue == nil {
+			fmt.Printf("%s is read-only.\n", item.DisplayName)
+			return nil
+		}
+		masked := strings.Contains(item.ID, "api_key")
+		current := ""
+		if !masked {
+			current, _ = item.GetValue(cfg).(string)
+		}
+		value, err := AskText(item.DisplayName, current, masked)
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			item.SetValue(cfg, value)
+			fmt.Printf("%s updated.\n", item.DisplayName)
+		}
+	case SettingTypeAction:
+		if item.ID == "user_preferences.enabled_llm_triggers" {
+			selected, err := askMultiSelectLinear("Error types that trigger AI analysis", defaultTriggerOptions(), cfg.UserPreferences.EnabledLLMTriggers)
+			if err != nil {
+				return err
+			}
+			cfg.UserPreferences.EnabledLLMTriggers = selected
+			return nil
+		}
+		if item.Confirm != "" {
+			ok, err := AskConfirm(item.Confirm, false)
+			if err != nil || !ok {
+				return err
+			}
+		}
+		if item.Run != nil {
+			result, err := item.Run()
+			if err == nil {
+				fmt.Println(result)
+			}
+			return err
+		}
+		if item.Action != nil {
+			return item.Action()
+		}
+	default:
+		fmt.Printf("%s is read-only.\n", item.DisplayName)
+	}
+	return nil
+}
+
+// describeSettingValue returns the spoken form of a setting's current value.
+func describeSettingValue(cfg *config.Config, item *SettingItem) string {
+	if item.GetValue == nil {
+		return "action"
+	}
+	switch v := item.GetValue(cfg).(type) {
+	case bool:
+		return onOff(v)
+	case string:
+		for _, opt := range item.Options {
+			if opt.Value == v {
+				return opt.DisplayName
+			}
+		}
+		if v == "" {
+			return "not set"
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// defaultTriggerOptions lists the error types offered in the trigger selection, matching the TUI.
+func defaultTriggerOptions() []string {
+	return []string{
+		"CommandNotFound",
+		"FileNotFoundOrDirectory",
+		"PermissionDenied",
+		"CannotExecute",
+		"InvalidArgumentOrOption",
+		"ResourceExists",
+		"NotADirectory",
+		"TerminatedBySignal",
+		"GenericError",
+	}
+}
+
+func readLinearLine(prompt string) (string, error) {
+	if linearReader == nil {
+		linearReader = bufio.NewReader(os.Stdin)
+	}
+	fmt.Print(prompt)
+	line, err := linearReader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// parseChoice accepts either a 1-based number or the exact option text (case-insensitive).
+func parseChoice(answer string, options []string) int {
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n >= 1 && n <= len(options) {
+			return n - 1
+		}
+		return -1
+	}
+	for i, opt := range options {
+		if strings.EqualFold(opt, answer) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func onOff(v bool) string {
+	if v {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// withLinearInput enables screen-reader mode and feeds the given lines to the linear prompts.
+func withLinearInput(t *testing.T, input string) {
+	t.Helper()
+	prevReader := linearReader
+	linearReader = bufio.NewReader(strings.NewReader(input))
+	SetScreenReaderMode(true)
+	t.Cleanup(func() {
+		linearReader = prevReader
+		screenReaderMode = false
+		SetPlainOutput(false)
+	})
+}
+
+func TestSetScreenReaderModeEnablesPlainOutput(t *testing.T) {
+	withLinearInput(t, "")
+	if !IsScreenReaderMode() || !IsPlainOutput() {
+		t.Error("Expected screen reader mode to also enable plain output")
+	}
+}
+
+func TestAskConfirmLinear(t *testing.T) {
+	withLinearInput(t, "maybe\nyes\n\n")
+
+	got, err := AskConfirm("Continue?", false)
+	if err != nil || !got {
+		t.Errorf("Expected true after re-prompt, got %v (err=%v)", got, err)
+	}
+	got, err = AskConfirm("Continue?", false)
+	if err != nil || got {
+		t.Errorf("Expected default false on empty input, got %v (err=%v)", got, err)
+	}
+}
+
+func TestAskSelectLinear(t *testing.T) {
+	withLinearInput(t, "7\n2\n\nollama\n")
+	options := []string{"openai", "gemini", "ollama"}
+
+	got, err := AskSelect("Provider", options, "openai")
+	if err != nil || got != "gemini" {
+		t.Errorf("Expected gemini after invalid number, got %q (err=%v)", got, err)
+	}
+	got, _ = AskSelect("Provider", options, "openai")
+	if got != "openai" {
+		t.Errorf("Expected default openai, got %q", got)
+	}
+	got, _ = AskSelect("Provider", options, "")
+	if got != "ollama" {
+		t.Errorf("Expected selection by name, got %q", got)
+	}
+}
+
+func TestAskTextLinearKeepsDefault(t *testing.T) {
+	withLinearInput(t, "\nnew-model\n")
+
+	got, _ := AskText("Model", "gpt-4", false)
+	if got != "gpt-4" {
+		t.Errorf("Expected default to be kept, got %q", got)
+	}
+	got, _ = AskText("Model", "gpt-4", false)
+	if got != "new-model" {
+		t.Errorf("Expected new value, got %q", got)
+	}
+}
+
+func TestMultiSelectLinear(t *testing.T) {
+	withLinearInput(t, "1, 3,1\n")
+	got, err := MultiSelectNoHelp("Pick", []string{"a", "b", "c"}, nil)
+	if err != nil {
+		t.Fatalf("MultiSelectNoHelp failed: %v", err)
+	}
+	if strings.Join(got, ",") != "a,c" {
+		t.Errorf("Expected a,c, got %v", got)
+	}
+}
+
+func TestRunLinearSettingsTogglesBoolean(t *testing.T) {
+	cfg := &config.Config{Providers: map[string]config.ProviderConfig{}}
+	// Setting 1 is "Auto-execute"; answer yes, then exit with an empty line.
+	withLinearInput(t, "1\nyes\n\n")
+
+	if err := runLinearSettings(cfg); err != nil {
+		t.Fatalf("runLinearSettings failed: %v", err)
+	}
+	if !cfg.UserPreferences.AutoExecute {
+		t.Error("Expected auto-execute to be enabled through linear settings")
+	}
+}
+
+func TestEnhancedPresenterPromptsReadLinearInput(t *testing.T) {
+	withLinearInput(t, "yes\n2\nmain\n")
+	ep := NewEnhancedPresenter(nil)
+
+	if !ep.ConfirmAction("Continue?", false) {
+		t.Error("ConfirmAction ignored the answer")
+	}
+	if got, err := ep.SelectOption("Provider", []string{"openai", "gemini"}); err != nil || got != "gemini" {
+		t.Errorf("SelectOption = %q, %v, want gemini", got, err)
+	}
+	if got, err := ep.GetInput("Branch", "dev"); err != nil || got != "main" {
+		t.Errorf("GetInput = %q, %v, want main", got, err)
+	}
+}
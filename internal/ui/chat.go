@@ -0,0 +1,328 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ChatHandlers connect the 'aish chat' UI to the conversation and the provider.
+type ChatHandlers struct {
+	// Generate returns the command for request, which may refine the commands before it.
+	Generate func(ctx context.Context, request string) (string, error)
+	// Run runs command on the terminal, which the chat hands over meanwhile. It reports
+	// whether the command ran at all (the user may decline a risky one) and its error.
+	Run func(command string) (bool, error)
+	// Reset starts a new conversation.
+	Reset func()
+}
+
+const chatHelp = "Enter a request to generate or refine the command · Enter on an empty line runs it · /edit · /reset · Esc quits"
+
+type chatGeneratedMsg struct {
+	command string
+	err     error
+}
+
+type chatRanMsg struct {
+	command string
+	ran     bool
+	err     error
+}
+
+// ChatModel is the bubbletea model of 'aish chat'. The transcript is printed above the
+// program, so it stays in the terminal's scrollback; the model only draws the current command
+// and the input line.
+type ChatModel struct {
+	handlers ChatHandlers
+	input    textinput.Model
+	command  string // Current command, run by Enter on an empty line
+	editing  bool   // The input holds the command for editing rather than a request
+	busy     bool
+	cancel   context.CancelFunc
+	status   string
+	initial  string
+}
+
+// NewChatModel returns the chat model; a non-empty initial request is sent right away.
+func NewChatModel(h ChatHandlers, initial string) *ChatModel {
+	ti := textinput.New()
+	ti.Placeholder = "Describe what you want to do"
+	ti.Prompt = plainGlyph("› ", "> ")
+	ti.Width = 60 // Until the window size is known; at 0 only the placeholder's first letter shows
+	ti.Focus()
+	return &ChatModel{handlers: h, input: ti, initial: strings.TrimSpace(initial)}
+}
+
+// Init implements tea.Model
+func (m *ChatModel) Init() tea.Cmd {
+	if m.initial != "" {
+		return m.request(m.initial)
+	}
+	return textinput.Blink
+}
+
+// Update implements tea.Model
+func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case chatGeneratedMsg:
+		m.busy, m.cancel = false, nil
+		switch {
+		case errors.Is(msg.err, context.Canceled):
+			m.status = "Cancelled."
+			return m, nil
+		case msg.err != nil:
+			m.status = "Could not generate a command: " + msg.err.Error()
+			return m, nil
+		}
+		m.command, m.status = msg.command, ""
+		return m, tea.Println(chatCommandLine(msg.command))
+
+	case chatRanMsg:
+		switch {
+		case !msg.ran:
+			m.status = "Not run."
+		case msg.err != nil:
+			m.status = "The command failed: " + msg.err.Error() + ". Describe what to change, or press Esc to quit."
+		default:
+			m.status = "Done. Describe a follow-up, or press Esc to quit."
+		}
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.input.Width = max(msg.Width-lipgloss.Width(m.input.Prompt)-1, 1)
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			if m.busy {
+				m.cancel()
+				return m, nil
+			}
+			return m, tea.Quit
+		case tea.KeyEsc:
+			if m.editing {
+				m.editing = false
+				m.input.Reset()
+				return m, nil
+			}
+			if m.busy {
+				m.cancel()
+				return m, nil
+			}
+			return m, tea.Quit
+		case tea.KeyEnter:
+			if m.busy {
+				return m, nil
+			}
+			return m.submit(strings.TrimSpace(m.input.Value()))
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// submit handles Enter: a request, a command of the chat, the edited command, or running the
+// current command on an empty line.
+func (m *ChatModel) submit(text string) (tea.Model, tea.Cmd) {
+	m.input.Reset()
+	if m.editing {
+		m.editing = false
+		if text == "" {
+			return m, nil
+		}
+		m.command = text
+		return m, tea.Println(chatCommandLine(text))
+	}
+
+	switch strings.ToLower(text) {
+	case "":
+		if m.command == "" {
+			return m, nil
+		}
+		return m, m.run(m.command)
+	case "/run":
+		if m.command == "" {
+			m.status = "There is no command to run yet."
+			return m, nil
+		}
+		return m, m.run(m.command)
+	case "/edit":
+		if m.command == "" {
+			m.status = "There is no command to edit yet."
+			return m, nil
+		}
+		m.editing, m.status = true, ""
+		m.input.SetValue(m.command)
+		m.input.CursorEnd()
+		return m, nil
+	case "/reset", "/new":
+		m.command, m.status = "", "Started a new conversation."
+		if m.handlers.Reset != nil {
+			m.handlers.Reset()
+		}
+		return m, nil
+	case "/quit", "/exit", "/q":
+		return m, tea.Quit
+	case "/help", "/?":
+		m.status = chatHelp
+		return m, nil
+	}
+	return m, m.request(text)
+}
+
+// request starts generating the command for text, which Ctrl+C or Esc cancels.
+func (m *ChatModel) request(text string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.busy, m.cancel, m.status = true, cancel, ""
+	generate := m.handlers.Generate
+	return tea.Sequence(
+		tea.Println(m.input.Prompt+text),
+		func() tea.Msg {
+			command, err := generate(ctx, text)
+			cancel()
+			if err == nil && strings.TrimSpace(command) == "" {
+				err = errors.New("the provider returned no command")
+			}
+			return chatGeneratedMsg{command: strings.TrimSpace(command), err: err}
+		},
+	)
+}
+
+// run hands the terminal to the Run handler for command.
+func (m *ChatModel) run(command string) tea.Cmd {
+	m.status = ""
+	c := &chatExec{run: m.handlers.Run, command: command}
+	return tea.Exec(c, func(err error) tea.Msg {
+		return chatRanMsg{command: command, ran: c.ran, err: err}
+	})
+}
+
+// View implements tea.Model
+func (m *ChatModel) View() string {
+	var b strings.Builder
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	switch {
+	case m.busy:
+		b.WriteString(dim.Render("Generating... (Esc to cancel)") + "\n")
+	case m.editing:
+		b.WriteString(dim.Render("Edit the command; Enter keeps it, Esc discards the edit.") + "\n")
+	case m.command != "":
+		b.WriteString(dim.Render("Enter runs: ") + lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(m.command) + "\n")
+	}
+	if m.status != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(m.status) + "\n")
+	}
+	b.WriteString(m.input.View() + "\n")
+	b.WriteString(dim.Render(chatHelp))
+	return b.String()
+}
+
+func chatCommandLine(command string) string {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("  $ " + command)
+}
+
+// chatExec adapts the Run handler to tea.Exec, which releases the terminal while it runs. The
+// handler uses the process's standard streams, so the ones tea.Exec offers are ignored.
+type chatExec struct {
+	run     func(string) (bool, error)
+	command string
+	ran     bool
+}
+
+func (c *chatExec) Run() error {
+	var err error
+	c.ran, err = c.run(c.command)
+	return err
+}
+
+func (c *chatExec) SetStdin(io.Reader)  {}
+func (c *chatExec) SetStdout(io.Writer) {}
+func (c *chatExec) SetStderr(io.Writer) {}
+
+// RunChat runs an 'aish chat' session until the user quits, starting with initial when it is
+// not empty. Under the screen-reader UI it reads requests line by line instead.
+func RunChat(h ChatHandlers, initial string) error {
+	if screenReaderMode {
+		return runLinearChat(h, initial)
+	}
+	if _, err := tea.NewProgram(NewChatModel(h, initial)).Run(); err != nil {
+		return fmt.Errorf("failed to run chat: %w", err)
+	}
+	return nil
+}
+
+// runLinearChat is the chat as a plain read-answer loop.
+func runLinearChat(h ChatHandlers, initial string) error {
+	fmt.Println(chatHelp)
+	var command string
+	text := strings.TrimSpace(initial)
+	for {
+		if text == "" {
+			line, err := readLinearLine("Request: ")
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+			text = strings.TrimSpace(line)
+		}
+
+		switch strings.ToLower(text) {
+		case "", "/run":
+			if command == "" {
+				fmt.Println("There is no command to run yet.")
+				break
+			}
+			if _, err := h.Run(command); err != nil {
+				fmt.Printf("The command failed: %v\n", err)
+			}
+		case "/edit":
+			if command == "" {
+				fmt.Println("There is no command to edit yet.")
+				break
+			}
+			edited, err := readLinearLine(fmt.Sprintf("Command (press Enter to keep %s): ", command))
+			if err != nil {
+				return err
+			}
+			if edited = strings.TrimSpace(edited); edited != "" {
+				command = edited
+			}
+		case "/reset", "/new":
+			command = ""
+			if h.Reset != nil {
+				h.Reset()
+			}
+			fmt.Println("Started a new conversation.")
+		case "/quit", "/exit", "/q":
+			return nil
+		case "/help", "/?":
+			fmt.Println(chatHelp)
+		default:
+			fmt.Println("Generating...")
+			generated, err := h.Generate(context.Background(), text)
+			if err == nil && strings.TrimSpace(generated) == "" {
+				err = errors.New("the provider returned no command")
+			}
+			if err != nil {
+				fmt.Printf("Could not generate a command: %v\n", err)
+				break
+			}
+			command = strings.TrimSpace(generated)
+			fmt.Println("Command: " + command)
+			fmt.Println("Press Enter to run it, or describe what to change.")
+		}
+		text = ""
+	}
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func typeText(m *ChatModel, text string) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestChatRefineAndEdit(t *testing.T) {
+	resets := 0
+	m := NewChatModel(ChatHandlers{
+		Generate: func(context.Context, string) (string, error) { return "ls -la", nil },
+		Run:      func(string) (bool, error) { return true, nil },
+		Reset:    func() { resets++ },
+	}, "")
+
+	typeText(m, "list files")
+	if !m.busy || m.input.Value() != "" {
+		t.Fatalf("a request should start generating (busy=%v, input=%q)", m.busy, m.input.Value())
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Error("Enter while generating should do nothing")
+	}
+	m.Update(chatGeneratedMsg{command: "ls -la"})
+	if m.busy || m.command != "ls -la" {
+		t.Fatalf("command = %q, busy = %v", m.command, m.busy)
+	}
+
+	typeText(m, "/edit")
+	if !m.editing || m.input.Value() != "ls -la" {
+		t.Fatalf("/edit should put the command in the input, got %q", m.input.Value())
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.editing || m.command != "ls -lah" {
+		t.Fatalf("edited command = %q", m.command)
+	}
+
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Error("Enter on an empty line should run the command")
+	}
+	m.Update(chatRanMsg{command: "ls -lah", ran: false})
+	if m.status != "Not run." {
+		t.Errorf("status = %q", m.status)
+	}
+
+	typeText(m, "/reset")
+	if resets != 1 || m.command != "" {
+		t.Errorf("/reset: resets=%d command=%q", resets, m.command)
+	}
+}
+
+func TestChatCancel(t *testing.T) {
+	m := NewChatModel(ChatHandlers{Generate: func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}}, "")
+	typeText(m, "list files")
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc}); cmd != nil {
+		t.Error("Esc while generating should cancel, not quit")
+	}
+	m.Update(chatGeneratedMsg{err: context.Canceled})
+	if m.busy || m.status != "Cancelled." {
+		t.Errorf("busy=%v status=%q", m.busy, m.status)
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc}); cmd == nil {
+		t.Error("Esc should quit")
+	}
+}
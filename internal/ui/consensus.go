@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Candidate is one provider's answer when two providers disagree about a risky command.
+type Candidate struct {
+	Provider    string
+	Command     string
+	Explanation string
+}
+
+// minSideBySideWidth is the narrowest terminal that still fits two candidates next to each other.
+const minSideBySideWidth = 100
+
+// ChooseCandidate shows the candidates side by side (stacked on narrow terminals, in plain
+// output and for screen readers) and asks which one to run. It returns the index of the
+// chosen candidate, or false when the user runs neither.
+func ChooseCandidate(reason string, candidates []Candidate) (int, bool, error) {
+	pterm.DefaultHeader.Println("Providers Disagree")
+	PrintDemoWatermark()
+	pterm.Warning.Printfln("This command %s, and the providers suggested different commands.", reason)
+	pterm.Println()
+
+	width := pterm.GetTerminalWidth()
+	if plainOutput || screenReaderMode || width < minSideBySideWidth {
+		for i, c := range candidates {
+			pterm.Println(pterm.Green(fmt.Sprintf("%d. %s:", i+1, c.Provider)))
+			pterm.Println(pterm.LightGreen(c.Command))
+			if c.Explanation != "" {
+				pterm.Println(c.Explanation)
+			}
+			pterm.Println()
+		}
+	} else {
+		colWidth := width/len(candidates) - 4
+		row := make([]pterm.Panel, 0, len(candidates))
+		for i, c := range candidates {
+			body := pterm.Green(fmt.Sprintf("%d. %s", i+1, c.Provider)) + "\n" +
+				pterm.LightGreen(wrapWords(c.Command, colWidth))
+			if c.Explanation != "" {
+				body += "\n\n" + wrapWords(c.Explanation, colWidth)
+			}
+			row = append(row, pterm.Panel{Data: body})
+		}
+		_ = pterm.DefaultPanel.WithPanels(pterm.Panels{row}).WithPadding(4).Render()
+		pterm.Println()
+	}
+
+	options := make([]string, 0, len(candidates)+1)
+	for i, c := range candidates {
+		options = append(options, fmt.Sprintf("Run %d (%s)", i+1, c.Provider))
+	}
+	const neither = "Run neither"
+	options = append(options, neither)
+	choice, err := AskSelect("Which command should run?", options, neither)
+	if err != nil {
+		return 0, false, err
+	}
+	for i := range candidates {
+		if choice == options[i] {
+			return i, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// wrapWords breaks text into lines of at most width runes at spaces, keeping longer words whole.
+func wrapWords(text string, width int) string {
+	if width <= 10 {
+		return text
+	}
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
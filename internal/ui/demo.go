@@ -0,0 +1,28 @@
+package ui
+
+import "github.com/pterm/pterm"
+
+// DemoWatermark marks output produced in demo mode, so screenshots and recordings cannot be
+// mistaken for answers from a real model.
+const DemoWatermark = "DEMO MODE · canned responses, not a real AI model"
+
+// demoMode is the classroom/demo mode: the mock provider is forced, configured secrets are
+// hidden and suggestions carry DemoWatermark.
+var demoMode bool
+
+// SetDemoMode enables or disables demo mode.
+func SetDemoMode(enabled bool) {
+	demoMode = enabled
+}
+
+// IsDemoMode reports whether demo mode is active.
+func IsDemoMode() bool {
+	return demoMode
+}
+
+// PrintDemoWatermark prints the watermark line when demo mode is active.
+func PrintDemoWatermark() {
+	if demoMode {
+		pterm.Println(pterm.Yellow("[" + DemoWatermark + "]"))
+	}
+}
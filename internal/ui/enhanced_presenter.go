@@ -54,8 +54,8 @@ const (
 // DefaultPresenterConfig 返回默認展示器配置
 func DefaultPresenterConfig() *PresenterConfig {
 	return &PresenterConfig{
-		EnableColors:   true,
-		EnableEmojis:   true,
+		EnableColors:   !plainOutput,
+		EnableEmojis:   !plainOutput,
 		AnimationSpeed: 100 * time.Millisecond,
 		ProgressStyle:  "modern",
 		ShowTimestamps: false,
@@ -393,75 +393,26 @@ func (ep *EnhancedPresenter) ShowList(items []string, title string, ordered bool
 	pterm.Println()
 }
 
-// ConfirmAction 確認操作
+// ConfirmAction 確認操作（經由 AskConfirm，螢幕閱讀器模式下改為逐行提問）
 func (ep *EnhancedPresenter) ConfirmAction(message string, defaultYes bool) bool {
-	if ep.config.EnableColors {
-		prompt := ""
-		if defaultYes {
-			prompt = " (Y/n)"
-		} else {
-			prompt = " (y/N)"
-		}
-
-		result, _ := pterm.DefaultInteractiveConfirm.
-			WithDefaultValue(defaultYes).
-			WithDefaultText(message + prompt).
-			Show()
-		return result
-	} else {
-		// 簡化的確認實現
-		prompt := ""
-		if defaultYes {
-			prompt = " (Y/n): "
-		} else {
-			prompt = " (y/N): "
-		}
-
-		fmt.Print(message + prompt)
-		// 這裡應該讀取用戶輸入，簡化實現返回默認值
+	result, err := AskConfirm(message, defaultYes)
+	if err != nil {
 		return defaultYes
 	}
+	return result
 }
 
-// SelectOption 選擇選項
+// SelectOption 選擇選項（經由 AskSelect）
 func (ep *EnhancedPresenter) SelectOption(message string, options []string) (string, error) {
-	if ep.config.EnableColors {
-		result, err := pterm.DefaultInteractiveSelect.
-			WithDefaultText(message).
-			WithOptions(options).
-			Show()
-		return result, err
-	} else {
-		// 簡化的選擇實現
-		fmt.Println(message)
-		for i, option := range options {
-			fmt.Printf("  %d. %s\n", i+1, option)
-		}
-		// 這裡應該讀取用戶輸入，簡化實現返回第一個選項
-		if len(options) > 0 {
-			return options[0], nil
-		}
+	if len(options) == 0 {
 		return "", fmt.Errorf("no options available")
 	}
+	return AskSelect(message, options, "")
 }
 
-// GetInput 獲取用戶輸入
+// GetInput 獲取用戶輸入（經由 AskText）
 func (ep *EnhancedPresenter) GetInput(prompt string, defaultValue string) (string, error) {
-	if ep.config.EnableColors {
-		result, err := pterm.DefaultInteractiveTextInput.
-			WithDefaultText(prompt).
-			WithDefaultValue(defaultValue).
-			Show()
-		return result, err
-	} else {
-		fmt.Printf("%s", prompt)
-		if defaultValue != "" {
-			fmt.Printf(" [%s]", defaultValue)
-		}
-		fmt.Print(": ")
-		// 這裡應該讀取用戶輸入，簡化實現返回默認值
-		return defaultValue, nil
-	}
+	return AskText(prompt, defaultValue, false)
 }
 
 // wrapText 文本自動換行
@@ -2,8 +2,13 @@ package ui
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"time"
 
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm"
 	"github.com/pterm/pterm"
 )
 
@@ -23,6 +28,7 @@ const (
 // UserFriendlyError represents an error with enhanced user guidance
 type UserFriendlyError struct {
 	Type        ErrorType
+	Code        aerrors.ErrorCode // Stable machine-readable code; derived from Type when empty
 	Title       string
 	Message     string
 	Suggestions []string
@@ -36,11 +42,40 @@ func (e *UserFriendlyError) Error() string {
 	return e.Message
 }
 
+// ErrorCode returns the stable error code reported in JSON output.
+func (e *UserFriendlyError) ErrorCode() aerrors.ErrorCode {
+	if e.Code != "" {
+		return e.Code
+	}
+	switch e.Type {
+	case ConfigurationError:
+		return aerrors.ErrConfigMissing
+	case NetworkError:
+		return aerrors.ErrNetwork
+	case AuthenticationError:
+		return aerrors.ErrProviderAuth
+	case ProviderError:
+		return aerrors.ErrProviderRequest
+	case ValidationError, UserError:
+		return aerrors.ErrUserInput
+	default:
+		return aerrors.ErrInternal
+	}
+}
+
+// ExitCode returns the process exit code matching the error code.
+func (e *UserFriendlyError) ExitCode() int {
+	return aerrors.ExitCodeFor(e.ErrorCode())
+}
+
 // ErrorHandler provides enhanced error display and guidance
 type ErrorHandler struct {
 	debugMode bool
 }
 
+// jsonErrorOutput receives error reports in JSON output mode.
+var jsonErrorOutput io.Writer = os.Stdout
+
 // NewErrorHandler creates a new error handler
 func NewErrorHandler(debugMode bool) *ErrorHandler {
 	return &ErrorHandler{
@@ -109,6 +144,118 @@ func (eh *ErrorHandler) CreateProviderError(message string, suggestions []string
 	}
 }
 
+// CreateProviderFailure creates an error for a failed provider request, with remediation steps
+// matching the kind of failure (see llm.ClassifyProviderError) and the error code it maps to.
+func (eh *ErrorHandler) CreateProviderFailure(providerName, message string, err error) *UserFriendlyError {
+	userErr := eh.CreateProviderError(message, []string{
+		"Check your internet connection",
+		"Verify your LLM provider configuration",
+		"Try switching to a different provider with 'aish use <name>'",
+	})
+	userErr.Cause = err
+	if err == nil {
+		return userErr
+	}
+	userErr.Code = llm.ErrorCodeOf(providerName, err)
+
+	fallback := "Let other providers take over with 'aish config set fallback_providers <name>,<name>'"
+	switch llm.Classify(providerName, err).Type {
+	case llm.AuthError:
+		userErr.Type = AuthenticationError
+		userErr.Title = "Authentication Failed"
+		userErr.Suggestions = []string{
+			fmt.Sprintf("Update the credentials with 'aish config set providers.%s.api_key <key>' (or 'aish init')", providerName),
+			fmt.Sprintf("Check that they work with 'aish use %s'", providerName),
+		}
+	case llm.RateLimitError:
+		userErr.Title = "Rate Limit Exceeded"
+		userErr.Suggestions = []string{
+			"Wait a minute and try again",
+			fallback,
+		}
+		if wait, ok := llm.RetryAfter(err); ok {
+			userErr.Message = fmt.Sprintf("%s: rate limited, resets in %s.", providerName, WaitString(wait))
+			userErr.Suggestions = []string{
+				fmt.Sprintf("Try again in %s", WaitString(wait)),
+				"Let aish wait for short resets and retry by itself with 'aish config set rate_limit_wait_seconds 60'",
+				fallback,
+			}
+		}
+	case llm.QuotaExceededError:
+		userErr.Title = "Quota Exceeded"
+		userErr.Suggestions = []string{
+			fmt.Sprintf("Check the plan, billing and credits of your %s account", providerName),
+			"Switch to another provider with 'aish use <name>'",
+			fallback,
+		}
+		if wait, ok := llm.RetryAfter(err); ok {
+			userErr.Message = fmt.Sprintf("%s: quota exceeded, resets in %s.", providerName, WaitString(wait))
+		}
+	case llm.ContextTooLongError:
+		userErr.Title = "Prompt Too Long"
+		userErr.Suggestions = []string{
+			fmt.Sprintf("Pick a model with a larger context window: 'aish use %s <model>'", providerName),
+			fmt.Sprintf("Tell aish the model's real window so it trims the output to fit: 'aish config set providers.%s.context_window <tokens>'", providerName),
+		}
+	case llm.ContentFilterError:
+		userErr.Title = "Blocked by Content Filter"
+		userErr.Message = fmt.Sprintf("%s declined the request: its safety filter flagged the command or its output. Nothing is wrong with your setup.", providerName)
+		userErr.Suggestions = []string{
+			"Rephrase the request",
+			"Retry once with only the failed command and the end of its error output: 'aish config set content_filter_retry true'",
+			fallback,
+		}
+	case llm.NetworkError, llm.TimeoutError:
+		userErr.Type = NetworkError
+		userErr.Title = "Network Connection Error"
+		userErr.HelpLink = "https://github.com/TonnyWong1052/aish/blob/main/docs/TROUBLESHOOTING.md#network-connectivity-problems"
+		userErr.Suggestions = []string{
+			"Check your internet connection",
+			fmt.Sprintf("Check the endpoint with 'aish config get providers.%s.api_endpoint'", providerName),
+			fallback,
+		}
+	case llm.ServerError:
+		userErr.Title = "Provider Unavailable"
+		userErr.Suggestions = []string{
+			fmt.Sprintf("%s is failing on its side; try again shortly", providerName),
+			fallback,
+		}
+	case llm.ModelNotFoundError:
+		userErr.Title = "Model Not Found"
+		userErr.Suggestions = []string{
+			fmt.Sprintf("Check the model name with 'aish config get providers.%s.model'", providerName),
+			fmt.Sprintf("Pick an available model with 'aish use %s <model>'", providerName),
+		}
+	case llm.InvalidResponseError, llm.EmptyResponseError:
+		userErr.Suggestions = []string{
+			"This can happen with certain errors or prompts.",
+			"Try a different prompt or check the provider's status.",
+			"You can also switch to another provider via 'aish use <name>'.",
+		}
+	}
+	return userErr
+}
+
+// WaitString formats the wait until a rate limit or quota resets the way providers write it:
+// "42s", "6m30s", "2h".
+func WaitString(d time.Duration) string {
+	if d < time.Second {
+		d = time.Second
+	}
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		d = d.Round(time.Minute)
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
 // CreateValidationError creates a validation-related error
 func (eh *ErrorHandler) CreateValidationError(message string, suggestions []string) *UserFriendlyError {
 	return &UserFriendlyError{
@@ -122,12 +269,23 @@ func (eh *ErrorHandler) CreateValidationError(message string, suggestions []stri
 
 // displayError renders the error with appropriate styling
 func (eh *ErrorHandler) displayError(err *UserFriendlyError) {
+	if IsJSONOutput() {
+		report := aerrors.NewReport(err.ErrorCode(), err.Message, err.Cause)
+		report.Suggestions = err.Suggestions
+		_ = report.WriteJSON(jsonErrorOutput)
+		return
+	}
+
 	// Error header with appropriate icon
 	icon := eh.getErrorIcon(err.Type)
 
 	pterm.Println()
 	headerStyle := pterm.NewStyle(pterm.FgRed, pterm.Bold)
-	headerStyle.Printf("%s %s\n", icon, err.Title)
+	if plainOutput {
+		headerStyle.Printf("Error: %s\n", err.Title)
+	} else {
+		headerStyle.Printf("%s %s\n", icon, err.Title)
+	}
 
 	// Error message
 	pterm.Println()
@@ -138,7 +296,7 @@ func (eh *ErrorHandler) displayError(err *UserFriendlyError) {
 	if len(err.Suggestions) > 0 {
 		pterm.Println()
 		suggestionStyle := pterm.NewStyle(pterm.FgYellow, pterm.Bold)
-		suggestionStyle.Println("💡 Suggestions:")
+		suggestionStyle.Println(plainGlyph("💡 ", "") + "Suggestions:")
 
 		for i, suggestion := range err.Suggestions {
 			pterm.Printf("   %d. %s\n", i+1, suggestion)
@@ -149,14 +307,14 @@ func (eh *ErrorHandler) displayError(err *UserFriendlyError) {
 	if err.HelpLink != "" {
 		pterm.Println()
 		linkStyle := pterm.NewStyle(pterm.FgCyan)
-		linkStyle.Printf("📚 For more help: %s\n", err.HelpLink)
+		linkStyle.Printf("%sFor more help: %s\n", plainGlyph("📚 ", ""), err.HelpLink)
 	}
 
 	// Debug information (only shown in debug mode)
 	if eh.debugMode && err.DebugInfo != "" {
 		pterm.Println()
 		debugStyle := pterm.NewStyle(pterm.FgGray)
-		debugStyle.Println("🔍 Debug Information:")
+		debugStyle.Println(plainGlyph("🔍 ", "") + "Debug Information:")
 		debugStyle.Println(err.DebugInfo)
 	}
 
@@ -164,7 +322,7 @@ func (eh *ErrorHandler) displayError(err *UserFriendlyError) {
 	if eh.debugMode && err.Cause != nil {
 		pterm.Println()
 		debugStyle := pterm.NewStyle(pterm.FgGray)
-		debugStyle.Println("🐛 Technical Details:")
+		debugStyle.Println(plainGlyph("🐛 ", "") + "Technical Details:")
 		debugStyle.Println(err.Cause.Error())
 	}
 
@@ -292,25 +450,25 @@ func (eh *ErrorHandler) convertToUserFriendlyError(err error) *UserFriendlyError
 // ShowSuccess displays a success message with styling
 func (eh *ErrorHandler) ShowSuccess(message string) {
 	successStyle := pterm.NewStyle(pterm.FgGreen, pterm.Bold)
-	successStyle.Printf("✅ %s\n", message)
+	successStyle.Printf("%s%s\n", plainGlyph("✅ ", "OK: "), message)
 }
 
 // ShowWarning displays a warning message with styling
 func (eh *ErrorHandler) ShowWarning(message string) {
 	warningStyle := pterm.NewStyle(pterm.FgYellow, pterm.Bold)
-	warningStyle.Printf("⚠️  %s\n", message)
+	warningStyle.Printf("%s%s\n", plainGlyph("⚠️  ", "Warning: "), message)
 }
 
 // ShowInfo displays an informational message with styling
 func (eh *ErrorHandler) ShowInfo(message string) {
 	infoStyle := pterm.NewStyle(pterm.FgCyan)
-	infoStyle.Printf("ℹ️  %s\n", message)
+	infoStyle.Printf("%s%s\n", plainGlyph("ℹ️  ", "Info: "), message)
 }
 
 // ConfirmAction asks the user to confirm an action
 func (eh *ErrorHandler) ConfirmAction(message string) bool {
 	confirmStyle := pterm.NewStyle(pterm.FgYellow)
-	confirmStyle.Printf("❓ %s (y/N): ", message)
+	confirmStyle.Printf("%s%s (y/N): ", plainGlyph("❓ ", ""), message)
 
 	var response string
 	_, _ = fmt.Scanln(&response)
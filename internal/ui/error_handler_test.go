@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	aerrors "github.com/TonnyWong1052/aish/internal/errors"
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+func TestCreateProviderFailure(t *testing.T) {
+	eh := NewErrorHandler(false)
+
+	userErr := eh.CreateProviderFailure("openai", "Failed", errors.New("API returned status 400: maximum context length is 128000 tokens"))
+	if userErr.Title != "Prompt Too Long" || !strings.Contains(strings.Join(userErr.Suggestions, "\n"), "providers.openai.context_window") {
+		t.Errorf("unexpected remediation: %+v", userErr)
+	}
+
+	userErr = eh.CreateProviderFailure("claude", "Failed", errors.New("API error (status 401): authentication_error"))
+	if userErr.Type != AuthenticationError || userErr.ExitCode() != aerrors.ExitAuth {
+		t.Errorf("auth failure not typed: %+v", userErr)
+	}
+
+	userErr = eh.CreateProviderFailure("openai", "Failed", llm.RefusalError("I can't help with that."))
+	if userErr.Title != "Blocked by Content Filter" || !strings.Contains(strings.Join(userErr.Suggestions, "\n"), "content_filter_retry") {
+		t.Errorf("unexpected refusal remediation: %+v", userErr)
+	}
+
+	userErr = eh.CreateProviderFailure("ollama", "Failed", nil)
+	if userErr.ErrorCode() != aerrors.ErrProviderRequest || len(userErr.Suggestions) == 0 {
+		t.Errorf("unexpected generic error: %+v", userErr)
+	}
+}
+
+func TestCreateProviderFailureRateLimitReset(t *testing.T) {
+	eh := NewErrorHandler(false)
+	err := llm.WithRetryAfter(errors.New("API returned status 429: rate_limit_exceeded"), http.Header{"Retry-After": {"42"}})
+	userErr := eh.CreateProviderFailure("openai", "Failed", err)
+	if !strings.Contains(userErr.Message, "rate limited, resets in 4") || userErr.ExitCode() != aerrors.ExitCodeFor(aerrors.ErrProviderQuota) {
+		t.Errorf("unexpected rate-limit error: %+v", userErr)
+	}
+
+	userErr = eh.CreateProviderFailure("gemini", "Failed", errors.New("API error: You exceeded your current quota. Please retry in 3h."))
+	if userErr.Title != "Quota Exceeded" || userErr.Message != "gemini: quota exceeded, resets in 3h." {
+		t.Errorf("unexpected quota error: %+v", userErr)
+	}
+}
+
+func TestWaitString(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		200 * time.Millisecond:                     "1s",
+		41600 * time.Millisecond:                   "42s",
+		6 * time.Minute:                            "6m",
+		6*time.Minute + 30*time.Second:             "6m30s",
+		2*time.Hour + 10*time.Second:               "2h",
+		time.Hour + 5*time.Minute + 20*time.Second: "1h5m",
+	} {
+		if got := WaitString(d); got != want {
+			t.Errorf("WaitString(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
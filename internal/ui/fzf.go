@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrSelectionCancelled is returned by FuzzySelect when the user backed out of the picker.
+var ErrSelectionCancelled = errors.New("selection cancelled")
+
+// FzfAvailable reports whether fzf can be used for pickers: it is installed and the UI is not
+// in screen-reader mode, where the built-in linear prompts work better.
+func FzfAvailable() bool {
+	if screenReaderMode {
+		return false
+	}
+	_, err := exec.LookPath("fzf")
+	return err == nil
+}
+
+// FuzzySelect lets the user pick one of options and returns its index. It pipes the options
+// into fzf when installed, showing previews[i] (if given) in fzf's preview pane, and falls back
+// to the built-in select otherwise.
+func FuzzySelect(question string, options, previews []string) (int, error) {
+	if len(options) == 0 {
+		return -1, fmt.Errorf("no options provided")
+	}
+	if !FzfAvailable() {
+		selected, err := AskSelect(question, options, "")
+		if err != nil {
+			return -1, err
+		}
+		if i := indexOf(options, selected); i >= 0 {
+			return i, nil
+		}
+		return -1, ErrSelectionCancelled
+	}
+	return fzfSelect(question, options, previews)
+}
+
+// fzfSelect runs fzf over "index<TAB>option<TAB>preview" lines, displaying only the option.
+func fzfSelect(question string, options, previews []string) (int, error) {
+	var input bytes.Buffer
+	for i, opt := range options {
+		preview := ""
+		if i < len(previews) {
+			preview = fzfEscape(previews[i])
+		}
+		fmt.Fprintf(&input, "%d\t%s\t%s\n", i, fzfField(opt), preview)
+	}
+
+	args := []string{
+		"--prompt", strings.TrimSuffix(question, ">") + "> ",
+		"--delimiter", "\t",
+		"--with-nth", "2",
+		"--height", "40%",
+		"--reverse",
+	}
+	if len(previews) > 0 {
+		// {3} is quoted by fzf; printf %b turns the escaped newlines back into line breaks
+		args = append(args, "--preview", "printf '%b' {3}", "--preview-window", "down,50%,wrap")
+	}
+	cmd := exec.Command("fzf", args...)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr // fzf draws its UI on /dev/tty and reports problems on stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// 1: no match, 130: interrupted with Esc or Ctrl-C
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+			return -1, ErrSelectionCancelled
+		}
+		return -1, fmt.Errorf("fzf: %w", err)
+	}
+
+	idx, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\t")
+	i, err := strconv.Atoi(idx)
+	if err != nil || i < 0 || i >= len(options) {
+		return -1, fmt.Errorf("fzf returned an unexpected selection %q", out)
+	}
+	return i, nil
+}
+
+// fzfField flattens a value onto one tab-free line.
+func fzfField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
+
+// fzfEscape keeps a multi-line preview on one line in the form printf %b expands again.
+func fzfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\r", "", "\n", `\n`).Replace(s)
+}
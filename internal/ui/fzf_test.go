@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeFzf puts a shell script named fzf first (and alone) on PATH.
+func fakeFzf(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake fzf is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fzf"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestFzfEscapeKeepsPreviewOnOneLine(t *testing.T) {
+	got := fzfEscape("line one\n\tC:\\path\r\nline two")
+	want := `line one\n\tC:\\path\nline two`
+	if got != want {
+		t.Errorf("fzfEscape = %q, want %q", got, want)
+	}
+	if got := fzfField("a\tb\nc"); got != "a b c" {
+		t.Errorf("fzfField = %q", got)
+	}
+}
+
+func TestFzfSelectParsesSelection(t *testing.T) {
+	// Picks the second line of its input
+	fakeFzf(t, "read -r first\nread -r second\necho \"$second\"\n")
+
+	i, err := FuzzySelect("Pick", []string{"first", "second", "third"}, []string{"a", "b", "c"})
+	if err != nil || i != 1 {
+		t.Fatalf("FuzzySelect = %d, %v; want 1", i, err)
+	}
+}
+
+func TestFzfSelectCancelled(t *testing.T) {
+	fakeFzf(t, "exit 130\n")
+
+	if _, err := FuzzySelect("Pick", []string{"only"}, nil); err != ErrSelectionCancelled {
+		t.Fatalf("expected ErrSelectionCancelled, got %v", err)
+	}
+}
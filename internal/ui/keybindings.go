@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Key binding styles for the settings TUI (user_preferences.ui.key_bindings).
+const (
+	KeyStyleDefault = "default" // Arrow keys plus h/j/k/l
+	KeyStyleVim     = "vim"     // h/j/k/l first, Ctrl+F/Ctrl+B to page
+	KeyStyleEmacs   = "emacs"   // Ctrl+P/N/B/F, Ctrl+V to page, Ctrl+G to quit; letters stay free
+)
+
+// KeyBindingStyles lists the styles in the order the settings page offers them.
+var KeyBindingStyles = []string{KeyStyleDefault, KeyStyleVim, KeyStyleEmacs}
+
+// KeyActions are the settings TUI actions user_preferences.ui.keys can remap.
+var KeyActions = []string{"up", "down", "left", "right", "page_up", "page_down", "enter", "toggle", "quit"}
+
+// keyStyles holds the keys of each action per style. Key names are bubbletea's ("ctrl+p",
+// "pgdown"), with "space" for the space bar.
+var keyStyles = map[string]map[string][]string{
+	KeyStyleDefault: {
+		"up": {"up", "k"}, "down": {"down", "j"}, "left": {"left", "h"}, "right": {"right", "l"},
+		"page_up": {"pgup", "b", "u"}, "page_down": {"pgdown", "f", "d"},
+		"enter": {"enter"}, "toggle": {"space"}, "quit": {"q", "esc"},
+	},
+	KeyStyleVim: {
+		"up": {"k", "up"}, "down": {"j", "down"}, "left": {"h", "left"}, "right": {"l", "right"},
+		"page_up": {"ctrl+b", "ctrl+u", "pgup"}, "page_down": {"ctrl+f", "ctrl+d", "pgdown"},
+		"enter": {"enter"}, "toggle": {"space"}, "quit": {"q", "esc"},
+	},
+	KeyStyleEmacs: {
+		"up": {"ctrl+p", "up"}, "down": {"ctrl+n", "down"}, "left": {"ctrl+b", "left"}, "right": {"ctrl+f", "right"},
+		"page_up": {"alt+v", "pgup"}, "page_down": {"ctrl+v", "pgdown"},
+		"enter": {"enter", "ctrl+j"}, "toggle": {"space"}, "quit": {"ctrl+g", "esc", "q"},
+	},
+}
+
+var keyActionHelp = map[string]string{
+	"up":        "up",
+	"down":      "down",
+	"left":      "previous option",
+	"right":     "next option",
+	"page_up":   "previous page",
+	"page_down": "next page",
+	"enter":     "confirm/execute",
+	"toggle":    "toggle",
+	"quit":      "quit",
+}
+
+// NewKeyMap returns the bindings of style ("" means default) with overrides applied; an
+// override replaces all keys of its action. It fails for an unknown style or action, an
+// action left without keys, or a key bound to two actions.
+func NewKeyMap(style string, overrides map[string][]string) (KeyMap, error) {
+	if style == "" {
+		style = KeyStyleDefault
+	}
+	base, ok := keyStyles[strings.ToLower(style)]
+	if !ok {
+		return KeyMap{}, fmt.Errorf("unknown key binding style %q (use %s)", style, strings.Join(KeyBindingStyles, ", "))
+	}
+
+	keys := make(map[string][]string, len(base))
+	for action, k := range base {
+		keys[action] = k
+	}
+	for action, k := range overrides {
+		if _, ok := base[action]; !ok {
+			return KeyMap{}, fmt.Errorf("unknown key action %q (use %s)", action, strings.Join(KeyActions, ", "))
+		}
+		var cleaned []string
+		for _, name := range k {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				cleaned = append(cleaned, name)
+			}
+		}
+		if len(cleaned) == 0 {
+			return KeyMap{}, fmt.Errorf("no keys given for %s", action)
+		}
+		keys[action] = cleaned
+	}
+
+	owner := map[string]string{}
+	for _, action := range KeyActions {
+		for _, name := range keys[action] {
+			if other, taken := owner[name]; taken {
+				return KeyMap{}, fmt.Errorf("key %q is bound to both %s and %s", name, other, action)
+			}
+			owner[name] = action
+		}
+	}
+
+	bind := func(action string) key.Binding {
+		names := make([]string, len(keys[action]))
+		for i, name := range keys[action] {
+			if name == "space" {
+				name = " "
+			}
+			names[i] = name
+		}
+		return key.NewBinding(key.WithKeys(names...), key.WithHelp(keyHelp(keys[action]), keyActionHelp[action]))
+	}
+	return KeyMap{
+		Up:       bind("up"),
+		Down:     bind("down"),
+		Left:     bind("left"),
+		Right:    bind("right"),
+		PageUp:   bind("page_up"),
+		PageDown: bind("page_down"),
+		Enter:    bind("enter"),
+		Space:    bind("toggle"),
+		Tab: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "next item"),
+		),
+		ShiftTab: key.NewBinding(
+			key.WithKeys("shift+tab"),
+			key.WithHelp("shift+tab", "previous item"),
+		),
+		Quit: bind("quit"),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
+	}, nil
+}
+
+// keyHelp writes key names the way the help line shows them, e.g. "↑/k".
+func keyHelp(names []string) string {
+	symbols := map[string]string{"up": "↑", "down": "↓", "left": "←", "right": "→"}
+	out := make([]string, len(names))
+	for i, name := range names {
+		if s, ok := symbols[name]; ok {
+			name = s
+		}
+		out[i] = name
+	}
+	return strings.Join(out, "/")
+}
+
+// FormatKeyOverrides writes overrides as "action=key,key" pairs sorted by action, for
+// 'aish config get'.
+func FormatKeyOverrides(overrides map[string][]string) string {
+	actions := make([]string, 0, len(overrides))
+	for action := range overrides {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	parts := make([]string, len(actions))
+	for i, action := range actions {
+		parts[i] = action + "=" + strings.Join(overrides[action], ",")
+	}
+	return strings.Join(parts, " ")
+}
+
+// helpLine describes the main bindings for the bottom line of the settings TUI.
+func (k KeyMap) helpLine() string {
+	return fmt.Sprintf("Navigate: %s %s  Toggle: %s  Select: %s %s  Action: %s  Quit: %s",
+		k.Up.Help().Key, k.Down.Help().Key, k.Space.Help().Key, k.Left.Help().Key, k.Right.Help().Key,
+		k.Enter.Help().Key, k.Quit.Help().Key)
+}
+
+// multiSelectHelpLine describes the bindings of the inline multi-select panel.
+func (k KeyMap) multiSelectHelpLine() string {
+	return fmt.Sprintf("%s %s Move  %s Toggle  a All  i Invert  %s Confirm  esc Cancel",
+		k.Up.Help().Key, k.Down.Help().Key, k.Space.Help().Key, k.Enter.Help().Key)
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func TestNewKeyMapStyles(t *testing.T) {
+	emacs, err := NewKeyMap("Emacs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyCtrlP}, emacs.Up) || key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}}, emacs.Up) {
+		t.Error("emacs style should move up with ctrl+p and leave k alone")
+	}
+	if got := emacs.helpLine(); !strings.Contains(got, "ctrl+p/↑") || !strings.Contains(got, "Quit: ctrl+g/esc/q") {
+		t.Errorf("help line %q does not show the emacs keys", got)
+	}
+
+	def, err := NewKeyMap("", nil)
+	if err != nil || !key.Matches(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}, def.Space) {
+		t.Fatalf("default style should toggle with space (%v)", err)
+	}
+}
+
+func TestNewKeyMapOverrides(t *testing.T) {
+	keys, err := NewKeyMap(KeyStyleVim, map[string][]string{"quit": {"ctrl+q", " X "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyCtrlQ}, keys.Quit) || key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}, keys.Quit) {
+		t.Error("an override should replace the action's keys")
+	}
+
+	for name, overrides := range map[string]map[string][]string{
+		"unknown action": {"jump": {"g"}},
+		"no keys":        {"up": {" "}},
+		"conflict":       {"quit": {"j"}},
+	} {
+		if _, err := NewKeyMap(KeyStyleDefault, overrides); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewKeyMap("nano", nil); err == nil {
+		t.Error("unknown style should fail")
+	}
+}
+
+func TestSettingsModelUsesConfiguredKeys(t *testing.T) {
+	cfg := &config.Config{Providers: map[string]config.ProviderConfig{}}
+	cfg.UserPreferences.UI.KeyBindings = KeyStyleEmacs
+	m := NewSettingsModel(cfg)
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+
+	start := m.list.Index()
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	if m.list.Index() == start {
+		t.Fatal("ctrl+n should move down in the emacs style")
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}}); cmd != nil || m.list.Index() != start+1 {
+		t.Fatalf("j should do nothing in the emacs style (index %d)", m.list.Index())
+	}
+}
+
+func TestMultiSelectMovesWithCtrlPN(t *testing.T) {
+	for _, style := range KeyBindingStyles {
+		cfg := &config.Config{Providers: map[string]config.ProviderConfig{}}
+		cfg.UserPreferences.UI.KeyBindings = style
+		m := NewSettingsModel(cfg)
+		m.multiActive = true
+		m.multiOptions = []string{"a", "b", "c"}
+		m.multiSelected = make([]bool, 3)
+
+		m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+		m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+		if m.multiCursor != 2 {
+			t.Errorf("%s: ctrl+n twice left the cursor at %d", style, m.multiCursor)
+		}
+		m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+		if m.multiCursor != 1 {
+			t.Errorf("%s: ctrl+p left the cursor at %d", style, m.multiCursor)
+		}
+	}
+}
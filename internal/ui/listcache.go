@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+// listCacheTTL is how long fetched model and project lists are reused. Setup is often re-run
+// within minutes, while the lists themselves change rarely.
+const listCacheTTL = 10 * time.Minute
+
+// listCacheDir is the directory under the cache directory holding the wizard's lists.
+const listCacheDir = "lists"
+
+type cachedList[T any] struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Items     []T       `json:"items"`
+}
+
+// listCacheKey builds a cache file name from a list kind and the inputs that decide its content,
+// hashed so API keys never appear on disk.
+func listCacheKey(kind string, parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return kind + "-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// cachedListOrFetch returns the list stored under key when it is younger than listCacheTTL,
+// otherwise calls fetch and stores a non-empty result. refresh skips the cached copy. Cache
+// failures only cost the speed-up.
+func cachedListOrFetch[T any](key string, refresh bool, fetch func() ([]T, error)) ([]T, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return fetch()
+	}
+	path := filepath.Join(dir, listCacheDir, key+".json")
+
+	if !refresh {
+		if data, err := os.ReadFile(path); err == nil {
+			var c cachedList[T]
+			if json.Unmarshal(data, &c) == nil && len(c.Items) > 0 && time.Since(c.FetchedAt) < listCacheTTL {
+				return c.Items, nil
+			}
+		}
+	}
+
+	items, err := fetch()
+	if err != nil || len(items) == 0 {
+		return items, err
+	}
+	if data, err := json.Marshal(cachedList[T]{FetchedAt: time.Now(), Items: items}); err == nil {
+		if os.MkdirAll(filepath.Dir(path), config.DefaultDirPermissions) == nil {
+			_ = os.WriteFile(path, data, 0600)
+		}
+	}
+	return items, nil
+}
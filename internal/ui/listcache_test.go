@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func TestCachedListOrFetch(t *testing.T) {
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	calls := 0
+	fetch := func() ([]string, error) {
+		calls++
+		return []string{"gpt-4o", "gpt-4o-mini"}, nil
+	}
+	key := listCacheKey("models", "https://api.openai.com/v1", "sk-secret")
+
+	for i := 0; i < 2; i++ {
+		got, err := cachedListOrFetch(key, false, fetch)
+		if err != nil || len(got) != 2 {
+			t.Fatalf("got %v, %v", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("second call should be served from the cache, fetched %d times", calls)
+	}
+	if _, err := cachedListOrFetch(key, true, fetch); err != nil || calls != 2 {
+		t.Errorf("refresh should fetch again: calls=%d err=%v", calls, err)
+	}
+}
+
+func TestCachedListOrFetchDoesNotCacheFailures(t *testing.T) {
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	key := listCacheKey("projects", "acct")
+	if _, err := cachedListOrFetch(key, false, func() ([]string, error) { return nil, errors.New("offline") }); err == nil {
+		t.Fatal("expected the fetch error")
+	}
+	got, err := cachedListOrFetch(key, false, func() ([]string, error) { return []string{"p1"}, nil })
+	if err != nil || len(got) != 1 {
+		t.Errorf("a failed fetch must not be cached: %v, %v", got, err)
+	}
+}
+
+func TestListCacheKeyHidesSecrets(t *testing.T) {
+	key := listCacheKey("models", "https://api.openai.com/v1", "sk-secret")
+	if strings.Contains(key, "sk-secret") || !strings.HasPrefix(key, "models-") {
+		t.Errorf("unexpected key %q", key)
+	}
+	if key == listCacheKey("models", "https://api.openai.com/v1", "sk-other") {
+		t.Error("different API keys must not share a cache entry")
+	}
+}
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocaleFormat writes timestamps, durations and numbers the way a locale does, in a chosen
+// time zone. The zero value uses ISO dates, a 24-hour clock and the local time zone.
+type LocaleFormat struct {
+	location *time.Location
+	style    localeStyle
+}
+
+// localeStyle is how one locale writes dates, times and numbers.
+type localeStyle struct {
+	date    string // Go layout for a date
+	clock   string // Go layout for a time of day
+	decimal string
+	group   string // Thousands separator
+}
+
+var isoStyle = localeStyle{date: "2006-01-02", clock: "15:04:05", decimal: ".", group: ","}
+
+// localeStyles holds the conventions by language and by language_REGION; a region entry
+// wins over its language's.
+var localeStyles = map[string]localeStyle{
+	"en":    {date: "02/01/2006", clock: "15:04:05", decimal: ".", group: ","},
+	"en_US": {date: "01/02/2006", clock: "3:04:05 PM", decimal: ".", group: ","},
+	"en_CA": {date: "2006-01-02", clock: "3:04:05 PM", decimal: ".", group: ","},
+	"de":    {date: "02.01.2006", clock: "15:04:05", decimal: ",", group: "."},
+	"de_CH": {date: "02.01.2006", clock: "15:04:05", decimal: ".", group: "’"},
+	"fr":    {date: "02/01/2006", clock: "15:04:05", decimal: ",", group: " "},
+	"fr_CA": {date: "2006-01-02", clock: "15:04:05", decimal: ",", group: " "},
+	"es":    {date: "02/01/2006", clock: "15:04:05", decimal: ",", group: "."},
+	"it":    {date: "02/01/2006", clock: "15:04:05", decimal: ",", group: "."},
+	"pt":    {date: "02/01/2006", clock: "15:04:05", decimal: ",", group: "."},
+	"nl":    {date: "02-01-2006", clock: "15:04:05", decimal: ",", group: "."},
+	"ru":    {date: "02.01.2006", clock: "15:04:05", decimal: ",", group: " "},
+	"pl":    {date: "02.01.2006", clock: "15:04:05", decimal: ",", group: " "},
+	"sv":    {date: "2006-01-02", clock: "15:04:05", decimal: ",", group: " "},
+	"ja":    {date: "2006/01/02", clock: "15:04:05", decimal: ".", group: ","},
+	"zh":    {date: "2006/01/02", clock: "15:04:05", decimal: ".", group: ","},
+	"ko":    {date: "2006. 01. 02.", clock: "15:04:05", decimal: ".", group: ","},
+}
+
+// NewLocaleFormat returns the format of locale (e.g. "de_DE", "en-US" or "ja"), showing times
+// in loc. Unknown or empty locales, and C/POSIX, get ISO dates and a 24-hour clock.
+func NewLocaleFormat(locale string, loc *time.Location) LocaleFormat {
+	if loc == nil {
+		loc = time.Local
+	}
+	return LocaleFormat{location: loc, style: lookupLocaleStyle(locale)}
+}
+
+func lookupLocaleStyle(locale string) localeStyle {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	lang, region, _ := strings.Cut(strings.ReplaceAll(locale, "-", "_"), "_")
+	lang = strings.ToLower(lang)
+	if style, ok := localeStyles[lang+"_"+strings.ToUpper(region)]; ok {
+		return style
+	}
+	if style, ok := localeStyles[lang]; ok {
+		return style
+	}
+	return isoStyle
+}
+
+func (f LocaleFormat) styled() localeStyle {
+	if f.style.date == "" {
+		return isoStyle
+	}
+	return f.style
+}
+
+func (f LocaleFormat) in(t time.Time) time.Time {
+	if f.location == nil {
+		return t.Local()
+	}
+	return t.In(f.location)
+}
+
+// Date formats the calendar date of t.
+func (f LocaleFormat) Date(t time.Time) string {
+	return f.in(t).Format(f.styled().date)
+}
+
+// DateTime formats t as a date and time of day.
+func (f LocaleFormat) DateTime(t time.Time) string {
+	style := f.styled()
+	return f.in(t).Format(style.date + " " + style.clock)
+}
+
+// Int formats n with the locale's thousands separator.
+func (f LocaleFormat) Int(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	return sign + groupDigits(digits, f.styled().group)
+}
+
+// Number formats v with the given number of decimals and the locale's separators.
+func (f LocaleFormat) Number(v float64, decimals int) string {
+	style := f.styled()
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	out := groupDigits(whole, style.group)
+	if frac != "" {
+		out += style.decimal + frac
+	}
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		out = "-" + out
+	}
+	return out
+}
+
+// Duration formats d for people: milliseconds below a second, seconds with one decimal below
+// a minute, then whole minutes and seconds, or hours and minutes.
+func (f LocaleFormat) Duration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%d ms", d.Milliseconds())
+	case d < time.Minute:
+		return f.Number(d.Seconds(), 1) + " s"
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%d min %d s", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		d = d.Round(time.Minute)
+		return fmt.Sprintf("%d h %d min", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// groupDigits inserts sep between groups of three digits, counting from the right.
+func groupDigits(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleFormatDates(t *testing.T) {
+	at := time.Date(2025, 3, 7, 14, 5, 9, 0, time.UTC)
+	cases := []struct {
+		locale   string
+		date     string
+		dateTime string
+	}{
+		{"", "2025-03-07", "2025-03-07 14:05:09"},
+		{"C", "2025-03-07", "2025-03-07 14:05:09"},
+		{"en_US", "03/07/2025", "03/07/2025 2:05:09 PM"},
+		{"en-GB", "07/03/2025", "07/03/2025 14:05:09"},
+		{"de_DE.UTF-8", "07.03.2025", "07.03.2025 14:05:09"},
+		{"ja_JP", "2025/03/07", "2025/03/07 14:05:09"},
+		{"xx_YY", "2025-03-07", "2025-03-07 14:05:09"},
+	}
+	for _, c := range cases {
+		f := NewLocaleFormat(c.locale, time.UTC)
+		if got := f.Date(at); got != c.date {
+			t.Errorf("%q Date = %q, want %q", c.locale, got, c.date)
+		}
+		if got := f.DateTime(at); got != c.dateTime {
+			t.Errorf("%q DateTime = %q, want %q", c.locale, got, c.dateTime)
+		}
+	}
+
+	tokyo := time.FixedZone("JST", 9*3600)
+	if got := NewLocaleFormat("", tokyo).DateTime(at); got != "2025-03-07 23:05:09" {
+		t.Errorf("DateTime in JST = %q", got)
+	}
+}
+
+func TestLocaleFormatNumbers(t *testing.T) {
+	us, de := NewLocaleFormat("en_US", nil), NewLocaleFormat("de_DE", nil)
+	if got := us.Int(1234567); got != "1,234,567" {
+		t.Errorf("en_US Int = %q", got)
+	}
+	if got := de.Int(-1234); got != "-1.234" {
+		t.Errorf("de_DE Int = %q", got)
+	}
+	if got := de.Number(1234.5, 2); got != "1.234,50" {
+		t.Errorf("de_DE Number = %q", got)
+	}
+	if got := us.Number(999, 0); got != "999" {
+		t.Errorf("en_US Number = %q", got)
+	}
+	if got := (LocaleFormat{}).Int(1000); got != "1,000" {
+		t.Errorf("zero value Int = %q", got)
+	}
+}
+
+func TestLocaleFormatDuration(t *testing.T) {
+	de := NewLocaleFormat("de", nil)
+	cases := map[time.Duration]string{
+		850 * time.Millisecond:        "850 ms",
+		1250 * time.Millisecond:       "1,2 s",
+		3*time.Minute + 5*time.Second: "3 min 5 s",
+		2*time.Hour + 30*time.Minute:  "2 h 30 min",
+	}
+	for d, want := range cases {
+		if got := de.Duration(d); got != want {
+			t.Errorf("Duration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
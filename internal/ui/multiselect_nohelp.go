@@ -13,10 +13,14 @@ import (
 // MultiSelectNoHelp renders a simple multiselect without the default PTerm help line.
 // Keys: space = toggle current, a = toggle all, i = invert, enter = confirm.
 // Arrow keys move the cursor; no type-to-filter.
+// In screen-reader mode it falls back to a numbered, line-based selection.
 func MultiSelectNoHelp(prompt string, options []string, defaultOptions []string) ([]string, error) {
 	if len(options) == 0 {
 		return nil, fmt.Errorf("no options provided")
 	}
+	if screenReaderMode {
+		return askMultiSelectLinear(strings.TrimSpace(prompt), options, defaultOptions)
+	}
 
 	// Initialize selection state
 	selected := make([]bool, len(options))
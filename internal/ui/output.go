@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/pterm/pterm"
+)
+
+// Output formats accepted by --output.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// outputFormat selects how errors are reported; JSON output is meant for wrappers and scripts.
+var outputFormat = OutputText
+
+// SetOutputFormat selects text or JSON output and reports whether the format is supported.
+func SetOutputFormat(format string) bool {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", OutputText:
+		outputFormat = OutputText
+	case OutputJSON:
+		outputFormat = OutputJSON
+	default:
+		return false
+	}
+	return true
+}
+
+// IsJSONOutput reports whether machine-readable output was requested.
+func IsJSONOutput() bool {
+	return outputFormat == OutputJSON
+}
+
+// quietOutput reserves stdout for the result alone (e.g. the generated command), so aish can be
+// used in command substitution; everything else pterm prints goes to stderr.
+var quietOutput bool
+
+// SetQuietOutput enables or disables quiet output.
+func SetQuietOutput(enabled bool) {
+	quietOutput = enabled
+	if enabled {
+		pterm.SetDefaultOutput(os.Stderr)
+		return
+	}
+	pterm.SetDefaultOutput(os.Stdout)
+}
+
+// IsQuietOutput reports whether quiet output is active.
+func IsQuietOutput() bool {
+	return quietOutput
+}
+
+// plainOutput is process-wide because pterm and lipgloss keep their styling state globally as well.
+var plainOutput bool
+
+// NoColorRequested reports whether the environment asks for uncolored output,
+// either through the NO_COLOR convention (https://no-color.org) or a dumb terminal.
+func NoColorRequested() bool {
+	if v, ok := os.LookupEnv("NO_COLOR"); ok && v != "" {
+		return true
+	}
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("TERM")), "dumb")
+}
+
+// SetPlainOutput switches between styled and plain output.
+// Plain output disables colors, spinners and box drawing in pterm, lipgloss and the presenter.
+func SetPlainOutput(enabled bool) {
+	plainOutput = enabled
+	if enabled {
+		pterm.DisableStyling()
+		lipgloss.SetColorProfile(termenv.Ascii)
+		return
+	}
+	pterm.EnableStyling()
+	lipgloss.SetColorProfile(termenv.EnvColorProfile())
+}
+
+// IsPlainOutput reports whether plain output mode is active.
+func IsPlainOutput() bool {
+	return plainOutput
+}
+
+// plainBorder returns the border used for framed TUI blocks, falling back to no border in plain mode.
+func plainBorder(styled lipgloss.Border) lipgloss.Border {
+	if plainOutput {
+		return lipgloss.HiddenBorder()
+	}
+	return styled
+}
+
+// plainGlyph returns the decorative glyph in styled mode and its ASCII fallback in plain mode.
+func plainGlyph(styled, fallback string) string {
+	if plainOutput {
+		return fallback
+	}
+	return styled
+}
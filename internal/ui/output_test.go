@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/pterm/pterm"
+)
+
+func TestNoColorRequested(t *testing.T) {
+	testCases := []struct {
+		name    string
+		noColor string
+		term    string
+		want    bool
+	}{
+		{name: "NO_COLOR set", noColor: "1", term: "xterm-256color", want: true},
+		{name: "NO_COLOR empty is ignored", noColor: "", term: "xterm-256color", want: false},
+		{name: "dumb terminal", noColor: "", term: "dumb", want: true},
+		{name: "regular terminal", noColor: "", term: "xterm", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			t.Setenv("TERM", tc.term)
+			if got := NoColorRequested(); got != tc.want {
+				t.Errorf("NoColorRequested() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetPlainOutput(t *testing.T) {
+	defer SetPlainOutput(false)
+
+	SetPlainOutput(true)
+	if !IsPlainOutput() {
+		t.Error("Expected plain output to be enabled")
+	}
+	if !pterm.RawOutput {
+		t.Error("Expected pterm styling to be disabled in plain mode")
+	}
+	if got := plainGlyph("❯ ", "> "); got != "> " {
+		t.Errorf("Expected ASCII fallback glyph, got %q", got)
+	}
+
+	SetPlainOutput(false)
+	if IsPlainOutput() || pterm.RawOutput {
+		t.Error("Expected styled output to be restored")
+	}
+	if got := plainGlyph("❯ ", "> "); got != "❯ " {
+		t.Errorf("Expected styled glyph, got %q", got)
+	}
+}
+
+func TestPresenterLoadingInPlainMode(t *testing.T) {
+	defer SetPlainOutput(false)
+	SetPlainOutput(true)
+
+	p := NewPresenter()
+	if err := p.ShowLoadingWithTimer("Testing"); err != nil {
+		t.Fatalf("ShowLoadingWithTimer failed in plain mode: %v", err)
+	}
+	if p.spinner != nil {
+		t.Error("Expected no spinner to be started in plain mode")
+	}
+	p.StopLoading(true)
+}
+
+func TestPresenterLoadingWithAnimationsDisabled(t *testing.T) {
+	defer SetAnimations(true)
+	SetAnimations(false)
+	if AnimationsEnabled() {
+		t.Fatal("Expected animations to be disabled")
+	}
+
+	p := NewPresenter()
+	if err := p.ShowLoadingWithTimer("Analyzing"); err != nil {
+		t.Fatalf("ShowLoadingWithTimer failed with animations disabled: %v", err)
+	}
+	if p.spinner != nil || p.timerCancel != nil {
+		t.Error("Expected neither a spinner nor timer updates with animations disabled")
+	}
+	p.StopLoading(true)
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/pterm/pterm"
+)
+
+// PlanStep is one command of a multi-step plan to present.
+type PlanStep struct {
+	Command     string
+	Explanation string
+}
+
+// StepAction is the user's answer to ConfirmStep.
+type StepAction int
+
+const (
+	StepRun StepAction = iota
+	StepSkip
+	StepQuit
+)
+
+// RenderPlan lists the numbered steps of a plan before they are confirmed one at a time.
+func (p *Presenter) RenderPlan(title string, steps []PlanStep) {
+	pterm.DefaultHeader.Println(title)
+	PrintDemoWatermark()
+
+	for i, s := range steps {
+		pterm.Println(pterm.Green(fmt.Sprintf("Step %d/%d:", i+1, len(steps))) + " " + pterm.LightGreen(s.Command))
+		if s.Explanation != "" {
+			pterm.Println("  " + layoutParagraph(s.Explanation, 0))
+		}
+	}
+	pterm.Println()
+	pterm.Println(pterm.LightWhite("Each step runs only after the previous one succeeded."))
+	pterm.Println()
+}
+
+// ConfirmStep asks whether to run step i (0-based) of total. The what-if preview, when
+// enabled, is shown here rather than in RenderPlan: only now have the earlier steps run.
+func (p *Presenter) ConfirmStep(i, total int, step PlanStep) (StepAction, error) {
+	pterm.Println(pterm.Green(fmt.Sprintf("Step %d/%d:", i+1, total)) + " " + pterm.LightGreen(step.Command))
+	if commandPreview {
+		renderPreview(step.Command)
+	}
+	pterm.Print("[Enter] run, [s] skip, [q] quit: ")
+
+	for {
+		input, cancelled, err := readOption()
+		if err != nil {
+			return StepQuit, err
+		}
+		if cancelled {
+			return StepQuit, nil
+		}
+		switch input {
+		case "", "y", "yes":
+			return StepRun, nil
+		case "s", "skip":
+			return StepSkip, nil
+		case "q", "quit", "n", "no":
+			return StepQuit, nil
+		}
+		pterm.Print("Please answer Enter, s or q: ")
+	}
+}
@@ -21,6 +21,8 @@ type Suggestion struct {
 	Explanation string
 	Command     string
 	Title       string // e.g., "AI Suggestion" or "Generated Command"
+	// ExplanationShown is set when a StreamView already printed the title and explanation
+	ExplanationShown bool
 }
 
 // Presenter handles the standardized display of suggestions and user interaction.
@@ -31,6 +33,9 @@ type Presenter struct {
     timerCancel context.CancelFunc
     timerWG     sync.WaitGroup
     ttyWriter   io.WriteCloser // 用於spinner輸出到/dev/tty,繞過stderr重定向
+    loadingBase string         // Message passed to ShowLoadingWithTimer
+    phaseMu     sync.Mutex     // Guards phase; separate from mu, which StopLoading holds while the timer goroutine exits
+    phase       string         // Current step shown after the loading message, e.g. "contacting provider"
 }
 
 // NewPresenter creates a new Presenter.
@@ -41,35 +46,74 @@ func NewPresenter() *Presenter {
 // Render displays a suggestion and handles user input.
 // Returns the user's new prompt, whether to proceed, and any error.
 func (p *Presenter) Render(suggestion Suggestion) (string, bool, error) {
-    pterm.DefaultHeader.Println(suggestion.Title)
+    if templateOutputActive() {
+        // Nothing to answer when the output feeds another program: print and stop
+        if err := renderTemplate(os.Stdout, suggestion); err != nil {
+            return "", false, fmt.Errorf("render output template: %w", err)
+        }
+        return "", false, nil
+    }
+    if !suggestion.ExplanationShown {
+        pterm.DefaultHeader.Println(suggestion.Title)
+        PrintDemoWatermark()
+    }
 
-	if suggestion.Explanation != "" {
+	if suggestion.Explanation != "" && !suggestion.ExplanationShown {
 		pterm.Println(pterm.Red("Explanation:"))
-		pterm.Println(suggestion.Explanation)
+		pterm.Println(layoutParagraph(suggestion.Explanation, 0))
 		pterm.Println()
 	}
 
 	pterm.Println(pterm.Green("Suggested Command:"))
 	pterm.Println(pterm.LightGreen(suggestion.Command))
 	pterm.Println()
+	if commandPreview {
+		renderPreview(suggestion.Command)
+	}
 
 	pterm.Println("Options:")
 	pterm.Println(pterm.LightWhite("  [Enter] - Execute the suggested command"))
 	pterm.Println(pterm.LightWhite("  [n/no]  - Reject and exit"))
-	pterm.Println(pterm.LightWhite("  [other] - Provide a new prompt for a different suggestion"))
+	pterm.Println(pterm.LightWhite("  [other] - Describe what to change, e.g. \"same but recursive\""))
 	pterm.Println()
 	pterm.Print("Select an option: ")
 
+	input, cancelled, err := readOption()
+	if err != nil {
+		return "", false, err
+	}
+	if cancelled {
+		pterm.Warning.Println("Operation cancelled by user.")
+		return "", false, nil
+	}
+
+	switch input {
+	case "": // Enter
+		return "", true, nil
+	case "n", "no":
+		pterm.Warning.Println("Operation cancelled by user.")
+		return "", false, nil
+	default:
+		return input, true, nil
+	}
+}
+
+// stdinReader is shared by every options prompt, so answers piped in together are not lost
+// to the buffer of the first prompt.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readOption reads the user's answer to an options prompt, trimmed and lower-cased. Ctrl+C
+// cancels the read instead of leaving it blocked on stdin.
+func readOption() (string, bool, error) {
     // 支援 Ctrl+C 即時取消，不阻塞在輸入讀取
     ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
     defer stop()
 
-    reader := bufio.NewReader(os.Stdin)
     readCh := make(chan string, 1)
     errCh := make(chan error, 1)
 
     go func(c context.Context) {
-        line, err := reader.ReadString('\n')
+        line, err := stdinReader.ReadString('\n')
         if err != nil {
             select {
             case <-c.Done():
@@ -87,26 +131,14 @@ func (p *Presenter) Render(suggestion Suggestion) (string, bool, error) {
         }
     }(ctx)
 
-    var input string
     select {
     case <-ctx.Done():
-        pterm.Warning.Println("Operation cancelled by user.")
-        return "", false, nil
+        return "", true, nil
     case err := <-errCh:
         return "", false, fmt.Errorf("error reading user input: %w", err)
     case line := <-readCh:
-        input = strings.TrimSpace(strings.ToLower(line))
+        return strings.TrimSpace(strings.ToLower(line)), false, nil
     }
-
-	switch input {
-	case "": // Enter
-		return "", true, nil
-	case "n", "no":
-		pterm.Warning.Println("Operation cancelled by user.")
-		return "", false, nil
-	default:
-		return input, true, nil
-	}
 }
 
 // ShowLoading displays a spinner with a message.
@@ -126,6 +158,10 @@ func (p *Presenter) ShowLoading(message string) {
         p.spinner = nil
     }
 
+    if !AnimationsEnabled() {
+        fmt.Fprintln(os.Stderr, message)
+        return
+    }
     p.spinner, _ = pterm.DefaultSpinner.Start(message)
 }
 
@@ -195,6 +231,18 @@ func (p *Presenter) ShowLoadingWithTimer(baseMessage string) error {
     }
 
     p.startTime = time.Now()
+    p.loadingBase = baseMessage
+    p.phaseMu.Lock()
+    p.phase = ""
+    p.phaseMu.Unlock()
+
+    // Plain output or animations off: a single static line instead of an animated,
+    // self-rewriting spinner, and no timer updates
+    if !AnimationsEnabled() {
+        p.mu.Unlock()
+        fmt.Fprintf(os.Stderr, "%s...\n", baseMessage)
+        return nil
+    }
 
     // Open /dev/tty for spinner output to bypass stderr redirection in shell hooks
     // This ensures spinner is always visible even when stderr is redirected to /dev/null
@@ -256,7 +304,7 @@ func (p *Presenter) ShowLoadingWithTimer(baseMessage string) error {
                 elapsedSec := int(time.Since(startAt).Seconds())
                 if elapsedSec != lastSec {
                     // Use non-blocking update to prevent deadlock
-                    spinnerPtr.UpdateText(fmt.Sprintf("%s... (%ds)", label, elapsedSec))
+                    spinnerPtr.UpdateText(fmt.Sprintf("%s... (%ds)", p.loadingLabel(label), elapsedSec))
                     lastSec = elapsedSec
                 }
             }
@@ -266,6 +314,32 @@ func (p *Presenter) ShowLoadingWithTimer(baseMessage string) error {
     return nil
 }
 
+// SetLoadingPhase shows phase next to the running loading message, e.g.
+// "Analyzing with AI · contacting provider... (3s)". With animations off the static line is
+// left alone.
+func (p *Presenter) SetLoadingPhase(phase string) {
+    p.phaseMu.Lock()
+    p.phase = phase
+    p.phaseMu.Unlock()
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.spinner != nil && p.timerCancel != nil {
+        elapsed := int(time.Since(p.startTime).Seconds())
+        p.spinner.UpdateText(fmt.Sprintf("%s... (%ds)", p.loadingLabel(p.loadingBase), elapsed))
+    }
+}
+
+// loadingLabel appends the current phase to the base loading message.
+func (p *Presenter) loadingLabel(base string) string {
+    p.phaseMu.Lock()
+    defer p.phaseMu.Unlock()
+    if p.phase == "" {
+        return base
+    }
+    return base + " · " + p.phase
+}
+
 // ShowErrorTriggersList displays only the current captured error type
 func (p *Presenter) ShowErrorTriggersList(
 	currentError string,
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TonnyWong1052/aish/internal/sandbox"
+	"github.com/pterm/pterm"
+)
+
+// commandPreview lists, under a suggested command, the files it would delete, move or
+// overwrite (user_preferences.command_preview).
+var commandPreview bool
+
+// maxPreviewEffects bounds the list; a glob can match far more files than fit on a screen.
+const maxPreviewEffects = 15
+
+// SetCommandPreview enables or disables the what-if preview of suggested commands.
+func SetCommandPreview(enabled bool) {
+	commandPreview = enabled
+}
+
+// renderPreview prints what sandbox.Analyze expects command to change when run in the current
+// directory. It prints nothing for commands that change no files.
+func renderPreview(command string) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	p := sandbox.Analyze(command, dir)
+	if p.Empty() {
+		return
+	}
+	pterm.Println(pterm.Yellow("It would change:"))
+	for i, e := range p.Effects {
+		if i == maxPreviewEffects {
+			pterm.Println(pterm.LightWhite(fmt.Sprintf("  ... and %d more", len(p.Effects)-i)))
+			break
+		}
+		pterm.Println(pterm.LightWhite("  " + strings.Replace(e.String(), " → ", plainGlyph(" → ", " to "), 1)))
+	}
+	for _, n := range p.Notes {
+		pterm.Println(pterm.Gray("  " + n))
+	}
+	pterm.Println()
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pterm/pterm"
+)
+
+// rtlMark (U+200F RIGHT-TO-LEFT MARK) tells bidi-aware terminals to lay the line out right to left.
+const rtlMark = "‏"
+
+// rtlLanguage is set from the configured language so explanations can be laid out right to left.
+var rtlLanguage bool
+
+// IsRTLLanguage reports whether a language preference is written right to left (Arabic, Hebrew, Persian, Urdu).
+func IsRTLLanguage(lang string) bool {
+	l := strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(l, "-_"); i > 0 {
+		l = l[:i]
+	}
+	switch l {
+	case "ar", "arabic", "he", "iw", "hebrew", "fa", "persian", "farsi", "ur", "urdu":
+		return true
+	}
+	return false
+}
+
+// SetLanguage records the user's language so the presenter can apply right-to-left layout for ar/he.
+func SetLanguage(lang string) {
+	rtlLanguage = IsRTLLanguage(lang)
+}
+
+// countScripts counts letters from right-to-left scripts and all other letters in s.
+func countScripts(s string) (rtl, ltr int) {
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko):
+			rtl++
+		case unicode.IsLetter(r):
+			ltr++
+		}
+	}
+	return rtl, ltr
+}
+
+// needsRTLLayout decides whether text should be laid out right to left. Text written mostly in an
+// RTL script always qualifies; with an RTL language configured, any RTL letters are enough, since
+// explanations routinely quote English command names.
+func needsRTLLayout(text string) bool {
+	rtl, ltr := countScripts(text)
+	if rtl == 0 {
+		return false
+	}
+	return rtlLanguage || rtl >= ltr
+}
+
+// layoutParagraph right-aligns right-to-left text to the terminal width and marks each line as RTL.
+// Left-to-right text, and any text in plain output mode, is returned unchanged: screen readers and
+// pipes handle bidi on their own and padding would only add noise.
+func layoutParagraph(text string, width int) string {
+	if plainOutput || !needsRTLLayout(text) {
+		return text
+	}
+	if width <= 0 {
+		width = pterm.GetTerminalWidth()
+	}
+	if width <= 0 {
+		width = 80
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = rtlMark + line
+		}
+	}
+	return lipgloss.NewStyle().Width(width).Align(lipgloss.Right).Render(strings.Join(lines, "\n"))
+}
+
+// settingRow lays out a settings TUI row as prefix+name followed by the value. For right-to-left
+// languages the row is mirrored: the value comes first and the name is right-aligned in its column.
+func settingRow(rtl bool, nameStyle lipgloss.Style, prefix, name, value string) string {
+	if !rtl {
+		return nameStyle.Render(prefix+name) + value
+	}
+	marker := strings.TrimSpace(prefix)
+	if marker == "❯" {
+		marker = "❮"
+	} else if marker == ">" {
+		marker = "<"
+	}
+	return value + nameStyle.Align(lipgloss.Right).Render(strings.TrimRight(rtlMark+name+" "+marker, " "))
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestIsRTLLanguage(t *testing.T) {
+	for _, lang := range []string{"ar", "arabic", "he", "he-IL", "ar_EG", "Hebrew"} {
+		if !IsRTLLanguage(lang) {
+			t.Errorf("expected %q to be RTL", lang)
+		}
+	}
+	for _, lang := range []string{"", "en", "zh-TW", "japanese", "area"} {
+		if IsRTLLanguage(lang) {
+			t.Errorf("expected %q to be LTR", lang)
+		}
+	}
+}
+
+func TestLayoutParagraph(t *testing.T) {
+	defer SetLanguage("")
+	defer SetPlainOutput(false)
+	SetPlainOutput(false)
+	SetLanguage("en")
+
+	ltr := "Use mkdir -p to create parent directories."
+	if got := layoutParagraph(ltr, 60); got != ltr {
+		t.Errorf("LTR text should be unchanged, got %q", got)
+	}
+
+	arabic := "استخدم الأمر لإنشاء المجلد"
+	got := layoutParagraph(arabic, 60)
+	if !strings.HasPrefix(strings.TrimLeft(got, " "), rtlMark) {
+		t.Errorf("RTL text should start with RLM after padding, got %q", got)
+	}
+	if !strings.HasPrefix(got, " ") || lipgloss.Width(got) != 60 {
+		t.Errorf("RTL text should be right-aligned to width 60, got %q (width %d)", got, lipgloss.Width(got))
+	}
+
+	// Mostly-English text with a few Arabic words is only mirrored when an RTL language is configured
+	mixed := "استخدم mkdir -p logs to create the folder"
+	if got := layoutParagraph(mixed, 60); got != mixed {
+		t.Errorf("mixed text should stay LTR for English users, got %q", got)
+	}
+	SetLanguage("ar")
+	if got := layoutParagraph(mixed, 60); got == mixed {
+		t.Error("mixed text should be laid out RTL for Arabic users")
+	}
+
+	SetPlainOutput(true)
+	if got := layoutParagraph(arabic, 60); got != arabic {
+		t.Errorf("plain output should not pad RTL text, got %q", got)
+	}
+}
+
+func TestSettingRowMirrorsForRTL(t *testing.T) {
+	nameStyle := lipgloss.NewStyle().Width(20).Align(lipgloss.Left)
+	ltr := settingRow(false, nameStyle, "   ", "Language", "English")
+	if !strings.HasPrefix(ltr, "   Language") || !strings.HasSuffix(ltr, "English") {
+		t.Errorf("unexpected LTR row %q", ltr)
+	}
+	rtl := settingRow(true, nameStyle, "> ", "Language", "العربية")
+	if !strings.HasPrefix(rtl, "العربية") || !strings.HasSuffix(rtl, "Language <") {
+		t.Errorf("unexpected RTL row %q", rtl)
+	}
+}
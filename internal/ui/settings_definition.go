@@ -3,6 +3,8 @@ package ui
 import (
     "strings"
 
+    tea "github.com/charmbracelet/bubbletea"
+
     "github.com/TonnyWong1052/aish/internal/config"
 )
 
@@ -32,6 +34,9 @@ type SettingItem struct {
     Type        SettingType     // UI control type
     Options     []SettingOption // Options for select type
     Action      func() error    // Function for action type
+    Run         func() (string, error) // Action that reports its outcome in the status line
+    Start       func() tea.Cmd  // Action that waits on the network: runs in the background and reports through an actionResultMsg
+    Confirm     string          // Question asked (y/N) before Action or Run
     GetValue    func(cfg *config.Config) interface{}
     SetValue    func(cfg *config.Config, value interface{})
 }
@@ -58,6 +63,30 @@ func GetSettingsDefinition(cfg *config.Config) []*SettingItem {
 			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.AutoExecute },
 			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.AutoExecute = v.(bool) },
 		},
+		{
+			ID:          "user_preferences.allow_complex_commands",
+			DisplayName: "Allow complex commands",
+			Description: "允許多行或含 $(...)、反引號的建議指令不經確認即執行",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.AllowComplexCommands },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.AllowComplexCommands = v.(bool) },
+		},
+		{
+			ID:          "user_preferences.command_preview",
+			DisplayName: "Command preview",
+			Description: "確認前列出建議指令會刪除、移動或覆寫的檔案",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.CommandPreview },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.CommandPreview = v.(bool) },
+		},
+		{
+			ID:          "user_preferences.fix_credential_permissions",
+			DisplayName: "Fix credential permissions",
+			Description: "啟動時將其他使用者可存取的設定與憑證檔權限改為 0600，而非僅警告",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.FixCredentialPermissions },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.FixCredentialPermissions = v.(bool) },
+		},
 		{
 			ID:          "user_preferences.show_tips",
 			DisplayName: "Show tips",
@@ -74,6 +103,70 @@ func GetSettingsDefinition(cfg *config.Config) []*SettingItem {
 			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.VerboseOutput },
 			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.VerboseOutput = v.(bool) },
 		},
+		{
+			ID:          "user_preferences.warmup",
+			DisplayName: "Connection warmup",
+			Description: "在背景預先建立與 AI 供應商的連線，縮短第一次回應時間",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.Warmup },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.Warmup = v.(bool) },
+		},
+		{
+			ID:          "user_preferences.accessibility.screen_reader",
+			DisplayName: "Screen reader mode",
+			Description: "以逐行提示取代互動式 TUI 與動畫，方便螢幕閱讀器使用",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.Accessibility.ScreenReader },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.Accessibility.ScreenReader = v.(bool) },
+		},
+		{
+			ID:          "user_preferences.ui.animations",
+			DisplayName: "Animations",
+			Description: "顯示轉圈動畫與即時計時；關閉後只顯示一行靜態的「Analyzing…」",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.UI.AnimationsEnabled() },
+			SetValue: func(c *config.Config, v interface{}) {
+				enabled := v.(bool)
+				c.UserPreferences.UI.Animations = &enabled
+			},
+		},
+		{
+			ID:          "user_preferences.ui.key_bindings",
+			DisplayName: "Key bindings",
+			Description: "設定頁的按鍵風格；個別按鍵可用 'aish config set ui.keys.<action>' 覆寫",
+			Type:        SettingTypeSelect,
+			Options: []SettingOption{
+				{Value: KeyStyleDefault, DisplayName: "Default (arrows + hjkl)"},
+				{Value: KeyStyleVim, DisplayName: "Vim"},
+				{Value: KeyStyleEmacs, DisplayName: "Emacs"},
+			},
+			GetValue: func(c *config.Config) interface{} {
+				if c.UserPreferences.UI.KeyBindings == "" {
+					return KeyStyleDefault
+				}
+				return c.UserPreferences.UI.KeyBindings
+			},
+			SetValue: func(c *config.Config, v interface{}) { c.UserPreferences.UI.KeyBindings = v.(string) },
+		},
+		{
+			ID:          "user_preferences.updates.check",
+			DisplayName: "Check for updates",
+			Description: "每週在背景檢查新版本，有新版時每天最多提示一次",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.Updates.CheckEnabled() },
+			SetValue: func(c *config.Config, v interface{}) {
+				enabled := v.(bool)
+				c.UserPreferences.Updates.Check = &enabled
+			},
+		},
+		{
+			ID:          "user_preferences.telemetry.enabled",
+			DisplayName: "Feature usage counters",
+			Description: "在本機匿名統計各指令的使用次數（不會上傳），以 'aish stats --features' 查看",
+			Type:        SettingTypeBoolean,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.Telemetry.Enabled },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.Telemetry.Enabled = v.(bool) },
+		},
 		{
 			ID:          "user_preferences.language",
 			DisplayName: "Language",
@@ -88,10 +181,28 @@ func GetSettingsDefinition(cfg *config.Config) []*SettingItem {
                 {Value: "es", DisplayName: "Español"},
                 {Value: "fr", DisplayName: "Français"},
                 {Value: "de", DisplayName: "Deutsch"},
+                {Value: "ar", DisplayName: "العربية"},
+                {Value: "he", DisplayName: "עברית"},
             },
 			GetValue: func(c *config.Config) interface{} { return c.UserPreferences.Language },
 			SetValue: func(c *config.Config, v interface{}) { c.UserPreferences.Language = v.(string) },
 		},
+		{
+			ID:          "user_preferences.locale",
+			DisplayName: "Date & number format",
+			Description: "日期與數字格式的地區設定，例如 de_DE、en_US；留空則依系統 locale",
+			Type:        SettingTypeText,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.Locale },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.Locale = v.(string) },
+		},
+		{
+			ID:          "user_preferences.timezone",
+			DisplayName: "Time zone",
+			Description: "顯示時間所用的時區（IANA 名稱，例如 Asia/Taipei）；留空則依系統時區",
+			Type:        SettingTypeText,
+			GetValue:    func(c *config.Config) interface{} { return c.UserPreferences.Timezone },
+			SetValue:    func(c *config.Config, v interface{}) { c.UserPreferences.Timezone = v.(string) },
+		},
 
     // LLM Providers
     {
@@ -107,9 +218,12 @@ func GetSettingsDefinition(cfg *config.Config) []*SettingItem {
 				{Value: config.ProviderOpenAI, DisplayName: "OpenAI"},
 				{Value: config.ProviderGemini, DisplayName: "Gemini API"},
 				{Value: config.ProviderGeminiCLI, DisplayName: "Gemini CLI"},
+				{Value: config.ProviderClaude, DisplayName: "Claude"},
+				{Value: config.ProviderOllama, DisplayName: "Ollama"},
+				{Value: config.ProviderVertex, DisplayName: "Vertex AI"},
 			},
 			GetValue: func(c *config.Config) interface{} { return c.DefaultProvider },
-			SetValue: func(c *config.Config, v interface{}) { c.DefaultProvider = v.(string) },
+			SetValue: func(c *config.Config, v interface{}) { _ = c.UseProvider(v.(string), "") },
 		},
     // API Host（Gemini CLI 不允許編輯，其餘可編輯）
     func() *SettingItem {
@@ -185,6 +299,13 @@ func GetSettingsDefinition(cfg *config.Config) []*SettingItem {
                 c.Providers[c.DefaultProvider] = p
             }
         },
+    },
+    {
+        ID:          "provider.check_connection",
+        DisplayName: "Check connection",
+        Description: "以目前的設定連線預設供應商並確認模型（與 aish use 相同的檢查）",
+        Type:        SettingTypeAction,
+        Start:       func() tea.Cmd { return checkDefaultProvider(cfg) },
     },
 		// 移除「啟動完整設定精靈」動作項，避免在設定頁面出現 [Action]
 
@@ -246,5 +367,6 @@ func GetSettingsDefinition(cfg *config.Config) []*SettingItem {
         }(),
     }
 
+    settings = append(settings, maintenanceSettings(cfg)...)
     return settings
 }
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/cache"
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/history"
+)
+
+// historyPruneAge is how old history entries must be for the prune action to drop them.
+const historyPruneAge = 30 * 24 * time.Hour
+
+// maintenanceSettings returns the actions for cache and history upkeep, the same work as
+// 'aish cache' and 'aish history clear' without leaving the settings page.
+func maintenanceSettings(cfg *config.Config) []*SettingItem {
+	return []*SettingItem{
+		{
+			DisplayName: "Maintenance",
+			Type:        SettingTypeGroup,
+		},
+		{
+			ID:          "maintenance.disk_usage",
+			DisplayName: "Show disk usage",
+			Description: "顯示 aish 設定、狀態、快取與日誌目錄佔用的空間",
+			Type:        SettingTypeAction,
+			Run:         describeDiskUsage,
+		},
+		{
+			ID:          "maintenance.clear_cache",
+			DisplayName: "Clear response cache",
+			Description: "刪除所有快取的 AI 回應",
+			Type:        SettingTypeAction,
+			Confirm:     "Delete every cached response?",
+			Run: func() (string, error) {
+				return clearResponseCache(cfg.UserPreferences.Cache)
+			},
+		},
+		{
+			ID:          "maintenance.prune_history",
+			DisplayName: "Prune history older than 30 days",
+			Description: "刪除 30 天前的歷史記錄",
+			Type:        SettingTypeAction,
+			Confirm:     "Delete history entries older than 30 days?",
+			Run: func() (string, error) {
+				n, err := history.Prune(time.Now().Add(-historyPruneAge))
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Removed %d history entries", n), nil
+			},
+		},
+		{
+			ID:          "maintenance.clear_history",
+			DisplayName: "Clear all history",
+			Description: "刪除全部歷史記錄",
+			Type:        SettingTypeAction,
+			Confirm:     "Delete the whole history? This cannot be undone.",
+			Run: func() (string, error) {
+				if err := history.Clear(); err != nil {
+					return "", err
+				}
+				return "History cleared", nil
+			},
+		},
+	}
+}
+
+// clearResponseCache empties the response cache configured by cacheCfg without creating it when
+// there is none.
+func clearResponseCache(cacheCfg config.CacheConfig) (string, error) {
+	cfg := cache.ResponseCacheConfig(cacheCfg)
+	if _, err := os.Stat(cfg.CacheDir); os.IsNotExist(err) {
+		return "The cache is already empty", nil
+	}
+	c, err := cache.NewCache(cfg)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	n := c.GetStats().Entries
+	if err := c.Clear(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Removed %d cached responses", n), nil
+}
+
+// dataDir is a directory aish writes to, as reported by the disk usage action.
+type dataDir struct {
+	label string
+	path  string
+}
+
+// describeDiskUsage sums the size of each aish data directory. The directories often nest
+// (the cache and logs live under the config directory by default), so each total leaves out
+// the others and a directory shared by several roles is listed once.
+func describeDiskUsage() (string, error) {
+	var dirs []dataDir
+	for _, d := range []struct {
+		label string
+		dir   func() (string, error)
+	}{
+		{"config", config.ConfigDir},
+		{"state", config.StateDir},
+		{"cache", config.CacheDir},
+		{"logs", config.LogDir},
+	} {
+		path, err := d.dir()
+		if err != nil {
+			return "", err
+		}
+		dirs = addDataDir(dirs, d.label, filepath.Clean(path))
+	}
+
+	parts := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		var others []string
+		for _, o := range dirs {
+			if o.path != d.path {
+				others = append(others, o.path)
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", d.label, humanBytes(dirSize(d.path, others))))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func addDataDir(dirs []dataDir, label, path string) []dataDir {
+	for i := range dirs {
+		if dirs[i].path == path {
+			dirs[i].label += "/" + label
+			return dirs
+		}
+	}
+	return append(dirs, dataDir{label: label, path: path})
+}
+
+// dirSize returns the total size of the regular files under root, skipping the directories
+// in exclude. A missing root is empty.
+func dirSize(root string, exclude []string) int64 {
+	var total int64
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			for _, ex := range exclude {
+				if path == ex {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// humanBytes formats n in binary units, e.g. "3.2 MiB".
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTPE"[exp])
+}
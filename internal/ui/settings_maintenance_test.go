@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+func TestActionConfirmation(t *testing.T) {
+	m := NewSettingsModel(&config.Config{Providers: map[string]config.ProviderConfig{}})
+	runs := 0
+	item := &SettingItem{
+		DisplayName: "Danger",
+		Type:        SettingTypeAction,
+		Confirm:     "Really?",
+		Run:         func() (string, error) { runs++; return "done", nil },
+	}
+
+	m.handleAction(item)
+	if m.confirmItem != item || runs != 0 {
+		t.Fatalf("action should wait for confirmation (runs=%d)", runs)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if runs != 0 || m.confirmItem != nil || m.message != "Canceled" {
+		t.Fatalf("n should cancel: runs=%d message=%q", runs, m.message)
+	}
+
+	m.handleAction(item)
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if runs != 1 || m.message != "done" {
+		t.Fatalf("y should run the action: runs=%d message=%q", runs, m.message)
+	}
+}
+
+func TestDirSizeSkipsNestedDataDirs(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(root, "cache")
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config.json"), make([]byte, 10), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, "entry"), make([]byte, 100), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := dirSize(root, []string{cacheDir}); got != 10 {
+		t.Errorf("config size = %d, want 10", got)
+	}
+	if got := dirSize(cacheDir, []string{root}); got != 100 {
+		t.Errorf("cache size = %d, want 100", got)
+	}
+	if got := dirSize(filepath.Join(root, "missing"), nil); got != 0 {
+		t.Errorf("missing dir size = %d, want 0", got)
+	}
+}
+
+func TestAddDataDirMergesSharedPaths(t *testing.T) {
+	dirs := addDataDir(nil, "config", "/a")
+	dirs = addDataDir(dirs, "state", "/a")
+	dirs = addDataDir(dirs, "cache", "/a/cache")
+	if len(dirs) != 2 || dirs[0].label != "config/state" || dirs[1].label != "cache" {
+		t.Fatalf("unexpected dirs: %+v", dirs)
+	}
+}
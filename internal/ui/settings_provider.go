@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	"github.com/TonnyWong1052/aish/internal/llm"
+	"github.com/TonnyWong1052/aish/internal/prompt"
+)
+
+// providerCheckTimeout bounds the connection check of the settings page.
+const providerCheckTimeout = 15 * time.Second
+
+// actionResultMsg carries the outcome of an action run in the background to the status line.
+type actionResultMsg struct {
+	result string
+	err    error
+}
+
+// checkDefaultProvider returns the check 'aish use' makes, asking the default provider for its
+// models with the settings as edited so far, as a command that reports through an
+// actionResultMsg, so the page stays responsive while the provider is waited for. The
+// settings are copied first: the page goes on editing cfg while the check runs.
+func checkDefaultProvider(cfg *config.Config) tea.Cmd {
+	name := cfg.DefaultProvider
+	pc := cfg.Providers[name]
+	pc.ExtraHeaders = maps.Clone(pc.ExtraHeaders)
+	return func() tea.Msg {
+		result, err := checkProvider(name, pc)
+		return actionResultMsg{result: result, err: err}
+	}
+}
+
+// checkProvider checks provider name with the settings in pc and describes the outcome for the
+// status line.
+func checkProvider(name string, pc config.ProviderConfig) (string, error) {
+	provider, err := llm.GetProvider(name, pc, prompt.NewDefaultManager())
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), providerCheckTimeout)
+	defer cancel()
+	models, err := llm.CheckConnection(ctx, name, provider)
+	if err != nil {
+		return "", fmt.Errorf("%s did not answer: %w", name, err)
+	}
+	if len(models) > 0 && pc.Model != "" && !llm.ModelListed(models, pc.Model) {
+		return fmt.Sprintf("%s answers but does not list the model %s", name, pc.Model), nil
+	}
+	return fmt.Sprintf("%s answers with %s", name, pc.Model), nil
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+	_ "github.com/TonnyWong1052/aish/internal/llm/mock"
+)
+
+// runCheck runs the connection check of the settings page to its result.
+func runCheck(cfg *config.Config) (string, error) {
+	res := checkDefaultProvider(cfg)().(actionResultMsg)
+	return res.result, res.err
+}
+
+func TestCheckDefaultProvider(t *testing.T) {
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	cfg := &config.Config{DefaultProvider: config.ProviderMock, Providers: map[string]config.ProviderConfig{
+		config.ProviderMock: {Model: "mock"},
+	}}
+
+	msg, err := runCheck(cfg)
+	if err != nil || msg != "mock answers with mock" {
+		t.Errorf("got %q, %v", msg, err)
+	}
+
+	cfg.Providers[config.ProviderMock] = config.ProviderConfig{Model: "other"}
+	if msg, _ := runCheck(cfg); !strings.Contains(msg, "does not list the model other") {
+		t.Errorf("unlisted model not reported: %q", msg)
+	}
+
+	cfg.DefaultProvider = "nope"
+	if _, err := runCheck(cfg); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestCheckDefaultProviderRunsInBackground(t *testing.T) {
+	t.Setenv(config.EnvAISHConfigDir, t.TempDir())
+	cfg := &config.Config{DefaultProvider: config.ProviderMock, Providers: map[string]config.ProviderConfig{
+		config.ProviderMock: {Model: "mock"},
+	}}
+	m := NewSettingsModel(cfg)
+	var item *SettingItem
+	for _, s := range m.settings {
+		if s.ID == "provider.check_connection" {
+			item = s
+		}
+	}
+	if item == nil {
+		t.Fatal("no connection check on the settings page")
+	}
+
+	_, cmd := m.handleAction(item)
+	if cmd == nil || !m.running || m.message != "Check connection…" {
+		t.Fatalf("the check should be started as a command: message=%q", m.message)
+	}
+	// Settings edited while the check runs do not change the check under way
+	cfg.Providers[config.ProviderMock] = config.ProviderConfig{Model: "other"}
+	if _, again := m.handleAction(item); again != nil {
+		t.Error("a second check started while the first was running")
+	}
+
+	m.Update(cmd())
+	if m.running || m.message != "mock answers with mock" {
+		t.Errorf("result not shown: running=%v message=%q", m.running, m.message)
+	}
+}
@@ -20,6 +20,8 @@ type KeyMap struct {
 	Down       key.Binding
 	Left       key.Binding
 	Right      key.Binding
+	PageUp     key.Binding
+	PageDown   key.Binding
 	Enter      key.Binding
 	Space      key.Binding
 	Tab        key.Binding
@@ -30,48 +32,8 @@ type KeyMap struct {
 
 // DefaultKeyMap returns the default key bindings
 func DefaultKeyMap() KeyMap {
-    return KeyMap{
-        Up: key.NewBinding(
-            key.WithKeys("up", "k"),
-            key.WithHelp("↑/k", "up"),
-        ),
-        Down: key.NewBinding(
-            key.WithKeys("down", "j"),
-            key.WithHelp("↓/j", "down"),
-        ),
-        Left: key.NewBinding(
-            key.WithKeys("left", "h"),
-            key.WithHelp("←/h", "previous option"),
-        ),
-        Right: key.NewBinding(
-            key.WithKeys("right", "l"),
-            key.WithHelp("→/l", "next option"),
-        ),
-        Enter: key.NewBinding(
-            key.WithKeys("enter"),
-            key.WithHelp("enter", "confirm/execute"),
-        ),
-        Space: key.NewBinding(
-            key.WithKeys(" "),
-            key.WithHelp("space", "toggle"),
-        ),
-        Tab: key.NewBinding(
-            key.WithKeys("tab"),
-            key.WithHelp("tab", "next item"),
-        ),
-        ShiftTab: key.NewBinding(
-            key.WithKeys("shift+tab"),
-            key.WithHelp("shift+tab", "previous item"),
-        ),
-        Quit: key.NewBinding(
-            key.WithKeys("q", "esc"),
-            key.WithHelp("q/esc", "quit"),
-        ),
-        Help: key.NewBinding(
-            key.WithKeys("?"),
-            key.WithHelp("?", "help"),
-        ),
-    }
+    keys, _ := NewKeyMap(KeyStyleDefault, nil)
+    return keys
 }
 
 // settingsItem wraps SettingItem for list.Item interface
@@ -104,6 +66,11 @@ type SettingsModel struct {
     multiOptions []string
     multiSelected []bool
     multiCursor  int
+
+    // action waiting for a y/N answer to its Confirm question
+    confirmItem *SettingItem
+    // an action started in the background has not reported back yet
+    running bool
 }
 
 // findFirstInteractiveItem finds the index of the first interactive setting item
@@ -147,13 +114,31 @@ func NewSettingsModel(cfg *config.Config) *SettingsModel {
         l.Select(firstInteractiveIndex)
     }
 
-    return &SettingsModel{
+    m := &SettingsModel{
         list:     l,
-        keys:     DefaultKeyMap(),
         config:   cfg,
         settings: settings,
         selectionInitialized: firstInteractiveIndex == 0, // set true if already at 0 (no adjustment needed later)
     }
+    m.applyKeyBindings()
+    return m
+}
+
+// applyKeyBindings loads the key bindings from the config into the model and the list, which
+// handles moving and paging itself. Invalid bindings fall back to the default style.
+func (m *SettingsModel) applyKeyBindings() {
+    ui := m.config.UserPreferences.UI
+    keys, err := NewKeyMap(ui.KeyBindings, ui.Keys)
+    if err != nil {
+        keys = DefaultKeyMap()
+        m.message = fmt.Sprintf("Invalid key bindings, using the defaults: %v", err)
+    }
+    m.keys = keys
+    m.list.KeyMap.CursorUp = keys.Up
+    m.list.KeyMap.CursorDown = keys.Down
+    m.list.KeyMap.PrevPage = keys.PageUp
+    m.list.KeyMap.NextPage = keys.PageDown
+    m.list.KeyMap.Quit = keys.Quit
 }
 
 // Init implements tea.Model
@@ -180,14 +165,48 @@ func (m *SettingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
             m.selectionInitialized = true
         }
 
+    case actionResultMsg:
+        m.running = false
+        if msg.err != nil {
+            m.message = fmt.Sprintf("Error: %v", msg.err)
+        } else {
+            m.message = msg.result
+        }
+        return m, nil
+
     case tea.KeyMsg:
         // 當多選面板開啟時，攔截按鍵事件處理
         if m.multiActive {
+            switch {
+            case key.Matches(msg, m.keys.Up):
+                if m.multiCursor > 0 { m.multiCursor-- } else { m.multiCursor = len(m.multiOptions) - 1 }
+                return m, nil
+            case key.Matches(msg, m.keys.Down):
+                if m.multiCursor < len(m.multiOptions)-1 { m.multiCursor++ } else { m.multiCursor = 0 }
+                return m, nil
+            case key.Matches(msg, m.keys.Space):
+                if len(m.multiSelected) > 0 {
+                    m.multiSelected[m.multiCursor] = !m.multiSelected[m.multiCursor]
+                }
+                return m, nil
+            case key.Matches(msg, m.keys.Enter):
+                // 套用選取
+                var result []string
+                for i, v := range m.multiSelected { if v { result = append(result, m.multiOptions[i]) } }
+                // 寫入設定
+                m.config.UserPreferences.EnabledLLMTriggers = result
+                // 關閉面板並刷新列表
+                m.multiActive = false
+                m.refreshSettings()
+                m.message = "Error triggers updated"
+                return m, nil
+            }
             switch msg.Type {
-            case tea.KeyUp, tea.KeyCtrlP:
+            // The panel has always moved with ctrl+p/ctrl+n too, whatever the style
+            case tea.KeyCtrlP:
                 if m.multiCursor > 0 { m.multiCursor-- } else { m.multiCursor = len(m.multiOptions) - 1 }
                 return m, nil
-            case tea.KeyDown, tea.KeyCtrlN:
+            case tea.KeyCtrlN:
                 if m.multiCursor < len(m.multiOptions)-1 { m.multiCursor++ } else { m.multiCursor = 0 }
                 return m, nil
             case tea.KeyRunes:
@@ -205,22 +224,6 @@ func (m *SettingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                         return m, nil
                     }
                 }
-            case tea.KeySpace:
-                if len(m.multiSelected) > 0 {
-                    m.multiSelected[m.multiCursor] = !m.multiSelected[m.multiCursor]
-                }
-                return m, nil
-            case tea.KeyEnter:
-                // 套用選取
-                var result []string
-                for i, v := range m.multiSelected { if v { result = append(result, m.multiOptions[i]) } }
-                // 寫入設定
-                m.config.UserPreferences.EnabledLLMTriggers = result
-                // 關閉面板並刷新列表
-                m.multiActive = false
-                m.refreshSettings()
-                m.message = "Error triggers updated"
-                return m, nil
             case tea.KeyEsc:
                 // 取消關閉
                 m.multiActive = false
@@ -230,6 +233,16 @@ func (m *SettingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
             // 若未處理的其它鍵，忽略
             return m, nil
         }
+        // A pending confirmation takes the next key: y runs the action, anything else cancels
+        if m.confirmItem != nil {
+            item := m.confirmItem
+            m.confirmItem = nil
+            if msg.Type == tea.KeyRunes && len(msg.Runes) == 1 && (msg.Runes[0] == 'y' || msg.Runes[0] == 'Y') {
+                return m.runAction(item)
+            }
+            m.message = "Canceled"
+            return m, nil
+        }
         // If we are in editing mode, route keys to text input
         if m.isEditing {
             switch msg.Type {
@@ -343,18 +356,33 @@ func (m *SettingsModel) View() string {
         editLine = promptStyle.Render(label+": ") + m.textInput.View()
     }
 
+    // Confirmation prompt line for actions that ask first
+    var confirmLine string
+    if m.confirmItem != nil {
+        confirmStyle := lipgloss.NewStyle().
+            Foreground(lipgloss.Color("15")). // White
+            Background(lipgloss.Color("1")).  // Red background
+            Padding(0, 2).
+            MarginTop(1).
+            Bold(true)
+        confirmLine = confirmStyle.Render(m.confirmItem.Confirm + " (y/N)")
+    }
+
     // Modern help line（根據是否開啟多選面板顯示不同提示）
     helpStyle := lipgloss.NewStyle().
         Foreground(lipgloss.Color("8")).      // Gray
         Background(lipgloss.Color("0")).      // Black background
         Padding(0, 2).
         MarginTop(1).
-        Border(lipgloss.RoundedBorder(), true, false, false, false).
+        Border(plainBorder(lipgloss.RoundedBorder()), true, false, false, false).
         BorderForeground(lipgloss.Color("8"))
 
-    helpText := "Navigate: ↑↓  Toggle: Space  Select: ←→  Action: Enter  Quit: q"
+    helpText := m.keys.helpLine()
+    if m.confirmItem != nil {
+        helpText = "y Confirm  any other key Cancel"
+    }
     if m.multiActive {
-        helpText = "↑↓ Move  Space Toggle  a All  i Invert  Enter Confirm  Esc Cancel"
+        helpText = m.keys.multiSelectHelpLine()
     }
     helpLine := helpStyle.Render(helpText)
 
@@ -362,7 +390,7 @@ func (m *SettingsModel) View() string {
     if m.multiActive {
         // 容器與清單樣式
         box := lipgloss.NewStyle().
-            Border(lipgloss.RoundedBorder()).
+            Border(plainBorder(lipgloss.RoundedBorder())).
             BorderForeground(lipgloss.Color("12")).
             Padding(1, 2).
             Width(m.width-6)
@@ -385,6 +413,8 @@ func (m *SettingsModel) View() string {
     // Combine all parts（一般模式）
     var content string
     switch {
+    case confirmLine != "":
+        content = lipgloss.JoinVertical(lipgloss.Left, listContent, confirmLine, helpLine)
     case m.isEditing && statusLine != "":
         content = lipgloss.JoinVertical(lipgloss.Left, listContent, editLine, statusLine, helpLine)
     case m.isEditing:
@@ -405,17 +435,7 @@ func (m *SettingsModel) handleAction(item *SettingItem) (*SettingsModel, tea.Cmd
         // 針對錯誤觸發類型，使用內嵌多選面板，不呼叫外部 Stdout UI
         if item.ID == "user_preferences.enabled_llm_triggers" {
             // 與 settings_definition 中的選項一致
-            opts := []string{
-                "CommandNotFound",
-                "FileNotFoundOrDirectory",
-                "PermissionDenied",
-                "CannotExecute",
-                "InvalidArgumentOrOption",
-                "ResourceExists",
-                "NotADirectory",
-                "TerminatedBySignal",
-                "GenericError",
-            }
+            opts := defaultTriggerOptions()
             sel := make([]bool, len(opts))
             // 依據現有設定預選
             current := map[string]bool{}
@@ -428,21 +448,46 @@ func (m *SettingsModel) handleAction(item *SettingItem) (*SettingsModel, tea.Cmd
             m.multiCursor = 0
             return m, nil
         }
-        if item.Action != nil {
-            if err := item.Action(); err != nil {
-                m.message = fmt.Sprintf("Error: %v", err)
-            } else {
-                m.message = "Action completed successfully"
-                // Refresh settings to update dynamic items
-                m.refreshSettings()
-            }
+        if item.Confirm != "" {
+            m.confirmItem = item
+            return m, nil
         }
+        return m.runAction(item)
     case SettingTypeBoolean:
         return m.toggleBoolean(item)
     }
     return m, nil
 }
 
+// runAction runs an action item and reports its outcome in the status line
+func (m *SettingsModel) runAction(item *SettingItem) (*SettingsModel, tea.Cmd) {
+    switch {
+    case item.Start != nil:
+        if m.running {
+            m.message = "Still waiting for the previous action"
+            return m, nil
+        }
+        m.running = true
+        m.message = item.DisplayName + "…"
+        return m, item.Start()
+    case item.Run != nil:
+        if result, err := item.Run(); err != nil {
+            m.message = fmt.Sprintf("Error: %v", err)
+        } else {
+            m.message = result
+        }
+    case item.Action != nil:
+        if err := item.Action(); err != nil {
+            m.message = fmt.Sprintf("Error: %v", err)
+        } else {
+            m.message = "Action completed successfully"
+            // Refresh settings to update dynamic items
+            m.refreshSettings()
+        }
+    }
+    return m, nil
+}
+
 // beginTextEdit initializes the text input for a text setting
 func (m *SettingsModel) beginTextEdit(item *SettingItem) (*SettingsModel, tea.Cmd) {
     m.isEditing = true
@@ -515,6 +560,9 @@ func (m *SettingsModel) changeSelectOption(item *SettingItem, direction int) (*S
     item.SetValue(m.config, newValue)
     // 選項變更後可能影響其他動態項目（例如 provider 切換後 API Host 編輯權限），刷新列表
     m.refreshSettings()
+    if item.ID == "user_preferences.ui.key_bindings" {
+        m.applyKeyBindings()
+    }
     return m, nil
 }
 
@@ -546,6 +594,7 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 // renderItem renders a single setting item
 func (d itemDelegate) renderItem(item settingsItem, isSelected bool) string {
 	setting := item.SettingItem
+	rtl := item.config != nil && IsRTLLanguage(item.config.UserPreferences.EffectiveLanguage())
 	
 	// Modern, clean style definitions
 	selectedStyle := lipgloss.NewStyle().
@@ -622,7 +671,7 @@ func (d itemDelegate) renderItem(item settingsItem, isSelected bool) string {
 			valueStyle = valueStyle.Foreground(lipgloss.Color("8")) // Gray for false
 		}
 		
-		content := nameStyle.Render("   "+setting.DisplayName) + valueStyle.Render(value)
+		content := settingRow(rtl, nameStyle, "   ", setting.DisplayName, valueStyle.Render(value))
 		return style.Render(content)
 
 	case SettingTypeSelect:
@@ -643,14 +692,14 @@ func (d itemDelegate) renderItem(item settingsItem, isSelected bool) string {
 		nameStyle := lipgloss.NewStyle().Width(39).Align(lipgloss.Left)
 		valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")) // Bright blue
 		
-		content := "❯ " + nameStyle.Render(setting.DisplayName) + valueStyle.Render(value)
+		content := settingRow(rtl, nameStyle, plainGlyph("❯ ", "> "), setting.DisplayName, valueStyle.Render(value))
 		return style.Render(content)
 
     case SettingTypeAction:
 		nameStyle := lipgloss.NewStyle().Width(40).Align(lipgloss.Left)
 		actionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("11")) // Yellow
 		
-		content := nameStyle.Render("   "+setting.DisplayName) + actionStyle.Render("[Action]")
+		content := settingRow(rtl, nameStyle, "   ", setting.DisplayName, actionStyle.Render("[Action]"))
 		return style.Render(content)
 
     case SettingTypeInfo:
@@ -664,7 +713,7 @@ func (d itemDelegate) renderItem(item settingsItem, isSelected bool) string {
 		nameStyle := lipgloss.NewStyle().Width(40).Align(lipgloss.Left)
 		valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8")) // Gray for info
 		
-        content := nameStyle.Render("   "+setting.DisplayName) + valueStyle.Render(value)
+        content := settingRow(rtl, nameStyle, "   ", setting.DisplayName, valueStyle.Render(value))
         return style.Render(content)
 
     case SettingTypeText:
@@ -676,7 +725,7 @@ func (d itemDelegate) renderItem(item settingsItem, isSelected bool) string {
         }
         nameStyle := lipgloss.NewStyle().Width(40).Align(lipgloss.Left)
         valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")) // Bright blue
-        content := nameStyle.Render("   "+setting.DisplayName) + valueStyle.Render(value)
+        content := settingRow(rtl, nameStyle, "   ", setting.DisplayName, valueStyle.Render(value))
         return style.Render(content)
 
 	default:
@@ -688,6 +737,17 @@ func (d itemDelegate) renderItem(item settingsItem, isSelected bool) string {
 
 // RunSettingsTUI runs the settings TUI
 func RunSettingsTUI(cfg *config.Config) error {
+	// Screen readers cannot follow an alt-screen TUI; use linear prompts instead
+	if screenReaderMode {
+		if err := runLinearSettings(cfg); err != nil {
+			return fmt.Errorf("failed to run settings: %w", err)
+		}
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+		return nil
+	}
+
 	model := NewSettingsModel(cfg)
 	
 	// Use default input/output for proper terminal handling
@@ -50,6 +50,12 @@ func (s *AnimatedSpinner) Start() {
 	s.isRunning = true
 	s.startTime = time.Now()
 
+	// 純文字模式或關閉動畫：只輸出一次訊息，不啟動動畫
+	if !AnimationsEnabled() {
+		fmt.Printf("%s...\n", s.message)
+		return
+	}
+
 	s.wg.Add(1)
 	go s.animate()
 }
@@ -67,10 +73,19 @@ func (s *AnimatedSpinner) Stop(success bool) {
 	s.wg.Wait()
 	s.isRunning = false
 
+	duration := time.Since(s.startTime)
+	if plainOutput {
+		if success {
+			fmt.Printf("%s done (%.1fs)\n", s.message, duration.Seconds())
+		} else {
+			fmt.Printf("%s failed (%.1fs)\n", s.message, duration.Seconds())
+		}
+		return
+	}
+
 	// 清除當前行並顯示結果
 	fmt.Print("\r\033[K")
 
-	duration := time.Since(s.startTime)
 	if success {
 		fmt.Printf("✅ %s (%.1fs)\n", s.message, duration.Seconds())
 	} else {
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+
+	"github.com/TonnyWong1052/aish/internal/llm"
+)
+
+// StreamView prints a provider response while it streams in, in place of the loading
+// spinner. It shows one JSON field of the response (or the whole response when it is plain
+// text), optionally passed through a visible func that picks out the readable part. Text that
+// has been printed cannot be taken back, so a later version that no longer extends it is not
+// shown. A nil *StreamView ignores every call.
+type StreamView struct {
+	presenter *Presenter
+	title     string
+	label     string
+	field     string
+	visible   func(string) string
+
+	mu        sync.Mutex
+	raw       strings.Builder
+	printed   string
+	held      bool
+	gen       int // Bumped by Reset; callbacks handed out before then are ignored
+	started   chan struct{}
+	startOnce sync.Once
+}
+
+// NewStreamView returns a view that prints the streamed value of field under title and label
+// (either may be empty) once the first text arrives, stopping the presenter's spinner. It
+// returns nil when the output is quiet, JSON or goes through an output template, where a
+// partial response must not be printed.
+func (p *Presenter) NewStreamView(title, label, field string, visible func(string) string) *StreamView {
+	if IsQuietOutput() || IsJSONOutput() || templateOutputActive() {
+		return nil
+	}
+	return &StreamView{presenter: p, title: title, label: label, field: field, visible: visible, started: make(chan struct{})}
+}
+
+// Callback returns the function providers pass the response to, or nil for a nil view so the
+// provider does not stream at all. Ask for it for each request: once Reset abandons a request,
+// text its callback still receives is dropped.
+func (v *StreamView) Callback() llm.StreamFunc {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	gen := v.gen
+	v.mu.Unlock()
+	return func(delta string) { v.write(gen, delta) }
+}
+
+func (v *StreamView) write(gen int, delta string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if gen != v.gen {
+		return
+	}
+	v.raw.WriteString(delta)
+	v.startOnce.Do(func() { close(v.started) })
+	if !v.held {
+		v.flushLocked()
+	}
+}
+
+// flushLocked prints the text that has arrived since the last flush.
+func (v *StreamView) flushLocked() {
+	text := llm.StreamedText(v.raw.String(), v.field)
+	if v.visible != nil {
+		text = v.visible(text)
+	}
+	if len(text) <= len(v.printed) || !strings.HasPrefix(text, v.printed) {
+		return
+	}
+	if v.printed == "" {
+		v.presenter.StopLoading(false)
+		if v.title != "" {
+			pterm.DefaultHeader.Println(v.title)
+			PrintDemoWatermark()
+		}
+		if v.label != "" {
+			pterm.Println(pterm.Red(v.label))
+		}
+	}
+	pterm.Print(text[len(v.printed):])
+	v.printed = text
+}
+
+// Started is closed when the first piece of the response arrives, even while the view is held.
+func (v *StreamView) Started() <-chan struct{} {
+	if v == nil {
+		return nil
+	}
+	return v.started
+}
+
+// Hold keeps arriving text from being printed, e.g. while the terminal is in raw mode.
+func (v *StreamView) Hold() {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	v.held = true
+	v.mu.Unlock()
+}
+
+// Release prints the text held back by Hold and resumes printing as it arrives.
+func (v *StreamView) Release() {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.held = false
+	v.flushLocked()
+}
+
+// Reset discards a response that was held and never printed, e.g. when the request is
+// abandoned for another provider, and ignores whatever the abandoned request still streams.
+func (v *StreamView) Reset() {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.gen++
+	if v.printed == "" {
+		v.raw.Reset()
+	}
+}
+
+// Finish completes the view with final, the text it was streaming as the caller ended up with
+// it: the part of final not printed yet is printed and the text is ended with a blank line. It
+// reports whether final is now on screen in full; when nothing was printed, or the printed text
+// turned out not to be the start of final, it returns false and the caller shows final as usual.
+func (v *StreamView) Finish(final string) bool {
+	if v == nil {
+		return false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.printed == "" {
+		return false
+	}
+	final = strings.TrimRight(final, " \t\r\n")
+	complete := false
+	switch {
+	case final == "":
+	case strings.HasPrefix(final, v.printed):
+		pterm.Print(final[len(v.printed):])
+		complete = true
+	case final == strings.TrimRight(v.printed, " \t\r\n"):
+		complete = true
+	}
+	pterm.Println()
+	pterm.Println()
+	return complete
+}
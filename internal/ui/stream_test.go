@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+)
+
+func captureStreamOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	pterm.SetDefaultOutput(&buf)
+	pterm.DisableStyling()
+	t.Cleanup(func() {
+		pterm.SetDefaultOutput(nil)
+		pterm.EnableStyling()
+	})
+	return &buf
+}
+
+func TestStreamViewPrintsFieldAsItArrives(t *testing.T) {
+	buf := captureStreamOutput(t)
+	v := NewPresenter().NewStreamView("", "Explanation:", "explanation", nil)
+	write := v.Callback()
+
+	write(`{"explanation": "The fi`)
+	write(`le is missing", "corrected_command": "touch f"}`)
+	if !v.Finish("The file is missing") {
+		t.Fatal("Finish should report the explanation as shown")
+	}
+	if got := buf.String(); !strings.Contains(got, "Explanation:\nThe file is missing\n") {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestStreamViewHoldAndReset(t *testing.T) {
+	buf := captureStreamOutput(t)
+	v := NewPresenter().NewStreamView("", "", "command", nil)
+	v.Hold()
+	v.Callback()(`{"command": "ls"`)
+	select {
+	case <-v.Started():
+	default:
+		t.Fatal("Started should be closed once text arrives")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("held view printed %q", buf.String())
+	}
+	v.Reset()
+	v.Release()
+	if buf.Len() != 0 || v.Finish("ls") {
+		t.Fatalf("reset view printed %q", buf.String())
+	}
+}
+
+func TestStreamViewIgnoresAbandonedRequest(t *testing.T) {
+	buf := captureStreamOutput(t)
+	v := NewPresenter().NewStreamView("", "", "command", nil)
+	// As awaitSuggestion does while it offers the fallback provider
+	v.Hold()
+	primary := v.Callback()
+	primary(`{"command": "ls -`)
+	v.Reset()
+	v.Release()
+
+	fallback := v.Callback()
+	primary(`la /tmp"}`)
+	fallback(`{"command": "find /tmp"}`)
+	if !v.Finish("find /tmp") {
+		t.Fatal("Finish should report the fallback's command as shown")
+	}
+	if got := buf.String(); strings.Contains(got, "ls") || !strings.Contains(got, "find /tmp") {
+		t.Errorf("late text from the abandoned request was printed: %q", got)
+	}
+}
+
+func TestStreamViewNil(t *testing.T) {
+	var v *StreamView
+	if v.Callback() != nil || v.Finish("x") {
+		t.Fatal("nil view should do nothing")
+	}
+	v.Hold()
+	v.Release()
+	v.Reset()
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"golang.org/x/term"
+)
+
+// outputTemplatePresets are names accepted for ui.output_template in place of a template.
+var outputTemplatePresets = map[string]string{
+	"command":  "{{.Command}}\n",
+	"markdown": "{{if .Explanation}}{{.Explanation}}\n\n{{end}}```sh\n{{.Command}}\n```\n",
+	"json":     "{{json .}}\n",
+}
+
+var outputTemplateFuncs = template.FuncMap{
+	"trim": strings.TrimSpace,
+	"json": func(s Suggestion) (string, error) {
+		data, err := json.Marshal(map[string]string{"title": s.Title, "explanation": s.Explanation, "command": s.Command})
+		return string(data), err
+	},
+}
+
+// outputTemplate renders suggestions when stdout is not a terminal, so aish can feed other
+// tools (an editor plugin, a clipboard, a CI log) instead of printing the interactive prompt.
+var outputTemplate *template.Template
+
+// ParseOutputTemplate parses a ui.output_template value: a preset name (command, markdown,
+// json) or a Go text/template over .Title, .Explanation and .Command.
+func ParseOutputTemplate(text string) (*template.Template, error) {
+	if preset, ok := outputTemplatePresets[strings.ToLower(strings.TrimSpace(text))]; ok {
+		text = preset
+	}
+	return template.New("output").Funcs(outputTemplateFuncs).Parse(text)
+}
+
+// SetOutputTemplate installs the template used for non-interactive output. An empty text
+// removes it and restores the interactive prompt everywhere.
+func SetOutputTemplate(text string) error {
+	if strings.TrimSpace(text) == "" {
+		outputTemplate = nil
+		return nil
+	}
+	tmpl, err := ParseOutputTemplate(text)
+	if err != nil {
+		return err
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+// templateOutputActive reports whether suggestions go through the output template: one is
+// configured and stdout is not a terminal.
+func templateOutputActive() bool {
+	return outputTemplate != nil && !term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// renderTemplate writes s through the output template.
+func renderTemplate(w io.Writer, s Suggestion) error {
+	return outputTemplate.Execute(w, s)
+}
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutputTemplatePresets(t *testing.T) {
+	s := Suggestion{Title: "Generated Command", Explanation: "List files", Command: "ls -la"}
+	cases := map[string]string{
+		"command":                                "ls -la\n",
+		"Markdown":                               "List files\n\n```sh\nls -la\n```\n",
+		"json":                                   `{"command":"ls -la","explanation":"List files","title":"Generated Command"}` + "\n",
+		"$ {{.Command}} # {{trim .Explanation}}": "$ ls -la # List files",
+	}
+	for text, want := range cases {
+		if err := SetOutputTemplate(text); err != nil {
+			t.Fatalf("SetOutputTemplate(%q): %v", text, err)
+		}
+		var buf bytes.Buffer
+		if err := renderTemplate(&buf, s); err != nil {
+			t.Fatalf("render %q: %v", text, err)
+		}
+		if buf.String() != want {
+			t.Errorf("template %q rendered %q, want %q", text, buf.String(), want)
+		}
+	}
+	_ = SetOutputTemplate("")
+	if outputTemplate != nil {
+		t.Error("empty template should clear the output template")
+	}
+}
+
+func TestParseOutputTemplateRejectsInvalid(t *testing.T) {
+	if _, err := ParseOutputTemplate("{{.Command"); err == nil {
+		t.Error("unterminated action should be rejected")
+	}
+}
@@ -28,6 +28,7 @@ type ConfigWizard struct {
 	config              *config.Config
 	AdvancedGateEnabled bool
 	QuickStartMode      bool
+	RefreshLists        bool // Fetch model and project lists again instead of using the short-lived cache
 }
 
 // NewConfigWizard creates a new configuration wizard
@@ -94,9 +95,7 @@ func (w *ConfigWizard) Run() error {
 			advancedPrompted = true
 			pterm.Println() // Add extra spacing before the prompt
 			pterm.DefaultSection.Println("Advanced Configuration Options")
-			configureAdvanced, _ := pterm.DefaultInteractiveConfirm.
-				WithDefaultValue(false).
-				Show("Would you like to configure advanced settings (Context, Logging, Cache)?")
+			configureAdvanced, _ := AskConfirm("Would you like to configure advanced settings (Context, Logging, Cache)?", false)
 			if !configureAdvanced {
 				skipAdvanced = true
 				pterm.Info.Println("Skipping advanced settings (Steps 4-6). Using default values.")
@@ -133,9 +132,7 @@ func (w *ConfigWizard) shouldUseQuickStart() bool {
 	pterm.Printf("  • All other settings: optimized defaults\n")
 	pterm.Println()
 
-	useQuickStart, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(true).
-		Show("Would you like to use Quick Start setup?")
+	useQuickStart, _ := AskConfirm("Would you like to use Quick Start setup?", true)
 
 	pterm.Println()
 	return useQuickStart
@@ -144,13 +141,14 @@ func (w *ConfigWizard) shouldUseQuickStart() bool {
 // configureProvider configures LLM provider
 func (w *ConfigWizard) configureProvider() error {
 	// Show provider options
-	providers := []string{"openai", "gemini", "gemini-cli", "claude", "ollama"}
+	providers := []string{"openai", "gemini", "gemini-cli", "vertex", "claude", "ollama"}
 	descriptions := map[string]string{
 		"openai":     "OpenAI GPT series models (requires API key)",
 		"gemini":     "Google Gemini public API (requires API key)",
 		"gemini-cli": "Google Cloud Code private API (requires OAuth)",
-		"claude":     "Anthropic Claude models via Genkit (requires API key)",
-		"ollama":     "Local Ollama models via Genkit (no API key, runs locally)",
+		"vertex":     "Gemini on Vertex AI (service account or gcloud credentials)",
+		"claude":     "Anthropic Claude models (requires API key)",
+		"ollama":     "Local Ollama models (no API key, runs locally)",
 	}
 
 	pterm.Info.Println("Available LLM providers:")
@@ -161,10 +159,7 @@ func (w *ConfigWizard) configureProvider() error {
 	// Tip: suggest gemini-cli to users as an easier, keyless option with generous free usage
 	pterm.Info.Println("Tip: 'gemini-cli' is recommended (OAuth login, no API key, easy setup, often higher free usage)")
 
-	selectedProvider, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(providers).
-		WithDefaultOption(w.config.DefaultProvider).
-		Show("Select the provider you want to configure")
+	selectedProvider, _ := AskSelect("Select the provider you want to configure", providers, w.config.DefaultProvider)
 
 	// Get existing config or create new one
 	providerConfig, exists := w.config.Providers[selectedProvider]
@@ -190,6 +185,10 @@ func (w *ConfigWizard) configureProvider() error {
 		if err := w.configureClaude(&providerConfig); err != nil {
 			return err
 		}
+	case "vertex":
+		if err := w.configureVertex(&providerConfig); err != nil {
+			return err
+		}
 	case "ollama":
 		if err := w.configureOllama(&providerConfig); err != nil {
 			return err
@@ -213,34 +212,86 @@ func (w *ConfigWizard) configureOpenAI(cfg *config.ProviderConfig) error {
 		cfg.APIEndpoint = defaultEndpoint
 	}
 
-	useCustomEndpoint, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(cfg.APIEndpoint != defaultEndpoint).
-		Show("Do you want to use a custom API endpoint?")
+	styles := []string{openAIStyleOfficial, openAIStyleCustom, openAIStyleAzure}
+	current := openAIStyleOfficial
+	switch {
+	case cfg.IsAzure():
+		current = openAIStyleAzure
+	case cfg.APIEndpoint != defaultEndpoint:
+		current = openAIStyleCustom
+	}
+	style, _ := AskSelect("Select the endpoint style", styles, current)
 
-	if useCustomEndpoint {
-		endpoint, _ := pterm.DefaultInteractiveTextInput.
-			WithDefaultValue(cfg.APIEndpoint).
-			Show("Enter OpenAI API endpoint")
+	switch style {
+	case openAIStyleAzure:
+		return w.configureAzureOpenAI(cfg)
+	case openAIStyleCustom:
+		endpoint, _ := AskText("Enter OpenAI API endpoint", cfg.APIEndpoint, false)
 		cfg.APIEndpoint = endpoint
-	} else {
+	default:
 		cfg.APIEndpoint = defaultEndpoint
 	}
+	cfg.AzureDeployment = ""
+	cfg.AzureAPIVersion = ""
 
 	// 自動判斷是否需要省略 /v1 前綴（若端點路徑已包含 /v* 則不再追加）
 	cfg.OmitV1Prefix = shouldOmitV1(cfg.APIEndpoint)
 
 	// API key
 	pterm.Info.Println("You can get your API key at https://platform.openai.com/api-keys")
-	apiKey, _ := pterm.DefaultInteractiveTextInput.
-		WithMask("*").
-		WithDefaultValue(cfg.APIKey).
-		Show("Enter your OpenAI API key")
+	apiKey, _ := AskText("Enter your OpenAI API key", cfg.APIKey, true)
 	cfg.APIKey = apiKey
 
 	// Model selection
 	return w.configureOpenAIModel(cfg)
 }
 
+// OpenAI endpoint styles offered by the wizard
+const (
+	openAIStyleOfficial = "OpenAI (api.openai.com)"
+	openAIStyleCustom   = "OpenAI-compatible endpoint"
+	openAIStyleAzure    = "Azure OpenAI deployment"
+)
+
+// configureAzureOpenAI configures the OpenAI provider for an Azure OpenAI deployment: the
+// resource endpoint, deployment name, API version and key.
+func (w *ConfigWizard) configureAzureOpenAI(cfg *config.ProviderConfig) error {
+	pterm.Info.Println("The endpoint, keys and deployments are under your Azure OpenAI resource in the Azure portal")
+	if !strings.Contains(cfg.APIEndpoint, ".azure.com") {
+		cfg.APIEndpoint = ""
+	}
+	endpoint, _ := AskText("Enter the resource endpoint (https://<resource>.openai.azure.com)", cfg.APIEndpoint, false)
+	cfg.APIEndpoint = strings.TrimSpace(endpoint)
+	cfg.OmitV1Prefix = false
+
+	deployment, _ := AskText("Enter the deployment name", cfg.AzureDeployment, false)
+	cfg.AzureDeployment = strings.TrimSpace(deployment)
+	if cfg.APIEndpoint == "" || cfg.AzureDeployment == "" {
+		return fmt.Errorf("an Azure OpenAI endpoint and deployment name are required")
+	}
+
+	version := cfg.AzureAPIVersion
+	if version == "" {
+		version = config.DefaultAzureAPIVersion
+	}
+	version, _ = AskText("Enter the API version", version, false)
+	if version = strings.TrimSpace(version); version == config.DefaultAzureAPIVersion {
+		version = ""
+	}
+	cfg.AzureAPIVersion = version
+
+	apiKey, _ := AskText("Enter your Azure OpenAI API key", cfg.APIKey, true)
+	cfg.APIKey = apiKey
+
+	// The deployment decides the model; its name still sets token limits and request parameters
+	pterm.Info.Println("Enter the model the deployment runs, e.g. gpt-4o or o3-mini")
+	model, _ := AskText("Enter model name", cfg.Model, false)
+	cfg.Model = strings.TrimSpace(model)
+
+	pterm.Success.Printf("Azure OpenAI configured: deployment %s (%s)\n", cfg.AzureDeployment, cfg.Model)
+	return nil
+}
+
 // configureOpenAIModel configures OpenAI model
 func (w *ConfigWizard) configureOpenAIModel(cfg *config.ProviderConfig) error {
 	pterm.DefaultHeader.Println("Model Selection")
@@ -252,10 +303,7 @@ func (w *ConfigWizard) configureOpenAIModel(cfg *config.ProviderConfig) error {
 		"Manually enter model name",
 	}
 
-	searchMethod, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(searchOptions).
-		WithDefaultOption(searchOptions[0]).
-		Show("Select model configuration method")
+	searchMethod, _ := AskSelect("Select model configuration method", searchOptions, searchOptions[0])
 
 	var selectedModel string
 	var err error
@@ -301,7 +349,8 @@ func (w *ConfigWizard) selectModelFromAPI(cfg *config.ProviderConfig) (string, e
 	if !ok {
 		return "", fmt.Errorf("provider type mismatch")
 	}
-	models, err := oai.GetAvailableModels(ctx)
+	models, err := cachedListOrFetch(listCacheKey("openai-models", cfg.APIEndpoint, cfg.APIKey), w.RefreshLists,
+		func() ([]string, error) { return oai.GetAvailableModels(ctx) })
 	if err != nil {
 		return "", err
 	}
@@ -366,10 +415,7 @@ func (w *ConfigWizard) selectModelFromAPI(cfg *config.ProviderConfig) (string, e
 		}
 	}
 
-	selectedModel, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(allOptions).
-		WithDefaultOption(defaultOption).
-		Show("Select a model")
+	selectedModel, _ := AskSelect("Select a model", allOptions, defaultOption)
 
 	if selectedModel == "Enter model name manually" {
 		return w.inputCustomModel(cfg)
@@ -392,10 +438,7 @@ func (w *ConfigWizard) selectFromCommonModels(cfg *config.ProviderConfig) (strin
 
 	allOptions := append(commonModels, "Enter model name manually")
 
-	selectedModel, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(allOptions).
-		WithDefaultOption(cfg.Model).
-		Show("Select a model")
+	selectedModel, _ := AskSelect("Select a model", allOptions, cfg.Model)
 
 	if selectedModel == "Enter model name manually" {
 		return w.inputCustomModel(cfg)
@@ -409,9 +452,7 @@ func (w *ConfigWizard) inputCustomModel(cfg *config.ProviderConfig) (string, err
 	pterm.Info.Println("You can enter any OpenAI-supported model name.")
 	pterm.Info.Println("Examples: gpt-4o, gpt-4, gpt-3.5-turbo, text-davinci-003, etc.")
 
-	customModel, _ := pterm.DefaultInteractiveTextInput.
-		WithDefaultValue(cfg.Model).
-		Show("Enter model name")
+	customModel, _ := AskText("Enter model name", cfg.Model, false)
 
 	if strings.TrimSpace(customModel) == "" {
 		return "", fmt.Errorf("model name cannot be empty")
@@ -437,14 +478,10 @@ func (w *ConfigWizard) configureGemini(cfg *config.ProviderConfig) error {
 		cfg.APIEndpoint = defaultEndpoint
 	}
 
-	useCustomEndpoint, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(cfg.APIEndpoint != defaultEndpoint).
-		Show("Do you want to use a custom API endpoint?")
+	useCustomEndpoint, _ := AskConfirm("Do you want to use a custom API endpoint?", cfg.APIEndpoint != defaultEndpoint)
 
 	if useCustomEndpoint {
-		endpoint, _ := pterm.DefaultInteractiveTextInput.
-			WithDefaultValue(cfg.APIEndpoint).
-			Show("Enter Gemini API endpoint")
+		endpoint, _ := AskText("Enter Gemini API endpoint", cfg.APIEndpoint, false)
 		cfg.APIEndpoint = endpoint
 	} else {
 		cfg.APIEndpoint = defaultEndpoint
@@ -452,10 +489,7 @@ func (w *ConfigWizard) configureGemini(cfg *config.ProviderConfig) error {
 
 	// API key
 	pterm.Info.Println("You can get an API key from https://makersuite.google.com/app/apikey")
-	apiKey, _ := pterm.DefaultInteractiveTextInput.
-		WithMask("*").
-		WithDefaultValue(cfg.APIKey).
-		Show("Enter your Gemini API key")
+	apiKey, _ := AskText("Enter your Gemini API key", cfg.APIKey, true)
 	cfg.APIKey = apiKey
 
 	// Model selection
@@ -467,15 +501,10 @@ func (w *ConfigWizard) configureGemini(cfg *config.ProviderConfig) error {
 		cfg.Model = "gemini-pro"
 	}
 
-	model, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(append(commonModels, "Enter model name manually")).
-		WithDefaultOption(cfg.Model).
-		Show("Select a model")
+	model, _ := AskSelect("Select a model", append(commonModels, "Enter model name manually"), cfg.Model)
 
 	if model == "Enter model name manually" {
-		customModel, _ := pterm.DefaultInteractiveTextInput.
-			WithDefaultValue(cfg.Model).
-			Show("Enter model name")
+		customModel, _ := AskText("Enter model name", cfg.Model, false)
 		cfg.Model = customModel
 	} else {
 		cfg.Model = model
@@ -501,17 +530,13 @@ func (w *ConfigWizard) configureGeminiCLI(cfg *config.ProviderConfig) error {
 		"Authenticate now via web browser",
 		"Use existing credentials from `gemini-cli`",
 	}
-	authMethod, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(authOptions).
-		WithDefaultOption("Authenticate now via web browser").
-		Show("How would you like to authenticate with Gemini CLI?")
+	authMethod, _ := AskSelect("How would you like to authenticate with Gemini CLI?", authOptions, "Authenticate now via web browser")
 
 	// Only ask for Project ID if using existing credentials
 	if authMethod == "Use existing credentials from `gemini-cli`" {
 		pterm.Info.Println("You need a Google Cloud Project ID for existing credentials.")
 		pterm.Println("Enter your Google Cloud Project ID:")
-		projectID, _ := pterm.DefaultInteractiveTextInput.
-			Show(">")
+		projectID, _ := AskText(">", "", false)
 		cfg.Project = projectID
 	}
 
@@ -525,15 +550,10 @@ func (w *ConfigWizard) configureGeminiCLI(cfg *config.ProviderConfig) error {
 		cfg.Model = "gemini-2.5-flash"
 	}
 
-	model, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(append(commonModels, "Enter model name manually")).
-		WithDefaultOption(cfg.Model).
-		Show("Select a model")
+	model, _ := AskSelect("Select a model", append(commonModels, "Enter model name manually"), cfg.Model)
 
 	if model == "Enter model name manually" {
-		customModel, _ := pterm.DefaultInteractiveTextInput.
-			WithDefaultValue(cfg.Model).
-			Show("Enter model name")
+		customModel, _ := AskText("Enter model name", cfg.Model, false)
 		cfg.Model = customModel
 	} else {
 		cfg.Model = model
@@ -645,9 +665,7 @@ func (w *ConfigWizard) configureGeminiCLI(cfg *config.ProviderConfig) error {
                 // gcloud not found? offer to install and set up
                 if !hasCommand("gcloud") {
                     pterm.Warning.Println("gcloud CLI not found. It is required for local project auto-detection.")
-                    install, _ := pterm.DefaultInteractiveConfirm.
-                        WithDefaultValue(true).
-                        Show("Install Google Cloud CLI (gcloud) now?")
+                    install, _ := AskConfirm("Install Google Cloud CLI (gcloud) now?", true)
                     if install {
                         if err := ensureGcloudInstalled(); err != nil {
                             pterm.Error.Printfln("Failed to install gcloud automatically: %v", err)
@@ -679,7 +697,7 @@ func (w *ConfigWizard) configureGeminiCLI(cfg *config.ProviderConfig) error {
 	                        pterm.Success.Printf("Detected default project from gcloud: %s\n", displayLabelForProject(cfg.Project))
                     } else {
                         // 列出可選專案供使用者選擇
-                        if list, err := listGcloudProjects(); err == nil && len(list) > 0 {
+                        if list, err := listGcloudProjects(w.RefreshLists); err == nil && len(list) > 0 {
                             if len(list) == 1 {
                                 s := strings.TrimSpace(list[0].ProjectID)
                                 if s != "" {
@@ -698,10 +716,7 @@ func (w *ConfigWizard) configureGeminiCLI(cfg *config.ProviderConfig) error {
                                     labelToID[label] = p.ProjectID
                                 }
                                 options = append(options, "Skip")
-                                choice, _ := pterm.DefaultInteractiveSelect.
-                                    WithOptions(options).
-                                    WithDefaultOption(options[0]).
-                                    Show("Select a Google Cloud Project to set as default")
+                                choice, _ := AskSelect("Select a Google Cloud Project to set as default", options, options[0])
                                 if id, ok := labelToID[choice]; ok && strings.TrimSpace(id) != "" {
 	                                    if err := runCommandInteractive("gcloud", "config", "set", "project", id); err == nil {
 	                                        if pid := getGcloudProjectID(); strings.TrimSpace(pid) != "" && pid != "(unset)" {
@@ -715,8 +730,7 @@ func (w *ConfigWizard) configureGeminiCLI(cfg *config.ProviderConfig) error {
                             }
                         } else {
                             // 回退：手動輸入
-                            manualID, _ := pterm.DefaultInteractiveTextInput.
-                                Show("Enter your Google Cloud Project ID to set as default (or leave empty to skip)")
+                            manualID, _ := AskText("Enter your Google Cloud Project ID to set as default (or leave empty to skip)", "", false)
                             if s := strings.TrimSpace(manualID); s != "" {
 	                                if err := runCommandInteractive("gcloud", "config", "set", "project", s); err == nil {
 	                                    if pid := getGcloudProjectID(); strings.TrimSpace(pid) != "" && pid != "(unset)" {
@@ -792,7 +806,7 @@ func (w *ConfigWizard) configureLanguage() error {
 	// Find current language display name
 	currentDisplay := "English"
 	for display, value := range languageValues {
-		if value == w.config.UserPreferences.Language {
+		if value == w.config.UserPreferences.EffectiveLanguage() {
 			currentDisplay = display
 			break
 		}
@@ -801,10 +815,7 @@ func (w *ConfigWizard) configureLanguage() error {
 	pterm.Info.Println("Select the language for AI responses:")
 	pterm.Info.Println("Note: This affects the language used by the AI when generating explanations and suggestions.")
 
-	selectedLanguage, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(languages).
-		WithDefaultOption(currentDisplay).
-		Show("Select your preferred response language")
+	selectedLanguage, _ := AskSelect("Select your preferred response language", languages, currentDisplay)
 
 	// Set the language value
 	if value, ok := languageValues[selectedLanguage]; ok {
@@ -874,30 +885,22 @@ func (w *ConfigWizard) configureContext() error {
 	pterm.DefaultHeader.Println("Context Settings")
 
 	// Max history entries
-	maxHistoryStr, _ := pterm.DefaultInteractiveTextInput.
-		WithDefaultValue(fmt.Sprintf("%d", w.config.UserPreferences.Context.MaxHistoryEntries)).
-		Show("Maximum command history entries (recommended: 10)")
+	maxHistoryStr, _ := AskText("Maximum command history entries (recommended: 10)", fmt.Sprintf("%d", w.config.UserPreferences.Context.MaxHistoryEntries), false)
 
 	if maxHistory, err := strconv.Atoi(maxHistoryStr); err == nil {
 		w.config.UserPreferences.Context.MaxHistoryEntries = maxHistory
 	}
 
 	// Include directory listing
-	includeDir, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(w.config.UserPreferences.Context.IncludeDirectories).
-		Show("Include current directory file listing in context?")
+	includeDir, _ := AskConfirm("Include current directory file listing in context?", w.config.UserPreferences.Context.IncludeDirectories)
 	w.config.UserPreferences.Context.IncludeDirectories = includeDir
 
 	// Filter sensitive commands
-	filterSensitive, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(w.config.UserPreferences.Context.FilterSensitiveCmd).
-		Show("Filter commands containing sensitive info (passwords, keys, etc.)?")
+	filterSensitive, _ := AskConfirm("Filter commands containing sensitive info (passwords, keys, etc.)?", w.config.UserPreferences.Context.FilterSensitiveCmd)
 	w.config.UserPreferences.Context.FilterSensitiveCmd = filterSensitive
 
 	// Enable enhanced analysis
-	enableEnhanced, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(w.config.UserPreferences.Context.EnableEnhanced).
-		Show("Enable enhanced context analysis?")
+	enableEnhanced, _ := AskConfirm("Enable enhanced context analysis?", w.config.UserPreferences.Context.EnableEnhanced)
 	w.config.UserPreferences.Context.EnableEnhanced = enableEnhanced
 
 	return nil
@@ -909,26 +912,17 @@ func (w *ConfigWizard) configureLogging() error {
 
 	// Log level
 	levels := []string{"trace", "debug", "info", "warn", "error"}
-	level, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(levels).
-		WithDefaultOption(w.config.UserPreferences.Logging.Level).
-		Show("Select log level")
+	level, _ := AskSelect("Select log level", levels, w.config.UserPreferences.Logging.Level)
 	w.config.UserPreferences.Logging.Level = level
 
 	// Log format
 	formats := []string{"text", "json"}
-	format, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(formats).
-		WithDefaultOption(w.config.UserPreferences.Logging.Format).
-		Show("Select log format")
+	format, _ := AskSelect("Select log format", formats, w.config.UserPreferences.Logging.Format)
 	w.config.UserPreferences.Logging.Format = format
 
 	// Log output
 	outputs := []string{"file", "console", "both"}
-	output, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(outputs).
-		WithDefaultOption(w.config.UserPreferences.Logging.Output).
-		Show("Select log output")
+	output, _ := AskSelect("Select log output", outputs, w.config.UserPreferences.Logging.Output)
 	w.config.UserPreferences.Logging.Output = output
 
 	return nil
@@ -939,9 +933,7 @@ func (w *ConfigWizard) configureCache() error {
 	pterm.DefaultHeader.Println("Cache Settings")
 
 	// Enable cache
-	enabled, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(w.config.UserPreferences.Cache.Enabled).
-		Show("Enable response caching (improves speed and saves API costs)?")
+	enabled, _ := AskConfirm("Enable response caching (improves speed and saves API costs)?", w.config.UserPreferences.Cache.Enabled)
 	w.config.UserPreferences.Cache.Enabled = enabled
 
 	if !enabled {
@@ -950,16 +942,12 @@ func (w *ConfigWizard) configureCache() error {
 	}
 
 	// Similarity matching
-	enableSimilarity, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(w.config.UserPreferences.Cache.EnableSimilarity).
-		Show("Enable intelligent similarity matching (reuse cache for similar queries)?")
+	enableSimilarity, _ := AskConfirm("Enable intelligent similarity matching (reuse cache for similar queries)?", w.config.UserPreferences.Cache.EnableSimilarity)
 	w.config.UserPreferences.Cache.EnableSimilarity = enableSimilarity
 
 	if enableSimilarity {
 		// Similarity threshold
-		thresholdStr, _ := pterm.DefaultInteractiveTextInput.
-			WithDefaultValue(fmt.Sprintf("%.2f", w.config.UserPreferences.Cache.SimilarityThreshold)).
-			Show("Similarity threshold (0.0-1.0, recommended: 0.85)")
+		thresholdStr, _ := AskText("Similarity threshold (0.0-1.0, recommended: 0.85)", fmt.Sprintf("%.2f", w.config.UserPreferences.Cache.SimilarityThreshold), false)
 
 		if threshold, err := strconv.ParseFloat(thresholdStr, 64); err == nil {
 			w.config.UserPreferences.Cache.SimilarityThreshold = threshold
@@ -967,9 +955,7 @@ func (w *ConfigWizard) configureCache() error {
 	}
 
 	// Cache size
-	maxEntriesStr, _ := pterm.DefaultInteractiveTextInput.
-		WithDefaultValue(fmt.Sprintf("%d", w.config.UserPreferences.Cache.MaxEntries)).
-		Show("Maximum cache entries (recommended: 1000)")
+	maxEntriesStr, _ := AskText("Maximum cache entries (recommended: 1000)", fmt.Sprintf("%d", w.config.UserPreferences.Cache.MaxEntries), false)
 
 	if maxEntries, err := strconv.Atoi(maxEntriesStr); err == nil {
 		w.config.UserPreferences.Cache.MaxEntries = maxEntries
@@ -978,10 +964,9 @@ func (w *ConfigWizard) configureCache() error {
 	return nil
 }
 
-// configureClaude configures Anthropic Claude provider via Genkit
+// configureClaude configures the Anthropic Claude provider
 func (w *ConfigWizard) configureClaude(cfg *config.ProviderConfig) error {
 	pterm.DefaultHeader.Println("Claude (Anthropic) Configuration")
-	pterm.Info.Println("Claude uses Genkit Go framework for unified LLM interaction")
 
 	// API endpoint
 	defaultEndpoint := config.ClaudeAPIEndpoint
@@ -989,14 +974,10 @@ func (w *ConfigWizard) configureClaude(cfg *config.ProviderConfig) error {
 		cfg.APIEndpoint = defaultEndpoint
 	}
 
-	useCustomEndpoint, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(cfg.APIEndpoint != defaultEndpoint).
-		Show("Do you want to use a custom API endpoint?")
+	useCustomEndpoint, _ := AskConfirm("Do you want to use a custom API endpoint?", cfg.APIEndpoint != defaultEndpoint)
 
 	if useCustomEndpoint {
-		endpoint, _ := pterm.DefaultInteractiveTextInput.
-			WithDefaultValue(cfg.APIEndpoint).
-			Show("Enter Claude API endpoint")
+		endpoint, _ := AskText("Enter Claude API endpoint", cfg.APIEndpoint, false)
 		cfg.APIEndpoint = endpoint
 	} else {
 		cfg.APIEndpoint = defaultEndpoint
@@ -1004,10 +985,7 @@ func (w *ConfigWizard) configureClaude(cfg *config.ProviderConfig) error {
 
 	// API key
 	pterm.Info.Println("You can get your API key from https://console.anthropic.com/settings/keys")
-	apiKey, _ := pterm.DefaultInteractiveTextInput.
-		WithMask("*").
-		WithDefaultValue(cfg.APIKey).
-		Show("Enter your Anthropic API key")
+	apiKey, _ := AskText("Enter your Anthropic API key", cfg.APIKey, true)
 	cfg.APIKey = apiKey
 
 	// Model input (manual entry)
@@ -1016,20 +994,16 @@ func (w *ConfigWizard) configureClaude(cfg *config.ProviderConfig) error {
 	}
 
 	pterm.Info.Println("Common models: claude-3-5-sonnet-20241022, claude-3-5-haiku-20241022, claude-3-opus-20240229")
-	pterm.Info.Println("Tip: For OpenAI-compatible endpoints, use prefix like 'openai/gpt-4' or 'openai/glm-4'")
-	model, _ := pterm.DefaultInteractiveTextInput.
-		WithDefaultValue(cfg.Model).
-		Show("Enter model name (with optional prefix)")
+	model, _ := AskText("Enter model name", cfg.Model, false)
 	cfg.Model = strings.TrimSpace(model)
 
-	pterm.Success.Printf("Claude configured: %s via Genkit\n", cfg.Model)
+	pterm.Success.Printf("Claude configured: %s\n", cfg.Model)
 	return nil
 }
 
-// configureOllama configures local Ollama provider via Genkit
+// configureOllama configures local Ollama provider
 func (w *ConfigWizard) configureOllama(cfg *config.ProviderConfig) error {
 	pterm.DefaultHeader.Println("Ollama (Local LLM) Configuration")
-	pterm.Info.Println("Ollama uses Genkit Go framework for unified LLM interaction")
 	pterm.Info.Println("Note: Ollama must be installed and running locally")
 
 	// API endpoint (local)
@@ -1038,14 +1012,10 @@ func (w *ConfigWizard) configureOllama(cfg *config.ProviderConfig) error {
 		cfg.APIEndpoint = defaultEndpoint
 	}
 
-	useCustomEndpoint, _ := pterm.DefaultInteractiveConfirm.
-		WithDefaultValue(cfg.APIEndpoint != defaultEndpoint).
-		Show("Do you want to use a custom Ollama endpoint?")
+	useCustomEndpoint, _ := AskConfirm("Do you want to use a custom Ollama endpoint?", cfg.APIEndpoint != defaultEndpoint)
 
 	if useCustomEndpoint {
-		endpoint, _ := pterm.DefaultInteractiveTextInput.
-			WithDefaultValue(cfg.APIEndpoint).
-			Show("Enter Ollama API endpoint")
+		endpoint, _ := AskText("Enter Ollama API endpoint", cfg.APIEndpoint, false)
 		cfg.APIEndpoint = endpoint
 	} else {
 		cfg.APIEndpoint = defaultEndpoint
@@ -1062,16 +1032,59 @@ func (w *ConfigWizard) configureOllama(cfg *config.ProviderConfig) error {
 
 	pterm.Info.Println("Common local models: llama3.3, llama3.1, codellama, mistral, gemma, qwen")
 	pterm.Info.Println("Tip: Make sure you have pulled the model with: ollama pull <model-name>")
-	model, _ := pterm.DefaultInteractiveTextInput.
-		WithDefaultValue(cfg.Model).
-		Show("Enter model name (prefix 'ollama/' will be added if not present)")
+	model, _ := AskText("Enter model name", cfg.Model, false)
 	cfg.Model = strings.TrimSpace(model)
 
-	pterm.Success.Printf("Ollama configured: %s via Genkit (local)\n", cfg.Model)
+	pterm.Success.Printf("Ollama configured: %s (local)\n", cfg.Model)
 	pterm.Warning.Println("Remember to start Ollama with: ollama serve")
 	return nil
 }
 
+// configureVertex configures Gemini on Vertex AI: the project, region, model and whether to
+// authenticate with a service account key or Application Default Credentials.
+func (w *ConfigWizard) configureVertex(cfg *config.ProviderConfig) error {
+	pterm.DefaultHeader.Println("Vertex AI Configuration")
+	pterm.Info.Println("Requests go to your project's Vertex AI endpoint; the Vertex AI API must be enabled")
+
+	project, _ := AskText("Enter your Google Cloud project ID (empty = the service account's project)", cfg.Project, false)
+	cfg.Project = strings.TrimSpace(project)
+
+	if cfg.Location == "" {
+		cfg.Location = config.DefaultVertexLocation
+	}
+	location, _ := AskText("Enter the Vertex AI region, or global", cfg.Location, false)
+	cfg.Location = strings.TrimSpace(location)
+
+	const keyFile, adc = "Service account key file (JSON)", "Application Default Credentials (gcloud, metadata server)"
+	current := adc
+	if cfg.CredentialsFile != "" {
+		current = keyFile
+	}
+	method, _ := AskSelect("How should aish authenticate?", []string{keyFile, adc}, current)
+	if method == keyFile {
+		path, _ := AskText("Enter the path of the key file", cfg.CredentialsFile, false)
+		cfg.CredentialsFile = strings.TrimSpace(path)
+		if _, err := os.Stat(config.ExpandHome(cfg.CredentialsFile)); err != nil {
+			pterm.Warning.Printfln("Cannot read %s: %v", cfg.CredentialsFile, err)
+		}
+	} else {
+		cfg.CredentialsFile = ""
+		pterm.Info.Println("Run 'gcloud auth application-default login' if this machine has no credentials yet")
+	}
+
+	if cfg.Model == "" {
+		cfg.Model = config.DefaultVertexModel
+	}
+	model, _ := AskSelect("Select a model", []string{"gemini-2.5-flash", "gemini-2.5-pro", "Enter model name manually"}, cfg.Model)
+	if model == "Enter model name manually" {
+		model, _ = AskText("Enter model name", cfg.Model, false)
+	}
+	cfg.Model = strings.TrimSpace(model)
+
+	pterm.Success.Printf("Vertex AI configured: %s in %s\n", cfg.Model, cfg.Location)
+	return nil
+}
+
 // finishConfiguration completes configuration
 func (w *ConfigWizard) finishConfiguration() error {
 	pterm.DefaultHeader.Println("Configuration Complete")
@@ -1082,9 +1095,7 @@ func (w *ConfigWizard) finishConfiguration() error {
 	if err != nil {
 		pterm.Error.Println("Configuration validation failed:", err)
 
-		retry, _ := pterm.DefaultInteractiveConfirm.
-			WithDefaultValue(true).
-			Show("Run the configuration wizard again?")
+		retry, _ := AskConfirm("Run the configuration wizard again?", true)
 
 		if retry {
 			return w.Run()
@@ -1129,7 +1140,7 @@ func (w *ConfigWizard) showConfigurationSummary() {
 	}
 
 	// User preferences
-	pterm.Printf("• Response Language: %s\n", w.config.UserPreferences.Language)
+	pterm.Printf("• Response Language: %s\n", w.config.UserPreferences.EffectiveLanguage())
 	pterm.Printf("• Enabled Error Triggers: %d\n", len(w.config.UserPreferences.EnabledLLMTriggers))
 
 	// Feature flags
@@ -1172,7 +1183,7 @@ func (w *ConfigWizard) runQuickStart() error {
 
 	// Step 2: Set optimal defaults for user preferences
 	pterm.Info.Println("✓ Configuring user preferences...")
-	w.config.UserPreferences.Language = "english"
+	w.config.UserPreferences.Language = "" // Follow the system locale
 	w.config.UserPreferences.AutoExecute = false // Safe default
 
 	// Enable all common error triggers for comprehensive coverage
@@ -1260,12 +1271,10 @@ func (w *ConfigWizard) runQuickStart() error {
 // enableGeminiAPIsForProject enables the required Google Cloud APIs for a project
 func enableGeminiAPIsForProject(ctx context.Context, projectID string) error {
     // Try to get access token from OAuth credentials
-    cfgPath, err := config.GetConfigPath()
+    credsPath, err := auth.CredentialsPath()
     if err != nil {
         return fmt.Errorf("failed to get config path: %w", err)
     }
-    dir := filepath.Dir(cfgPath)
-    credsPath := filepath.Join(dir, "gemini_oauth_creds.json")
 
     data, err := os.ReadFile(credsPath)
     if err != nil {
@@ -1329,14 +1338,12 @@ func enableGeminiAPIsForProject(ctx context.Context, projectID string) error {
     }
 }
 
-// readProjectIDFromAishCreds 嘗試從 AISH 設定目錄中的 gemini_oauth_creds.json 讀取 project_id
+// readProjectIDFromAishCreds 嘗試從目前 profile 的 gemini_oauth_creds.json 讀取 project_id
 func readProjectIDFromAishCreds() string {
-    cfgPath, err := config.GetConfigPath()
+    path, err := auth.CredentialsPath()
     if err != nil {
         return ""
     }
-    dir := filepath.Dir(cfgPath)
-    path := filepath.Join(dir, "gemini_oauth_creds.json")
     b, err := os.ReadFile(path)
     if err != nil || len(b) == 0 {
         return ""
@@ -1387,11 +1394,32 @@ type gcloudProject struct {
     LifecycleState  string `json:"lifecycleState"`
 }
 
-// listGcloudProjects returns ACTIVE projects visible to the current gcloud account
-func listGcloudProjects() ([]gcloudProject, error) {
+// listGcloudProjects returns ACTIVE projects visible to the current gcloud account, reusing a
+// recent listing for the same gcloud configuration unless refresh is set
+func listGcloudProjects(refresh bool) ([]gcloudProject, error) {
     if !hasCommand("gcloud") {
         return nil, fmt.Errorf("gcloud not found")
     }
+    return cachedListOrFetch(listCacheKey("gcloud-projects", gcloudConfigFingerprint()), refresh, fetchGcloudProjects)
+}
+
+// gcloudConfigFingerprint returns the contents of the active gcloud configuration, which names
+// the account, so a cached project list is not reused after switching accounts.
+func gcloudConfigFingerprint() string {
+    dir := os.Getenv("CLOUDSDK_CONFIG")
+    if dir == "" {
+        home, _ := os.UserHomeDir()
+        dir = filepath.Join(home, ".config", "gcloud")
+    }
+    active := "default"
+    if data, err := os.ReadFile(filepath.Join(dir, "active_config")); err == nil && strings.TrimSpace(string(data)) != "" {
+        active = strings.TrimSpace(string(data))
+    }
+    data, _ := os.ReadFile(filepath.Join(dir, "configurations", "config_"+active))
+    return active + "\n" + string(data)
+}
+
+func fetchGcloudProjects() ([]gcloudProject, error) {
     out, err := exec.Command("gcloud", "projects", "list", "--format=json").CombinedOutput()
     if err != nil {
         return nil, fmt.Errorf("gcloud list failed: %v", err)
@@ -0,0 +1,126 @@
+package update
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// PublicKey is a minisign public key.
+type PublicKey struct {
+	KeyID [8]byte
+	Key   ed25519.PublicKey
+}
+
+// Signature is a parsed .minisig file. Its trusted comment is kept unexported: it is only checked
+// by Verify through the global signature and never shown or used.
+type Signature struct {
+	Algorithm       [2]byte // "Ed" signs the file itself, "ED" signs its BLAKE2b-512 hash
+	KeyID           [8]byte
+	Sig             []byte
+	trustedComment  string
+	globalSignature []byte
+}
+
+// ErrSignatureMismatch is returned when a signature does not verify.
+var ErrSignatureMismatch = errors.New("signature verification failed")
+
+// ParsePublicKey parses a minisign public key, either the bare base64 line or the full
+// minisign.pub file with its "untrusted comment:" line.
+func ParsePublicKey(s string) (PublicKey, error) {
+	var pk PublicKey
+	line := lastNonCommentLine(s)
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		return pk, fmt.Errorf("decode public key: %w", err)
+	}
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return pk, errors.New("not a minisign Ed25519 public key")
+	}
+	copy(pk.KeyID[:], raw[2:10])
+	pk.Key = ed25519.PublicKey(raw[10:])
+	return pk, nil
+}
+
+// ParseSignature parses the four-line .minisig format.
+func ParseSignature(data []byte) (Signature, error) {
+	var sig Signature
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	if len(lines) < 4 {
+		return sig, errors.New("truncated minisign signature")
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	if !strings.HasPrefix(lines[0], "untrusted comment:") {
+		return sig, errors.New("minisign signature is missing its untrusted comment")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return sig, fmt.Errorf("decode signature: %w", err)
+	}
+	if len(raw) != 2+8+ed25519.SignatureSize {
+		return sig, errors.New("malformed minisign signature")
+	}
+	copy(sig.Algorithm[:], raw[:2])
+	copy(sig.KeyID[:], raw[2:10])
+	sig.Sig = raw[10:]
+
+	const trustedPrefix = "trusted comment: "
+	if !strings.HasPrefix(lines[2], trustedPrefix) {
+		return sig, errors.New("minisign signature is missing its trusted comment")
+	}
+	sig.trustedComment = strings.TrimPrefix(lines[2], trustedPrefix)
+
+	if sig.globalSignature, err = base64.StdEncoding.DecodeString(lines[3]); err != nil {
+		return sig, fmt.Errorf("decode global signature: %w", err)
+	}
+	if len(sig.globalSignature) != ed25519.SignatureSize {
+		return sig, errors.New("malformed minisign global signature")
+	}
+	return sig, nil
+}
+
+// Verify checks sig over message, including the signature of the trusted comment, so a
+// signature cannot be replayed with a different comment.
+func (pk PublicKey) Verify(message []byte, sig Signature) error {
+	if sig.KeyID != pk.KeyID {
+		return fmt.Errorf("%w: signed with key %X, expected %X", ErrSignatureMismatch, sig.KeyID, pk.KeyID)
+	}
+
+	signed := message
+	switch string(sig.Algorithm[:]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(message)
+		signed = sum[:]
+	default:
+		return fmt.Errorf("unsupported minisign algorithm %q", sig.Algorithm[:])
+	}
+	if !ed25519.Verify(pk.Key, signed, sig.Sig) {
+		return ErrSignatureMismatch
+	}
+
+	global := append(bytes.Clone(sig.Sig), sig.trustedComment...)
+	if !ed25519.Verify(pk.Key, global, sig.globalSignature) {
+		return fmt.Errorf("%w: trusted comment was altered", ErrSignatureMismatch)
+	}
+	return nil
+}
+
+func lastNonCommentLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			return line
+		}
+	}
+	return ""
+}
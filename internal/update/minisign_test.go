@@ -0,0 +1,68 @@
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// testKey returns a minisign public key line and a function producing .minisig files.
+func testKey(t *testing.T) (string, func(msg []byte, comment string) []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	pkLine := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+
+	sign := func(msg []byte, comment string) []byte {
+		hash := blake2b.Sum512(msg)
+		sig := ed25519.Sign(priv, hash[:])
+		global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+		raw := append(append([]byte("ED"), keyID...), sig...)
+		return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+			base64.StdEncoding.EncodeToString(raw), comment, base64.StdEncoding.EncodeToString(global)))
+	}
+	return "untrusted comment: minisign public key 0807060504030201\n" + pkLine + "\n", sign
+}
+
+func TestVerifyMinisignSignature(t *testing.T) {
+	pkText, sign := testKey(t)
+	pk, err := ParsePublicKey(pkText)
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	msg := []byte("abc123  aish_1.0.0_linux_amd64.tar.gz\n")
+	sig, err := ParseSignature(sign(msg, "timestamp:1700000000\tfile:checksums.txt"))
+	if err != nil {
+		t.Fatalf("ParseSignature: %v", err)
+	}
+	if err := pk.Verify(msg, sig); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+
+	if err := pk.Verify([]byte("tampered"), sig); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("tampered message: %v", err)
+	}
+	sig.trustedComment = "timestamp:0"
+	if err := pk.Verify(msg, sig); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("altered trusted comment: %v", err)
+	}
+}
+
+func TestVerifyRejectsOtherKey(t *testing.T) {
+	_, sign := testKey(t)
+	otherText, _ := testKey(t)
+	other, _ := ParsePublicKey(otherText)
+
+	sig, _ := ParseSignature(sign([]byte("data"), "c"))
+	if err := other.Verify([]byte("data"), sig); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("signature from another key accepted: %v", err)
+	}
+}
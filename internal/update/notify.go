@@ -0,0 +1,79 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TonnyWong1052/aish/internal/config"
+)
+
+const (
+	// CheckInterval is how often the background check asks GitHub for the latest release.
+	CheckInterval = 7 * 24 * time.Hour
+	// NoticeInterval is the minimum time between two new-release notices.
+	NoticeInterval = 24 * time.Hour
+
+	checkStateFile = "update_check.json"
+)
+
+// CheckState is what the background check remembers between runs.
+type CheckState struct {
+	CheckedAt  time.Time `json:"checked_at"`
+	Latest     string    `json:"latest,omitempty"`
+	NotifiedAt time.Time `json:"notified_at"`
+}
+
+// CheckStatePath returns where the check state is kept.
+func CheckStatePath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, checkStateFile), nil
+}
+
+// LoadCheckState reads the check state; a missing or unreadable file is an empty state.
+func LoadCheckState(path string) CheckState {
+	var st CheckState
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &st)
+	}
+	return st
+}
+
+// SaveCheckState writes the check state atomically.
+func SaveCheckState(path string, st CheckState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CheckDue reports whether the last release check is older than CheckInterval.
+func (s CheckState) CheckDue(now time.Time) bool {
+	return now.Sub(s.CheckedAt) >= CheckInterval
+}
+
+// TakeNotice returns the one-line notice about a newer release than current, at most once per
+// NoticeInterval, and records that it was shown.
+func (s *CheckState) TakeNotice(current string, now time.Time) (string, bool) {
+	if s.Latest == "" || CompareVersions(s.Latest, current) <= 0 {
+		return "", false
+	}
+	if now.Sub(s.NotifiedAt) < NoticeInterval {
+		return "", false
+	}
+	s.NotifiedAt = now
+	return fmt.Sprintf("aish %s is available (you have %s); run 'aish upgrade' to update.", s.Latest, current), true
+}
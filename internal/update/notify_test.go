@@ -0,0 +1,41 @@
+package update
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTakeNoticeThrottlesToOncePerDay(t *testing.T) {
+	now := time.Now()
+	st := CheckState{CheckedAt: now, Latest: "v1.3.0"}
+
+	if _, ok := st.TakeNotice("v1.3.0", now); ok {
+		t.Fatal("no notice when already on the latest release")
+	}
+	if msg, ok := st.TakeNotice("v1.2.0", now); !ok || msg == "" {
+		t.Fatal("expected a notice for a newer release")
+	}
+	if _, ok := st.TakeNotice("v1.2.0", now.Add(time.Hour)); ok {
+		t.Error("second notice within a day should be suppressed")
+	}
+	if _, ok := st.TakeNotice("v1.2.0", now.Add(25*time.Hour)); !ok {
+		t.Error("notice should come back after a day")
+	}
+}
+
+func TestCheckStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", checkStateFile)
+	now := time.Now().Truncate(time.Second)
+
+	if st := LoadCheckState(path); !st.CheckDue(now) {
+		t.Fatal("a missing state should make the check due")
+	}
+	if err := SaveCheckState(path, CheckState{CheckedAt: now, Latest: "v2.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	st := LoadCheckState(path)
+	if st.Latest != "v2.0.0" || st.CheckDue(now.Add(time.Hour)) || !st.CheckDue(now.Add(CheckInterval)) {
+		t.Errorf("unexpected state after reload: %+v", st)
+	}
+}
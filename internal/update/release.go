@@ -0,0 +1,255 @@
+// Package update finds, verifies and installs aish releases published on GitHub.
+package update
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAPIBase is the GitHub API endpoint releases are looked up from.
+	DefaultAPIBase = "https://api.github.com"
+	// Repository is the GitHub repository aish is released from.
+	Repository = "TonnyWong1052/aish"
+
+	maxDownloadSize = 100 << 20
+)
+
+// releasePublicKey is the minisign public key release checksums are signed with. Release
+// builds inject it with -X github.com/TonnyWong1052/aish/internal/update.releasePublicKey=...;
+// builds without one cannot verify downloads and only upgrade with --insecure.
+var releasePublicKey string
+
+// ReleasePublicKey returns the embedded release signing key, if this build has one.
+func ReleasePublicKey() (PublicKey, bool) {
+	if strings.TrimSpace(releasePublicKey) == "" {
+		return PublicKey{}, false
+	}
+	pk, err := ParsePublicKey(releasePublicKey)
+	return pk, err == nil
+}
+
+// Release is a published GitHub release.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Client talks to the GitHub releases API.
+type Client struct {
+	HTTP    *http.Client
+	APIBase string
+}
+
+// NewClient returns a client for the public GitHub API.
+func NewClient() *Client {
+	return &Client{HTTP: &http.Client{Timeout: 60 * time.Second}, APIBase: DefaultAPIBase}
+}
+
+// Latest returns the newest non-prerelease release.
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	url := strings.TrimRight(c.APIBase, "/") + "/repos/" + Repository + "/releases/latest"
+	data, err := c.get(ctx, url, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	var rel Release
+	if err := json.Unmarshal(data, &rel); err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	if rel.TagName == "" {
+		return nil, errors.New("release has no tag")
+	}
+	return &rel, nil
+}
+
+// Download fetches the named asset of rel.
+func (c *Client) Download(ctx context.Context, rel *Release, name string) ([]byte, error) {
+	asset, ok := rel.Asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no asset %s", rel.TagName, name)
+	}
+	return c.get(ctx, asset.URL, maxDownloadSize)
+}
+
+func (c *Client) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: response larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// Asset returns the asset called name.
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// ArchiveName is the GoReleaser archive name for a version and platform.
+func ArchiveName(version, goos, goarch string) string {
+	return fmt.Sprintf("aish_%s_%s_%s.tar.gz", strings.TrimPrefix(version, "v"), goos, goarch)
+}
+
+// ChecksumsName is the GoReleaser checksums file name for a version. Its minisign signature
+// is published next to it with a .minisig suffix.
+func ChecksumsName(version string) string {
+	return fmt.Sprintf("aish_%s_checksums.txt", strings.TrimPrefix(version, "v"))
+}
+
+// VerifyArchive checks that checksums is signed by pk and lists archive under name with a
+// matching SHA-256.
+func VerifyArchive(pk PublicKey, checksums, signature []byte, name string, archive []byte) error {
+	sig, err := ParseSignature(signature)
+	if err != nil {
+		return err
+	}
+	if err := pk.Verify(checksums, sig); err != nil {
+		return fmt.Errorf("checksums: %w", err)
+	}
+
+	want, ok := checksumFor(checksums, name)
+	if !ok {
+		return fmt.Errorf("checksums do not list %s", name)
+	}
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return fmt.Errorf("%s: SHA-256 %s does not match signed checksum %s", name, got, want)
+	}
+	return nil
+}
+
+// checksumFor finds name in a sha256sum-style listing.
+func checksumFor(checksums []byte, name string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], true
+		}
+	}
+	return "", false
+}
+
+// ExtractBinary returns the contents of the file called name from a .tar.gz archive.
+func ExtractBinary(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("archive does not contain %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == name {
+			return io.ReadAll(io.LimitReader(tr, maxDownloadSize))
+		}
+	}
+}
+
+// ReplaceExecutable atomically replaces the executable at path with data, keeping its mode.
+// The new binary is written next to the old one so the final rename never crosses filesystems.
+func ReplaceExecutable(path string, data []byte) error {
+	mode := os.FileMode(0o755)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".aish-upgrade-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, mode); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// CompareVersions compares two vX.Y.Z versions numerically, returning -1, 0 or 1. Missing or
+// non-numeric parts count as zero and a pre-release suffix is ignored.
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < 3; i++ {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) [3]int {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	for i, s := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(s)
+	}
+	return parts
+}
@@ -0,0 +1,118 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write([]byte(body))
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}
+
+func TestVerifyArchive(t *testing.T) {
+	pkText, sign := testKey(t)
+	pk, _ := ParsePublicKey(pkText)
+
+	archive := tarGz(t, map[string]string{"aish": "new binary"})
+	name := ArchiveName("v1.2.3", "linux", "amd64")
+	sum := sha256.Sum256(archive)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	signature := sign(checksums, "release")
+
+	if err := VerifyArchive(pk, checksums, signature, name, archive); err != nil {
+		t.Fatalf("VerifyArchive: %v", err)
+	}
+	if err := VerifyArchive(pk, checksums, signature, name, append(archive, 0)); err == nil {
+		t.Error("modified archive should fail its checksum")
+	}
+	if err := VerifyArchive(pk, checksums, signature, "aish_1.2.3_darwin_arm64.tar.gz", archive); err == nil {
+		t.Error("archive missing from the checksums should be rejected")
+	}
+
+	bin, err := ExtractBinary(archive, "aish")
+	if err != nil || string(bin) != "new binary" {
+		t.Errorf("ExtractBinary: %q, %v", bin, err)
+	}
+}
+
+func TestLatestAndDownload(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + Repository + "/releases/latest":
+			_, _ = w.Write([]byte(`{"tag_name":"v1.2.3","assets":[{"name":"aish_1.2.3_checksums.txt","browser_download_url":"` + srv.URL + `/dl/sums"}]}`))
+		case "/dl/sums":
+			_, _ = w.Write([]byte("sums"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{HTTP: srv.Client(), APIBase: srv.URL}
+	rel, err := c.Latest(context.Background())
+	if err != nil || rel.TagName != "v1.2.3" {
+		t.Fatalf("Latest: %+v, %v", rel, err)
+	}
+	data, err := c.Download(context.Background(), rel, ChecksumsName(rel.TagName))
+	if err != nil || string(data) != "sums" {
+		t.Errorf("Download: %q, %v", data, err)
+	}
+	if _, err := c.Download(context.Background(), rel, "missing.minisig"); err == nil || !strings.Contains(err.Error(), "no asset") {
+		t.Errorf("missing asset: %v", err)
+	}
+}
+
+func TestReplaceExecutableKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aish")
+	if err := os.WriteFile(path, []byte("old"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceExecutable(path, []byte("new")); err != nil {
+		t.Fatalf("ReplaceExecutable: %v", err)
+	}
+	info, _ := os.Stat(path)
+	data, _ := os.ReadFile(path)
+	if string(data) != "new" || info.Mode().Perm() != 0o750 {
+		t.Errorf("got %q with mode %v", data, info.Mode().Perm())
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.10.0", "v1.9.9", 1},
+		{"0.0.2", "v0.0.10", -1},
+		{"v1.0.0-rc1", "v1.0.0", 0},
+	}
+	for _, c := range cases {
+		if got := CompareVersions(c.a, c.b); got != c.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}